@@ -10,33 +10,41 @@ import (
 
 func runAdd(args []string) error {
 	fs := flag.NewFlagSet("add", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	format := fs.String("format", "text", "output format (json or text)")
 	var files multiString
 	fs.Var(&files, "file", "file to add (can be specified multiple times)")
 	noAutoDisambiguate := fs.Bool("no-auto-disambiguate", false,
 		"disable automatic link rewriting when basename collision occurs")
+	keepBackup, cleanBackups := backupFlags(fs)
+	preserveMtime := fs.Bool("preserve-mtime", false, "keep the original modification time of files whose links are rewritten")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	files = vaultPathArgs(fs, files)
 	if err := validateFormat(*format); err != nil {
 		return err
 	}
+	if *cleanBackups {
+		return runCleanBackups(*vault, *format)
+	}
 	if len(files) == 0 {
 		return fmt.Errorf("--file is required")
 	}
 	result, err := core.Add(*vault, core.AddOptions{
 		Files:            files,
 		AutoDisambiguate: !*noAutoDisambiguate,
+		KeepBackup:       *keepBackup,
+		PreserveMtime:    *preserveMtime,
 	})
 	if err != nil {
 		return err
 	}
 	switch *format {
 	case "json":
-		return printAddJSON(os.Stdout, result)
+		return printAddJSON(summaryOut(os.Stdout), result)
 	default:
-		printAddText(os.Stdout, result)
+		printAddText(summaryOut(os.Stdout), result)
 		return nil
 	}
 }
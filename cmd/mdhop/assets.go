@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+func runAssets(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("assets: subcommand required (move, prune)")
+	}
+	switch args[0] {
+	case "move":
+		return runAssetsMove(args[1:])
+	case "prune":
+		return runAssetsPrune(args[1:])
+	default:
+		return fmt.Errorf("assets: unknown subcommand: %s", args[0])
+	}
+}
+
+// runAssetsMove moves a single asset, rewriting the embeds and markdown
+// image links that point to it. A directory destination keeps the file name.
+func runAssetsMove(args []string) error {
+	fs := flag.NewFlagSet("assets move", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	from := fs.String("from", "", "source asset path (vault-relative)")
+	to := fs.String("to", "", "destination file path or directory (vault-relative)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	// Accept "assets move image.png assets/" as well as --from/--to.
+	rest := fs.Args()
+	if *from == "" && len(rest) > 0 {
+		*from, rest = rest[0], rest[1:]
+	}
+	if *to == "" && len(rest) > 0 {
+		*to, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(rest, " "))
+	}
+	if *from == "" {
+		return fmt.Errorf("--from is required")
+	}
+	if *to == "" {
+		return fmt.Errorf("--to is required")
+	}
+	*from = vaultPathArg(fs, *from)
+	*to = vaultPathArg(fs, *to)
+
+	src := core.NormalizePath(*from)
+	if strings.HasSuffix(strings.ToLower(src), ".md") {
+		return fmt.Errorf("not an asset: %s (use 'mdhop move' for notes)", src)
+	}
+	dest := *to
+	if isDirArg(*vault, dest) || isRegisteredDir(*vault, dest) {
+		dest = path.Join(core.NormalizePath(strings.TrimSuffix(dest, "/")), path.Base(src))
+	}
+	dest = core.NormalizePath(dest)
+	if strings.HasSuffix(strings.ToLower(dest), ".md") {
+		return fmt.Errorf("destination must not be a note path: %s", dest)
+	}
+
+	result, err := core.Move(*vault, core.MoveOptions{From: src, To: dest})
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "json":
+		return printMoveJSON(summaryOut(os.Stdout), src, dest, result)
+	default:
+		printMoveText(summaryOut(os.Stdout), src, dest, result)
+		return nil
+	}
+}
+
+func runAssetsPrune(args []string) error {
+	fs := flag.NewFlagSet("assets prune", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	apply := fs.Bool("apply", false, "delete the files (default is a dry run)")
+	minAge := fs.Duration("min-age", 24*time.Hour, "keep assets modified more recently than this")
+	all := fs.Bool("all", false, "also delete unreferenced files that are not images, audio, video or PDF")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	if *minAge < 0 {
+		return fmt.Errorf("--min-age must be >= 0")
+	}
+
+	result, err := core.PruneAssets(*vault, core.PruneAssetsOptions{Apply: *apply, MinAge: *minAge, All: *all})
+	if err != nil && result == nil {
+		return err
+	}
+
+	var printErr error
+	switch *format {
+	case "json":
+		printErr = printPruneAssetsJSON(summaryOut(os.Stdout), result)
+	default:
+		printPruneAssetsText(summaryOut(os.Stdout), result)
+	}
+	if err != nil {
+		return err
+	}
+	return printErr
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+// backupFlags registers --keep-backup and --clean-backups, shared by the
+// commands that rewrite links in files.
+func backupFlags(fs *flag.FlagSet) (keep, clean *bool) {
+	keep = fs.Bool("keep-backup", false, "save the original of every rewritten file as <path>.bak")
+	clean = fs.Bool("clean-backups", false, "remove the .bak files left by --keep-backup and exit")
+	return keep, clean
+}
+
+// runCleanBackups handles --clean-backups.
+func runCleanBackups(vault, format string) error {
+	removed, err := core.CleanBackups(vault)
+	if err != nil {
+		return err
+	}
+	w := summaryOut(os.Stdout)
+	if format == "json" {
+		if removed == nil {
+			removed = []string{}
+		}
+		return encodeJSON(w, map[string][]string{"removed": removed})
+	}
+	printStringListText(w, "removed", removed)
+	return nil
+}
@@ -1,16 +1,162 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/ryotapoi/mdhop/internal/core"
 )
 
 func runBuild(args []string) error {
 	fs := flag.NewFlagSet("build", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	followSymlinks := fs.Bool("follow-symlinks", false, "descend into symlinked directories")
+	respectGitignore := fs.Bool("respect-gitignore", false, "skip paths ignored by .gitignore files in the vault")
+	interactive := fs.Bool("interactive", false, "prompt for a target when a basename link is ambiguous, then retry")
+	baseDir := fs.String("base-dir", "", "index only this subdirectory, resolving links as if it were the vault root")
+	edgesOnly := fs.Bool("edges-only", false, "re-parse registered notes and recreate edges, keeping node ids")
+	threads := fs.Int("threads", 0, "number of notes parsed concurrently (0: number of CPUs)")
+	var excludePaths multiString
+	fs.Var(&excludePaths, "exclude", "exclude paths matching glob for this build (repeatable)")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
-	return core.Build(*vault)
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	if *threads < 0 {
+		return fmt.Errorf("--threads must be >= 0")
+	}
+	if *interactive && *format == "json" {
+		return fmt.Errorf("--interactive cannot be used with --format json")
+	}
+	if *interactive && *baseDir != "" {
+		return fmt.Errorf("--interactive cannot be used with --base-dir")
+	}
+	if *edgesOnly && (*followSymlinks || *respectGitignore || *interactive || *baseDir != "" || len(excludePaths) > 0 || *threads != 0) {
+		return fmt.Errorf("--edges-only cannot be used with --follow-symlinks, --respect-gitignore, --interactive, --base-dir, --exclude or --threads")
+	}
+
+	opts := core.BuildOptions{
+		FollowSymlinks:   *followSymlinks,
+		RespectGitignore: *respectGitignore,
+		ExcludePaths:     excludePaths,
+		BaseDir:          *baseDir,
+		Threads:          *threads,
+	}
+	if *interactive {
+		return buildInteractive(os.Stdin, os.Stderr, *vault, opts)
+	}
+	var err error
+	if *edgesOnly {
+		err = core.RebuildEdges(*vault)
+	} else {
+		err = core.BuildWithOptions(*vault, opts)
+	}
+	if *format != "json" {
+		return err
+	}
+
+	var be *core.BuildError
+	if err != nil && !errors.As(err, &be) {
+		return err
+	}
+	if be == nil {
+		be = &core.BuildError{}
+	}
+	if perr := printBuildErrorsJSON(os.Stdout, be); perr != nil {
+		return perr
+	}
+	if len(be.Issues) > 0 {
+		return fmt.Errorf("build failed: %d error(s)", len(be.Issues))
+	}
+	return nil
+}
+
+// ambiguousName groups the ambiguous links that share one basename.
+type ambiguousName struct {
+	name       string
+	candidates []string
+}
+
+// buildInteractive runs build, and on ambiguous basename links asks once per
+// basename which candidate the links should point to, rewrites them to that
+// full path, and retries. Errors that cannot be resolved by choosing a
+// candidate (vault-escape links, ambiguous assets) are returned as-is.
+func buildInteractive(in io.Reader, out io.Writer, vault string, opts core.BuildOptions) error {
+	r := bufio.NewReader(in)
+	for {
+		err := core.BuildWithOptions(vault, opts)
+		var be *core.BuildError
+		if !errors.As(err, &be) {
+			return err
+		}
+		names := groupAmbiguous(be.Issues)
+		if len(names) == 0 {
+			return err
+		}
+		for _, a := range names {
+			target, perr := promptCandidate(r, out, a)
+			if perr != nil {
+				return perr
+			}
+			result, derr := core.DisambiguateScan(vault, core.DisambiguateOptions{
+				Name:   a.name,
+				Target: target,
+			})
+			if derr != nil {
+				return derr
+			}
+			fmt.Fprintf(out, "rewrote %d link(s) to %s\n", len(result.Rewritten), target)
+		}
+	}
+}
+
+// groupAmbiguous returns the ambiguous note basenames in first-seen order.
+func groupAmbiguous(issues []core.BuildIssue) []ambiguousName {
+	var out []ambiguousName
+	seen := make(map[string]bool)
+	for _, is := range issues {
+		if is.Kind != "ambiguous" || len(is.Candidates) == 0 {
+			continue
+		}
+		key := strings.ToLower(is.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, ambiguousName{name: is.Name, candidates: is.Candidates})
+	}
+	return out
+}
+
+// promptCandidate lists the candidates for a and reads a 1-based choice,
+// asking again on invalid input.
+func promptCandidate(r *bufio.Reader, out io.Writer, a ambiguousName) (string, error) {
+	fmt.Fprintf(out, "ambiguous link: %s\n", a.name)
+	for i, c := range a.candidates {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, c)
+	}
+	for {
+		fmt.Fprintf(out, "choose [1-%d]: ", len(a.candidates))
+		line, err := r.ReadString('\n')
+		n, convErr := strconv.Atoi(strings.TrimSpace(line))
+		if convErr == nil && n >= 1 && n <= len(a.candidates) {
+			return a.candidates[n-1], nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				return "", fmt.Errorf("build aborted: no choice for %s", a.name)
+			}
+			return "", err
+		}
+		fmt.Fprintln(out, "invalid choice")
+	}
 }
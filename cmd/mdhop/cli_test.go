@@ -3,10 +3,16 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ryotapoi/mdhop/internal/core"
 	"github.com/ryotapoi/mdhop/internal/testutil"
@@ -19,6 +25,13 @@ func TestRunBuild_InvalidFlag(t *testing.T) {
 	}
 }
 
+func TestRunBuild_EdgesOnlyWithBaseDir(t *testing.T) {
+	err := runBuild([]string{"--edges-only", "--base-dir", "sub"})
+	if err == nil || !strings.Contains(err.Error(), "--edges-only cannot be used") {
+		t.Errorf("expected --edges-only conflict error, got: %v", err)
+	}
+}
+
 func TestRunResolve_MissingFrom(t *testing.T) {
 	err := runResolve([]string{"--link", "[[X]]"})
 	if err == nil || !strings.Contains(err.Error(), "--from is required") {
@@ -47,6 +60,65 @@ func TestRunResolve_InvalidField(t *testing.T) {
 	}
 }
 
+func TestPrintResolveAbs(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_full")
+
+	result, err := core.Resolve(vault, "Index.md", "[[sub/Impl]]")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := printResolveAbs(&buf, vault, result, "text"); err != nil {
+		t.Fatalf("print: %v", err)
+	}
+	got := strings.TrimSuffix(buf.String(), "\n")
+	if !filepath.IsAbs(got) {
+		t.Errorf("path %q is not absolute", got)
+	}
+	if _, err := os.Stat(got); err != nil {
+		t.Errorf("resolved path does not exist: %v", err)
+	}
+	if filepath.Base(got) != "Impl.md" {
+		t.Errorf("path = %q, want .../sub/Impl.md", got)
+	}
+
+	buf.Reset()
+	if err := printResolveAbs(&buf, vault, result, "json"); err != nil {
+		t.Fatalf("print json: %v", err)
+	}
+	var out resolveAbsJSON
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.Type != "note" || !out.Exists || out.Path != got {
+		t.Errorf("json = %+v, want note at %s", out, got)
+	}
+}
+
+func TestPrintResolveAbs_Phantom(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_full")
+
+	result, err := core.Resolve(vault, "Index.md", "[[Missing]]")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	var buf bytes.Buffer
+	err = printResolveAbs(&buf, vault, result, "text")
+	if err == nil || !strings.Contains(err.Error(), "phantom") {
+		t.Errorf("expected phantom error, got: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("output = %q, want none", buf.String())
+	}
+}
+
+func TestRunResolve_AbsWithAll(t *testing.T) {
+	err := runResolve([]string{"--all", "A.md", "--abs"})
+	if err == nil || !strings.Contains(err.Error(), "--abs cannot be combined with --all") {
+		t.Errorf("expected --abs/--all error, got: %v", err)
+	}
+}
+
 func TestRunQuery_InvalidFormat(t *testing.T) {
 	err := runQuery([]string{"--file", "A.md", "--format", "yaml"})
 	if err == nil || !strings.Contains(err.Error(), "invalid format") {
@@ -79,6 +151,35 @@ func TestRunStats_InvalidField(t *testing.T) {
 	}
 }
 
+func TestRunStats_IncomingRequiresHistogram(t *testing.T) {
+	err := runStats([]string{"--incoming"})
+	if err == nil || !strings.Contains(err.Error(), "--incoming requires --histogram") {
+		t.Errorf("expected --incoming requires --histogram error, got: %v", err)
+	}
+}
+
+func TestRunStats_NoTagsRequiresTop(t *testing.T) {
+	err := runStats([]string{"--no-tags"})
+	if err == nil || !strings.Contains(err.Error(), "--no-tags requires --top") {
+		t.Errorf("expected --no-tags requires --top error, got: %v", err)
+	}
+}
+
+func TestPrintStatsText_Top(t *testing.T) {
+	r := &core.StatsResult{
+		TopReferenced: []core.NoteCount{{Path: "Design.md", Count: 4}},
+		TopLinking:    []core.NoteCount{},
+	}
+	var buf bytes.Buffer
+	if err := printStatsText(&buf, r, []string{"notes_total"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "notes_total: 0\ntop_referenced:\n- path: Design.md\n  count: 4\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
 func setupVaultForCLI(t *testing.T, name string) string {
 	t.Helper()
 	root := filepath.Join("..", "..", "testdata", name)
@@ -445,6 +546,14 @@ func TestRunMove_MissingFrom(t *testing.T) {
 	}
 }
 
+func TestRunMove_MaxDepthRequiresDir(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_move_basic")
+	err := runMove([]string{"--vault", vault, "--from", "A.md", "--to", "Z.md", "--max-depth", "1"})
+	if err == nil || !strings.Contains(err.Error(), "--max-depth requires a directory --from") {
+		t.Errorf("expected --max-depth error, got: %v", err)
+	}
+}
+
 func TestRunMove_MissingTo(t *testing.T) {
 	err := runMove([]string{"--from", "A.md"})
 	if err == nil || !strings.Contains(err.Error(), "--to is required") {
@@ -482,6 +591,49 @@ func TestRunMove_Integration(t *testing.T) {
 	}
 }
 
+func TestRunMove_RenameRoot(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_move_basic")
+
+	if err := runMove([]string{"--vault", vault, "--rename", "A.md", "Renamed.md"}); err != nil {
+		t.Fatalf("move --rename: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(vault, "Renamed.md")); err != nil {
+		t.Error("Renamed.md should exist on disk after rename")
+	}
+	b, err := os.ReadFile(filepath.Join(vault, "B.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "[[Renamed]]") {
+		t.Errorf("B.md = %q, want [[A]] rewritten to [[Renamed]]", b)
+	}
+}
+
+func TestRunMove_RenameInSubdir(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_move_basic")
+
+	if err := runMove([]string{"--vault", vault, "--rename", "--from", "sub/D.md", "--to", "E.md"}); err != nil {
+		t.Fatalf("move --rename: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(vault, "sub", "E.md")); err != nil {
+		t.Error("sub/E.md should exist on disk after rename")
+	}
+	if _, err := os.Stat(filepath.Join(vault, "E.md")); err == nil {
+		t.Error("E.md should not be created at the vault root")
+	}
+	if _, err := core.Query(vault, core.EntrySpec{File: "sub/E.md"}, core.QueryOptions{}); err != nil {
+		t.Errorf("querying sub/E.md: %v", err)
+	}
+}
+
+func TestRunMove_RenameRejectsPath(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_move_basic")
+	err := runMove([]string{"--vault", vault, "--rename", "A.md", "sub/A2.md"})
+	if err == nil || !strings.Contains(err.Error(), "not a path") {
+		t.Errorf("expected file name error, got: %v", err)
+	}
+}
+
 // --- Diagnose CLI tests ---
 
 func TestRunDiagnose_InvalidFormat(t *testing.T) {
@@ -535,6 +687,23 @@ func TestRunDiagnose_TextOutput(t *testing.T) {
 	}
 }
 
+func TestPrintDiagnoseText_FragileRootPriority(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_root_priority")
+
+	result, err := core.Diagnose(vault, core.DiagnoseOptions{Fields: []string{"fragile_root_priority"}})
+	if err != nil {
+		t.Fatalf("diagnose: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := printDiagnoseText(&buf, result, []string{"fragile_root_priority"}); err != nil {
+		t.Fatalf("printDiagnoseText: %v", err)
+	}
+	want := "fragile_root_priority:\n- name: A\n  root_path: A.md\n  paths:\n  - A.md\n  - sub/A.md\n  sources:\n  - B.md\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
 func TestRunDiagnose_JSONOutput(t *testing.T) {
 	vault := setupVaultForCLI(t, "vault_query_ambiguous_name")
 
@@ -624,8 +793,193 @@ func TestPrintDiagnoseJSON_FieldsFilter(t *testing.T) {
 	}
 }
 
+func TestPrintDiagnoseJSON_EmptySectionsAreArrays(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_empty")
+
+	result, err := core.Diagnose(vault, core.DiagnoseOptions{})
+	if err != nil {
+		t.Fatalf("diagnose: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printDiagnoseJSON(&buf, result, nil); err != nil {
+		t.Fatalf("printDiagnoseJSON: %v", err)
+	}
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("json unmarshal: %v", err)
+	}
+	// Dashboards read every section as an array, even when it is empty.
+	for field := range validDiagnoseFieldsCLI {
+		if got := string(m[field]); got != "[]" {
+			t.Errorf("%s = %s, want []", field, got)
+		}
+	}
+}
+
 // --- Disambiguate CLI tests ---
 
+func TestRunVerify_InvalidFormat(t *testing.T) {
+	err := runVerify([]string{"--format", "yaml"})
+	if err == nil || !strings.Contains(err.Error(), "invalid format") {
+		t.Errorf("expected invalid format error, got: %v", err)
+	}
+}
+
+func TestRunVerify_FailsOnDiscrepancy(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_query_ambiguous_name")
+	if err := runVerify([]string{"--vault", vault, "--format", "json"}); err != nil {
+		t.Fatalf("verify on fresh build: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(vault, "Extra.md"), []byte("# Extra\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := runVerify([]string{"--vault", vault, "--format", "json"})
+	if err == nil || !strings.Contains(err.Error(), "index verification failed: 1 issue(s)") {
+		t.Errorf("expected verification failure, got: %v", err)
+	}
+}
+
+func TestPrintVerifyText(t *testing.T) {
+	r := &core.VerifyResult{Issues: []core.VerifyIssue{{Kind: "missing_file", Path: "A.md", Detail: "indexed but not on disk"}}}
+	var buf bytes.Buffer
+	if err := printVerifyText(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	want := "ok: false\nissues:\n- kind: missing_file\n  path: A.md\n  detail: indexed but not on disk\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintPruneAssetsText(t *testing.T) {
+	r := &core.PruneAssetsResult{
+		Pruned:         []core.PrunedAsset{{Path: "orphan.txt", Bytes: 12}},
+		SkippedRecent:  []string{"new.png"},
+		ReclaimedBytes: 12,
+	}
+	var buf bytes.Buffer
+	printPruneAssetsText(&buf, r)
+	want := "dry_run: true\npruned:\n- path: orphan.txt\n  bytes: 12\nskipped_recent:\n- new.png\nreclaimed_bytes: 12\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestRunAssets_UnknownSubcommand(t *testing.T) {
+	if err := runAssets([]string{}); err == nil || !strings.Contains(err.Error(), "subcommand required") {
+		t.Errorf("expected subcommand required error, got: %v", err)
+	}
+	if err := runAssets([]string{"list"}); err == nil || !strings.Contains(err.Error(), "unknown subcommand") {
+		t.Errorf("expected unknown subcommand error, got: %v", err)
+	}
+}
+
+func TestRunAssetsPrune_DryRunKeepsFiles(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_assets")
+	if err := runAssetsPrune([]string{"--vault", vault, "--min-age", "0s", "--format", "json"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(vault, "orphan.txt")); err != nil {
+		t.Errorf("dry run removed orphan.txt: %v", err)
+	}
+}
+
+func TestRunAssetsMove_IntoDirectory(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_assets")
+	if err := os.MkdirAll(filepath.Join(vault, "files"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := runAssetsMove([]string{"--vault", vault, "doc.pdf", "files/"}); err != nil {
+		t.Fatalf("assets move: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(vault, "files", "doc.pdf")); err != nil {
+		t.Errorf("files/doc.pdf should exist: %v", err)
+	}
+	a, err := os.ReadFile(filepath.Join(vault, "A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The basename stays unique, so the link keeps resolving without a rewrite.
+	if !strings.Contains(string(a), "[doc](doc.pdf)") {
+		t.Errorf("A.md = %q, want link unchanged", a)
+	}
+}
+
+func TestRunAssetsMove_RejectsNote(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_assets")
+	err := runAssetsMove([]string{"--vault", vault, "A.md", "notes/"})
+	if err == nil || !strings.Contains(err.Error(), "not an asset") {
+		t.Errorf("expected not an asset error, got: %v", err)
+	}
+}
+
+func TestRunTag_Positional(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_assets")
+	if err := runTag([]string{"add", "--vault", vault, "#review", "A.md"}); err != nil {
+		t.Fatalf("tag add: %v", err)
+	}
+	a, err := os.ReadFile(filepath.Join(vault, "A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(a), "---\ntags:\n  - review\n---\n") {
+		t.Errorf("A.md = %q, want frontmatter with review", a)
+	}
+}
+
+func TestRunTag_MissingSubcommand(t *testing.T) {
+	err := runTag(nil)
+	if err == nil || !strings.Contains(err.Error(), "subcommand required") {
+		t.Errorf("expected subcommand error, got: %v", err)
+	}
+}
+
+func TestPrintTagsText_Tree(t *testing.T) {
+	tags := []core.TagCount{
+		{Tag: "#a", Count: 3},
+		{Tag: "#a/x", Count: 2, Depth: 1},
+		{Tag: "#b", Count: 1},
+	}
+	var buf bytes.Buffer
+	printTagsText(&buf, tags, true)
+	want := "tags:\n- tag: #a\n  count: 3\n  - tag: #a/x\n    count: 2\n- tag: #b\n  count: 1\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintPhantomsText(t *testing.T) {
+	phantoms := []core.PhantomRefs{
+		{Name: "Missing", Count: 2, RawLinks: []string{"[[Missing]]", "[[Missing|alias]]"}},
+	}
+	var buf bytes.Buffer
+	printPhantomsText(&buf, phantoms)
+	want := "phantoms:\n- name: Missing\n  count: 2\n  raw_links:\n  - \"[[Missing]]\"\n  - \"[[Missing|alias]]\"\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintSearchText(t *testing.T) {
+	r := &core.SearchResult{Mode: "fts5", Hits: []core.SearchHit{{Path: "Index.md", Snippet: "[Welcome] to the vault."}}}
+	var buf bytes.Buffer
+	printSearchText(&buf, r)
+	want := "mode: fts5\nhits:\n- path: Index.md\n  snippet: [Welcome] to the vault.\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestRunSearch_MissingQuery(t *testing.T) {
+	err := runSearch([]string{"--vault", t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "search query is required") {
+		t.Errorf("expected query required error, got: %v", err)
+	}
+}
+
 func TestRunDisambiguate_InvalidFormat(t *testing.T) {
 	err := runDisambiguate([]string{"--name", "A", "--format", "yaml"})
 	if err == nil || !strings.Contains(err.Error(), "invalid format") {
@@ -790,11 +1144,588 @@ func TestRunMove_DirToMdError(t *testing.T) {
 	}
 }
 
-func TestRunMove_FileToDirError(t *testing.T) {
+func TestPrintMovePorcelain(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_move_basic")
+
+	result, err := core.Move(vault, core.MoveOptions{From: "A.md", To: "sub/A.md"})
+	if err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	var buf bytes.Buffer
+	printMovePorcelain(&buf, "A.md", "sub/A.md", result)
+	want := "moved\tA.md\tsub/A.md\n" +
+		"C.md\t[link to A](./A.md)\t[link to A](sub/A.md)\n" +
+		"sub/D.md\t[path link](../A.md)\t[path link](sub/A.md)\n" +
+		"sub/A.md\t[link to B](./B.md)\t[link to B](../B.md)\n" +
+		"sub/A.md\t[link to C](./C.md)\t[link to C](../C.md)\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%q\nwant:\n%q", buf.String(), want)
+	}
+}
+
+func TestRunMove_FileToDirAppendsBasename(t *testing.T) {
 	vault := setupVaultForCLI(t, "vault_delete_dir")
 
 	err := runMove([]string{"--vault", vault, "--from", "Root.md", "--to", "newdir/"})
-	if err == nil || !strings.Contains(err.Error(), "cannot use directory destination for single file move") {
-		t.Errorf("expected directory destination error, got: %v", err)
+	if err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(vault, "newdir", "Root.md")); err != nil {
+		t.Error("newdir/Root.md should exist after move")
+	}
+	if _, err := core.Query(vault, core.EntrySpec{File: "newdir/Root.md"}, core.QueryOptions{}); err != nil {
+		t.Errorf("querying newdir/Root.md: %v", err)
+	}
+}
+
+func TestRunMove_FileToExistingDirNoSlash(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_delete_dir")
+
+	err := runMove([]string{"--vault", vault, "--from", "Root.md", "--to", "sub"})
+	if err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(vault, "sub", "Root.md")); err != nil {
+		t.Error("sub/Root.md should exist after move")
+	}
+}
+
+func TestRunMove_FileToRegisteredDirMissingOnDisk(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_delete_dir")
+	// sub/inner is still registered in the index but gone from disk.
+	if err := os.RemoveAll(filepath.Join(vault, "sub", "inner")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runMove([]string{"--vault", vault, "--from", "Root.md", "--to", "sub/inner"})
+	if err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(vault, "sub", "inner", "Root.md")); err != nil {
+		t.Error("sub/inner/Root.md should exist after move")
+	}
+}
+
+func TestRunMove_FileToDirDestinationExists(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_delete_dir")
+	if err := os.WriteFile(filepath.Join(vault, "sub", "Root.md"), []byte("other\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runMove([]string{"--vault", vault, "--from", "Root.md", "--to", "sub/"})
+	if err == nil || !strings.Contains(err.Error(), "already exists on disk: sub/Root.md") {
+		t.Errorf("expected destination exists error, got: %v", err)
+	}
+}
+
+func TestRunMove_FileToDirEscapesVault(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_delete_dir")
+
+	err := runMove([]string{"--vault", vault, "--from", "Root.md", "--to", "../"})
+	if err == nil || !strings.Contains(err.Error(), "destination escapes vault: ../Root.md") {
+		t.Errorf("expected vault escape error, got: %v", err)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"1700000000", 1700000000},
+		{"2023-11-14T22:13:20Z", 1700000000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseSince(tt.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Unix() != tt.want {
+				t.Errorf("parseSince(%q) = %d, want %d", tt.in, got.Unix(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRunUpdate_InvalidSince(t *testing.T) {
+	err := runUpdate([]string{"--since", "yesterday"})
+	if err == nil || !strings.Contains(err.Error(), "invalid --since") {
+		t.Errorf("expected invalid --since error, got: %v", err)
+	}
+}
+
+func TestRunQuery_BrokenWithEntry(t *testing.T) {
+	err := runQuery([]string{"--vault", t.TempDir(), "--broken", "--file", "A.md"})
+	if err == nil || !strings.Contains(err.Error(), "--broken cannot be combined") {
+		t.Errorf("expected combination error, got: %v", err)
+	}
+}
+
+func TestPrintBuildErrorsJSON(t *testing.T) {
+	vault := filepath.Join(t.TempDir(), "vault")
+	if err := testutil.CopyDir(filepath.Join("..", "..", "testdata", "vault_build_multi_error"), vault); err != nil {
+		t.Fatalf("copy vault: %v", err)
+	}
+	var be *core.BuildError
+	if err := core.Build(vault); !errors.As(err, &be) {
+		t.Fatalf("expected *core.BuildError, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printBuildErrorsJSON(&buf, be); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Errors []struct {
+			File    string `json:"file"`
+			Line    int    `json:"line"`
+			Kind    string `json:"kind"`
+			Message string `json:"message"`
+		} `json:"errors"`
+		Truncated *bool `json:"truncated"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("json unmarshal: %v", err)
+	}
+	kinds := map[string]int{}
+	for _, e := range out.Errors {
+		kinds[e.Kind]++
+	}
+	if kinds["ambiguous"] != 2 || kinds["escape"] != 1 {
+		t.Errorf("kinds = %v, want 2 ambiguous + 1 escape\n%s", kinds, buf.String())
+	}
+	if out.Truncated == nil || *out.Truncated {
+		t.Errorf("truncated = %v, want false", out.Truncated)
+	}
+}
+
+func TestBuildInteractive(t *testing.T) {
+	vault := filepath.Join(t.TempDir(), "vault")
+	if err := testutil.CopyDir(filepath.Join("..", "..", "testdata", "vault_build_multi_error"), vault); err != nil {
+		t.Fatalf("copy vault: %v", err)
+	}
+	// Drop the vault-escape link so only ambiguities remain.
+	if err := os.Remove(filepath.Join(vault, "File3.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	// "x" is rejected and re-prompted; A → sub1/A.md, B → sub2/B.md.
+	if err := buildInteractive(strings.NewReader("x\n1\n2\n"), &out, vault, core.BuildOptions{}); err != nil {
+		t.Fatalf("buildInteractive: %v\n%s", err, out.String())
+	}
+	if strings.Count(out.String(), "ambiguous link:") != 2 {
+		t.Errorf("expected one prompt per basename, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "invalid choice") {
+		t.Errorf("expected invalid choice message, got:\n%s", out.String())
+	}
+	for file, want := range map[string]string{"File1.md": "[[sub1/A]]", "File2.md": "[[sub2/B]]"} {
+		data, err := os.ReadFile(filepath.Join(vault, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(string(data)) != want {
+			t.Errorf("%s = %q, want %q", file, data, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(vault, ".mdhop", "index.sqlite")); err != nil {
+		t.Errorf("index not built: %v", err)
+	}
+}
+
+func TestBuildInteractive_EscapeNotPrompted(t *testing.T) {
+	vault := filepath.Join(t.TempDir(), "vault")
+	if err := testutil.CopyDir(filepath.Join("..", "..", "testdata", "vault_build_multi_error"), vault); err != nil {
+		t.Fatalf("copy vault: %v", err)
+	}
+
+	var out bytes.Buffer
+	err := buildInteractive(strings.NewReader("1\n1\n"), &out, vault, core.BuildOptions{})
+	if err == nil || !strings.Contains(err.Error(), "escapes vault") {
+		t.Errorf("expected vault escape error, got %v", err)
+	}
+}
+
+func TestBuildInteractive_EOF(t *testing.T) {
+	vault := filepath.Join(t.TempDir(), "vault")
+	if err := testutil.CopyDir(filepath.Join("..", "..", "testdata", "vault_build_multi_error"), vault); err != nil {
+		t.Fatalf("copy vault: %v", err)
+	}
+
+	var out bytes.Buffer
+	err := buildInteractive(strings.NewReader(""), &out, vault, core.BuildOptions{})
+	if err == nil || !strings.Contains(err.Error(), "build aborted") {
+		t.Errorf("expected abort error, got %v", err)
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for _, tc := range []struct {
+		args  []string
+		rest  []string
+		level core.LogLevel
+	}{
+		{[]string{"build"}, []string{"build"}, core.LogNormal},
+		{[]string{"--quiet", "build"}, []string{"build"}, core.LogQuiet},
+		{[]string{"-v", "move", "--from", "A.md"}, []string{"move", "--from", "A.md"}, core.LogVerbose},
+		// After the command name -v belongs to the command (a search term).
+		{[]string{"search", "-v"}, []string{"search", "-v"}, core.LogNormal},
+	} {
+		globals, rest := splitGlobalArgs(tc.args)
+		globals, level, err := parseLogLevel(globals)
+		rest = append(globals, rest...)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.args, err)
+		}
+		if strings.Join(rest, " ") != strings.Join(tc.rest, " ") || level != tc.level {
+			t.Errorf("%v: got %v %d, want %v %d", tc.args, rest, level, tc.rest, tc.level)
+		}
+	}
+	if _, _, err := parseLogLevel([]string{"-q", "-v"}); err == nil {
+		t.Error("expected error for --quiet with --verbose")
+	}
+}
+
+func TestParseLockTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		args    []string
+		rest    []string
+		timeout time.Duration
+		set     bool
+	}{
+		{[]string{"build"}, []string{"build"}, 0, false},
+		{[]string{"--lock-timeout", "2s", "build"}, []string{"build"}, 2 * time.Second, true},
+		{[]string{"--lock-timeout=0", "-q", "move"}, []string{"-q", "move"}, 0, true},
+		{[]string{"search", "--lock-timeout=1s"}, []string{"search", "--lock-timeout=1s"}, 0, false},
+	} {
+		globals, rest := splitGlobalArgs(tc.args)
+		globals, timeout, set, err := parseLockTimeout(globals)
+		rest = append(globals, rest...)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.args, err)
+		}
+		if strings.Join(rest, " ") != strings.Join(tc.rest, " ") || timeout != tc.timeout || set != tc.set {
+			t.Errorf("%v: got %v %s %v, want %v %s %v", tc.args, rest, timeout, set, tc.rest, tc.timeout, tc.set)
+		}
+	}
+	for _, args := range [][]string{{"--lock-timeout"}, {"--lock-timeout=soon", "build"}, {"--lock-timeout=-1s"}} {
+		if _, _, _, err := parseLockTimeout(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestSummaryOutQuiet(t *testing.T) {
+	defer func() { quiet = false }()
+	var buf bytes.Buffer
+	quiet = true
+	fmt.Fprint(summaryOut(&buf), "summary")
+	if buf.Len() != 0 {
+		t.Errorf("quiet output = %q, want empty", buf.String())
+	}
+	quiet = false
+	fmt.Fprint(summaryOut(&buf), "summary")
+	if buf.String() != "summary" {
+		t.Errorf("output = %q, want %q", buf.String(), "summary")
+	}
+}
+
+func TestStreamQueryText_MatchesBuffered(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_full")
+
+	tests := []struct {
+		name  string
+		entry core.EntrySpec
+		opts  core.QueryOptions
+	}{
+		{"note", core.EntrySpec{File: "sub/Impl.md"}, core.QueryOptions{}},
+		{"tag paged", core.EntrySpec{Tag: "#overview"}, core.QueryOptions{MaxBacklinks: 1, Offset: 1}},
+		{"offset past end", core.EntrySpec{Tag: "#overview"}, core.QueryOptions{Offset: 5}},
+		{"phantom", core.EntrySpec{Phantom: "Missing"}, core.QueryOptions{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Fields = []string{"backlinks"}
+
+			result, err := core.Query(vault, tt.entry, opts)
+			if err != nil {
+				t.Fatalf("query: %v", err)
+			}
+			var buffered, streamed bytes.Buffer
+			if err := printQueryText(&buffered, result); err != nil {
+				t.Fatalf("printQueryText: %v", err)
+			}
+			if err := streamQueryText(&streamed, vault, tt.entry, opts); err != nil {
+				t.Fatalf("streamQueryText: %v", err)
+			}
+			if streamed.String() != buffered.String() {
+				t.Errorf("streamed output differs\nstreamed:\n%s\nbuffered:\n%s", streamed.String(), buffered.String())
+			}
+		})
+	}
+}
+
+func TestRunQuery_StreamRequiresBacklinksText(t *testing.T) {
+	for _, args := range [][]string{
+		{"--file", "a.md", "--stream"},
+		{"--file", "a.md", "--stream", "--fields", "backlinks", "--format", "json"},
+		{"--file", "a.md", "--stream", "--fields", "backlinks", "--positions"},
+	} {
+		if err := runQuery(args); err == nil || !strings.Contains(err.Error(), "--stream") {
+			t.Errorf("runQuery(%v) error = %v, want --stream error", args, err)
+		}
+	}
+}
+
+func TestRunLint_FailOn(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_full")
+
+	// vault_build_full has broken links (error severity).
+	if err := runLint([]string{"--vault", vault, "--format", "json"}); err == nil || !strings.Contains(err.Error(), "lint failed") {
+		t.Errorf("default --fail-on: err = %v, want lint failed", err)
+	}
+	if err := runLint([]string{"--vault", vault, "--format", "json", "--fail-on", "none"}); err != nil {
+		t.Errorf("--fail-on none: unexpected error: %v", err)
+	}
+	if err := runLint([]string{"--vault", vault, "--format", "json", "--exclude", "broken-link"}); err != nil {
+		t.Errorf("--exclude broken-link: unexpected error: %v", err)
+	}
+	if err := runLint([]string{"--vault", vault, "--fail-on", "fatal"}); err == nil {
+		t.Error("expected error for invalid --fail-on")
+	}
+}
+
+func TestPrintLintText(t *testing.T) {
+	var buf bytes.Buffer
+	printLintText(&buf, []core.LintFinding{
+		{Rule: "broken-link", Severity: "error", Path: "A.md", Line: 3, Message: "[[X]] points to missing note \"X\""},
+		{Rule: "orphan-note", Severity: "info", Path: "B.md", Message: "no other note links here"},
+	})
+	want := `findings:
+- rule: broken-link
+  severity: error
+  path: A.md
+  line: 3
+  message: [[X]] points to missing note "X"
+- rule: orphan-note
+  severity: info
+  path: B.md
+  message: no other note links here
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestServe_Query(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_full")
+	ix, err := core.OpenIndex(vault)
+	if err != nil {
+		t.Fatalf("open index: %v", err)
+	}
+	defer ix.Close()
+	srv := httptest.NewServer(newServeHandler(ix, vault))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("healthz: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("healthz status = %d, want 200", resp.StatusCode)
+	}
+
+	resp, err = http.Post(srv.URL+"/query", "application/json",
+		strings.NewReader(`{"file": "Design.md", "fields": ["backlinks", "outgoing"]}`))
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("query status = %d, want 200", resp.StatusCode)
+	}
+	var out queryJSONOutput
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var backlinks []string
+	for _, n := range out.Backlinks {
+		backlinks = append(backlinks, n.Path)
+	}
+	if want := []string{"Index.md", "sub/Impl.md"}; !reflect.DeepEqual(backlinks, want) {
+		t.Errorf("backlinks = %v, want %v", backlinks, want)
+	}
+	if len(out.Outgoing) == 0 {
+		t.Error("expected outgoing links")
+	}
+
+	// Errors come back as JSON with a 4xx status.
+	resp, err = http.Post(srv.URL+"/query", "application/json", strings.NewReader(`{"file": "Nope.md"}`))
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("missing note status = %d, want 422", resp.StatusCode)
+	}
+	resp, err = http.Get(srv.URL + "/query")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /query status = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestServe_Resolve(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_full")
+	ix, err := core.OpenIndex(vault)
+	if err != nil {
+		t.Fatalf("open index: %v", err)
+	}
+	defer ix.Close()
+	srv := httptest.NewServer(newServeHandler(ix, vault))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/resolve", "application/json",
+		strings.NewReader(`{"from": "Index.md", "link": "[[Design]]"}`))
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out["path"] != "Design.md" {
+		t.Errorf("resolve = %v, want path Design.md", out)
+	}
+
+	resp, err = http.Post(srv.URL+"/resolve", "application/json",
+		strings.NewReader(`{"from": "Design.md", "all": true}`))
+	if err != nil {
+		t.Fatalf("resolve all: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("resolve all status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestParseVault(t *testing.T) {
+	for _, tc := range []struct {
+		args  []string
+		rest  []string
+		vault string
+		set   bool
+	}{
+		{[]string{"build"}, []string{"build"}, "", false},
+		{[]string{"--vault", "notes", "build"}, []string{"build"}, "notes", true},
+		{[]string{"--vault=v", "query", "--file", "A.md"}, []string{"query", "--file", "A.md"}, "v", true},
+		// The command's own --vault is left to its flag set.
+		{[]string{"query", "--vault=v"}, []string{"query", "--vault=v"}, "", false},
+		{[]string{"--", "--vault=v"}, []string{"--", "--vault=v"}, "", false},
+	} {
+		globals, rest := splitGlobalArgs(tc.args)
+		globals, vault, set, err := parseVault(globals)
+		rest = append(globals, rest...)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.args, err)
+		}
+		if strings.Join(rest, " ") != strings.Join(tc.rest, " ") || vault != tc.vault || set != tc.set {
+			t.Errorf("%v: got %v %q %v, want %v %q %v", tc.args, rest, vault, set, tc.rest, tc.vault, tc.set)
+		}
+	}
+	for _, args := range [][]string{{"--vault"}, {"--vault=", "build"}} {
+		if _, _, _, err := parseVault(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+// chdir changes the working directory for the rest of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	prev, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(prev) })
+}
+
+func TestDiscoverVaultFromSubdirectory(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_full")
+	if err := runBuild([]string{"--vault", vault}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	sub := filepath.Join(vault, "sub")
+	chdir(t, sub)
+	root, base := discoverVault()
+	if _, err := os.Stat(filepath.Join(root, ".mdhop")); err != nil {
+		t.Fatalf("discoverVault() = %s, want the vault root: %v", root, err)
+	}
+	if base != "sub" {
+		t.Fatalf("discoverVault() base = %q, want sub", base)
+	}
+
+	// Without the discovered default, update would look for sub/.mdhop.
+	// Path arguments are relative to the working directory.
+	defer func(prev, prevBase string) { defaultVault, argBase = prev, prevBase }(defaultVault, argBase)
+	defaultVault, argBase = root, base
+	if err := runUpdate([]string{"--file", "Impl.md"}); err != nil {
+		t.Fatalf("update from a subdirectory: %v", err)
+	}
+	if err := runQuery([]string{"--file", "Impl.md", "--fields", "backlinks"}); err != nil {
+		t.Errorf("query from a subdirectory: %v", err)
+	}
+	if err := runUpdate([]string{"--file", "../Index.md"}); err != nil {
+		t.Errorf("update of a parent file from a subdirectory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(sub, ".mdhop")); !os.IsNotExist(err) {
+		t.Errorf("sub/.mdhop exists (err=%v), want the vault root's index used", err)
+	}
+	// A command's own --vault takes vault-relative paths.
+	if err := runUpdate([]string{"--vault", vault, "--file", "sub/Impl.md"}); err != nil {
+		t.Errorf("update with the command's --vault: %v", err)
+	}
+
+	// At the vault root itself the default stays relative.
+	chdir(t, vault)
+	if got, base := discoverVault(); got != "." || base != "" {
+		t.Errorf("discoverVault() at the root = %q %q, want . and no base", got, base)
+	}
+}
+
+func TestDiscoverVaultExplicitOverrides(t *testing.T) {
+	vault := setupVaultForCLI(t, "vault_build_full")
+	if err := runBuild([]string{"--vault", vault}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	other := t.TempDir()
+	chdir(t, other)
+	if got, _ := discoverVault(); got != "." {
+		t.Errorf("discoverVault() outside any vault = %q, want .", got)
+	}
+
+	defer func(prev string) { defaultVault = prev }(defaultVault)
+	if err := runUpdate([]string{"--file", "Index.md"}); err == nil || !strings.Contains(err.Error(), "index not found") {
+		t.Errorf("update outside any vault: got %v, want index not found", err)
+	}
+	globals, rest := splitGlobalArgs([]string{"--vault", vault, "update", "--file", "Index.md"})
+	_, v, set, err := parseVault(globals)
+	if err != nil || !set {
+		t.Fatalf("parseVault: %v %v", set, err)
+	}
+	defaultVault = v
+	if err := runUpdate(rest[1:]); err != nil {
+		t.Errorf("update with --vault: %v", err)
 	}
 }
@@ -10,7 +10,7 @@ import (
 
 func runConvert(args []string) error {
 	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	format := fs.String("format", "text", "output format (json or text)")
 	toFormat := fs.String("to", "", "target format: wikilink or markdown (required)")
 	dryRun := fs.Bool("dry-run", false, "show what would be converted without making changes")
@@ -19,6 +19,7 @@ func runConvert(args []string) error {
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	files = vaultPathArgs(fs, files)
 	if err := validateFormat(*format); err != nil {
 		return err
 	}
@@ -37,14 +38,14 @@ func runConvert(args []string) error {
 
 	switch *format {
 	case "json":
-		if err := printConvertJSON(os.Stdout, result); err != nil {
+		if err := printConvertJSON(summaryOut(os.Stdout), result); err != nil {
 			return err
 		}
 	default:
-		printRewrittenText(os.Stdout, result.Rewritten)
+		printRewrittenText(summaryOut(os.Stdout), result.Rewritten)
 	}
 	if !*dryRun && len(result.Rewritten) > 0 {
-		fmt.Fprintln(os.Stderr, "hint: run 'mdhop build' to create or update the index")
+		fmt.Fprintln(summaryOut(os.Stderr), "hint: run 'mdhop build' to create or update the index")
 	}
 	return nil
 }
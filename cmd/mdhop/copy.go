@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+func runCopy(args []string) error {
+	fs := flag.NewFlagSet("copy", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	from := fs.String("from", "", "source note path (vault-relative)")
+	to := fs.String("to", "", "destination note path (vault-relative)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	if *from == "" {
+		return fmt.Errorf("--from is required")
+	}
+	if *to == "" {
+		return fmt.Errorf("--to is required")
+	}
+	*from = vaultPathArg(fs, *from)
+	*to = vaultPathArg(fs, *to)
+
+	result, err := core.Copy(*vault, core.CopyOptions{
+		From: *from,
+		To:   *to,
+	})
+	if err != nil {
+		return err
+	}
+	normFrom := core.NormalizePath(*from)
+	normTo := core.NormalizePath(*to)
+	switch *format {
+	case "json":
+		return printCopyJSON(summaryOut(os.Stdout), normFrom, normTo, result)
+	default:
+		printCopyText(summaryOut(os.Stdout), normFrom, normTo, result)
+		return nil
+	}
+}
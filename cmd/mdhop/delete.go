@@ -22,7 +22,7 @@ func isDirArg(vaultPath, arg string) bool {
 
 func runDelete(args []string) error {
 	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	format := fs.String("format", "text", "output format (json or text)")
 	rm := fs.Bool("rm", false, "remove files from disk before updating index")
 	var files multiString
@@ -30,6 +30,7 @@ func runDelete(args []string) error {
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	files = vaultPathArgs(fs, files)
 	if err := validateFormat(*format); err != nil {
 		return err
 	}
@@ -109,9 +110,9 @@ func runDelete(args []string) error {
 
 	switch *format {
 	case "json":
-		return printDeleteJSON(os.Stdout, result)
+		return printDeleteJSON(summaryOut(os.Stdout), result)
 	default:
-		printDeleteText(os.Stdout, result)
+		printDeleteText(summaryOut(os.Stdout), result)
 		return nil
 	}
 }
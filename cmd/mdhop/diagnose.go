@@ -9,9 +9,11 @@ import (
 
 func runDiagnose(args []string) error {
 	fs := flag.NewFlagSet("diagnose", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	format := fs.String("format", "text", "output format (json or text)")
 	fields := fs.String("fields", "", "comma-separated fields to output")
+	suggest := fs.Bool("suggest", false, "suggest the closest existing note for each phantom")
+	similarity := fs.Float64("similarity", 0, "also report notes at least this similar (0-1) as duplicate_notes")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -25,7 +27,11 @@ func runDiagnose(args []string) error {
 		return err
 	}
 
-	result, err := core.Diagnose(*vault, core.DiagnoseOptions{Fields: fieldList})
+	result, err := core.Diagnose(*vault, core.DiagnoseOptions{
+		Fields:     fieldList,
+		Suggest:    *suggest,
+		Similarity: *similarity,
+	})
 	if err != nil {
 		return err
 	}
@@ -10,16 +10,20 @@ import (
 
 func runDisambiguate(args []string) error {
 	fs := flag.NewFlagSet("disambiguate", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	format := fs.String("format", "text", "output format (json or text)")
 	name := fs.String("name", "", "basename to disambiguate")
 	target := fs.String("target", "", "target file path (required if multiple candidates)")
 	scan := fs.Bool("scan", false, "scan all files without DB")
+	dryRun := fs.Bool("dry-run", false, "show what would be rewritten without making changes")
+	preserveMtime := fs.Bool("preserve-mtime", false, "keep the original modification time of files whose links are rewritten")
 	var files multiString
 	fs.Var(&files, "file", "limit rewriting to these source files")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	files = vaultPathArgs(fs, files)
+	*target = vaultPathArg(fs, *target)
 	if err := validateFormat(*format); err != nil {
 		return err
 	}
@@ -30,15 +34,19 @@ func runDisambiguate(args []string) error {
 	var err error
 	if *scan {
 		result, err = core.DisambiguateScan(*vault, core.DisambiguateOptions{
-			Name:   *name,
-			Target: *target,
-			Files:  files,
+			Name:          *name,
+			Target:        *target,
+			Files:         files,
+			DryRun:        *dryRun,
+			PreserveMtime: *preserveMtime,
 		})
 	} else {
 		result, err = core.Disambiguate(*vault, core.DisambiguateOptions{
-			Name:   *name,
-			Target: *target,
-			Files:  files,
+			Name:          *name,
+			Target:        *target,
+			Files:         files,
+			DryRun:        *dryRun,
+			PreserveMtime: *preserveMtime,
 		})
 	}
 	if err != nil {
@@ -46,9 +54,9 @@ func runDisambiguate(args []string) error {
 	}
 	switch *format {
 	case "json":
-		return printDisambiguateJSON(os.Stdout, result)
+		return printDisambiguateJSON(summaryOut(os.Stdout), result)
 	default:
-		printDisambiguateText(os.Stdout, result)
+		printDisambiguateText(summaryOut(os.Stdout), result)
 		return nil
 	}
 }
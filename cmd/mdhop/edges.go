@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+func runEdges(args []string) error {
+	fs := flag.NewFlagSet("edges", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	limit := fs.Int("limit", 0, "max edges to list (0 = all)")
+	offset := fs.Int("offset", 0, "skip first N edges (for paging with --limit)")
+	linkType := fs.String("link-type", "", "comma-separated link types to keep: wikilink, markdown, tag, frontmatter, frontmatter-link")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	if *limit < 0 {
+		return fmt.Errorf("--limit must be >= 0")
+	}
+	if *offset < 0 {
+		return fmt.Errorf("--offset must be >= 0")
+	}
+
+	edges, err := core.ListEdges(*vault, core.ListEdgesOptions{
+		Limit:     *limit,
+		Offset:    *offset,
+		LinkTypes: parseFields(*linkType),
+	})
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		return printEdgesJSON(os.Stdout, edges)
+	default:
+		printEdgesText(os.Stdout, edges)
+		return nil
+	}
+}
@@ -75,6 +75,12 @@ var validResolveFields = map[string]bool{
 
 // --- Resolve output ---
 
+type resolveAbsJSON struct {
+	Type   string `json:"type"`
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
 func printResolveJSON(w io.Writer, r *core.ResolveResult, fields []string) error {
 	return encodeJSON(w, buildResolveMap(r, fields))
 }
@@ -97,6 +103,14 @@ func printResolveText(w io.Writer, r *core.ResolveResult, fields []string) error
 	if show["subpath"] && r.Subpath != "" {
 		fmt.Fprintf(w, "subpath: %s\n", r.Subpath)
 	}
+	if a := r.Anchor; a != nil {
+		fmt.Fprintf(w, "anchor: %s\n", a.Heading)
+		if a.Line > 0 {
+			fmt.Fprintf(w, "anchor_line: %d\n", a.Line)
+		} else {
+			fmt.Fprintln(w, "anchor_missing: true")
+		}
+	}
 	return nil
 }
 
@@ -118,23 +132,33 @@ func buildResolveMap(r *core.ResolveResult, fields []string) map[string]any {
 	if show["subpath"] && r.Subpath != "" {
 		m["subpath"] = r.Subpath
 	}
+	if a := r.Anchor; a != nil {
+		m["anchor"] = a.Heading
+		if a.Line > 0 {
+			m["anchor_line"] = a.Line
+		} else {
+			m["anchor_missing"] = true
+		}
+	}
 	return m
 }
 
 // --- Stats output ---
 
 var validStatsFieldsCLI = map[string]bool{
-	"notes_total":    true,
-	"notes_exists":   true,
-	"edges_total":    true,
-	"tags_total":     true,
-	"phantoms_total": true,
-	"assets_total":   true,
+	"notes_total":          true,
+	"notes_exists":         true,
+	"edges_total":          true,
+	"tags_total":           true,
+	"phantoms_total":       true,
+	"assets_total":         true,
+	"external_links_total": true,
+	"external_urls_total":  true,
 }
 
 func printStatsJSON(w io.Writer, r *core.StatsResult, fields []string) error {
 	show := fieldSet(fields, validStatsFieldsCLI)
-	m := make(map[string]int)
+	m := make(map[string]any)
 	if show["notes_total"] {
 		m["notes_total"] = r.NotesTotal
 	}
@@ -153,9 +177,74 @@ func printStatsJSON(w io.Writer, r *core.StatsResult, fields []string) error {
 	if show["assets_total"] {
 		m["assets_total"] = r.AssetsTotal
 	}
+	if show["external_links_total"] {
+		m["external_links_total"] = r.ExternalLinksTotal
+	}
+	if show["external_urls_total"] {
+		m["external_urls_total"] = r.ExternalURLsTotal
+	}
+	if r.Dirs != nil {
+		dirs := make([]statsDirJSON, len(r.Dirs))
+		for i, d := range r.Dirs {
+			dirs[i] = statsDirJSON{Dir: d.Dir, Notes: d.Notes, Edges: d.Edges, Orphans: d.Orphans}
+		}
+		m["by_dir"] = dirs
+	}
+	if r.OutgoingHistogram != nil {
+		m["outgoing_histogram"] = newStatsHistogramJSON(r.OutgoingHistogram)
+	}
+	if r.IncomingHistogram != nil {
+		m["incoming_histogram"] = newStatsHistogramJSON(r.IncomingHistogram)
+	}
+	if r.TopReferenced != nil {
+		m["top_referenced"] = newStatsTopJSON(r.TopReferenced)
+	}
+	if r.TopLinking != nil {
+		m["top_linking"] = newStatsTopJSON(r.TopLinking)
+	}
 	return encodeJSON(w, m)
 }
 
+type statsTopJSON struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+func newStatsTopJSON(notes []core.NoteCount) []statsTopJSON {
+	out := make([]statsTopJSON, len(notes))
+	for i, n := range notes {
+		out[i] = statsTopJSON{Path: n.Path, Count: n.Count}
+	}
+	return out
+}
+
+type statsHistogramJSON struct {
+	Min     int                        `json:"min"`
+	Max     int                        `json:"max"`
+	Median  float64                    `json:"median"`
+	Buckets []statsHistogramBucketJSON `json:"buckets"`
+}
+
+type statsHistogramBucketJSON struct {
+	Links int `json:"links"`
+	Notes int `json:"notes"`
+}
+
+func newStatsHistogramJSON(h *core.LinkHistogram) statsHistogramJSON {
+	out := statsHistogramJSON{Min: h.Min, Max: h.Max, Median: h.Median, Buckets: []statsHistogramBucketJSON{}}
+	for _, b := range h.Buckets {
+		out.Buckets = append(out.Buckets, statsHistogramBucketJSON{Links: b.Links, Notes: b.Notes})
+	}
+	return out
+}
+
+type statsDirJSON struct {
+	Dir     string `json:"dir"`
+	Notes   int    `json:"notes"`
+	Edges   int    `json:"edges"`
+	Orphans int    `json:"orphans"`
+}
+
 func printStatsText(w io.Writer, r *core.StatsResult, fields []string) error {
 	show := fieldSet(fields, validStatsFieldsCLI)
 	if show["notes_total"] {
@@ -176,22 +265,91 @@ func printStatsText(w io.Writer, r *core.StatsResult, fields []string) error {
 	if show["assets_total"] {
 		fmt.Fprintf(w, "assets_total: %d\n", r.AssetsTotal)
 	}
+	if show["external_links_total"] {
+		fmt.Fprintf(w, "external_links_total: %d\n", r.ExternalLinksTotal)
+	}
+	if show["external_urls_total"] {
+		fmt.Fprintf(w, "external_urls_total: %d\n", r.ExternalURLsTotal)
+	}
+	if len(r.Dirs) > 0 {
+		fmt.Fprintln(w, "by_dir:")
+		for _, d := range r.Dirs {
+			fmt.Fprintf(w, "- dir: %s\n", d.Dir)
+			fmt.Fprintf(w, "  notes: %d\n", d.Notes)
+			fmt.Fprintf(w, "  edges: %d\n", d.Edges)
+			fmt.Fprintf(w, "  orphans: %d\n", d.Orphans)
+		}
+	}
+	printStatsHistogramText(w, "outgoing_histogram", r.OutgoingHistogram)
+	printStatsHistogramText(w, "incoming_histogram", r.IncomingHistogram)
+	printStatsTopText(w, "top_referenced", r.TopReferenced)
+	printStatsTopText(w, "top_linking", r.TopLinking)
 	return nil
 }
 
+func printStatsTopText(w io.Writer, key string, notes []core.NoteCount) {
+	if len(notes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", key)
+	for _, n := range notes {
+		fmt.Fprintf(w, "- path: %s\n", n.Path)
+		fmt.Fprintf(w, "  count: %d\n", n.Count)
+	}
+}
+
+func printStatsHistogramText(w io.Writer, key string, h *core.LinkHistogram) {
+	if h == nil {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", key)
+	fmt.Fprintf(w, "  min: %d\n", h.Min)
+	fmt.Fprintf(w, "  max: %d\n", h.Max)
+	fmt.Fprintf(w, "  median: %g\n", h.Median)
+	fmt.Fprintln(w, "  buckets:")
+	for _, b := range h.Buckets {
+		fmt.Fprintf(w, "  - links: %d\n", b.Links)
+		fmt.Fprintf(w, "    notes: %d\n", b.Notes)
+	}
+}
+
 // --- Diagnose output ---
 
 var validDiagnoseFieldsCLI = map[string]bool{
 	"basename_conflicts":       true,
 	"asset_basename_conflicts": true,
+	"fragile_root_priority":    true,
+	"embed_cycles":             true,
+	"duplicate_notes":          true,
 	"phantoms":                 true,
 }
 
+type diagnoseJSONEmbedCycle struct {
+	Paths []string `json:"paths"`
+}
+
+type diagnoseJSONDuplicate struct {
+	Paths []string `json:"paths"`
+}
+
+type diagnoseJSONRootPriority struct {
+	Name     string   `json:"name"`
+	RootPath string   `json:"root_path"`
+	Paths    []string `json:"paths"`
+	Sources  []string `json:"sources"`
+}
+
 type diagnoseJSONConflict struct {
 	Name  string   `json:"name"`
 	Paths []string `json:"paths"`
 }
 
+type diagnoseJSONSuggestion struct {
+	Phantom  string `json:"phantom"`
+	Suggest  string `json:"suggest"`
+	Distance int    `json:"distance"`
+}
+
 func printDiagnoseJSON(w io.Writer, r *core.DiagnoseResult, fields []string) error {
 	show := fieldSet(fields, validDiagnoseFieldsCLI)
 	m := make(map[string]any)
@@ -209,6 +367,27 @@ func printDiagnoseJSON(w io.Writer, r *core.DiagnoseResult, fields []string) err
 		}
 		m["asset_basename_conflicts"] = conflicts
 	}
+	if show["fragile_root_priority"] {
+		fragile := make([]diagnoseJSONRootPriority, len(r.FragileRootPriority))
+		for i, c := range r.FragileRootPriority {
+			fragile[i] = diagnoseJSONRootPriority{Name: c.Name, RootPath: c.RootPath, Paths: c.Paths, Sources: c.Sources}
+		}
+		m["fragile_root_priority"] = fragile
+	}
+	if show["embed_cycles"] {
+		cycles := make([]diagnoseJSONEmbedCycle, len(r.EmbedCycles))
+		for i, c := range r.EmbedCycles {
+			cycles[i] = diagnoseJSONEmbedCycle{Paths: c.Paths}
+		}
+		m["embed_cycles"] = cycles
+	}
+	if show["duplicate_notes"] {
+		dups := make([]diagnoseJSONDuplicate, len(r.DuplicateNotes))
+		for i, d := range r.DuplicateNotes {
+			dups[i] = diagnoseJSONDuplicate{Paths: d.Paths}
+		}
+		m["duplicate_notes"] = dups
+	}
 	if show["phantoms"] {
 		if r.Phantoms != nil {
 			m["phantoms"] = r.Phantoms
@@ -216,6 +395,13 @@ func printDiagnoseJSON(w io.Writer, r *core.DiagnoseResult, fields []string) err
 			m["phantoms"] = []string{}
 		}
 	}
+	if r.Suggestions != nil {
+		sugg := make([]diagnoseJSONSuggestion, len(r.Suggestions))
+		for i, sg := range r.Suggestions {
+			sugg[i] = diagnoseJSONSuggestion{Phantom: sg.Phantom, Suggest: sg.Path, Distance: sg.Distance}
+		}
+		m["suggestions"] = sugg
+	}
 	return encodeJSON(w, m)
 }
 
@@ -241,42 +427,194 @@ func printDiagnoseText(w io.Writer, r *core.DiagnoseResult, fields []string) err
 			}
 		}
 	}
+	if show["fragile_root_priority"] && len(r.FragileRootPriority) > 0 {
+		fmt.Fprintln(w, "fragile_root_priority:")
+		for _, c := range r.FragileRootPriority {
+			fmt.Fprintf(w, "- name: %s\n", c.Name)
+			fmt.Fprintf(w, "  root_path: %s\n", c.RootPath)
+			fmt.Fprintln(w, "  paths:")
+			for _, p := range c.Paths {
+				fmt.Fprintf(w, "  - %s\n", p)
+			}
+			if len(c.Sources) > 0 {
+				fmt.Fprintln(w, "  sources:")
+				for _, s := range c.Sources {
+					fmt.Fprintf(w, "  - %s\n", s)
+				}
+			}
+		}
+	}
+	if show["embed_cycles"] && len(r.EmbedCycles) > 0 {
+		fmt.Fprintln(w, "embed_cycles:")
+		for _, c := range r.EmbedCycles {
+			fmt.Fprintln(w, "- paths:")
+			for _, p := range c.Paths {
+				fmt.Fprintf(w, "  - %s\n", p)
+			}
+		}
+	}
+	if show["duplicate_notes"] && len(r.DuplicateNotes) > 0 {
+		fmt.Fprintln(w, "duplicate_notes:")
+		for _, d := range r.DuplicateNotes {
+			fmt.Fprintln(w, "- paths:")
+			for _, p := range d.Paths {
+				fmt.Fprintf(w, "  - %s\n", p)
+			}
+		}
+	}
 	if show["phantoms"] && len(r.Phantoms) > 0 {
 		fmt.Fprintln(w, "phantoms:")
 		for _, name := range r.Phantoms {
 			fmt.Fprintf(w, "- %s\n", name)
 		}
 	}
+	if len(r.Suggestions) > 0 {
+		fmt.Fprintln(w, "suggestions:")
+		for _, sg := range r.Suggestions {
+			fmt.Fprintf(w, "- phantom: %s\n", sg.Phantom)
+			fmt.Fprintf(w, "  suggest: %s\n", sg.Path)
+			fmt.Fprintf(w, "  distance: %d\n", sg.Distance)
+		}
+	}
+	return nil
+}
+
+// --- Verify output ---
+
+type verifyJSONIssue struct {
+	Kind   string `json:"kind"`
+	Path   string `json:"path"`
+	Detail string `json:"detail"`
+}
+
+// --- Search output ---
+
+type searchHitJSON struct {
+	Path    string `json:"path"`
+	Snippet string `json:"snippet"`
+}
+
+func printSearchJSON(w io.Writer, r *core.SearchResult) error {
+	hits := make([]searchHitJSON, len(r.Hits))
+	for i, h := range r.Hits {
+		hits[i] = searchHitJSON{Path: h.Path, Snippet: h.Snippet}
+	}
+	return encodeJSON(w, map[string]any{
+		"mode": r.Mode,
+		"hits": hits,
+	})
+}
+
+func printSearchText(w io.Writer, r *core.SearchResult) {
+	fmt.Fprintf(w, "mode: %s\n", r.Mode)
+	if len(r.Hits) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "hits:")
+	for _, h := range r.Hits {
+		fmt.Fprintf(w, "- path: %s\n", h.Path)
+		fmt.Fprintf(w, "  snippet: %s\n", h.Snippet)
+	}
+}
+
+func printVerifyJSON(w io.Writer, r *core.VerifyResult) error {
+	issues := make([]verifyJSONIssue, len(r.Issues))
+	for i, is := range r.Issues {
+		issues[i] = verifyJSONIssue{Kind: is.Kind, Path: is.Path, Detail: is.Detail}
+	}
+	return encodeJSON(w, map[string]any{
+		"ok":     r.OK(),
+		"issues": issues,
+	})
+}
+
+func printVerifyText(w io.Writer, r *core.VerifyResult) error {
+	if r.OK() {
+		fmt.Fprintln(w, "ok: true")
+		return nil
+	}
+	fmt.Fprintln(w, "ok: false")
+	fmt.Fprintln(w, "issues:")
+	for _, is := range r.Issues {
+		fmt.Fprintf(w, "- kind: %s\n", is.Kind)
+		fmt.Fprintf(w, "  path: %s\n", is.Path)
+		fmt.Fprintf(w, "  detail: %s\n", is.Detail)
+	}
+	return nil
+}
+
+func printVerifyFastJSON(w io.Writer, r *core.FastVerifyResult) error {
+	return encodeJSON(w, map[string]any{
+		"up_to_date": r.UpToDate(),
+		"recorded":   r.Recorded,
+		"index": map[string]int64{
+			"notes": r.IndexNotes, "max_mtime": r.IndexMaxMtime, "digest": r.IndexDigest,
+			"assets": r.IndexAssets, "asset_max_mtime": r.IndexAssetMaxMtime, "asset_digest": r.IndexAssetDigest,
+		},
+		"disk": map[string]int64{
+			"notes": r.DiskNotes, "max_mtime": r.DiskMaxMtime, "digest": r.DiskDigest,
+			"assets": r.DiskAssets, "asset_max_mtime": r.DiskAssetMaxMtime, "asset_digest": r.DiskAssetDigest,
+		},
+	})
+}
+
+func printVerifyFastText(w io.Writer, r *core.FastVerifyResult) error {
+	if r.UpToDate() {
+		fmt.Fprintln(w, "up to date")
+		return nil
+	}
+	fmt.Fprintln(w, "stale")
+	if !r.Recorded {
+		fmt.Fprintln(w, "index: no aggregate recorded")
+	} else {
+		fmt.Fprintf(w, "index: %d notes, max mtime %d; %d assets, max mtime %d\n",
+			r.IndexNotes, r.IndexMaxMtime, r.IndexAssets, r.IndexAssetMaxMtime)
+	}
+	fmt.Fprintf(w, "disk: %d notes, max mtime %d; %d assets, max mtime %d\n",
+		r.DiskNotes, r.DiskMaxMtime, r.DiskAssets, r.DiskAssetMaxMtime)
 	return nil
 }
 
 var validQueryFieldsCLI = map[string]bool{
-	"backlinks": true,
-	"tags":      true,
-	"twohop":    true,
-	"outgoing":  true,
-	"head":      true,
-	"snippet":   true,
+	"backlinks":     true,
+	"tags":          true,
+	"twohop":        true,
+	"twohop-ranked": true,
+	"outgoing":      true,
+	"headings":      true,
+	"title":         true,
+	"head":          true,
+	"snippet":       true,
 }
 
 // --- Query output ---
 
 // queryJSONOutput is the JSON-serializable form of QueryResult.
 type queryJSONOutput struct {
-	Entry     *jsonNodeInfo    `json:"entry"`
-	Backlinks []jsonNodeInfo   `json:"backlinks,omitempty"`
-	Outgoing  []jsonNodeInfo   `json:"outgoing,omitempty"`
-	Tags      []string         `json:"tags,omitempty"`
-	TwoHop    []jsonTwoHop     `json:"twohop,omitempty"`
-	Head      []string         `json:"head,omitempty"`
-	Snippets  []jsonSnippet    `json:"snippet,omitempty"`
+	Entry             *jsonNodeInfo      `json:"entry,omitempty"`
+	Candidates        []jsonNodeInfo     `json:"candidates,omitempty"`
+	ViaAlias          string             `json:"via_alias,omitempty"`
+	Title             string             `json:"title,omitempty"`
+	Backlinks         []jsonNodeInfo     `json:"backlinks,omitempty"`
+	TotalBacklinks    int                `json:"total_backlinks,omitempty"`
+	Outgoing          []jsonNodeInfo     `json:"outgoing,omitempty"`
+	BacklinkPositions []jsonLinkPosition `json:"backlink_positions,omitempty"`
+	OutgoingPositions []jsonLinkPosition `json:"outgoing_positions,omitempty"`
+	Tags              []string           `json:"tags,omitempty"`
+	TagLocations      []jsonTagLocation  `json:"tag_locations,omitempty"`
+	TwoHop            []jsonTwoHop       `json:"twohop,omitempty"`
+	TwoHopRanked      []jsonTwoHopTarget `json:"twohop_ranked,omitempty"`
+	Headings          []jsonHeading      `json:"headings,omitempty"`
+	Head              []string           `json:"head,omitempty"`
+	Snippets          []jsonSnippet      `json:"snippet,omitempty"`
 }
 
 type jsonNodeInfo struct {
-	Type   string `json:"type"`
-	Name   string `json:"name"`
-	Path   string `json:"path,omitempty"`
-	Exists *bool  `json:"exists,omitempty"`
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Path    string   `json:"path,omitempty"`
+	Exists  *bool    `json:"exists,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
 }
 
 type jsonTwoHop struct {
@@ -284,6 +622,42 @@ type jsonTwoHop struct {
 	Targets []jsonNodeInfo `json:"targets"`
 }
 
+type jsonLinkPosition struct {
+	Node    jsonNodeInfo `json:"node"`
+	Lines   string       `json:"lines"`
+	RawLink string       `json:"raw_link"`
+}
+
+func toJSONLinkPositions(ps []core.LinkPosition) []jsonLinkPosition {
+	out := make([]jsonLinkPosition, len(ps))
+	for i, p := range ps {
+		out[i] = jsonLinkPosition{
+			Node:    toJSONNodeInfo(p.Node),
+			Lines:   fmt.Sprintf("%d-%d", p.LineStart, p.LineEnd),
+			RawLink: p.RawLink,
+		}
+	}
+	return out
+}
+
+type jsonTagLocation struct {
+	Tag    string `json:"tag"`
+	Line   int    `json:"line"`
+	Source string `json:"source"`
+}
+
+type jsonTwoHopTarget struct {
+	Target jsonNodeInfo   `json:"target"`
+	Weight int            `json:"weight"`
+	Vias   []jsonNodeInfo `json:"vias"`
+}
+
+type jsonHeading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	Line  int    `json:"line"`
+}
+
 type jsonSnippet struct {
 	Source  string   `json:"source"`
 	Lines   string   `json:"lines"`
@@ -296,12 +670,23 @@ func toJSONNodeInfo(n core.NodeInfo) jsonNodeInfo {
 		ji.Path = n.Path
 		ji.Exists = &n.Exists
 	}
+	ji.Aliases = n.Aliases
 	return ji
 }
 
 func printQueryJSON(w io.Writer, r *core.QueryResult) error {
+	if r.Candidates != nil {
+		out := queryJSONOutput{Candidates: make([]jsonNodeInfo, len(r.Candidates))}
+		for i, n := range r.Candidates {
+			out.Candidates[i] = toJSONNodeInfo(n)
+		}
+		return encodeJSON(w, out)
+	}
 	out := queryJSONOutput{
-		Entry: func() *jsonNodeInfo { v := toJSONNodeInfo(r.Entry); return &v }(),
+		Entry:          func() *jsonNodeInfo { v := toJSONNodeInfo(r.Entry); return &v }(),
+		ViaAlias:       r.ViaAlias,
+		Title:          r.Title,
+		TotalBacklinks: r.TotalBacklinks,
 	}
 	if r.Backlinks != nil {
 		out.Backlinks = make([]jsonNodeInfo, len(r.Backlinks))
@@ -315,9 +700,21 @@ func printQueryJSON(w io.Writer, r *core.QueryResult) error {
 			out.Outgoing[i] = toJSONNodeInfo(n)
 		}
 	}
+	if r.BacklinkPositions != nil {
+		out.BacklinkPositions = toJSONLinkPositions(r.BacklinkPositions)
+	}
+	if r.OutgoingPositions != nil {
+		out.OutgoingPositions = toJSONLinkPositions(r.OutgoingPositions)
+	}
 	if r.Tags != nil {
 		out.Tags = r.Tags
 	}
+	if r.TagLocations != nil {
+		out.TagLocations = make([]jsonTagLocation, len(r.TagLocations))
+		for i, l := range r.TagLocations {
+			out.TagLocations[i] = jsonTagLocation{Tag: l.Tag, Line: l.Line, Source: l.Source}
+		}
+	}
 	if r.TwoHop != nil {
 		out.TwoHop = make([]jsonTwoHop, len(r.TwoHop))
 		for i, th := range r.TwoHop {
@@ -331,6 +728,26 @@ func printQueryJSON(w io.Writer, r *core.QueryResult) error {
 			}
 		}
 	}
+	if r.TwoHopRanked != nil {
+		out.TwoHopRanked = make([]jsonTwoHopTarget, len(r.TwoHopRanked))
+		for i, rt := range r.TwoHopRanked {
+			vias := make([]jsonNodeInfo, len(rt.Vias))
+			for j, v := range rt.Vias {
+				vias[j] = toJSONNodeInfo(v)
+			}
+			out.TwoHopRanked[i] = jsonTwoHopTarget{
+				Target: toJSONNodeInfo(rt.Target),
+				Weight: rt.Weight,
+				Vias:   vias,
+			}
+		}
+	}
+	if r.Headings != nil {
+		out.Headings = make([]jsonHeading, len(r.Headings))
+		for i, h := range r.Headings {
+			out.Headings[i] = jsonHeading{Level: h.Level, Text: h.Text, Line: h.Line}
+		}
+	}
 	if r.Head != nil {
 		out.Head = r.Head
 	}
@@ -349,9 +766,20 @@ func printQueryJSON(w io.Writer, r *core.QueryResult) error {
 }
 
 func printQueryText(w io.Writer, r *core.QueryResult) error {
-	// entry (always present)
-	fmt.Fprintln(w, "entry:")
-	writeNodeInfoText(w, r.Entry, "  ", "  ")
+	// An ambiguous --name with --allow-ambiguous has candidates but no entry.
+	if r.Candidates != nil {
+		fmt.Fprintln(w, "candidates:")
+		for _, n := range r.Candidates {
+			writeNodeInfoText(w, n, "- ", "  ")
+		}
+		return nil
+	}
+
+	// entry (always present otherwise)
+	writeQueryEntryText(w, r)
+	if r.Title != "" {
+		fmt.Fprintf(w, "title: %q\n", r.Title)
+	}
 
 	if r.Backlinks != nil {
 		fmt.Fprintln(w, "backlinks:")
@@ -359,6 +787,9 @@ func printQueryText(w io.Writer, r *core.QueryResult) error {
 			writeNodeInfoText(w, n, "- ", "  ")
 		}
 	}
+	if r.TotalBacklinks > 0 {
+		fmt.Fprintf(w, "total_backlinks: %d\n", r.TotalBacklinks)
+	}
 
 	if r.Outgoing != nil {
 		fmt.Fprintln(w, "outgoing:")
@@ -367,12 +798,23 @@ func printQueryText(w io.Writer, r *core.QueryResult) error {
 		}
 	}
 
+	writeLinkPositionsText(w, "backlink_positions", r.BacklinkPositions)
+	writeLinkPositionsText(w, "outgoing_positions", r.OutgoingPositions)
+
 	if r.Tags != nil {
 		fmt.Fprintln(w, "tags:")
 		for _, t := range r.Tags {
 			fmt.Fprintf(w, "- %s\n", t)
 		}
 	}
+	if r.TagLocations != nil {
+		fmt.Fprintln(w, "tag_locations:")
+		for _, l := range r.TagLocations {
+			fmt.Fprintf(w, "- tag: %s\n", l.Tag)
+			fmt.Fprintf(w, "  line: %d\n", l.Line)
+			fmt.Fprintf(w, "  source: %s\n", l.Source)
+		}
+	}
 
 	if r.TwoHop != nil {
 		fmt.Fprintln(w, "twohop:")
@@ -385,6 +827,27 @@ func printQueryText(w io.Writer, r *core.QueryResult) error {
 		}
 	}
 
+	if r.TwoHopRanked != nil {
+		fmt.Fprintln(w, "twohop_ranked:")
+		for _, rt := range r.TwoHopRanked {
+			fmt.Fprintf(w, "- target: %s\n", nodeInfoOneLine(rt.Target))
+			fmt.Fprintf(w, "  weight: %d\n", rt.Weight)
+			fmt.Fprintln(w, "  vias:")
+			for _, v := range rt.Vias {
+				fmt.Fprintf(w, "  - %s\n", nodeInfoOneLine(v))
+			}
+		}
+	}
+
+	if r.Headings != nil {
+		fmt.Fprintln(w, "headings:")
+		for _, h := range r.Headings {
+			fmt.Fprintf(w, "- level: %d\n", h.Level)
+			fmt.Fprintf(w, "  text: %q\n", h.Text)
+			fmt.Fprintf(w, "  line: %d\n", h.Line)
+		}
+	}
+
 	if r.Head != nil {
 		fmt.Fprintln(w, "head:")
 		for _, line := range r.Head {
@@ -407,8 +870,289 @@ func printQueryText(w io.Writer, r *core.QueryResult) error {
 	return nil
 }
 
+func writeLinkPositionsText(w io.Writer, key string, ps []core.LinkPosition) {
+	if ps == nil {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", key)
+	for _, p := range ps {
+		fmt.Fprintf(w, "- node: %s\n", nodeInfoOneLine(p.Node))
+		fmt.Fprintf(w, "  lines: %d-%d\n", p.LineStart, p.LineEnd)
+		fmt.Fprintf(w, "  raw_link: %q\n", p.RawLink)
+	}
+}
+
+// --- Tag intersection output ---
+
+type tagQueryJSONOutput struct {
+	Tags  []string       `json:"tags"`
+	Notes []jsonNodeInfo `json:"notes"`
+}
+
+func printTagQueryJSON(w io.Writer, r *core.TagQueryResult) error {
+	out := tagQueryJSONOutput{
+		Tags:  r.Tags,
+		Notes: make([]jsonNodeInfo, len(r.Notes)),
+	}
+	for i, n := range r.Notes {
+		out.Notes[i] = toJSONNodeInfo(n)
+	}
+	return encodeJSON(w, out)
+}
+
+func printTagQueryText(w io.Writer, r *core.TagQueryResult) error {
+	printStringListText(w, "tags", r.Tags)
+	if len(r.Notes) > 0 {
+		fmt.Fprintln(w, "notes:")
+		for _, n := range r.Notes {
+			writeNodeInfoText(w, n, "- ", "  ")
+		}
+	}
+	return nil
+}
+
+// --- Tag tree output ---
+
+type tagTreeJSON struct {
+	Tag      string         `json:"tag"`
+	Notes    []jsonNodeInfo `json:"notes"`
+	Children []tagTreeJSON  `json:"children,omitempty"`
+}
+
+func toTagTreeJSON(n core.TagTreeNode) tagTreeJSON {
+	out := tagTreeJSON{Tag: n.Tag, Notes: make([]jsonNodeInfo, len(n.Notes))}
+	for i, note := range n.Notes {
+		out.Notes[i] = toJSONNodeInfo(note)
+	}
+	for _, c := range n.Children {
+		out.Children = append(out.Children, toTagTreeJSON(c))
+	}
+	return out
+}
+
+func printTagTreeJSON(w io.Writer, r *core.TagTreeResult) error {
+	return encodeJSON(w, toTagTreeJSON(r.Root))
+}
+
+func printTagTreeText(w io.Writer, r *core.TagTreeResult) error {
+	writeTagTreeText(w, r.Root, "", "")
+	return nil
+}
+
+// writeTagTreeText writes a tag tree node; children nest one list level deeper.
+func writeTagTreeText(w io.Writer, n core.TagTreeNode, firstIndent, restIndent string) {
+	fmt.Fprintf(w, "%stag: %s\n", firstIndent, n.Tag)
+	if len(n.Notes) > 0 {
+		fmt.Fprintf(w, "%snotes:\n", restIndent)
+		for _, note := range n.Notes {
+			fmt.Fprintf(w, "%s- %s\n", restIndent, note.Path)
+		}
+	}
+	if len(n.Children) > 0 {
+		fmt.Fprintf(w, "%schildren:\n", restIndent)
+		for _, c := range n.Children {
+			writeTagTreeText(w, c, restIndent+"- ", restIndent+"  ")
+		}
+	}
+}
+
+// --- Tag parents output ---
+
+type tagLevelJSON struct {
+	Tag   string         `json:"tag"`
+	Notes []jsonNodeInfo `json:"notes"`
+}
+
+func printTagParentsJSON(w io.Writer, r *core.TagParentsResult) error {
+	ancestors := make([]tagLevelJSON, len(r.Ancestors))
+	for i, a := range r.Ancestors {
+		ancestors[i] = tagLevelJSON{Tag: a.Tag, Notes: make([]jsonNodeInfo, len(a.Notes))}
+		for j, note := range a.Notes {
+			ancestors[i].Notes[j] = toJSONNodeInfo(note)
+		}
+	}
+	return encodeJSON(w, map[string]any{"tag": r.Tag, "ancestors": ancestors})
+}
+
+func printTagParentsText(w io.Writer, r *core.TagParentsResult) error {
+	fmt.Fprintf(w, "tag: %s\n", r.Tag)
+	if len(r.Ancestors) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "ancestors:")
+	for _, a := range r.Ancestors {
+		fmt.Fprintf(w, "- tag: %s\n", a.Tag)
+		if len(a.Notes) > 0 {
+			fmt.Fprintln(w, "  notes:")
+			for _, note := range a.Notes {
+				fmt.Fprintf(w, "  - %s\n", note.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// --- Broken links output ---
+
+type brokenSourceJSON struct {
+	Source string           `json:"source"`
+	Links  []brokenLinkJSON `json:"links"`
+}
+
+type brokenLinkJSON struct {
+	Line     int    `json:"line"`
+	LinkType string `json:"link_type"`
+	Embed    bool   `json:"embed"`
+	RawLink  string `json:"raw_link"`
+	Target   string `json:"target"`
+}
+
+func printBrokenJSON(w io.Writer, sources []core.BrokenSource) error {
+	out := make([]brokenSourceJSON, len(sources))
+	for i, src := range sources {
+		links := make([]brokenLinkJSON, len(src.Links))
+		for j, l := range src.Links {
+			links[j] = brokenLinkJSON{Line: l.Line, LinkType: l.LinkType, Embed: l.Embed, RawLink: l.RawLink, Target: l.Target}
+		}
+		out[i] = brokenSourceJSON{Source: src.Path, Links: links}
+	}
+	return encodeJSON(w, map[string]any{"broken": out})
+}
+
+func printBrokenText(w io.Writer, sources []core.BrokenSource) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "broken:")
+	for _, src := range sources {
+		fmt.Fprintf(w, "- source: %s\n", src.Path)
+		fmt.Fprintln(w, "  links:")
+		for _, l := range src.Links {
+			fmt.Fprintf(w, "  - line: %d\n", l.Line)
+			fmt.Fprintf(w, "    link_type: %s\n", l.LinkType)
+			fmt.Fprintf(w, "    embed: %v\n", l.Embed)
+			fmt.Fprintf(w, "    raw_link: %q\n", l.RawLink)
+			fmt.Fprintf(w, "    target: %s\n", l.Target)
+		}
+	}
+	return nil
+}
+
+// --- Link cycles output ---
+
+type linkCycleJSON struct {
+	Paths []string `json:"paths"`
+}
+
+func printCyclesJSON(w io.Writer, cycles []core.LinkCycle) error {
+	out := make([]linkCycleJSON, len(cycles))
+	for i, c := range cycles {
+		out[i] = linkCycleJSON{Paths: c.Paths}
+	}
+	return encodeJSON(w, map[string]any{"cycles": out})
+}
+
+func printCyclesText(w io.Writer, cycles []core.LinkCycle) error {
+	if len(cycles) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "cycles:")
+	for _, c := range cycles {
+		fmt.Fprintln(w, "- paths:")
+		for _, p := range c.Paths {
+			fmt.Fprintf(w, "  - %s\n", p)
+		}
+	}
+	return nil
+}
+
+// --- Impact output ---
+
+func printImpactJSON(w io.Writer, notes []core.NodeInfo) error {
+	out := make([]jsonNodeInfo, len(notes))
+	for i, n := range notes {
+		out[i] = toJSONNodeInfo(n)
+	}
+	return encodeJSON(w, map[string]any{"orphaned": out})
+}
+
+func printImpactText(w io.Writer, notes []core.NodeInfo) error {
+	if len(notes) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "orphaned:")
+	for _, n := range notes {
+		fmt.Fprintf(w, "- %s\n", nodeInfoOneLine(n))
+	}
+	return nil
+}
+
+// --- External links output ---
+
+type externalLinkJSON struct {
+	Source string `json:"source"`
+	Line   int    `json:"line"`
+	URL    string `json:"url"`
+}
+
+func printExternalJSON(w io.Writer, links []core.ExternalLink) error {
+	out := make([]externalLinkJSON, len(links))
+	for i, l := range links {
+		out[i] = externalLinkJSON{Source: l.Path, Line: l.Line, URL: l.URL}
+	}
+	return encodeJSON(w, map[string]any{"external": out})
+}
+
+func printExternalText(w io.Writer, links []core.ExternalLink) error {
+	if len(links) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "external:")
+	for _, l := range links {
+		fmt.Fprintf(w, "- source: %s\n", l.Path)
+		fmt.Fprintf(w, "  line: %d\n", l.Line)
+		fmt.Fprintf(w, "  url: %s\n", l.URL)
+	}
+	return nil
+}
+
 // writeNodeInfoText writes a NodeInfo in multi-line text format.
 // firstIndent is the indent for the first line (type:), restIndent for subsequent lines.
+// writeQueryEntryText writes the entry header of printQueryText.
+func writeQueryEntryText(w io.Writer, r *core.QueryResult) {
+	fmt.Fprintln(w, "entry:")
+	writeNodeInfoText(w, r.Entry, "  ", "  ")
+	if r.ViaAlias != "" {
+		fmt.Fprintf(w, "via_alias: %s\n", r.ViaAlias)
+	}
+}
+
+// streamQueryText writes the same output as printQueryText for a
+// backlinks-only query, printing each backlink as its row is read.
+func streamQueryText(w io.Writer, vault string, entry core.EntrySpec, opts core.QueryOptions) error {
+	total := 0
+	started := false
+	err := core.QueryBacklinksFunc(vault, entry, opts, func(r *core.QueryResult) error {
+		total = r.TotalBacklinks
+		writeQueryEntryText(w, r)
+		return nil
+	}, func(n core.NodeInfo) error {
+		if !started {
+			fmt.Fprintln(w, "backlinks:")
+			started = true
+		}
+		writeNodeInfoText(w, n, "- ", "  ")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if total > 0 {
+		fmt.Fprintf(w, "total_backlinks: %d\n", total)
+	}
+	return nil
+}
+
 func writeNodeInfoText(w io.Writer, n core.NodeInfo, firstIndent, restIndent string) {
 	fmt.Fprintf(w, "%stype: %s\n", firstIndent, n.Type)
 	fmt.Fprintf(w, "%sname: %s\n", restIndent, n.Name)
@@ -416,6 +1160,12 @@ func writeNodeInfoText(w io.Writer, n core.NodeInfo, firstIndent, restIndent str
 		fmt.Fprintf(w, "%spath: %s\n", restIndent, n.Path)
 		fmt.Fprintf(w, "%sexists: %v\n", restIndent, n.Exists)
 	}
+	if len(n.Aliases) > 0 {
+		fmt.Fprintf(w, "%saliases:\n", restIndent)
+		for _, a := range n.Aliases {
+			fmt.Fprintf(w, "%s- %s\n", restIndent, a)
+		}
+	}
 }
 
 // nodeInfoOneLine returns a compact one-line representation for twohop via/targets.
@@ -501,18 +1251,76 @@ func printDeleteJSON(w io.Writer, r *core.DeleteResult) error {
 	return encodeJSON(w, out)
 }
 
+// --- Assets prune output ---
+
+type prunedAssetJSON struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+}
+
+type pruneAssetsJSONOutput struct {
+	DryRun         bool              `json:"dry_run"`
+	Pruned         []prunedAssetJSON `json:"pruned"`
+	SkippedRecent  []string          `json:"skipped_recent"`
+	SkippedLinked  []string          `json:"skipped_linked"`
+	SkippedOther   []string          `json:"skipped_other"`
+	ReclaimedBytes int64             `json:"reclaimed_bytes"`
+}
+
+func printPruneAssetsText(w io.Writer, r *core.PruneAssetsResult) {
+	fmt.Fprintf(w, "dry_run: %t\n", !r.Applied)
+	if len(r.Pruned) > 0 {
+		fmt.Fprintln(w, "pruned:")
+		for _, a := range r.Pruned {
+			fmt.Fprintf(w, "- path: %s\n", a.Path)
+			fmt.Fprintf(w, "  bytes: %d\n", a.Bytes)
+		}
+	}
+	printStringListText(w, "skipped_recent", r.SkippedRecent)
+	printStringListText(w, "skipped_linked", r.SkippedLinked)
+	printStringListText(w, "skipped_other", r.SkippedOther)
+	fmt.Fprintf(w, "reclaimed_bytes: %d\n", r.ReclaimedBytes)
+}
+
+func printPruneAssetsJSON(w io.Writer, r *core.PruneAssetsResult) error {
+	out := pruneAssetsJSONOutput{
+		DryRun:         !r.Applied,
+		Pruned:         make([]prunedAssetJSON, len(r.Pruned)),
+		SkippedRecent:  r.SkippedRecent,
+		SkippedLinked:  r.SkippedLinked,
+		SkippedOther:   r.SkippedOther,
+		ReclaimedBytes: r.ReclaimedBytes,
+	}
+	for i, a := range r.Pruned {
+		out.Pruned[i] = prunedAssetJSON{Path: a.Path, Bytes: a.Bytes}
+	}
+	if out.SkippedRecent == nil {
+		out.SkippedRecent = []string{}
+	}
+	if out.SkippedLinked == nil {
+		out.SkippedLinked = []string{}
+	}
+	if out.SkippedOther == nil {
+		out.SkippedOther = []string{}
+	}
+	return encodeJSON(w, out)
+}
+
 // --- Update output ---
 
 type updateJSONOutput struct {
-	Updated   []string `json:"updated"`
-	Deleted   []string `json:"deleted"`
-	Phantomed []string `json:"phantomed"`
+	Updated   []string        `json:"updated"`
+	Deleted   []string        `json:"deleted"`
+	Phantomed []string        `json:"phantomed"`
+	Moved     []movedFileJSON `json:"moved,omitempty"`
+	Rewritten []rewrittenJSON `json:"rewritten,omitempty"`
 }
 
 func printUpdateText(w io.Writer, r *core.UpdateResult) {
 	printStringListText(w, "updated", r.Updated)
 	printStringListText(w, "deleted", r.Deleted)
 	printStringListText(w, "phantomed", r.Phantomed)
+	printMoveDirText(w, &core.MoveDirResult{Moved: r.Moved, Rewritten: r.Rewritten})
 }
 
 func printUpdateJSON(w io.Writer, r *core.UpdateResult) error {
@@ -520,6 +1328,10 @@ func printUpdateJSON(w io.Writer, r *core.UpdateResult) error {
 		Updated:   r.Updated,
 		Deleted:   r.Deleted,
 		Phantomed: r.Phantomed,
+		Rewritten: toRewrittenJSON(r.Rewritten),
+	}
+	for _, m := range r.Moved {
+		out.Moved = append(out.Moved, movedFileJSON{From: m.From, To: m.To})
 	}
 	if out.Updated == nil {
 		out.Updated = []string{}
@@ -601,6 +1413,7 @@ type movedFileJSON struct {
 type moveDirJSONOutput struct {
 	Moved     []movedFileJSON `json:"moved"`
 	Rewritten []rewrittenJSON `json:"rewritten"`
+	Renamed   []movedFileJSON `json:"renamed,omitempty"`
 }
 
 func printMoveDirText(w io.Writer, r *core.MoveDirResult) {
@@ -611,6 +1424,13 @@ func printMoveDirText(w io.Writer, r *core.MoveDirResult) {
 			fmt.Fprintf(w, "  to: %s\n", m.To)
 		}
 	}
+	if len(r.Renamed) > 0 {
+		fmt.Fprintln(w, "renamed:")
+		for _, m := range r.Renamed {
+			fmt.Fprintf(w, "- from: %s\n", m.From)
+			fmt.Fprintf(w, "  to: %s\n", m.To)
+		}
+	}
 	printRewrittenText(w, r.Rewritten)
 }
 
@@ -623,6 +1443,9 @@ func printMoveDirJSON(w io.Writer, r *core.MoveDirResult) error {
 		Moved:     moved,
 		Rewritten: toRewrittenJSON(r.Rewritten),
 	}
+	for _, m := range r.Renamed {
+		out.Renamed = append(out.Renamed, movedFileJSON{From: m.From, To: m.To})
+	}
 	if out.Moved == nil {
 		out.Moved = []movedFileJSON{}
 	}
@@ -632,6 +1455,35 @@ func printMoveDirJSON(w io.Writer, r *core.MoveDirResult) error {
 	return encodeJSON(w, out)
 }
 
+// --- Move porcelain output ---
+//
+// One tab-separated record per line, no headers. The format is append-only:
+// existing record shapes never change.
+//   moved<TAB>from<TAB>to
+//   file<TAB>oldlink<TAB>newlink
+//   renamed<TAB>from<TAB>to   (directory moves with --rename-on-conflict)
+
+func printMovePorcelain(w io.Writer, from, to string, r *core.MoveResult) {
+	fmt.Fprintf(w, "moved\t%s\t%s\n", from, to)
+	printRewrittenPorcelain(w, r.Rewritten)
+}
+
+func printMoveDirPorcelain(w io.Writer, r *core.MoveDirResult) {
+	for _, m := range r.Moved {
+		fmt.Fprintf(w, "moved\t%s\t%s\n", m.From, m.To)
+	}
+	for _, m := range r.Renamed {
+		fmt.Fprintf(w, "renamed\t%s\t%s\n", m.From, m.To)
+	}
+	printRewrittenPorcelain(w, r.Rewritten)
+}
+
+func printRewrittenPorcelain(w io.Writer, links []core.RewrittenLink) {
+	for _, rl := range links {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", rl.File, rl.OldLink, rl.NewLink)
+	}
+}
+
 // --- Disambiguate output ---
 
 type disambiguateJSONOutput struct {
@@ -766,3 +1618,349 @@ func printSimplifyJSON(w io.Writer, r *core.SimplifyResult) error {
 	}
 	return encodeJSON(w, out)
 }
+
+// --- Normalize output ---
+
+type normalizeJSONOutput struct {
+	Rewritten []rewrittenJSON `json:"rewritten"`
+}
+
+func printNormalizeText(w io.Writer, r *core.NormalizeResult) {
+	printRewrittenText(w, r.Rewritten)
+}
+
+func printNormalizeJSON(w io.Writer, r *core.NormalizeResult) error {
+	out := normalizeJSONOutput{
+		Rewritten: toRewrittenJSON(r.Rewritten),
+	}
+	if out.Rewritten == nil {
+		out.Rewritten = []rewrittenJSON{}
+	}
+	return encodeJSON(w, out)
+}
+
+// --- Build output ---
+
+type buildIssueJSON struct {
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	Kind       string   `json:"kind"`
+	Message    string   `json:"message"`
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+type buildErrorsJSONOutput struct {
+	Errors    []buildIssueJSON `json:"errors"`
+	Truncated bool             `json:"truncated"`
+}
+
+func printBuildErrorsJSON(w io.Writer, e *core.BuildError) error {
+	out := buildErrorsJSONOutput{
+		Errors:    make([]buildIssueJSON, len(e.Issues)),
+		Truncated: e.Truncated,
+	}
+	for i, is := range e.Issues {
+		out.Errors[i] = buildIssueJSON{
+			File:       is.File,
+			Line:       is.Line,
+			Kind:       is.Kind,
+			Message:    is.Message,
+			Candidates: is.Candidates,
+		}
+	}
+	return encodeJSON(w, out)
+}
+
+// --- Copy output ---
+
+type copyJSONOutput struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Promoted  []string        `json:"promoted"`
+	Rewritten []rewrittenJSON `json:"rewritten"`
+}
+
+func printCopyText(w io.Writer, from, to string, r *core.CopyResult) {
+	fmt.Fprintf(w, "from: %s\n", from)
+	fmt.Fprintf(w, "to: %s\n", to)
+	printStringListText(w, "promoted", r.Promoted)
+	printRewrittenText(w, r.Rewritten)
+}
+
+func printCopyJSON(w io.Writer, from, to string, r *core.CopyResult) error {
+	out := copyJSONOutput{
+		From:      from,
+		To:        to,
+		Promoted:  r.Promoted,
+		Rewritten: toRewrittenJSON(r.Rewritten),
+	}
+	if out.Promoted == nil {
+		out.Promoted = []string{}
+	}
+	if out.Rewritten == nil {
+		out.Rewritten = []rewrittenJSON{}
+	}
+	return encodeJSON(w, out)
+}
+
+// --- Split output ---
+
+type splitJSONOutput struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Link      string          `json:"link"`
+	Promoted  []string        `json:"promoted"`
+	Rewritten []rewrittenJSON `json:"rewritten"`
+}
+
+func printSplitText(w io.Writer, from, to string, r *core.SplitResult) {
+	fmt.Fprintf(w, "from: %s\n", from)
+	fmt.Fprintf(w, "to: %s\n", to)
+	fmt.Fprintf(w, "link: %s\n", r.Link)
+	printStringListText(w, "promoted", r.Promoted)
+	printRewrittenText(w, r.Rewritten)
+}
+
+func printSplitJSON(w io.Writer, from, to string, r *core.SplitResult) error {
+	out := splitJSONOutput{
+		From:      from,
+		To:        to,
+		Link:      r.Link,
+		Promoted:  r.Promoted,
+		Rewritten: toRewrittenJSON(r.Rewritten),
+	}
+	if out.Promoted == nil {
+		out.Promoted = []string{}
+	}
+	if out.Rewritten == nil {
+		out.Rewritten = []rewrittenJSON{}
+	}
+	return encodeJSON(w, out)
+}
+
+// --- Resolve --all output ---
+
+type linkResolutionJSON struct {
+	RawLink    string         `json:"raw_link"`
+	LineStart  int            `json:"line_start"`
+	LineEnd    int            `json:"line_end"`
+	Status     string         `json:"status"`
+	Target     map[string]any `json:"target,omitempty"`
+	Candidates []string       `json:"candidates,omitempty"`
+}
+
+type resolveAllJSONOutput struct {
+	Links []linkResolutionJSON `json:"links"`
+}
+
+func printResolveAllJSON(w io.Writer, links []core.LinkResolution) error {
+	out := resolveAllJSONOutput{Links: make([]linkResolutionJSON, len(links))}
+	for i, l := range links {
+		out.Links[i] = linkResolutionJSON{
+			RawLink:    l.RawLink,
+			LineStart:  l.LineStart,
+			LineEnd:    l.LineEnd,
+			Status:     l.Status,
+			Candidates: l.Candidates,
+		}
+		if l.Target != nil {
+			out.Links[i].Target = buildResolveMap(l.Target, nil)
+		}
+	}
+	return encodeJSON(w, out)
+}
+
+func printResolveAllText(w io.Writer, links []core.LinkResolution) {
+	if len(links) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "links:")
+	for _, l := range links {
+		fmt.Fprintf(w, "- raw_link: %s\n", l.RawLink)
+		fmt.Fprintf(w, "  line: %d\n", l.LineStart)
+		fmt.Fprintf(w, "  status: %s\n", l.Status)
+		if t := l.Target; t != nil {
+			fmt.Fprintf(w, "  type: %s\n", t.Type)
+			if t.Type == "note" || t.Type == "asset" {
+				fmt.Fprintf(w, "  path: %s\n", t.Path)
+			} else {
+				fmt.Fprintf(w, "  name: %s\n", t.Name)
+			}
+			if t.Subpath != "" {
+				fmt.Fprintf(w, "  subpath: %s\n", t.Subpath)
+			}
+		}
+		if len(l.Candidates) > 0 {
+			fmt.Fprintln(w, "  candidates:")
+			for _, c := range l.Candidates {
+				fmt.Fprintf(w, "  - %s\n", c)
+			}
+		}
+	}
+}
+
+// --- Lint output ---
+
+type lintFindingJSON struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+func printLintJSON(w io.Writer, findings []core.LintFinding) error {
+	out := make([]lintFindingJSON, len(findings))
+	for i, f := range findings {
+		out[i] = lintFindingJSON{Rule: f.Rule, Severity: f.Severity, Path: f.Path, Line: f.Line, Message: f.Message}
+	}
+	return encodeJSON(w, map[string]any{"findings": out})
+}
+
+func printLintText(w io.Writer, findings []core.LintFinding) error {
+	if len(findings) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w, "findings:")
+	for _, f := range findings {
+		fmt.Fprintf(w, "- rule: %s\n", f.Rule)
+		fmt.Fprintf(w, "  severity: %s\n", f.Severity)
+		fmt.Fprintf(w, "  path: %s\n", f.Path)
+		if f.Line > 0 {
+			fmt.Fprintf(w, "  line: %d\n", f.Line)
+		}
+		fmt.Fprintf(w, "  message: %s\n", f.Message)
+	}
+	return nil
+}
+
+// --- Tags output ---
+
+type tagCountJSON struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+func printTagsJSON(w io.Writer, tags []core.TagCount) error {
+	out := make([]tagCountJSON, len(tags))
+	for i, t := range tags {
+		out[i] = tagCountJSON{Tag: t.Tag, Count: t.Count}
+	}
+	return encodeJSON(w, map[string]any{"tags": out})
+}
+
+// printTagsText writes one list item per tag. With tree, nested tags are
+// indented one level per depth under their parent.
+func printTagsText(w io.Writer, tags []core.TagCount, tree bool) {
+	if len(tags) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "tags:")
+	for _, t := range tags {
+		indent := ""
+		if tree {
+			indent = strings.Repeat("  ", t.Depth)
+		}
+		fmt.Fprintf(w, "%s- tag: %s\n", indent, t.Tag)
+		fmt.Fprintf(w, "%s  count: %d\n", indent, t.Count)
+	}
+}
+
+// --- Phantoms output ---
+
+type phantomRefsJSON struct {
+	Name     string   `json:"name"`
+	Count    int      `json:"count"`
+	RawLinks []string `json:"raw_links"`
+}
+
+func printPhantomsJSON(w io.Writer, phantoms []core.PhantomRefs) error {
+	out := make([]phantomRefsJSON, len(phantoms))
+	for i, p := range phantoms {
+		out[i] = phantomRefsJSON{Name: p.Name, Count: p.Count, RawLinks: p.RawLinks}
+		if out[i].RawLinks == nil {
+			out[i].RawLinks = []string{}
+		}
+	}
+	return encodeJSON(w, map[string]any{"phantoms": out})
+}
+
+func printPhantomsText(w io.Writer, phantoms []core.PhantomRefs) {
+	if len(phantoms) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "phantoms:")
+	for _, p := range phantoms {
+		fmt.Fprintf(w, "- name: %s\n", p.Name)
+		fmt.Fprintf(w, "  count: %d\n", p.Count)
+		if len(p.RawLinks) > 0 {
+			fmt.Fprintln(w, "  raw_links:")
+			for _, rl := range p.RawLinks {
+				fmt.Fprintf(w, "  - %q\n", rl)
+			}
+		}
+	}
+}
+
+// --- Edges output ---
+
+type edgeJSON struct {
+	Source   string       `json:"source"`
+	Target   jsonNodeInfo `json:"target"`
+	LinkType string       `json:"link_type"`
+	RawLink  string       `json:"raw_link"`
+	Subpath  string       `json:"subpath,omitempty"`
+	Lines    string       `json:"lines"`
+}
+
+func printEdgesJSON(w io.Writer, edges []core.EdgeRecord) error {
+	out := make([]edgeJSON, len(edges))
+	for i, e := range edges {
+		out[i] = edgeJSON{
+			Source:   e.Source,
+			Target:   toJSONNodeInfo(e.Target),
+			LinkType: e.LinkType,
+			RawLink:  e.RawLink,
+			Subpath:  e.Subpath,
+			Lines:    fmt.Sprintf("%d-%d", e.LineStart, e.LineEnd),
+		}
+	}
+	return encodeJSON(w, map[string]any{"edges": out})
+}
+
+func printEdgesText(w io.Writer, edges []core.EdgeRecord) {
+	if len(edges) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "edges:")
+	for _, e := range edges {
+		fmt.Fprintf(w, "- source: %s\n", e.Source)
+		fmt.Fprintf(w, "  target: %s\n", nodeInfoOneLine(e.Target))
+		fmt.Fprintf(w, "  link_type: %s\n", e.LinkType)
+		fmt.Fprintf(w, "  raw_link: %q\n", e.RawLink)
+		fmt.Fprintf(w, "  lines: %d-%d\n", e.LineStart, e.LineEnd)
+	}
+}
+
+// --- Tag output ---
+
+type tagJSONOutput struct {
+	Updated   []string `json:"updated"`
+	Unchanged []string `json:"unchanged"`
+}
+
+func printTagText(w io.Writer, r *core.TagResult) {
+	printStringListText(w, "updated", r.Updated)
+	printStringListText(w, "unchanged", r.Unchanged)
+}
+
+func printTagJSON(w io.Writer, r *core.TagResult) error {
+	out := tagJSONOutput{Updated: r.Updated, Unchanged: r.Unchanged}
+	if out.Updated == nil {
+		out.Updated = []string{}
+	}
+	if out.Unchanged == nil {
+		out.Unchanged = []string{}
+	}
+	return encodeJSON(w, out)
+}
@@ -106,6 +106,29 @@ func TestPrintResolveText_Note(t *testing.T) {
 	}
 }
 
+func TestPrintResolve_Anchor(t *testing.T) {
+	r := &core.ResolveResult{
+		Type: "note", Name: "Design", Path: "Design.md", Exists: true, Subpath: "#Details",
+		Anchor: &core.Anchor{Heading: "Details", Line: 7},
+	}
+	var buf bytes.Buffer
+	printResolveText(&buf, r, []string{"path"})
+	if want := "path: Design.md\nanchor: Details\nanchor_line: 7\n"; buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	r.Anchor = &core.Anchor{Heading: "Nope"}
+	buf.Reset()
+	printResolveJSON(&buf, r, []string{"path"})
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["anchor"] != "Nope" || m["anchor_missing"] != true || m["anchor_line"] != nil {
+		t.Errorf("got %v, want anchor Nope reported missing", m)
+	}
+}
+
 func TestPrintResolveText_Phantom(t *testing.T) {
 	r := &core.ResolveResult{
 		Type: "phantom", Name: "MissingNote", Path: "", Exists: false,
@@ -191,6 +214,7 @@ func TestPrintQueryText_Full(t *testing.T) {
 			Via:     core.NodeInfo{Type: "note", Name: "Design", Path: "Notes/Design.md", Exists: true},
 			Targets: []core.NodeInfo{{Type: "note", Name: "Spec", Path: "Notes/Spec.md", Exists: true}},
 		}},
+		Headings: []core.Heading{{Level: 1, Text: "Index", Line: 1}},
 		Head:     []string{"# Index", "This is the main index."},
 		Snippets: []core.SnippetEntry{{SourcePath: "Notes/Design.md", LineStart: 5, LineEnd: 7, Lines: []string{"Before", "See [[Index]]", "After"}}},
 	}
@@ -204,6 +228,7 @@ func TestPrintQueryText_Full(t *testing.T) {
 		"outgoing:\n- type: note",
 		"tags:\n- #project",
 		"twohop:\n- via: note: Notes/Design.md\n  targets:\n  - note: Notes/Spec.md",
+		"headings:\n- level: 1\n  text: \"Index\"\n  line: 1",
 		"head:\n- \"# Index\"",
 		"snippet:\n- source: Notes/Design.md\n  lines: 5-7",
 	}
@@ -269,6 +294,97 @@ func TestPrintQueryJSON_Full(t *testing.T) {
 	}
 }
 
+func TestPrintQuery_LinkPositions(t *testing.T) {
+	r := &core.QueryResult{
+		Entry: core.NodeInfo{Type: "note", Name: "Target", Path: "Target.md", Exists: true},
+		BacklinkPositions: []core.LinkPosition{{
+			Node:      core.NodeInfo{Type: "note", Name: "Src", Path: "Src.md", Exists: true},
+			LineStart: 3,
+			LineEnd:   3,
+			RawLink:   "[[Target]]",
+		}},
+	}
+	var buf bytes.Buffer
+	printQueryText(&buf, r)
+	want := "backlink_positions:\n- node: note: Src.md\n  lines: 3-3\n  raw_link: \"[[Target]]\"\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("text output missing %q:\n%s", want, buf.String())
+	}
+	if strings.Contains(buf.String(), "outgoing_positions:") {
+		t.Error("nil outgoing positions should be omitted")
+	}
+
+	buf.Reset()
+	printQueryJSON(&buf, r)
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	pos := m["backlink_positions"].([]any)[0].(map[string]any)
+	if pos["lines"] != "3-3" || pos["raw_link"] != "[[Target]]" {
+		t.Errorf("backlink position = %v", pos)
+	}
+}
+
+func TestPrintQuery_TagLocations(t *testing.T) {
+	r := &core.QueryResult{
+		Entry:        core.NodeInfo{Type: "note", Name: "A", Path: "A.md", Exists: true},
+		Tags:         []string{"#fm_tag", "#simple"},
+		TagLocations: []core.TagLocation{{Tag: "#fm_tag", Line: 3, Source: "frontmatter"}, {Tag: "#simple", Line: 8, Source: "inline"}},
+	}
+	var buf bytes.Buffer
+	printQueryText(&buf, r)
+	want := "tag_locations:\n- tag: #fm_tag\n  line: 3\n  source: frontmatter\n- tag: #simple\n  line: 8\n  source: inline\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("text output missing %q:\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	printQueryJSON(&buf, r)
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	loc := m["tag_locations"].([]any)[1].(map[string]any)
+	if loc["tag"] != "#simple" || loc["line"] != float64(8) || loc["source"] != "inline" {
+		t.Errorf("tag location = %v", loc)
+	}
+}
+
+func TestPrintQuery_TwoHopRanked(t *testing.T) {
+	r := &core.QueryResult{
+		Entry: core.NodeInfo{Type: "note", Name: "Index", Path: "Index.md", Exists: true},
+		TwoHopRanked: []core.TwoHopTarget{{
+			Target: core.NodeInfo{Type: "note", Name: "Spec", Path: "Notes/Spec.md", Exists: true},
+			Weight: 2,
+			Vias: []core.NodeInfo{
+				{Type: "note", Name: "Design", Path: "Notes/Design.md", Exists: true},
+				{Type: "tag", Name: "#project"},
+			},
+		}},
+	}
+	var buf bytes.Buffer
+	printQueryText(&buf, r)
+	want := "twohop_ranked:\n- target: note: Notes/Spec.md\n  weight: 2\n  vias:\n  - note: Notes/Design.md\n  - tag: #project\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("text output missing %q:\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	printQueryJSON(&buf, r)
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	ranked := m["twohop_ranked"].([]any)[0].(map[string]any)
+	if ranked["weight"] != float64(2) {
+		t.Errorf("weight = %v, want 2", ranked["weight"])
+	}
+	if vias := ranked["vias"].([]any); len(vias) != 2 {
+		t.Errorf("vias = %v, want 2 entries", vias)
+	}
+}
+
 func TestPrintQueryJSON_ExistsFalse(t *testing.T) {
 	r := &core.QueryResult{
 		Entry:     core.NodeInfo{Type: "note", Name: "A", Path: "A.md", Exists: false},
@@ -295,6 +411,167 @@ func TestPrintQueryJSON_ExistsFalse(t *testing.T) {
 	}
 }
 
+func TestPrintQueryText_EntryAliases(t *testing.T) {
+	r := &core.QueryResult{
+		Entry:    core.NodeInfo{Type: "note", Name: "Bar", Path: "Bar.md", Exists: true, Aliases: []string{"Foo", "Baz"}},
+		ViaAlias: "Foo",
+	}
+	var buf bytes.Buffer
+	printQueryText(&buf, r)
+	got := buf.String()
+	for _, c := range []string{"  aliases:\n  - Foo\n  - Baz\n", "via_alias: Foo\n"} {
+		if !strings.Contains(got, c) {
+			t.Errorf("text output missing %q:\n%s", c, got)
+		}
+	}
+}
+
+func TestPrintQueryText_TotalBacklinks(t *testing.T) {
+	r := &core.QueryResult{
+		Entry:          core.NodeInfo{Type: "note", Name: "Hub", Path: "Hub.md", Exists: true},
+		Backlinks:      []core.NodeInfo{{Type: "note", Name: "A", Path: "A.md", Exists: true}},
+		TotalBacklinks: 7,
+	}
+	var buf bytes.Buffer
+	printQueryText(&buf, r)
+	if !strings.Contains(buf.String(), "total_backlinks: 7\n") {
+		t.Errorf("text output missing total_backlinks:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	printQueryJSON(&buf, r)
+	if !strings.Contains(buf.String(), `"total_backlinks": 7`) {
+		t.Errorf("json output missing total_backlinks:\n%s", buf.String())
+	}
+}
+
+func TestPrintDiagnoseText_Suggestions(t *testing.T) {
+	r := &core.DiagnoseResult{
+		Phantoms:    []string{"Desgin"},
+		Suggestions: []core.PhantomSuggestion{{Phantom: "Desgin", Path: "Design.md", Distance: 2}},
+	}
+	var buf bytes.Buffer
+	printDiagnoseText(&buf, r, nil)
+	want := "phantoms:\n- Desgin\nsuggestions:\n- phantom: Desgin\n  suggest: Design.md\n  distance: 2\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintDiagnoseText_DuplicateNotes(t *testing.T) {
+	r := &core.DiagnoseResult{
+		DuplicateNotes: []core.DuplicateGroup{{Paths: []string{"Original.md", "sub/Copy.md"}}},
+	}
+	var buf bytes.Buffer
+	printDiagnoseText(&buf, r, []string{"duplicate_notes"})
+	want := "duplicate_notes:\n- paths:\n  - Original.md\n  - sub/Copy.md\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintTagTreeText(t *testing.T) {
+	r := &core.TagTreeResult{Root: core.TagTreeNode{
+		Tag:   "#project",
+		Notes: []core.NodeInfo{{Type: "note", Name: "P", Path: "P.md", Exists: true}},
+		Children: []core.TagTreeNode{
+			{Tag: "#project/alpha", Notes: []core.NodeInfo{{Type: "note", Name: "A", Path: "a/A.md", Exists: true}}},
+			{Tag: "#project/beta", Children: []core.TagTreeNode{
+				{Tag: "#project/beta/x", Notes: []core.NodeInfo{{Type: "note", Name: "X", Path: "X.md", Exists: true}}},
+			}},
+		},
+	}}
+	var buf bytes.Buffer
+	printTagTreeText(&buf, r)
+	want := "tag: #project\nnotes:\n- P.md\nchildren:\n" +
+		"- tag: #project/alpha\n  notes:\n  - a/A.md\n" +
+		"- tag: #project/beta\n  children:\n  - tag: #project/beta/x\n    notes:\n    - X.md\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintTagParentsText(t *testing.T) {
+	r := &core.TagParentsResult{
+		Tag: "#project/beta/x",
+		Ancestors: []core.TagLevel{
+			{Tag: "#project", Notes: []core.NodeInfo{{Type: "note", Name: "P", Path: "P.md", Exists: true}}},
+			{Tag: "#project/beta"},
+		},
+	}
+	var buf bytes.Buffer
+	printTagParentsText(&buf, r)
+	want := "tag: #project/beta/x\nancestors:\n" +
+		"- tag: #project\n  notes:\n  - P.md\n" +
+		"- tag: #project/beta\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintBrokenText(t *testing.T) {
+	sources := []core.BrokenSource{{
+		Path:  "A.md",
+		Links: []core.BrokenLink{{Line: 1, LinkType: "wikilink", RawLink: "[[Missing]]", Target: "Missing", Embed: true}},
+	}}
+	var buf bytes.Buffer
+	printBrokenText(&buf, sources)
+	want := "broken:\n- source: A.md\n  links:\n  - line: 1\n    link_type: wikilink\n    embed: true\n    raw_link: \"[[Missing]]\"\n    target: Missing\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintBrokenJSON_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	printBrokenJSON(&buf, []core.BrokenSource{})
+	if !strings.Contains(buf.String(), `"broken": []`) {
+		t.Errorf("empty broken should be [], got:\n%s", buf.String())
+	}
+}
+
+func TestPrintExternalText(t *testing.T) {
+	links := []core.ExternalLink{{Path: "A.md", Line: 2, URL: "https://example.com"}}
+	var buf bytes.Buffer
+	printExternalText(&buf, links)
+	want := "external:\n- source: A.md\n  line: 2\n  url: https://example.com\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestPrintExternalJSON_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	printExternalJSON(&buf, []core.ExternalLink{})
+	if !strings.Contains(buf.String(), `"external": []`) {
+		t.Errorf("empty external should be [], got:\n%s", buf.String())
+	}
+}
+
+func TestPrintTagQueryText(t *testing.T) {
+	r := &core.TagQueryResult{
+		Tags:  []string{"#project", "#status"},
+		Notes: []core.NodeInfo{{Type: "note", Name: "Index", Path: "Index.md", Exists: true}},
+	}
+	var buf bytes.Buffer
+	printTagQueryText(&buf, r)
+	got := buf.String()
+	for _, c := range []string{"tags:\n- #project\n- #status", "notes:\n- type: note\n  name: Index\n  path: Index.md"} {
+		if !strings.Contains(got, c) {
+			t.Errorf("text output missing %q:\n%s", c, got)
+		}
+	}
+}
+
+func TestPrintTagQueryJSON_Empty(t *testing.T) {
+	r := &core.TagQueryResult{Tags: []string{"#a"}, Notes: []core.NodeInfo{}}
+	var buf bytes.Buffer
+	printTagQueryJSON(&buf, r)
+	if !strings.Contains(buf.String(), `"notes": []`) {
+		t.Errorf("empty notes should be [], got:\n%s", buf.String())
+	}
+}
+
 func TestPrintResolveText_Tag(t *testing.T) {
 	r := &core.ResolveResult{Type: "tag", Name: "#project"}
 	var buf bytes.Buffer
@@ -587,3 +864,58 @@ func TestPrintDisambiguateJSON(t *testing.T) {
 		t.Errorf("rewritten = %s, want []", m["rewritten"])
 	}
 }
+
+func TestPrintNormalizeJSON(t *testing.T) {
+	r := &core.NormalizeResult{
+		Rewritten: []core.RewrittenLink{
+			{File: "sub/Src.md", OldLink: "[[./B]]", NewLink: "[[B]]"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := printNormalizeJSON(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Rewritten []map[string]string `json:"rewritten"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Rewritten) != 1 || out.Rewritten[0]["new"] != "[[B]]" {
+		t.Errorf("rewritten = %+v", out.Rewritten)
+	}
+}
+
+func TestPrintResolveAllJSON(t *testing.T) {
+	links := []core.LinkResolution{
+		{RawLink: "[[A#H]]", LineStart: 3, LineEnd: 3, Status: "ok",
+			Target: &core.ResolveResult{Type: "note", Name: "A", Path: "A.md", Exists: true, Subpath: "#H"}},
+		{RawLink: "[[X]]", LineStart: 4, LineEnd: 4, Status: "ambiguous",
+			Candidates: []string{"a/X.md", "b/X.md"}},
+	}
+	var buf bytes.Buffer
+	if err := printResolveAllJSON(&buf, links); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Links []struct {
+			RawLink    string         `json:"raw_link"`
+			LineStart  int            `json:"line_start"`
+			Status     string         `json:"status"`
+			Target     map[string]any `json:"target"`
+			Candidates []string       `json:"candidates"`
+		} `json:"links"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Links) != 2 {
+		t.Fatalf("links = %+v", out.Links)
+	}
+	if l := out.Links[0]; l.Status != "ok" || l.LineStart != 3 || l.Target["path"] != "A.md" || l.Target["subpath"] != "#H" {
+		t.Errorf("links[0] = %+v", l)
+	}
+	if l := out.Links[1]; l.Status != "ambiguous" || l.Target != nil || len(l.Candidates) != 2 {
+		t.Errorf("links[1] = %+v", l)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+func runLint(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	only := fs.String("only", "", "comma-separated rules to run (default: all)")
+	exclude := fs.String("exclude", "", "comma-separated rules to skip")
+	failOn := fs.String("fail-on", core.LintError, "exit non-zero when findings at or above this severity exist (info, warning, error, or none)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	if *failOn != "none" {
+		if _, err := core.LintSeverityAtLeast(core.LintError, *failOn); err != nil {
+			return fmt.Errorf("invalid --fail-on: %s", *failOn)
+		}
+	}
+
+	findings, err := core.Lint(*vault, core.LintOptions{Only: parseFields(*only), Exclude: parseFields(*exclude)})
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		err = printLintJSON(os.Stdout, findings)
+	default:
+		err = printLintText(os.Stdout, findings)
+	}
+	if err != nil {
+		return err
+	}
+	if *failOn == "none" {
+		return nil
+	}
+	n := 0
+	for _, f := range findings {
+		if ok, _ := core.LintSeverityAtLeast(f.Severity, *failOn); ok {
+			n++
+		}
+	}
+	if n > 0 {
+		return fmt.Errorf("lint failed: %d finding(s) at or above %s", n, *failOn)
+	}
+	return nil
+}
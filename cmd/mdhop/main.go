@@ -6,52 +6,122 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/ryotapoi/mdhop/internal/core"
 )
 
 var version = "dev"
 
+// quiet suppresses the success summary of mutating commands (--quiet).
+var quiet bool
+
+// defaultVault is the default of every command's --vault flag: the global
+// --vault when given, otherwise the discovered vault (see discoverVault).
+var defaultVault = "."
+
+// argBase is the working directory relative to the discovered vault root,
+// or "" when the vault was given with --vault or is the working directory.
+// Path arguments are relative to the working directory, so commands rebase
+// them with vaultPathArg.
+var argBase string
+
 func main() {
-	if len(os.Args) < 2 {
+	globals, args := splitGlobalArgs(os.Args[1:])
+	globals, level, err := parseLogLevel(globals)
+	if err == nil {
+		var timeout time.Duration
+		var set bool
+		globals, timeout, set, err = parseLockTimeout(globals)
+		if set {
+			core.SetLockTimeout(timeout)
+		}
+	}
+	if err == nil {
+		var vault string
+		var set bool
+		globals, vault, set, err = parseVault(globals)
+		if set {
+			defaultVault = vault
+		} else {
+			defaultVault, argBase = discoverVault()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	// Anything left before the command (--version, --help) is dispatched as
+	// the command itself.
+	args = append(globals, args...)
+	if len(args) < 1 {
 		printUsage()
 		os.Exit(1)
 	}
+	quiet = level == core.LogQuiet
+	core.SetLogLevel(level)
 
-	var err error
-	switch os.Args[1] {
+	switch args[0] {
 	case "build":
-		err = runBuild(os.Args[2:])
+		err = runBuild(args[1:])
 	case "resolve":
-		err = runResolve(os.Args[2:])
+		err = runResolve(args[1:])
 	case "query":
-		err = runQuery(os.Args[2:])
+		err = runQuery(args[1:])
 	case "stats":
-		err = runStats(os.Args[2:])
+		err = runStats(args[1:])
+	case "tags":
+		err = runTags(args[1:])
+	case "phantoms":
+		err = runPhantoms(args[1:])
+	case "edges":
+		err = runEdges(args[1:])
+	case "serve":
+		err = runServe(args[1:])
+	case "search":
+		err = runSearch(args[1:])
 	case "diagnose":
-		err = runDiagnose(os.Args[2:])
+		err = runDiagnose(args[1:])
+	case "verify":
+		err = runVerify(args[1:])
+	case "lint":
+		err = runLint(args[1:])
 	case "delete":
-		err = runDelete(os.Args[2:])
+		err = runDelete(args[1:])
 	case "update":
-		err = runUpdate(os.Args[2:])
+		err = runUpdate(args[1:])
 	case "add":
-		err = runAdd(os.Args[2:])
+		err = runAdd(args[1:])
 	case "move":
-		err = runMove(os.Args[2:])
+		err = runMove(args[1:])
+	case "copy":
+		err = runCopy(args[1:])
+	case "split":
+		err = runSplit(args[1:])
 	case "disambiguate":
-		err = runDisambiguate(os.Args[2:])
+		err = runDisambiguate(args[1:])
 	case "simplify":
-		err = runSimplify(os.Args[2:])
+		err = runSimplify(args[1:])
+	case "normalize":
+		err = runNormalize(args[1:])
 	case "repair":
-		err = runRepair(os.Args[2:])
+		err = runRepair(args[1:])
 	case "convert":
-		err = runConvert(os.Args[2:])
+		err = runConvert(args[1:])
+	case "assets":
+		err = runAssets(args[1:])
+	case "tag":
+		err = runTag(args[1:])
 	case "--version":
 		printVersion(os.Stdout)
 		return
 	case "help", "--help", "-h":
 		printUsage()
 	default:
-		fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", args[0])
 		printUsage()
 		os.Exit(1)
 	}
@@ -65,6 +135,163 @@ func main() {
 	}
 }
 
+// splitGlobalArgs splits args at the command name. Global options are only
+// recognized before it, so a command's own arguments (a search term "-v", a
+// file named "--vault") are never taken for them.
+func splitGlobalArgs(args []string) (globals, rest []string) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" || !strings.HasPrefix(a, "-") {
+			return args[:i], args[i:]
+		}
+		switch a {
+		case "--vault", "-vault", "--lock-timeout", "-lock-timeout":
+			i++ // skip the value
+		}
+	}
+	return args, nil
+}
+
+// parseLogLevel removes --quiet/-q and --verbose/-v from the global options
+// and returns the remaining ones and the chosen level.
+func parseLogLevel(args []string) ([]string, core.LogLevel, error) {
+	level := core.LogNormal
+	var q, v bool
+	rest := make([]string, 0, len(args))
+	for _, a := range args {
+		switch a {
+		case "-q", "-quiet", "--quiet":
+			q = true
+			level = core.LogQuiet
+		case "-v", "-verbose", "--verbose":
+			v = true
+			level = core.LogVerbose
+		default:
+			rest = append(rest, a)
+		}
+	}
+	if q && v {
+		return nil, 0, fmt.Errorf("--quiet and --verbose cannot be combined")
+	}
+	return rest, level, nil
+}
+
+// parseLockTimeout removes --lock-timeout <duration> (or --lock-timeout=<duration>)
+// from the global options. set is false when the option is absent.
+func parseLockTimeout(args []string) ([]string, time.Duration, bool, error) {
+	rest, val, set, err := extractGlobalOption(args, "lock-timeout", "a duration")
+	if err != nil || !set {
+		return rest, 0, false, err
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d < 0 {
+		return nil, 0, false, fmt.Errorf("invalid --lock-timeout: %s", val)
+	}
+	return rest, d, true, nil
+}
+
+// parseVault removes --vault <path> (or --vault=<path>) from the global
+// options. set is false when the option is absent.
+func parseVault(args []string) ([]string, string, bool, error) {
+	rest, val, set, err := extractGlobalOption(args, "vault", "a path")
+	if err == nil && set && val == "" {
+		err = fmt.Errorf("--vault requires a path")
+	}
+	return rest, val, set, err
+}
+
+// extractGlobalOption removes every "--name value" / "--name=value" (one or
+// two dashes) from the global options and returns the last value. what names
+// the value in the error for a missing one.
+func extractGlobalOption(args []string, name, what string) ([]string, string, bool, error) {
+	var val string
+	set := false
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--"+name || a == "-"+name:
+			if i+1 >= len(args) {
+				return nil, "", false, fmt.Errorf("--%s requires %s", name, what)
+			}
+			i++
+			val = args[i]
+		case strings.HasPrefix(a, "--"+name+"="):
+			val = strings.TrimPrefix(a, "--"+name+"=")
+		case strings.HasPrefix(a, "-"+name+"="):
+			val = strings.TrimPrefix(a, "-"+name+"=")
+		default:
+			rest = append(rest, a)
+			continue
+		}
+		set = true
+	}
+	return rest, val, set, nil
+}
+
+// discoverVault returns the vault root for commands run without --vault: the
+// nearest directory at or above the working directory that holds .mdhop/, or
+// "." when there is none (build then creates the index in the working
+// directory). base is the working directory relative to that root, in slash
+// form, or "" when they are the same.
+func discoverVault() (root, base string) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ".", ""
+	}
+	root, ok := core.FindVault(cwd)
+	if !ok || root == cwd {
+		return ".", ""
+	}
+	rel, err := filepath.Rel(root, cwd)
+	if err != nil {
+		return root, ""
+	}
+	return root, filepath.ToSlash(rel)
+}
+
+// vaultPathArg rebases a path argument given relative to the working
+// directory onto the discovered vault root, so "query --file Impl.md" run in
+// sub/ means sub/Impl.md. A command given its own --vault takes
+// vault-relative paths as before.
+func vaultPathArg(fs *flag.FlagSet, p string) string {
+	if argBase == "" || p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	explicit := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "vault" {
+			explicit = true
+		}
+	})
+	if explicit {
+		return p
+	}
+	rebased := filepath.ToSlash(filepath.Join(argBase, p))
+	if strings.HasSuffix(p, "/") {
+		rebased += "/" // keep marking a destination directory
+	}
+	return rebased
+}
+
+// vaultPathArgs applies vaultPathArg to every path in ps.
+func vaultPathArgs(fs *flag.FlagSet, ps []string) []string {
+	for i, p := range ps {
+		ps[i] = vaultPathArg(fs, p)
+	}
+	return ps
+}
+
+// summaryOut returns w, or io.Discard when --quiet is set. Mutating commands
+// write their success summary and hints through it; errors always reach
+// stderr from main.
+func summaryOut(w io.Writer) io.Writer {
+	if quiet {
+		return io.Discard
+	}
+	return w
+}
+
 func printVersion(w io.Writer) {
 	v := version
 	if v == "dev" {
@@ -79,21 +306,45 @@ func printUsage() {
 	fmt.Fprint(os.Stderr, `Usage: mdhop <command> [options]
 
 Index Commands:
-  build         Build the index from the vault
+  build         Build the index from the vault (--edges-only keeps node ids)
   add           Add new files to the index
   update        Update specified files in the index
   delete        Remove files from the index
   move          Move a file and update links
+  copy          Duplicate a note and rewrite its relative links
+  split         Extract a heading section into a new note
   disambiguate  Rewrite basename links to full paths
   simplify      Shorten path links to basename when unambiguous
+  normalize     Rewrite resolvable links to one canonical form
   repair        Fix broken path links by rewriting to basename
   convert       Convert between wikilink and markdown link formats
+  assets move   Move an asset and rewrite embeds/image links to it
+  assets prune  Delete unreferenced assets (dry run unless --apply)
+  tag add       Add a frontmatter tag to notes
+  tag remove    Remove a frontmatter tag from notes
 
 Query Commands:
   resolve    Resolve a link from a source file
   query      Query related information for a node
   stats      Show vault statistics
-  diagnose   Show basename conflicts and phantom nodes
+  tags       List tags with the number of notes using each
+  phantoms   List phantoms with the links that reference them
+  edges      List every link of the index (paged with --limit/--offset)
+  search     Full-text search over note bodies
+  diagnose   Show basename conflicts, duplicate notes and phantom nodes
+  verify     Check that the index matches the vault on disk
+  lint       Report vault hygiene issues (broken links, orphans, ...)
+  serve      Serve query/resolve as a local HTTP JSON API
+
+Global Options (before the command):
+  -q, --quiet    Suppress the summary printed by index commands on success
+  -v, --verbose  Print progress of each index step to stderr
+  --lock-timeout <duration>
+                 How long to wait for another mdhop process to release
+                 the index (default 10s; 0 fails immediately)
+  --vault <path> Vault root directory (default: the nearest directory at or
+                 above the current one containing .mdhop/, else the current
+                 directory)
 
 Run 'mdhop <command> --help' for command-specific help.
 Use 'mdhop --version' for version information.
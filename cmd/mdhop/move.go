@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path"
 	"strings"
 
 	"github.com/ryotapoi/mdhop/internal/core"
@@ -11,25 +12,68 @@ import (
 
 func runMove(args []string) error {
 	fs := flag.NewFlagSet("move", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	format := fs.String("format", "text", "output format (json or text)")
 	from := fs.String("from", "", "source file path (vault-relative)")
-	to := fs.String("to", "", "destination file path (vault-relative)")
+	to := fs.String("to", "", "destination file path or directory (vault-relative)")
+	porcelain := fs.Bool("porcelain", false, "stable tab-separated output for scripts (overrides --format)")
+	maxDepth := fs.Int("max-depth", 0, "directory mode: only move files up to N levels below --from (0 = unlimited)")
+	pruneEmpty := fs.Bool("prune-empty", false, "directory mode: remove --from and its subdirectories once they hold only hidden files")
+	renameOnConflict := fs.Bool("rename-on-conflict", false, "directory mode: move files whose destination exists to a numeric suffix (A.md -> A-1.md)")
+	force := fs.Bool("force", false, "move even if the moved file changed since the last build (reparses files from disk)")
+	updateOnly := fs.Bool("update-only", false, "only update the index and links for a file already moved on disk (never moves files)")
+	rename := fs.Bool("rename", false, "rename in place: --to (or the second argument) is a file name in --from's directory")
+	keepBackup, cleanBackups := backupFlags(fs)
+	preserveMtime := fs.Bool("preserve-mtime", false, "keep the original modification time of files whose links are rewritten")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 	if err := validateFormat(*format); err != nil {
 		return err
 	}
+	if *cleanBackups {
+		return runCleanBackups(*vault, *format)
+	}
+	if *rename {
+		// Accept "move --rename A.md NewName.md" as well as --from/--to.
+		rest := fs.Args()
+		if *from == "" && len(rest) > 0 {
+			*from, rest = rest[0], rest[1:]
+		}
+		if *to == "" && len(rest) > 0 {
+			*to, rest = rest[0], rest[1:]
+		}
+		if len(rest) > 0 {
+			return fmt.Errorf("unexpected arguments: %s", strings.Join(rest, " "))
+		}
+	}
 	if *from == "" {
 		return fmt.Errorf("--from is required")
 	}
 	if *to == "" {
 		return fmt.Errorf("--to is required")
 	}
+	*from = vaultPathArg(fs, *from)
+	if !*rename {
+		*to = vaultPathArg(fs, *to)
+	}
 
 	fromIsDir := isDirArg(*vault, *from)
 
+	if *rename {
+		if fromIsDir {
+			return fmt.Errorf("--rename requires a file --from")
+		}
+		if strings.ContainsAny(*to, "/\\") {
+			return fmt.Errorf("--rename takes a file name, not a path: %s", *to)
+		}
+		*to = path.Join(path.Dir(core.NormalizePath(*from)), *to)
+	}
+
+	if fromIsDir && *updateOnly {
+		return fmt.Errorf("--update-only requires a file --from")
+	}
+
 	if fromIsDir {
 		// Directory mode.
 		toIsFile := strings.HasSuffix(strings.ToLower(*to), ".md")
@@ -39,41 +83,84 @@ func runMove(args []string) error {
 		fromDir := core.NormalizePath(strings.TrimSuffix(*from, "/"))
 		toDir := core.NormalizePath(strings.TrimSuffix(*to, "/"))
 		result, err := core.MoveDir(*vault, core.MoveDirOptions{
-			FromDir: fromDir,
-			ToDir:   toDir,
+			FromDir:          fromDir,
+			ToDir:            toDir,
+			MaxDepth:         *maxDepth,
+			PruneEmpty:       *pruneEmpty,
+			Force:            *force,
+			KeepBackup:       *keepBackup,
+			PreserveMtime:    *preserveMtime,
+			RenameOnConflict: *renameOnConflict,
 		})
 		if err != nil {
 			return err
 		}
+		if *porcelain {
+			printMoveDirPorcelain(summaryOut(os.Stdout), result)
+			return nil
+		}
 		switch *format {
 		case "json":
-			return printMoveDirJSON(os.Stdout, result)
+			return printMoveDirJSON(summaryOut(os.Stdout), result)
 		default:
-			printMoveDirText(os.Stdout, result)
+			printMoveDirText(summaryOut(os.Stdout), result)
 			return nil
 		}
 	}
 
-	// Single file mode.
-	toIsDir := strings.HasSuffix(*to, "/")
-	if toIsDir {
-		return fmt.Errorf("cannot use directory destination for single file move")
+	if *maxDepth != 0 {
+		return fmt.Errorf("--max-depth requires a directory --from")
+	}
+	if *pruneEmpty {
+		return fmt.Errorf("--prune-empty requires a directory --from")
+	}
+	if *renameOnConflict {
+		return fmt.Errorf("--rename-on-conflict requires a directory --from")
+	}
+
+	// Single file mode. A directory destination keeps the source basename.
+	dest := *to
+	if isDirArg(*vault, dest) || isRegisteredDir(*vault, dest) {
+		dest = path.Join(core.NormalizePath(strings.TrimSuffix(dest, "/")), path.Base(core.NormalizePath(*from)))
 	}
 
 	result, err := core.Move(*vault, core.MoveOptions{
-		From: *from,
-		To:   *to,
+		From:          *from,
+		To:            dest,
+		Force:         *force,
+		KeepBackup:    *keepBackup,
+		PreserveMtime: *preserveMtime,
+		UpdateOnly:    *updateOnly,
 	})
 	if err != nil {
 		return err
 	}
 	normalizedFrom := core.NormalizePath(*from)
-	normalizedTo := core.NormalizePath(*to)
+	normalizedTo := core.NormalizePath(dest)
+	if *porcelain {
+		printMovePorcelain(summaryOut(os.Stdout), normalizedFrom, normalizedTo, result)
+		return nil
+	}
 	switch *format {
 	case "json":
-		return printMoveJSON(os.Stdout, normalizedFrom, normalizedTo, result)
+		return printMoveJSON(summaryOut(os.Stdout), normalizedFrom, normalizedTo, result)
 	default:
-		printMoveText(os.Stdout, normalizedFrom, normalizedTo, result)
+		printMoveText(summaryOut(os.Stdout), normalizedFrom, normalizedTo, result)
 		return nil
 	}
 }
+
+// isRegisteredDir reports whether the index has notes or assets under arg,
+// even if the directory no longer exists on disk.
+func isRegisteredDir(vaultPath, arg string) bool {
+	dir := core.NormalizePath(strings.TrimSuffix(arg, "/"))
+	notes, err := core.ListDirNotes(vaultPath, dir)
+	if err != nil {
+		return false
+	}
+	if len(notes) > 0 {
+		return true
+	}
+	assets, err := core.ListDirAssets(vaultPath, dir)
+	return err == nil && len(assets) > 0
+}
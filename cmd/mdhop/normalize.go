@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+func runNormalize(args []string) error {
+	fs := flag.NewFlagSet("normalize", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	style := fs.String("style", "basename", "link style: basename (shortest unambiguous) or path (full vault path)")
+	dryRun := fs.Bool("dry-run", false, "show what would be normalized without making changes")
+	var files multiString
+	fs.Var(&files, "file", "limit normalization to these source files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	files = vaultPathArgs(fs, files)
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+
+	result, err := core.Normalize(*vault, core.NormalizeOptions{
+		Style:  *style,
+		DryRun: *dryRun,
+		Files:  files,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		if err := printNormalizeJSON(summaryOut(os.Stdout), result); err != nil {
+			return err
+		}
+	default:
+		printNormalizeText(summaryOut(os.Stdout), result)
+	}
+	if !*dryRun && len(result.Rewritten) > 0 {
+		fmt.Fprintln(summaryOut(os.Stderr), "hint: run 'mdhop build' to create or update the index")
+	}
+	return nil
+}
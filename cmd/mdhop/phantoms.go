@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+func runPhantoms(args []string) error {
+	fs := flag.NewFlagSet("phantoms", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+
+	phantoms, err := core.ListPhantoms(*vault)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		return printPhantomsJSON(os.Stdout, phantoms)
+	default:
+		printPhantomsText(os.Stdout, phantoms)
+		return nil
+	}
+}
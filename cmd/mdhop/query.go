@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 
 	"github.com/ryotapoi/mdhop/internal/core"
@@ -9,18 +10,42 @@ import (
 
 func runQuery(args []string) error {
 	fs := flag.NewFlagSet("query", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	file := fs.String("file", "", "note entry (vault-relative path)")
 	tag := fs.String("tag", "", "tag entry")
+	tags := fs.String("tags", "", "comma-separated tags: list notes having all of them")
+	broken := fs.Bool("broken", false, "list all links pointing to phantoms, grouped by source")
+	external := fs.Bool("external", false, "list all external (http/https) links")
+	cycles := fs.Bool("cycles", false, "list groups of notes that link to each other in a cycle")
+	dedupe := fs.Bool("dedupe", false, "with --external: list each URL once")
+	tree := fs.Bool("tree", false, "with --tag: list descendant tags and the notes tagged at each")
+	impact := fs.String("impact", "", "list the notes that would lose their last backlink if this note were deleted")
+	parents := fs.Bool("parents", false, "with --tag: list ancestor tags and the notes tagged at each")
 	phantom := fs.String("phantom", "", "phantom entry")
+	asset := fs.String("asset", "", "asset entry (path, or basename resolved like a link)")
 	name := fs.String("name", "", "auto-detect entry")
+	heading := fs.String("heading", "", "with --file or --name: only backlinks that link to this heading of the note")
 	format := fs.String("format", "text", "output format (json or text)")
 	fields := fs.String("fields", "", "comma-separated fields to output")
 	includeHead := fs.Int("include-head", 0, "include first N lines of note")
+	includeFrontmatter := fs.Bool("include-frontmatter", false, "with --include-head: count from the first line, frontmatter included")
 	includeSnippet := fs.Int("include-snippet", 0, "include N context lines around links")
+	snippetMode := fs.String("snippet-mode", "lines", "snippet window: lines (--include-snippet N around the link) or paragraph (enclosing paragraph)")
+	snippetQuery := fs.String("snippet-query", "", "rank snippets by occurrences of this term")
+	lineNumbers := fs.Bool("line-numbers", false, "prefix snippet lines with their line numbers")
 	maxBacklinks := fs.Int("max-backlinks", 100, "max backlinks")
+	offset := fs.Int("offset", 0, "skip first N backlinks (for paging with --max-backlinks)")
 	maxTwoHop := fs.Int("max-twohop", 100, "max twohop entries")
 	maxViaPerTarget := fs.Int("max-via-per-target", 10, "max via entries per twohop target")
+	positions := fs.Bool("positions", false, "include line positions of backlinks/outgoing links (first per node)")
+	perEdge := fs.Bool("per-edge", false, "include line positions of every link occurrence, e.g. to find all references (implies --positions)")
+	tagLocations := fs.Bool("tag-locations", false, "include the line and source (inline or frontmatter) of each tag")
+	targetType := fs.String("target-type", "", "comma-separated outgoing target types to keep: note, phantom, asset")
+	includeSelf := fs.Bool("include-self", false, "keep the entry's own self-links ([[#Heading]]) in backlinks and outgoing")
+	sortByWeight := fs.Bool("sort-by-weight", false, "order twohop-ranked targets by weight (descending)")
+	reparseStale := fs.Bool("reparse-stale", false, "read head/snippet from files changed since the last build instead of failing")
+	allowAmbiguous := fs.Bool("allow-ambiguous", false, "with --name or --asset: list all candidates instead of failing when the name is ambiguous")
+	stream := fs.Bool("stream", false, "print backlinks as they are read instead of buffering them (text format, backlinks only)")
 	var excludePaths multiString
 	var excludeTags multiString
 	fs.Var(&excludePaths, "exclude", "exclude paths matching glob (repeatable)")
@@ -29,10 +54,18 @@ func runQuery(args []string) error {
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	*file = vaultPathArg(fs, *file)
+	*impact = vaultPathArg(fs, *impact)
 
 	if err := validateFormat(*format); err != nil {
 		return err
 	}
+	if *offset < 0 {
+		return fmt.Errorf("--offset must be >= 0")
+	}
+	if *heading != "" && *file == "" && *name == "" {
+		return fmt.Errorf("--heading requires --file or --name")
+	}
 
 	fieldList := parseFields(*fields)
 	if err := validateFields(fieldList, validQueryFieldsCLI, "query"); err != nil {
@@ -55,18 +88,168 @@ func runQuery(args []string) error {
 	entry := core.EntrySpec{
 		File:    *file,
 		Tag:     *tag,
+		Tags:    parseFields(*tags),
 		Phantom: *phantom,
+		Asset:   *asset,
 		Name:    *name,
+		Heading: *heading,
+	}
+
+	if *dedupe && !*external {
+		return fmt.Errorf("--dedupe requires --external")
+	}
+	if *external {
+		if *broken || *cycles || *impact != "" || entry.File != "" || entry.Tag != "" || len(entry.Tags) > 0 || entry.Phantom != "" || entry.Asset != "" || entry.Name != "" {
+			return fmt.Errorf("--external cannot be combined with entry options, --broken, --cycles or --impact")
+		}
+		links, err := core.QueryExternal(*vault, core.ExternalOptions{Dedupe: *dedupe, Exclude: ef})
+		if err != nil {
+			return err
+		}
+		switch *format {
+		case "json":
+			return printExternalJSON(os.Stdout, links)
+		default:
+			return printExternalText(os.Stdout, links)
+		}
+	}
+
+	if *cycles {
+		if *broken || *impact != "" || entry.File != "" || entry.Tag != "" || len(entry.Tags) > 0 || entry.Phantom != "" || entry.Asset != "" || entry.Name != "" {
+			return fmt.Errorf("--cycles cannot be combined with entry options, --broken or --impact")
+		}
+		result, err := core.QueryCycles(*vault, core.QueryOptions{Exclude: ef})
+		if err != nil {
+			return err
+		}
+		switch *format {
+		case "json":
+			return printCyclesJSON(os.Stdout, result)
+		default:
+			return printCyclesText(os.Stdout, result)
+		}
+	}
+
+	if *impact != "" {
+		if *broken || entry.File != "" || entry.Tag != "" || len(entry.Tags) > 0 || entry.Phantom != "" || entry.Asset != "" || entry.Name != "" {
+			return fmt.Errorf("--impact cannot be combined with entry options or --broken")
+		}
+		result, err := core.QueryImpact(*vault, *impact, core.QueryOptions{Exclude: ef})
+		if err != nil {
+			return err
+		}
+		switch *format {
+		case "json":
+			return printImpactJSON(os.Stdout, result)
+		default:
+			return printImpactText(os.Stdout, result)
+		}
+	}
+
+	if *broken {
+		if entry.File != "" || entry.Tag != "" || len(entry.Tags) > 0 || entry.Phantom != "" || entry.Asset != "" || entry.Name != "" {
+			return fmt.Errorf("--broken cannot be combined with entry options")
+		}
+		result, err := core.QueryBroken(*vault, core.QueryOptions{Exclude: ef})
+		if err != nil {
+			return err
+		}
+		switch *format {
+		case "json":
+			return printBrokenJSON(os.Stdout, result)
+		default:
+			return printBrokenText(os.Stdout, result)
+		}
+	}
+
+	if *parents {
+		if *tree {
+			return fmt.Errorf("--parents cannot be combined with --tree")
+		}
+		result, err := core.QueryTagParents(*vault, entry, core.QueryOptions{Exclude: ef})
+		if err != nil {
+			return err
+		}
+		switch *format {
+		case "json":
+			return printTagParentsJSON(os.Stdout, result)
+		default:
+			return printTagParentsText(os.Stdout, result)
+		}
+	}
+
+	if *tree {
+		result, err := core.QueryTagTree(*vault, entry, core.QueryOptions{Exclude: ef})
+		if err != nil {
+			return err
+		}
+		switch *format {
+		case "json":
+			return printTagTreeJSON(os.Stdout, result)
+		default:
+			return printTagTreeText(os.Stdout, result)
+		}
+	}
+
+	if len(entry.Tags) > 0 {
+		result, err := core.QueryTags(*vault, entry, core.QueryOptions{Exclude: ef})
+		if err != nil {
+			return err
+		}
+		switch *format {
+		case "json":
+			return printTagQueryJSON(os.Stdout, result)
+		default:
+			return printTagQueryText(os.Stdout, result)
+		}
 	}
 
 	opts := core.QueryOptions{
-		Fields:          fieldList,
-		IncludeHead:     *includeHead,
-		IncludeSnippet:  *includeSnippet,
-		MaxBacklinks:    *maxBacklinks,
-		MaxTwoHop:       *maxTwoHop,
-		MaxViaPerTarget: *maxViaPerTarget,
-		Exclude:         ef,
+		Fields:              fieldList,
+		IncludeHead:         *includeHead,
+		IncludeFrontmatter:  *includeFrontmatter,
+		IncludeSnippet:      *includeSnippet,
+		SnippetMode:         *snippetMode,
+		SnippetQuery:        *snippetQuery,
+		SnippetGutter:       *lineNumbers,
+		MaxBacklinks:        *maxBacklinks,
+		Offset:              *offset,
+		MaxTwoHop:           *maxTwoHop,
+		MaxViaPerTarget:     *maxViaPerTarget,
+		SortByWeight:        *sortByWeight,
+		Positions:           *positions,
+		PerEdge:             *perEdge,
+		TagsWithLocation:    *tagLocations,
+		OutgoingTargetTypes: parseFields(*targetType),
+		IncludeSelf:         *includeSelf,
+		AllowAmbiguous:      *allowAmbiguous,
+		ReparseStale:        *reparseStale,
+		Exclude:             ef,
+	}
+	if *allowAmbiguous && *name == "" && *asset == "" {
+		return fmt.Errorf("--allow-ambiguous requires --name or --asset")
+	}
+	if *includeFrontmatter && *includeHead <= 0 {
+		return fmt.Errorf("--include-frontmatter requires --include-head")
+	}
+	if *lineNumbers && *includeSnippet <= 0 && *snippetMode != "paragraph" {
+		return fmt.Errorf("--line-numbers requires --include-snippet or --snippet-mode paragraph")
+	}
+
+	if *stream {
+		if *format != "text" {
+			return fmt.Errorf("--stream requires --format text")
+		}
+		if len(fieldList) != 1 || fieldList[0] != "backlinks" {
+			return fmt.Errorf("--stream requires --fields backlinks")
+		}
+		if *positions || *perEdge {
+			return fmt.Errorf("--stream cannot be combined with --positions or --per-edge")
+		}
+		if *allowAmbiguous {
+			return fmt.Errorf("--stream cannot be combined with --allow-ambiguous")
+		}
+		return streamQueryText(os.Stdout, *vault, entry, opts)
 	}
 
 	result, err := core.Query(*vault, entry, opts)
@@ -10,18 +10,27 @@ import (
 
 func runRepair(args []string) error {
 	fs := flag.NewFlagSet("repair", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	format := fs.String("format", "text", "output format (json or text)")
 	dryRun := fs.Bool("dry-run", false, "show what would be repaired without making changes")
+	reportOnly := fs.Bool("report-only", false, "list planned rewrites and skipped links without writing (same as --dry-run)")
+	keepBackup, cleanBackups := backupFlags(fs)
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if *reportOnly {
+		*dryRun = true
+	}
 	if err := validateFormat(*format); err != nil {
 		return err
 	}
+	if *cleanBackups {
+		return runCleanBackups(*vault, *format)
+	}
 
 	result, err := core.Repair(*vault, core.RepairOptions{
-		DryRun: *dryRun,
+		DryRun:     *dryRun,
+		KeepBackup: *keepBackup,
 	})
 	if err != nil {
 		return err
@@ -29,14 +38,14 @@ func runRepair(args []string) error {
 
 	switch *format {
 	case "json":
-		if err := printRepairJSON(os.Stdout, result); err != nil {
+		if err := printRepairJSON(summaryOut(os.Stdout), result); err != nil {
 			return err
 		}
 	default:
-		printRepairText(os.Stdout, result)
+		printRepairText(summaryOut(os.Stdout), result)
 	}
 	if !*dryRun && len(result.Rewritten) > 0 {
-		fmt.Fprintln(os.Stderr, "hint: run 'mdhop build' to create or update the index")
+		fmt.Fprintln(summaryOut(os.Stderr), "hint: run 'mdhop build' to create or update the index")
 	}
 	return nil
 }
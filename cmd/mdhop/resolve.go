@@ -3,21 +3,57 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/ryotapoi/mdhop/internal/core"
 )
 
 func runResolve(args []string) error {
 	fs := flag.NewFlagSet("resolve", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	from := fs.String("from", "", "source file (vault-relative path)")
 	link := fs.String("link", "", "link text to resolve")
 	format := fs.String("format", "text", "output format (json or text)")
 	fields := fs.String("fields", "", "comma-separated fields to output")
+	all := fs.String("all", "", "resolve every link in this file (vault-relative path)")
+	abs := fs.Bool("abs", false, "print the absolute filesystem path of the resolved note or asset")
+	withAnchor := fs.Bool("with-anchor", false, "also print the line of the #heading the link points to")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	*from = vaultPathArg(fs, *from)
+	*all = vaultPathArg(fs, *all)
+
+	if *all != "" {
+		if *abs {
+			return fmt.Errorf("--abs cannot be combined with --all")
+		}
+		if *withAnchor {
+			return fmt.Errorf("--with-anchor cannot be combined with --all")
+		}
+		if *from != "" || *link != "" {
+			return fmt.Errorf("--all cannot be combined with --from or --link")
+		}
+		if *fields != "" {
+			return fmt.Errorf("--fields cannot be combined with --all")
+		}
+		if err := validateFormat(*format); err != nil {
+			return err
+		}
+		links, err := core.ResolveAll(*vault, *all)
+		if err != nil {
+			return err
+		}
+		switch *format {
+		case "json":
+			return printResolveAllJSON(os.Stdout, links)
+		default:
+			printResolveAllText(os.Stdout, links)
+			return nil
+		}
+	}
 
 	if *from == "" {
 		return fmt.Errorf("--from is required")
@@ -29,6 +65,12 @@ func runResolve(args []string) error {
 		return err
 	}
 
+	if *abs && *fields != "" {
+		return fmt.Errorf("--fields cannot be combined with --abs")
+	}
+	if *abs && *withAnchor {
+		return fmt.Errorf("--with-anchor cannot be combined with --abs")
+	}
 	parsedFields := parseFields(*fields)
 	if err := validateFields(parsedFields, validResolveFields, "resolve"); err != nil {
 		return err
@@ -38,6 +80,13 @@ func runResolve(args []string) error {
 	if err != nil {
 		return err
 	}
+	if !*withAnchor {
+		result.Anchor = nil
+	}
+
+	if *abs {
+		return printResolveAbs(os.Stdout, *vault, result, *format)
+	}
 
 	switch *format {
 	case "json":
@@ -46,3 +95,20 @@ func runResolve(args []string) error {
 		return printResolveText(os.Stdout, result, parsedFields)
 	}
 }
+
+// printResolveAbs handles --abs: the bare absolute path in text format, or
+// the path with the target's type and existence in JSON.
+func printResolveAbs(w io.Writer, vault string, r *core.ResolveResult, format string) error {
+	if r.Type != "note" && r.Type != "asset" {
+		return fmt.Errorf("link resolves to a %s, which has no file: %s", r.Type, r.Name)
+	}
+	p, err := filepath.Abs(filepath.Join(vault, filepath.FromSlash(r.Path)))
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		return encodeJSON(w, resolveAbsJSON{Type: r.Type, Path: p, Exists: r.Exists})
+	}
+	fmt.Fprintln(w, p)
+	return nil
+}
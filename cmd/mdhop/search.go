@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	limit := fs.Int("limit", 20, "max notes to return")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	if *limit <= 0 {
+		return fmt.Errorf("--limit must be > 0")
+	}
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("search query is required")
+	}
+
+	result, err := core.Search(*vault, query, core.SearchOptions{Limit: *limit})
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		return printSearchJSON(os.Stdout, result)
+	default:
+		printSearchText(os.Stdout, result)
+		return nil
+	}
+}
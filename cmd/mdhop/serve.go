@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+// serveQueryRequest is the JSON body of /query and /backlinks: the EntrySpec
+// fields followed by the QueryOptions fields, in the CLI's snake_case.
+type serveQueryRequest struct {
+	File    string   `json:"file"`
+	Tag     string   `json:"tag"`
+	Tags    []string `json:"tags"`
+	Phantom string   `json:"phantom"`
+	Asset   string   `json:"asset"`
+	Name    string   `json:"name"`
+	Heading string   `json:"heading"`
+
+	Fields             []string `json:"fields"`
+	IncludeHead        int      `json:"include_head"`
+	IncludeFrontmatter bool     `json:"include_frontmatter"`
+	IncludeSnippet     int      `json:"include_snippet"`
+	SnippetMode        string   `json:"snippet_mode"`
+	SnippetQuery       string   `json:"snippet_query"`
+	LineNumbers        bool     `json:"line_numbers"`
+	MaxBacklinks       int      `json:"max_backlinks"`
+	Offset             int      `json:"offset"`
+	MaxTwoHop          int      `json:"max_twohop"`
+	MaxViaPerTarget    int      `json:"max_via_per_target"`
+	SortByWeight       bool     `json:"sort_by_weight"`
+	Positions          bool     `json:"positions"`
+	PerEdge            bool     `json:"per_edge"`
+	TagLocations       bool     `json:"tag_locations"`
+	TargetType         []string `json:"target_type"`
+	IncludeSelf        bool     `json:"include_self"`
+	AllowAmbiguous     bool     `json:"allow_ambiguous"`
+	ReparseStale       bool     `json:"reparse_stale"`
+	Exclude            []string `json:"exclude"`
+	ExcludeTag         []string `json:"exclude_tag"`
+	NoExclude          bool     `json:"no_exclude"`
+}
+
+// serveResolveRequest is the JSON body of /resolve. All resolves every link
+// in From, like `resolve --all`; WithAnchor is `resolve --with-anchor`.
+type serveResolveRequest struct {
+	From       string `json:"from"`
+	Link       string `json:"link"`
+	All        bool   `json:"all"`
+	WithAnchor bool   `json:"with_anchor"`
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	port := fs.Int("port", 8765, "port to listen on (127.0.0.1 only; 0 picks a free port)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *port < 0 || *port > 65535 {
+		return fmt.Errorf("--port must be between 0 and 65535")
+	}
+
+	ix, err := core.OpenIndex(*vault)
+	if err != nil {
+		return err
+	}
+	defer ix.Close()
+
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", *port))
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: newServeHandler(ix, *vault), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "mdhop: serving %s on http://%s\n", *vault, ln.Addr())
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	// Let in-flight requests finish before closing the index.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// newServeHandler returns the HTTP API over ix. Request and response bodies
+// are JSON; responses use the same shape as `--format json`.
+func newServeHandler(ix *core.Index, vault string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeServeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
+		serveQuery(w, r, ix, vault, nil)
+	})
+	mux.HandleFunc("/backlinks", func(w http.ResponseWriter, r *http.Request) {
+		serveQuery(w, r, ix, vault, []string{"backlinks"})
+	})
+	mux.HandleFunc("/resolve", func(w http.ResponseWriter, r *http.Request) {
+		var req serveResolveRequest
+		if !decodeServeRequest(w, r, &req) {
+			return
+		}
+		if req.All {
+			if req.From == "" || req.Link != "" || req.WithAnchor {
+				writeServeError(w, http.StatusBadRequest, fmt.Errorf("all requires from and no link or with_anchor"))
+				return
+			}
+			links, err := ix.ResolveAll(req.From)
+			if err != nil {
+				writeServeError(w, http.StatusUnprocessableEntity, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			printResolveAllJSON(w, links)
+			return
+		}
+		if req.From == "" || req.Link == "" {
+			writeServeError(w, http.StatusBadRequest, fmt.Errorf("from and link are required"))
+			return
+		}
+		result, err := ix.Resolve(req.From, req.Link)
+		if err != nil {
+			writeServeError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if !req.WithAnchor {
+			result.Anchor = nil
+		}
+		w.Header().Set("Content-Type", "application/json")
+		printResolveJSON(w, result, nil)
+	})
+	return mux
+}
+
+// serveQuery handles /query; fields, when set, overrides the request's fields.
+func serveQuery(w http.ResponseWriter, r *http.Request, ix *core.Index, vault string, fields []string) {
+	var req serveQueryRequest
+	if !decodeServeRequest(w, r, &req) {
+		return
+	}
+	if fields == nil {
+		fields = req.Fields
+	}
+	if err := validateFields(fields, validQueryFieldsCLI, "query"); err != nil {
+		writeServeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var cfgExclude core.ExcludeConfig
+	if !req.NoExclude {
+		cfg, err := core.LoadConfig(vault)
+		if err != nil {
+			writeServeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		cfgExclude = cfg.Exclude
+	}
+	ef, err := core.NewExcludeFilter(cfgExclude, req.Exclude, req.ExcludeTag)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := ix.Query(core.EntrySpec{
+		File:    req.File,
+		Tag:     req.Tag,
+		Tags:    req.Tags,
+		Phantom: req.Phantom,
+		Asset:   req.Asset,
+		Name:    req.Name,
+		Heading: req.Heading,
+	}, core.QueryOptions{
+		Fields:              fields,
+		IncludeHead:         req.IncludeHead,
+		IncludeFrontmatter:  req.IncludeFrontmatter,
+		IncludeSnippet:      req.IncludeSnippet,
+		SnippetMode:         req.SnippetMode,
+		SnippetQuery:        req.SnippetQuery,
+		SnippetGutter:       req.LineNumbers,
+		MaxBacklinks:        req.MaxBacklinks,
+		Offset:              req.Offset,
+		MaxTwoHop:           req.MaxTwoHop,
+		MaxViaPerTarget:     req.MaxViaPerTarget,
+		SortByWeight:        req.SortByWeight,
+		Positions:           req.Positions,
+		PerEdge:             req.PerEdge,
+		TagsWithLocation:    req.TagLocations,
+		OutgoingTargetTypes: req.TargetType,
+		IncludeSelf:         req.IncludeSelf,
+		AllowAmbiguous:      req.AllowAmbiguous,
+		ReparseStale:        req.ReparseStale,
+		Exclude:             ef,
+	})
+	if err != nil {
+		writeServeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	printQueryJSON(w, result)
+}
+
+// decodeServeRequest reads a POST JSON body into v, writing the error
+// response itself when it fails.
+func decodeServeRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeServeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed: %s", r.Method))
+		return false
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeServeError(w http.ResponseWriter, status int, err error) {
+	writeServeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encodeJSON(w, v)
+}
@@ -10,21 +10,27 @@ import (
 
 func runSimplify(args []string) error {
 	fs := flag.NewFlagSet("simplify", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	format := fs.String("format", "text", "output format (json or text)")
 	dryRun := fs.Bool("dry-run", false, "show what would be simplified without making changes")
 	var files multiString
 	fs.Var(&files, "file", "limit simplification to these source files")
+	keepBackup, cleanBackups := backupFlags(fs)
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	files = vaultPathArgs(fs, files)
 	if err := validateFormat(*format); err != nil {
 		return err
 	}
+	if *cleanBackups {
+		return runCleanBackups(*vault, *format)
+	}
 
 	result, err := core.Simplify(*vault, core.SimplifyOptions{
-		DryRun: *dryRun,
-		Files:  files,
+		DryRun:     *dryRun,
+		Files:      files,
+		KeepBackup: *keepBackup,
 	})
 	if err != nil {
 		return err
@@ -32,14 +38,14 @@ func runSimplify(args []string) error {
 
 	switch *format {
 	case "json":
-		if err := printSimplifyJSON(os.Stdout, result); err != nil {
+		if err := printSimplifyJSON(summaryOut(os.Stdout), result); err != nil {
 			return err
 		}
 	default:
-		printSimplifyText(os.Stdout, result)
+		printSimplifyText(summaryOut(os.Stdout), result)
 	}
 	if !*dryRun && len(result.Rewritten) > 0 {
-		fmt.Fprintln(os.Stderr, "hint: run 'mdhop build' to create or update the index")
+		fmt.Fprintln(summaryOut(os.Stderr), "hint: run 'mdhop build' to create or update the index")
 	}
 	return nil
 }
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+func runSplit(args []string) error {
+	fs := flag.NewFlagSet("split", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	file := fs.String("file", "", "note to split (vault-relative)")
+	heading := fs.String("heading", "", "heading of the section to extract")
+	to := fs.String("to", "", "path of the new note (vault-relative)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("--file is required")
+	}
+	if *heading == "" {
+		return fmt.Errorf("--heading is required")
+	}
+	if *to == "" {
+		return fmt.Errorf("--to is required")
+	}
+	*file = vaultPathArg(fs, *file)
+	*to = vaultPathArg(fs, *to)
+
+	result, err := core.Split(*vault, core.SplitOptions{
+		Source:  *file,
+		Heading: *heading,
+		Target:  *to,
+	})
+	if err != nil {
+		return err
+	}
+	normFile := core.NormalizePath(*file)
+	normTo := core.NormalizePath(*to)
+	switch *format {
+	case "json":
+		return printSplitJSON(summaryOut(os.Stdout), normFile, normTo, result)
+	default:
+		printSplitText(summaryOut(os.Stdout), normFile, normTo, result)
+		return nil
+	}
+}
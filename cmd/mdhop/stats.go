@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 
 	"github.com/ryotapoi/mdhop/internal/core"
@@ -9,12 +10,30 @@ import (
 
 func runStats(args []string) error {
 	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	format := fs.String("format", "text", "output format (json or text)")
 	fields := fs.String("fields", "", "comma-separated fields to output")
+	byDir := fs.Bool("by-dir", false, "group notes, edges, and orphans by directory")
+	depth := fs.Int("depth", 0, "directory depth for --by-dir (default 1; implies --by-dir)")
+	histogram := fs.Bool("histogram", false, "distribution of outgoing link counts per note")
+	incoming := fs.Bool("incoming", false, "also the distribution of incoming link counts (requires --histogram)")
+	top := fs.Int("top", 0, "list the N most-linked and most-linking notes")
+	noTags := fs.Bool("no-tags", false, "do not count tags as outgoing links for --top")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if *depth < 0 {
+		return fmt.Errorf("--depth must be positive")
+	}
+	if *incoming && !*histogram {
+		return fmt.Errorf("--incoming requires --histogram")
+	}
+	if *top < 0 {
+		return fmt.Errorf("--top must be >= 0")
+	}
+	if *noTags && *top == 0 {
+		return fmt.Errorf("--no-tags requires --top")
+	}
 
 	if err := validateFormat(*format); err != nil {
 		return err
@@ -25,7 +44,15 @@ func runStats(args []string) error {
 		return err
 	}
 
-	result, err := core.Stats(*vault, core.StatsOptions{Fields: fieldList})
+	result, err := core.Stats(*vault, core.StatsOptions{
+		Fields:    fieldList,
+		ByDir:     *byDir || *depth > 0,
+		Depth:     *depth,
+		Histogram: *histogram,
+		Incoming:  *incoming,
+		Top:       *top,
+		NoTags:    *noTags,
+	})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+func runTag(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("tag: subcommand required (add, remove)")
+	}
+	switch args[0] {
+	case "add", "remove":
+		return runTagOp(args[0], args[1:])
+	default:
+		return fmt.Errorf("tag: unknown subcommand: %s", args[0])
+	}
+}
+
+// runTagOp adds or removes a frontmatter tag across the given notes.
+func runTagOp(op string, args []string) error {
+	fs := flag.NewFlagSet("tag "+op, flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	tag := fs.String("tag", "", "tag name (# optional)")
+	var files multiString
+	fs.Var(&files, "file", "note to edit (can be specified multiple times)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	// Accept "tag add review A.md B.md" as well as --tag/--file.
+	rest := fs.Args()
+	if *tag == "" && len(rest) > 0 {
+		*tag, rest = rest[0], rest[1:]
+	}
+	files = vaultPathArgs(fs, append(files, rest...))
+	if *tag == "" {
+		return fmt.Errorf("--tag is required")
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("--file is required")
+	}
+
+	result, err := core.Tag(*vault, core.TagOptions{Op: op, Tag: *tag, Files: files})
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "json":
+		return printTagJSON(summaryOut(os.Stdout), result)
+	default:
+		printTagText(summaryOut(os.Stdout), result)
+		return nil
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+func runTags(args []string) error {
+	fs := flag.NewFlagSet("tags", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	minCount := fs.Int("min-count", 0, "only list tags used by at least N notes")
+	leafOnly := fs.Bool("leaf-only", false, "only list tags without nested tags below them")
+	tree := fs.Bool("tree", false, "group nested tags under their parent, indented")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+
+	tags, err := core.ListTags(*vault, core.TagsOptions{
+		MinCount: *minCount,
+		LeafOnly: *leafOnly,
+		Tree:     *tree,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		return printTagsJSON(os.Stdout, tags)
+	default:
+		printTagsText(os.Stdout, tags, *tree)
+		return nil
+	}
+}
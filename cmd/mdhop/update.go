@@ -4,34 +4,59 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/ryotapoi/mdhop/internal/core"
 )
 
 func runUpdate(args []string) error {
 	fs := flag.NewFlagSet("update", flag.ContinueOnError)
-	vault := fs.String("vault", ".", "vault root directory")
+	vault := fs.String("vault", defaultVault, "vault root directory")
 	format := fs.String("format", "text", "output format (json or text)")
 	var files multiString
 	fs.Var(&files, "file", "file to update (can be specified multiple times)")
+	sinceStr := fs.String("since", "", "only re-parse files modified after this time (RFC3339 or unix seconds)")
+	detectMoves := fs.Bool("detect-moves", false, "treat a missing note whose content reappears at a new path as a move")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	files = vaultPathArgs(fs, files)
 	if err := validateFormat(*format); err != nil {
 		return err
 	}
-	if len(files) == 0 {
+	var since time.Time
+	if *sinceStr != "" {
+		t, err := parseSince(*sinceStr)
+		if err != nil {
+			return err
+		}
+		since = t
+	}
+	if len(files) == 0 && since.IsZero() && !*detectMoves {
 		return fmt.Errorf("--file is required")
 	}
-	result, err := core.Update(*vault, core.UpdateOptions{Files: files})
+	result, err := core.Update(*vault, core.UpdateOptions{Files: files, Since: since, DetectMoves: *detectMoves})
 	if err != nil {
 		return err
 	}
 	switch *format {
 	case "json":
-		return printUpdateJSON(os.Stdout, result)
+		return printUpdateJSON(summaryOut(os.Stdout), result)
 	default:
-		printUpdateText(os.Stdout, result)
+		printUpdateText(summaryOut(os.Stdout), result)
 		return nil
 	}
 }
+
+// parseSince parses an RFC3339 timestamp or unix seconds.
+func parseSince(s string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since: %q (must be RFC3339 or unix seconds)", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ryotapoi/mdhop/internal/core"
+)
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	vault := fs.String("vault", defaultVault, "vault root directory")
+	format := fs.String("format", "text", "output format (json or text)")
+	fast := fs.Bool("fast", false, "only compare note count and newest mtime against the disk")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+
+	if *fast {
+		return runVerifyFast(*vault, *format)
+	}
+
+	result, err := core.Verify(*vault)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		err = printVerifyJSON(os.Stdout, result)
+	default:
+		err = printVerifyText(os.Stdout, result)
+	}
+	if err != nil {
+		return err
+	}
+	if !result.OK() {
+		return fmt.Errorf("index verification failed: %d issue(s)", len(result.Issues))
+	}
+	return nil
+}
+
+func runVerifyFast(vault, format string) error {
+	result, err := core.VerifyFast(vault)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		err = printVerifyFastJSON(os.Stdout, result)
+	default:
+		err = printVerifyFastText(os.Stdout, result)
+	}
+	if err != nil {
+		return err
+	}
+	if !result.UpToDate() {
+		return fmt.Errorf("index is stale: run 'mdhop build'")
+	}
+	return nil
+}
@@ -12,6 +12,8 @@ import (
 type AddOptions struct {
 	Files            []string
 	AutoDisambiguate bool
+	KeepBackup       bool // as MoveOptions.KeepBackup, for files rewritten by AutoDisambiguate
+	PreserveMtime    bool // as MoveOptions.PreserveMtime, for files rewritten by AutoDisambiguate
 }
 
 // RewrittenLink records a single link rewrite performed by auto-disambiguate.
@@ -36,10 +38,24 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
 	}
 
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	return addLocked(vaultPath, opts)
+}
+
+// addLocked is Add for a caller that already holds the exclusive index lock.
+func addLocked(vaultPath string, opts AddOptions) (*AddResult, error) {
+	dbp := dbPath(vaultPath)
+
 	// Normalize and deduplicate input paths.
 	type addFile struct {
-		path  string
-		mtime int64
+		path    string
+		mtime   int64
+		isAsset bool
 	}
 	seen := make(map[string]bool)
 	var files []addFile
@@ -49,7 +65,7 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 			continue
 		}
 		seen[np] = true
-		files = append(files, addFile{path: np})
+		files = append(files, addFile{path: np, isAsset: !strings.HasSuffix(strings.ToLower(np), ".md")})
 	}
 
 	db, err := openDBAt(dbp)
@@ -57,12 +73,23 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 		return nil, err
 	}
 	defer db.Close()
+	if err := ensureEdgeCol(db); err != nil {
+		return nil, err
+	}
+
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check that no file is already registered.
 	for _, f := range files {
-		key := noteKey(f.path)
+		key, typ := noteKey(f.path), "note"
+		if f.isAsset {
+			key, typ = assetKey(f.path), "asset"
+		}
 		var id int64
-		err := db.QueryRow("SELECT id FROM nodes WHERE node_key = ? AND type = 'note'", key).Scan(&id)
+		err := db.QueryRow("SELECT id FROM nodes WHERE node_key = ? AND type = ?", key, typ).Scan(&id)
 		if err == nil {
 			return nil, fmt.Errorf("file already registered: %s", f.path)
 		}
@@ -88,13 +115,27 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	rm.applyBuildConfig(cfg.Build)
 	oldBasenameCounts := make(map[string]int, len(rm.basenameCounts))
 	for k, v := range rm.basenameCounts {
 		oldBasenameCounts[k] = v
 	}
+	oldAssetBasenameCounts := make(map[string]int, len(rm.assetBasenameCounts))
+	for k, v := range rm.assetBasenameCounts {
+		oldAssetBasenameCounts[k] = v
+	}
 
 	// Adjust maps for post-add state.
 	for _, f := range files {
+		if f.isAsset {
+			rm.assetPathSet[strings.ToLower(f.path)] = f.path
+			abk := assetBasenameKey(f.path)
+			rm.assetBasenameCounts[abk]++
+			if isRootFile(f.path) {
+				rm.assetRootBasenameToPath[abk] = f.path
+			}
+			continue
+		}
 		rel := strings.ToLower(f.path)
 		rm.pathSet[rel] = f.path
 		noExt := strings.TrimSuffix(f.path, filepath.Ext(f.path))
@@ -107,103 +148,140 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 	}
 
 	// Check if adding causes existing links to become ambiguous.
-	// Build oldBasenameToPath for pattern A detection.
-	oldBasenameToPath := make(map[string]string)
-	for bk, count := range oldBasenameCounts {
-		if count == 1 {
-			for p := range rm.pathToID {
-				if basenameKey(p) == bk {
-					oldBasenameToPath[bk] = p
-					break
-				}
-			}
-		}
+	// Notes and assets are checked separately since their basename keys
+	// live in separate namespaces ("A" vs "image.png").
+	type basenameSpace struct {
+		isAsset   bool
+		key       func(string) string
+		counts    map[string]int
+		oldCounts map[string]int
+		pathToID  map[string]int64
+	}
+	spaces := []basenameSpace{
+		{false, basenameKey, rm.basenameCounts, oldBasenameCounts, rm.pathToID},
+		{true, assetBasenameKey, rm.assetBasenameCounts, oldAssetBasenameCounts, rm.assetPathToID},
 	}
 
 	var allRewrites []rewriteEntry
 
-	for bk, newCount := range rm.basenameCounts {
-		if newCount <= 1 {
-			continue
-		}
-		oldCount := oldBasenameCounts[bk]
-		if oldCount >= 2 {
-			continue // already ambiguous before add
-		}
-
-		// Find the target node that existing basename links pointed to.
-		var targetID int64
-		var isPatternA bool
-		if oldCount == 1 {
-			// Pattern A: existing unique note becomes ambiguous.
-			oldTarget := oldBasenameToPath[bk]
-			if isRootFile(oldTarget) {
-				continue // Root-priority: [[A]] still resolves to root A.md → no ambiguity.
-			}
-			targetID = rm.pathToID[oldTarget]
-			isPatternA = true
-		} else {
-			// Pattern B: phantom (oldCount == 0, adding 2+ files with same basename).
-			// Check if any of the new files is at root → root priority resolves.
-			hasNewRoot := false
-			for _, f := range files {
-				if basenameKey(f.path) == bk && isRootFile(f.path) {
-					hasNewRoot = true
-					break
+	for _, sp := range spaces {
+		// Build oldBasenameToPath for pattern A detection.
+		oldBasenameToPath := make(map[string]string)
+		for bk, count := range sp.oldCounts {
+			if count == 1 {
+				for p := range sp.pathToID {
+					if sp.key(p) == bk {
+						oldBasenameToPath[bk] = p
+						break
+					}
 				}
 			}
-			if hasNewRoot {
-				continue // Root priority: [[A]] resolves to root file → not ambiguous.
+		}
+
+		for bk, newCount := range sp.counts {
+			if newCount <= 1 {
+				continue
 			}
-			pk := phantomKey(bk)
-			err := db.QueryRow("SELECT id FROM nodes WHERE node_key = ?", pk).Scan(&targetID)
-			if err == sql.ErrNoRows {
-				continue // no phantom, so no existing basename links
+			oldCount := sp.oldCounts[bk]
+			if oldCount >= 2 {
+				continue // already ambiguous before add
 			}
-			if err != nil {
-				return nil, err
+
+			// Find the target node that existing basename links pointed to.
+			var targetID int64
+			var isPatternA bool
+			if oldCount == 1 {
+				// Pattern A: existing unique note (or asset) becomes ambiguous.
+				oldTarget := oldBasenameToPath[bk]
+				if isRootFile(oldTarget) && !rm.noRootPriority {
+					continue // Root-priority: [[A]] still resolves to root A.md → no ambiguity.
+				}
+				targetID = sp.pathToID[oldTarget]
+				// A root file's path link is its basename link, so there is
+				// nothing to disambiguate to without root priority.
+				isPatternA = !isRootFile(oldTarget)
+			} else {
+				// Pattern B: phantom (oldCount == 0, adding 2+ files with same basename).
+				// Check if any of the new files is at root → root priority resolves.
+				hasNewRoot := false
+				for _, f := range files {
+					if f.isAsset == sp.isAsset && sp.key(f.path) == bk && isRootFile(f.path) {
+						hasNewRoot = true
+						break
+					}
+				}
+				if hasNewRoot && !rm.noRootPriority {
+					continue // Root priority: [[A]] resolves to root file → not ambiguous.
+				}
+				pk := phantomKey(bk)
+				err := db.QueryRow("SELECT id FROM nodes WHERE node_key = ?", pk).Scan(&targetID)
+				if err == sql.ErrNoRows {
+					continue // no phantom, so no existing basename links
+				}
+				if err != nil {
+					return nil, err
+				}
 			}
-		}
 
-		// Query edges with source info for potential rewriting.
-		rows, err := db.Query(
-			`SELECT e.id, e.raw_link, e.link_type, e.line_start, sn.path, sn.id
+			// Query edges with source info for potential rewriting.
+			rows, err := db.Query(
+				`SELECT e.id, e.raw_link, e.link_type, e.line_start, COALESCE(e.col, -1), sn.path, sn.id
 			 FROM edges e JOIN nodes sn ON sn.id = e.source_id AND sn.exists_flag = 1
 			 WHERE e.target_id = ?
-			 AND e.link_type IN ('wikilink', 'markdown')`, targetID)
-		if err != nil {
-			return nil, err
-		}
-		var basenameEdges []rewriteEntry
-		for rows.Next() {
-			var re rewriteEntry
-			if err := rows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.sourcePath, &re.sourceID); err != nil {
-				rows.Close()
+			 AND e.link_type IN ('wikilink', 'markdown', 'frontmatter-link')`, targetID)
+			if err != nil {
+				return nil, err
+			}
+			var basenameEdges []rewriteEntry
+			for rows.Next() {
+				var re rewriteEntry
+				if err := rows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.col, &re.sourcePath, &re.sourceID); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				if isBasenameRawLink(re.rawLink, re.linkType) {
+					basenameEdges = append(basenameEdges, re)
+				}
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
 				return nil, err
 			}
-			if isBasenameRawLink(re.rawLink, re.linkType) {
-				basenameEdges = append(basenameEdges, re)
+
+			if len(basenameEdges) == 0 {
+				continue
 			}
-		}
-		rows.Close()
-		if err := rows.Err(); err != nil {
-			return nil, err
-		}
 
-		if len(basenameEdges) == 0 {
-			continue
+			if isPatternA && opts.AutoDisambiguate {
+				// Compute new raw links for each edge.
+				oldTarget := oldBasenameToPath[bk]
+				for i := range basenameEdges {
+					basenameEdges[i].newRawLink = rewriteRawLink(basenameEdges[i].rawLink, basenameEdges[i].linkType, oldTarget)
+				}
+				allRewrites = append(allRewrites, basenameEdges...)
+			} else {
+				// Pattern B or auto-disambiguate not enabled → error.
+				return nil, fmt.Errorf("adding files would make existing links ambiguous")
+			}
 		}
+	}
 
-		if isPatternA && opts.AutoDisambiguate {
-			// Compute new raw links for each edge.
-			oldTarget := oldBasenameToPath[bk]
-			for i := range basenameEdges {
-				basenameEdges[i].newRawLink = rewriteRawLink(basenameEdges[i].rawLink, basenameEdges[i].linkType, oldTarget)
+	// Folder-first: a new note would take over basename links from its folder
+	// that name the root note of the same basename. A root note's path link is
+	// its basename link, so there is nothing to rewrite them to.
+	if rm.folderFirst {
+		var notes []string
+		for _, f := range files {
+			if !f.isAsset {
+				notes = append(notes, f.path)
 			}
-			allRewrites = append(allRewrites, basenameEdges...)
-		} else {
-			// Pattern B or auto-disambiguate not enabled → error.
-			return nil, fmt.Errorf("adding files would make existing links ambiguous")
+		}
+		captured, err := folderCapturedLink(db, notes, nil)
+		if err != nil {
+			return nil, err
+		}
+		if captured != nil {
+			return nil, fmt.Errorf("adding files would change the target of existing links: %s in %s", captured.rawLink, captured.sourcePath)
 		}
 	}
 
@@ -246,29 +324,50 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 		// Search new files if not found.
 		if _, ok := rm.basenameToPath[bk]; !ok {
 			for _, f := range files {
-				if basenameKey(f.path) == bk {
+				if !f.isAsset && basenameKey(f.path) == bk {
 					rm.basenameToPath[bk] = f.path
 					break
 				}
 			}
 		}
 	}
+	// Rebuild assetBasenameToPath likewise.
+	rm.assetBasenameToPath = make(map[string]string)
+	for p := range rm.assetPathToID {
+		if abk := assetBasenameKey(p); rm.assetBasenameCounts[abk] == 1 {
+			rm.assetBasenameToPath[abk] = p
+		}
+	}
+	for _, f := range files {
+		if abk := assetBasenameKey(f.path); f.isAsset && rm.assetBasenameCounts[abk] == 1 {
+			rm.assetBasenameToPath[abk] = f.path
+		}
+	}
 
 	// Parse all new files and check for ambiguous links.
 	type parsedFile struct {
-		file  addFile
-		links []linkOccur
+		file     addFile
+		links    []linkOccur
+		headings []headingOccur
+		external []externalLinkOccur
+		aliases  []string
+		body     string
 	}
 	var parsed []parsedFile
 	for _, f := range files {
+		if f.isAsset {
+			// Assets carry no links; they only need a node.
+			parsed = append(parsed, parsedFile{file: f})
+			continue
+		}
 		content, err := os.ReadFile(filepath.Join(vaultPath, f.path))
 		if err != nil {
 			return nil, err
 		}
-		links := parseLinks(string(content))
+		links := parseIndexLinks(string(content), cfg.Build)
 
 		for _, link := range links {
-			if link.linkType != "wikilink" && link.linkType != "markdown" {
+			if !isFileLinkType(link.linkType) {
 				continue
 			}
 			if link.isRelative && escapesVault(f.path, link.target) {
@@ -277,12 +376,22 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 			if !link.isRelative && !link.isBasename && pathEscapesVault(link.target) {
 				return nil, fmt.Errorf("link escapes vault: %s in %s", link.rawLink, f.path)
 			}
-			if link.isBasename && isAmbiguousBasenameLink(link.target, rm) {
+			if link.isBasename && isAmbiguousBasenameLink(f.path, link.target, rm) {
 				return nil, fmt.Errorf("ambiguous link: %s in %s", link.target, f.path)
 			}
+			if isAmbiguousFolderNoteLink(f.path, link, rm) {
+				return nil, fmt.Errorf("ambiguous link: %s in %s (note and folder note)", link.target, f.path)
+			}
 		}
 
-		parsed = append(parsed, parsedFile{file: f, links: links})
+		parsed = append(parsed, parsedFile{
+			file:     f,
+			links:    links,
+			headings: parseHeadings(string(content)),
+			external: parseExternalLinks(string(content)),
+			aliases:  parseAliases(string(content)),
+			body:     string(content),
+		})
 	}
 
 	// Apply disk rewrites before transaction (so DB rollback is safe).
@@ -296,7 +405,7 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 			groups[re.sourcePath] = append(groups[re.sourcePath], re)
 		}
 		var applyErr error
-		newMtimes, backups, applyErr = applyFileRewrites(vaultPath, groups)
+		newMtimes, backups, applyErr = applyFileRewrites(vaultPath, groups, opts.PreserveMtime || cfg.Rewrite.PreserveMtime)
 		if applyErr != nil {
 			return nil, applyErr
 		}
@@ -319,39 +428,77 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 	}()
 
 	result := &AddResult{}
+	nt, err := newNoteText(tx)
+	if err != nil {
+		return nil, err
+	}
 
-	// Insert all note nodes.
+	// Insert all note and asset nodes.
 	for _, pf := range parsed {
+		if pf.file.isAsset {
+			id, err := upsertAsset(tx, pf.file.path, filepath.Base(pf.file.path), pf.file.mtime)
+			if err != nil {
+				return nil, err
+			}
+			rm.assetPathToID[pf.file.path] = id
+			result.Added = append(result.Added, pf.file.path)
+			continue
+		}
 		name := basename(pf.file.path)
 		id, err := upsertNote(tx, pf.file.path, name, pf.file.mtime)
 		if err != nil {
 			return nil, err
 		}
 		rm.pathToID[pf.file.path] = id
+		if err := replaceHeadings(tx, id, pf.headings); err != nil {
+			return nil, err
+		}
+		if err := replaceExternalLinks(tx, id, pf.external); err != nil {
+			return nil, err
+		}
+		if err := replaceAliases(tx, id, pf.aliases); err != nil {
+			return nil, err
+		}
+		if err := nt.replace(tx, id, pf.body); err != nil {
+			return nil, err
+		}
 		result.Added = append(result.Added, pf.file.path)
 	}
 
+	for _, pf := range parsed {
+		if len(pf.aliases) > 0 {
+			setNoteAliases(rm, pf.file.path, pf.aliases)
+		}
+	}
+
 	// Phantom → note promotion (root-priority aware).
 	// When multiple files share a basename, prefer root file for phantom promotion.
-	rootForBasename := make(map[string]string) // bk → root file path
+	// Asset phantoms are keyed by filename with extension ("image.png").
+	promoteName := func(f addFile) string {
+		if f.isAsset {
+			return filepath.Base(f.path)
+		}
+		return basename(f.path)
+	}
+	rootForBasename := make(map[string]string) // phantom key → root file path
 	for _, pf := range parsed {
-		bk := basenameKey(pf.file.path)
+		pk := phantomKey(promoteName(pf.file))
 		if isRootFile(pf.file.path) {
-			rootForBasename[bk] = pf.file.path
+			rootForBasename[pk] = pf.file.path
 		}
 	}
 	promotedBasenames := make(map[string]bool)
+	promotedPaths := make(map[string]bool)
 	for _, pf := range parsed {
-		bk := basenameKey(pf.file.path)
-		if promotedBasenames[bk] {
+		pk := phantomKey(promoteName(pf.file))
+		if promotedBasenames[pk] {
 			continue
 		}
 		// If a root file exists for this basename but this isn't it, skip (root will promote).
-		if rp, ok := rootForBasename[bk]; ok && rp != pf.file.path {
+		if rp, ok := rootForBasename[pk]; ok && rp != pf.file.path {
 			continue
 		}
 
-		pk := phantomKey(basename(pf.file.path))
 		var phantomID int64
 		err := tx.QueryRow("SELECT id FROM nodes WHERE node_key = ?", pk).Scan(&phantomID)
 		if err == sql.ErrNoRows {
@@ -362,6 +509,9 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 		}
 
 		noteID := rm.pathToID[pf.file.path]
+		if pf.file.isAsset {
+			noteID = rm.assetPathToID[pf.file.path]
+		}
 
 		// Reassign incoming edges from phantom to note.
 		if _, err := tx.Exec("UPDATE edges SET target_id = ? WHERE target_id = ?", noteID, phantomID); err != nil {
@@ -373,10 +523,26 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 			return nil, err
 		}
 
-		promotedBasenames[bk] = true
+		promotedBasenames[pk] = true
+		promotedPaths[pf.file.path] = true
 		result.Promoted = append(result.Promoted, pf.file.path)
 	}
 
+	// Alias phantom → note promotion: [[Missing]] and a new note declaring
+	// "aliases: [Missing]".
+	for _, pf := range parsed {
+		if pf.file.isAsset {
+			continue
+		}
+		promoted, err := promoteAliasPhantoms(tx, rm, pf.file.path)
+		if err != nil {
+			return nil, err
+		}
+		if promoted && !promotedPaths[pf.file.path] {
+			result.Promoted = append(result.Promoted, pf.file.path)
+		}
+	}
+
 	// Resolve links and create edges.
 	for _, pf := range parsed {
 		sourceID := rm.pathToID[pf.file.path]
@@ -388,7 +554,7 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 			if targetID == 0 {
 				continue
 			}
-			if err := insertEdge(tx, sourceID, targetID, link.linkType, link.rawLink, subpath, link.lineStart, link.lineEnd); err != nil {
+			if err := insertEdge(tx, sourceID, targetID, link, subpath); err != nil {
 				return nil, err
 			}
 		}
@@ -406,6 +572,9 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 				NewLink: re.newRawLink,
 			})
 		}
+		if err := shiftEdgeCols(tx, allRewrites); err != nil {
+			return nil, err
+		}
 		// Update source mtime in DB.
 		mtimeUpdated := make(map[int64]bool)
 		for _, re := range allRewrites {
@@ -417,6 +586,9 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 			if _, err := tx.Exec("UPDATE nodes SET mtime = ? WHERE id = ? AND type = 'note'", mt, re.sourceID); err != nil {
 				return nil, err
 			}
+			if err := nt.refresh(tx, vaultPath, re.sourceID, re.sourcePath); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -426,10 +598,62 @@ func Add(vaultPath string, opts AddOptions) (*AddResult, error) {
 	}
 
 	// Commit.
+	if err := bumpIndexVersion(tx); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 	committed = true
+	verbosef("add: committed %d added, %d promoted, %d rewritten", len(result.Added), len(result.Promoted), len(result.Rewritten))
+
+	if opts.KeepBackup {
+		if err := keepBackups(vaultPath, backups); err != nil {
+			return nil, err
+		}
+	}
 
 	return result, nil
 }
+
+// folderCapturedLink returns the first basename link that, under
+// folder-first resolution, one of the new note paths would take over: a link
+// from a note in the same folder naming another note of that basename. Links
+// from or to the notes being moved (pre-move paths) are skipped. It returns
+// nil when there is none.
+func folderCapturedLink(db dbExecer, notes []string, moved map[string]bool) (*rewriteEntry, error) {
+	added := make(map[string]bool, len(notes)) // lowercase paths
+	for _, p := range notes {
+		added[strings.ToLower(p)] = true
+	}
+
+	rows, err := db.Query(
+		`SELECT e.id, e.raw_link, e.link_type, e.line_start, COALESCE(e.col, -1), sn.path, sn.id, tn.path
+		 FROM edges e
+		 JOIN nodes sn ON sn.id = e.source_id AND sn.exists_flag = 1
+		 JOIN nodes tn ON tn.id = e.target_id AND tn.type = 'note'
+		 WHERE e.link_type IN ('wikilink', 'markdown', 'frontmatter-link')
+		 ORDER BY sn.path, e.line_start`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var re rewriteEntry
+		var targetPath string
+		if err := rows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.col, &re.sourcePath, &re.sourceID, &targetPath); err != nil {
+			return nil, err
+		}
+		if moved[re.sourcePath] || moved[targetPath] || !isBasenameRawLink(re.rawLink, re.linkType) {
+			continue
+		}
+		bk := basenameKey(targetPath)
+		if strings.ToLower(rawLinkTarget(re.rawLink, re.linkType)) != bk {
+			continue // alias link
+		}
+		if added[localNoteKey(re.sourcePath, bk)] {
+			return &re, nil
+		}
+	}
+	return nil, rows.Err()
+}
@@ -1175,3 +1175,101 @@ func TestAddAutoDisambiguateSubdirTarget(t *testing.T) {
 		t.Fatalf("rebuild after auto-disambiguate: %v", err)
 	}
 }
+
+func TestAddAutoDisambiguateAsset(t *testing.T) {
+	// Old unique asset sub/diagram.png. Adding other/diagram.png makes
+	// ![[diagram.png]] ambiguous → rewritten to the full asset path.
+	vault := copyVault(t, "vault_add_asset_disambiguate")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(vault, "other"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "other", "diagram.png"), []byte("PNG"), 0o644); err != nil {
+		t.Fatalf("write other/diagram.png: %v", err)
+	}
+
+	result, err := Add(vault, AddOptions{
+		Files:            []string{"other/diagram.png"},
+		AutoDisambiguate: true,
+	})
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if len(result.Added) != 1 || result.Added[0] != "other/diagram.png" {
+		t.Errorf("Added = %v, want [other/diagram.png]", result.Added)
+	}
+	if len(result.Rewritten) != 2 {
+		t.Fatalf("Rewritten = %d, want 2", len(result.Rewritten))
+	}
+
+	content, err := os.ReadFile(filepath.Join(vault, "A.md"))
+	if err != nil {
+		t.Fatalf("read A.md: %v", err)
+	}
+	lines := strings.Split(string(content), "\n")
+	if lines[0] != "![[sub/diagram.png]]" {
+		t.Errorf("line 1 = %q, want ![[sub/diagram.png]]", lines[0])
+	}
+	if lines[1] != "[img](sub/diagram.png)" {
+		t.Errorf("line 2 = %q, want [img](sub/diagram.png)", lines[1])
+	}
+
+	// Unreferenced assets are not kept as nodes; the edge raw_link is updated.
+	edges := queryEdges(t, dbPath(vault), "A.md")
+	if len(edges) != 2 {
+		t.Fatalf("edges = %d, want 2", len(edges))
+	}
+	for _, e := range edges {
+		if e.targetKey != "asset:path:sub/diagram.png" || !strings.Contains(e.rawLink, "sub/diagram.png") {
+			t.Errorf("edge = %s %q, want target sub/diagram.png", e.targetKey, e.rawLink)
+		}
+	}
+
+	// Rebuild should succeed.
+	if err := Build(vault); err != nil {
+		t.Fatalf("rebuild after auto-disambiguate: %v", err)
+	}
+}
+
+func TestAddAutoDisambiguateAssetRootTarget(t *testing.T) {
+	// Old asset at root → root priority keeps ![[diagram.png]] unambiguous.
+	vault := copyVault(t, "vault_add_asset_disambiguate")
+	if err := os.Rename(filepath.Join(vault, "sub", "diagram.png"), filepath.Join(vault, "diagram.png")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "sub", "diagram.png"), []byte("PNG"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Add(vault, AddOptions{
+		Files:            []string{"sub/diagram.png"},
+		AutoDisambiguate: true,
+	})
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if len(result.Rewritten) != 0 {
+		t.Errorf("Rewritten = %v, want none", result.Rewritten)
+	}
+}
+
+func TestAddAssetPatternANoAutoDisambiguate(t *testing.T) {
+	vault := copyVault(t, "vault_add_asset_disambiguate")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "diagram.png"), []byte("PNG"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Add(vault, AddOptions{Files: []string{"diagram.png"}})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous error, got %v", err)
+	}
+}
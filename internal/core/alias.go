@@ -0,0 +1,184 @@
+package core
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Basename links fall back to frontmatter aliases: [[Missing]] resolves to the
+// note declaring "aliases: [Missing]" when no note or asset has that basename
+// and exactly one note claims the alias. Otherwise it stays a phantom.
+
+// aliasTargets maps each lowercase alias claimed by exactly one note to that
+// note's path. An alias equal to the note's own basename adds nothing.
+func aliasTargets(noteAliases map[string][]string) map[string]string {
+	owners := make(map[string]map[string]bool)
+	for path, aliases := range noteAliases {
+		for _, a := range aliases {
+			lower := strings.ToLower(a)
+			if lower == "" || lower == basenameKey(path) {
+				continue
+			}
+			if owners[lower] == nil {
+				owners[lower] = make(map[string]bool)
+			}
+			owners[lower][path] = true
+		}
+	}
+	out := make(map[string]string, len(owners))
+	for lower, paths := range owners {
+		if len(paths) != 1 {
+			continue
+		}
+		for p := range paths {
+			out[lower] = p
+		}
+	}
+	return out
+}
+
+// setNoteAliases records path's aliases in rm and recomputes rm.aliasToPath.
+// A nil aliases removes the note's entry.
+func setNoteAliases(rm *resolveMaps, path string, aliases []string) {
+	if rm.noteAliases == nil {
+		rm.noteAliases = make(map[string][]string)
+	}
+	if len(aliases) == 0 {
+		delete(rm.noteAliases, path)
+	} else {
+		rm.noteAliases[path] = aliases
+	}
+	rm.aliasToPath = aliasTargets(rm.noteAliases)
+}
+
+// renameNoteAliases moves the aliases recorded for from to to.
+func renameNoteAliases(rm *resolveMaps, from, to string) {
+	aliases, ok := rm.noteAliases[from]
+	if !ok {
+		return
+	}
+	delete(rm.noteAliases, from)
+	setNoteAliases(rm, to, aliases)
+}
+
+// aliasResolvesTo reports whether a basename link naming alias resolves to
+// the note at path: the alias is unique to it and no note or asset basename
+// takes precedence.
+func aliasResolvesTo(rm *resolveMaps, alias, path string) bool {
+	lower := strings.ToLower(alias)
+	if rm.basenameCounts[lower] > 0 || rm.assetBasenameCounts[lower] > 0 {
+		return false
+	}
+	return rm.aliasToPath[lower] == path
+}
+
+// droppedAliases returns the entries of old missing from cur (case-insensitive).
+func droppedAliases(old, cur []string) []string {
+	keep := make(map[string]bool, len(cur))
+	for _, a := range cur {
+		keep[strings.ToLower(a)] = true
+	}
+	var out []string
+	for _, a := range old {
+		if !keep[strings.ToLower(a)] {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// promoteAliasPhantoms retargets the incoming edges of phantoms named by the
+// note's aliases to the note and deletes those phantoms. Returns whether any
+// phantom was promoted.
+func promoteAliasPhantoms(tx dbExecer, rm *resolveMaps, path string) (bool, error) {
+	noteID := rm.pathToID[path]
+	promoted := false
+	for _, a := range rm.noteAliases[path] {
+		if !aliasResolvesTo(rm, a, path) {
+			continue
+		}
+		var phantomID int64
+		err := tx.QueryRow("SELECT id FROM nodes WHERE node_key = ? AND type = 'phantom'", phantomKey(a)).Scan(&phantomID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if _, err := tx.Exec("UPDATE edges SET target_id = ? WHERE target_id = ?", noteID, phantomID); err != nil {
+			return false, err
+		}
+		if _, err := tx.Exec("DELETE FROM nodes WHERE id = ?", phantomID); err != nil {
+			return false, err
+		}
+		promoted = true
+	}
+	return promoted, nil
+}
+
+// demoteAliasEdges moves the edges that reach noteID through one of aliases
+// (basename links naming the alias rather than the note) to a phantom for
+// the alias, as a rebuild would once the alias is gone.
+func demoteAliasEdges(tx dbExecer, noteID int64, aliases []string) error {
+	if len(aliases) == 0 {
+		return nil
+	}
+	want := make(map[string]bool, len(aliases))
+	for _, a := range aliases {
+		want[strings.ToLower(a)] = true
+	}
+
+	rows, err := tx.Query(
+		`SELECT id, raw_link, link_type FROM edges
+		 WHERE target_id = ? AND link_type IN ('wikilink', 'markdown', 'frontmatter-link')`, noteID)
+	if err != nil {
+		return err
+	}
+	type aliasEdge struct {
+		id     int64
+		target string
+	}
+	var moves []aliasEdge
+	for rows.Next() {
+		var id int64
+		var rawLink, linkType string
+		if err := rows.Scan(&id, &rawLink, &linkType); err != nil {
+			rows.Close()
+			return err
+		}
+		if !isBasenameRawLink(rawLink, linkType) {
+			continue
+		}
+		if target := rawLinkTarget(rawLink, linkType); want[strings.ToLower(target)] {
+			moves = append(moves, aliasEdge{id: id, target: target})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range moves {
+		phantomID, err := upsertPhantom(tx, m.target)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE edges SET target_id = ? WHERE id = ?", phantomID, m.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rawLinkTarget returns the link target of a stored raw link, without
+// subpath, alias, or ".md" (e.g. "[[Note#H|a]]" → "Note").
+func rawLinkTarget(rawLink, linkType string) string {
+	if linkType == "frontmatter-link" {
+		target, _ := extractSubpath(rawLink)
+		return normalizeBasename(target)
+	}
+	if links := parseLinks(rawLink); len(links) > 0 {
+		return links[0].target
+	}
+	return ""
+}
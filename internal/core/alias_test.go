@@ -0,0 +1,176 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// aliasEdgeTargets returns targetKey for each edge from sourcePath, keyed by raw link.
+func aliasEdgeTargets(t *testing.T, vault, sourcePath string) map[string]string {
+	t.Helper()
+	out := make(map[string]string)
+	for _, e := range queryEdges(t, dbPath(vault), sourcePath) {
+		out[e.rawLink] = e.targetKey
+	}
+	return out
+}
+
+func TestAdd_AliasPromotesPhantom(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "[[Missing]] and [[missing#Sec]]\n",
+		"B.md": "[m](Missing.md)\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	phantoms := queryNodes(t, dbPath(vault), "phantom")
+	if len(phantoms) != 1 || phantoms[0].nodeKey != phantomKey("Missing") {
+		t.Fatalf("phantoms before add = %+v, want one Missing phantom", phantoms)
+	}
+
+	writeVaultFiles(t, vault, map[string]string{
+		"notes/Real.md": "---\naliases: [Missing]\n---\n# Real\n",
+	})
+	result, err := Add(vault, AddOptions{Files: []string{"notes/Real.md"}})
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if len(result.Promoted) != 1 || result.Promoted[0] != "notes/Real.md" {
+		t.Errorf("Promoted = %v, want [notes/Real.md]", result.Promoted)
+	}
+
+	want := noteKey("notes/Real.md")
+	for src, n := range map[string]int{"A.md": 2, "B.md": 1} {
+		targets := aliasEdgeTargets(t, vault, src)
+		if len(targets) != n {
+			t.Errorf("%s: got %d edges, want %d", src, len(targets), n)
+		}
+		for raw, key := range targets {
+			if key != want {
+				t.Errorf("%s: %s → %s, want %s", src, raw, key, want)
+			}
+		}
+	}
+	if phantoms := queryNodes(t, dbPath(vault), "phantom"); len(phantoms) != 0 {
+		t.Errorf("phantoms after add = %+v, want none", phantoms)
+	}
+
+	// The incremental result matches a rebuild.
+	if err := Build(vault); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	for raw, key := range aliasEdgeTargets(t, vault, "A.md") {
+		if key != want {
+			t.Errorf("rebuild: %s → %s, want %s", raw, key, want)
+		}
+	}
+}
+
+func TestBuild_AliasFallback(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"Real.md":  "---\naliases: [Nick, Shared, Other]\n---\n",
+		"Dup.md":   "---\naliases: [Shared]\n---\n",
+		"Other.md": "# Other\n",
+		"A.md":     "[[Nick]] [[Shared]] [[Other]]\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	targets := aliasEdgeTargets(t, vault, "A.md")
+	if got := targets["[[Nick]]"]; got != noteKey("Real.md") {
+		t.Errorf("[[Nick]] → %s, want Real.md", got)
+	}
+	// An alias claimed by two notes does not resolve.
+	if got := targets["[[Shared]]"]; got != phantomKey("Shared") {
+		t.Errorf("[[Shared]] → %s, want phantom", got)
+	}
+	// A note basename wins over an alias.
+	if got := targets["[[Other]]"]; got != noteKey("Other.md") {
+		t.Errorf("[[Other]] → %s, want Other.md", got)
+	}
+
+	res, err := Resolve(vault, "A.md", "[[Nick]]")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if res.Type != "note" || res.Path != "Real.md" {
+		t.Errorf("resolve [[Nick]] = %+v, want note Real.md", res)
+	}
+}
+
+func TestDelete_AliasEdgesBecomePhantom(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"Real.md": "---\naliases: [Nick]\n---\n",
+		"A.md":    "[[Nick]] [[Real]]\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if err := os.Remove(filepath.Join(vault, "Real.md")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Delete(vault, DeleteOptions{Files: []string{"Real.md"}}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	targets := aliasEdgeTargets(t, vault, "A.md")
+	if got := targets["[[Nick]]"]; got != phantomKey("Nick") {
+		t.Errorf("[[Nick]] → %s, want phantom Nick", got)
+	}
+	if got := targets["[[Real]]"]; got != phantomKey("Real") {
+		t.Errorf("[[Real]] → %s, want phantom Real", got)
+	}
+}
+
+func TestUpdate_AliasChanges(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"Real.md": "---\naliases: [Old]\n---\n",
+		"A.md":    "[[Old]] [[New]]\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	writeVaultFiles(t, vault, map[string]string{"Real.md": "---\naliases: [New]\n---\n"})
+	if _, err := Update(vault, UpdateOptions{Files: []string{"Real.md"}}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	targets := aliasEdgeTargets(t, vault, "A.md")
+	if got := targets["[[Old]]"]; got != phantomKey("Old") {
+		t.Errorf("[[Old]] → %s, want phantom Old", got)
+	}
+	if got := targets["[[New]]"]; got != noteKey("Real.md") {
+		t.Errorf("[[New]] → %s, want Real.md", got)
+	}
+}
+
+func TestMove_KeepsAliasLinks(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"Real.md": "---\naliases: [Nick]\n---\n",
+		"A.md":    "[[Nick]] [[Real]]\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if _, err := Move(vault, MoveOptions{From: "Real.md", To: "sub/Renamed.md"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(vault, "A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), "[[Nick]] ") {
+		t.Errorf("A.md = %q, alias link should be kept", content)
+	}
+	for raw, key := range aliasEdgeTargets(t, vault, "A.md") {
+		if key != noteKey("sub/Renamed.md") {
+			t.Errorf("%s → %s, want sub/Renamed.md", raw, key)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package core
 
 import (
 	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -242,6 +244,66 @@ func TestQueryAssetBacklinks(t *testing.T) {
 	}
 }
 
+func TestQueryAssetEntry(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":          "# A\n![[image.png]]\n",
+		"sub/B.md":      "# B\n\n![pic](../image.png)\n",
+		"C.md":          "[[sub/image.png]]\n",
+		"image.png":     "png",
+		"sub/image.png": "png",
+		"one/icon.svg":  "svg",
+		"two/icon.svg":  "svg",
+	})
+	buildVault(t, vault)
+
+	// A shared basename resolves to the root asset, as a link would.
+	result, err := Query(vault, EntrySpec{Asset: "image.png"}, QueryOptions{
+		Fields:  []string{"backlinks"},
+		PerEdge: true,
+	})
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if result.Entry.Type != "asset" || result.Entry.Path != "image.png" {
+		t.Fatalf("entry = %+v, want asset image.png", result.Entry)
+	}
+	var got []string
+	for _, p := range result.BacklinkPositions {
+		got = append(got, fmt.Sprintf("%s:%d", p.Node.Path, p.LineStart))
+	}
+	if want := []string{"A.md:2", "sub/B.md:3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("backlink positions = %v, want %v", got, want)
+	}
+
+	result, err = Query(vault, EntrySpec{Asset: "sub/image.png"}, QueryOptions{Fields: []string{"backlinks"}})
+	if err != nil {
+		t.Fatalf("query sub/image.png: %v", err)
+	}
+	if len(result.Backlinks) != 1 || result.Backlinks[0].Path != "C.md" {
+		t.Errorf("sub/image.png backlinks = %+v, want C.md", result.Backlinks)
+	}
+
+	// Without a root asset a shared basename is ambiguous.
+	result, err = Query(vault, EntrySpec{Asset: "icon.svg"}, QueryOptions{AllowAmbiguous: true})
+	if err != nil {
+		t.Fatalf("query icon.svg: %v", err)
+	}
+	if len(result.Candidates) != 2 {
+		t.Errorf("icon.svg candidates = %+v, want 2", result.Candidates)
+	}
+	if _, err := Query(vault, EntrySpec{Asset: "icon.svg"}, QueryOptions{}); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("icon.svg: got %v, want ambiguous error", err)
+	}
+
+	for _, missing := range []string{"missing.png", "sub/missing.png"} {
+		_, err := Query(vault, EntrySpec{Asset: missing}, QueryOptions{})
+		if err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("%s: got %v, want not found", missing, err)
+		}
+	}
+}
+
 func TestQueryNoteOutgoingIncludesAsset(t *testing.T) {
 	vault := copyVault(t, "vault_build_assets")
 	if err := Build(vault); err != nil {
@@ -501,6 +563,93 @@ func TestMoveAsset_MarkdownLink_BasenameChanged(t *testing.T) {
 	}
 }
 
+// writeImageVault creates a vault where image.png is referenced by an embed
+// from A.md and by markdown image links from B.md.
+func writeImageVault(t *testing.T) string {
+	t.Helper()
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":      "![[image.png]]\n",
+		"B.md":      "See ![shot](image.png) and ![wide](<image.png>).\n",
+		"image.png": "png",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	return vault
+}
+
+func TestMoveAsset_ImageLinks_BasenameUnchanged(t *testing.T) {
+	vault := writeImageVault(t)
+
+	result, err := Move(vault, MoveOptions{From: "image.png", To: "assets/image.png"})
+	if err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	// Basename is unique and unchanged: both embed and image link still resolve.
+	if len(result.Rewritten) != 0 {
+		t.Fatalf("expected no rewrites, got %v", result.Rewritten)
+	}
+	if got := aliasEdgeTargets(t, vault, "B.md")["[shot](image.png)"]; got != assetKey("assets/image.png") {
+		t.Errorf("[shot](image.png) target = %q, want %q", got, assetKey("assets/image.png"))
+	}
+}
+
+func TestMoveAsset_ImageLinks_BasenameChanged(t *testing.T) {
+	vault := writeImageVault(t)
+
+	if _, err := Move(vault, MoveOptions{From: "image.png", To: "assets/photo.png"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+
+	a, err := os.ReadFile(filepath.Join(vault, "A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != "![[assets/photo.png]]\n" {
+		t.Errorf("A.md = %q", a)
+	}
+	b, err := os.ReadFile(filepath.Join(vault, "B.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "See ![shot](assets/photo.png) and ![wide](<assets/photo.png>).\n"; string(b) != want {
+		t.Errorf("B.md = %q, want %q", b, want)
+	}
+	if !assetNodeExists(t, dbPath(vault), "assets/photo.png") {
+		t.Fatal("asset node should exist at new path")
+	}
+}
+
+func TestMoveAsset_ImageLinks_AmbiguousAfterMove(t *testing.T) {
+	vault := writeImageVault(t)
+	writeVaultFiles(t, vault, map[string]string{"other/image.png": "png2"})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	// The root copy resolved basename links by root priority; once it moves
+	// into a subdirectory the name is ambiguous, so links switch to paths.
+	if _, err := Move(vault, MoveOptions{From: "image.png", To: "assets/image.png"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+
+	a, err := os.ReadFile(filepath.Join(vault, "A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != "![[assets/image.png]]\n" {
+		t.Errorf("A.md = %q", a)
+	}
+	b, err := os.ReadFile(filepath.Join(vault, "B.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "See ![shot](assets/image.png) and ![wide](<assets/image.png>).\n"; string(b) != want {
+		t.Errorf("B.md = %q, want %q", b, want)
+	}
+}
+
 // --- MoveDir with assets ---
 
 func TestMoveDirWithAssets(t *testing.T) {
@@ -580,7 +729,7 @@ func TestIsAmbiguousBasenameLink_AssetNotAmbiguousInNoteKeySpace(t *testing.T) {
 		assetBasenameCounts: map[string]int{"image.png": 1},
 		assetPathSet:        map[string]string{},
 	}
-	if isAmbiguousBasenameLink("image.png", rm) {
+	if isAmbiguousBasenameLink("A.md", "image.png", rm) {
 		t.Fatal("expected not ambiguous")
 	}
 }
@@ -594,7 +743,7 @@ func TestIsAmbiguousBasenameLink_NoteAndAssetSeparateKeySpaces(t *testing.T) {
 		assetBasenameCounts: map[string]int{"note": 2},
 		assetPathSet:        map[string]string{"note": "sub/note"},
 	}
-	if isAmbiguousBasenameLink("Note", rm) {
+	if isAmbiguousBasenameLink("A.md", "Note", rm) {
 		t.Fatal("expected not ambiguous (note key space has unique match)")
 	}
 }
@@ -819,3 +968,41 @@ func TestQueryAssetExcludeIntegration(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildAssets_WikilinkWithExtension(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":            "[[report.pdf]] [[report]] ![[photo.jpg]] [[notes.txt]]\n",
+		"report.md":       "# Report\n",
+		"docs/report.pdf": "pdf",
+		"img/photo.jpg":   "jpg",
+		"notes.txt.md":    "# Note named like a file\n",
+		"files/notes.txt": "txt",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	targets := aliasEdgeTargets(t, vault, "A.md")
+	want := map[string]string{
+		"[[report.pdf]]": assetKey("docs/report.pdf"),
+		"[[report]]":     noteKey("report.md"),
+		"[[photo.jpg]]":  assetKey("img/photo.jpg"), // embed; raw_link omits "!"
+		"[[notes.txt]]":  assetKey("files/notes.txt"),
+	}
+	for raw, key := range want {
+		if got := targets[raw]; got != key {
+			t.Errorf("%s → %s, want %s", raw, got, key)
+		}
+	}
+
+	// resolve agrees with build.
+	for link, path := range map[string]string{"[[notes.txt]]": "files/notes.txt", "[[report]]": "report.md"} {
+		res, err := Resolve(vault, "A.md", link)
+		if err != nil {
+			t.Fatalf("resolve %s: %v", link, err)
+		}
+		if res.Path != path {
+			t.Errorf("resolve %s = %s, want %s", link, res.Path, path)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PruneAssetsOptions controls the unreferenced asset cleanup.
+type PruneAssetsOptions struct {
+	Apply  bool          // false = dry run (report only)
+	MinAge time.Duration // assets modified more recently than this are kept
+	Now    time.Time     // zero = time.Now(); reference point for MinAge
+	// All also prunes unreferenced files that are not attachments (see
+	// pruneExtensions), such as LICENSE or scripts. They are skipped otherwise.
+	All bool
+}
+
+// PrunedAsset is an unreferenced asset that was (or would be) removed.
+type PrunedAsset struct {
+	Path  string
+	Bytes int64
+}
+
+// PruneAssetsResult reports the outcome of PruneAssets.
+type PruneAssetsResult struct {
+	Applied        bool          // false for a dry run
+	Pruned         []PrunedAsset // removed assets (or candidates on a dry run), sorted by path
+	SkippedRecent  []string      // unreferenced but newer than MinAge
+	SkippedLinked  []string      // no edges, but linked from a note on disk (changed since the build, or excluded)
+	SkippedOther   []string      // unreferenced, but not an attachment type (pruned only with All)
+	ReclaimedBytes int64
+}
+
+// pruneExtensions are the attachment types PruneAssets removes by default: the
+// image, audio, video and PDF files a note can embed.
+var pruneExtensions = map[string]bool{
+	".avif": true, ".bmp": true, ".gif": true, ".jpeg": true, ".jpg": true, ".png": true, ".svg": true, ".webp": true,
+	".flac": true, ".m4a": true, ".mp3": true, ".ogg": true, ".wav": true, ".3gp": true,
+	".mkv": true, ".mov": true, ".mp4": true, ".ogv": true, ".webm": true,
+	".pdf": true,
+}
+
+// pruningSuffix is appended to an asset's name while PruneAssets removes it,
+// so the file can be put back if the index update fails.
+const pruningSuffix = ".mdhop-pruning"
+
+// linkedFileNames returns the lowercased file names of every wikilink and
+// markdown link target in the notes on disk, including notes excluded by
+// build.exclude_paths. Matching by name alone errs on the side of keeping
+// an asset, whatever folder a link would resolve it in.
+func linkedFileNames(vaultPath string, cfg BuildConfig) (map[string]bool, error) {
+	files, err := collectMarkdownFiles(vaultPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, f := range files {
+		content, err := os.ReadFile(filepath.Join(vaultPath, f))
+		if err != nil {
+			return nil, err
+		}
+		for _, link := range parseIndexLinks(string(content), cfg) {
+			if isFileLinkType(link.linkType) && link.target != "" {
+				names[strings.ToLower(path.Base(link.target))] = true
+			}
+		}
+	}
+	return names, nil
+}
+
+// PruneAssets finds asset nodes with no incoming edges and, when opts.Apply is
+// set, deletes them from disk and the index. Assets in hidden directories,
+// under build.exclude_paths, or modified within opts.MinAge are never touched,
+// and neither are files outside pruneExtensions unless opts.All is set.
+// Since the edges may lag behind the notes, an asset is also kept when any
+// note on disk, excluded notes included, links to a file of its name.
+func PruneAssets(vaultPath string, opts PruneAssetsOptions) (*PruneAssetsResult, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+	if opts.MinAge < 0 {
+		return nil, fmt.Errorf("min age must be >= 0")
+	}
+
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateGlobPatterns(cfg.Build.ExcludePaths); err != nil {
+		return nil, err
+	}
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(
+		`SELECT n.id, n.path FROM nodes n
+		 WHERE n.type = 'asset' AND n.exists_flag = 1
+		 AND NOT EXISTS (SELECT 1 FROM edges e WHERE e.target_id = n.id)
+		 ORDER BY n.path`)
+	if err != nil {
+		return nil, err
+	}
+	type candidate struct {
+		id    int64
+		path  string
+		bytes int64
+	}
+	var unreferenced []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		unreferenced = append(unreferenced, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	linked, err := linkedFileNames(vaultPath, cfg.Build)
+	if err != nil {
+		return nil, err
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	result := &PruneAssetsResult{Applied: opts.Apply}
+	var candidates []candidate
+	for _, u := range unreferenced {
+		if isIgnoredAssetPath(u.path) || len(filterBuildExcludes([]string{u.path}, cfg.Build.ExcludePaths)) == 0 {
+			continue
+		}
+		if pathEscapesVault(u.path) {
+			return nil, fmt.Errorf("path escapes vault: %s", u.path)
+		}
+		info, err := os.Stat(filepath.Join(vaultPath, u.path))
+		if os.IsNotExist(err) {
+			continue // stale index entry; verify reports these
+		}
+		if err != nil {
+			return nil, err
+		}
+		if linked[strings.ToLower(path.Base(u.path))] {
+			result.SkippedLinked = append(result.SkippedLinked, u.path)
+			continue
+		}
+		if !opts.All && !pruneExtensions[strings.ToLower(path.Ext(u.path))] {
+			result.SkippedOther = append(result.SkippedOther, u.path)
+			continue
+		}
+		if now.Sub(info.ModTime()) < opts.MinAge {
+			result.SkippedRecent = append(result.SkippedRecent, u.path)
+			continue
+		}
+		u.bytes = info.Size()
+		candidates = append(candidates, u)
+	}
+
+	if !opts.Apply {
+		for _, c := range candidates {
+			result.Pruned = append(result.Pruned, PrunedAsset{Path: c.path, Bytes: c.bytes})
+			result.ReclaimedBytes += c.bytes
+		}
+		return result, nil
+	}
+
+	// Set the files aside, drop their nodes, and remove the files only once
+	// the index is committed; any earlier failure puts every file back.
+	var setAside []candidate
+	restore := func() {
+		for _, c := range setAside {
+			full := filepath.Join(vaultPath, c.path)
+			_ = os.Rename(full+pruningSuffix, full)
+		}
+	}
+	for _, c := range candidates {
+		full := filepath.Join(vaultPath, c.path)
+		if err := os.Rename(full, full+pruningSuffix); err != nil {
+			restore()
+			return nil, err
+		}
+		setAside = append(setAside, c)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		restore()
+		return nil, err
+	}
+	defer tx.Rollback()
+	for _, c := range candidates {
+		if _, err := tx.Exec("DELETE FROM nodes WHERE id = ?", c.id); err != nil {
+			restore()
+			return nil, err
+		}
+	}
+	if err := bumpIndexVersion(tx); err != nil {
+		restore()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		restore()
+		return nil, err
+	}
+
+	var removeErr error
+	for _, c := range candidates {
+		if err := os.Remove(filepath.Join(vaultPath, c.path) + pruningSuffix); err != nil && removeErr == nil {
+			removeErr = fmt.Errorf("removed from the index, but not from disk: %w", err)
+		}
+		result.Pruned = append(result.Pruned, PrunedAsset{Path: c.path, Bytes: c.bytes})
+		result.ReclaimedBytes += c.bytes
+	}
+	if removeErr != nil {
+		return result, removeErr
+	}
+	return result, nil
+}
+
+// isIgnoredAssetPath reports whether path lies in a directory that build never
+// scans (hidden directories and the mdhop data directory) or is the config file.
+func isIgnoredAssetPath(path string) bool {
+	if path == "mdhop.yaml" {
+		return true
+	}
+	parts := strings.Split(path, "/")
+	for _, dir := range parts[:len(parts)-1] {
+		if dir == dataDirName || strings.HasPrefix(dir, ".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPruneAssets_DryRun(t *testing.T) {
+	vault := copyVault(t, "vault_build_assets")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	result, err := PruneAssets(vault, PruneAssetsOptions{All: true})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if result.Applied {
+		t.Error("Applied = true, want false for dry run")
+	}
+	if len(result.Pruned) != 1 || result.Pruned[0].Path != "orphan.txt" {
+		t.Fatalf("Pruned = %+v, want [orphan.txt]", result.Pruned)
+	}
+	info, err := os.Stat(filepath.Join(vault, "orphan.txt"))
+	if err != nil {
+		t.Fatalf("dry run removed orphan.txt: %v", err)
+	}
+	if result.Pruned[0].Bytes != info.Size() || result.ReclaimedBytes != info.Size() {
+		t.Errorf("bytes = %d / %d, want %d", result.Pruned[0].Bytes, result.ReclaimedBytes, info.Size())
+	}
+}
+
+func TestPruneAssets_Apply(t *testing.T) {
+	vault := copyVault(t, "vault_build_assets")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	result, err := PruneAssets(vault, PruneAssetsOptions{Apply: true, All: true})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if len(result.Pruned) != 1 || result.Pruned[0].Path != "orphan.txt" {
+		t.Fatalf("Pruned = %+v, want [orphan.txt]", result.Pruned)
+	}
+	if _, err := os.Stat(filepath.Join(vault, "orphan.txt")); !os.IsNotExist(err) {
+		t.Errorf("orphan.txt still on disk: %v", err)
+	}
+	for _, kept := range []string{"image.png", "doc.pdf", "sub/photo.jpg"} {
+		if _, err := os.Stat(filepath.Join(vault, kept)); err != nil {
+			t.Errorf("referenced asset %s removed: %v", kept, err)
+		}
+	}
+
+	db := openTestDB(t, dbPath(vault))
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM nodes WHERE node_key = ?", assetKey("orphan.txt")).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("orphan.txt node count = %d, want 0", n)
+	}
+
+	v, err := Verify(vault)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !v.OK() {
+		t.Errorf("verify issues after prune: %+v", v.Issues)
+	}
+}
+
+func TestPruneAssets_MinAgeKeepsRecent(t *testing.T) {
+	vault := copyVault(t, "vault_build_assets")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	result, err := PruneAssets(vault, PruneAssetsOptions{Apply: true, MinAge: time.Hour, All: true})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if len(result.Pruned) != 0 {
+		t.Errorf("Pruned = %+v, want none", result.Pruned)
+	}
+	if len(result.SkippedRecent) != 1 || result.SkippedRecent[0] != "orphan.txt" {
+		t.Errorf("SkippedRecent = %v, want [orphan.txt]", result.SkippedRecent)
+	}
+	if _, err := os.Stat(filepath.Join(vault, "orphan.txt")); err != nil {
+		t.Errorf("recent orphan.txt removed: %v", err)
+	}
+
+	// The same file is pruned once it is old enough.
+	result, err = PruneAssets(vault, PruneAssetsOptions{MinAge: time.Hour, Now: time.Now().Add(2 * time.Hour), All: true})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if len(result.Pruned) != 1 {
+		t.Errorf("Pruned = %+v, want [orphan.txt]", result.Pruned)
+	}
+}
+
+func TestPruneAssets_SkipsConfigAndExcluded(t *testing.T) {
+	vault := copyVault(t, "vault_build_assets")
+	cfg := "build:\n  exclude_paths:\n    - \"archive/*\"\n"
+	if err := os.WriteFile(filepath.Join(vault, "mdhop.yaml"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	// Registered later via add, but excluded from build.
+	if err := os.MkdirAll(filepath.Join(vault, "archive"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "archive", "old.bin"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db := openTestDB(t, dbPath(vault))
+	if _, err := db.Exec("INSERT INTO nodes (node_key, type, name, path, exists_flag, mtime) VALUES (?, 'asset', 'old.bin', 'archive/old.bin', 1, 0)", assetKey("archive/old.bin")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := PruneAssets(vault, PruneAssetsOptions{Apply: true, All: true})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	for _, p := range result.Pruned {
+		if p.Path == "mdhop.yaml" || p.Path == "archive/old.bin" {
+			t.Errorf("pruned protected file %s", p.Path)
+		}
+	}
+	for _, kept := range []string{"mdhop.yaml", "archive/old.bin"} {
+		if _, err := os.Stat(filepath.Join(vault, kept)); err != nil {
+			t.Errorf("%s removed: %v", kept, err)
+		}
+	}
+}
+
+func TestPruneAssets_KeepsAssetsLinkedOnDisk(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"mdhop.yaml":     "build:\n  exclude_paths:\n    - \"drafts/*\"\n",
+		"A.md":           "# A\n",
+		"drafts/D.md":    "![[draft.png]]\n",
+		"pic.png":        "x",
+		"draft.png":      "x",
+		"img/my pic.jpg": "x",
+		"unused.bin":     "x",
+		"B.md":           "# B\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	// Link pic.png and "my pic.jpg" after the build, without updating the index.
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "# A\n\n![[pic.png]]\n",
+		"B.md": "![p](img/my%20pic.jpg)\n",
+	})
+
+	result, err := PruneAssets(vault, PruneAssetsOptions{Apply: true, All: true})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if len(result.Pruned) != 1 || result.Pruned[0].Path != "unused.bin" {
+		t.Errorf("Pruned = %+v, want [unused.bin]", result.Pruned)
+	}
+	if !reflect.DeepEqual(result.SkippedLinked, []string{"draft.png", "img/my pic.jpg", "pic.png"}) {
+		t.Errorf("SkippedLinked = %v", result.SkippedLinked)
+	}
+	for _, kept := range []string{"pic.png", "draft.png", "img/my pic.jpg"} {
+		if _, err := os.Stat(filepath.Join(vault, kept)); err != nil {
+			t.Errorf("linked asset %s removed: %v", kept, err)
+		}
+	}
+}
+
+func TestPruneAssets_SkipsNonAttachments(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":       "# A\n",
+		"LICENSE":    "MIT",
+		"Makefile":   "all:",
+		"run.sh":     "#!/bin/sh",
+		"unused.PNG": "x",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	result, err := PruneAssets(vault, PruneAssetsOptions{Apply: true})
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if len(result.Pruned) != 1 || result.Pruned[0].Path != "unused.PNG" {
+		t.Errorf("Pruned = %+v, want [unused.PNG]", result.Pruned)
+	}
+	if !reflect.DeepEqual(result.SkippedOther, []string{"LICENSE", "Makefile", "run.sh"}) {
+		t.Errorf("SkippedOther = %v", result.SkippedOther)
+	}
+	for _, kept := range []string{"LICENSE", "Makefile", "run.sh"} {
+		if _, err := os.Stat(filepath.Join(vault, kept)); err != nil {
+			t.Errorf("%s removed: %v", kept, err)
+		}
+	}
+}
+
+func TestPruneAssets_RestoresFilesOnIndexFailure(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":  "# A\n",
+		"a.png": "x",
+		"b.png": "x",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	// A trigger makes deleting b.png's node fail inside the transaction.
+	db := openTestDB(t, dbPath(vault))
+	if _, err := db.Exec(`CREATE TRIGGER keep_b BEFORE DELETE ON nodes WHEN old.path = 'b.png'
+		BEGIN SELECT RAISE(ABORT, 'keep b'); END`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if _, err := PruneAssets(vault, PruneAssetsOptions{Apply: true}); err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, p := range []string{"a.png", "b.png"} {
+		if _, err := os.Stat(filepath.Join(vault, p)); err != nil {
+			t.Errorf("%s not restored: %v", p, err)
+		}
+		if _, err := os.Stat(filepath.Join(vault, p+pruningSuffix)); !os.IsNotExist(err) {
+			t.Errorf("%s left behind", p+pruningSuffix)
+		}
+	}
+	db = openTestDB(t, dbPath(vault))
+	defer db.Close()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM nodes WHERE type = 'asset'").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("asset nodes = %d, want 2", n)
+	}
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// With KeepBackup, a mutating command saves the original content of every
+// file it rewrote next to it as "<path>.bak" once the change has succeeded.
+// The backup paths are recorded in .mdhop/backups so that CleanBackups
+// removes only files mdhop wrote.
+
+const (
+	backupSuffix   = ".bak"
+	backupListName = "backups"
+)
+
+// keepBackups writes each backup's content to its path + ".bak", replacing an
+// older backup of the same file, and records the paths. It runs after the
+// change is applied, so an error here leaves the change in place.
+func keepBackups(vaultPath string, backups []rewriteBackup) error {
+	if len(backups) == 0 {
+		return nil
+	}
+	if err := writeBackups(vaultPath, backups); err != nil {
+		return fmt.Errorf("changes applied, but writing backups failed: %w", err)
+	}
+	return nil
+}
+
+func writeBackups(vaultPath string, backups []rewriteBackup) error {
+	dir, err := ensureDataDir(vaultPath)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, backupListName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for _, b := range backups {
+		rel := b.path + backupSuffix
+		if err := writeFilePreservePerm(filepath.Join(vaultPath, rel), b.content, b.perm); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(f, rel); err != nil {
+			return err
+		}
+		verbosef("backup: wrote %s", rel)
+	}
+	return nil
+}
+
+// CleanBackups removes the .bak files written by earlier commands run with
+// KeepBackup and returns their vault-relative paths (sorted). Backups already
+// deleted by hand are skipped.
+func CleanBackups(vaultPath string) ([]string, error) {
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	listPath := filepath.Join(vaultPath, dataDirName, backupListName)
+	f, err := os.Open(listPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if rel := strings.TrimSpace(sc.Text()); rel != "" {
+			seen[rel] = true
+		}
+	}
+	f.Close()
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for rel := range seen {
+		// Only ever delete what keepBackups could have written.
+		if !strings.HasSuffix(rel, backupSuffix) || pathEscapesVault(rel) {
+			continue
+		}
+		err := os.Remove(filepath.Join(vaultPath, rel))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		removed = append(removed, rel)
+	}
+	if err := os.Remove(listPath); err != nil {
+		return nil, err
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
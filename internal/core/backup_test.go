@@ -0,0 +1,105 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveKeepBackup(t *testing.T) {
+	vault := t.TempDir()
+	originals := map[string]string{
+		"A.md":     "[[sub/B]]\n",
+		"C.md":     "see [b](sub/B.md)\n",
+		"sub/B.md": "[up](../A.md)\n",
+		"D.md":     "[[A]]\n",
+	}
+	writeVaultFiles(t, vault, originals)
+	buildVault(t, vault)
+
+	result, err := Move(vault, MoveOptions{From: "sub/B.md", To: "new/deep/B.md", KeepBackup: true})
+	if err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if len(result.Rewritten) != 3 {
+		t.Fatalf("rewritten = %+v, want 3", result.Rewritten)
+	}
+
+	// Every rewritten file has a backup of its pre-move content; the moved
+	// file's backup sits next to its new path.
+	want := map[string]string{
+		"A.md.bak":          originals["A.md"],
+		"C.md.bak":          originals["C.md"],
+		"new/deep/B.md.bak": originals["sub/B.md"],
+	}
+	for rel, content := range want {
+		data, err := os.ReadFile(filepath.Join(vault, rel))
+		if err != nil {
+			t.Errorf("backup %s: %v", rel, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", rel, data, content)
+		}
+	}
+	if fileExists(filepath.Join(vault, "D.md.bak")) {
+		t.Error("D.md.bak written for a file that was not rewritten")
+	}
+
+	removed, err := CleanBackups(vault)
+	if err != nil {
+		t.Fatalf("clean backups: %v", err)
+	}
+	if len(removed) != 3 || removed[0] != "A.md.bak" || removed[1] != "C.md.bak" || removed[2] != "new/deep/B.md.bak" {
+		t.Errorf("removed = %v", removed)
+	}
+	for rel := range want {
+		if fileExists(filepath.Join(vault, rel)) {
+			t.Errorf("%s still exists after clean", rel)
+		}
+	}
+}
+
+func TestMoveWithoutKeepBackup(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":     "[[sub/B]]\n",
+		"sub/B.md": "# B\n",
+	})
+	buildVault(t, vault)
+
+	if _, err := Move(vault, MoveOptions{From: "sub/B.md", To: "new/B.md"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if fileExists(filepath.Join(vault, "A.md.bak")) {
+		t.Error("backup written without KeepBackup")
+	}
+}
+
+func TestCleanBackupsKeepsUnrecordedFiles(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":      "[[sub/B]]\n",
+		"sub/B.md":  "# B\n",
+		"notes.bak": "user file\n",
+	})
+	buildVault(t, vault)
+
+	if _, err := Move(vault, MoveOptions{From: "sub/B.md", To: "new/B.md", KeepBackup: true}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if _, err := CleanBackups(vault); err != nil {
+		t.Fatalf("clean backups: %v", err)
+	}
+	if !fileExists(filepath.Join(vault, "notes.bak")) {
+		t.Error("clean removed a .bak file mdhop did not write")
+	}
+	// A second clean has nothing to do.
+	removed, err := CleanBackups(vault)
+	if err != nil {
+		t.Fatalf("second clean: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("second clean removed %v", removed)
+	}
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"fmt"
+	"os"
+)
+
+// BrokenLink is a link occurrence whose target is a phantom node.
+type BrokenLink struct {
+	Line     int
+	LinkType string // "wikilink" or "markdown"
+	RawLink  string
+	Target   string // phantom name
+	Embed    bool   // ![[...]] / ![...](...)
+}
+
+// BrokenSource groups broken links by source note.
+type BrokenSource struct {
+	Path  string
+	Links []BrokenLink // sorted by line
+}
+
+// QueryBroken returns every wikilink/markdown edge pointing to a phantom,
+// grouped by source note (sorted by path). Only opts.Exclude is used.
+func QueryBroken(vaultPath string, opts QueryOptions) ([]BrokenSource, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return queryBrokenLinks(db, opts.Exclude)
+}
+
+func queryBrokenLinks(db dbExecer, ef *ExcludeFilter) ([]BrokenSource, error) {
+	q := `SELECT s.path, e.line_start, e.link_type, e.raw_link, t.name, e.is_embed
+		 FROM edges e
+		 JOIN nodes s ON s.id = e.source_id
+		 JOIN nodes t ON t.id = e.target_id
+		 WHERE t.type = 'phantom' AND e.link_type IN ('wikilink','markdown')`
+	var args []any
+
+	if ef != nil {
+		pathSQL, pathArgs := ef.PathExcludeSQL("s.path")
+		q += pathSQL
+		args = append(args, pathArgs...)
+	}
+
+	q += ` ORDER BY s.path, e.line_start, e.id`
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []BrokenSource{}
+	for rows.Next() {
+		var path string
+		var bl BrokenLink
+		var embed int
+		if err := rows.Scan(&path, &bl.Line, &bl.LinkType, &bl.RawLink, &bl.Target, &embed); err != nil {
+			return nil, err
+		}
+		bl.Embed = embed == 1
+		if n := len(result); n == 0 || result[n-1].Path != path {
+			result = append(result, BrokenSource{Path: path})
+		}
+		result[len(result)-1].Links = append(result[len(result)-1].Links, bl)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestQueryBroken(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_broken")
+	buildForQuery(t, vault)
+
+	got, err := QueryBroken(vault, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("sources = %+v, want 2", got)
+	}
+
+	if got[0].Path != "A.md" {
+		t.Errorf("sources[0] = %q, want A.md", got[0].Path)
+	}
+	wantA := []BrokenLink{
+		{Line: 1, LinkType: "wikilink", RawLink: "[[Missing]]", Target: "Missing", Embed: true},
+		{Line: 2, LinkType: "wikilink", RawLink: "[[Missing|alias]]", Target: "Missing"},
+		{Line: 3, LinkType: "markdown", RawLink: "[x](Gone.md)", Target: "Gone"},
+		{Line: 4, LinkType: "markdown", RawLink: "[img](pic.png)", Target: "pic.png", Embed: true},
+	}
+	if len(got[0].Links) != len(wantA) {
+		t.Fatalf("A.md links = %+v, want %+v", got[0].Links, wantA)
+	}
+	for i := range wantA {
+		if got[0].Links[i] != wantA[i] {
+			t.Errorf("A.md links[%d] = %+v, want %+v", i, got[0].Links[i], wantA[i])
+		}
+	}
+
+	if got[1].Path != "B.md" || len(got[1].Links) != 1 || got[1].Links[0].Target != "Nope" || got[1].Links[0].Line != 3 {
+		t.Errorf("sources[1] = %+v, want B.md with Nope at line 3", got[1])
+	}
+}
+
+func TestQueryBrokenNone(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_build_basic")
+	buildForQuery(t, vault)
+
+	got, err := QueryBroken(vault, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("sources = %+v, want empty", got)
+	}
+}
+
+func TestQueryBrokenExclude(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_broken")
+	buildForQuery(t, vault)
+
+	ef, err := NewExcludeFilter(ExcludeConfig{}, []string{"A.md"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := QueryBroken(vault, QueryOptions{Exclude: ef})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Path != "B.md" {
+		t.Errorf("sources = %+v, want only B.md", got)
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 )
 
 const maxBuildErrors = 5
@@ -23,36 +26,138 @@ type resolveMaps struct {
 	assetRootBasenameToPath map[string]string // lower asset basename → root path
 	assetPathToID           map[string]int64
 	assetBasenameCounts     map[string]int
+	// alias
+	noteAliases map[string][]string // note path → frontmatter aliases
+	aliasToPath map[string]string   // lower alias → path (unique only, see aliasTargets)
+	// folderNotes enables folder note resolution (build.folder_notes).
+	folderNotes bool
+	// noRootPriority disables root-priority resolution (build.root_priority: false).
+	noRootPriority bool
+	// preserveTagCase keeps tags differing in case apart (build.tag_case: preserve).
+	preserveTagCase bool
+	// folderFirst resolves basename links to a note in the source's folder
+	// first (build.link_resolution: folder-first).
+	folderFirst bool
+}
+
+// applyBuildConfig sets the resolution options that come from the build
+// section of mdhop.yaml.
+func (rm *resolveMaps) applyBuildConfig(bc BuildConfig) {
+	rm.folderNotes = bc.FolderNotes
+	rm.noRootPriority = !bc.rootPriority()
+	rm.preserveTagCase = bc.preserveTagCase()
+	rm.folderFirst = bc.folderFirst()
+}
+
+// rootPath returns the root note a duplicated basename resolves to under
+// root priority.
+func (rm *resolveMaps) rootPath(bk string) (string, bool) {
+	if rm.noRootPriority {
+		return "", false
+	}
+	p, ok := rm.rootBasenameToPath[bk]
+	return p, ok
+}
+
+// localNotePath returns the note a lowercase basename names in the folder of
+// sourcePath under folder-first resolution.
+func (rm *resolveMaps) localNotePath(sourcePath, lower string) (string, bool) {
+	if !rm.folderFirst {
+		return "", false
+	}
+	p, ok := rm.pathSet[localNoteKey(sourcePath, lower)]
+	return p, ok
+}
+
+// rootWins is hasRootInPathSet honoring build.root_priority: with root
+// priority off, a root file never wins over files sharing its basename.
+func (rm *resolveMaps) rootWins(bk string, pathSet map[string]string) bool {
+	return !rm.noRootPriority && hasRootInPathSet(bk, pathSet)
+}
+
+// BuildOptions controls Build behavior.
+type BuildOptions struct {
+	FollowSymlinks   bool     // descend into symlinked directories (also build.follow_symlinks)
+	RespectGitignore bool     // skip paths ignored by .gitignore files in the vault (also build.respect_gitignore)
+	ExcludePaths     []string // extra glob patterns appended to build.exclude_paths
+	// BaseDir indexes only this vault-relative directory and resolves links as
+	// if it were the vault root: root-priority, vault-relative paths, and
+	// vault-escape checks use the base dir. Stored paths stay vault-relative.
+	BaseDir string
+	// Threads caps the number of notes read and parsed concurrently; 0 means
+	// runtime.NumCPU(). 1 parses serially.
+	Threads int
 }
 
 // Build parses the vault and creates the index DB.
 func Build(vaultPath string) error {
+	return BuildWithOptions(vaultPath, BuildOptions{})
+}
+
+// BuildWithOptions is Build with explicit options. Options enable behavior on
+// top of mdhop.yaml; they never turn off a setting enabled in the config.
+func BuildWithOptions(vaultPath string, opts BuildOptions) error {
 	if _, err := ensureDataDir(vaultPath); err != nil {
 		return err
 	}
-
-	// Pass 0: collect .md files.
-	files, err := collectMarkdownFiles(vaultPath)
+	lock, err := lockIndex(vaultPath)
 	if err != nil {
 		return err
 	}
+	defer lock.unlock()
 
 	cfg, err := LoadConfig(vaultPath)
 	if err != nil {
 		return err
 	}
+	if opts.FollowSymlinks {
+		cfg.Build.FollowSymlinks = true
+	}
+	if opts.RespectGitignore {
+		cfg.Build.RespectGitignore = true
+	}
+	cfg.Build.ExcludePaths = append(cfg.Build.ExcludePaths, opts.ExcludePaths...)
+
+	// Pass 0: collect .md files.
+	files, err := collectMarkdownFiles(vaultPath, cfg.Build)
+	if err != nil {
+		return err
+	}
 	if err := validateGlobPatterns(cfg.Build.ExcludePaths); err != nil {
 		return err
 	}
 	files = filterBuildExcludes(files, cfg.Build.ExcludePaths)
 
 	// Pass 0.5: collect asset files.
-	assetFiles, err := collectAssetFiles(vaultPath)
+	assetFiles, err := collectAssetFiles(vaultPath, cfg.Build)
 	if err != nil {
 		return err
 	}
 	assetFiles = filterBuildExcludes(assetFiles, cfg.Build.ExcludePaths)
 
+	// Scope to the base dir: from here on paths are base-relative, and
+	// storedPath maps them back to vault-relative paths for the DB.
+	root := vaultPath
+	storedPath := func(p string) string { return p }
+	if opts.BaseDir != "" {
+		base := NormalizePath(opts.BaseDir)
+		if base == "." || base == "" {
+			base = ""
+		} else {
+			if pathEscapesVault(base) {
+				return fmt.Errorf("base dir escapes vault: %s", opts.BaseDir)
+			}
+			if info, err := os.Stat(filepath.Join(vaultPath, base)); err != nil || !info.IsDir() {
+				return fmt.Errorf("base dir not found: %s", base)
+			}
+			files = trimBaseDir(files, base)
+			assetFiles = trimBaseDir(assetFiles, base)
+			root = filepath.Join(vaultPath, base)
+			storedPath = func(p string) string { return base + "/" + p }
+		}
+	}
+	verbosef("build: found %d notes, %d assets", len(files), len(assetFiles))
+
 	// Build resolve maps for notes and assets.
 	nm := buildNoteResolveMaps(files)
 	am := buildAssetResolveMaps(assetFiles)
@@ -69,42 +174,49 @@ func Build(vaultPath string) error {
 		assetPathToID:           make(map[string]int64),
 		assetBasenameCounts:     am.basenameCounts,
 	}
+	rm.applyBuildConfig(cfg.Build)
 
 	// Read all files, parse links, stat for mtime, and validate.
 	// Done before DB creation so failures leave no temp file behind.
-	type parsedFile struct {
-		path  string
-		mtime int64
-		links []linkOccur
+	threads := opts.Threads
+	if threads <= 0 {
+		threads = runtime.NumCPU()
 	}
-	parsed := make([]parsedFile, 0, len(files))
-	var userErrors []string
-	for _, rel := range files {
-		fullPath := filepath.Join(vaultPath, rel)
-		content, err := os.ReadFile(fullPath)
-		if err != nil {
-			return err
-		}
-		info, err := os.Stat(fullPath)
-		if err != nil {
-			return err
-		}
-		links := parseLinks(string(content))
+	parsed, err := parseNoteFiles(root, files, cfg.Build, threads)
+	if err != nil {
+		return err
+	}
+	var userErrors []BuildIssue
+	for _, pf := range parsed {
+		rel := pf.path
 
 		// Validate links: collect user errors (ambiguous, vault-escape) up to maxBuildErrors.
-		for _, link := range links {
-			if link.linkType != "wikilink" && link.linkType != "markdown" {
+		for _, link := range pf.links {
+			if !isFileLinkType(link.linkType) {
 				continue
 			}
-			if link.isRelative && escapesVault(rel, link.target) {
-				userErrors = append(userErrors, fmt.Sprintf("link escapes vault: %s in %s", link.rawLink, rel))
-			} else if !link.isRelative && !link.isBasename && pathEscapesVault(link.target) {
-				userErrors = append(userErrors, fmt.Sprintf("link escapes vault: %s in %s", link.rawLink, rel))
-			} else if link.isBasename && isAmbiguousBasenameLink(link.target, rm) {
-				userErrors = append(userErrors, fmt.Sprintf("ambiguous link: %s in %s", link.target, rel))
+			issue := BuildIssue{File: storedPath(rel), Line: link.lineStart}
+			if (link.isRelative && escapesVault(rel, link.target)) ||
+				(!link.isRelative && !link.isBasename && pathEscapesVault(link.target)) {
+				issue.Kind = "escape"
+				issue.Message = fmt.Sprintf("link escapes vault: %s in %s", link.rawLink, issue.File)
+			} else if link.isBasename && isAmbiguousBasenameLink(rel, link.target, rm) {
+				issue.Kind = "ambiguous"
+				issue.Message = fmt.Sprintf("ambiguous link: %s in %s", link.target, issue.File)
+				issue.Name = link.target
+				if rm.basenameCounts[strings.ToLower(link.target)] > 1 {
+					for _, c := range noteCandidates(files, link.target) {
+						issue.Candidates = append(issue.Candidates, storedPath(c))
+					}
+				}
+			} else if isAmbiguousFolderNoteLink(rel, link, rm) {
+				issue.Kind = "ambiguous"
+				issue.Message = fmt.Sprintf("ambiguous link: %s in %s (note and folder note)", link.target, issue.File)
+				issue.Name = link.target
 			} else {
 				continue
 			}
+			userErrors = append(userErrors, issue)
 			if len(userErrors) >= maxBuildErrors {
 				break
 			}
@@ -112,16 +224,19 @@ func Build(vaultPath string) error {
 		if len(userErrors) >= maxBuildErrors {
 			break
 		}
-
-		parsed = append(parsed, parsedFile{
-			path:  rel,
-			mtime: info.ModTime().Unix(),
-			links: links,
-		})
 	}
 	if len(userErrors) > 0 {
 		return formatBuildErrors(userErrors)
 	}
+	verbosef("build: parsed %d notes", len(parsed))
+
+	rm.noteAliases = make(map[string][]string)
+	for _, pf := range parsed {
+		if len(pf.aliases) > 0 {
+			rm.noteAliases[pf.path] = pf.aliases
+		}
+	}
+	rm.aliasToPath = aliasTargets(rm.noteAliases)
 
 	// Stat asset files for mtime.
 	type assetInfo struct {
@@ -130,7 +245,7 @@ func Build(vaultPath string) error {
 	}
 	assetInfos := make([]assetInfo, 0, len(assetFiles))
 	for _, rel := range assetFiles {
-		fullPath := filepath.Join(vaultPath, rel)
+		fullPath := filepath.Join(root, rel)
 		info, err := os.Stat(fullPath)
 		if err != nil {
 			return err
@@ -140,8 +255,8 @@ func Build(vaultPath string) error {
 
 	// Create temp DB.
 	tmpPath := dbPath(vaultPath) + ".tmp"
-	_ = os.Remove(tmpPath)
-	defer os.Remove(tmpPath)
+	removeDBFiles(tmpPath)
+	defer removeDBFiles(tmpPath)
 
 	db, err := openDBAt(tmpPath)
 	if err != nil {
@@ -153,33 +268,54 @@ func Build(vaultPath string) error {
 		return err
 	}
 
-	tx, err := db.Begin()
+	dbTx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	tx := newStmtCache(dbTx)
+	defer tx.close()
+	nt, err := newNoteText(tx)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
 	// Pass 1: insert all note nodes.
 	for _, pf := range parsed {
 		name := basename(pf.path)
-		id, err := upsertNote(tx, pf.path, name, pf.mtime)
+		id, err := upsertNote(tx, storedPath(pf.path), name, pf.mtime)
 		if err != nil {
 			return err
 		}
 		rm.pathToID[pf.path] = id
+		if err := replaceHeadings(tx, id, pf.headings); err != nil {
+			return err
+		}
+		if err := replaceExternalLinks(tx, id, pf.external); err != nil {
+			return err
+		}
+		if err := replaceAliases(tx, id, pf.aliases); err != nil {
+			return err
+		}
+		if err := nt.replace(tx, id, pf.body); err != nil {
+			return err
+		}
 	}
 
 	// Pass 1.5: insert all asset nodes.
 	for _, ai := range assetInfos {
 		name := filepath.Base(ai.path)
-		id, err := upsertAsset(tx, ai.path, name, ai.mtime)
+		id, err := upsertAsset(tx, storedPath(ai.path), name, ai.mtime)
 		if err != nil {
 			return err
 		}
 		rm.assetPathToID[ai.path] = id
 	}
 
+	verbosef("build: inserted %d note nodes, %d asset nodes", len(parsed), len(assetInfos))
+
 	// Pass 2: resolve links and create edges (using cached parsed data).
+	edges := 0
 	for _, pf := range parsed {
 		sourceID := rm.pathToID[pf.path]
 		for _, link := range pf.links {
@@ -190,13 +326,29 @@ func Build(vaultPath string) error {
 			if targetID == 0 {
 				continue
 			}
-			if err := insertEdge(tx, sourceID, targetID, link.linkType, link.rawLink, subpath, link.lineStart, link.lineEnd); err != nil {
+			if err := insertEdge(tx, sourceID, targetID, link, subpath); err != nil {
 				return err
 			}
+			edges++
 		}
 	}
+	verbosef("build: inserted %d edges", edges)
 
-	if err := tx.Commit(); err != nil {
+	tx.close()
+	if err := bumpIndexVersion(dbTx); err != nil {
+		return err
+	}
+	if err := saveBuildOptions(dbTx, cfg.Build); err != nil {
+		return err
+	}
+	if root != vaultPath {
+		// Mark the index so that commands resolving from the vault root
+		// refuse it (checkFullIndex).
+		if _, err := dbTx.Exec(`INSERT INTO meta (key, value) VALUES ('base_dir', 1)`); err != nil {
+			return err
+		}
+	}
+	if err := dbTx.Commit(); err != nil {
 		return err
 	}
 
@@ -204,12 +356,27 @@ func Build(vaultPath string) error {
 		return err
 	}
 
+	// Side files of the previous index must not be applied to the new one.
+	_ = os.Remove(dbPath(vaultPath) + "-wal")
+	_ = os.Remove(dbPath(vaultPath) + "-shm")
 	if err := os.Rename(tmpPath, dbPath(vaultPath)); err != nil {
 		return err
 	}
+	verbosef("build: wrote %s", dbPath(vaultPath))
 	return nil
 }
 
+// trimBaseDir keeps the paths under base and makes them base-relative.
+func trimBaseDir(paths []string, base string) []string {
+	var out []string
+	for _, p := range paths {
+		if rest, ok := strings.CutPrefix(p, base+"/"); ok {
+			out = append(out, rest)
+		}
+	}
+	return out
+}
+
 // resolveLink resolves a linkOccur to a target node ID and subpath.
 // Returns (0, "", nil) if the link should be skipped.
 func resolveLink(db dbExecer, sourcePath string, link linkOccur, rm *resolveMaps) (int64, string, error) {
@@ -221,7 +388,7 @@ func resolveLink(db dbExecer, sourcePath string, link linkOccur, rm *resolveMaps
 
 	// Tag or frontmatter tag
 	if link.linkType == "tag" || link.linkType == "frontmatter" {
-		id, err := upsertTag(db, link.target)
+		id, err := upsertTag(db, link.target, rm.preserveTagCase)
 		if err != nil {
 			return 0, "", err
 		}
@@ -250,35 +417,45 @@ func resolveLink(db dbExecer, sourcePath string, link linkOccur, rm *resolveMaps
 		return resolvePathTarget(db, stripped, link, rm)
 	}
 
-	// Wikilink with vault-relative path (contains /, not relative): [[path/to/Note]]
-	if link.linkType == "wikilink" && !link.isBasename {
+	// Wikilink or frontmatter link with vault-relative path (contains /, not relative): [[path/to/Note]]
+	if (link.linkType == "wikilink" || link.linkType == "frontmatter-link") && !link.isBasename {
 		return resolvePathTarget(db, target, link, rm)
 	}
 
-	// Basename resolution (wikilink and markdown)
+	// Basename resolution (wikilink, markdown, and frontmatter link)
 	if link.isBasename {
 		lower := strings.ToLower(target)
+		// 0. asset first when the target has a non-.md extension
+		if prefersAsset(target) {
+			if id, ok := assetBasenameID(rm, lower); ok {
+				return id, link.subpath, nil
+			}
+		}
+		// 0.5. note in the source's folder (folder-first)
+		if path, ok := rm.localNotePath(sourcePath, lower); ok {
+			id := rm.pathToID[path]
+			return id, link.subpath, nil
+		}
 		// 1. note unique
 		if path, ok := rm.basenameToPath[lower]; ok {
 			id := rm.pathToID[path]
 			return id, link.subpath, nil
 		}
 		// 2. note root-priority
-		if path, ok := rm.rootBasenameToPath[lower]; ok {
+		if path, ok := rm.rootPath(lower); ok {
 			id := rm.pathToID[path]
 			return id, link.subpath, nil
 		}
-		// 3. asset unique
-		if path, ok := rm.assetBasenameToPath[lower]; ok {
-			id := rm.assetPathToID[path]
+		// 3. asset unique, 4. asset root-priority
+		if id, ok := assetBasenameID(rm, lower); ok {
 			return id, link.subpath, nil
 		}
-		// 4. asset root-priority
-		if path, ok := rm.assetRootBasenameToPath[lower]; ok {
-			id := rm.assetPathToID[path]
+		// 5. alias unique
+		if path, ok := rm.aliasToPath[lower]; ok {
+			id := rm.pathToID[path]
 			return id, link.subpath, nil
 		}
-		// 5. phantom fallback
+		// 6. phantom fallback
 		id, err := upsertPhantom(db, target)
 		if err != nil {
 			return 0, "", err
@@ -290,18 +467,41 @@ func resolveLink(db dbExecer, sourcePath string, link linkOccur, rm *resolveMaps
 	return resolvePathTarget(db, target, link, rm)
 }
 
+// assetBasenameID returns the asset a lowercase basename resolves to: the
+// unique asset of that name, else the one at the vault root.
+func assetBasenameID(rm *resolveMaps, lower string) (int64, bool) {
+	if path, ok := rm.assetBasenameToPath[lower]; ok {
+		return rm.assetPathToID[path], true
+	}
+	if path, ok := rm.assetRootBasenameToPath[lower]; ok && !rm.noRootPriority {
+		return rm.assetPathToID[path], true
+	}
+	return 0, false
+}
+
 // resolvePathTarget tries to find a file by path in pathSet, falling back to asset then phantom.
 func resolvePathTarget(db dbExecer, resolved string, link linkOccur, rm *resolveMaps) (int64, string, error) {
 	lower := strings.ToLower(resolved)
-	// 1. note exact path
-	if actualPath, ok := rm.pathSet[lower]; ok {
-		id := rm.pathToID[actualPath]
-		return id, link.subpath, nil
+	// A trailing slash names the folder, so only its folder note can match.
+	folderOnly := rm.folderNotes && strings.HasSuffix(link.target, "/")
+	if !folderOnly {
+		// 1. note exact path
+		if actualPath, ok := rm.pathSet[lower]; ok {
+			id := rm.pathToID[actualPath]
+			return id, link.subpath, nil
+		}
+		// 2. note with .md extension
+		if actualPath, ok := rm.pathSet[lower+".md"]; ok {
+			id := rm.pathToID[actualPath]
+			return id, link.subpath, nil
+		}
 	}
-	// 2. note with .md extension
-	if actualPath, ok := rm.pathSet[lower+".md"]; ok {
-		id := rm.pathToID[actualPath]
-		return id, link.subpath, nil
+	// 2.5. folder note: Dir → Dir/Dir.md
+	if rm.folderNotes {
+		if actualPath, ok := rm.pathSet[folderNotePath(lower)]; ok {
+			id := rm.pathToID[actualPath]
+			return id, link.subpath, nil
+		}
 	}
 	// 3. asset exact path
 	if actualPath, ok := rm.assetPathSet[lower]; ok {
@@ -320,58 +520,81 @@ func resolvePathTarget(db dbExecer, resolved string, link linkOccur, rm *resolve
 	return id, link.subpath, nil
 }
 
-func formatBuildErrors(errs []string) error {
+// BuildIssue is one user error (ambiguous or vault-escape link) that stops a build.
+type BuildIssue struct {
+	File       string
+	Line       int
+	Kind       string // "ambiguous" or "escape"
+	Message    string
+	Name       string   // ambiguous basename as written in the link
+	Candidates []string // notes sharing the ambiguous basename (empty for assets)
+}
+
+// BuildError is returned by Build when links fail validation. Error keeps the
+// plain multi-line report; Issues carries the same entries for tooling.
+// Truncated is set when collection stopped at maxBuildErrors.
+type BuildError struct {
+	Issues    []BuildIssue
+	Truncated bool
+}
+
+func (e *BuildError) Error() string {
 	hasAmbiguous := false
-	for _, e := range errs {
-		if strings.HasPrefix(e, "ambiguous link:") {
+	for _, is := range e.Issues {
+		if is.Kind == "ambiguous" {
 			hasAmbiguous = true
 			break
 		}
 	}
 
-	if len(errs) == 1 {
-		s := errs[0]
+	if len(e.Issues) == 1 {
+		s := e.Issues[0].Message
 		if hasAmbiguous {
 			s += "\nhint: run 'mdhop disambiguate --scan --name <basename>' to resolve ambiguous links"
 		}
-		return fmt.Errorf("%s", s)
+		return s
 	}
 	var b strings.Builder
-	for _, e := range errs {
-		b.WriteString(e)
+	for _, is := range e.Issues {
+		b.WriteString(is.Message)
 		b.WriteByte('\n')
 	}
-	if len(errs) >= maxBuildErrors {
+	if e.Truncated {
 		fmt.Fprintf(&b, "too many errors (first %d shown)", maxBuildErrors)
 	} else {
-		fmt.Fprintf(&b, "%d errors total", len(errs))
+		fmt.Fprintf(&b, "%d errors total", len(e.Issues))
 	}
 	if hasAmbiguous {
 		b.WriteString("\nhint: run 'mdhop disambiguate --scan --name <basename>' to resolve ambiguous links")
 	}
-	return fmt.Errorf("%s", b.String())
+	return b.String()
 }
 
-func collectMarkdownFiles(vaultPath string) ([]string, error) {
-	var files []string
-	err := filepath.WalkDir(vaultPath, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			if d.Name() == dataDirName {
-				return filepath.SkipDir
-			}
-			return nil
+// noteCandidates returns the note paths whose basename matches name, sorted.
+func noteCandidates(files []string, name string) []string {
+	key := strings.ToLower(name)
+	var out []string
+	for _, f := range files {
+		if basenameKey(f) == key {
+			out = append(out, f)
 		}
-		if strings.HasSuffix(strings.ToLower(d.Name()), ".md") {
-			rel, err := filepath.Rel(vaultPath, path)
-			if err != nil {
-				return err
-			}
-			files = append(files, NormalizePath(rel))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func formatBuildErrors(issues []BuildIssue) error {
+	return &BuildError{Issues: issues, Truncated: len(issues) >= maxBuildErrors}
+}
+
+func collectMarkdownFiles(vaultPath string, cfg BuildConfig) ([]string, error) {
+	var files []string
+	err := walkVault(vaultPath, cfg, func(name string) bool {
+		return name == dataDirName
+	}, func(rel, name string) {
+		if strings.HasSuffix(strings.ToLower(name), ".md") {
+			files = append(files, rel)
 		}
-		return nil
 	})
 	return files, err
 }
@@ -394,37 +617,116 @@ func countAssetBasenames(files []string) map[string]int {
 
 // collectAssetFiles collects all non-.md files in the vault, skipping hidden
 // files/directories and the .mdhop directory.
-func collectAssetFiles(vaultPath string) ([]string, error) {
+func collectAssetFiles(vaultPath string, cfg BuildConfig) ([]string, error) {
 	var files []string
-	err := filepath.WalkDir(vaultPath, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
+	err := walkVault(vaultPath, cfg, func(name string) bool {
+		return name == dataDirName || strings.HasPrefix(name, ".")
+	}, func(rel, name string) {
+		// Skip hidden files, .md files (those are notes), and note backups
+		// written by --keep-backup.
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(lower, ".md") || strings.HasSuffix(lower, ".md"+backupSuffix) {
+			return
+		}
+		files = append(files, rel)
+	})
+	return files, err
+}
+
+// walkVault calls visit with the vault-relative path and base name of every
+// file under vaultPath, skipping directories for which skipDir returns true.
+// With FollowSymlinks, symlinked directories are descended into and their files
+// reported at the link path. Links whose real path lies outside the vault are
+// skipped, as are links back to a directory already on the current walk path,
+// so link cycles terminate. With RespectGitignore, gitignored files are skipped
+// and gitignored directories are not entered.
+func walkVault(vaultPath string, cfg BuildConfig, skipDir func(name string) bool, visit func(rel, name string)) error {
+	followSymlinks := cfg.FollowSymlinks
+	var ignore *gitignoreMatcher
+	if cfg.RespectGitignore {
+		ignore = newGitignoreMatcher()
+	}
+	root := vaultPath
+	if followSymlinks {
+		var err error
+		if root, err = realPath(vaultPath); err != nil {
 			return err
 		}
-		name := d.Name()
-		if d.IsDir() {
-			if name == dataDirName || strings.HasPrefix(name, ".") {
-				return filepath.SkipDir
+	}
+
+	// chain holds the real directories containing each followed link, so a
+	// link resolving to one of them (or an ancestor) would revisit itself.
+	var walk func(dir, relPrefix string, chain []string) error
+	walk = func(dir, relPrefix string, chain []string) error {
+		return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
 			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			if relPrefix != "" {
+				rel = filepath.Join(relPrefix, rel)
+			}
+			if d.IsDir() {
+				if path != dir && (skipDir(d.Name()) || ignore.match(rel, true)) {
+					return filepath.SkipDir
+				}
+				if ignore != nil {
+					ignore.load(path, rel)
+				}
+				return nil
+			}
+			if followSymlinks && d.Type()&os.ModeSymlink != 0 {
+				real, err := realPath(path)
+				if err != nil {
+					return nil // dangling link
+				}
+				if !isWithinDir(real, root) {
+					return nil // resolves outside the vault
+				}
+				info, err := os.Stat(real)
+				if err != nil {
+					return err
+				}
+				if info.IsDir() {
+					if skipDir(d.Name()) || ignore.match(rel, true) {
+						return nil
+					}
+					next := append(chain[:len(chain):len(chain)], filepath.Dir(path))
+					for _, c := range next {
+						if isWithinDir(c, real) {
+							return nil // cycle
+						}
+					}
+					return walk(real, rel, next)
+				}
+			}
+			if ignore.match(rel, false) {
+				return nil
+			}
+			visit(NormalizePath(rel), d.Name())
 			return nil
-		}
-		// Skip hidden files.
-		if strings.HasPrefix(name, ".") {
-			return nil
-		}
-		// Skip .md files (those are notes).
-		if strings.HasSuffix(strings.ToLower(name), ".md") {
-			return nil
-		}
-		rel, err := filepath.Rel(vaultPath, path)
-		if err != nil {
-			return err
-		}
-		files = append(files, NormalizePath(rel))
-		return nil
-	})
-	return files, err
+		})
+	}
+	return walk(root, "", nil)
 }
 
+// isWithinDir reports whether path is dir or lies beneath it.
+func isWithinDir(path, dir string) bool {
+	r, err := filepath.Rel(dir, path)
+	return err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator))
+}
+
+// realPath resolves symlinks in p and returns it as an absolute path.
+func realPath(p string) (string, error) {
+	real, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(real)
+}
 
 func escapesVault(fromPath, target string) bool {
 	base := filepath.Dir(fromPath)
@@ -440,3 +742,80 @@ func pathEscapesVault(target string) bool {
 	n := NormalizePath(stripped)
 	return n == ".." || strings.HasPrefix(n, "../")
 }
+
+// parsedFile is a note read and parsed by build.
+type parsedFile struct {
+	path     string
+	mtime    int64
+	links    []linkOccur
+	headings []headingOccur
+	external []externalLinkOccur
+	aliases  []string
+	body     string
+}
+
+// parseNoteFiles reads and parses files (relative to root) with up to threads
+// workers. Results are in files order, and the error returned is the one of
+// the first failing file, so the outcome does not depend on threads.
+func parseNoteFiles(root string, files []string, cfg BuildConfig, threads int) ([]parsedFile, error) {
+	parsed := make([]parsedFile, len(files))
+	errs := make([]error, len(files))
+	parse := func(i int) {
+		parsed[i], errs[i] = parseNoteFile(filepath.Join(root, files[i]), files[i], cfg)
+	}
+	if threads <= 1 || len(files) <= 1 {
+		for i := range files {
+			if parse(i); errs[i] != nil {
+				return nil, errs[i]
+			}
+		}
+		return parsed, nil
+	}
+
+	if threads > len(files) {
+		threads = len(files)
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < threads; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				parse(i)
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+func parseNoteFile(fullPath, rel string, cfg BuildConfig) (parsedFile, error) {
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return parsedFile{}, err
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return parsedFile{}, err
+	}
+	body := string(content)
+	return parsedFile{
+		path:     rel,
+		mtime:    info.ModTime().Unix(),
+		links:    parseIndexLinks(body, cfg),
+		headings: parseHeadings(body),
+		external: parseExternalLinks(body),
+		aliases:  parseAliases(body),
+		body:     body,
+	}, nil
+}
@@ -2,8 +2,12 @@ package core
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
@@ -616,6 +620,25 @@ func TestBuildTagsCodeFenceExcluded(t *testing.T) {
 	}
 }
 
+func TestBuildLinksInInfoStringFenceExcluded(t *testing.T) {
+	// Any ``` fence is skipped whatever its info string, so a custom
+	// "mdhop-ignore" fence needs no configuration.
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "# A\n",
+		"B.md": "```mdhop-ignore\n[[A]]\n[[Nowhere]]\n```\n[[A]]\n",
+	})
+	buildVault(t, vault)
+
+	edges := queryEdges(t, dbPath(vault), "B.md")
+	if len(edges) != 1 || edges[0].targetKey != noteKey("A.md") || edges[0].lineStart != 5 {
+		t.Errorf("edges = %+v, want only [[A]] on line 5", edges)
+	}
+	for _, p := range queryNodes(t, dbPath(vault), "phantom") {
+		t.Errorf("link in ignore fence created phantom %s", p.name)
+	}
+}
+
 func TestBuildTagsSharedAcrossFiles(t *testing.T) {
 	vault := copyVault(t, "vault_build_tags")
 	if err := Build(vault); err != nil {
@@ -896,6 +919,123 @@ func TestBuildRootPriorityRoundTrip(t *testing.T) {
 	}
 }
 
+func TestBuildRootPriorityDisabled(t *testing.T) {
+	// Same vault as TestBuildRootPriority, but root_priority: false makes
+	// [[A]] ambiguous between A.md and sub/A.md.
+	vault := copyVault(t, "vault_build_root_priority")
+	writeVaultFiles(t, vault, map[string]string{"mdhop.yaml": "build:\n  root_priority: false\n"})
+
+	err := Build(vault)
+	if err == nil {
+		t.Fatal("expected build error for ambiguous link (root priority disabled)")
+	}
+	if !strings.Contains(err.Error(), "ambiguous link: A in B.md") {
+		t.Errorf("error = %v, want ambiguous link: A in B.md", err)
+	}
+
+	// An explicit root_priority: true keeps the default.
+	writeVaultFiles(t, vault, map[string]string{"mdhop.yaml": "build:\n  root_priority: true\n"})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build with root_priority: true: %v", err)
+	}
+}
+
+func TestAddRootPriorityDisabled(t *testing.T) {
+	// [[A]] points at the unique root A.md. Adding sub/A.md makes it
+	// ambiguous, and a root file has no path form to disambiguate to.
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"mdhop.yaml": "build:\n  root_priority: false\n",
+		"A.md":       "# A at root\n",
+		"B.md":       "[[A]]\n",
+	})
+	buildVault(t, vault)
+	writeVaultFiles(t, vault, map[string]string{"sub/A.md": "# A in sub\n"})
+
+	_, err := Add(vault, AddOptions{Files: []string{"sub/A.md"}, AutoDisambiguate: true})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous error, got: %v", err)
+	}
+}
+
+func TestBuildFolderFirst(t *testing.T) {
+	// a/Note.md and b/Note.md share a basename with no root file. Under
+	// folder-first, [[Note]] in each folder resolves to its sibling.
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"mdhop.yaml": "build:\n  link_resolution: folder-first\n",
+		"a/Note.md":  "# A note\n",
+		"a/X.md":     "[[Note]]\n",
+		"b/Note.md":  "# B note\n",
+		"b/Y.md":     "[x](Note.md)\n",
+	})
+	buildVault(t, vault)
+
+	for _, tt := range []struct{ from, link, want string }{
+		{"a/X.md", "[[Note]]", "a/Note.md"},
+		{"b/Y.md", "[x](Note.md)", "b/Note.md"},
+	} {
+		got, err := Resolve(vault, tt.from, tt.link)
+		if err != nil {
+			t.Fatalf("resolve %s in %s: %v", tt.link, tt.from, err)
+		}
+		if got.Path != tt.want {
+			t.Errorf("resolve %s in %s = %q, want %q", tt.link, tt.from, got.Path, tt.want)
+		}
+	}
+
+	// A folder without its own Note.md still sees a genuine ambiguity.
+	writeVaultFiles(t, vault, map[string]string{"c/Z.md": "[[Note]]\n"})
+	err := Build(vault)
+	if err == nil || !strings.Contains(err.Error(), "ambiguous link: Note in c/Z.md") {
+		t.Fatalf("err = %v, want ambiguous link: Note in c/Z.md", err)
+	}
+
+	// The default resolution reports the same links as ambiguous.
+	writeVaultFiles(t, vault, map[string]string{"mdhop.yaml": "build:\n  link_resolution: global\n", "c/Z.md": "\n"})
+	if err := Build(vault); err == nil || !strings.Contains(err.Error(), "ambiguous link: Note in a/X.md") {
+		t.Fatalf("err = %v, want ambiguous link: Note in a/X.md", err)
+	}
+
+	writeVaultFiles(t, vault, map[string]string{"mdhop.yaml": "build:\n  link_resolution: nearest\n"})
+	if _, err := LoadConfig(vault); err == nil || !strings.Contains(err.Error(), "invalid build.link_resolution") {
+		t.Errorf("err = %v, want invalid build.link_resolution", err)
+	}
+}
+
+func TestAddFolderFirstCapture(t *testing.T) {
+	// [[Note]] in sub/B.md points at the root Note.md. Adding sub/Note.md
+	// would take it over under folder-first, and a root file has no path form
+	// to keep the link on it.
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"mdhop.yaml": "build:\n  link_resolution: folder-first\n",
+		"Note.md":    "# Root note\n",
+		"sub/B.md":   "[[Note]]\n",
+		"C.md":       "[[Note]]\n",
+	})
+	buildVault(t, vault)
+	writeVaultFiles(t, vault, map[string]string{"sub/Note.md": "# Sub note\n"})
+
+	_, err := Add(vault, AddOptions{Files: []string{"sub/Note.md"}, AutoDisambiguate: true})
+	if err == nil || !strings.Contains(err.Error(), "would change the target of existing links: [[Note]] in sub/B.md") {
+		t.Fatalf("err = %v, want target change error for sub/B.md", err)
+	}
+
+	// Elsewhere the new note takes over nothing.
+	if err := os.Remove(filepath.Join(vault, "sub/Note.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeVaultFiles(t, vault, map[string]string{"other/Note.md": "# Other note\n"})
+	if _, err := Add(vault, AddOptions{Files: []string{"other/Note.md"}}); err != nil {
+		t.Fatalf("add other/Note.md: %v", err)
+	}
+	got, err := Resolve(vault, "sub/B.md", "[[Note]]")
+	if err != nil || got.Path != "Note.md" {
+		t.Errorf("resolve [[Note]] in sub/B.md = %+v, %v; want Note.md", got, err)
+	}
+}
+
 func TestBuildExcludesMdhopDir(t *testing.T) {
 	vault := copyVault(t, "vault_build_basic")
 	// Create a .md file inside .mdhop dir — it should be excluded from the index.
@@ -968,6 +1108,58 @@ func TestBuildIdempotent(t *testing.T) {
 	}
 }
 
+func TestBuildThreadsSameIndex(t *testing.T) {
+	vault := copyVault(t, "vault_build_full")
+
+	if err := BuildWithOptions(vault, BuildOptions{Threads: 1}); err != nil {
+		t.Fatalf("build --threads 1: %v", err)
+	}
+	serial := dumpTables(t, dbPath(vault), "nodes", "edges", "headings", "aliases", "external_links")
+	if err := BuildWithOptions(vault, BuildOptions{Threads: 4}); err != nil {
+		t.Fatalf("build --threads 4: %v", err)
+	}
+	parallel := dumpTables(t, dbPath(vault), "nodes", "edges", "headings", "aliases", "external_links")
+	if !reflect.DeepEqual(serial, parallel) {
+		t.Errorf("index differs between 1 and 4 threads:\n%v\nvs\n%v", serial, parallel)
+	}
+
+	// Build errors are reported in file order whatever the thread count.
+	errVault := copyVault(t, "vault_build_multi_error")
+	err1 := BuildWithOptions(errVault, BuildOptions{Threads: 1})
+	err4 := BuildWithOptions(errVault, BuildOptions{Threads: 4})
+	if err1 == nil || err4 == nil || err1.Error() != err4.Error() {
+		t.Errorf("errors differ:\n%v\nvs\n%v", err1, err4)
+	}
+}
+
+// dumpTables returns every row of tables, in rowid order, as strings.
+func dumpTables(t *testing.T, dbp string, tables ...string) []string {
+	t.Helper()
+	db := openTestDB(t, dbp)
+	defer db.Close()
+	var out []string
+	for _, table := range tables {
+		rows, err := db.Query("SELECT * FROM " + table + " ORDER BY rowid")
+		if err != nil {
+			t.Fatalf("dump %s: %v", table, err)
+		}
+		cols, _ := rows.Columns()
+		for rows.Next() {
+			vals := make([]any, len(cols))
+			ptrs := make([]any, len(cols))
+			for i := range vals {
+				ptrs[i] = &vals[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				t.Fatalf("scan %s: %v", table, err)
+			}
+			out = append(out, fmt.Sprintf("%s%v", table, vals))
+		}
+		rows.Close()
+	}
+	return out
+}
+
 func TestBuildCollectsMultipleErrors(t *testing.T) {
 	vault := copyVault(t, "vault_build_multi_error")
 	err := Build(vault)
@@ -997,6 +1189,31 @@ func TestBuildCollectsMultipleErrors(t *testing.T) {
 	}
 }
 
+func TestBuildErrorStructured(t *testing.T) {
+	vault := copyVault(t, "vault_build_multi_error")
+	err := Build(vault)
+	var be *BuildError
+	if !errors.As(err, &be) {
+		t.Fatalf("expected *BuildError, got %T: %v", err, err)
+	}
+	if be.Truncated {
+		t.Error("Truncated should be false for 3 errors")
+	}
+	kinds := map[string]int{}
+	for _, is := range be.Issues {
+		kinds[is.Kind]++
+		if is.File == "" || is.Line != 1 {
+			t.Errorf("unexpected position: %+v", is)
+		}
+		if is.Kind == "ambiguous" && len(is.Candidates) != 2 {
+			t.Errorf("expected 2 candidates, got %+v", is)
+		}
+	}
+	if kinds["ambiguous"] != 2 || kinds["escape"] != 1 {
+		t.Errorf("kinds = %v, want 2 ambiguous + 1 escape", kinds)
+	}
+}
+
 func TestBuildSingleErrorFormatUnchanged(t *testing.T) {
 	vault := copyVault(t, "vault_build_conflict")
 	err := Build(vault)
@@ -1053,6 +1270,10 @@ func TestBuildErrorCapAtMax(t *testing.T) {
 	if !strings.Contains(msg, "too many errors (first 5 shown)") {
 		t.Errorf("missing cap summary: %s", msg)
 	}
+	var be *BuildError
+	if !errors.As(err, &be) || !be.Truncated {
+		t.Errorf("expected truncated *BuildError, got %#v", err)
+	}
 	if !strings.Contains(msg, "hint: run 'mdhop disambiguate") {
 		t.Errorf("expected disambiguate hint, got: %s", msg)
 	}
@@ -1219,3 +1440,682 @@ func TestBuildExclude_InvalidPattern(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
+
+func TestBuildExclude_Option(t *testing.T) {
+	vault := copyVault(t, "vault_build_exclude")
+	if err := os.Remove(filepath.Join(vault, "mdhop.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if err := BuildWithOptions(vault, BuildOptions{ExcludePaths: []string{"daily/**"}}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	notes := queryNodes(t, dbPath(vault), "note")
+	for _, n := range notes {
+		if strings.HasPrefix(n.path, "daily/") {
+			t.Errorf("excluded file should not be indexed: %s", n.path)
+		}
+	}
+	if len(notes) != 3 {
+		t.Errorf("expected 3 notes (A, B, templates/T), got %d", len(notes))
+	}
+	// [[D]] must not resolve to the excluded daily/D.md.
+	for _, e := range queryEdges(t, dbPath(vault), "A.md") {
+		if e.rawLink == "[[D]]" && e.targetType != "phantom" {
+			t.Errorf("[[D]] should be phantom, got %s", e.targetType)
+		}
+	}
+
+	// The exclusion applies to that invocation only.
+	if err := Build(vault); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	if notes := queryNodes(t, dbPath(vault), "note"); len(notes) != 4 {
+		t.Errorf("expected 4 notes after rebuild without --exclude, got %d", len(notes))
+	}
+}
+
+func TestBuildExclude_OptionAppendsToConfig(t *testing.T) {
+	vault := copyVault(t, "vault_build_exclude")
+	// mdhop.yaml excludes daily/* and templates/*; the option adds B.md.
+	if err := BuildWithOptions(vault, BuildOptions{ExcludePaths: []string{"B.md"}}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	notes := queryNodes(t, dbPath(vault), "note")
+	if len(notes) != 1 || notes[0].path != "A.md" {
+		t.Errorf("notes = %+v, want only A.md", notes)
+	}
+}
+
+func TestBuildFrontmatterLinks(t *testing.T) {
+	vault := copyVault(t, "vault_build_frontmatter_links")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	targets := func(src string) map[string]string {
+		got := make(map[string]string)
+		for _, e := range queryEdges(t, dbPath(vault), src) {
+			if e.linkType == "frontmatter-link" {
+				got[e.rawLink] = e.targetKey
+			}
+		}
+		return got
+	}
+
+	inline := targets("Inline.md")
+	wantInline := map[string]string{
+		"Design":    "note:path:Design.md",
+		"DesignDoc": "note:path:DesignDoc.md",
+		"sub/Impl":  "note:path:sub/Impl.md",
+	}
+	if len(inline) != len(wantInline) {
+		t.Fatalf("Inline.md frontmatter links = %v, want %v", inline, wantInline)
+	}
+	for raw, key := range wantInline {
+		if inline[raw] != key {
+			t.Errorf("Inline.md %s → %s, want %s", raw, inline[raw], key)
+		}
+	}
+
+	block := targets("Block.md")
+	wantBlock := map[string]string{
+		"Design":      "note:path:Design.md",
+		"sub/Impl.md": "note:path:sub/Impl.md",
+		"Ghost":       "phantom:name:ghost",
+	}
+	if len(block) != len(wantBlock) {
+		t.Fatalf("Block.md frontmatter links = %v, want %v", block, wantBlock)
+	}
+	for raw, key := range wantBlock {
+		if block[raw] != key {
+			t.Errorf("Block.md %s → %s, want %s", raw, block[raw], key)
+		}
+	}
+}
+
+func TestBuildFrontmatterLinksDisabled(t *testing.T) {
+	vault := copyVault(t, "vault_build_frontmatter_links")
+	cfg := "build:\n  frontmatter_link_keys: []\n"
+	if err := os.WriteFile(filepath.Join(vault, "mdhop.yaml"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	for _, e := range queryEdges(t, dbPath(vault), "Inline.md") {
+		if e.linkType == "frontmatter-link" {
+			t.Errorf("unexpected frontmatter link: %+v", e)
+		}
+	}
+}
+
+// setupSymlinkVault creates a vault whose "linked" directory is a symlink to
+// "shared", a sibling of the vault, plus a link to a directory outside it and
+// a link back to the vault root.
+func setupSymlinkVault(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	vault := filepath.Join(root, "vault")
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(filepath.Join(vault, "Index.md"), "[[Shared]]\n")
+	writeFile(filepath.Join(vault, "real", "Shared.md"), "# Shared\n")
+	writeFile(filepath.Join(root, "outside", "Secret.md"), "# Secret\n")
+	links := map[string]string{
+		filepath.Join(vault, "linked"):       filepath.Join(vault, "real"),
+		filepath.Join(vault, "escape"):       filepath.Join(root, "outside"),
+		filepath.Join(vault, "real", "loop"): vault,
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+	return vault
+}
+
+func notePaths(t *testing.T, vault string) []string {
+	t.Helper()
+	var paths []string
+	for _, n := range queryNodes(t, dbPath(vault), "note") {
+		paths = append(paths, n.path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestBuildFollowSymlinks(t *testing.T) {
+	vault := setupSymlinkVault(t)
+	// The link path "linked/" and the real path "real/" both hold Shared.md,
+	// so the basename link is ambiguous; point it at the link path explicitly.
+	if err := os.WriteFile(filepath.Join(vault, "Index.md"), []byte("[[linked/Shared]]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := BuildWithOptions(vault, BuildOptions{FollowSymlinks: true}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	got := notePaths(t, vault)
+	want := []string{"Index.md", "linked/Shared.md", "real/Shared.md"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("notes = %v, want %v", got, want)
+	}
+	edges := queryEdges(t, dbPath(vault), "Index.md")
+	if len(edges) != 1 || edges[0].targetKey != "note:path:linked/Shared.md" {
+		t.Errorf("edges = %+v, want link to linked/Shared.md", edges)
+	}
+}
+
+func TestBuildFollowSymlinks_Config(t *testing.T) {
+	vault := setupSymlinkVault(t)
+	if err := os.RemoveAll(filepath.Join(vault, "real", "loop")); err != nil {
+		t.Fatal(err)
+	}
+	cfg := "build:\n  follow_symlinks: true\n  exclude_paths:\n    - \"real/*\"\n"
+	if err := os.WriteFile(filepath.Join(vault, "mdhop.yaml"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	got := notePaths(t, vault)
+	want := []string{"Index.md", "linked/Shared.md"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("notes = %v, want %v", got, want)
+	}
+}
+
+func TestBuildFollowSymlinks_Disabled(t *testing.T) {
+	vault := setupSymlinkVault(t)
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	got := notePaths(t, vault)
+	want := []string{"Index.md", "real/Shared.md"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("notes = %v, want %v", got, want)
+	}
+}
+
+func setupGitignoreVault(t *testing.T) string {
+	t.Helper()
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		".gitignore":                 "node_modules/\n/dist\n*.png\n",
+		"Index.md":                   "[[Keep]]\n",
+		"node_modules/pkg/README.md": "# pkg\n",
+		"dist/Out.md":                "# out\n",
+		"sub/dist/Kept.md":           "# anchored /dist only matches at the root\n",
+		"img/a.png":                  "png",
+		"img/b.jpg":                  "jpg",
+		"drafts/.gitignore":          "*.md\n!Keep.md\n",
+		"drafts/Keep.md":             "# keep\n",
+		"drafts/Scratch.md":          "# scratch\n",
+	})
+	return vault
+}
+
+func TestBuildRespectGitignore(t *testing.T) {
+	vault := setupGitignoreVault(t)
+	if err := BuildWithOptions(vault, BuildOptions{RespectGitignore: true}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	got := notePaths(t, vault)
+	want := []string{"Index.md", "drafts/Keep.md", "sub/dist/Kept.md"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("notes = %v, want %v", got, want)
+	}
+	var assets []string
+	for _, n := range queryNodes(t, dbPath(vault), "asset") {
+		assets = append(assets, n.path)
+	}
+	if strings.Join(assets, ",") != "img/b.jpg" {
+		t.Errorf("assets = %v, want [img/b.jpg]", assets)
+	}
+}
+
+func TestBuildRespectGitignore_ConfigWithExclude(t *testing.T) {
+	vault := setupGitignoreVault(t)
+	cfg := "build:\n  respect_gitignore: true\n  exclude_paths:\n    - \"sub/*\"\n"
+	if err := os.WriteFile(filepath.Join(vault, "mdhop.yaml"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	got := notePaths(t, vault)
+	want := []string{"Index.md", "drafts/Keep.md"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("notes = %v, want %v", got, want)
+	}
+}
+
+func TestBuildRespectGitignore_Disabled(t *testing.T) {
+	vault := setupGitignoreVault(t)
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	got := notePaths(t, vault)
+	if len(got) != 6 {
+		t.Errorf("notes = %v, want all 6 notes", got)
+	}
+}
+
+// setupFolderNoteVault creates a vault using the folder note convention.
+// folderNotes controls build.folder_notes in mdhop.yaml.
+func setupFolderNoteVault(t *testing.T, folderNotes bool, extra map[string]string) string {
+	t.Helper()
+	vault := t.TempDir()
+	files := map[string]string{
+		"Projects/Projects.md":  "# Projects\n",
+		"Area/Topic/Topic.md":   "# Topic\n",
+		"Src.md":                "[[Projects/]]\n[[Area/Topic]]\n[[Projects]]\n",
+		"Area/Topic/Sibling.md": "[[./]]\n",
+	}
+	if folderNotes {
+		files["mdhop.yaml"] = "build:\n  folder_notes: true\n"
+	}
+	for rel, content := range extra {
+		files[rel] = content
+	}
+	for rel, content := range files {
+		p := filepath.Join(vault, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return vault
+}
+
+func TestBuildFolderNotes(t *testing.T) {
+	vault := setupFolderNoteVault(t, true, nil)
+	if err := Build(vault); err != nil {
+		t.Fatal(err)
+	}
+	edges := queryEdges(t, dbPath(vault), "Src.md")
+	want := []string{
+		"note:path:Projects/Projects.md",
+		"note:path:Area/Topic/Topic.md",
+		"note:path:Projects/Projects.md",
+	}
+	if len(edges) != len(want) {
+		t.Fatalf("expected %d edges, got %+v", len(want), edges)
+	}
+	for i, e := range edges {
+		if e.targetKey != want[i] {
+			t.Errorf("edge %d (%s) → %s, want %s", i, e.rawLink, e.targetKey, want[i])
+		}
+	}
+
+	sib := queryEdges(t, dbPath(vault), "Area/Topic/Sibling.md")
+	if len(sib) != 1 || sib[0].targetKey != "note:path:Area/Topic/Topic.md" {
+		t.Errorf("[[./]] from Area/Topic should resolve to its folder note, got %+v", sib)
+	}
+
+	res, err := Resolve(vault, "Src.md", "[[Area/Topic]]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Path != "Area/Topic/Topic.md" {
+		t.Errorf("resolve path = %s, want Area/Topic/Topic.md", res.Path)
+	}
+}
+
+func TestBuildFolderNotes_Disabled(t *testing.T) {
+	vault := setupFolderNoteVault(t, false, nil)
+	if err := Build(vault); err != nil {
+		t.Fatal(err)
+	}
+	edges := queryEdges(t, dbPath(vault), "Src.md")
+	if len(edges) != 3 {
+		t.Fatalf("expected 3 edges, got %+v", edges)
+	}
+	if edges[1].targetType != "phantom" {
+		t.Errorf("[[Area/Topic]] should be phantom without folder_notes, got %+v", edges[1])
+	}
+	// Basename links match the folder note by basename as before.
+	if edges[2].targetKey != "note:path:Projects/Projects.md" {
+		t.Errorf("[[Projects]] → %s", edges[2].targetKey)
+	}
+}
+
+func TestBuildFolderNotes_Ambiguous(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra map[string]string
+		want  string
+	}{
+		{"basename", map[string]string{"Projects.md": "# real\n"}, "ambiguous link: Projects in Src.md"},
+		{"path", map[string]string{"Area/Topic.md": "# real\n"}, "ambiguous link: Area/Topic in Src.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := setupFolderNoteVault(t, true, tt.extra)
+			err := Build(vault)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+			// A trailing slash picks the folder note explicitly and is not reported.
+			if strings.Contains(err.Error(), "Projects/ in") {
+				t.Errorf("trailing-slash link reported as ambiguous: %v", err)
+			}
+		})
+	}
+}
+
+func TestBuildLogLevels(t *testing.T) {
+	defer SetLogLevel(LogNormal)
+	defer SetLogOutput(os.Stderr)
+
+	for _, tc := range []struct {
+		name  string
+		level LogLevel
+		want  bool // progress expected
+	}{
+		{"quiet", LogQuiet, false},
+		{"normal", LogNormal, false},
+		{"verbose", LogVerbose, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			vault := copyVault(t, "vault_build_basic")
+			var buf strings.Builder
+			SetLogOutput(&buf)
+			SetLogLevel(tc.level)
+
+			if err := Build(vault); err != nil {
+				t.Fatalf("build: %v", err)
+			}
+			got := buf.String()
+			if !tc.want {
+				if got != "" {
+					t.Errorf("unexpected output: %q", got)
+				}
+				return
+			}
+			for _, s := range []string{"build: found ", "build: inserted ", "edges", "build: wrote "} {
+				if !strings.Contains(got, s) {
+					t.Errorf("output missing %q:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildPercentEncodedMarkdownLink(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"sub/My Note.md": "# My Note\n",
+		"A.md":           "[n](sub/My%20Note.md)\n[m](./sub/My%20Note.md#My%20Note)\n",
+	})
+
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	edges := queryEdges(t, dbPath(vault), "A.md")
+	if len(edges) != 2 {
+		t.Fatalf("expected 2 edges, got %d: %+v", len(edges), edges)
+	}
+	for _, e := range edges {
+		if e.targetKey != noteKey("sub/My Note.md") {
+			t.Errorf("edge %s → %s, want note sub/My Note.md", e.rawLink, e.targetKey)
+		}
+	}
+	if phantoms := queryNodes(t, dbPath(vault), "phantom"); len(phantoms) != 0 {
+		t.Errorf("unexpected phantoms: %+v", phantoms)
+	}
+}
+
+// writeVaultFiles writes files (vault-relative path → content) under vault.
+func writeVaultFiles(t *testing.T, vault string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(vault, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuildBaseDir(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":            "# outside A\n",
+		"Top.md":          "[[A]]\n",
+		"sub/A.md":        "# A\n",
+		"sub/x/A.md":      "# nested A\n",
+		"sub/x/Note.md":   "[[A]] [[x/A]] [[Top]]\n",
+		"sub/img/pic.png": "png",
+		"sub/Index.md":    "![[pic.png]] [up](../Top.md)\n",
+	})
+
+	// ../Top.md leaves the base dir.
+	err := BuildWithOptions(vault, BuildOptions{BaseDir: "sub"})
+	if err == nil || !strings.Contains(err.Error(), "link escapes vault: [up](../Top.md) in sub/Index.md") {
+		t.Fatalf("expected escape error, got %v", err)
+	}
+
+	writeVaultFiles(t, vault, map[string]string{"sub/Index.md": "![[pic.png]]\n"})
+	if err := BuildWithOptions(vault, BuildOptions{BaseDir: "sub/"}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	notes := queryNodes(t, dbPath(vault), "note")
+	var paths []string
+	for _, n := range notes {
+		paths = append(paths, n.path)
+	}
+	sort.Strings(paths)
+	if got := strings.Join(paths, ","); got != "sub/A.md,sub/Index.md,sub/x/A.md,sub/x/Note.md" {
+		t.Errorf("notes = %s", got)
+	}
+
+	targets := make(map[string]string)
+	for _, e := range queryEdges(t, dbPath(vault), "sub/x/Note.md") {
+		targets[e.rawLink] = e.targetKey
+	}
+	// sub/A.md is the root file for basename A within the base dir.
+	if got := targets["[[A]]"]; got != noteKey("sub/A.md") {
+		t.Errorf("[[A]] → %s, want sub/A.md", got)
+	}
+	if got := targets["[[x/A]]"]; got != noteKey("sub/x/A.md") {
+		t.Errorf("[[x/A]] → %s, want sub/x/A.md", got)
+	}
+	// Files outside the base dir are not indexed.
+	if got := targets["[[Top]]"]; got != phantomKey("Top") {
+		t.Errorf("[[Top]] → %s, want phantom", got)
+	}
+	edges := queryEdges(t, dbPath(vault), "sub/Index.md")
+	if len(edges) != 1 || edges[0].targetKey != assetKey("sub/img/pic.png") {
+		t.Errorf("Index edges = %+v, want asset sub/img/pic.png", edges)
+	}
+}
+
+// TestBuildBaseDirRefusedByOtherCommands checks that commands resolving
+// from the vault root refuse a base-dir index instead of misresolving it,
+// until a plain build replaces it.
+func TestBuildBaseDirRefusedByOtherCommands(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"other/A.md":    "# other\n",
+		"sub/A.md":      "[[x/Note]]\n",
+		"sub/x/Note.md": "# Note\n",
+	})
+	if err := BuildWithOptions(vault, BuildOptions{BaseDir: "sub"}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	checks := map[string]func() error{
+		"verify": func() error { _, err := Verify(vault); return err },
+		"update": func() error { _, err := Update(vault, UpdateOptions{Files: []string{"sub/A.md"}}); return err },
+		"add": func() error {
+			writeVaultFiles(t, vault, map[string]string{"sub/B.md": "# B\n"})
+			_, err := Add(vault, AddOptions{Files: []string{"sub/B.md"}})
+			return err
+		},
+		"move":    func() error { _, err := Move(vault, MoveOptions{From: "sub/A.md", To: "sub/C.md"}); return err },
+		"resolve": func() error { _, err := Resolve(vault, "sub/A.md", "[[x/Note]]"); return err },
+	}
+	for name, run := range checks {
+		if err := run(); err == nil || !strings.Contains(err.Error(), "--base-dir") {
+			t.Errorf("%s: expected base-dir error, got %v", name, err)
+		}
+	}
+	edges := queryEdges(t, dbPath(vault), "sub/A.md")
+	if len(edges) != 1 || edges[0].targetKey != noteKey("sub/x/Note.md") {
+		t.Errorf("sub/A.md edges = %+v, want sub/x/Note.md", edges)
+	}
+
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if _, err := Verify(vault); err != nil {
+		t.Errorf("verify after full build: %v", err)
+	}
+}
+
+func TestBuildBaseDirInvalid(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{"A.md": "# A\n"})
+	for _, base := range []string{"missing", "../other"} {
+		if err := BuildWithOptions(vault, BuildOptions{BaseDir: base}); err == nil {
+			t.Errorf("BaseDir %q: expected error", base)
+		}
+	}
+}
+
+func TestBuildFailureRemovesTempWALFiles(t *testing.T) {
+	vault := copyVault(t, "vault_build_full")
+	// A non-empty directory at the index path makes the final rename fail
+	// after the temp DB has been written.
+	if err := os.MkdirAll(filepath.Join(dbPath(vault), "block"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(vault); err == nil {
+		t.Fatal("expected build error when the index path is a directory")
+	}
+	for _, suffix := range []string{".tmp", ".tmp-wal", ".tmp-shm"} {
+		if _, err := os.Stat(dbPath(vault) + suffix); err == nil {
+			t.Errorf("%s should be cleaned up on failure", suffix)
+		}
+	}
+}
+
+func TestBuildUsesWAL(t *testing.T) {
+	vault := copyVault(t, "vault_build_full")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	db := openTestDB(t, dbPath(vault))
+	defer db.Close()
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Errorf("journal_mode = %q, want wal", mode)
+	}
+}
+
+// BenchmarkBuild builds a generated vault of 2000 linked notes, with the
+// default pragmas (WAL, synchronous=NORMAL) and with SQLite's rollback
+// journal and synchronous=FULL for comparison.
+func BenchmarkBuild(b *testing.B) {
+	vault := b.TempDir()
+	const n = 2000
+	for i := 0; i < n; i++ {
+		content := fmt.Sprintf("# Note %d\n\n[[Note%d]] [[Note%d]] #tag%d\n", i, (i+1)%n, (i*7)%n, i%50)
+		dir := filepath.Join(vault, fmt.Sprintf("d%d", i%20))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("Note%d.md", i)), []byte(content), 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, bc := range []struct {
+		name    string
+		pragmas string
+	}{
+		{"wal", dbPragmas},
+		{"rollback", "_pragma=journal_mode(DELETE)&_pragma=synchronous(FULL)"},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			prev := dbPragmas
+			dbPragmas = bc.pragmas
+			defer func() { dbPragmas = prev }()
+			for i := 0; i < b.N; i++ {
+				removeDBFiles(dbPath(vault))
+				if err := Build(vault); err != nil {
+					b.Fatalf("build: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestFindVault(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{"sub/deep/A.md": "# A\n"})
+	if _, ok := FindVault(filepath.Join(vault, "sub", "deep")); ok {
+		t.Fatal("found a vault before build")
+	}
+	buildVault(t, vault)
+
+	want, err := filepath.EvalSymlinks(vault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{vault, filepath.Join(vault, "sub"), filepath.Join(vault, "sub", "deep")} {
+		root, ok := FindVault(dir)
+		if !ok {
+			t.Fatalf("FindVault(%s): not found", dir)
+		}
+		if got, _ := filepath.EvalSymlinks(root); got != want {
+			t.Errorf("FindVault(%s) = %s, want %s", dir, root, vault)
+		}
+	}
+}
+
+func TestBuildIgnoreTemplateLinks(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"mdhop.yaml": "build:\n  ignore_template_links: true\n",
+		"Tpl.md":     "# {{title}}\n\n[[{{link}}]] [[{{date}}|today]] [x]({{url}}.md) [[Real]]\n",
+	})
+	buildVault(t, vault)
+
+	phantoms := queryNodes(t, dbPath(vault), "phantom")
+	if len(phantoms) != 1 || phantoms[0].name != "Real" {
+		t.Errorf("phantoms = %+v, want [Real]", phantoms)
+	}
+	edges := queryEdges(t, dbPath(vault), "Tpl.md")
+	if len(edges) != 1 || edges[0].rawLink != "[[Real]]" {
+		t.Errorf("edges = %+v, want only [[Real]]", edges)
+	}
+
+	// Without the option, placeholders are indexed as phantoms.
+	writeVaultFiles(t, vault, map[string]string{"mdhop.yaml": ""})
+	buildVault(t, vault)
+	if got := queryNodes(t, dbPath(vault), "phantom"); len(got) != 4 {
+		t.Errorf("phantoms without ignore_template_links = %+v, want 4", got)
+	}
+}
@@ -13,11 +13,59 @@ import (
 type Config struct {
 	Build   BuildConfig   `yaml:"build"`
 	Exclude ExcludeConfig `yaml:"exclude"`
+	Rewrite RewriteConfig `yaml:"rewrite"`
 }
 
 // BuildConfig holds build-time settings.
 type BuildConfig struct {
-	ExcludePaths []string `yaml:"exclude_paths"`
+	ExcludePaths        []string `yaml:"exclude_paths"`
+	FrontmatterLinkKeys []string `yaml:"frontmatter_link_keys"` // nil = ["related"]
+	FollowSymlinks      bool     `yaml:"follow_symlinks"`
+	RespectGitignore    bool     `yaml:"respect_gitignore"`
+	FolderNotes         bool     `yaml:"folder_notes"`          // [[Dir]] / [[Dir/]] may resolve to Dir/Dir.md
+	RootPriority        *bool    `yaml:"root_priority"`         // nil = true; false makes every duplicated basename ambiguous
+	IgnoreTemplateLinks bool     `yaml:"ignore_template_links"` // skip links whose target contains {{ or }}
+	TagCase             string   `yaml:"tag_case"`              // "fold" (default) or "preserve"
+	LinkResolution      string   `yaml:"link_resolution"`       // "global" (default) or "folder-first"
+}
+
+// defaultFrontmatterLinkKeys are used when build.frontmatter_link_keys is unset.
+var defaultFrontmatterLinkKeys = []string{"related"}
+
+// linkKeys returns the frontmatter keys whose values are parsed as links.
+// An explicit empty list disables frontmatter links.
+func (c BuildConfig) linkKeys() []string {
+	if c.FrontmatterLinkKeys == nil {
+		return defaultFrontmatterLinkKeys
+	}
+	return c.FrontmatterLinkKeys
+}
+
+// rootPriority reports whether a basename link shared by several files
+// resolves to the one at the vault root. Unset means true.
+func (c BuildConfig) rootPriority() bool {
+	return c.RootPriority == nil || *c.RootPriority
+}
+
+// preserveTagCase reports whether tags differing only in case are distinct
+// nodes (tag_case: preserve). By default they fold into one node named after
+// the first-seen spelling.
+func (c BuildConfig) preserveTagCase() bool {
+	return c.TagCase == "preserve"
+}
+
+// folderFirst reports whether a basename link resolves to a note of that
+// name in the linking note's own folder before the vault-wide rules
+// (link_resolution: folder-first).
+func (c BuildConfig) folderFirst() bool {
+	return c.LinkResolution == "folder-first"
+}
+
+// RewriteConfig holds defaults for commands that rewrite links on disk.
+type RewriteConfig struct {
+	// PreserveMtime turns on PreserveMtime for move, add, disambiguate and
+	// update --detect-moves, as if --preserve-mtime were always given.
+	PreserveMtime bool `yaml:"preserve_mtime"`
 }
 
 // ExcludeConfig holds exclusion patterns from the config file.
@@ -48,6 +96,16 @@ func LoadConfig(vaultPath string) (Config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return Config{}, fmt.Errorf("mdhop.yaml: %w", err)
 	}
+	switch cfg.Build.TagCase {
+	case "", "fold", "preserve":
+	default:
+		return Config{}, fmt.Errorf("mdhop.yaml: invalid build.tag_case: %q (want fold or preserve)", cfg.Build.TagCase)
+	}
+	switch cfg.Build.LinkResolution {
+	case "", "global", "folder-first":
+	default:
+		return Config{}, fmt.Errorf("mdhop.yaml: invalid build.link_resolution: %q (want global or folder-first)", cfg.Build.LinkResolution)
+	}
 	return cfg, nil
 }
 
@@ -23,16 +23,22 @@ type ConvertResult struct {
 // Convert converts links between wikilink and markdown link formats.
 // It works by scanning files directly (no DB required).
 func Convert(vaultPath string, opts ConvertOptions) (*ConvertResult, error) {
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
 	if opts.ToFormat != "wikilink" && opts.ToFormat != "markdown" {
 		return nil, fmt.Errorf("invalid ToFormat: %q (must be wikilink or markdown)", opts.ToFormat)
 	}
 
-	files, err := collectMarkdownFiles(vaultPath)
+	cfg, err := LoadConfig(vaultPath)
 	if err != nil {
 		return nil, err
 	}
 
-	cfg, err := LoadConfig(vaultPath)
+	files, err := collectMarkdownFiles(vaultPath, cfg.Build)
 	if err != nil {
 		return nil, err
 	}
@@ -121,6 +127,7 @@ func Convert(vaultPath string, opts ConvertOptions) (*ConvertResult, error) {
 				rawLink:    lo.rawLink,
 				linkType:   lo.linkType,
 				lineStart:  lo.lineStart,
+				col:        lo.col,
 				sourcePath: sourcePath,
 				newRawLink: newRawLink,
 			})
@@ -145,7 +152,7 @@ func Convert(vaultPath string, opts ConvertOptions) (*ConvertResult, error) {
 	for _, re := range rewrites {
 		groups[re.sourcePath] = append(groups[re.sourcePath], re)
 	}
-	_, _, applyErr := applyFileRewrites(vaultPath, groups)
+	_, _, applyErr := applyFileRewrites(vaultPath, groups, false)
 	if applyErr != nil {
 		return nil, applyErr
 	}
@@ -167,6 +174,7 @@ func convertMarkdownToWikilink(rawLink string) string {
 	}
 
 	target, subpath := extractSubpath(url)
+	target = decodeMarkdownPath(normalizeSeparators(target))
 
 	// Self-link: [text](#heading)
 	if target == "" && subpath != "" {
@@ -215,6 +223,7 @@ func convertWikilinkToMarkdown(rawLink string, isAssetTarget func(string) bool)
 	}
 
 	target, subpath := extractSubpath(inner)
+	target = normalizeSeparators(target)
 
 	// Self-link: [[#Heading]] or [[#Heading|alias]]
 	if target == "" && subpath != "" {
@@ -244,7 +253,7 @@ func convertWikilinkToMarkdown(rawLink string, isAssetTarget func(string) bool)
 		text = alias
 	}
 
-	return "[" + text + "](" + mdTarget + subpath + ")"
+	return "[" + text + "](" + formatMarkdownURL(mdTarget, subpath, false) + ")"
 }
 
 // extractMarkdownParts extracts text and url from a markdown link [text](url).
@@ -258,7 +267,7 @@ func extractMarkdownParts(rawLink string) (text, url string) {
 	}
 	text = rawLink[1:mid]
 	url = rawLink[mid+2:]
-	url = strings.TrimSuffix(url, ")")
+	url, _ = unwrapAngleURL(strings.TrimSuffix(url, ")"))
 	return text, url
 }
 
@@ -305,8 +314,12 @@ func parseLinksForConvert(content string) []linkOccur {
 		if inFence {
 			continue
 		}
-		clean := stripInlineCode(lines[i])
-		out = append(out, parseMarkdownSelfLinks(clean, lineNum)...)
+		clean, offsets := stripInlineCodeOffsets(lines[i])
+		self := parseMarkdownSelfLinks(clean, lineNum)
+		for j := range self {
+			self[j].col = offsets[self[j].col]
+		}
+		out = append(out, self...)
 	}
 	return out
 }
@@ -315,6 +328,7 @@ func parseLinksForConvert(content string) []linkOccur {
 func parseMarkdownSelfLinks(line string, lineNum int) []linkOccur {
 	var out []linkOccur
 	remaining := line
+	base := 0 // offset of remaining in line
 	for {
 		open := strings.Index(remaining, "[")
 		if open == -1 {
@@ -323,6 +337,7 @@ func parseMarkdownSelfLinks(line string, lineNum int) []linkOccur {
 		// Skip wikilinks.
 		if open+1 < len(remaining) && remaining[open+1] == '[' {
 			remaining = remaining[open+2:]
+			base += open + 2
 			continue
 		}
 		mid := strings.Index(remaining[open:], "](")
@@ -349,9 +364,11 @@ func parseMarkdownSelfLinks(line string, lineNum int) []linkOccur {
 				subpath:    rawTarget,
 				lineStart:  lineNum,
 				lineEnd:    lineNum,
+				col:        base + open,
 			})
 		}
 		remaining = remaining[close+1:]
+		base += close + 1
 	}
 	return out
 }
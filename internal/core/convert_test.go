@@ -720,6 +720,35 @@ func TestConvertRoundTrip(t *testing.T) {
 	}
 }
 
+func TestConvertRedundantAliasDropped(t *testing.T) {
+	isAsset := func(target string) bool {
+		return !isNoteTarget(target, map[string]bool{"a": true})
+	}
+
+	// An alias equal to the displayed basename survives wiki→md as link text
+	// and is dropped on the way back.
+	tests := []struct {
+		wikiLink string
+		mdLink   string
+		back     string
+	}{
+		{"[[A|Foo]]", "[Foo](A.md)", "[[A|Foo]]"},
+		{"[[A|A]]", "[A](A.md)", "[[A]]"},
+		{"[[sub/A|A]]", "[A](sub/A.md)", "[[sub/A]]"},
+		{"[[A#H|A#H]]", "[A#H](A.md#H)", "[[A#H]]"},
+		{"[[A|a]]", "[a](A.md)", "[[A|a]]"},
+	}
+	for _, tt := range tests {
+		md := convertWikilinkToMarkdown(tt.wikiLink, isAsset)
+		if md != tt.mdLink {
+			t.Errorf("wiki→md %q: got %q, want %q", tt.wikiLink, md, tt.mdLink)
+		}
+		if wiki := convertMarkdownToWikilink(md); wiki != tt.back {
+			t.Errorf("md→wiki %q: got %q, want %q", md, wiki, tt.back)
+		}
+	}
+}
+
 func TestConvertEmbedPreserved(t *testing.T) {
 	tmp := t.TempDir()
 	if err := testutil.CopyDir("../../testdata/vault_convert", tmp); err != nil {
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CopyOptions controls the copy operation.
+type CopyOptions struct {
+	From string // vault-relative source note
+	To   string // vault-relative destination path
+}
+
+// CopyResult reports the outcome of the copy operation.
+type CopyResult struct {
+	Rewritten []RewrittenLink // relative links rewritten in the copy
+	Promoted  []string        // phantom nodes promoted to the new note
+}
+
+// Copy duplicates a registered note to a new path and adds the copy to the
+// index. Relative links in the copy are rewritten so they still point at the
+// same targets from the new location; the source file is left untouched.
+// The index lock is held throughout. If adding the copy fails (e.g. it makes
+// existing links ambiguous), the copied file is removed again.
+func Copy(vaultPath string, opts CopyOptions) (*CopyResult, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	from := NormalizePath(opts.From)
+	to := NormalizePath(opts.To)
+
+	if pathEscapesVault(to) {
+		return nil, fmt.Errorf("destination escapes vault: %s", to)
+	}
+	if from == to {
+		return nil, fmt.Errorf("source and destination are the same: %s", from)
+	}
+	if !strings.HasSuffix(strings.ToLower(to), ".md") {
+		return nil, fmt.Errorf("destination must be a .md file: %s", to)
+	}
+
+	if err := checkCopyRegistration(dbp, from, to); err != nil {
+		return nil, err
+	}
+
+	fromFull := filepath.Join(vaultPath, from)
+	toFull := filepath.Join(vaultPath, to)
+	if !fileExists(fromFull) {
+		return nil, fmt.Errorf("source file not found on disk: %s", from)
+	}
+	if fileExists(toFull) {
+		return nil, fmt.Errorf("destination already exists on disk: %s", to)
+	}
+
+	info, err := os.Stat(fromFull)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(fromFull)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rewrite relative links from the source's perspective to the copy's.
+	result := &CopyResult{}
+	lines := strings.Split(string(content), "\n")
+	lineReps := make(map[int][]linkReplacement)
+	for _, link := range parseLinks(string(content)) {
+		if !link.isRelative || (link.linkType != "wikilink" && link.linkType != "markdown") {
+			continue
+		}
+		newRL, err := rewriteOutgoingRelativeLink(link.rawLink, link.linkType, from, to)
+		if err != nil {
+			return nil, err
+		}
+		if newRL == link.rawLink || link.lineStart < 1 || link.lineStart > len(lines) {
+			continue
+		}
+		idx := link.lineStart - 1
+		lineReps[idx] = append(lineReps[idx], linkReplacement{link.linkType, link.rawLink, newRL, link.col})
+		result.Rewritten = append(result.Rewritten, RewrittenLink{
+			File:    to,
+			OldLink: link.rawLink,
+			NewLink: newRL,
+		})
+	}
+	for idx, reps := range lineReps {
+		lines[idx] = replaceRawLinks(lines[idx], reps)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(toFull), 0o755); err != nil {
+		return nil, err
+	}
+	if err := writeFilePreservePerm(toFull, []byte(strings.Join(lines, "\n")), info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+
+	addResult, err := addLocked(vaultPath, AddOptions{Files: []string{to}})
+	if err != nil {
+		_ = os.Remove(toFull)
+		_ = CleanupEmptyDirs(vaultPath, []string{to})
+		return nil, err
+	}
+	result.Promoted = addResult.Promoted
+	return result, nil
+}
+
+// checkCopyRegistration verifies that from is a registered note and that to
+// is not registered yet.
+func checkCopyRegistration(dbp, from, to string) error {
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var id int64
+	err = db.QueryRow("SELECT id FROM nodes WHERE node_key = ? AND type = 'note'", noteKey(from)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("note not registered: %s", from)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = db.QueryRow("SELECT id FROM nodes WHERE node_key = ?", noteKey(to)).Scan(&id)
+	if err == nil {
+		return fmt.Errorf("destination already registered: %s", to)
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+	return nil
+}
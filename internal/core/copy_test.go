@@ -0,0 +1,118 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopy_RewritesRelativeLinks(t *testing.T) {
+	vault := copyVault(t, "vault_copy")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	orig, err := os.ReadFile(filepath.Join(vault, "Template.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Copy(vault, CopyOptions{From: "Template.md", To: "projects/2026/Plan.md"})
+	if err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if len(result.Rewritten) != 2 {
+		t.Errorf("expected 2 rewrites, got %+v", result.Rewritten)
+	}
+
+	got, err := os.ReadFile(filepath.Join(vault, "projects/2026/Plan.md"))
+	if err != nil {
+		t.Fatalf("read copy: %v", err)
+	}
+	for _, want := range []string{"[B](../../B.md)", "[[../../B#Heading|b]]", "Also [[B]]."} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("copy missing %q:\n%s", want, got)
+		}
+	}
+
+	// Source is untouched.
+	after, err := os.ReadFile(filepath.Join(vault, "Template.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(orig) {
+		t.Errorf("source modified:\n%s", after)
+	}
+
+	// The copy is registered and its links resolve to the same note.
+	edges := queryEdges(t, dbPath(vault), "projects/2026/Plan.md")
+	var noteEdges int
+	for _, e := range edges {
+		if e.targetType == "note" {
+			noteEdges++
+			if e.targetKey != "note:path:B.md" {
+				t.Errorf("edge %s → %s, want B.md", e.rawLink, e.targetKey)
+			}
+		}
+	}
+	if noteEdges != 3 {
+		t.Errorf("expected 3 note edges, got %+v", edges)
+	}
+}
+
+func TestCopy_DestinationExists(t *testing.T) {
+	vault := copyVault(t, "vault_copy")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	_, err := Copy(vault, CopyOptions{From: "Template.md", To: "B.md"})
+	if err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("expected already registered error, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(vault, "C.md"), []byte("x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Copy(vault, CopyOptions{From: "Template.md", To: "C.md"})
+	if err == nil || !strings.Contains(err.Error(), "already exists on disk") {
+		t.Errorf("expected already exists on disk error, got %v", err)
+	}
+}
+
+func TestCopy_NotRegistered(t *testing.T) {
+	vault := copyVault(t, "vault_copy")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	_, err := Copy(vault, CopyOptions{From: "Missing.md", To: "New.md"})
+	if err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Errorf("expected not registered error, got %v", err)
+	}
+}
+
+func TestCopy_RollbackOnAddFailure(t *testing.T) {
+	vault := copyVault(t, "vault_copy")
+	if err := os.MkdirAll(filepath.Join(vault, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// [[B]] in Template.md resolves only while sub/B.md is the sole B. A second
+	// non-root B makes it ambiguous, so Add fails and the copy is rolled back.
+	if err := os.Rename(filepath.Join(vault, "B.md"), filepath.Join(vault, "sub", "B.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "Template.md"), []byte("[[B]]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	_, err := Copy(vault, CopyOptions{From: "sub/B.md", To: "other/B.md"})
+	if err == nil {
+		t.Fatal("expected error for copy that makes links ambiguous")
+	}
+	if _, statErr := os.Stat(filepath.Join(vault, "other")); !os.IsNotExist(statErr) {
+		t.Errorf("copy and its directory should be removed after failure, stat err = %v", statErr)
+	}
+}
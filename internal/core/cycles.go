@@ -0,0 +1,140 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// LinkCycle is a group of notes that reach each other through links: a
+// strongly connected component of the note link graph.
+type LinkCycle struct {
+	Paths []string // sorted
+}
+
+// QueryCycles returns the link cycles among notes: every strongly connected
+// component with more than one note, following wikilink/markdown edges
+// (embeds included) between notes. Tags, phantoms and assets are not part of
+// the graph, and self-links never form a cycle on their own. Cycles are
+// sorted by their first path. Only opts.Exclude is used; an excluded note is
+// left out of the graph.
+func QueryCycles(vaultPath string, opts QueryOptions) ([]LinkCycle, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return queryLinkCycles(db, opts.Exclude)
+}
+
+func queryLinkCycles(db dbExecer, ef *ExcludeFilter) ([]LinkCycle, error) {
+	q := `SELECT DISTINCT s.path, t.path
+		 FROM edges e
+		 JOIN nodes s ON s.id = e.source_id
+		 JOIN nodes t ON t.id = e.target_id
+		 WHERE s.type = 'note' AND t.type = 'note' AND s.exists_flag = 1 AND t.exists_flag = 1
+		 AND e.link_type IN ('wikilink','markdown') AND s.id != t.id`
+	var args []any
+
+	if ef != nil {
+		for _, col := range []string{"s.path", "t.path"} {
+			pathSQL, pathArgs := ef.PathExcludeSQL(col)
+			q += pathSQL
+			args = append(args, pathArgs...)
+		}
+	}
+
+	q += ` ORDER BY s.path, t.path`
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	graph := make(map[string][]string)
+	var nodes []string
+	for rows.Next() {
+		var src, dst string
+		if err := rows.Scan(&src, &dst); err != nil {
+			return nil, err
+		}
+		if _, ok := graph[src]; !ok {
+			nodes = append(nodes, src)
+		}
+		graph[src] = append(graph[src], dst)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := []LinkCycle{}
+	for _, comp := range stronglyConnected(nodes, graph) {
+		if len(comp) < 2 {
+			continue
+		}
+		sort.Strings(comp)
+		result = append(result, LinkCycle{Paths: comp})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Paths[0] < result[j].Paths[0] })
+	return result, nil
+}
+
+// stronglyConnected returns the strongly connected components of graph
+// using Tarjan's algorithm, visiting nodes in the given order.
+func stronglyConnected(nodes []string, graph map[string][]string) [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var comps [][]string
+
+	var visit func(v string)
+	visit = func(v string) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range graph[v] {
+			if _, seen := index[w]; !seen {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var comp []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			comp = append(comp, w)
+			if w == v {
+				break
+			}
+		}
+		comps = append(comps, comp)
+	}
+
+	for _, v := range nodes {
+		if _, seen := index[v]; !seen {
+			visit(v)
+		}
+	}
+	return comps
+}
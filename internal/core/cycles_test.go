@@ -0,0 +1,40 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryCycles(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "[[B]]\n",
+		"B.md": "[C](C.md)\n",
+		"C.md": "![[A]]\n",
+		"D.md": "[[A]] [[#Top]]\n# Top\n",
+		"E.md": "#topic [[F]]\n",
+		"F.md": "#topic [[Missing]]\n",
+	})
+	buildVault(t, vault)
+
+	got, err := QueryCycles(vault, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []LinkCycle{{Paths: []string{"A.md", "B.md", "C.md"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cycles = %+v, want %+v", got, want)
+	}
+
+	ef, err := NewExcludeFilter(ExcludeConfig{}, []string{"B.md"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = QueryCycles(vault, QueryOptions{Exclude: ef})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("cycles with B.md excluded = %+v, want none", got)
+	}
+}
@@ -1,10 +1,14 @@
 package core
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	_ "modernc.org/sqlite"
@@ -34,8 +38,98 @@ func ensureDataDir(vaultPath string) (string, error) {
 	return dir, nil
 }
 
+// FindVault returns the nearest directory at or above dir that contains an
+// index data directory (.mdhop/), like git's repository discovery. dir is
+// made absolute first; ok is false when no ancestor has one.
+func FindVault(dir string) (root string, ok bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if info, err := os.Stat(filepath.Join(dir, dataDirName)); err == nil && info.IsDir() {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// dbPragmas is applied to every connection. WAL with synchronous=NORMAL
+// avoids an fsync per commit; the build's single large transaction benefits
+// most. busy_timeout covers the brief window where a WAL checkpoint holds a
+// lock.
+var dbPragmas = "_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)"
+
 func openDBAt(path string) (*sql.DB, error) {
-	return sql.Open("sqlite", fmt.Sprintf("file:%s", path))
+	return sql.Open("sqlite", fmt.Sprintf("file:%s?%s", path, dbPragmas))
+}
+
+// removeDBFiles removes a database file and its WAL side files (-wal, -shm).
+func removeDBFiles(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		_ = os.Remove(path + suffix)
+	}
+}
+
+// stmtCache is a dbExecer over a transaction that prepares each distinct
+// query once and reuses the statement for later rows. Build uses it for its
+// bulk inserts.
+type stmtCache struct {
+	tx    *sql.Tx
+	stmts map[string]*sql.Stmt
+}
+
+func newStmtCache(tx *sql.Tx) *stmtCache {
+	return &stmtCache{tx: tx, stmts: make(map[string]*sql.Stmt)}
+}
+
+func (c *stmtCache) stmt(query string) (*sql.Stmt, error) {
+	if s, ok := c.stmts[query]; ok {
+		return s, nil
+	}
+	s, err := c.tx.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[query] = s
+	return s, nil
+}
+
+func (c *stmtCache) Exec(query string, args ...any) (sql.Result, error) {
+	s, err := c.stmt(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.Exec(args...)
+}
+
+func (c *stmtCache) Query(query string, args ...any) (*sql.Rows, error) {
+	s, err := c.stmt(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.Query(args...)
+}
+
+func (c *stmtCache) QueryRow(query string, args ...any) *sql.Row {
+	s, err := c.stmt(query)
+	if err != nil {
+		// The unprepared query reports the same error through Row.Scan.
+		return c.tx.QueryRow(query, args...)
+	}
+	return s.QueryRow(args...)
+}
+
+// close releases the prepared statements.
+func (c *stmtCache) close() {
+	for _, s := range c.stmts {
+		s.Close()
+	}
+	c.stmts = nil
 }
 
 func initSchema(db *sql.DB) error {
@@ -47,7 +141,8 @@ func initSchema(db *sql.DB) error {
 			name        TEXT NOT NULL,
 			path        TEXT,
 			exists_flag INTEGER NOT NULL DEFAULT 1,
-			mtime       INTEGER
+			mtime       INTEGER,
+			content_hash TEXT
 		);`,
 		`CREATE INDEX IF NOT EXISTS idx_nodes_type_name ON nodes(type, name);`,
 		`CREATE INDEX IF NOT EXISTS idx_nodes_path ON nodes(path);`,
@@ -60,21 +155,321 @@ func initSchema(db *sql.DB) error {
 			subpath    TEXT,
 			line_start INTEGER,
 			line_end   INTEGER,
+			is_embed   INTEGER NOT NULL DEFAULT 0,
+			col        INTEGER,
 			FOREIGN KEY(source_id) REFERENCES nodes(id),
 			FOREIGN KEY(target_id) REFERENCES nodes(id)
 		);`,
 		`CREATE INDEX IF NOT EXISTS idx_edges_source ON edges(source_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_edges_target ON edges(target_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_edges_source_target ON edges(source_id, target_id);`,
+		`CREATE TABLE IF NOT EXISTS headings (
+			id      INTEGER PRIMARY KEY,
+			node_id INTEGER NOT NULL,
+			level   INTEGER NOT NULL,
+			text    TEXT NOT NULL,
+			line    INTEGER NOT NULL,
+			FOREIGN KEY(node_id) REFERENCES nodes(id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_headings_node ON headings(node_id);`,
+		`CREATE TABLE IF NOT EXISTS aliases (
+			id      INTEGER PRIMARY KEY,
+			node_id INTEGER NOT NULL,
+			alias   TEXT NOT NULL,
+			FOREIGN KEY(node_id) REFERENCES nodes(id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_aliases_node ON aliases(node_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_aliases_alias ON aliases(alias COLLATE NOCASE);`,
+		`CREATE TABLE IF NOT EXISTS external_links (
+			id      INTEGER PRIMARY KEY,
+			node_id INTEGER NOT NULL,
+			url     TEXT NOT NULL,
+			line    INTEGER NOT NULL,
+			FOREIGN KEY(node_id) REFERENCES nodes(id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_external_links_node ON external_links(node_id);`,
+		metaTableSQL,
+		buildExcludesTableSQL,
 	}
 	for _, stmt := range stmts {
 		if _, err := db.Exec(stmt); err != nil {
 			return err
 		}
 	}
+	initNoteText(db)
+	return nil
+}
+
+const metaTableSQL = `CREATE TABLE IF NOT EXISTS meta (
+			key   TEXT PRIMARY KEY,
+			value INTEGER NOT NULL
+		);`
+
+// bumpIndexVersion increments the index version in meta. Every mutating
+// transaction calls it before committing so that caches built from the index
+// (see resolverContext) can tell they are stale. The table is created on
+// demand for indexes built before it existed. It also refreshes the note and
+// asset aggregates read by VerifyFast.
+func bumpIndexVersion(tx dbExecer) error {
+	if _, err := tx.Exec(metaTableSQL); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES ('version', 1)
+		ON CONFLICT(key) DO UPDATE SET value = value + 1`); err != nil {
+		return err
+	}
+	for _, typ := range []string{"note", "asset"} {
+		rows, err := tx.Query(`SELECT path, COALESCE(mtime, 0) FROM nodes WHERE type = ? AND exists_flag = 1`, typ)
+		if err != nil {
+			return err
+		}
+		var stamps []noteStamp
+		var maxMtime int64
+		for rows.Next() {
+			var n noteStamp
+			if err := rows.Scan(&n.path, &n.mtime); err != nil {
+				rows.Close()
+				return err
+			}
+			maxMtime = max(maxMtime, n.mtime)
+			stamps = append(stamps, n)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for key, value := range map[string]int64{
+			typ + "_count":     int64(len(stamps)),
+			typ + "_max_mtime": maxMtime,
+			typ + "_digest":    notesDigest(stamps),
+		} {
+			if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES (?, ?)
+				ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// noteStamp is a note or asset path with its mtime (Unix seconds).
+type noteStamp struct {
+	path  string
+	mtime int64
+}
+
+// notesDigest hashes the sorted path/mtime pairs of files into an int64 that
+// fits the meta value column. Unlike the count and max mtime it changes when a
+// file is renamed or replaced by an older one.
+func notesDigest(notes []noteStamp) int64 {
+	sort.Slice(notes, func(i, j int) bool { return notes[i].path < notes[j].path })
+	h := sha256.New()
+	for _, n := range notes {
+		fmt.Fprintf(h, "%s\x00%d\n", n.path, n.mtime)
+	}
+	return int64(binary.BigEndian.Uint64(h.Sum(nil)[:8]))
+}
+
+// indexVersion returns the index version, or ok=false for an index that has
+// never recorded one.
+func indexVersion(db dbExecer) (version int64, ok bool, err error) {
+	return metaValue(db, "version")
+}
+
+// metaValue returns the meta value for key, or ok=false when the index has no
+// meta table or no such key.
+func metaValue(db dbExecer, key string) (value int64, ok bool, err error) {
+	var name string
+	err = db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'meta'`).Scan(&name)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	err = db.QueryRow(`SELECT value FROM meta WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return value, true, nil
+}
+
+const buildExcludesTableSQL = `CREATE TABLE IF NOT EXISTS build_excludes (
+			pattern TEXT NOT NULL
+		);`
+
+// saveBuildOptions records the effective walk options of a build, including
+// one-off BuildOptions, so that later scans of the vault (verify, detect
+// moves) see the same set of files as the index.
+func saveBuildOptions(tx dbExecer, bc BuildConfig) error {
+	for key, on := range map[string]bool{"follow_symlinks": bc.FollowSymlinks, "respect_gitignore": bc.RespectGitignore} {
+		if !on {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES (?, 1)`, key); err != nil {
+			return err
+		}
+	}
+	for _, p := range bc.ExcludePaths {
+		if _, err := tx.Exec(`INSERT INTO build_excludes (pattern) VALUES (?)`, p); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// indexedBuildConfig returns bc with the walk options saved by the build that
+// created the index turned on, the same way BuildWithOptions layers options
+// on top of mdhop.yaml. Indexes built before the options were saved return bc
+// unchanged.
+func indexedBuildConfig(db dbExecer, bc BuildConfig) (BuildConfig, error) {
+	if _, ok, err := metaValue(db, "follow_symlinks"); err != nil {
+		return bc, err
+	} else if ok {
+		bc.FollowSymlinks = true
+	}
+	if _, ok, err := metaValue(db, "respect_gitignore"); err != nil {
+		return bc, err
+	} else if ok {
+		bc.RespectGitignore = true
+	}
+	var name string
+	err := db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'build_excludes'`).Scan(&name)
+	if err == sql.ErrNoRows {
+		return bc, nil
+	}
+	if err != nil {
+		return bc, err
+	}
+	rows, err := db.Query(`SELECT pattern FROM build_excludes`)
+	if err != nil {
+		return bc, err
+	}
+	defer rows.Close()
+	seen := make(map[string]bool, len(bc.ExcludePaths))
+	patterns := append([]string(nil), bc.ExcludePaths...)
+	for _, p := range patterns {
+		seen[p] = true
+	}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return bc, err
+		}
+		if !seen[p] {
+			seen[p] = true
+			patterns = append(patterns, p)
+		}
+	}
+	bc.ExcludePaths = patterns
+	return bc, rows.Err()
+}
+
+// checkFullIndex fails for an index built with BuildOptions.BaseDir. Its
+// links were resolved from the base dir, while every other command resolves
+// links and compares files from the vault root, so using them on it would
+// silently turn valid links into phantoms.
+func checkFullIndex(db dbExecer) error {
+	_, ok, err := metaValue(db, "base_dir")
+	if err != nil {
+		return err
+	}
+	if ok {
+		return fmt.Errorf("index was built with --base-dir: run 'mdhop build' without --base-dir to use this command")
+	}
+	return nil
+}
+
+// initNoteText creates the full-text index used by search: a contentless
+// FTS5 table, so the index keeps the tokens but never the note bodies. Without
+// FTS5 (or contentless deletes, SQLite 3.43+) no table is created and search
+// scans the note files instead.
+func initNoteText(db *sql.DB) {
+	_, _ = db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS note_fts USING fts5(body, content='', contentless_delete=1)`)
+}
+
+// hasNoteFTS reports whether the index has the note_fts full-text table.
+func hasNoteFTS(db dbExecer) (bool, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'note_fts'`).Scan(&n)
+	return n > 0, err
+}
+
+// noteText indexes note bodies for search within one transaction. The
+// index's schema is looked up once by newNoteText, not per note.
+type noteText struct {
+	hashed bool // nodes has content_hash (see hasContentHash)
+	fts    bool // note_fts exists (see hasNoteFTS)
+}
+
+func newNoteText(db dbExecer) (*noteText, error) {
+	hashed, err := hasContentHash(db)
+	if err != nil {
+		return nil, err
+	}
+	fts, err := hasNoteFTS(db)
+	if err != nil {
+		return nil, err
+	}
+	return &noteText{hashed: hashed, fts: fts}, nil
+}
+
+// replace indexes the body of a note for search and records its content
+// hash. The row id is the node id.
+func (nt *noteText) replace(db dbExecer, nodeID int64, body string) error {
+	if nt.hashed {
+		if _, err := db.Exec("UPDATE nodes SET content_hash = ? WHERE id = ?", contentHash(body), nodeID); err != nil {
+			return err
+		}
+	}
+	if !nt.fts {
+		return nil
+	}
+	if _, err := db.Exec("DELETE FROM note_fts WHERE rowid = ?", nodeID); err != nil {
+		return err
+	}
+	_, err := db.Exec("INSERT INTO note_fts (rowid, body) VALUES (?, ?)", nodeID, body)
+	return err
+}
+
+// refresh re-reads a note from disk and indexes its body for search.
+func (nt *noteText) refresh(db dbExecer, vaultPath string, nodeID int64, path string) error {
+	content, err := os.ReadFile(filepath.Join(vaultPath, path))
+	if err != nil {
+		return err
+	}
+	return nt.replace(db, nodeID, string(content))
+}
+
+// hasContentHash reports whether nodes has the content_hash column; indexes
+// built before it existed lack it until the next build.
+func hasContentHash(db dbExecer) (bool, error) {
+	var n int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM pragma_table_info('nodes') WHERE name = 'content_hash'`,
+	).Scan(&n)
+	return n > 0, err
+}
+
+// contentHash returns the hex SHA-256 of a note's full content.
+func contentHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// deleteNoteText removes a note from the search index.
+func deleteNoteText(db dbExecer, nodeID int64) error {
+	fts, err := hasNoteFTS(db)
+	if err != nil || !fts {
+		return err
+	}
+	_, err = db.Exec("DELETE FROM note_fts WHERE rowid = ?", nodeID)
+	return err
+}
+
 func upsertNote(db dbExecer, path, name string, mtime int64) (int64, error) {
 	res, err := db.Exec(
 		`INSERT INTO nodes (node_key, type, name, path, exists_flag, mtime)
@@ -175,8 +570,33 @@ func upsertPhantom(db dbExecer, name string) (int64, error) {
 	return id, nil
 }
 
-func upsertTag(db dbExecer, name string) (int64, error) {
-	key := fmt.Sprintf("tag:name:%s", strings.ToLower(name))
+// tagKey returns the node key of the tag name ("#" prefixed), folding case
+// unless preserveCase is set.
+func tagKey(name string, preserveCase bool) string {
+	if !preserveCase {
+		name = strings.ToLower(name)
+	}
+	return "tag:name:" + name
+}
+
+// lookupTagKey returns the node key of an indexed tag: the exact spelling
+// (tag_case: preserve) or else the folded one. Without a match it returns
+// the folded key, which the caller's lookup then reports as missing.
+func lookupTagKey(db dbExecer, name string) (string, error) {
+	key := tagKey(name, true)
+	var id int64
+	err := db.QueryRow("SELECT id FROM nodes WHERE node_key = ?", key).Scan(&id)
+	if err == nil {
+		return key, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+	return tagKey(name, false), nil
+}
+
+func upsertTag(db dbExecer, name string, preserveCase bool) (int64, error) {
+	key := tagKey(name, preserveCase)
 	res, err := db.Exec(
 		`INSERT INTO nodes (node_key, type, name, path, exists_flag)
 		 VALUES (?, 'tag', ?, NULL, 0)
@@ -206,15 +626,79 @@ func upsertTag(db dbExecer, name string) (int64, error) {
 	return id, nil
 }
 
-func insertEdge(db dbExecer, sourceID, targetID int64, linkType, rawLink, subpath string, lineStart, lineEnd int) error {
+func insertEdge(db dbExecer, sourceID, targetID int64, link linkOccur, subpath string) error {
+	embed := 0
+	if link.isEmbed {
+		embed = 1
+	}
 	_, err := db.Exec(
-		`INSERT INTO edges (source_id, target_id, link_type, raw_link, subpath, line_start, line_end)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		sourceID, targetID, linkType, rawLink, subpath, lineStart, lineEnd,
+		`INSERT INTO edges (source_id, target_id, link_type, raw_link, subpath, line_start, line_end, is_embed, col)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		sourceID, targetID, link.linkType, link.rawLink, subpath, link.lineStart, link.lineEnd, embed, link.col,
 	)
 	return err
 }
 
+// ensureEdgeCol adds the edges.col column to indexes built before it
+// existed. Commands that insert or rewrite edges call it after opening the
+// index; the old rows keep a NULL column and are rewritten wherever their raw
+// link parses on the line (see replaceLinksAt).
+func ensureEdgeCol(db dbExecer) error {
+	var n int
+	if err := db.QueryRow(
+		`SELECT COUNT(*) FROM pragma_table_info('edges') WHERE name = 'col'`,
+	).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err := db.Exec(`ALTER TABLE edges ADD COLUMN col INTEGER`)
+	return err
+}
+
+// replaceHeadings replaces all headings of a note with the given ones.
+func replaceHeadings(db dbExecer, nodeID int64, headings []headingOccur) error {
+	if _, err := db.Exec("DELETE FROM headings WHERE node_id = ?", nodeID); err != nil {
+		return err
+	}
+	for _, h := range headings {
+		if _, err := db.Exec(
+			`INSERT INTO headings (node_id, level, text, line) VALUES (?, ?, ?, ?)`,
+			nodeID, h.level, h.text, h.line,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceAliases replaces all frontmatter aliases of a note with the given ones.
+func replaceAliases(db dbExecer, nodeID int64, aliases []string) error {
+	if _, err := db.Exec("DELETE FROM aliases WHERE node_id = ?", nodeID); err != nil {
+		return err
+	}
+	for _, a := range aliases {
+		if _, err := db.Exec(`INSERT INTO aliases (node_id, alias) VALUES (?, ?)`, nodeID, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceExternalLinks replaces all external (http/https) links of a note with the given ones.
+func replaceExternalLinks(db dbExecer, nodeID int64, links []externalLinkOccur) error {
+	if _, err := db.Exec("DELETE FROM external_links WHERE node_id = ?", nodeID); err != nil {
+		return err
+	}
+	for _, l := range links {
+		if _, err := db.Exec(`INSERT INTO external_links (node_id, url, line) VALUES (?, ?, ?)`, nodeID, l.url, l.line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func getNodeID(db dbExecer, nodeKey string) (int64, error) {
 	var id int64
 	row := db.QueryRow("SELECT id FROM nodes WHERE node_key = ?", nodeKey)
@@ -228,6 +712,30 @@ func getNodeID(db dbExecer, nodeKey string) (int64, error) {
 // (excluding self-links via source_id != nodeID), converts to phantom.
 // Otherwise fully deletes the node and its edges.
 func removeOrPhantomize(tx dbExecer, nodeID int64, name string) (phantomized bool, err error) {
+	// Links that reached the note through an alias fall back to the alias's phantom.
+	aliases, err := queryAliases(tx, nodeID)
+	if err != nil {
+		return false, err
+	}
+	if err := demoteAliasEdges(tx, nodeID, aliases); err != nil {
+		return false, err
+	}
+
+	// Headings, aliases, external links, and body text belong to the note content
+	// and never survive removal.
+	if _, err := tx.Exec("DELETE FROM headings WHERE node_id = ?", nodeID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM external_links WHERE node_id = ?", nodeID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM aliases WHERE node_id = ?", nodeID); err != nil {
+		return false, err
+	}
+	if err := deleteNoteText(tx, nodeID); err != nil {
+		return false, err
+	}
+
 	// Check incoming edges (excluding self-links).
 	var incomingCount int
 	if err := tx.QueryRow("SELECT COUNT(*) FROM edges WHERE target_id = ? AND source_id != ?", nodeID, nodeID).Scan(&incomingCount); err != nil {
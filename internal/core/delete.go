@@ -29,7 +29,19 @@ func Delete(vaultPath string, opts DeleteOptions) (*DeleteResult, error) {
 		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
 	}
 
-	db, err := openDBAt(dbp)
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	return deleteLocked(vaultPath, opts)
+}
+
+// deleteLocked is Delete for a caller that already holds the exclusive index
+// lock.
+func deleteLocked(vaultPath string, opts DeleteOptions) (*DeleteResult, error) {
+	db, err := openDBAt(dbPath(vaultPath))
 	if err != nil {
 		return nil, err
 	}
@@ -134,10 +146,14 @@ func Delete(vaultPath string, opts DeleteOptions) (*DeleteResult, error) {
 	if err := cleanupOrphanedNodes(tx); err != nil {
 		return nil, err
 	}
+	if err := bumpIndexVersion(tx); err != nil {
+		return nil, err
+	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
+	verbosef("delete: committed %d deleted, %d phantomed", len(result.Deleted), len(result.Phantomed))
 
 	return result, nil
 }
@@ -3,13 +3,18 @@ package core
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 )
 
 // DiagnoseOptions controls which fields to return.
 type DiagnoseOptions struct {
-	Fields []string // nil/empty = all
+	Fields  []string // nil/empty = all
+	Suggest bool     // suggest the closest note for each phantom
+	// Similarity, when above 0, also groups notes whose word-shingle
+	// Jaccard similarity is at least this value (0 < Similarity <= 1).
+	Similarity float64
 }
 
 // BasenameConflict represents a group of nodes with the same case-insensitive basename.
@@ -18,11 +23,46 @@ type BasenameConflict struct {
 	Paths []string // vault-relative paths (sorted)
 }
 
+// RootPriorityConflict is a note basename shared by several files that
+// basename links resolve only because one of them sits at the vault root.
+// Moving the root file breaks (or retargets) every dependent link.
+type RootPriorityConflict struct {
+	Name     string   // basename of the root file
+	RootPath string   // the root file basename links resolve to
+	Paths    []string // every note with this basename (sorted)
+	Sources  []string // notes with basename links to RootPath (sorted, unique)
+}
+
+// EmbedCycle is a chain of notes that transclude each other in a loop
+// (A embeds B embeds A); renderers following it never terminate.
+type EmbedCycle struct {
+	Paths []string // notes in embed order, starting from the smallest path
+}
+
+// DuplicateGroup is a set of notes with the same content once frontmatter is
+// stripped and whitespace collapsed (or, with DiagnoseOptions.Similarity,
+// nearly the same content).
+type DuplicateGroup struct {
+	Paths []string // sorted
+}
+
 // DiagnoseResult contains diagnostic information about the indexed vault.
 type DiagnoseResult struct {
-	BasenameConflicts      []BasenameConflict // sorted by name (notes)
-	AssetBasenameConflicts []BasenameConflict // sorted by name (assets)
-	Phantoms               []string           // sorted by name
+	BasenameConflicts      []BasenameConflict     // sorted by name (notes)
+	AssetBasenameConflicts []BasenameConflict     // sorted by name (assets)
+	FragileRootPriority    []RootPriorityConflict // sorted by name
+	EmbedCycles            []EmbedCycle           // sorted by first path
+	DuplicateNotes         []DuplicateGroup       // sorted by first path
+	Phantoms               []string               // sorted by name
+	Suggestions            []PhantomSuggestion    // sorted by phantom; nil = not requested
+}
+
+// PhantomSuggestion is a likely fix for a phantom: the existing note whose
+// basename is closest by edit distance.
+type PhantomSuggestion struct {
+	Phantom  string
+	Path     string // suggested note path
+	Distance int    // Levenshtein distance (case-insensitive)
 }
 
 // Diagnose returns diagnostic information for the indexed vault.
@@ -32,12 +72,22 @@ func Diagnose(vaultPath string, opts DiagnoseOptions) (*DiagnoseResult, error) {
 		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
 	}
 
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
 	db, err := openDBAt(dbp)
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
 
+	if opts.Similarity < 0 || opts.Similarity > 1 {
+		return nil, fmt.Errorf("similarity must be between 0 and 1")
+	}
+
 	result := &DiagnoseResult{}
 
 	if isFieldActive("basename_conflicts", opts.Fields) {
@@ -129,6 +179,34 @@ func Diagnose(vaultPath string, opts DiagnoseOptions) (*DiagnoseResult, error) {
 		}
 	}
 
+	if isFieldActive("fragile_root_priority", opts.Fields) {
+		cfg, err := LoadConfig(vaultPath)
+		if err != nil {
+			return nil, err
+		}
+		conflicts, err := fragileRootPriority(db, cfg.Build)
+		if err != nil {
+			return nil, err
+		}
+		result.FragileRootPriority = conflicts
+	}
+
+	if isFieldActive("embed_cycles", opts.Fields) {
+		cycles, err := embedCycles(db)
+		if err != nil {
+			return nil, err
+		}
+		result.EmbedCycles = cycles
+	}
+
+	if isFieldActive("duplicate_notes", opts.Fields) {
+		groups, err := duplicateNotes(db, vaultPath, opts.Similarity)
+		if err != nil {
+			return nil, err
+		}
+		result.DuplicateNotes = groups
+	}
+
 	if isFieldActive("phantoms", opts.Fields) {
 		rows, err := db.Query(`SELECT name FROM nodes WHERE type='phantom' ORDER BY name`)
 		if err != nil {
@@ -148,5 +226,395 @@ func Diagnose(vaultPath string, opts DiagnoseOptions) (*DiagnoseResult, error) {
 		}
 	}
 
+	if opts.Suggest {
+		sugg, err := suggestPhantomFixes(db)
+		if err != nil {
+			return nil, err
+		}
+		result.Suggestions = sugg
+	}
+
+	return result, nil
+}
+
+// fragileRootPriority lists note basenames that have more than one file but
+// resolve by root priority, with the notes whose basename links depend on it.
+// With build.root_priority off nothing resolves that way, so none are listed.
+func fragileRootPriority(db dbExecer, bc BuildConfig) ([]RootPriorityConflict, error) {
+	rm, err := buildMapsFromDB(db)
+	if err != nil {
+		return nil, err
+	}
+	rm.applyBuildConfig(bc)
+
+	var out []RootPriorityConflict
+	for bk := range rm.rootBasenameToPath {
+		rootPath, ok := rm.rootPath(bk)
+		if !ok || rm.basenameCounts[bk] < 2 {
+			continue
+		}
+		var paths []string
+		for p := range rm.pathToID {
+			if basenameKey(p) == bk {
+				paths = append(paths, p)
+			}
+		}
+		sort.Strings(paths)
+
+		rows, err := db.Query(
+			`SELECT DISTINCT s.path, e.raw_link, e.link_type FROM edges e
+			 JOIN nodes s ON s.id = e.source_id AND s.exists_flag = 1
+			 WHERE e.target_id = ? AND e.link_type IN ('wikilink', 'markdown', 'frontmatter-link')
+			 ORDER BY s.path`, rm.pathToID[rootPath])
+		if err != nil {
+			return nil, err
+		}
+		sources := []string{}
+		for rows.Next() {
+			var src, rawLink, linkType string
+			if err := rows.Scan(&src, &rawLink, &linkType); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			// Path links and alias links keep resolving after a move.
+			if !isBasenameRawLink(rawLink, linkType) || strings.ToLower(rawLinkTarget(rawLink, linkType)) != bk {
+				continue
+			}
+			if n := len(sources); n == 0 || sources[n-1] != src {
+				sources = append(sources, src)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		out = append(out, RootPriorityConflict{
+			Name:     basename(rootPath),
+			RootPath: rootPath,
+			Paths:    paths,
+			Sources:  sources,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return strings.ToLower(out[i].Name) < strings.ToLower(out[j].Name) })
+	return out, nil
+}
+
+// embedCycles finds cycles in the embed subgraph of existing notes with a
+// depth-first search: each edge back to a note on the current path closes a
+// cycle. A cycle is rotated to start at its smallest path and reported once,
+// whichever note the search entered it from. Self-embeds are one-note cycles.
+func embedCycles(db dbExecer) ([]EmbedCycle, error) {
+	rows, err := db.Query(
+		`SELECT DISTINCT s.path, t.path FROM edges e
+		 JOIN nodes s ON s.id = e.source_id AND s.type = 'note' AND s.exists_flag = 1
+		 JOIN nodes t ON t.id = e.target_id AND t.type = 'note' AND t.exists_flag = 1
+		 WHERE e.is_embed = 1
+		 ORDER BY s.path, t.path`)
+	if err != nil {
+		return nil, err
+	}
+	adj := make(map[string][]string)
+	var order []string
+	for rows.Next() {
+		var src, dst string
+		if err := rows.Scan(&src, &dst); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if _, ok := adj[src]; !ok {
+			order = append(order, src)
+		}
+		adj[src] = append(adj[src], dst)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int)
+	var path []string
+	seen := make(map[string]bool)
+	var out []EmbedCycle
+
+	var visit func(n string)
+	visit = func(n string) {
+		state[n] = onPath
+		path = append(path, n)
+		for _, next := range adj[n] {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case onPath:
+				start := len(path) - 1
+				for path[start] != next {
+					start--
+				}
+				cycle := canonicalCycle(path[start:])
+				if key := strings.Join(cycle, "\x00"); !seen[key] {
+					seen[key] = true
+					out = append(out, EmbedCycle{Paths: cycle})
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[n] = done
+	}
+	for _, n := range order {
+		if state[n] == unvisited {
+			visit(n)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return strings.Join(out[i].Paths, "\x00") < strings.Join(out[j].Paths, "\x00")
+	})
+	return out, nil
+}
+
+// canonicalCycle copies cycle rotated to begin at its smallest path.
+func canonicalCycle(cycle []string) []string {
+	minIdx := 0
+	for i, p := range cycle {
+		if p < cycle[minIdx] {
+			minIdx = i
+		}
+	}
+	out := make([]string, 0, len(cycle))
+	out = append(out, cycle[minIdx:]...)
+	return append(out, cycle[:minIdx]...)
+}
+
+// duplicateNotes groups existing notes by the hash of their normalized body
+// (see normalizedContent), read from the note files on disk. With
+// similarity > 0, notes whose word 3-shingle sets have a Jaccard similarity
+// of at least similarity are joined into the same group; every pair is
+// compared, so this is quadratic in the number of notes. Notes that are empty
+// once normalized are never reported.
+func duplicateNotes(db dbExecer, vaultPath string, similarity float64) ([]DuplicateGroup, error) {
+	notes, err := listRegisteredNotes(db)
+	if err != nil {
+		return nil, err
+	}
+	var paths, bodies []string
+	for _, path := range notes {
+		content, err := os.ReadFile(filepath.Join(vaultPath, path))
+		if os.IsNotExist(err) {
+			continue // deleted since the last build or update
+		}
+		if err != nil {
+			return nil, err
+		}
+		if norm := normalizedContent(string(content)); norm != "" {
+			paths = append(paths, path)
+			bodies = append(bodies, norm)
+		}
+	}
+
+	// Union-find over note indexes; the root is always the smallest index,
+	// so groups come out in path order.
+	parent := make([]int, len(paths))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		ri, rj := find(i), find(j)
+		if ri > rj {
+			ri, rj = rj, ri
+		}
+		parent[rj] = ri
+	}
+
+	byHash := make(map[string]int)
+	for i, body := range bodies {
+		h := contentHash(body)
+		if j, ok := byHash[h]; ok {
+			union(j, i)
+			continue
+		}
+		byHash[h] = i
+	}
+	if similarity > 0 {
+		shingles := make([]map[string]bool, len(bodies))
+		for i, body := range bodies {
+			shingles[i] = wordShingles(body)
+		}
+		for i := range bodies {
+			for j := i + 1; j < len(bodies); j++ {
+				if find(i) != find(j) && jaccard(shingles[i], shingles[j]) >= similarity {
+					union(i, j)
+				}
+			}
+		}
+	}
+
+	members := make(map[int][]string)
+	var roots []int
+	for i, p := range paths {
+		r := find(i)
+		if _, ok := members[r]; !ok {
+			roots = append(roots, r)
+		}
+		members[r] = append(members[r], p)
+	}
+	var out []DuplicateGroup
+	for _, r := range roots {
+		if len(members[r]) > 1 {
+			out = append(out, DuplicateGroup{Paths: members[r]})
+		}
+	}
+	return out, nil
+}
+
+// normalizedContent returns a note's body without its frontmatter, with runs
+// of whitespace collapsed to single spaces.
+func normalizedContent(content string) string {
+	lines := strings.Split(content, "\n")
+	if fmEnd := frontmatterEnd(lines); fmEnd > 0 {
+		lines = lines[fmEnd+1:]
+	}
+	return strings.Join(strings.Fields(strings.Join(lines, "\n")), " ")
+}
+
+// wordShingles returns the set of 3-word sequences in normalized text, or the
+// whole text for notes of fewer than three words.
+func wordShingles(text string) map[string]bool {
+	const k = 3
+	words := strings.Fields(strings.ToLower(text))
+	set := make(map[string]bool)
+	if len(words) < k {
+		set[strings.Join(words, " ")] = true
+		return set
+	}
+	for i := 0; i+k <= len(words); i++ {
+		set[strings.Join(words[i:i+k], " ")] = true
+	}
+	return set
+}
+
+// jaccard returns |a ∩ b| / |a ∪ b|.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	inter := 0
+	for s := range a {
+		if b[s] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// suggestPhantomFixes finds, for each phantom, the note basename with the
+// smallest edit distance within suggestThreshold. Phantoms without a close
+// match are omitted. Ties go to the lexicographically first path.
+func suggestPhantomFixes(db dbExecer) ([]PhantomSuggestion, error) {
+	type note struct {
+		name []rune
+		path string
+	}
+	rows, err := db.Query(`SELECT name, path FROM nodes WHERE type='note' AND exists_flag=1 ORDER BY path`)
+	if err != nil {
+		return nil, err
+	}
+	var notes []note
+	for rows.Next() {
+		var name, path string
+		if err := rows.Scan(&name, &path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		notes = append(notes, note{name: []rune(strings.ToLower(name)), path: path})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prows, err := db.Query(`SELECT name FROM nodes WHERE type='phantom' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	var phantoms []string
+	for prows.Next() {
+		var name string
+		if err := prows.Scan(&name); err != nil {
+			prows.Close()
+			return nil, err
+		}
+		phantoms = append(phantoms, name)
+	}
+	prows.Close()
+	if err := prows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := []PhantomSuggestion{}
+	for _, ph := range phantoms {
+		pr := []rune(strings.ToLower(ph))
+		best := suggestThreshold(len(pr)) + 1
+		var bestPath string
+		for _, n := range notes {
+			// Length difference is a lower bound on edit distance.
+			diff := len(pr) - len(n.name)
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff >= best {
+				continue
+			}
+			if d := levenshtein(pr, n.name); d < best {
+				best = d
+				bestPath = n.path
+			}
+		}
+		if bestPath != "" {
+			result = append(result, PhantomSuggestion{Phantom: ph, Path: bestPath, Distance: best})
+		}
+	}
 	return result, nil
 }
+
+// suggestThreshold is the largest edit distance accepted for a name of n
+// runes: roughly one edit per three characters, capped at 3.
+func suggestThreshold(n int) int {
+	t := n / 3
+	if t > 3 {
+		t = 3
+	}
+	return t
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
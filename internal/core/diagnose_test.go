@@ -40,6 +40,59 @@ func TestDiagnose_BasenameConflicts(t *testing.T) {
 	}
 }
 
+func TestDiagnose_FragileRootPriority(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_build_root_priority")
+	// A path link keeps resolving when A.md moves, so C.md is not a dependent.
+	writeVaultFiles(t, vault, map[string]string{"C.md": "[[/A]] and [[sub/A]]\n", "D.md": "[[sub/A]]\n"})
+	buildForQuery(t, vault)
+
+	result, err := Diagnose(vault, DiagnoseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.FragileRootPriority) != 1 {
+		t.Fatalf("fragile_root_priority = %+v, want 1 entry", result.FragileRootPriority)
+	}
+	c := result.FragileRootPriority[0]
+	if c.Name != "A" || c.RootPath != "A.md" {
+		t.Errorf("conflict = %+v, want name A at A.md", c)
+	}
+	if len(c.Paths) != 2 || c.Paths[0] != "A.md" || c.Paths[1] != "sub/A.md" {
+		t.Errorf("paths = %v, want [A.md sub/A.md]", c.Paths)
+	}
+	if len(c.Sources) != 1 || c.Sources[0] != "B.md" {
+		t.Errorf("sources = %v, want [B.md]", c.Sources)
+	}
+}
+
+func TestDiagnose_FragileRootPriorityDisabled(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_build_root_priority")
+	buildForQuery(t, vault)
+	// Without root priority [[A]] does not resolve to A.md, so nothing depends on it.
+	writeVaultFiles(t, vault, map[string]string{"mdhop.yaml": "build:\n  root_priority: false\n"})
+
+	result, err := Diagnose(vault, DiagnoseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.FragileRootPriority) != 0 {
+		t.Errorf("fragile_root_priority = %+v, want none", result.FragileRootPriority)
+	}
+}
+
+func TestDiagnose_FragileRootPriorityNoRoot(t *testing.T) {
+	vault := setupVaultForDiagnose(t, "vault_query_ambiguous_name")
+
+	result, err := Diagnose(vault, DiagnoseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// sub1/A.md and sub2/A.md conflict, but neither is at the root.
+	if len(result.FragileRootPriority) != 0 {
+		t.Errorf("fragile_root_priority = %+v, want none", result.FragileRootPriority)
+	}
+}
+
 func TestDiagnose_Phantoms(t *testing.T) {
 	vault := setupVaultForDiagnose(t, "vault_build_phantom")
 
@@ -129,3 +182,125 @@ func TestDiagnose_FieldsFilter(t *testing.T) {
 	}
 }
 
+
+func TestDiagnose_Suggest(t *testing.T) {
+	vault := setupVaultForDiagnose(t, "vault_diagnose_suggest")
+
+	result, err := Diagnose(vault, DiagnoseOptions{Suggest: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Zebra Crossing has no close note → omitted.
+	want := []PhantomSuggestion{
+		{Phantom: "Desgin", Path: "Design.md", Distance: 2},
+		{Phantom: "Raodmap", Path: "sub/Roadmap.md", Distance: 2},
+	}
+	if len(result.Suggestions) != len(want) {
+		t.Fatalf("suggestions = %+v, want %+v", result.Suggestions, want)
+	}
+	for i := range want {
+		if result.Suggestions[i] != want[i] {
+			t.Errorf("suggestions[%d] = %+v, want %+v", i, result.Suggestions[i], want[i])
+		}
+	}
+}
+
+func TestDiagnose_SuggestNotRequested(t *testing.T) {
+	vault := setupVaultForDiagnose(t, "vault_diagnose_suggest")
+
+	result, err := Diagnose(vault, DiagnoseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Suggestions != nil {
+		t.Errorf("suggestions = %+v, want nil", result.Suggestions)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"desgin", "design", 2},
+		{"日本語", "日本", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDiagnose_EmbedCycles(t *testing.T) {
+	vault := setupVaultForDiagnose(t, "vault_diagnose_embed_cycle")
+
+	result, err := Diagnose(vault, DiagnoseOptions{Fields: []string{"embed_cycles"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A.md ⇄ B.md embed each other and Self.md embeds itself. Reader.md only
+	// embeds into the cycle, and Reader.md ⇄ C.md are plain links.
+	want := [][]string{{"A.md", "B.md"}, {"Self.md"}}
+	if len(result.EmbedCycles) != len(want) {
+		t.Fatalf("embed_cycles = %+v, want %v", result.EmbedCycles, want)
+	}
+	for i, c := range result.EmbedCycles {
+		if strings.Join(c.Paths, ",") != strings.Join(want[i], ",") {
+			t.Errorf("embed_cycles[%d] = %v, want %v", i, c.Paths, want[i])
+		}
+	}
+}
+
+func TestCanonicalCycle(t *testing.T) {
+	got := canonicalCycle([]string{"c.md", "a.md", "b.md"})
+	if strings.Join(got, ",") != "a.md,b.md,c.md" {
+		t.Errorf("canonicalCycle = %v, want [a.md b.md c.md]", got)
+	}
+}
+
+func TestDiagnose_DuplicateNotes(t *testing.T) {
+	vault := setupVaultForDiagnose(t, "vault_diagnose_duplicates")
+
+	result, err := Diagnose(vault, DiagnoseOptions{Fields: []string{"duplicate_notes"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Original.md and sub/Copy.md differ only in frontmatter and whitespace.
+	// Edited.md changes one word; Stub1.md and Stub2.md are empty bodies.
+	if len(result.DuplicateNotes) != 1 {
+		t.Fatalf("duplicate_notes = %+v, want 1 group", result.DuplicateNotes)
+	}
+	if got := strings.Join(result.DuplicateNotes[0].Paths, ","); got != "Original.md,sub/Copy.md" {
+		t.Errorf("duplicate_notes[0] = %s, want Original.md,sub/Copy.md", got)
+	}
+}
+
+func TestDiagnose_DuplicateNotesSimilarity(t *testing.T) {
+	vault := setupVaultForDiagnose(t, "vault_diagnose_duplicates")
+
+	result, err := Diagnose(vault, DiagnoseOptions{Fields: []string{"duplicate_notes"}, Similarity: 0.5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.DuplicateNotes) != 1 {
+		t.Fatalf("duplicate_notes = %+v, want 1 group", result.DuplicateNotes)
+	}
+	if got := strings.Join(result.DuplicateNotes[0].Paths, ","); got != "Edited.md,Original.md,sub/Copy.md" {
+		t.Errorf("duplicate_notes[0] = %s, want Edited.md,Original.md,sub/Copy.md", got)
+	}
+
+	if _, err := Diagnose(vault, DiagnoseOptions{Similarity: 1.5}); err == nil {
+		t.Error("expected error for similarity above 1")
+	}
+}
+
+func TestNormalizedContent(t *testing.T) {
+	got := normalizedContent("---\ntags: [a]\n---\n# Title\n\n  body\ttext  \n")
+	if got != "# Title body text" {
+		t.Errorf("normalizedContent = %q, want %q", got, "# Title body text")
+	}
+}
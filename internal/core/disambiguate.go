@@ -14,6 +14,9 @@ type DisambiguateOptions struct {
 	Name   string   // basename to disambiguate (required)
 	Target string   // target file path (required if multiple candidates)
 	Files  []string // limit rewriting to these source files
+	DryRun bool     // report the rewrites without changing files or the index
+	// PreserveMtime as MoveOptions.PreserveMtime, for the rewritten files.
+	PreserveMtime bool
 }
 
 // DisambiguateResult reports the outcome of the disambiguate operation.
@@ -29,12 +32,29 @@ func Disambiguate(vaultPath string, opts DisambiguateOptions) (*DisambiguateResu
 		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
 	}
 
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
 	// Open DB.
 	db, err := openDBAt(dbp)
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
+	if err := ensureEdgeCol(db); err != nil {
+		return nil, err
+	}
+	if err := checkFullIndex(db); err != nil {
+		return nil, err
+	}
+
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
 
 	// Find candidate notes matching the basename.
 	nameKey := strings.TrimSuffix(strings.ToLower(opts.Name), ".md")
@@ -114,7 +134,7 @@ func Disambiguate(vaultPath string, opts DisambiguateOptions) (*DisambiguateResu
 	var edgeRows *sql.Rows
 	if phantomID.Valid {
 		edgeRows, err = db.Query(
-			`SELECT e.id, e.raw_link, e.link_type, e.line_start, sn.path, sn.id, tn.type
+			`SELECT e.id, e.raw_link, e.link_type, e.line_start, COALESCE(e.col, -1), sn.path, sn.id, tn.type
 			 FROM edges e
 			 JOIN nodes sn ON sn.id = e.source_id AND sn.exists_flag = 1
 			 JOIN nodes tn ON tn.id = e.target_id
@@ -122,7 +142,7 @@ func Disambiguate(vaultPath string, opts DisambiguateOptions) (*DisambiguateResu
 			target.id, phantomID.Int64)
 	} else {
 		edgeRows, err = db.Query(
-			`SELECT e.id, e.raw_link, e.link_type, e.line_start, sn.path, sn.id, tn.type
+			`SELECT e.id, e.raw_link, e.link_type, e.line_start, COALESCE(e.col, -1), sn.path, sn.id, tn.type
 			 FROM edges e
 			 JOIN nodes sn ON sn.id = e.source_id AND sn.exists_flag = 1
 			 JOIN nodes tn ON tn.id = e.target_id
@@ -135,7 +155,7 @@ func Disambiguate(vaultPath string, opts DisambiguateOptions) (*DisambiguateResu
 	for edgeRows.Next() {
 		var re rewriteEntry
 		var targetType string
-		if err := edgeRows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.sourcePath, &re.sourceID, &targetType); err != nil {
+		if err := edgeRows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.col, &re.sourcePath, &re.sourceID, &targetType); err != nil {
 			edgeRows.Close()
 			return nil, err
 		}
@@ -191,12 +211,16 @@ func Disambiguate(vaultPath string, opts DisambiguateOptions) (*DisambiguateResu
 		}
 	}
 
+	if opts.DryRun {
+		return &DisambiguateResult{Rewritten: rewrittenLinks(rewrites)}, nil
+	}
+
 	// Apply disk rewrites.
 	groups := make(map[string][]rewriteEntry)
 	for _, re := range rewrites {
 		groups[re.sourcePath] = append(groups[re.sourcePath], re)
 	}
-	newMtimes, backups, applyErr := applyFileRewrites(vaultPath, groups)
+	newMtimes, backups, applyErr := applyFileRewrites(vaultPath, groups, opts.PreserveMtime || cfg.Rewrite.PreserveMtime)
 	if applyErr != nil {
 		return nil, applyErr
 	}
@@ -215,6 +239,10 @@ func Disambiguate(vaultPath string, opts DisambiguateOptions) (*DisambiguateResu
 		}
 	}()
 
+	nt, err := newNoteText(tx)
+	if err != nil {
+		return nil, err
+	}
 	result := &DisambiguateResult{}
 	for _, re := range rewrites {
 		if _, err := tx.Exec("UPDATE edges SET raw_link = ? WHERE id = ?", re.newRawLink, re.edgeID); err != nil {
@@ -226,6 +254,9 @@ func Disambiguate(vaultPath string, opts DisambiguateOptions) (*DisambiguateResu
 			NewLink: re.newRawLink,
 		})
 	}
+	if err := shiftEdgeCols(tx, rewrites); err != nil {
+		return nil, err
+	}
 
 	// Update source mtime in DB.
 	mtimeUpdated := make(map[int64]bool)
@@ -238,8 +269,14 @@ func Disambiguate(vaultPath string, opts DisambiguateOptions) (*DisambiguateResu
 		if _, err := tx.Exec("UPDATE nodes SET mtime = ? WHERE id = ? AND type = 'note'", mt, re.sourceID); err != nil {
 			return nil, err
 		}
+		if err := nt.refresh(tx, vaultPath, re.sourceID, re.sourcePath); err != nil {
+			return nil, err
+		}
 	}
 
+	if err := bumpIndexVersion(tx); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -280,12 +317,12 @@ func resolveDisambiguateTarget(name string, candidates []string, target string)
 // It scans all .md files in the vault directly.
 func DisambiguateScan(vaultPath string, opts DisambiguateOptions) (*DisambiguateResult, error) {
 	// Collect all .md files.
-	files, err := collectMarkdownFiles(vaultPath)
+	cfg, err := LoadConfig(vaultPath)
 	if err != nil {
 		return nil, err
 	}
 
-	cfg, err := LoadConfig(vaultPath)
+	files, err := collectMarkdownFiles(vaultPath, cfg.Build)
 	if err != nil {
 		return nil, err
 	}
@@ -386,6 +423,7 @@ func DisambiguateScan(vaultPath string, opts DisambiguateOptions) (*Disambiguate
 				rawLink:    lo.rawLink,
 				linkType:   lo.linkType,
 				lineStart:  lo.lineStart,
+				col:        lo.col,
 				sourcePath: sourcePath,
 				sourceID:   0,
 				newRawLink: newRawLink,
@@ -396,30 +434,33 @@ func DisambiguateScan(vaultPath string, opts DisambiguateOptions) (*Disambiguate
 	if len(rewrites) == 0 {
 		return &DisambiguateResult{}, nil
 	}
+	result := &DisambiguateResult{Rewritten: rewrittenLinks(rewrites)}
+	if opts.DryRun {
+		return result, nil
+	}
 
 	// Apply disk rewrites.
 	groups := make(map[string][]rewriteEntry)
 	for _, re := range rewrites {
 		groups[re.sourcePath] = append(groups[re.sourcePath], re)
 	}
-	_, _, applyErr := applyFileRewrites(vaultPath, groups)
+	_, _, applyErr := applyFileRewrites(vaultPath, groups, opts.PreserveMtime || cfg.Rewrite.PreserveMtime)
 	if applyErr != nil {
 		return nil, applyErr
 	}
 
-	// Build result.
-	result := &DisambiguateResult{}
-	for _, re := range rewrites {
-		result.Rewritten = append(result.Rewritten, RewrittenLink{
-			File:    re.sourcePath,
-			OldLink: re.rawLink,
-			NewLink: re.newRawLink,
-		})
-	}
-
 	return result, nil
 }
 
+// rewrittenLinks reports rewrite entries in result form.
+func rewrittenLinks(rewrites []rewriteEntry) []RewrittenLink {
+	out := make([]RewrittenLink, len(rewrites))
+	for i, re := range rewrites {
+		out[i] = RewrittenLink{File: re.sourcePath, OldLink: re.rawLink, NewLink: re.newRawLink}
+	}
+	return out
+}
+
 // isLinkBrokenForScan checks if a path link target does not resolve to any
 // known file in the vault. Used by DisambiguateScan to detect broken path links.
 func isLinkBrokenForScan(sourcePath string, lo linkOccur, pathSetLower map[string]bool) bool {
@@ -3,6 +3,7 @@ package core
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -742,3 +743,74 @@ func TestDisambiguatePhantomPathLinkScan(t *testing.T) {
 	}
 }
 
+
+func TestDisambiguateDryRunMatchesRun(t *testing.T) {
+	vault := copyVault(t, "vault_disambiguate")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	bPath := filepath.Join(vault, "B.md")
+	orig, err := os.ReadFile(bPath)
+	if err != nil {
+		t.Fatalf("read B.md: %v", err)
+	}
+
+	plan, err := Disambiguate(vault, DisambiguateOptions{Name: "A", DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	after, _ := os.ReadFile(bPath)
+	if string(after) != string(orig) {
+		t.Error("B.md was modified during dry-run")
+	}
+
+	result, err := Disambiguate(vault, DisambiguateOptions{Name: "A"})
+	if err != nil {
+		t.Fatalf("disambiguate: %v", err)
+	}
+	if len(plan.Rewritten) != 5 || !reflect.DeepEqual(plan.Rewritten, result.Rewritten) {
+		t.Errorf("dry-run Rewritten = %v, want %v", plan.Rewritten, result.Rewritten)
+	}
+}
+
+func TestDisambiguateDryRunStaleSource(t *testing.T) {
+	vault := copyVault(t, "vault_disambiguate")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	time.Sleep(1100 * time.Millisecond) // ensure mtime changes
+	if err := os.WriteFile(filepath.Join(vault, "B.md"), []byte("[[A]]\nmodified\n"), 0o644); err != nil {
+		t.Fatalf("write B.md: %v", err)
+	}
+
+	_, err := Disambiguate(vault, DisambiguateOptions{Name: "A", DryRun: true})
+	if err == nil || !strings.Contains(err.Error(), "source file is stale") {
+		t.Errorf("error = %v, want containing 'source file is stale'", err)
+	}
+}
+
+func TestDisambiguateScanDryRunMatchesRun(t *testing.T) {
+	vault := copyVault(t, "vault_disambiguate")
+	bPath := filepath.Join(vault, "B.md")
+	orig, err := os.ReadFile(bPath)
+	if err != nil {
+		t.Fatalf("read B.md: %v", err)
+	}
+
+	plan, err := DisambiguateScan(vault, DisambiguateOptions{Name: "A", DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	after, _ := os.ReadFile(bPath)
+	if string(after) != string(orig) {
+		t.Error("B.md was modified during dry-run")
+	}
+
+	result, err := DisambiguateScan(vault, DisambiguateOptions{Name: "A"})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if len(plan.Rewritten) != 5 || !reflect.DeepEqual(plan.Rewritten, result.Rewritten) {
+		t.Errorf("dry-run Rewritten = %v, want %v", plan.Rewritten, result.Rewritten)
+	}
+}
@@ -0,0 +1,109 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ListEdgesOptions controls ListEdges.
+type ListEdgesOptions struct {
+	Limit     int      // 0 = no limit
+	Offset    int      // edges to skip before Limit applies
+	LinkTypes []string // nil = all; otherwise only these link types (wikilink, markdown, tag, frontmatter, frontmatter-link)
+}
+
+// EdgeRecord is a single edge of the index: one link occurrence in a note.
+type EdgeRecord struct {
+	Source    string   // vault-relative path of the linking note
+	Target    NodeInfo // note, phantom, tag or asset
+	LinkType  string
+	RawLink   string
+	Subpath   string // "#Heading" or "#^block"; "" when the link has none
+	LineStart int
+	LineEnd   int
+}
+
+var edgeLinkTypes = map[string]bool{
+	"wikilink":         true,
+	"markdown":         true,
+	"tag":              true,
+	"frontmatter":      true,
+	"frontmatter-link": true,
+}
+
+// ListEdges returns the edges of the index ordered by source path, line and
+// edge id, so that pages taken with Limit and Offset never overlap.
+func ListEdges(vaultPath string, opts ListEdgesOptions) ([]EdgeRecord, error) {
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("limit must be >= 0")
+	}
+	if opts.Offset < 0 {
+		return nil, fmt.Errorf("offset must be >= 0")
+	}
+	for _, lt := range opts.LinkTypes {
+		if !edgeLinkTypes[lt] {
+			return nil, fmt.Errorf("unknown link type: %s", lt)
+		}
+	}
+
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return queryEdgeRecords(db, opts)
+}
+
+func queryEdgeRecords(db dbExecer, opts ListEdgesOptions) ([]EdgeRecord, error) {
+	q := `SELECT s.path, t.type, t.name, COALESCE(t.path,''), t.exists_flag,
+		        e.link_type, e.raw_link, COALESCE(e.subpath,''), COALESCE(e.line_start,0), COALESCE(e.line_end,0)
+		 FROM edges e
+		 JOIN nodes s ON s.id = e.source_id
+		 JOIN nodes t ON t.id = e.target_id`
+	var args []any
+	if len(opts.LinkTypes) > 0 {
+		q += ` WHERE e.link_type IN (?` + strings.Repeat(",?", len(opts.LinkTypes)-1) + `)`
+		for _, lt := range opts.LinkTypes {
+			args = append(args, lt)
+		}
+	}
+	q += ` ORDER BY s.path, e.line_start, e.id`
+	limit := opts.Limit
+	if limit == 0 {
+		limit = -1
+	}
+	q += ` LIMIT ? OFFSET ?`
+	args = append(args, limit, opts.Offset)
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []EdgeRecord{}
+	for rows.Next() {
+		var r EdgeRecord
+		var exists int
+		if err := rows.Scan(&r.Source, &r.Target.Type, &r.Target.Name, &r.Target.Path, &exists,
+			&r.LinkType, &r.RawLink, &r.Subpath, &r.LineStart, &r.LineEnd); err != nil {
+			return nil, err
+		}
+		r.Target.Exists = exists == 1
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
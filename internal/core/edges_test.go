@@ -0,0 +1,86 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListEdgesPaging(t *testing.T) {
+	vault := setupFullVault(t)
+	total := countEdges(t, dbPath(vault))
+	if total < 3 {
+		t.Fatalf("fixture has %d edges, want several", total)
+	}
+
+	all, err := ListEdges(vault, ListEdgesOptions{})
+	if err != nil {
+		t.Fatalf("list edges: %v", err)
+	}
+	if len(all) != total {
+		t.Fatalf("edges = %d, want %d", len(all), total)
+	}
+
+	var paged []EdgeRecord
+	for offset := 0; ; offset += 2 {
+		page, err := ListEdges(vault, ListEdgesOptions{Limit: 2, Offset: offset})
+		if err != nil {
+			t.Fatalf("list edges offset %d: %v", offset, err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		paged = append(paged, page...)
+	}
+	if len(paged) != total {
+		t.Fatalf("edges across pages = %d, want %d", len(paged), total)
+	}
+	for i := range all {
+		if !reflect.DeepEqual(all[i], paged[i]) {
+			t.Errorf("edge %d: paged %+v, want %+v", i, paged[i], all[i])
+		}
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i-1].Source > all[i].Source {
+			t.Errorf("edges not ordered by source: %s before %s", all[i-1].Source, all[i].Source)
+		}
+	}
+}
+
+func TestListEdgesLinkTypes(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "---\ntags: [fm]\n---\n[[B#Intro]] #inline\n[b](B.md)\n",
+		"B.md": "# Intro\n",
+	})
+	buildVault(t, vault)
+
+	edges, err := ListEdges(vault, ListEdgesOptions{LinkTypes: []string{"wikilink"}})
+	if err != nil {
+		t.Fatalf("list edges: %v", err)
+	}
+	if len(edges) != 1 {
+		t.Fatalf("edges = %+v, want one wikilink", edges)
+	}
+	e := edges[0]
+	if e.Source != "A.md" || e.Target.Path != "B.md" || e.Target.Type != "note" || e.RawLink != "[[B#Intro]]" ||
+		e.Subpath != "#Intro" || e.LineStart != 4 || e.LineEnd != 4 {
+		t.Errorf("edge = %+v", e)
+	}
+
+	edges, err = ListEdges(vault, ListEdgesOptions{LinkTypes: []string{"tag", "frontmatter"}})
+	if err != nil {
+		t.Fatalf("list edges: %v", err)
+	}
+	var got []string
+	for _, e := range edges {
+		got = append(got, e.LinkType+":"+e.Target.Name)
+	}
+	if strings.Join(got, ",") != "frontmatter:#fm,tag:#inline" {
+		t.Errorf("tag edges = %v", got)
+	}
+
+	if _, err := ListEdges(vault, ListEdgesOptions{LinkTypes: []string{"embed"}}); err == nil || !strings.Contains(err.Error(), "unknown link type: embed") {
+		t.Errorf("expected unknown link type error, got %v", err)
+	}
+}
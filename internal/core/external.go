@@ -0,0 +1,75 @@
+package core
+
+import (
+	"fmt"
+	"os"
+)
+
+// ExternalLink is an http(s) URL occurring in a note.
+type ExternalLink struct {
+	Path string // source note
+	Line int
+	URL  string
+}
+
+// ExternalOptions controls QueryExternal.
+type ExternalOptions struct {
+	Dedupe  bool           // keep only the first occurrence of each URL
+	Exclude *ExcludeFilter // nil = no exclusion (paths only)
+}
+
+// QueryExternal returns every external link in the vault, sorted by source
+// path and line. External links are stored per note, not as graph edges.
+func QueryExternal(vaultPath string, opts ExternalOptions) ([]ExternalLink, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	q := `SELECT n.path, x.line, x.url
+		 FROM external_links x
+		 JOIN nodes n ON n.id = x.node_id
+		 WHERE n.type = 'note'`
+	var args []any
+	if ef := opts.Exclude; ef != nil {
+		pathSQL, pathArgs := ef.PathExcludeSQL("n.path")
+		q += pathSQL
+		args = append(args, pathArgs...)
+	}
+	q += ` ORDER BY n.path, x.line, x.id`
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []ExternalLink{}
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var l ExternalLink
+		if err := rows.Scan(&l.Path, &l.Line, &l.URL); err != nil {
+			return nil, err
+		}
+		if opts.Dedupe {
+			if seen[l.URL] {
+				continue
+			}
+			seen[l.URL] = true
+		}
+		result = append(result, l)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestQueryExternal(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_external")
+	buildForQuery(t, vault)
+
+	got, err := QueryExternal(vault, ExternalOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []ExternalLink{
+		{Path: "A.md", Line: 1, URL: "http://example.com"},
+		{Path: "A.md", Line: 2, URL: "https://example.org/path"},
+		{Path: "A.md", Line: 2, URL: "https://example.net"},
+		{Path: "B.md", Line: 1, URL: "http://example.com"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("external = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("external[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// External URLs are not graph edges; the internal note.md link is.
+	edges := queryEdges(t, dbPath(vault), "A.md")
+	if len(edges) != 1 || edges[0].targetKey != "note:path:note.md" {
+		t.Errorf("A.md edges = %+v, want only note.md", edges)
+	}
+}
+
+func TestQueryExternalDedupe(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_external")
+	buildForQuery(t, vault)
+
+	got, err := QueryExternal(vault, ExternalOptions{Dedupe: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("external = %+v, want 3 unique URLs", got)
+	}
+	for _, l := range got {
+		if l.Path == "B.md" {
+			t.Errorf("duplicate URL from B.md kept: %+v", l)
+		}
+	}
+}
+
+func TestQueryExternalAfterDelete(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_external")
+	buildForQuery(t, vault)
+
+	if _, err := Delete(vault, DeleteOptions{Files: []string{"A.md"}, RemoveFiles: true}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	got, err := QueryExternal(vault, ExternalOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Path != "B.md" {
+		t.Errorf("external = %+v, want only B.md", got)
+	}
+}
+
+func TestStatsExternal(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_external")
+	buildForQuery(t, vault)
+
+	got, err := Stats(vault, StatsOptions{Fields: []string{"external_links_total", "external_urls_total"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ExternalLinksTotal != 4 || got.ExternalURLsTotal != 3 {
+		t.Errorf("external links/urls = %d/%d, want 4/3", got.ExternalLinksTotal, got.ExternalURLsTotal)
+	}
+}
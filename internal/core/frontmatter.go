@@ -0,0 +1,428 @@
+package core
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rewriteFrontmatterTags renames oldTag to newTag in the frontmatter "tags"
+// value of content. The YAML text is edited in place, swapping only the tag
+// token, so quotes, indentation, comments, and block/flow list style survive.
+// Nested tags move with their parent (old/sub → new/sub), matching is
+// case-insensitive, and each entry keeps its own "#" prefix style. Entries
+// whose text cannot be located exactly (multi-line or escaped scalars) are
+// left alone. Returns the new content and the number of entries changed.
+func rewriteFrontmatterTags(content, oldTag, newTag string) (string, int) {
+	lines := strings.Split(content, "\n")
+	fmEnd := frontmatterEnd(lines)
+	if fmEnd <= 0 {
+		return content, 0
+	}
+	mapping := frontmatterMapping(lines[:fmEnd+1])
+	if mapping == nil {
+		return content, 0
+	}
+	oldBare := strings.TrimPrefix(oldTag, "#")
+	newBare := strings.TrimPrefix(newTag, "#")
+
+	type tagScalar struct {
+		node *yaml.Node
+		list bool // comma-separated single scalar: "tags: a, b"
+		flow bool // inside a flow sequence: "tags: [a, b]"
+	}
+	var scalars []tagScalar
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != "tags" {
+			continue
+		}
+		val := mapping.Content[i+1]
+		switch val.Kind {
+		case yaml.SequenceNode:
+			for _, item := range val.Content {
+				if item.Kind == yaml.ScalarNode {
+					scalars = append(scalars, tagScalar{node: item, flow: val.Style&yaml.FlowStyle != 0})
+				}
+			}
+		case yaml.ScalarNode:
+			scalars = append(scalars, tagScalar{node: val, list: true})
+		}
+	}
+
+	// Edit right to left so earlier columns on a shared line stay valid.
+	sort.Slice(scalars, func(a, b int) bool {
+		na, nb := scalars[a].node, scalars[b].node
+		if na.Line != nb.Line {
+			return na.Line > nb.Line
+		}
+		return na.Column > nb.Column
+	})
+
+	changed := 0
+	for _, s := range scalars {
+		// yaml line 1 is lines[1]; lines[0] is the opening "---".
+		idx := s.node.Line
+		if idx < 1 || idx >= fmEnd {
+			continue
+		}
+		var newValue string
+		var n int
+		if s.list {
+			newValue, n = renameTagList(s.node.Value, oldBare, newBare)
+		} else if v, ok := renameTagToken(s.node.Value, oldBare, newBare); ok {
+			newValue, n = v, 1
+		}
+		if n == 0 {
+			continue
+		}
+		if line, ok := replaceYAMLScalar(lines[idx], s.node, newValue, s.flow); ok {
+			lines[idx] = line
+			changed += n
+		}
+	}
+	if changed == 0 {
+		return content, 0
+	}
+	return strings.Join(lines, "\n"), changed
+}
+
+// renameTagToken returns tok renamed from oldBare to newBare, or ok=false when
+// tok is neither oldBare nor nested under it. A leading "#" is kept as written.
+func renameTagToken(tok, oldBare, newBare string) (string, bool) {
+	hash := ""
+	bare := tok
+	if strings.HasPrefix(bare, "#") {
+		hash, bare = "#", bare[1:]
+	}
+	lower, oldLower := strings.ToLower(bare), strings.ToLower(oldBare)
+	if lower != oldLower && !strings.HasPrefix(lower, oldLower+"/") {
+		return "", false
+	}
+	return hash + newBare + bare[len(oldBare):], true
+}
+
+// renameTagList applies renameTagToken to each comma-separated token of s,
+// keeping the whitespace around tokens.
+func renameTagList(s, oldBare, newBare string) (string, int) {
+	parts := strings.Split(s, ",")
+	n := 0
+	for i, p := range parts {
+		tok := strings.TrimSpace(p)
+		if tok == "" {
+			continue
+		}
+		if r, ok := renameTagToken(tok, oldBare, newBare); ok {
+			lead := p[:strings.Index(p, tok)]
+			parts[i] = lead + r + p[len(lead)+len(tok):]
+			n++
+		}
+	}
+	return strings.Join(parts, ","), n
+}
+
+// replaceYAMLScalar rewrites the scalar node on line to newValue, keeping its
+// quoting style. A plain scalar that would no longer parse as the same plain
+// string is double-quoted instead. ok is false when the scalar's text on the
+// line does not match its value (e.g. escapes or line folding).
+func replaceYAMLScalar(line string, node *yaml.Node, newValue string, flow bool) (string, bool) {
+	runes := []rune(line)
+	start := node.Column - 1
+	if start < 0 || start >= len(runes) {
+		return line, false
+	}
+
+	var oldText, newText string
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		oldText, newText = quoteDouble(node.Value), quoteDouble(newValue)
+	case yaml.SingleQuotedStyle:
+		oldText, newText = quoteSingle(node.Value), quoteSingle(newValue)
+	case 0:
+		oldText, newText = node.Value, newValue
+		if plainNeedsQuotes(newValue, flow) {
+			newText = quoteDouble(newValue)
+		}
+	default:
+		return line, false // literal/folded block scalars
+	}
+
+	end := start + len([]rune(oldText))
+	if end > len(runes) || string(runes[start:end]) != oldText {
+		return line, false
+	}
+	return string(runes[:start]) + newText + string(runes[end:]), true
+}
+
+func quoteDouble(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func quoteSingle(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// plainNeedsQuotes reports whether s cannot be written as a plain YAML scalar
+// without changing its meaning. It is conservative: a false positive only
+// adds quotes.
+func plainNeedsQuotes(s string, flow bool) bool {
+	if s == "" || s != strings.TrimSpace(s) {
+		return true
+	}
+	if strings.ContainsRune("#&*!|>'\"%@`-?:[]{},", rune(s[0])) {
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	return flow && strings.ContainsAny(s, ",[]{}")
+}
+
+// addFrontmatterTag adds the tag bare (without "#") to the frontmatter "tags"
+// value of content, creating the frontmatter or the key when missing. As with
+// rewriteFrontmatterTags, the YAML is edited as text so the rest of the
+// frontmatter keeps its formatting: a block list gets a new item in the same
+// indentation, a one-line flow list and a comma-separated scalar are extended
+// in place. changed is false when the tag is already listed (case-insensitive
+// unless preserveCase).
+func addFrontmatterTag(content, bare string, preserveCase bool) (string, bool, error) {
+	item := bare
+	if plainNeedsQuotes(bare, true) {
+		item = quoteDouble(bare)
+	}
+	lines := strings.Split(content, "\n")
+	fmEnd := frontmatterEnd(lines)
+	if fmEnd < 0 {
+		return "---\ntags:\n  - " + item + "\n---\n" + content, true, nil
+	}
+	newKey := []string{"tags:", "  - " + item}
+	if fmEnd == 1 {
+		return joinLines(insertLines(lines, 1, newKey)), true, nil
+	}
+	mapping := frontmatterMapping(lines[:fmEnd+1])
+	if mapping == nil {
+		return content, false, errUneditableTags
+	}
+	key, val := frontmatterKey(mapping, "tags")
+	if key == nil {
+		return joinLines(insertLines(lines, fmEnd, newKey)), true, nil
+	}
+	if frontmatterHasTag(val, bare, preserveCase) {
+		return content, false, nil
+	}
+
+	switch {
+	case val.Kind == yaml.SequenceNode && val.Style&yaml.FlowStyle != 0:
+		idx := val.Line
+		if !singleLineNode(val) || idx < 1 || idx >= fmEnd {
+			return content, false, errUneditableTags
+		}
+		end := strings.LastIndex(lines[idx], "]")
+		if end < 0 {
+			return content, false, errUneditableTags
+		}
+		sep := ", "
+		if len(val.Content) == 0 {
+			sep = ""
+		}
+		lines[idx] = lines[idx][:end] + sep + item + lines[idx][end:]
+	case val.Kind == yaml.SequenceNode:
+		last := val.Content[len(val.Content)-1]
+		idx := last.Line
+		runes := []rune(lines[idx])
+		if idx < 1 || idx >= fmEnd || last.Column-1 > len(runes) {
+			return content, false, errUneditableTags
+		}
+		lines = insertLines(lines, idx+1, []string{string(runes[:last.Column-1]) + item})
+	case val.Kind == yaml.ScalarNode && val.Value == "":
+		// "tags:" without a value.
+		idx := key.Line
+		if idx < 1 || idx >= fmEnd || val.Line != key.Line {
+			return content, false, errUneditableTags
+		}
+		lines[idx] = string([]rune(lines[idx])[:key.Column-1]) + "tags:"
+		lines = insertLines(lines, idx+1, []string{"  - " + item})
+	case val.Kind == yaml.ScalarNode:
+		idx := val.Line
+		if idx < 1 || idx >= fmEnd {
+			return content, false, errUneditableTags
+		}
+		line, ok := replaceYAMLScalar(lines[idx], val, val.Value+", "+bare, false)
+		if !ok {
+			return content, false, errUneditableTags
+		}
+		lines[idx] = line
+	default:
+		return content, false, errUneditableTags
+	}
+	return joinLines(lines), true, nil
+}
+
+// removeFrontmatterTag removes the tag bare (without "#") from the
+// frontmatter "tags" value of content, editing the YAML as text. Only exact
+// entries (case-insensitive unless preserveCase) are removed, not nested tags
+// under bare. A block
+// list losing its last item loses the "tags:" key too. changed is false when
+// the tag is not listed.
+func removeFrontmatterTag(content, bare string, preserveCase bool) (string, bool, error) {
+	lines := strings.Split(content, "\n")
+	fmEnd := frontmatterEnd(lines)
+	if fmEnd <= 1 {
+		return content, false, nil
+	}
+	mapping := frontmatterMapping(lines[:fmEnd+1])
+	if mapping == nil {
+		return content, false, nil
+	}
+	key, val := frontmatterKey(mapping, "tags")
+	if key == nil || !frontmatterHasTag(val, bare, preserveCase) {
+		return content, false, nil
+	}
+
+	switch val.Kind {
+	case yaml.SequenceNode:
+		var keep, drop []*yaml.Node
+		for _, item := range val.Content {
+			if item.Kind == yaml.ScalarNode && tagEntryMatches(item.Value, bare, preserveCase) {
+				drop = append(drop, item)
+			} else {
+				keep = append(keep, item)
+			}
+		}
+		if val.Style&yaml.FlowStyle != 0 {
+			idx := val.Line
+			if !singleLineNode(val) || idx < 1 || idx >= fmEnd {
+				return content, false, errUneditableTags
+			}
+			runes := []rune(lines[idx])
+			start := val.Column - 1
+			end := strings.LastIndex(string(runes[start:]), "]")
+			if end < 0 {
+				return content, false, errUneditableTags
+			}
+			items := make([]string, len(keep))
+			for i, n := range keep {
+				items[i] = yamlScalarText(n)
+			}
+			rest := string(runes[start:])[end+1:]
+			lines[idx] = string(runes[:start]) + "[" + strings.Join(items, ", ") + "]" + rest
+			break
+		}
+		// Block list: delete the item lines bottom-up.
+		remove := make(map[int]bool)
+		for _, n := range drop {
+			if n.Line < 1 || n.Line >= fmEnd {
+				return content, false, errUneditableTags
+			}
+			remove[n.Line] = true
+		}
+		if len(keep) == 0 && key.Line != val.Line {
+			remove[key.Line] = true
+		}
+		out := lines[:0]
+		for i, l := range lines {
+			if !remove[i] {
+				out = append(out, l)
+			}
+		}
+		lines = out
+	case yaml.ScalarNode:
+		idx := val.Line
+		if idx < 1 || idx >= fmEnd {
+			return content, false, errUneditableTags
+		}
+		var parts []string
+		for _, p := range strings.Split(val.Value, ",") {
+			if tok := strings.TrimSpace(p); tok != "" && !tagEntryMatches(tok, bare, preserveCase) {
+				parts = append(parts, tok)
+			}
+		}
+		if len(parts) == 0 {
+			lines[idx] = strings.TrimRight(string([]rune(lines[idx])[:val.Column-1]), " ")
+			break
+		}
+		line, ok := replaceYAMLScalar(lines[idx], val, strings.Join(parts, ", "), false)
+		if !ok {
+			return content, false, errUneditableTags
+		}
+		lines[idx] = line
+	default:
+		return content, false, errUneditableTags
+	}
+	return joinLines(lines), true, nil
+}
+
+var errUneditableTags = errors.New("frontmatter tags cannot be edited in place")
+
+// frontmatterKey returns the key and value nodes of name in mapping, or nils.
+func frontmatterKey(mapping *yaml.Node, name string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// frontmatterHasTag reports whether the "tags" value val lists bare.
+func frontmatterHasTag(val *yaml.Node, bare string, preserveCase bool) bool {
+	switch val.Kind {
+	case yaml.SequenceNode:
+		for _, item := range val.Content {
+			if item.Kind == yaml.ScalarNode && tagEntryMatches(item.Value, bare, preserveCase) {
+				return true
+			}
+		}
+	case yaml.ScalarNode:
+		for _, tok := range strings.Split(val.Value, ",") {
+			if tagEntryMatches(strings.TrimSpace(tok), bare, preserveCase) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tagEntryMatches compares a frontmatter tag entry, with or without "#", to
+// bare, case-insensitively unless preserveCase.
+func tagEntryMatches(entry, bare string, preserveCase bool) bool {
+	entry = strings.TrimPrefix(entry, "#")
+	if preserveCase {
+		return entry == bare
+	}
+	return strings.EqualFold(entry, bare)
+}
+
+// singleLineNode reports whether a flow collection and its items sit on one line.
+func singleLineNode(n *yaml.Node) bool {
+	for _, c := range n.Content {
+		if c.Line != n.Line {
+			return false
+		}
+	}
+	return true
+}
+
+// yamlScalarText returns a scalar as written, in its quoting style.
+func yamlScalarText(n *yaml.Node) string {
+	switch n.Style {
+	case yaml.DoubleQuotedStyle:
+		return quoteDouble(n.Value)
+	case yaml.SingleQuotedStyle:
+		return quoteSingle(n.Value)
+	}
+	return n.Value
+}
+
+func insertLines(lines []string, at int, add []string) []string {
+	out := make([]string, 0, len(lines)+len(add))
+	out = append(out, lines[:at]...)
+	out = append(out, add...)
+	return append(out, lines[at:]...)
+}
+
+func joinLines(lines []string) string {
+	return strings.Join(lines, "\n")
+}
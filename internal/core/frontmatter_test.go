@@ -0,0 +1,113 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRewriteFrontmatterTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		old     string
+		new     string
+		want    string
+		changed int
+	}{
+		{
+			name:    "double-quoted",
+			content: "---\ntags:\n  - \"#old\"\n  - keep\n---\nbody #old\n",
+			old:     "old", new: "new",
+			want:    "---\ntags:\n  - \"#new\"\n  - keep\n---\nbody #old\n",
+			changed: 1,
+		},
+		{
+			name:    "single-quoted",
+			content: "---\ntags: ['#old', 'x']\n---\n",
+			old:     "#old", new: "#new",
+			want:    "---\ntags: ['#new', 'x']\n---\n",
+			changed: 1,
+		},
+		{
+			name:    "block list keeps indentation and comment",
+			content: "---\ntitle: T\ntags:\n    - old  # legacy\n    - other\n---\n",
+			old:     "old", new: "renamed",
+			want:    "---\ntitle: T\ntags:\n    - renamed  # legacy\n    - other\n---\n",
+			changed: 1,
+		},
+		{
+			name:    "flow list",
+			content: "---\ntags: [old, x, old/sub]\n---\n",
+			old:     "old", new: "project",
+			want:    "---\ntags: [project, x, project/sub]\n---\n",
+			changed: 2,
+		},
+		{
+			name:    "comma-separated scalar",
+			content: "---\ntags: x, Old,y\n---\n",
+			old:     "old", new: "new",
+			want:    "---\ntags: x, new,y\n---\n",
+			changed: 1,
+		},
+		{
+			name:    "prefix without slash is not nested",
+			content: "---\ntags: [old, older]\n---\n",
+			old:     "old", new: "new",
+			want:    "---\ntags: [new, older]\n---\n",
+			changed: 1,
+		},
+		{
+			name:    "plain value that needs quoting",
+			content: "---\ntags: [old, x]\n---\n",
+			old:     "old", new: "a,b",
+			want:    "---\ntags: [\"a,b\", x]\n---\n",
+			changed: 1,
+		},
+		{
+			name:    "multibyte columns",
+			content: "---\ntags: [日本語, old]\n---\n",
+			old:     "old", new: "新しい",
+			want:    "---\ntags: [日本語, 新しい]\n---\n",
+			changed: 1,
+		},
+		{
+			name:    "other keys untouched",
+			content: "---\nrelated: [old]\ntags: [x]\n---\n",
+			old:     "old", new: "new",
+			want:    "---\nrelated: [old]\ntags: [x]\n---\n",
+			changed: 0,
+		},
+		{
+			name:    "no frontmatter",
+			content: "#old\n",
+			old:     "old", new: "new",
+			want:    "#old\n",
+			changed: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n := rewriteFrontmatterTags(tt.content, tt.old, tt.new)
+			if got != tt.want {
+				t.Errorf("content:\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if n != tt.changed {
+				t.Errorf("changed = %d, want %d", n, tt.changed)
+			}
+			// The result must still parse to the renamed tags.
+			if n > 0 && len(parseFrontmatter(splitFrontmatter(t, got))) == 0 {
+				t.Errorf("rewritten frontmatter no longer yields tags:\n%s", got)
+			}
+		})
+	}
+}
+
+func splitFrontmatter(t *testing.T, content string) []string {
+	t.Helper()
+	lines := strings.Split(content, "\n")
+	end := frontmatterEnd(lines)
+	if end <= 0 {
+		t.Fatalf("no frontmatter in:\n%s", content)
+	}
+	return lines[:end+1]
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitignoreRule is one pattern line of a .gitignore file.
+type gitignoreRule struct {
+	re      *regexp.Regexp // matches a path relative to the .gitignore's directory
+	negate  bool           // "!pattern" re-includes
+	dirOnly bool           // "pattern/" matches directories only
+}
+
+// gitignoreMatcher applies the .gitignore files found while walking the vault
+// (build.respect_gitignore). Files are loaded per directory as the walk enters
+// it, and a path is checked against every .gitignore from the vault root down
+// to its parent, the last matching rule winning. As in git, a path inside an
+// ignored directory cannot be re-included, since the walk never enters it.
+// .gitignore files above the vault root and global excludes are not read.
+type gitignoreMatcher struct {
+	rules map[string][]gitignoreRule // vault-relative dir ("" = root) → rules
+}
+
+func newGitignoreMatcher() *gitignoreMatcher {
+	return &gitignoreMatcher{rules: make(map[string][]gitignoreRule)}
+}
+
+// load reads the .gitignore in dirPath, registering it for the vault-relative
+// directory rel. A missing or unreadable file adds no rules.
+func (g *gitignoreMatcher) load(dirPath, rel string) {
+	f, err := os.Open(filepath.Join(dirPath, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var rules []gitignoreRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if r, ok := parseGitignoreLine(sc.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) > 0 {
+		g.rules[gitignoreDirKey(rel)] = rules
+	}
+}
+
+// match reports whether the vault-relative path is ignored.
+func (g *gitignoreMatcher) match(rel string, isDir bool) bool {
+	if g == nil {
+		return false
+	}
+	rel = NormalizePath(rel)
+	if rel == "." || rel == "" {
+		return false
+	}
+	ignored := false
+	check := func(base, sub string) {
+		for _, r := range g.rules[base] {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if r.re.MatchString(sub) {
+				ignored = !r.negate
+			}
+		}
+	}
+	check("", rel)
+	for i := 0; i < len(rel); i++ {
+		if rel[i] == '/' {
+			check(rel[:i], rel[i+1:])
+		}
+	}
+	return ignored
+}
+
+func gitignoreDirKey(rel string) string {
+	rel = NormalizePath(rel)
+	if rel == "." {
+		return ""
+	}
+	return rel
+}
+
+// parseGitignoreLine compiles one .gitignore line. Blank lines and comments
+// yield ok=false.
+func parseGitignoreLine(line string) (gitignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are ignored unless escaped.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return gitignoreRule{}, false
+	}
+
+	var r gitignoreRule
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return gitignoreRule{}, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to the
+	// .gitignore's directory; otherwise it matches at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	expr := gitignoreRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return gitignoreRule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+// gitignoreRegexp translates a gitignore glob into a regular expression:
+// "*" and "?" stay within one path segment, "**" spans segments, and
+// "[...]" is a character class ("[!...]" negated).
+func gitignoreRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			// Leading "**/" or "/**/": zero or more directories.
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
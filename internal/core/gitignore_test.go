@@ -0,0 +1,52 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGitignoreMatch(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		".gitignore":     "# comment\n\n*.log\n/build\ntmp/\ndocs/**/draft-*.md\n\\#hash.md\n",
+		"sub/.gitignore": "!keep.log\nlocal.md\n",
+	})
+	g := newGitignoreMatcher()
+	g.load(vault, ".")
+	g.load(filepath.Join(vault, "sub"), "sub")
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.log", false, true},
+		{"deep/x/a.log", false, true},
+		{"sub/keep.log", false, false}, // negated in the nested file
+		{"sub/other.log", false, true},
+		{"build", true, true},
+		{"sub/build", true, false}, // "/build" is anchored to the root
+		{"tmp", true, true},
+		{"sub/tmp", true, true},
+		{"tmp", false, false}, // "tmp/" matches directories only
+		{"docs/draft-a.md", false, true},
+		{"docs/x/y/draft-a.md", false, true},
+		{"docs/final.md", false, false},
+		{"#hash.md", false, true},
+		{"sub/local.md", false, true},
+		{"local.md", false, false}, // sub/.gitignore applies under sub only
+		{"Note.md", false, false},
+	}
+	for _, tt := range tests {
+		if got := g.match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("match(%q, dir=%v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestGitignoreMatchNil(t *testing.T) {
+	var g *gitignoreMatcher
+	if g.match("a.md", false) {
+		t.Error("nil matcher must not ignore anything")
+	}
+}
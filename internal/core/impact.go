@@ -0,0 +1,78 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// QueryImpact returns the notes that would lose their last backlink if the
+// note at file were deleted: the notes it links to (wikilink, markdown or
+// frontmatter link) that no other note links to. A note's links to itself do
+// not count as backlinks. Results are sorted by path. Only opts.Exclude is
+// used; excluded notes are left out of the result.
+func QueryImpact(vaultPath, file string, opts QueryOptions) ([]NodeInfo, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	path := NormalizePath(file)
+	hubID, err := getNodeID(db, noteKey(path))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("note not in index: %s", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return queryImpact(db, hubID, opts.Exclude)
+}
+
+func queryImpact(db dbExecer, hubID int64, ef *ExcludeFilter) ([]NodeInfo, error) {
+	q := `SELECT DISTINCT t.type, t.name, t.path, t.exists_flag
+		 FROM edges e
+		 JOIN nodes t ON t.id = e.target_id
+		 WHERE e.source_id = ? AND t.type = 'note' AND t.id != ?
+		 AND NOT EXISTS (
+		   SELECT 1 FROM edges o
+		   WHERE o.target_id = t.id AND o.source_id != ? AND o.source_id != t.id)`
+	args := []any{hubID, hubID, hubID}
+
+	if ef != nil {
+		pathSQL, pathArgs := ef.PathExcludeSQL("t.path")
+		q += pathSQL
+		args = append(args, pathArgs...)
+	}
+
+	q += ` ORDER BY t.path`
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []NodeInfo{}
+	for rows.Next() {
+		var n NodeInfo
+		var exists int
+		if err := rows.Scan(&n.Type, &n.Name, &n.Path, &exists); err != nil {
+			return nil, err
+		}
+		n.Exists = exists == 1
+		result = append(result, n)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQueryImpact(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"Hub.md":    "[[Only]] [[Shared]] [Only](Only.md) [[Missing]] #topic [[Hub]]\n",
+		"Only.md":   "[[Only#Top]] [[Hub]]\n# Top\n",
+		"Shared.md": "# Shared\n",
+		"Other.md":  "[[Shared]]\n",
+	})
+	buildVault(t, vault)
+
+	got, err := QueryImpact(vault, "Hub.md", QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Only.md's self-link is not a backlink; Shared.md keeps Other.md's.
+	if len(got) != 1 || got[0].Path != "Only.md" || got[0].Type != "note" {
+		t.Errorf("impact = %+v, want [Only.md]", got)
+	}
+
+	got, err = QueryImpact(vault, "Other.md", QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("impact of Other.md = %+v, want none", got)
+	}
+
+	ef, err := NewExcludeFilter(ExcludeConfig{}, []string{"Only.md"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = QueryImpact(vault, "Hub.md", QueryOptions{Exclude: ef})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("impact with Only.md excluded = %+v, want none", got)
+	}
+
+	if _, err := QueryImpact(vault, "Nope.md", QueryOptions{}); err == nil || !strings.Contains(err.Error(), "note not in index: Nope.md") {
+		t.Errorf("expected not in index error, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Index is a read-only handle on a vault's index for long-lived processes
+// such as `mdhop serve`. The database stays open across calls; each call
+// still takes the shared lock, and the database is reopened when a build has
+// replaced the index file. ResolveAll caches its resolve maps until the
+// index changes.
+type Index struct {
+	vaultPath string
+
+	mu   sync.Mutex
+	db   *sql.DB
+	info os.FileInfo // index file the open db belongs to
+	rc   *resolverContext
+}
+
+// OpenIndex opens the index of vaultPath for repeated reads.
+func OpenIndex(vaultPath string) (*Index, error) {
+	ix := &Index{vaultPath: vaultPath}
+	if _, _, err := ix.current(); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// Close closes the underlying database.
+func (ix *Index) Close() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.db == nil {
+		return nil
+	}
+	err := ix.db.Close()
+	ix.db = nil
+	return err
+}
+
+// Query is Query against the open index.
+func (ix *Index) Query(entry EntrySpec, opts QueryOptions) (*QueryResult, error) {
+	if err := validateQueryOptions(opts); err != nil {
+		return nil, err
+	}
+	var result *QueryResult
+	err := ix.read(func(db *sql.DB, _ *resolverContext) error {
+		var err error
+		result, err = queryDB(db, ix.vaultPath, entry, opts)
+		return err
+	})
+	return result, err
+}
+
+// Resolve is Resolve against the open index. It resolves the one link with
+// database lookups, so it does not use the cached resolve maps.
+func (ix *Index) Resolve(fromPath, link string) (*ResolveResult, error) {
+	var result *ResolveResult
+	err := ix.read(func(db *sql.DB, _ *resolverContext) error {
+		var err error
+		result, err = resolveDB(db, ix.vaultPath, fromPath, link)
+		return err
+	})
+	return result, err
+}
+
+// ResolveAll is ResolveAll against the open index, reusing the cached
+// resolve maps.
+func (ix *Index) ResolveAll(sourcePath string) ([]LinkResolution, error) {
+	var result []LinkResolution
+	err := ix.read(func(db *sql.DB, rc *resolverContext) error {
+		var err error
+		result, err = resolveAllDB(db, ix.vaultPath, sourcePath, rc)
+		return err
+	})
+	return result, err
+}
+
+// read runs fn under the shared lock with the current database and its
+// resolver context.
+func (ix *Index) read(fn func(db *sql.DB, rc *resolverContext) error) error {
+	lock, err := rlockIndex(ix.vaultPath)
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	db, rc, err := ix.current()
+	if err != nil {
+		return err
+	}
+	return fn(db, rc)
+}
+
+// current returns the open database and its resolver context, reopening
+// the database when the index file on disk is no longer the one it was
+// opened from.
+func (ix *Index) current() (*sql.DB, *resolverContext, error) {
+	dbp := dbPath(ix.vaultPath)
+	info, err := os.Stat(dbp)
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.db != nil && os.SameFile(ix.info, info) {
+		return ix.db, ix.rc, nil
+	}
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ix.db != nil {
+		// Safe under the shared lock: the replacing build held the
+		// exclusive lock, so no reader is still using the old database.
+		ix.db.Close()
+	}
+	// A rebuilt index starts its version over, so the cache cannot carry over.
+	ix.db, ix.info, ix.rc = db, info, &resolverContext{}
+	return db, ix.rc, nil
+}
+
+// resolverContext caches the resolve maps built from an index. The maps are
+// reused while the index version in meta is unchanged and rebuilt after any
+// mutation has bumped it. Callers must not modify the returned maps.
+type resolverContext struct {
+	mu      sync.Mutex
+	rm      *resolveMaps
+	version int64
+	builds  int // times the maps were built, for tests
+}
+
+// maps returns the resolve maps for db, rebuilding them when the cached ones
+// are stale. An index without a version is never cached.
+func (rc *resolverContext) maps(db dbExecer) (*resolveMaps, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	version, ok, err := indexVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if ok && rc.rm != nil && rc.version == version {
+		return rc.rm, nil
+	}
+	rm, err := buildMapsFromDB(db)
+	if err != nil {
+		return nil, err
+	}
+	rc.builds++
+	rc.rm, rc.version = nil, 0
+	if ok {
+		rc.rm, rc.version = rm, version
+	}
+	return rm, nil
+}
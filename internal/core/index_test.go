@@ -0,0 +1,106 @@
+package core
+
+import (
+	"testing"
+)
+
+func readIndexVersion(t *testing.T, vault string) int64 {
+	t.Helper()
+	db, err := openDBAt(dbPath(vault))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	v, ok, err := indexVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("index has no version")
+	}
+	return v
+}
+
+func TestIndexVersionBumpedByMutation(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "[[B]]\n",
+	})
+	buildVault(t, vault)
+	if v := readIndexVersion(t, vault); v != 1 {
+		t.Fatalf("version after build = %d, want 1", v)
+	}
+
+	writeVaultFiles(t, vault, map[string]string{"B.md": "# B\n"})
+	if _, err := Add(vault, AddOptions{Files: []string{"B.md"}}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if v := readIndexVersion(t, vault); v != 2 {
+		t.Errorf("version after add = %d, want 2", v)
+	}
+
+	// Reads leave the version alone.
+	if _, err := ResolveAll(vault, "A.md"); err != nil {
+		t.Fatalf("resolve all: %v", err)
+	}
+	if v := readIndexVersion(t, vault); v != 2 {
+		t.Errorf("version after resolve = %d, want 2", v)
+	}
+}
+
+func TestIndexResolveAllReusesMaps(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "[[B]]\n",
+	})
+	buildVault(t, vault)
+
+	ix, err := OpenIndex(vault)
+	if err != nil {
+		t.Fatalf("open index: %v", err)
+	}
+	defer ix.Close()
+
+	resolveB := func() string {
+		t.Helper()
+		links, err := ix.ResolveAll("A.md")
+		if err != nil {
+			t.Fatalf("resolve all: %v", err)
+		}
+		if len(links) != 1 {
+			t.Fatalf("links = %+v, want 1", links)
+		}
+		return links[0].Status
+	}
+
+	if got := resolveB(); got != "not_found" {
+		t.Errorf("status before add = %q, want not_found", got)
+	}
+	if got := resolveB(); got != "not_found" {
+		t.Errorf("status on repeat = %q, want not_found", got)
+	}
+	if ix.rc.builds != 1 {
+		t.Errorf("maps built %d times for repeated reads, want 1", ix.rc.builds)
+	}
+
+	writeVaultFiles(t, vault, map[string]string{"B.md": "# B\n"})
+	if _, err := Add(vault, AddOptions{Files: []string{"B.md"}}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if got := resolveB(); got != "ok" {
+		t.Errorf("status after add = %q, want ok", got)
+	}
+	if ix.rc.builds != 2 {
+		t.Errorf("maps built %d times after a mutation, want 2", ix.rc.builds)
+	}
+
+	// A rebuild replaces the index file and restarts the version; the
+	// handle reopens it with an empty cache.
+	buildVault(t, vault)
+	if got := resolveB(); got != "ok" {
+		t.Errorf("status after rebuild = %q, want ok", got)
+	}
+	if ix.rc.builds != 1 {
+		t.Errorf("maps built %d times since rebuild, want 1", ix.rc.builds)
+	}
+}
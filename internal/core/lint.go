@@ -0,0 +1,237 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Lint severities, lowest first.
+const (
+	LintInfo    = "info"
+	LintWarning = "warning"
+	LintError   = "error"
+)
+
+var lintSeverityRank = map[string]int{LintInfo: 0, LintWarning: 1, LintError: 2}
+
+// LintRules lists every lint rule name with its severity.
+var LintRules = map[string]string{
+	"broken-link":        LintError,   // link to a phantom
+	"fragile-link":       LintWarning, // basename link resolved only by root priority
+	"orphan-note":        LintInfo,    // note nobody links to
+	"unreferenced-asset": LintWarning, // asset nobody links to
+	"missing-heading":    LintInfo,    // note without a level-1 heading
+}
+
+// LintOptions selects which rules Lint runs.
+type LintOptions struct {
+	Only    []string // rule names to run; nil/empty = all
+	Exclude []string // rule names to skip
+}
+
+// LintFinding is one vault hygiene issue.
+type LintFinding struct {
+	Rule     string
+	Severity string
+	Path     string
+	Line     int // 0 = the file as a whole
+	Message  string
+}
+
+// LintSeverityAtLeast reports whether sev is at or above min.
+// It returns an error when min is not a known severity.
+func LintSeverityAtLeast(sev, min string) (bool, error) {
+	m, ok := lintSeverityRank[min]
+	if !ok {
+		return false, fmt.Errorf("unknown severity: %s", min)
+	}
+	return lintSeverityRank[sev] >= m, nil
+}
+
+// Lint runs the selected hygiene rules over the index and returns the
+// findings sorted by path, line, and rule.
+func Lint(vaultPath string, opts LintOptions) ([]LintFinding, error) {
+	for _, r := range append(append([]string{}, opts.Only...), opts.Exclude...) {
+		if _, ok := LintRules[r]; !ok {
+			return nil, fmt.Errorf("unknown lint rule: %s", r)
+		}
+	}
+
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	active := func(rule string) bool {
+		for _, r := range opts.Exclude {
+			if r == rule {
+				return false
+			}
+		}
+		if len(opts.Only) == 0 {
+			return true
+		}
+		for _, r := range opts.Only {
+			if r == rule {
+				return true
+			}
+		}
+		return false
+	}
+
+	checks := []struct {
+		rule string
+		run  func(dbExecer) ([]LintFinding, error)
+	}{
+		{"broken-link", lintBrokenLinks},
+		{"fragile-link", lintFragileLinks},
+		{"orphan-note", lintOrphanNotes},
+		{"unreferenced-asset", lintUnreferencedAssets},
+		{"missing-heading", lintMissingHeadings},
+	}
+
+	findings := []LintFinding{}
+	for _, c := range checks {
+		if !active(c.rule) {
+			continue
+		}
+		fs, err := c.run(db)
+		if err != nil {
+			return nil, err
+		}
+		for i := range fs {
+			fs[i].Rule = c.rule
+			fs[i].Severity = LintRules[c.rule]
+		}
+		findings = append(findings, fs...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Rule < b.Rule
+	})
+	return findings, nil
+}
+
+func lintBrokenLinks(db dbExecer) ([]LintFinding, error) {
+	sources, err := queryBrokenLinks(db, nil)
+	if err != nil {
+		return nil, err
+	}
+	var out []LintFinding
+	for _, s := range sources {
+		for _, l := range s.Links {
+			out = append(out, LintFinding{
+				Path:    s.Path,
+				Line:    l.Line,
+				Message: fmt.Sprintf("%s points to missing note %q", l.RawLink, l.Target),
+			})
+		}
+	}
+	return out, nil
+}
+
+// lintFragileLinks finds basename links whose target name is shared by
+// several notes and that resolve only because one of them sits at the vault
+// root. Adding or moving a same-named note can silently change them.
+func lintFragileLinks(db dbExecer) ([]LintFinding, error) {
+	rows, err := db.Query(
+		`SELECT s.path, e.line_start, e.raw_link, e.link_type, t.name, t.path
+		 FROM edges e
+		 JOIN nodes s ON s.id = e.source_id
+		 JOIN nodes t ON t.id = e.target_id
+		 WHERE t.type = 'note' AND t.exists_flag = 1 AND INSTR(t.path, '/') = 0
+		 AND e.link_type IN ('wikilink','markdown')
+		 AND (SELECT COUNT(*) FROM nodes n
+		      WHERE n.type = 'note' AND n.exists_flag = 1 AND LOWER(n.name) = LOWER(t.name)) > 1
+		 ORDER BY s.path, e.line_start, e.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []LintFinding
+	for rows.Next() {
+		var src, rawLink, linkType, name, target string
+		var line int
+		if err := rows.Scan(&src, &line, &rawLink, &linkType, &name, &target); err != nil {
+			return nil, err
+		}
+		if !isBasenameRawLink(rawLink, linkType) || !strings.EqualFold(rawLinkTarget(rawLink, linkType), name) {
+			continue
+		}
+		out = append(out, LintFinding{
+			Path:    src,
+			Line:    line,
+			Message: fmt.Sprintf("%s resolves to %s only by root priority", rawLink, target),
+		})
+	}
+	return out, rows.Err()
+}
+
+// lintOrphanNotes finds notes with no incoming links from other notes.
+func lintOrphanNotes(db dbExecer) ([]LintFinding, error) {
+	return lintPaths(db,
+		`SELECT n.path FROM nodes n
+		 WHERE n.type = 'note' AND n.exists_flag = 1
+		 AND NOT EXISTS (SELECT 1 FROM edges e WHERE e.target_id = n.id AND e.source_id != n.id)
+		 ORDER BY n.path`,
+		"no other note links here")
+}
+
+func lintUnreferencedAssets(db dbExecer) ([]LintFinding, error) {
+	return lintPaths(db,
+		`SELECT n.path FROM nodes n
+		 WHERE n.type = 'asset' AND n.exists_flag = 1
+		 AND NOT EXISTS (SELECT 1 FROM edges e WHERE e.target_id = n.id)
+		 ORDER BY n.path`,
+		"no note links to this asset")
+}
+
+func lintMissingHeadings(db dbExecer) ([]LintFinding, error) {
+	return lintPaths(db,
+		`SELECT n.path FROM nodes n
+		 WHERE n.type = 'note' AND n.exists_flag = 1
+		 AND NOT EXISTS (SELECT 1 FROM headings h WHERE h.node_id = n.id AND h.level = 1)
+		 ORDER BY n.path`,
+		"no level-1 heading")
+}
+
+// lintPaths returns a whole-file finding with message for each path q selects.
+func lintPaths(db dbExecer, q, message string) ([]LintFinding, error) {
+	rows, err := db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []LintFinding
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		out = append(out, LintFinding{Path: path, Message: message})
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// lintFindings runs Lint and returns "rule path:line" for each finding.
+func lintFindings(t *testing.T, vault string, opts LintOptions) []string {
+	t.Helper()
+	findings, err := Lint(vault, opts)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	var out []string
+	for _, f := range findings {
+		out = append(out, fmt.Sprintf("%s %s:%d", f.Rule, f.Path, f.Line))
+	}
+	return out
+}
+
+func lintVault(t *testing.T) string {
+	t.Helper()
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"Index.md":   "# Index\n\n[[Missing]]\n[[Dup]]\n![[img.png]]\n",
+		"Dup.md":     "# Dup\n\n[[Index]]\n",
+		"sub/Dup.md": "no heading\n",
+		"img.png":    "png",
+		"unused.png": "png",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	return vault
+}
+
+func TestLint_Rules(t *testing.T) {
+	vault := lintVault(t)
+
+	got := lintFindings(t, vault, LintOptions{})
+	want := []string{
+		"broken-link Index.md:3",
+		"fragile-link Index.md:4",
+		"missing-heading sub/Dup.md:0",
+		"orphan-note sub/Dup.md:0",
+		"unreferenced-asset unused.png:0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findings = %v, want %v", got, want)
+	}
+
+	findings, err := Lint(vault, LintOptions{Only: []string{"broken-link"}})
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Severity != LintError {
+		t.Errorf("broken-link findings = %+v, want one error", findings)
+	}
+}
+
+func TestLint_OnlyExclude(t *testing.T) {
+	vault := lintVault(t)
+
+	got := lintFindings(t, vault, LintOptions{Only: []string{"orphan-note", "missing-heading"}, Exclude: []string{"missing-heading"}})
+	want := []string{"orphan-note sub/Dup.md:0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findings = %v, want %v", got, want)
+	}
+
+	if _, err := Lint(vault, LintOptions{Only: []string{"nope"}}); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+}
+
+func TestLintSeverityAtLeast(t *testing.T) {
+	tests := []struct {
+		sev, min string
+		want     bool
+	}{
+		{LintError, LintWarning, true},
+		{LintWarning, LintWarning, true},
+		{LintInfo, LintWarning, false},
+		{LintInfo, LintInfo, true},
+	}
+	for _, tt := range tests {
+		got, err := LintSeverityAtLeast(tt.sev, tt.min)
+		if err != nil || got != tt.want {
+			t.Errorf("LintSeverityAtLeast(%s, %s) = %v, %v; want %v", tt.sev, tt.min, got, err, tt.want)
+		}
+	}
+	if _, err := LintSeverityAtLeast(LintError, "fatal"); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
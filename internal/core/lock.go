@@ -0,0 +1,82 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Index access is serialized across processes with an advisory lock on
+// .mdhop/lock: mutating operations hold it exclusively, read-only ones share
+// it. A vault without a .mdhop directory has no index to protect, so locking
+// is a no-op there.
+
+const lockFileName = "lock"
+
+// lockPollInterval is how often a blocked lock attempt is retried.
+const lockPollInterval = 20 * time.Millisecond
+
+var lockTimeout = 10 * time.Second
+
+// errLockBusy is returned by tryLockFile when another holder has the lock.
+var errLockBusy = errors.New("lock busy")
+
+// SetLockTimeout sets how long an operation waits for the index lock before
+// failing. Zero fails immediately when the index is locked.
+func SetLockTimeout(d time.Duration) {
+	lockTimeout = d
+}
+
+// indexLock is a held index lock; release it with unlock.
+type indexLock struct {
+	f *os.File
+}
+
+// lockIndex acquires the exclusive index lock for a mutating operation.
+func lockIndex(vaultPath string) (*indexLock, error) {
+	return acquireIndexLock(vaultPath, true)
+}
+
+// rlockIndex acquires the shared index lock for a read-only operation.
+func rlockIndex(vaultPath string) (*indexLock, error) {
+	return acquireIndexLock(vaultPath, false)
+}
+
+func acquireIndexLock(vaultPath string, exclusive bool) (*indexLock, error) {
+	dir := filepath.Join(vaultPath, dataDirName)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return &indexLock{}, nil
+	}
+	f, err := os.OpenFile(filepath.Join(dir, lockFileName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		err := tryLockFile(f, exclusive)
+		if err == nil {
+			return &indexLock{f: f}, nil
+		}
+		if err != errLockBusy {
+			f.Close()
+			return nil, err
+		}
+		if !time.Now().Before(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("index is locked by another process (waited %s)", lockTimeout)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// unlock releases the lock. It is safe to call on a no-op lock.
+func (l *indexLock) unlock() {
+	if l.f == nil {
+		return
+	}
+	unlockFile(l.f)
+	l.f.Close()
+	l.f = nil
+}
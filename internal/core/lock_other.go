@@ -0,0 +1,14 @@
+//go:build !unix
+
+package core
+
+import "os"
+
+// Advisory file locks are only implemented on unix; elsewhere concurrent
+// mdhop processes are not serialized.
+
+func tryLockFile(f *os.File, exclusive bool) error {
+	return nil
+}
+
+func unlockFile(f *os.File) {}
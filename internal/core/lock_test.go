@@ -0,0 +1,98 @@
+//go:build unix
+
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func setLockTimeoutForTest(t *testing.T, d time.Duration) {
+	t.Helper()
+	prev := lockTimeout
+	SetLockTimeout(d)
+	t.Cleanup(func() { SetLockTimeout(prev) })
+}
+
+func TestIndexLock_ContentionErrors(t *testing.T) {
+	vault := copyVault(t, "vault_build_full")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	setLockTimeoutForTest(t, 50*time.Millisecond)
+
+	held, err := lockIndex(vault)
+	if err != nil {
+		t.Fatalf("lockIndex: %v", err)
+	}
+	defer held.unlock()
+
+	errc := make(chan error, 1)
+	go func() { errc <- Build(vault) }()
+	if err := <-errc; err == nil || !strings.Contains(err.Error(), "index is locked by another process") {
+		t.Errorf("Build while locked: err = %v, want lock error", err)
+	}
+	if _, err := Query(vault, EntrySpec{File: "Index.md"}, QueryOptions{}); err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("Query while exclusively locked: err = %v, want lock error", err)
+	}
+}
+
+func TestIndexLock_SharedReaders(t *testing.T) {
+	vault := copyVault(t, "vault_build_full")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	setLockTimeoutForTest(t, 50*time.Millisecond)
+
+	reader, err := rlockIndex(vault)
+	if err != nil {
+		t.Fatalf("rlockIndex: %v", err)
+	}
+	defer reader.unlock()
+
+	// Another reader proceeds; a writer does not.
+	if _, err := Stats(vault, StatsOptions{}); err != nil {
+		t.Errorf("Stats under shared lock: %v", err)
+	}
+	if _, err := Update(vault, UpdateOptions{Files: []string{"Index.md"}}); err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("Update under shared lock: err = %v, want lock error", err)
+	}
+}
+
+func TestIndexLock_SecondWriterWaits(t *testing.T) {
+	vault := copyVault(t, "vault_build_full")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	setLockTimeoutForTest(t, 5*time.Second)
+
+	held, err := lockIndex(vault)
+	if err != nil {
+		t.Fatalf("lockIndex: %v", err)
+	}
+	const hold = 100 * time.Millisecond
+	go func() {
+		time.Sleep(hold)
+		held.unlock()
+	}()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() { errc <- Build(vault) }()
+	if err := <-errc; err != nil {
+		t.Fatalf("second Build: %v", err)
+	}
+	if waited := time.Since(start); waited < hold/2 {
+		t.Errorf("second Build finished after %s, expected it to wait for the lock", waited)
+	}
+}
+
+func TestIndexLock_NoIndexIsNoop(t *testing.T) {
+	vault := t.TempDir()
+	l, err := lockIndex(vault)
+	if err != nil {
+		t.Fatalf("lockIndex without .mdhop: %v", err)
+	}
+	l.unlock()
+}
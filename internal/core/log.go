@@ -0,0 +1,41 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// LogLevel controls how much progress output core operations write.
+type LogLevel int
+
+const (
+	LogQuiet   LogLevel = -1 // nothing but errors
+	LogNormal  LogLevel = 0  // default: no progress output
+	LogVerbose LogLevel = 1  // one line per pass / DB step
+)
+
+var (
+	logLevel           = LogNormal
+	logOut   io.Writer = os.Stderr
+)
+
+// SetLogLevel sets the level for progress output. It is meant to be called
+// once at startup (the CLI parses --quiet/--verbose in main).
+func SetLogLevel(level LogLevel) {
+	logLevel = level
+}
+
+// SetLogOutput redirects progress output (stderr by default). Errors are
+// returned to the caller, never logged.
+func SetLogOutput(w io.Writer) {
+	logOut = w
+}
+
+// verbosef writes one progress line when the level is LogVerbose.
+func verbosef(format string, args ...any) {
+	if logLevel < LogVerbose {
+		return
+	}
+	fmt.Fprintf(logOut, format+"\n", args...)
+}
@@ -5,13 +5,33 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 // MoveOptions controls the move operation.
 type MoveOptions struct {
 	From string // vault-relative old path
 	To   string // vault-relative new path
+	// Force skips the moved file's stale check. The moved file and every
+	// source whose links get rewritten are reparsed from their current disk
+	// content instead of trusting the index, trading safety for convenience.
+	Force bool
+	// KeepBackup saves the original content of every rewritten file as
+	// "<path>.bak" after a successful move (see CleanBackups). In MoveBatch,
+	// any move setting it keeps backups for the whole batch.
+	KeepBackup bool
+	// PreserveMtime restores the original modification time of every file
+	// whose links get rewritten, the moved file included, and records it in
+	// the index, so mtime-sorted views and sync tools do not see a link fix
+	// as an edit. In MoveBatch, any move setting it applies to the whole
+	// batch. Also enabled by rewrite.preserve_mtime in mdhop.yaml.
+	PreserveMtime bool
+	// UpdateOnly catches the index up with a rename already done outside
+	// mdhop: it requires From to be gone and To to exist on disk, and never
+	// moves files itself. Links are still rewritten as in a normal move.
+	UpdateOnly bool
 }
 
 // MoveResult reports the outcome of the move operation.
@@ -29,9 +49,19 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
 	}
 
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
 	from := NormalizePath(opts.From)
 	to := NormalizePath(opts.To)
 
+	if pathEscapesVault(to) {
+		return nil, fmt.Errorf("destination escapes vault: %s", to)
+	}
+
 	if from == to {
 		return nil, fmt.Errorf("source and destination are the same: %s", from)
 	}
@@ -41,6 +71,15 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 		return nil, err
 	}
 	defer db.Close()
+	if err := ensureEdgeCol(db); err != nil {
+		return nil, err
+	}
+
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	preserveMtime := opts.PreserveMtime || cfg.Rewrite.PreserveMtime
 
 	// Check from is registered as a note or asset in DB.
 	var nodeID int64
@@ -82,6 +121,14 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 	// Determine disk state: from present, to present.
 	fromOnDisk := fileExists(filepath.Join(vaultPath, from))
 	toOnDisk := fileExists(filepath.Join(vaultPath, to))
+	if opts.UpdateOnly {
+		if fromOnDisk {
+			return nil, fmt.Errorf("source file still exists on disk: %s (update-only requires it to be moved already)", from)
+		}
+		if !toOnDisk {
+			return nil, fmt.Errorf("destination file not found on disk: %s", to)
+		}
+	}
 
 	// Determine whether we need to do the disk move.
 	var needDiskMove bool
@@ -98,13 +145,13 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 		return nil, fmt.Errorf("source file not found on disk: %s", from)
 	}
 
-	// Stale check for the moved file.
+	// Stale check for the moved file (skipped with Force).
 	if needDiskMove {
 		info, err := os.Stat(filepath.Join(vaultPath, from))
 		if err != nil {
 			return nil, err
 		}
-		if info.ModTime().Unix() != dbMtime {
+		if !opts.Force && info.ModTime().Unix() != dbMtime {
 			return nil, fmt.Errorf("source file is stale: %s", from)
 		}
 	} else {
@@ -114,7 +161,7 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 		if err != nil {
 			return nil, err
 		}
-		if info.ModTime().Unix() != dbMtime {
+		if !opts.Force && info.ModTime().Unix() != dbMtime {
 			return nil, fmt.Errorf("moved file is stale: %s", to)
 		}
 	}
@@ -124,6 +171,17 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	rm.applyBuildConfig(cfg.Build)
+
+	// With Force, links added since the last build have no edge to look up;
+	// keep the pre-move maps to resolve them.
+	var preRM *resolveMaps
+	if opts.Force {
+		if preRM, err = buildMapsFromDB(db); err != nil {
+			return nil, err
+		}
+		preRM.applyBuildConfig(cfg.Build)
+	}
 
 	// Save pre-move pathSet for Phase 2/2.5 root-priority checks.
 	var preMovePathSet map[string]string
@@ -185,6 +243,7 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 		if isRootFile(to) {
 			rm.rootBasenameToPath[basenameKey(to)] = to
 		}
+		renameNoteAliases(rm, from, to)
 
 		// Rebuild basenameToPath (count == 1 only).
 		rm.basenameToPath = make(map[string]string)
@@ -198,9 +257,9 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 
 	// Phase 2: incoming link rewrite.
 	incomingRows, err := db.Query(
-		`SELECT e.id, e.raw_link, e.link_type, e.line_start, sn.path, sn.id
+		`SELECT e.id, e.raw_link, e.link_type, e.line_start, COALESCE(e.col, -1), sn.path, sn.id
 		 FROM edges e JOIN nodes sn ON sn.id = e.source_id AND sn.exists_flag = 1
-		 WHERE e.target_id = ? AND e.link_type IN ('wikilink', 'markdown')`, nodeID)
+		 WHERE e.target_id = ? AND e.link_type IN ('wikilink', 'markdown', 'frontmatter-link')`, nodeID)
 	if err != nil {
 		return nil, err
 	}
@@ -227,7 +286,7 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 
 	for incomingRows.Next() {
 		var re rewriteEntry
-		if err := incomingRows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.sourcePath, &re.sourceID); err != nil {
+		if err := incomingRows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.col, &re.sourcePath, &re.sourceID); err != nil {
 			incomingRows.Close()
 			return nil, err
 		}
@@ -236,6 +295,9 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 			continue
 		}
 		if isBasenameRawLink(re.rawLink, re.linkType) {
+			if !isAsset && strings.ToLower(rawLinkTarget(re.rawLink, re.linkType)) != moveBKFrom {
+				continue // alias link: the alias moves with the note
+			}
 			// Basename link: determine if rewrite is needed.
 			if moveBKFrom != moveBKTo {
 				// Basename changed → must rewrite.
@@ -243,9 +305,11 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 				incomingRewrites = append(incomingRewrites, re)
 			} else if moveBasenameCounts[moveBKTo] > 1 {
 				// Basename unchanged but ambiguous after move.
-				preRoot := hasRootInPathSet(moveBKTo, preMovePathSet)
-				postRoot := hasRootInPathSet(moveBKTo, movePathSet)
-				if !(preRoot && postRoot) {
+				preRoot := rm.rootWins(moveBKTo, preMovePathSet)
+				postRoot := rm.rootWins(moveBKTo, movePathSet)
+				// Under folder-first the link may have named the moved note
+				// from its own folder rather than through the root.
+				if !(preRoot && postRoot) || (rm.folderFirst && !isAsset) {
 					re.newRawLink = rewriteRawLink(re.rawLink, re.linkType, to)
 					incomingRewrites = append(incomingRewrites, re)
 				}
@@ -265,8 +329,8 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 	// Phase 2.5: collateral rewrite for the destination basename.
 	var collateralRewrites []rewriteEntry
 	if moveBasenameCounts[moveBKTo] > 1 {
-		preRoot := hasRootInPathSet(moveBKTo, preMovePathSet)
-		postRoot := hasRootInPathSet(moveBKTo, movePathSet)
+		preRoot := rm.rootWins(moveBKTo, preMovePathSet)
+		postRoot := rm.rootWins(moveBKTo, movePathSet)
 		if !(preRoot && postRoot) {
 			// Query target type matching the moved node.
 			targetType := "note"
@@ -276,11 +340,11 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 				collateralName = filepath.Base(to)
 			}
 			rows, err := db.Query(
-				`SELECT e.id, e.raw_link, e.link_type, e.line_start, sn.path, sn.id, tn.path, tn.id
+				`SELECT e.id, e.raw_link, e.link_type, e.line_start, COALESCE(e.col, -1), sn.path, sn.id, tn.path, tn.id
 				 FROM edges e
 				 JOIN nodes sn ON sn.id = e.source_id AND sn.exists_flag = 1
 				 JOIN nodes tn ON tn.id = e.target_id AND tn.type = ? AND tn.exists_flag = 1
-				 WHERE tn.name = ? AND e.link_type IN ('wikilink', 'markdown')`,
+				 WHERE tn.name = ? AND e.link_type IN ('wikilink', 'markdown', 'frontmatter-link')`,
 				targetType, collateralName)
 			if err != nil {
 				return nil, err
@@ -289,7 +353,7 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 				var re rewriteEntry
 				var targetPath string
 				var targetNodeID int64
-				if err := rows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.sourcePath, &re.sourceID, &targetPath, &targetNodeID); err != nil {
+				if err := rows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.col, &re.sourcePath, &re.sourceID, &targetPath, &targetNodeID); err != nil {
 					rows.Close()
 					return nil, err
 				}
@@ -315,16 +379,26 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 	allExternalRewrites := make([]rewriteEntry, 0, len(incomingRewrites)+len(collateralRewrites))
 	allExternalRewrites = append(allExternalRewrites, incomingRewrites...)
 	allExternalRewrites = append(allExternalRewrites, collateralRewrites...)
+	if rm.noRootPriority {
+		if err := checkRootRewrites(allExternalRewrites); err != nil {
+			return nil, err
+		}
+	}
+	if rm.folderFirst && !isAsset {
+		captured, err := folderCapturedLink(db, []string{to}, map[string]bool{from: true})
+		if err != nil {
+			return nil, err
+		}
+		if captured != nil {
+			return nil, fmt.Errorf("move would change the target of existing link: %s in %s", captured.rawLink, captured.sourcePath)
+		}
+	}
 
 	// Phase 3: outgoing link rewrite (only for notes; assets have no outgoing links).
-	type outgoingRewrite struct {
-		rawLink    string
-		newRawLink string
-		lineStart  int
-	}
 	var outgoingRewrites []outgoingRewrite
 	var movedContent []byte
 	var movedPerm os.FileMode
+	var movedMtime time.Time
 	var movedFilePath string
 
 	if !isAsset {
@@ -339,14 +413,15 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 			return nil, err
 		}
 		movedPerm = movedInfo.Mode().Perm()
+		movedMtime = movedInfo.ModTime()
 		movedContent, err = os.ReadFile(movedFilePath)
 		if err != nil {
 			return nil, err
 		}
-		outgoingLinks := parseLinks(string(movedContent))
+		outgoingLinks := parseIndexLinks(string(movedContent), cfg.Build)
 
 		for _, link := range outgoingLinks {
-			if link.linkType != "wikilink" && link.linkType != "markdown" {
+			if !isFileLinkType(link.linkType) {
 				continue
 			}
 			// Basename link: check if resolution changes after move.
@@ -359,19 +434,26 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 				err := db.QueryRow(
 					`SELECT COALESCE(tn.path, '') FROM edges e
 					 JOIN nodes tn ON tn.id = e.target_id
-					 WHERE e.source_id = ? AND e.raw_link = ? AND e.link_type IN ('wikilink', 'markdown')
+					 WHERE e.source_id = ? AND e.raw_link = ? AND e.link_type IN ('wikilink', 'markdown', 'frontmatter-link')
 					 LIMIT 1`, nodeID, link.rawLink).Scan(&preMoveTargetPath)
 				if err != nil && err != sql.ErrNoRows {
 					return nil, err
 				}
+				if preMoveTargetPath == "" && preRM != nil {
+					preMoveTargetPath = forcedPreMoveTarget(preRM, from, link)
+				}
 
 				if preMoveTargetPath != "" {
 					// Determine post-move resolution.
-					if p, ok := rm.basenameToPath[bk]; ok {
+					if p, ok := rm.localNotePath(to, bk); ok {
 						if p != preMoveTargetPath {
 							needRewrite = true
 						}
-					} else if p, ok := rm.rootBasenameToPath[bk]; ok {
+					} else if p, ok := rm.basenameToPath[bk]; ok {
+						if p != preMoveTargetPath {
+							needRewrite = true
+						}
+					} else if p, ok := rm.rootPath(bk); ok {
 						if p != preMoveTargetPath {
 							needRewrite = true
 						}
@@ -386,6 +468,8 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 						rawLink:    link.rawLink,
 						newRawLink: newRL,
 						lineStart:  link.lineStart,
+						linkType:   link.linkType,
+						col:        link.col,
 					})
 				}
 				continue
@@ -401,6 +485,8 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 						rawLink:    link.rawLink,
 						newRawLink: newRL,
 						lineStart:  link.lineStart,
+						linkType:   link.linkType,
+						col:        link.col,
 					})
 				}
 			}
@@ -418,8 +504,13 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 		for _, re := range allExternalRewrites {
 			groups[re.sourcePath] = append(groups[re.sourcePath], re)
 		}
+		if opts.Force {
+			if allExternalRewrites, err = relocateRewrites(vaultPath, groups, cfg.Build); err != nil {
+				return nil, err
+			}
+		}
 		var applyErr error
-		externalMtimes, externalBackups, applyErr = applyFileRewrites(vaultPath, groups)
+		externalMtimes, externalBackups, applyErr = applyFileRewrites(vaultPath, groups, preserveMtime)
 		if applyErr != nil {
 			return nil, applyErr
 		}
@@ -444,9 +535,11 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 				continue
 			}
 			idx := lineNum - 1
-			for _, ow := range ows {
-				lines[idx] = replaceOutsideInlineCode(lines[idx], ow.rawLink, ow.newRawLink)
+			reps := make([]linkReplacement, len(ows))
+			for j, ow := range ows {
+				reps[j] = linkReplacement{ow.linkType, ow.rawLink, ow.newRawLink, ow.col}
 			}
+			lines[idx] = replaceRawLinks(lines[idx], reps)
 		}
 		movedContent = []byte(strings.Join(lines, "\n"))
 
@@ -455,6 +548,13 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 			restoreBackups(vaultPath, externalBackups)
 			return nil, err
 		}
+		if preserveMtime {
+			if err := restoreMtime(movedFilePath, movedMtime); err != nil {
+				_ = writeFilePreservePerm(movedFilePath, movedFileBackup.content, movedFileBackup.perm)
+				restoreBackups(vaultPath, externalBackups)
+				return nil, err
+			}
+		}
 	}
 
 	// 4.3: disk move (if needed).
@@ -523,6 +623,10 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 			restoreBackups(vaultPath, externalBackups)
 		}
 	}()
+	nt, err := newNoteText(tx)
+	if err != nil {
+		return nil, err
+	}
 
 	// 5.1: update node for the moved file.
 	var newName string
@@ -544,7 +648,10 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 		}
 
 		// 5.3: re-parse moved file content and create new edges (using new path).
-		newLinks := parseLinks(string(movedContent))
+		if err := nt.replace(tx, nodeID, string(movedContent)); err != nil {
+			return nil, err
+		}
+		newLinks := parseIndexLinks(string(movedContent), cfg.Build)
 		for _, link := range newLinks {
 			targetID, subpath, err := resolveLink(tx, to, link, rm)
 			if err != nil {
@@ -553,23 +660,35 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 			if targetID == 0 {
 				continue
 			}
-			if err := insertEdge(tx, nodeID, targetID, link.linkType, link.rawLink, subpath, link.lineStart, link.lineEnd); err != nil {
+			if err := insertEdge(tx, nodeID, targetID, link, subpath); err != nil {
 				return nil, err
 			}
 		}
 	}
 
 	// 5.4: update incoming + collateral edge raw_links.
-	for _, re := range allExternalRewrites {
-		if _, err := tx.Exec("UPDATE edges SET raw_link = ? WHERE id = ?", re.newRawLink, re.edgeID); err != nil {
+	if opts.Force {
+		if err := reindexRewrittenSources(tx, vaultPath, allExternalRewrites, rm, cfg.Build); err != nil {
 			return nil, err
 		}
+	}
+	for _, re := range allExternalRewrites {
+		if !opts.Force {
+			if _, err := tx.Exec("UPDATE edges SET raw_link = ? WHERE id = ?", re.newRawLink, re.edgeID); err != nil {
+				return nil, err
+			}
+		}
 		result.Rewritten = append(result.Rewritten, RewrittenLink{
 			File:    re.sourcePath,
 			OldLink: re.rawLink,
 			NewLink: re.newRawLink,
 		})
 	}
+	if !opts.Force {
+		if err := shiftEdgeCols(tx, allExternalRewrites); err != nil {
+			return nil, err
+		}
+	}
 
 	// 5.5: update source file mtimes for all externally rewritten files.
 	if externalMtimes != nil {
@@ -583,6 +702,9 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 			if _, err := tx.Exec("UPDATE nodes SET mtime = ? WHERE id = ? AND type = 'note'", mt, re.sourceID); err != nil {
 				return nil, err
 			}
+			if err := nt.refresh(tx, vaultPath, re.sourceID, re.sourcePath); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -620,24 +742,64 @@ func Move(vaultPath string, opts MoveOptions) (*MoveResult, error) {
 	if err := cleanupOrphanedNodes(tx); err != nil {
 		return nil, err
 	}
+	if err := bumpIndexVersion(tx); err != nil {
+		return nil, err
+	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 	committed = true
+	verbosef("move: committed %s -> %s, %d links rewritten", from, to, len(result.Rewritten))
+
+	if opts.KeepBackup {
+		backups := externalBackups
+		if movedFileBackup != nil {
+			backups = append(backups, rewriteBackup{path: to, content: movedFileBackup.content, perm: movedFileBackup.perm})
+		}
+		if err := keepBackups(vaultPath, backups); err != nil {
+			return nil, err
+		}
+	}
 
 	return result, nil
 }
 
+// dirDepth returns how deep path lies below dir: 1 for a file directly in dir,
+// 2 for a file in a direct subdirectory, and so on.
+func dirDepth(path, dir string) int {
+	return strings.Count(strings.TrimPrefix(path, dir+"/"), "/") + 1
+}
+
+// filterDirDepth keeps the paths at most maxDepth levels below dir.
+func filterDirDepth(paths []string, dir string, maxDepth int) []string {
+	var out []string
+	for _, p := range paths {
+		if dirDepth(p, dir) <= maxDepth {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// outgoingRewrite is a rewrite of a link in a moved file itself.
+type outgoingRewrite struct {
+	rawLink    string
+	newRawLink string
+	lineStart  int
+	linkType   string
+	col        int
+}
+
 // queryCollateralRewrites finds basename links to non-moved nodes of the given type
 // that need rewriting due to root-priority changes.
 func queryCollateralRewrites(db dbExecer, nodeType, name string, movedNodeIDs map[int64]bool) ([]rewriteEntry, error) {
 	rows, err := db.Query(
-		`SELECT e.id, e.raw_link, e.link_type, e.line_start, sn.path, sn.id, tn.path, tn.id
+		`SELECT e.id, e.raw_link, e.link_type, e.line_start, COALESCE(e.col, -1), sn.path, sn.id, tn.path, tn.id
 		 FROM edges e
 		 JOIN nodes sn ON sn.id = e.source_id AND sn.exists_flag = 1
 		 JOIN nodes tn ON tn.id = e.target_id AND tn.type = ? AND tn.exists_flag = 1
-		 WHERE tn.name = ? AND e.link_type IN ('wikilink', 'markdown')`,
+		 WHERE tn.name = ? AND e.link_type IN ('wikilink', 'markdown', 'frontmatter-link')`,
 		nodeType, name)
 	if err != nil {
 		return nil, err
@@ -649,7 +811,7 @@ func queryCollateralRewrites(db dbExecer, nodeType, name string, movedNodeIDs ma
 		var re rewriteEntry
 		var targetPath string
 		var targetNodeID int64
-		if err := rows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.sourcePath, &re.sourceID, &targetPath, &targetNodeID); err != nil {
+		if err := rows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.col, &re.sourcePath, &re.sourceID, &targetPath, &targetNodeID); err != nil {
 			return nil, err
 		}
 		if movedNodeIDs[re.sourceID] {
@@ -669,14 +831,26 @@ func queryCollateralRewrites(db dbExecer, nodeType, name string, movedNodeIDs ma
 
 // MoveDirOptions controls the directory move operation.
 type MoveDirOptions struct {
-	FromDir string // vault-relative directory prefix (e.g., "sub")
-	ToDir   string // vault-relative directory prefix (e.g., "newdir")
+	FromDir  string // vault-relative directory prefix (e.g., "sub")
+	ToDir    string // vault-relative directory prefix (e.g., "newdir")
+	MaxDepth int    // 0 = unlimited; 1 = only files directly under FromDir, 2 = one subdirectory deeper, ...
+	// PruneEmpty removes FromDir (and emptied subdirectories and ancestors)
+	// after a successful move when only hidden files such as .DS_Store remain.
+	PruneEmpty bool
+	Force      bool // as MoveOptions.Force, for every moved file
+	KeepBackup    bool // as MoveOptions.KeepBackup
+	PreserveMtime bool // as MoveOptions.PreserveMtime
+	// RenameOnConflict moves a file whose destination is already taken to
+	// the first free numeric suffix (A.md → A-1.md) instead of failing.
+	// Links to a renamed note are rewritten like any basename change.
+	RenameOnConflict bool
 }
 
 // MoveDirResult reports the outcome of the directory move operation.
 type MoveDirResult struct {
 	Moved     []MovedFile
 	Rewritten []RewrittenLink
+	Renamed   []MovedFile // moves given a suffixed destination (RenameOnConflict); also in Moved
 }
 
 // MovedFile records a single file move within a directory move.
@@ -694,6 +868,12 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
 	}
 
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
 	fromDir := NormalizePath(opts.FromDir)
 	toDir := NormalizePath(opts.ToDir)
 
@@ -717,6 +897,10 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		return nil, fmt.Errorf("source and destination are the same: %s", fromDir)
 	}
 
+	if opts.MaxDepth < 0 {
+		return nil, fmt.Errorf("max depth must be >= 0")
+	}
+
 	// Overlap check.
 	if strings.HasPrefix(toDir+"/", fromDir+"/") || strings.HasPrefix(fromDir+"/", toDir+"/") {
 		return nil, fmt.Errorf("source and destination directories overlap")
@@ -727,6 +911,14 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		return nil, err
 	}
 	defer db.Close()
+	if err := ensureEdgeCol(db); err != nil {
+		return nil, err
+	}
+
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get all notes under fromDir.
 	fromNotePaths, err := listDirNodesByType(db, fromDir, "note")
@@ -744,15 +936,17 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		return nil, fmt.Errorf("no files registered under directory: %s", fromDir)
 	}
 
+	// Leave files nested deeper than MaxDepth in place.
+	if opts.MaxDepth > 0 {
+		fromNotePaths = filterDirDepth(fromNotePaths, fromDir, opts.MaxDepth)
+		fromAssetPaths = filterDirDepth(fromAssetPaths, fromDir, opts.MaxDepth)
+		if len(fromNotePaths) == 0 && len(fromAssetPaths) == 0 {
+			return nil, fmt.Errorf("no files registered within depth %d under directory: %s", opts.MaxDepth, fromDir)
+		}
+	}
+
 	// Build move list for notes.
-	type moveInfo struct {
-		from    string
-		to      string
-		nodeID  int64
-		dbMtime int64
-		isAsset bool
-	}
-	moves := make([]moveInfo, 0, len(fromNotePaths)+len(fromAssetPaths))
+	moves := make([]batchMove, 0, len(fromNotePaths)+len(fromAssetPaths))
 	for _, from := range fromNotePaths {
 		to := toDir + "/" + strings.TrimPrefix(from, fromDir+"/")
 		var nodeID, dbMtime int64
@@ -763,7 +957,7 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		if err != nil {
 			return nil, err
 		}
-		moves = append(moves, moveInfo{from: from, to: to, nodeID: nodeID, dbMtime: dbMtime})
+		moves = append(moves, batchMove{from: from, to: to, nodeID: nodeID, dbMtime: dbMtime, force: opts.Force})
 	}
 
 	// Build move list for assets.
@@ -777,29 +971,11 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		if err != nil {
 			return nil, err
 		}
-		moves = append(moves, moveInfo{from: from, to: to, nodeID: nodeID, dbMtime: dbMtime, isAsset: true})
-	}
-
-	// Check destinations not registered.
-	for _, m := range moves {
-		var toKey string
-		if m.isAsset {
-			toKey = assetKey(m.to)
-		} else {
-			toKey = noteKey(m.to)
-		}
-		var existingID int64
-		err := db.QueryRow("SELECT id FROM nodes WHERE node_key = ?", toKey).Scan(&existingID)
-		if err == nil {
-			return nil, fmt.Errorf("destination already registered: %s", m.to)
-		}
-		if err != sql.ErrNoRows {
-			return nil, err
-		}
+		moves = append(moves, batchMove{from: from, to: to, nodeID: nodeID, dbMtime: dbMtime, isAsset: true, force: opts.Force})
 	}
 
 	// Collect non-registered disk files under fromDir for disk-only move.
-	var diskOnlyFiles []struct{ from, to string }
+	var diskOnlyFiles []pathMove
 	absDir := filepath.Join(vaultPath, fromDir)
 	registeredPaths := make(map[string]bool)
 	for _, m := range moves {
@@ -827,20 +1003,282 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		}
 		rel, _ := filepath.Rel(vaultPath, path)
 		relNorm := NormalizePath(rel)
+		if opts.MaxDepth > 0 && dirDepth(relNorm, fromDir) > opts.MaxDepth {
+			return nil
+		}
 		if !registeredPaths[relNorm] {
 			to := toDir + "/" + strings.TrimPrefix(relNorm, fromDir+"/")
-			diskOnlyFiles = append(diskOnlyFiles, struct{ from, to string }{relNorm, to})
+			diskOnlyFiles = append(diskOnlyFiles, pathMove{relNorm, to})
 		}
 		return nil
 	}); err != nil {
 		return nil, err
 	}
 
+	var renamed []MovedFile
+	if opts.RenameOnConflict {
+		renamed, err = suffixConflictingMoves(vaultPath, db, moves, diskOnlyFiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := moveFiles(vaultPath, db, cfg, moves, diskOnlyFiles, opts.KeepBackup, opts.PreserveMtime || cfg.Rewrite.PreserveMtime, nil)
+	if err != nil {
+		return nil, err
+	}
+	result.Renamed = renamed
+	if opts.PruneEmpty {
+		pruneEmptyDirs(vaultPath, fromDir)
+	}
+	return result, nil
+}
+
+// suffixConflictingMoves gives every move whose destination is taken (a
+// registered file, or a file on disk while the source is still in place)
+// the first free numeric suffix, updating moves and diskOnly in place. A
+// suffix is free when it is neither registered, on disk, nor another move's
+// destination. It returns the renamed moves with their new destinations.
+func suffixConflictingMoves(vaultPath string, db dbExecer, moves []batchMove, diskOnly []pathMove) ([]MovedFile, error) {
+	planned := make(map[string]bool, len(moves)+len(diskOnly))
+	for _, m := range moves {
+		planned[strings.ToLower(m.to)] = true
+	}
+	for _, df := range diskOnly {
+		planned[strings.ToLower(df.to)] = true
+	}
+	registered := func(p string) (bool, error) {
+		key := assetKey(p)
+		if strings.HasSuffix(strings.ToLower(p), ".md") {
+			key = noteKey(p)
+		}
+		var id int64
+		err := db.QueryRow("SELECT id FROM nodes WHERE node_key = ?", key).Scan(&id)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	// suffixed returns to unchanged when it is free, else the first free
+	// suffixed path.
+	suffixed := func(from, to string) (string, error) {
+		taken, err := registered(to)
+		if err != nil {
+			return "", err
+		}
+		if !taken && !(fileExists(filepath.Join(vaultPath, from)) && fileExists(filepath.Join(vaultPath, to))) {
+			return to, nil
+		}
+		ext := filepath.Ext(to)
+		stem := strings.TrimSuffix(to, ext)
+		for n := 1; ; n++ {
+			cand := fmt.Sprintf("%s-%d%s", stem, n, ext)
+			if planned[strings.ToLower(cand)] || fileExists(filepath.Join(vaultPath, cand)) {
+				continue
+			}
+			taken, err := registered(cand)
+			if err != nil {
+				return "", err
+			}
+			if !taken {
+				planned[strings.ToLower(cand)] = true
+				return cand, nil
+			}
+		}
+	}
+
+	var renamed []MovedFile
+	for i := range moves {
+		to, err := suffixed(moves[i].from, moves[i].to)
+		if err != nil {
+			return nil, err
+		}
+		if to != moves[i].to {
+			moves[i].to = to
+			renamed = append(renamed, MovedFile{From: moves[i].from, To: to})
+		}
+	}
+	for i := range diskOnly {
+		to, err := suffixed(diskOnly[i].from, diskOnly[i].to)
+		if err != nil {
+			return nil, err
+		}
+		if to != diskOnly[i].to {
+			diskOnly[i].to = to
+			renamed = append(renamed, MovedFile{From: diskOnly[i].from, To: to})
+		}
+	}
+	return renamed, nil
+}
+
+// MoveBatchResult reports the outcome of a batch move.
+type MoveBatchResult struct {
+	Moved     []MovedFile
+	Rewritten []RewrittenLink
+}
+
+// MoveBatch moves several files in one operation. All moves are validated
+// before anything is touched, links are rewritten against the combined
+// post-move state, and the index is updated in a single transaction; on any
+// error the disk is rolled back. Files may trade places (A→B with B→A), but
+// two moves may not share a source or a destination.
+func MoveBatch(vaultPath string, opts []MoveOptions) (*MoveBatchResult, error) {
+	// Phase 0: validation.
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+	if len(opts) == 0 {
+		return nil, fmt.Errorf("no moves given")
+	}
+
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	seenFrom := make(map[string]bool, len(opts))
+	seenTo := make(map[string]bool, len(opts))
+	for _, o := range opts {
+		from := NormalizePath(o.From)
+		to := NormalizePath(o.To)
+		if pathEscapesVault(to) {
+			return nil, fmt.Errorf("destination escapes vault: %s", to)
+		}
+		if from == to {
+			return nil, fmt.Errorf("source and destination are the same: %s", from)
+		}
+		if seenFrom[from] {
+			return nil, fmt.Errorf("source listed more than once: %s", from)
+		}
+		if seenTo[to] {
+			return nil, fmt.Errorf("destinations collide: %s", to)
+		}
+		seenFrom[from] = true
+		seenTo[to] = true
+	}
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := ensureEdgeCol(db); err != nil {
+		return nil, err
+	}
+
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+
+	keepBackup := false
+	preserveMtime := cfg.Rewrite.PreserveMtime
+	moves := make([]batchMove, 0, len(opts))
+	for _, o := range opts {
+		keepBackup = keepBackup || o.KeepBackup
+		preserveMtime = preserveMtime || o.PreserveMtime
+		m := batchMove{from: NormalizePath(o.From), to: NormalizePath(o.To), force: o.Force, updateOnly: o.UpdateOnly}
+		err := db.QueryRow("SELECT id, mtime FROM nodes WHERE node_key = ? AND type = 'note'", noteKey(m.from)).Scan(&m.nodeID, &m.dbMtime)
+		if err == sql.ErrNoRows {
+			err = db.QueryRow("SELECT id, mtime FROM nodes WHERE node_key = ? AND type = 'asset'", assetKey(m.from)).Scan(&m.nodeID, &m.dbMtime)
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("file not registered: %s", m.from)
+			}
+			m.isAsset = true
+		}
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, m)
+	}
+
+	result, err := moveFiles(vaultPath, db, cfg, moves, nil, keepBackup, preserveMtime, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &MoveBatchResult{Moved: result.Moved, Rewritten: result.Rewritten}, nil
+}
+
+// movingTmpSuffix is appended to a file's name while a chained batch move
+// (e.g. a swap) renames it.
+const movingTmpSuffix = ".mdhop-moving"
+
+// batchMove is one file of a multi-file move.
+type batchMove struct {
+	from    string
+	to      string
+	nodeID  int64
+	dbMtime int64
+	isAsset bool
+	force   bool // skip the stale check (MoveOptions.Force)
+	// updateOnly requires the file to be at to already (MoveOptions.UpdateOnly).
+	updateOnly bool
+}
+
+// pathMove is an unregistered file moved along with a directory.
+type pathMove struct {
+	from, to string
+}
+
+// moveFiles moves registered files (and unregistered diskOnly files) in one
+// batch: every rewrite is computed against a single map snapshot adjusted for
+// all moves, and the index is updated in one transaction. On error, disk
+// changes are rolled back. Moves may swap or rotate paths among themselves.
+// With keepBackup, rewritten files are backed up once the batch succeeds.
+// With preserveMtime, rewritten files keep their original mtime.
+// beforeCommit, if set, runs in the index transaction after the moves are
+// applied; an error from it rolls back the whole batch.
+func moveFiles(vaultPath string, db *sql.DB, cfg Config, moves []batchMove, diskOnlyFiles []pathMove, keepBackup, preserveMtime bool, beforeCommit func(tx *sql.Tx, result *MoveDirResult) error) (*MoveDirResult, error) {
+	force := false
+	movingFrom := make(map[string]bool, len(moves))
+	for _, m := range moves {
+		force = force || m.force
+		movingFrom[m.from] = true
+	}
+	// A chained batch moves some file onto a path another one vacates, so
+	// renames go through temporary names.
+	chained := false
+	for _, m := range moves {
+		chained = chained || movingFrom[m.to]
+	}
+
+	// Check destinations not registered. A destination that another move
+	// in the batch vacates (a swap or rotation) is free.
+	for _, m := range moves {
+		if movingFrom[m.to] {
+			continue
+		}
+		var toKey string
+		if m.isAsset {
+			toKey = assetKey(m.to)
+		} else {
+			toKey = noteKey(m.to)
+		}
+		var existingID int64
+		err := db.QueryRow("SELECT id FROM nodes WHERE node_key = ?", toKey).Scan(&existingID)
+		if err == nil {
+			return nil, fmt.Errorf("destination already registered: %s", m.to)
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+
 	// Determine disk state.
 	var normalMode, alreadyMovedMode bool
 	for _, m := range moves {
 		fromOnDisk := fileExists(filepath.Join(vaultPath, m.from))
-		toOnDisk := fileExists(filepath.Join(vaultPath, m.to))
+		toOnDisk := !movingFrom[m.to] && fileExists(filepath.Join(vaultPath, m.to))
+		if m.updateOnly {
+			if fromOnDisk {
+				return nil, fmt.Errorf("source file still exists on disk: %s (update-only requires it to be moved already)", m.from)
+			}
+			if !toOnDisk {
+				return nil, fmt.Errorf("destination file not found on disk: %s", m.to)
+			}
+		}
 		switch {
 		case fromOnDisk && !toOnDisk:
 			normalMode = true
@@ -853,7 +1291,7 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		}
 	}
 	if normalMode && alreadyMovedMode {
-		return nil, fmt.Errorf("inconsistent disk state for directory move")
+		return nil, fmt.Errorf("inconsistent disk state for move")
 	}
 	needDiskMove := normalMode
 
@@ -869,7 +1307,7 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		if err != nil {
 			return nil, err
 		}
-		if info.ModTime().Unix() != m.dbMtime {
+		if !m.force && info.ModTime().Unix() != m.dbMtime {
 			if needDiskMove {
 				return nil, fmt.Errorf("source file is stale: %s", m.from)
 			}
@@ -882,6 +1320,15 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	rm.applyBuildConfig(cfg.Build)
+
+	var preRM *resolveMaps
+	if force {
+		if preRM, err = buildMapsFromDB(db); err != nil {
+			return nil, err
+		}
+		preRM.applyBuildConfig(cfg.Build)
+	}
 
 	preMovePathSet := make(map[string]string, len(rm.pathSet))
 	for k, v := range rm.pathSet {
@@ -900,7 +1347,15 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		movedNodeIDs[m.nodeID] = true
 	}
 
-	// Remove all from paths, add all to paths.
+	// Remove all from paths, add all to paths. Aliases are detached before
+	// any are reattached so a swap does not mix up the two notes' aliases.
+	movedAliases := make(map[string][]string)
+	for _, m := range moves {
+		if aliases, ok := rm.noteAliases[m.from]; ok && !m.isAsset {
+			movedAliases[m.to] = aliases
+			delete(rm.noteAliases, m.from)
+		}
+	}
 	for _, m := range moves {
 		if m.isAsset {
 			delete(rm.assetPathToID, m.from)
@@ -943,6 +1398,12 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 			}
 		}
 	}
+	for to, aliases := range movedAliases {
+		rm.noteAliases[to] = aliases
+	}
+	if len(movedAliases) > 0 {
+		rm.aliasToPath = aliasTargets(rm.noteAliases)
+	}
 
 	// Rebuild basenameToPath (count == 1 only).
 	rm.basenameToPath = make(map[string]string)
@@ -965,10 +1426,12 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 	var incomingRewrites []rewriteEntry
 	nodeIDs := make([]int64, 0, len(moves))
 	nodeIDToPath := make(map[int64]string, len(moves))
+	nodeIDFromPath := make(map[int64]string, len(moves))
 	nodeIDIsAsset := make(map[int64]bool, len(moves))
 	for _, m := range moves {
 		nodeIDs = append(nodeIDs, m.nodeID)
 		nodeIDToPath[m.nodeID] = m.to
+		nodeIDFromPath[m.nodeID] = m.from
 		nodeIDIsAsset[m.nodeID] = m.isAsset
 	}
 
@@ -988,9 +1451,9 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 			args[i] = id
 		}
 		query := fmt.Sprintf(
-			`SELECT e.id, e.raw_link, e.link_type, e.line_start, sn.path, sn.id, e.target_id
+			`SELECT e.id, e.raw_link, e.link_type, e.line_start, COALESCE(e.col, -1), sn.path, sn.id, e.target_id
 			 FROM edges e JOIN nodes sn ON sn.id = e.source_id AND sn.exists_flag = 1
-			 WHERE e.target_id IN (%s) AND e.link_type IN ('wikilink', 'markdown')`,
+			 WHERE e.target_id IN (%s) AND e.link_type IN ('wikilink', 'markdown', 'frontmatter-link')`,
 			strings.Join(placeholders, ","),
 		)
 		rows, err := db.Query(query, args...)
@@ -1000,7 +1463,7 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		for rows.Next() {
 			var re rewriteEntry
 			var targetID int64
-			if err := rows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.sourcePath, &re.sourceID, &targetID); err != nil {
+			if err := rows.Scan(&re.edgeID, &re.rawLink, &re.linkType, &re.lineStart, &re.col, &re.sourcePath, &re.sourceID, &targetID); err != nil {
 				rows.Close()
 				return nil, err
 			}
@@ -1015,26 +1478,33 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 			}
 
 			if isBasenameRawLink(re.rawLink, re.linkType) {
-				// In dir move, basename doesn't change. Check if ambiguous.
 				// Use the correct key function/counts/pathSet based on node type.
-				var fromBK string
+				fromPath := nodeIDFromPath[targetID]
+				var fromBK, toBK string
 				var counts map[string]int
 				var prePS, postPS map[string]string
 				if nodeIDIsAsset[targetID] {
-					fromBK = assetBasenameKey(toPath)
+					fromBK, toBK = assetBasenameKey(fromPath), assetBasenameKey(toPath)
 					counts = rm.assetBasenameCounts
 					prePS = preMoveAssetPathSet
 					postPS = rm.assetPathSet
 				} else {
-					fromBK = basenameKey(toPath)
+					fromBK, toBK = basenameKey(fromPath), basenameKey(toPath)
 					counts = rm.basenameCounts
 					prePS = preMovePathSet
 					postPS = rm.pathSet
+					if strings.ToLower(rawLinkTarget(re.rawLink, re.linkType)) != fromBK {
+						continue // alias link: the alias moves with the note
+					}
 				}
-				if counts[fromBK] > 1 {
-					preRoot := hasRootInPathSet(fromBK, prePS)
-					postRoot := hasRootInPathSet(fromBK, postPS)
-					if !(preRoot && postRoot) {
+				if fromBK != toBK {
+					// Renamed: the old basename no longer names the file.
+					re.newRawLink = rewriteRawLink(re.rawLink, re.linkType, toPath)
+					incomingRewrites = append(incomingRewrites, re)
+				} else if counts[fromBK] > 1 {
+					preRoot := rm.rootWins(fromBK, prePS)
+					postRoot := rm.rootWins(fromBK, postPS)
+					if !(preRoot && postRoot) || (rm.folderFirst && !nodeIDIsAsset[targetID]) {
 						re.newRawLink = rewriteRawLink(re.rawLink, re.linkType, toPath)
 						incomingRewrites = append(incomingRewrites, re)
 					}
@@ -1067,8 +1537,8 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		}
 	}
 	for bk := range affectedNoteBasenames {
-		preRoot := hasRootInPathSet(bk, preMovePathSet)
-		postRoot := hasRootInPathSet(bk, rm.pathSet)
+		preRoot := rm.rootWins(bk, preMovePathSet)
+		postRoot := rm.rootWins(bk, rm.pathSet)
 		if preRoot && postRoot {
 			continue
 		}
@@ -1098,8 +1568,8 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		}
 	}
 	for abk := range affectedAssetBasenames {
-		preRoot := hasRootInPathSet(abk, preMoveAssetPathSet)
-		postRoot := hasRootInPathSet(abk, rm.assetPathSet)
+		preRoot := rm.rootWins(abk, preMoveAssetPathSet)
+		postRoot := rm.rootWins(abk, rm.assetPathSet)
 		if preRoot && postRoot {
 			continue
 		}
@@ -1120,16 +1590,35 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 	allExternalRewrites := make([]rewriteEntry, 0, len(incomingRewrites)+len(collateralRewrites))
 	allExternalRewrites = append(allExternalRewrites, incomingRewrites...)
 	allExternalRewrites = append(allExternalRewrites, collateralRewrites...)
+	if rm.noRootPriority {
+		if err := checkRootRewrites(allExternalRewrites); err != nil {
+			return nil, err
+		}
+	}
+	if rm.folderFirst {
+		var notes []string
+		moved := make(map[string]bool)
+		for _, m := range moves {
+			if !m.isAsset {
+				notes = append(notes, m.to)
+				moved[m.from] = true
+			}
+		}
+		captured, err := folderCapturedLink(db, notes, moved)
+		if err != nil {
+			return nil, err
+		}
+		if captured != nil {
+			return nil, fmt.Errorf("move would change the target of existing link: %s in %s", captured.rawLink, captured.sourcePath)
+		}
+	}
 
 	// Phase 3: outgoing link rewrite.
 	type movedFileRewrite struct {
 		content    []byte
 		perm       os.FileMode
-		outRewrites []struct {
-			rawLink    string
-			newRawLink string
-			lineStart  int
-		}
+		mtime      time.Time
+		outRewrites []outgoingRewrite
 	}
 	movedFileRewrites := make([]movedFileRewrite, len(moves))
 	for i, m := range moves {
@@ -1153,11 +1642,12 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		movedFileRewrites[i] = movedFileRewrite{
 			content: content,
 			perm:    info.Mode().Perm(),
+			mtime:   info.ModTime(),
 		}
 
-		links := parseLinks(string(content))
+		links := parseIndexLinks(string(content), cfg.Build)
 		for _, link := range links {
-			if link.linkType != "wikilink" && link.linkType != "markdown" {
+			if !isFileLinkType(link.linkType) {
 				continue
 			}
 
@@ -1168,11 +1658,14 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 				err := db.QueryRow(
 					`SELECT COALESCE(tn.path, '') FROM edges e
 					 JOIN nodes tn ON tn.id = e.target_id
-					 WHERE e.source_id = ? AND e.raw_link = ? AND e.link_type IN ('wikilink', 'markdown')
+					 WHERE e.source_id = ? AND e.raw_link = ? AND e.link_type IN ('wikilink', 'markdown', 'frontmatter-link')
 					 LIMIT 1`, m.nodeID, link.rawLink).Scan(&preMoveTargetPath)
 				if err != nil && err != sql.ErrNoRows {
 					return nil, err
 				}
+				if preMoveTargetPath == "" && preRM != nil {
+					preMoveTargetPath = forcedPreMoveTarget(preRM, m.from, link)
+				}
 				if preMoveTargetPath == "" {
 					continue // phantom target, skip
 				}
@@ -1185,11 +1678,15 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 
 				// Determine post-move resolution.
 				needRewrite := false
-				if p, ok := rm.basenameToPath[bk]; ok {
+				if p, ok := rm.localNotePath(m.to, bk); ok {
+					if p != postMoveTargetPath {
+						needRewrite = true
+					}
+				} else if p, ok := rm.basenameToPath[bk]; ok {
 					if p != postMoveTargetPath {
 						needRewrite = true
 					}
-				} else if p, ok := rm.rootBasenameToPath[bk]; ok {
+				} else if p, ok := rm.rootPath(bk); ok {
 					if p != postMoveTargetPath {
 						needRewrite = true
 					}
@@ -1199,11 +1696,7 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 
 				if needRewrite {
 					newRL := rewriteRawLink(link.rawLink, link.linkType, postMoveTargetPath)
-					movedFileRewrites[i].outRewrites = append(movedFileRewrites[i].outRewrites, struct {
-						rawLink    string
-						newRawLink string
-						lineStart  int
-					}{link.rawLink, newRL, link.lineStart})
+					movedFileRewrites[i].outRewrites = append(movedFileRewrites[i].outRewrites, outgoingRewrite{link.rawLink, newRL, link.lineStart, link.linkType, link.col})
 				}
 				continue
 			}
@@ -1214,11 +1707,7 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 					return nil, err
 				}
 				if newRL != link.rawLink {
-					movedFileRewrites[i].outRewrites = append(movedFileRewrites[i].outRewrites, struct {
-						rawLink    string
-						newRawLink string
-						lineStart  int
-					}{link.rawLink, newRL, link.lineStart})
+					movedFileRewrites[i].outRewrites = append(movedFileRewrites[i].outRewrites, outgoingRewrite{link.rawLink, newRL, link.lineStart, link.linkType, link.col})
 				}
 				continue
 			}
@@ -1229,21 +1718,20 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 			err = db.QueryRow(
 				`SELECT COALESCE(tn.path, '') FROM edges e
 				 JOIN nodes tn ON tn.id = e.target_id
-				 WHERE e.source_id = ? AND e.raw_link = ? AND e.link_type IN ('wikilink', 'markdown')
+				 WHERE e.source_id = ? AND e.raw_link = ? AND e.link_type IN ('wikilink', 'markdown', 'frontmatter-link')
 				 LIMIT 1`, m.nodeID, link.rawLink).Scan(&preMoveTargetPath)
 			if err != nil && err != sql.ErrNoRows {
 				return nil, err
 			}
+			if preMoveTargetPath == "" && preRM != nil {
+				preMoveTargetPath = forcedPreMoveTarget(preRM, m.from, link)
+			}
 			if preMoveTargetPath == "" {
 				continue // phantom target, skip
 			}
 			if newPath, ok := movedFromTo[preMoveTargetPath]; ok {
 				newRL := rewriteRawLink(link.rawLink, link.linkType, newPath)
-				movedFileRewrites[i].outRewrites = append(movedFileRewrites[i].outRewrites, struct {
-					rawLink    string
-					newRawLink string
-					lineStart  int
-				}{link.rawLink, newRL, link.lineStart})
+				movedFileRewrites[i].outRewrites = append(movedFileRewrites[i].outRewrites, outgoingRewrite{link.rawLink, newRL, link.lineStart, link.linkType, link.col})
 			}
 		}
 	}
@@ -1259,8 +1747,13 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		for _, re := range allExternalRewrites {
 			groups[re.sourcePath] = append(groups[re.sourcePath], re)
 		}
+		if force {
+			if allExternalRewrites, err = relocateRewrites(vaultPath, groups, cfg.Build); err != nil {
+				return nil, err
+			}
+		}
 		var applyErr error
-		externalMtimes, externalBackups, applyErr = applyFileRewrites(vaultPath, groups)
+		externalMtimes, externalBackups, applyErr = applyFileRewrites(vaultPath, groups, preserveMtime)
 		if applyErr != nil {
 			return nil, applyErr
 		}
@@ -1269,6 +1762,7 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 	// 4.2: apply outgoing rewrites to moved files.
 	type movedBackup struct {
 		restorePath string
+		finalPath   string
 		content     []byte
 		perm        os.FileMode
 	}
@@ -1286,16 +1780,13 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		}
 		movedFileBackups = append(movedFileBackups, movedBackup{
 			restorePath: diskPath,
+			finalPath:   m.to,
 			content:     mfr.content,
 			perm:        mfr.perm,
 		})
 
 		lines := strings.Split(string(mfr.content), "\n")
-		lineRewrites := make(map[int][]struct {
-			rawLink    string
-			newRawLink string
-			lineStart  int
-		})
+		lineRewrites := make(map[int][]outgoingRewrite)
 		for _, ow := range mfr.outRewrites {
 			lineRewrites[ow.lineStart] = append(lineRewrites[ow.lineStart], ow)
 		}
@@ -1304,15 +1795,23 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 				continue
 			}
 			idx := lineNum - 1
-			for _, ow := range ows {
-				lines[idx] = replaceOutsideInlineCode(lines[idx], ow.rawLink, ow.newRawLink)
+			reps := make([]linkReplacement, len(ows))
+			for j, ow := range ows {
+				reps[j] = linkReplacement{ow.linkType, ow.rawLink, ow.newRawLink, ow.col}
 			}
+			lines[idx] = replaceRawLinks(lines[idx], reps)
 		}
 		newContent := []byte(strings.Join(lines, "\n"))
 		movedFileRewrites[i].content = newContent
 
 		fullPath := filepath.Join(vaultPath, diskPath)
-		if err := writeFilePreservePerm(fullPath, newContent, mfr.perm); err != nil {
+		err := writeFilePreservePerm(fullPath, newContent, mfr.perm)
+		if err == nil && preserveMtime {
+			if err = restoreMtime(fullPath, mfr.mtime); err != nil {
+				_ = writeFilePreservePerm(fullPath, mfr.content, mfr.perm)
+			}
+		}
+		if err != nil {
 			// Restore previous moved file backups.
 			for _, b := range movedFileBackups[:len(movedFileBackups)-1] {
 				_ = writeFilePreservePerm(filepath.Join(vaultPath, b.restorePath), b.content, b.perm)
@@ -1352,16 +1851,32 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 	}()
 
 	if needDiskMove {
+		if chained {
+			for _, m := range moves {
+				tmp := m.from + movingTmpSuffix
+				if fileExists(filepath.Join(vaultPath, tmp)) {
+					return nil, fmt.Errorf("temporary file already exists: %s", tmp)
+				}
+				if err := os.Rename(filepath.Join(vaultPath, m.from), filepath.Join(vaultPath, tmp)); err != nil {
+					return nil, err
+				}
+				completedRenames = append(completedRenames, completedRename{from: m.from, to: tmp})
+			}
+		}
 		for _, m := range moves {
+			from := m.from
+			if chained {
+				from += movingTmpSuffix
+			}
 			toFull := filepath.Join(vaultPath, m.to)
 			toFileDir := filepath.Dir(toFull)
 			if err := os.MkdirAll(toFileDir, 0o755); err != nil {
 				return nil, err
 			}
-			if err := os.Rename(filepath.Join(vaultPath, m.from), toFull); err != nil {
+			if err := os.Rename(filepath.Join(vaultPath, from), toFull); err != nil {
 				return nil, err
 			}
-			completedRenames = append(completedRenames, completedRename{from: m.from, to: m.to})
+			completedRenames = append(completedRenames, completedRename{from: from, to: m.to})
 		}
 		// Move disk-only files (not registered in DB).
 		for _, df := range diskOnlyFiles {
@@ -1398,7 +1913,16 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		}
 	}()
 
-	// 5.1: update nodes for moved files.
+	// 5.1: update nodes for moved files. node_key is unique, so a chained
+	// batch first parks the keys on temporary values.
+	if chained {
+		for _, m := range moves {
+			if _, err := tx.Exec("UPDATE nodes SET node_key = ? WHERE id = ?",
+				fmt.Sprintf("moving:%d", m.nodeID), m.nodeID); err != nil {
+				return nil, err
+			}
+		}
+	}
 	for _, m := range moves {
 		var newName, toKeyStr string
 		if m.isAsset {
@@ -1414,6 +1938,10 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 			return nil, err
 		}
 	}
+	nt, err := newNoteText(tx)
+	if err != nil {
+		return nil, err
+	}
 
 	// 5.2: delete old outgoing edges and re-parse (notes only; assets have no outgoing).
 	for i, m := range moves {
@@ -1423,7 +1951,10 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		if _, err := tx.Exec("DELETE FROM edges WHERE source_id = ?", m.nodeID); err != nil {
 			return nil, err
 		}
-		newLinks := parseLinks(string(movedFileRewrites[i].content))
+		if err := nt.replace(tx, m.nodeID, string(movedFileRewrites[i].content)); err != nil {
+			return nil, err
+		}
+		newLinks := parseIndexLinks(string(movedFileRewrites[i].content), cfg.Build)
 		for _, link := range newLinks {
 			targetID, subpath, err := resolveLink(tx, m.to, link, rm)
 			if err != nil {
@@ -1432,23 +1963,35 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 			if targetID == 0 {
 				continue
 			}
-			if err := insertEdge(tx, m.nodeID, targetID, link.linkType, link.rawLink, subpath, link.lineStart, link.lineEnd); err != nil {
+			if err := insertEdge(tx, m.nodeID, targetID, link, subpath); err != nil {
 				return nil, err
 			}
 		}
 	}
 
 	// 5.3: update external edge raw_links.
-	for _, re := range allExternalRewrites {
-		if _, err := tx.Exec("UPDATE edges SET raw_link = ? WHERE id = ?", re.newRawLink, re.edgeID); err != nil {
+	if force {
+		if err := reindexRewrittenSources(tx, vaultPath, allExternalRewrites, rm, cfg.Build); err != nil {
 			return nil, err
 		}
+	}
+	for _, re := range allExternalRewrites {
+		if !force {
+			if _, err := tx.Exec("UPDATE edges SET raw_link = ? WHERE id = ?", re.newRawLink, re.edgeID); err != nil {
+				return nil, err
+			}
+		}
 		result.Rewritten = append(result.Rewritten, RewrittenLink{
 			File:    re.sourcePath,
 			OldLink: re.rawLink,
 			NewLink: re.newRawLink,
 		})
 	}
+	if !force {
+		if err := shiftEdgeCols(tx, allExternalRewrites); err != nil {
+			return nil, err
+		}
+	}
 
 	// 5.4: update source file mtimes for externally rewritten files.
 	if externalMtimes != nil {
@@ -1462,6 +2005,9 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 			if _, err := tx.Exec("UPDATE nodes SET mtime = ? WHERE id = ? AND type = 'note'", mt, re.sourceID); err != nil {
 				return nil, err
 			}
+			if err := nt.refresh(tx, vaultPath, re.sourceID, re.sourcePath); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -1507,19 +2053,157 @@ func MoveDir(vaultPath string, opts MoveDirOptions) (*MoveDirResult, error) {
 		}
 	}
 
+	if beforeCommit != nil {
+		if err := beforeCommit(tx, result); err != nil {
+			return nil, err
+		}
+	}
+
 	// Orphan cleanup.
 	if err := cleanupOrphanedNodes(tx); err != nil {
 		return nil, err
 	}
+	if err := bumpIndexVersion(tx); err != nil {
+		return nil, err
+	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 	committed = true
+	verbosef("move: committed %d files, %d links rewritten", len(result.Moved), len(result.Rewritten))
 
+	if keepBackup {
+		backups := externalBackups
+		for _, b := range movedFileBackups {
+			backups = append(backups, rewriteBackup{path: b.finalPath, content: b.content, perm: b.perm})
+		}
+		if err := keepBackups(vaultPath, backups); err != nil {
+			return nil, err
+		}
+	}
 	return result, nil
 }
 
+// checkRootRewrites rejects rewrites that leave a basename link unchanged.
+// Without root priority, a link to a root file whose basename is now shared
+// has no path form that differs from its basename form.
+func checkRootRewrites(rewrites []rewriteEntry) error {
+	for _, re := range rewrites {
+		if re.newRawLink == re.rawLink {
+			return fmt.Errorf("move would make existing link ambiguous: %s in %s", re.rawLink, re.sourcePath)
+		}
+	}
+	return nil
+}
+
+// forcedPreMoveTarget resolves a link of a forced move that has no edge in
+// the index (it was added after the last build) against the pre-move maps,
+// from the source's pre-move path. Ambiguous and unresolved links return "".
+func forcedPreMoveTarget(pre *resolveMaps, sourcePath string, link linkOccur) string {
+	if link.isBasename {
+		bk := basenameKey(link.target)
+		if p, ok := pre.localNotePath(sourcePath, bk); ok {
+			return p
+		}
+		if p, ok := pre.basenameToPath[bk]; ok {
+			return p
+		}
+		p, _ := pre.rootPath(bk)
+		return p
+	}
+	return pre.pathSet[strings.ToLower(strings.TrimPrefix(link.target, "/"))]
+}
+
+// relocateRewrites points the rewrites of a forced move at the positions where
+// each raw link occurs in the sources' current content, since the indexed
+// lines and columns may be stale. Links no longer present are dropped. groups
+// is updated in place; the flattened rewrites are returned in source path
+// order.
+func relocateRewrites(vaultPath string, groups map[string][]rewriteEntry, cfg BuildConfig) ([]rewriteEntry, error) {
+	type linkKey struct{ rawLink, linkType string }
+	type linkPos struct{ line, col int }
+	sources := make([]string, 0, len(groups))
+	for sourcePath := range groups {
+		sources = append(sources, sourcePath)
+	}
+	sort.Strings(sources)
+
+	var all []rewriteEntry
+	for _, sourcePath := range sources {
+		content, err := os.ReadFile(filepath.Join(vaultPath, sourcePath))
+		if err != nil {
+			return nil, err
+		}
+		positions := make(map[linkKey][]linkPos)
+		for _, link := range parseIndexLinks(string(content), cfg) {
+			k := linkKey{link.rawLink, link.linkType}
+			pos := linkPos{link.lineStart, link.col}
+			if link.linkType == "frontmatter-link" {
+				// Frontmatter entries are matched whole per line.
+				pos.col = -1
+			}
+			if ps := positions[k]; len(ps) == 0 || ps[len(ps)-1] != pos {
+				positions[k] = append(positions[k], pos)
+			}
+		}
+
+		var relocated []rewriteEntry
+		seen := make(map[linkKey]bool)
+		for _, re := range groups[sourcePath] {
+			k := linkKey{re.rawLink, re.linkType}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			for _, pos := range positions[k] {
+				moved := re
+				moved.lineStart, moved.col = pos.line, pos.col
+				relocated = append(relocated, moved)
+			}
+		}
+		if len(relocated) == 0 {
+			delete(groups, sourcePath)
+			continue
+		}
+		groups[sourcePath] = relocated
+		all = append(all, relocated...)
+	}
+	return all, nil
+}
+
+// reindexRewrittenSources rebuilds the outgoing edges of every source touched
+// by a forced move from its rewritten disk content, resolving against rm.
+func reindexRewrittenSources(tx dbExecer, vaultPath string, rewrites []rewriteEntry, rm *resolveMaps, cfg BuildConfig) error {
+	done := make(map[int64]bool)
+	for _, re := range rewrites {
+		if done[re.sourceID] {
+			continue
+		}
+		done[re.sourceID] = true
+		content, err := os.ReadFile(filepath.Join(vaultPath, re.sourcePath))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM edges WHERE source_id = ?", re.sourceID); err != nil {
+			return err
+		}
+		for _, link := range parseIndexLinks(string(content), cfg) {
+			targetID, subpath, err := resolveLink(tx, re.sourcePath, link, rm)
+			if err != nil {
+				return err
+			}
+			if targetID == 0 {
+				continue
+			}
+			if err := insertEdge(tx, re.sourceID, targetID, link, subpath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // rewriteOutgoingRelativeLinkBatch is like rewriteOutgoingRelativeLink
 // but accounts for the target file also being moved.
 func rewriteOutgoingRelativeLinkBatch(rawLink, linkType, from, to string, movedFromTo map[string]string) (string, error) {
@@ -1549,20 +2233,9 @@ func rewriteOutgoingRelativeLinkBatch(rawLink, linkType, from, to string, movedF
 		}
 
 		// Compute relative from new location.
-		rel, err := filepath.Rel(filepath.Dir(to), resolvedTarget)
+		rel, err := relativeLinkPath(filepath.Dir(to), resolvedTarget)
 		if err != nil {
-			return "", err
-		}
-		rel = filepath.ToSlash(rel)
-
-		// Check vault escape.
-		if strings.HasPrefix(NormalizePath(filepath.Join(filepath.Dir(to), rel)), "..") {
-			return "", fmt.Errorf("rewritten link would escape vault: %s", rawLink)
-		}
-
-		// Add ./ prefix if needed.
-		if !strings.HasPrefix(rel, "..") {
-			rel = "./" + rel
+			return "", fmt.Errorf("%w: %s", err, rawLink)
 		}
 
 		// Wikilink: always remove .md.
@@ -1571,24 +2244,15 @@ func rewriteOutgoingRelativeLinkBatch(rawLink, linkType, from, to string, movedF
 		return "[[" + rel + subpath + alias + "]]", nil
 
 	case "markdown":
-		start := strings.Index(rawLink, "](")
-		if start < 0 {
+		textPart, urlPart, frag, angled, ok := splitMarkdownRawLink(rawLink)
+		if !ok {
 			return rawLink, nil
 		}
-		textPart := rawLink[:start+2]
-		urlPart := rawLink[start+2:]
-		urlPart = strings.TrimSuffix(urlPart, ")")
-
-		var frag string
-		if idx := strings.Index(urlPart, "#"); idx >= 0 {
-			frag = urlPart[idx:]
-			urlPart = urlPart[:idx]
-		}
 
 		hasMdExt := strings.HasSuffix(strings.ToLower(urlPart), ".md")
 
 		// Resolve from old location.
-		resolvedTarget := NormalizePath(filepath.Join(filepath.Dir(from), urlPart))
+		resolvedTarget := NormalizePath(filepath.Join(filepath.Dir(from), decodeMarkdownPath(urlPart)))
 
 		// Check if target is also being moved.
 		if newTarget, ok := movedFromTo[resolvedTarget]; ok {
@@ -1604,20 +2268,9 @@ func rewriteOutgoingRelativeLinkBatch(rawLink, linkType, from, to string, movedF
 		}
 
 		// Compute relative from new location.
-		rel, err := filepath.Rel(filepath.Dir(to), resolvedTarget)
+		rel, err := relativeLinkPath(filepath.Dir(to), resolvedTarget)
 		if err != nil {
-			return "", err
-		}
-		rel = filepath.ToSlash(rel)
-
-		// Check vault escape.
-		if strings.HasPrefix(NormalizePath(filepath.Join(filepath.Dir(to), rel)), "..") {
-			return "", fmt.Errorf("rewritten link would escape vault: %s", rawLink)
-		}
-
-		// Add ./ prefix if needed.
-		if !strings.HasPrefix(rel, "..") {
-			rel = "./" + rel
+			return "", fmt.Errorf("%w: %s", err, rawLink)
 		}
 
 		// Markdown: preserve .md extension presence.
@@ -1628,8 +2281,9 @@ func rewriteOutgoingRelativeLinkBatch(rawLink, linkType, from, to string, movedF
 		} else {
 			rel = strings.TrimSuffix(rel, ".md")
 		}
+		rel = encodeSpacesLike(urlPart, rel)
 
-		return textPart + rel + frag + ")", nil
+		return textPart + formatMarkdownURL(rel, frag, angled) + ")", nil
 	}
 	return rawLink, nil
 }
@@ -1637,95 +2291,26 @@ func rewriteOutgoingRelativeLinkBatch(rawLink, linkType, from, to string, movedF
 // rewriteOutgoingRelativeLink rewrites a relative link in the moved file
 // from the old path perspective to the new path perspective.
 func rewriteOutgoingRelativeLink(rawLink, linkType, from, to string) (string, error) {
-	switch linkType {
-	case "wikilink":
-		inner := strings.TrimPrefix(rawLink, "[[")
-		inner = strings.TrimSuffix(inner, "]]")
-
-		var alias, subpath string
-		if idx := strings.Index(inner, "|"); idx >= 0 {
-			alias = inner[idx:]
-			inner = inner[:idx]
-		}
-		if idx := strings.Index(inner, "#"); idx >= 0 {
-			subpath = inner[idx:]
-			inner = inner[:idx]
-		}
-
-		// Resolve from old location.
-		resolvedTarget := NormalizePath(filepath.Join(filepath.Dir(from), inner))
-
-		// Compute relative from new location.
-		rel, err := filepath.Rel(filepath.Dir(to), resolvedTarget)
-		if err != nil {
-			return "", err
-		}
-		rel = filepath.ToSlash(rel)
-
-		// Check vault escape.
-		if strings.HasPrefix(NormalizePath(filepath.Join(filepath.Dir(to), rel)), "..") {
-			return "", fmt.Errorf("rewritten link would escape vault: %s", rawLink)
-		}
-
-		// Add ./ prefix if needed.
-		if !strings.HasPrefix(rel, "..") {
-			rel = "./" + rel
-		}
-
-		// Wikilink: always remove .md.
-		rel = strings.TrimSuffix(rel, ".md")
-
-		return "[[" + rel + subpath + alias + "]]", nil
-
-	case "markdown":
-		start := strings.Index(rawLink, "](")
-		if start < 0 {
-			return rawLink, nil
-		}
-		textPart := rawLink[:start+2]
-		urlPart := rawLink[start+2:]
-		urlPart = strings.TrimSuffix(urlPart, ")")
-
-		var frag string
-		if idx := strings.Index(urlPart, "#"); idx >= 0 {
-			frag = urlPart[idx:]
-			urlPart = urlPart[:idx]
-		}
-
-		hasMdExt := strings.HasSuffix(strings.ToLower(urlPart), ".md")
-
-		// Resolve from old location.
-		resolvedTarget := NormalizePath(filepath.Join(filepath.Dir(from), urlPart))
-
-		// Compute relative from new location.
-		rel, err := filepath.Rel(filepath.Dir(to), resolvedTarget)
-		if err != nil {
-			return "", err
-		}
-		rel = filepath.ToSlash(rel)
-
-		// Check vault escape.
-		if strings.HasPrefix(NormalizePath(filepath.Join(filepath.Dir(to), rel)), "..") {
-			return "", fmt.Errorf("rewritten link would escape vault: %s", rawLink)
-		}
-
-		// Add ./ prefix if needed.
-		if !strings.HasPrefix(rel, "..") {
-			rel = "./" + rel
-		}
-
-		// Markdown: preserve .md extension presence.
-		if hasMdExt {
-			if !strings.HasSuffix(strings.ToLower(rel), ".md") {
-				rel += ".md"
-			}
-		} else {
-			rel = strings.TrimSuffix(rel, ".md")
-		}
+	return rewriteOutgoingRelativeLinkBatch(rawLink, linkType, from, to, nil)
+}
 
-		return textPart + rel + frag + ")", nil
+// relativeLinkPath returns the relative link from dir to the vault-relative
+// target: "../" segments for each level to climb, otherwise a "./" prefix.
+// Only the resolved target is checked against the vault root, so a link may
+// climb any number of levels as long as it lands inside the vault.
+func relativeLinkPath(dir, target string) (string, error) {
+	if pathEscapesVault(target) {
+		return "", fmt.Errorf("rewritten link would escape vault")
 	}
-	return rawLink, nil
+	rel, err := filepath.Rel(dir, target)
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel != ".." && !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel, nil
 }
 
 // fileExists checks if a file exists at the given path.
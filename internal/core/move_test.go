@@ -1563,6 +1563,131 @@ func TestMoveDir_Basic(t *testing.T) {
 	}
 }
 
+func TestMoveDir_MaxDepth(t *testing.T) {
+	vault := copyVault(t, "vault_move_dir")
+	if err := os.WriteFile(filepath.Join(vault, "sub", "inner", "X.md"), []byte("[[sub/A]]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	result, err := MoveDir(vault, MoveDirOptions{FromDir: "sub", ToDir: "newdir", MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("MoveDir: %v", err)
+	}
+	if len(result.Moved) != 2 {
+		t.Fatalf("expected 2 moved files, got %d: %+v", len(result.Moved), result.Moved)
+	}
+	for _, m := range result.Moved {
+		if m.From == "sub/inner/X.md" {
+			t.Errorf("sub/inner/X.md should stay in place")
+		}
+	}
+
+	// The deeper file stays on disk and in the index.
+	if !fileExists(filepath.Join(vault, "sub", "inner", "X.md")) {
+		t.Error("sub/inner/X.md should still exist on disk")
+	}
+	if fileExists(filepath.Join(vault, "newdir", "inner", "X.md")) {
+		t.Error("newdir/inner/X.md should not exist")
+	}
+	var foundX bool
+	for _, n := range queryNodes(t, dbPath(vault), "note") {
+		if n.path == "sub/inner/X.md" {
+			foundX = true
+		}
+	}
+	if !foundX {
+		t.Error("DB should still have sub/inner/X.md")
+	}
+
+	// Its path link into the moved set is rewritten.
+	content, err := os.ReadFile(filepath.Join(vault, "sub", "inner", "X.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "[[newdir/A]]\n" {
+		t.Errorf("X.md = %q, want %q", string(content), "[[newdir/A]]\n")
+	}
+}
+
+func TestMoveDir_PruneEmpty(t *testing.T) {
+	vault := copyVault(t, "vault_move_dir")
+	// Hidden files and empty nested directories do not keep sub/ alive.
+	if err := os.WriteFile(filepath.Join(vault, "sub", ".DS_Store"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(vault, "sub", "empty", "deeper"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if _, err := MoveDir(vault, MoveDirOptions{FromDir: "sub", ToDir: "newdir", PruneEmpty: true}); err != nil {
+		t.Fatalf("MoveDir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(vault, "sub")); !os.IsNotExist(err) {
+		t.Errorf("sub/ should be removed, stat err = %v", err)
+	}
+	if !fileExists(filepath.Join(vault, "Other.md")) {
+		t.Error("vault root contents should be untouched")
+	}
+	if !fileExists(filepath.Join(vault, "newdir", "inner", "X.md")) {
+		t.Error("newdir/inner/X.md should exist")
+	}
+}
+
+func TestMoveDir_PruneEmptyKeepsRemainingFiles(t *testing.T) {
+	vault := copyVault(t, "vault_move_dir")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if _, err := MoveDir(vault, MoveDirOptions{FromDir: "sub", ToDir: "newdir", MaxDepth: 1, PruneEmpty: true}); err != nil {
+		t.Fatalf("MoveDir: %v", err)
+	}
+	if !fileExists(filepath.Join(vault, "sub", "inner", "X.md")) {
+		t.Error("sub/inner/X.md was not moved and must survive pruning")
+	}
+}
+
+func TestMoveDir_NoPruneByDefault(t *testing.T) {
+	vault := copyVault(t, "vault_move_dir")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if _, err := MoveDir(vault, MoveDirOptions{FromDir: "sub", ToDir: "newdir"}); err != nil {
+		t.Fatalf("MoveDir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(vault, "sub")); err != nil {
+		t.Errorf("sub/ should be left in place without PruneEmpty: %v", err)
+	}
+}
+
+func TestMoveDir_MaxDepthValidation(t *testing.T) {
+	vault := copyVault(t, "vault_move_dir")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	_, err := MoveDir(vault, MoveDirOptions{FromDir: "sub", ToDir: "newdir", MaxDepth: -1})
+	if err == nil || !strings.Contains(err.Error(), "max depth must be >= 0") {
+		t.Errorf("expected max depth error, got: %v", err)
+	}
+	// Overlap is still rejected when depth-limited.
+	_, err = MoveDir(vault, MoveDirOptions{FromDir: "sub", ToDir: "sub/inner/deeper", MaxDepth: 1})
+	if err == nil || !strings.Contains(err.Error(), "overlap") {
+		t.Errorf("expected overlap error, got: %v", err)
+	}
+	_, err = MoveDir(vault, MoveDirOptions{FromDir: "sub", ToDir: "../outside", MaxDepth: 1})
+	if err == nil || !strings.Contains(err.Error(), "escapes vault") {
+		t.Errorf("expected escape error, got: %v", err)
+	}
+}
+
 func TestMoveDir_NoFiles(t *testing.T) {
 	vault := copyVault(t, "vault_move_dir")
 	if err := Build(vault); err != nil {
@@ -2414,3 +2539,856 @@ func TestMoveDir_ConsecutiveWithCrossLinks(t *testing.T) {
 		t.Errorf("ResA.md should reference ThoB after second move, got: %s", s)
 	}
 }
+
+func TestMove_AngleBracketMarkdownLink(t *testing.T) {
+	vault := t.TempDir()
+	files := map[string]string{
+		"A.md":             "[x](<notes/my note.md>)\n[y](<notes/my note.md>#Intro)\n",
+		"notes/my note.md": "# Intro\n",
+	}
+	for rel, content := range files {
+		full := filepath.Join(vault, rel)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	// The space-containing path resolves to the note, not a phantom.
+	edges := queryEdges(t, dbPath(vault), "A.md")
+	if len(edges) != 2 {
+		t.Fatalf("edges = %d, want 2", len(edges))
+	}
+	for _, e := range edges {
+		if e.targetKey != "note:path:notes/my note.md" {
+			t.Errorf("target = %s, want note:path:notes/my note.md", e.targetKey)
+		}
+	}
+
+	if _, err := Move(vault, MoveOptions{From: "notes/my note.md", To: "archive/my note.md"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(vault, "A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[x](<archive/my note.md>)\n[y](<archive/my note.md#Intro>)\n"
+	if string(content) != want {
+		t.Errorf("A.md = %q, want %q", string(content), want)
+	}
+}
+
+func TestMove_FrontmatterLinkPathRewrite(t *testing.T) {
+	vault := copyVault(t, "vault_build_frontmatter_links")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if _, err := Move(vault, MoveOptions{From: "sub/Impl.md", To: "other/Impl.md"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+
+	inline, err := os.ReadFile(filepath.Join(vault, "Inline.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\nrelated: [Design, DesignDoc, other/Impl]\n---\n# Inline\n"; string(inline) != want {
+		t.Errorf("Inline.md = %q, want %q", string(inline), want)
+	}
+	block, err := os.ReadFile(filepath.Join(vault, "Block.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\nrelated:\n  - Design\n  - other/Impl.md\n  - Ghost\n---\n# Block\n"; string(block) != want {
+		t.Errorf("Block.md = %q, want %q", string(block), want)
+	}
+
+	for _, e := range queryEdges(t, dbPath(vault), "Block.md") {
+		if e.rawLink == "other/Impl.md" && e.targetKey != "note:path:other/Impl.md" {
+			t.Errorf("edge target = %s, want note:path:other/Impl.md", e.targetKey)
+		}
+	}
+}
+
+func TestMove_FrontmatterLinkBasenameChange(t *testing.T) {
+	vault := copyVault(t, "vault_build_frontmatter_links")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if _, err := Move(vault, MoveOptions{From: "Design.md", To: "archive/Spec.md"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+
+	// DesignDoc shares a prefix with Design but is a different entry.
+	inline, err := os.ReadFile(filepath.Join(vault, "Inline.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\nrelated: [archive/Spec, DesignDoc, sub/Impl]\n---\n# Inline\n"; string(inline) != want {
+		t.Errorf("Inline.md = %q, want %q", string(inline), want)
+	}
+	block, err := os.ReadFile(filepath.Join(vault, "Block.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\nrelated:\n  - archive/Spec\n  - sub/Impl.md\n  - Ghost\n---\n# Block\n"; string(block) != want {
+		t.Errorf("Block.md = %q, want %q", string(block), want)
+	}
+}
+
+func TestMoveDir_FrontmatterLinkInsideMovedSet(t *testing.T) {
+	vault := copyVault(t, "vault_build_frontmatter_links")
+	if err := os.WriteFile(filepath.Join(vault, "sub", "Notes.md"), []byte("---\nrelated: [sub/Impl]\n---\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if _, err := MoveDir(vault, MoveDirOptions{FromDir: "sub", ToDir: "lib"}); err != nil {
+		t.Fatalf("movedir: %v", err)
+	}
+
+	notes, err := os.ReadFile(filepath.Join(vault, "lib", "Notes.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\nrelated: [lib/Impl]\n---\n"; string(notes) != want {
+		t.Errorf("lib/Notes.md = %q, want %q", string(notes), want)
+	}
+	inline, err := os.ReadFile(filepath.Join(vault, "Inline.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\nrelated: [Design, DesignDoc, lib/Impl]\n---\n# Inline\n"; string(inline) != want {
+		t.Errorf("Inline.md = %q, want %q", string(inline), want)
+	}
+}
+
+func TestMove_PreservesPercentEncoding(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"sub/My Note.md": "# My Note\n",
+		"A.md":           "[n](sub/My%20Note.md)\n[r](./sub/My%20Note.md)\n",
+		"B.md":           "[s](<sub/My Note.md>)\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	// Moving the source rewrites its outgoing relative link, keeping %20.
+	if _, err := Move(vault, MoveOptions{From: "A.md", To: "x/A.md"}); err != nil {
+		t.Fatalf("move source: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(vault, "x/A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[n](sub/My%20Note.md)\n[r](../sub/My%20Note.md)\n"; string(content) != want {
+		t.Errorf("x/A.md = %q, want %q", content, want)
+	}
+
+	// Moving the target rewrites incoming links in their own style.
+	if _, err := Move(vault, MoveOptions{From: "sub/My Note.md", To: "other/My Note.md"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	content, err = os.ReadFile(filepath.Join(vault, "x/A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[n](other/My%20Note.md)\n[r](other/My%20Note.md)\n"; string(content) != want {
+		t.Errorf("x/A.md = %q, want %q", content, want)
+	}
+	content, err = os.ReadFile(filepath.Join(vault, "B.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[s](<other/My Note.md>)\n"; string(content) != want {
+		t.Errorf("B.md = %q, want %q", content, want)
+	}
+}
+
+func TestMove_ForceStaleSource(t *testing.T) {
+	vault := copyVault(t, "vault_move_basic")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	// Edit A.md after build: shift its links down and add a new one.
+	time.Sleep(1100 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(vault, "A.md"), []byte("intro\n\n[link to B](./B.md)\n[[C]]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Move(vault, MoveOptions{From: "A.md", To: "sub2/A.md"}); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Fatalf("expected stale error without force, got: %v", err)
+	}
+
+	if _, err := Move(vault, MoveOptions{From: "A.md", To: "sub2/A.md", Force: true}); err != nil {
+		t.Fatalf("forced move: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(vault, "sub2", "A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "intro\n\n[link to B](../B.md)\n[[C]]\n"; string(content) != want {
+		t.Errorf("sub2/A.md = %q, want %q", content, want)
+	}
+	targets := aliasEdgeTargets(t, vault, "sub2/A.md")
+	if targets["[link to B](../B.md)"] != noteKey("B.md") || targets["[[C]]"] != noteKey("C.md") {
+		t.Errorf("edges = %v, want links to B.md and C.md", targets)
+	}
+}
+
+func TestMove_ForceReparsesStaleIncoming(t *testing.T) {
+	vault := copyVault(t, "vault_move_basic")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	// B.md's [[A]] moved from line 1 to line 3, and a second one was added.
+	time.Sleep(1100 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(vault, "B.md"), []byte("new top\n\n[[A]]\n#shared\nsee [[A]] again\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Move(vault, MoveOptions{From: "A.md", To: "X.md", Force: true})
+	if err != nil {
+		t.Fatalf("forced move: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(vault, "B.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "new top\n\n[[X]]\n#shared\nsee [[X]] again\n"; string(content) != want {
+		t.Errorf("B.md = %q, want %q", content, want)
+	}
+	n := 0
+	for _, r := range result.Rewritten {
+		if r.File == "B.md" {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Errorf("B.md rewrites = %d, want 2 (one per line): %v", n, result.Rewritten)
+	}
+	if got := aliasEdgeTargets(t, vault, "B.md")["[[X]]"]; got != noteKey("X.md") {
+		t.Errorf("B.md [[X]] target = %q, want %q", got, noteKey("X.md"))
+	}
+}
+
+func TestMoveDir_ForceStaleSource(t *testing.T) {
+	vault := copyVault(t, "vault_move_basic")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(vault, "sub", "D.md"), []byte("edited\n[[A]]\n[path link](../A.md)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MoveDir(vault, MoveDirOptions{FromDir: "sub", ToDir: "deep/sub"}); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Fatalf("expected stale error without force, got: %v", err)
+	}
+
+	if _, err := MoveDir(vault, MoveDirOptions{FromDir: "sub", ToDir: "deep/sub", Force: true}); err != nil {
+		t.Fatalf("forced move: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(vault, "deep", "sub", "D.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "edited\n[[A]]\n[path link](../../A.md)\n"; string(content) != want {
+		t.Errorf("deep/sub/D.md = %q, want %q", content, want)
+	}
+}
+
+func TestMoveRewritesBackslashPathWithSlashes(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":     "[link](sub\\B.md)\n| [[sub\\B\\|b]] |\n",
+		"sub/B.md": "# B\n",
+	})
+	buildVault(t, vault)
+
+	if _, err := Move(vault, MoveOptions{From: "sub/B.md", To: "new/B.md"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(vault, "A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[link](new/B.md)\n| [[new/B\\|b]] |\n"
+	if string(data) != want {
+		t.Errorf("A.md = %q, want %q", data, want)
+	}
+}
+
+func TestMoveBatch_Swap(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "# A\n[[B]]\n",
+		"B.md": "# B\n",
+		"C.md": "[[A]] and [[B]]\n[a](A.md)\n",
+	})
+	buildVault(t, vault)
+
+	result, err := MoveBatch(vault, []MoveOptions{
+		{From: "A.md", To: "B.md"},
+		{From: "B.md", To: "A.md"},
+	})
+	if err != nil {
+		t.Fatalf("move batch: %v", err)
+	}
+	if len(result.Moved) != 2 {
+		t.Errorf("moved = %+v, want 2", result.Moved)
+	}
+
+	want := map[string]string{
+		"A.md": "# B\n",
+		"B.md": "# A\n[[A]]\n",
+		"C.md": "[[B]] and [[A]]\n[a](B.md)\n",
+	}
+	for path, content := range want {
+		data, err := os.ReadFile(filepath.Join(vault, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", path, data, content)
+		}
+	}
+	if fileExists(filepath.Join(vault, "A.md"+movingTmpSuffix)) || fileExists(filepath.Join(vault, "B.md"+movingTmpSuffix)) {
+		t.Error("temporary files left behind")
+	}
+
+	// Index edges follow the swapped content.
+	targets := make(map[string]string)
+	for _, e := range queryEdges(t, dbPath(vault), "C.md") {
+		targets[e.rawLink] = e.targetKey
+	}
+	if targets["[[B]]"] != noteKey("B.md") || targets["[[A]]"] != noteKey("A.md") {
+		t.Errorf("C.md edges = %v", targets)
+	}
+	edges := queryEdges(t, dbPath(vault), "B.md")
+	if len(edges) != 1 || edges[0].rawLink != "[[A]]" || edges[0].targetKey != noteKey("A.md") {
+		t.Errorf("B.md edges = %+v, want [[A]] -> A.md", edges)
+	}
+}
+
+func TestMoveBatch_DestinationsCollide(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "# A\n",
+		"B.md": "# B\n",
+	})
+	buildVault(t, vault)
+
+	_, err := MoveBatch(vault, []MoveOptions{
+		{From: "A.md", To: "sub/X.md"},
+		{From: "B.md", To: "sub/X.md"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "collide") {
+		t.Fatalf("expected collision error, got %v", err)
+	}
+	if !fileExists(filepath.Join(vault, "A.md")) || !fileExists(filepath.Join(vault, "B.md")) {
+		t.Error("files moved despite validation error")
+	}
+}
+
+func TestMoveBatch_StaleLeavesVaultUntouched(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "# A\n",
+		"B.md": "# B\n",
+		"C.md": "[[A]] [[B]]\n",
+	})
+	buildVault(t, vault)
+
+	time.Sleep(1100 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(vault, "B.md"), []byte("# B changed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := MoveBatch(vault, []MoveOptions{
+		{From: "A.md", To: "B.md"},
+		{From: "B.md", To: "A.md"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Fatalf("expected stale error, got %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(vault, "A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "# A\n" {
+		t.Errorf("A.md = %q, want unchanged", data)
+	}
+	data, err = os.ReadFile(filepath.Join(vault, "C.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[[A]] [[B]]\n" {
+		t.Errorf("C.md = %q, want unchanged", data)
+	}
+}
+
+func TestMove_PreservesEncodedFragment(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"n/Note.md": "# Some Heading\n",
+		"A.md":      "[h](n/Note.md#Some%20Heading)\n[[n/Note#Some%20Heading]]\n",
+		"sub/B.md":  "[h](../n/Note.md#Some%20Heading%3F)\n",
+		"sub/C.md":  "[a](../A.md#x%2Fy%20z)\n",
+	})
+	buildVault(t, vault)
+
+	// Incoming links: the path is rewritten, the fragment kept byte-for-byte.
+	if _, err := Move(vault, MoveOptions{From: "n/Note.md", To: "dir x/Note.md"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	// Outgoing relative link of a moved file.
+	if _, err := Move(vault, MoveOptions{From: "sub/C.md", To: "a/b/C.md"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	// Directory move: incoming and outgoing links in one batch.
+	if _, err := MoveDir(vault, MoveDirOptions{FromDir: "sub", ToDir: "deep/sub"}); err != nil {
+		t.Fatalf("move dir: %v", err)
+	}
+
+	want := map[string]string{
+		"A.md":          "[h](<dir x/Note.md#Some%20Heading>)\n[[dir x/Note#Some%20Heading]]\n",
+		"deep/sub/B.md": "[h](<dir x/Note.md#Some%20Heading%3F>)\n",
+		"a/b/C.md":      "[a](../../A.md#x%2Fy%20z)\n",
+	}
+	for path, content := range want {
+		data, err := os.ReadFile(filepath.Join(vault, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", path, data, content)
+		}
+	}
+}
+
+func TestMove_RelativeLinkUpTwoLevels(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"a/b/c/N.md":         "[x](../../shared/deep/Y.md)\n[[../../shared/deep/Y]]\n",
+		"a/shared/deep/Y.md": "# Y\n",
+	})
+	buildVault(t, vault)
+
+	if _, err := Move(vault, MoveOptions{From: "a/b/c/N.md", To: "a/N.md"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(vault, "a", "N.md"))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	want := "[x](./shared/deep/Y.md)\n[[./shared/deep/Y]]\n"
+	if string(content) != want {
+		t.Errorf("a/N.md = %q, want %q", content, want)
+	}
+	edges := queryEdges(t, dbPath(vault), "a/N.md")
+	if len(edges) != 2 {
+		t.Fatalf("edges from a/N.md = %d, want 2", len(edges))
+	}
+	for _, e := range edges {
+		if e.targetKey != noteKey("a/shared/deep/Y.md") {
+			t.Errorf("edge %s → %s, want a/shared/deep/Y.md", e.rawLink, e.targetKey)
+		}
+	}
+}
+
+func TestMove_RootPriorityDisabled(t *testing.T) {
+	// [[A]] points at the unique root A.md. Moving X.md to sub/A.md would
+	// shadow it only under root priority; with it off the link is ambiguous.
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"mdhop.yaml": "build:\n  root_priority: false\n",
+		"A.md":       "# A at root\n",
+		"B.md":       "[[A]]\n",
+		"X.md":       "# X\n",
+	})
+	buildVault(t, vault)
+
+	_, err := Move(vault, MoveOptions{From: "X.md", To: "sub/A.md"})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous error, got: %v", err)
+	}
+	if !fileExists(filepath.Join(vault, "X.md")) {
+		t.Error("X.md should not be moved after the error")
+	}
+}
+
+func TestMove_UpdateOnly(t *testing.T) {
+	vault := copyVault(t, "vault_move_basic")
+	buildVault(t, vault)
+
+	if err := os.MkdirAll(filepath.Join(vault, "newsub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(vault, "A.md"), filepath.Join(vault, "newsub", "A.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Move(vault, MoveOptions{From: "A.md", To: "newsub/A.md", UpdateOnly: true})
+	if err != nil {
+		t.Fatalf("move (update-only): %v", err)
+	}
+	var cRewritten bool
+	for _, rw := range result.Rewritten {
+		if rw.File == "C.md" && rw.OldLink == "[link to A](./A.md)" {
+			cRewritten = true
+		}
+	}
+	if !cRewritten {
+		t.Error("C.md path link should be rewritten in update-only mode")
+	}
+	if len(queryEdges(t, dbPath(vault), "newsub/A.md")) == 0 {
+		t.Error("DB should hold the edges of newsub/A.md")
+	}
+}
+
+func TestMove_UpdateOnlySourceStillOnDisk(t *testing.T) {
+	vault := copyVault(t, "vault_move_basic")
+	buildVault(t, vault)
+
+	_, err := Move(vault, MoveOptions{From: "A.md", To: "newsub/A.md", UpdateOnly: true})
+	if err == nil || !strings.Contains(err.Error(), "source file still exists on disk") {
+		t.Fatalf("expected source-still-exists error, got: %v", err)
+	}
+	if !fileExists(filepath.Join(vault, "A.md")) || fileExists(filepath.Join(vault, "newsub", "A.md")) {
+		t.Error("update-only must not move files")
+	}
+	if len(queryEdges(t, dbPath(vault), "A.md")) == 0 {
+		t.Error("index should still hold A.md")
+	}
+
+	// Both gone: nothing to catch up with.
+	if err := os.Remove(filepath.Join(vault, "A.md")); err != nil {
+		t.Fatal(err)
+	}
+	_, err = Move(vault, MoveOptions{From: "A.md", To: "newsub/A.md", UpdateOnly: true})
+	if err == nil || !strings.Contains(err.Error(), "destination file not found on disk") {
+		t.Errorf("expected destination-not-found error, got: %v", err)
+	}
+}
+
+func TestMoveBatch_UpdateOnly(t *testing.T) {
+	vault := copyVault(t, "vault_move_basic")
+	buildVault(t, vault)
+
+	// Files still in place: the batch must fail without moving anything.
+	batch := []MoveOptions{
+		{From: "A.md", To: "newsub/A.md", UpdateOnly: true},
+		{From: "B.md", To: "newsub/B.md", UpdateOnly: true},
+	}
+	_, err := MoveBatch(vault, batch)
+	if err == nil || !strings.Contains(err.Error(), "source file still exists on disk") {
+		t.Fatalf("expected source-still-exists error, got: %v", err)
+	}
+	if !fileExists(filepath.Join(vault, "A.md")) || fileExists(filepath.Join(vault, "newsub", "A.md")) {
+		t.Error("update-only batch must not move files")
+	}
+
+	// Renamed outside mdhop: the batch catches the index up.
+	if err := os.MkdirAll(filepath.Join(vault, "newsub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"A.md", "B.md"} {
+		if err := os.Rename(filepath.Join(vault, name), filepath.Join(vault, "newsub", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := MoveBatch(vault, batch)
+	if err != nil {
+		t.Fatalf("move batch (update-only): %v", err)
+	}
+	if len(result.Moved) != 2 {
+		t.Errorf("moved = %+v, want 2", result.Moved)
+	}
+	if len(queryEdges(t, dbPath(vault), "newsub/A.md")) == 0 {
+		t.Error("DB should hold the edges of newsub/A.md")
+	}
+}
+
+func TestMove_FolderFirst(t *testing.T) {
+	// Under folder-first, y/B.md's [[Note]] names its sibling y/Note.md;
+	// the other [[Note]] links name the root Note.md.
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"mdhop.yaml": "build:\n  link_resolution: folder-first\n",
+		"Note.md":    "# Root note\n",
+		"C.md":       "[[Note]]\n",
+		"sub/A.md":   "[[Note]]\n",
+		"x/Note.md":  "# X note\n",
+		"y/Note.md":  "# Y note\n",
+		"y/B.md":     "[[Note]]\n",
+	})
+	buildVault(t, vault)
+
+	// Moving a note next to a link that names the root note would take it
+	// over.
+	_, err := Move(vault, MoveOptions{From: "x/Note.md", To: "sub/Note.md"})
+	if err == nil || !strings.Contains(err.Error(), "would change the target of existing link: [[Note]] in sub/A.md") {
+		t.Fatalf("err = %v, want target change error for sub/A.md", err)
+	}
+
+	// Moving a note out of the folder of a link that names it rewrites the
+	// link to its new path instead of letting it fall back to the root.
+	result, err := Move(vault, MoveOptions{From: "y/Note.md", To: "z/Note.md"})
+	if err != nil {
+		t.Fatalf("move y/Note.md: %v", err)
+	}
+	if len(result.Rewritten) != 1 || result.Rewritten[0].File != "y/B.md" || result.Rewritten[0].NewLink != "[[z/Note]]" {
+		t.Errorf("rewritten = %+v, want y/B.md [[Note]] -> [[z/Note]]", result.Rewritten)
+	}
+	got, err := Resolve(vault, "C.md", "[[Note]]")
+	if err != nil || got.Path != "Note.md" {
+		t.Errorf("resolve [[Note]] in C.md = %+v, %v; want Note.md", got, err)
+	}
+}
+
+func TestMoveDir_RenameOnConflict(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"src/A.md":    "[[src/B]]\n",
+		"src/B.md":    "[[src/A]]\n",
+		"src/img.png": "new",
+		"dst/A.md":    "other\n",
+		"dst/img.png": "old",
+		"Other.md":    "[[src/A]] [[dst/A]]\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	result, err := MoveDir(vault, MoveDirOptions{FromDir: "src", ToDir: "dst", RenameOnConflict: true})
+	if err != nil {
+		t.Fatalf("MoveDir: %v", err)
+	}
+
+	var renamed []string
+	for _, m := range result.Renamed {
+		renamed = append(renamed, m.From+"->"+m.To)
+	}
+	if got := strings.Join(renamed, ","); got != "src/A.md->dst/A-1.md,src/img.png->dst/img-1.png" {
+		t.Errorf("renamed = %s", got)
+	}
+	for _, rel := range []string{"dst/A.md", "dst/A-1.md", "dst/B.md", "dst/img.png", "dst/img-1.png"} {
+		if !fileExists(filepath.Join(vault, rel)) {
+			t.Errorf("%s should exist on disk", rel)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(vault, "dst", "A.md")); string(data) != "other\n" {
+		t.Errorf("dst/A.md overwritten: %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(vault, "dst", "img.png")); string(data) != "old" {
+		t.Errorf("dst/img.png overwritten: %q", data)
+	}
+
+	// Incoming links follow the suffixed name; links to dst/A.md stay.
+	for rel, want := range map[string]string{
+		"Other.md":   "[[dst/A-1]] [[dst/A]]\n",
+		"dst/B.md":   "[[dst/A-1]]\n",
+		"dst/A-1.md": "[[dst/B]]\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(vault, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", rel, data, want)
+		}
+	}
+	notes := make(map[string]bool)
+	for _, n := range queryNodes(t, dbPath(vault), "note") {
+		notes[n.path] = true
+	}
+	for _, want := range []string{"dst/A.md", "dst/A-1.md", "dst/B.md"} {
+		if !notes[want] {
+			t.Errorf("note %s not registered: %v", want, notes)
+		}
+	}
+}
+
+func TestMoveDir_RenameOnConflictOff(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"src/A.md": "content\n",
+		"dst/A.md": "other\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	_, err := MoveDir(vault, MoveDirOptions{FromDir: "src", ToDir: "dst"})
+	if err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("expected 'already registered' error, got: %v", err)
+	}
+	if !fileExists(filepath.Join(vault, "src", "A.md")) {
+		t.Error("src/A.md should stay in place")
+	}
+}
+
+// assertMtimeKept checks that rel keeps mtime on disk and in the index.
+func assertMtimeKept(t *testing.T, vault, rel string, mtime time.Time) {
+	t.Helper()
+	info, err := os.Stat(filepath.Join(vault, rel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("%s mtime = %v, want %v", rel, info.ModTime(), mtime)
+	}
+	db := openTestDB(t, dbPath(vault))
+	defer db.Close()
+	var dbMtime int64
+	if err := db.QueryRow("SELECT mtime FROM nodes WHERE path = ?", rel).Scan(&dbMtime); err != nil {
+		t.Fatal(err)
+	}
+	if dbMtime != mtime.Unix() {
+		t.Errorf("%s db mtime = %d, want %d", rel, dbMtime, mtime.Unix())
+	}
+}
+
+func TestMove_PreserveMtime(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"sub/A.md": "[B](../B.md)\n",
+		"B.md":     "b\n",
+		"C.md":     "[[sub/A]]\n",
+	})
+	old := time.Unix(1_000_000_000, 0)
+	for _, rel := range []string{"sub/A.md", "C.md"} {
+		if err := os.Chtimes(filepath.Join(vault, rel), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if _, err := Move(vault, MoveOptions{From: "sub/A.md", To: "x/y/A.md", PreserveMtime: true}); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	for rel, want := range map[string]string{
+		"x/y/A.md": "[B](../../B.md)\n",
+		"C.md":     "[[x/y/A]]\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(vault, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", rel, data, want)
+		}
+		assertMtimeKept(t, vault, rel, old)
+	}
+}
+
+func TestMoveDir_PreserveMtimeConfig(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"mdhop.yaml": "rewrite:\n  preserve_mtime: true\n",
+		"src/A.md":   "[B](../B.md)\n",
+		"B.md":       "b\n",
+		"C.md":       "[[src/A]]\n",
+	})
+	old := time.Unix(1_000_000_000, 0)
+	for _, rel := range []string{"src/A.md", "C.md"} {
+		if err := os.Chtimes(filepath.Join(vault, rel), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if _, err := MoveDir(vault, MoveDirOptions{FromDir: "src", ToDir: "x/y"}); err != nil {
+		t.Fatalf("MoveDir: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(vault, "C.md")); string(data) != "[[x/y/A]]\n" {
+		t.Errorf("C.md = %q", data)
+	}
+	assertMtimeKept(t, vault, "x/y/A.md", old)
+	assertMtimeKept(t, vault, "C.md", old)
+}
+
+func TestMove_RewritesShiftLaterLinksOnLine(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"sub/A.md": "a\n",
+		"sub/B.md": "b\n",
+		"C.md":     "[[sub/A]] `[[sub/B]]` [[sub/B]]\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	// The first move lengthens [[sub/A]], so the indexed column of [[sub/B]]
+	// must follow it for the second move to find the link.
+	if _, err := Move(vault, MoveOptions{From: "sub/A.md", To: "archive/A.md"}); err != nil {
+		t.Fatalf("Move A: %v", err)
+	}
+	if _, err := Move(vault, MoveOptions{From: "sub/B.md", To: "x/B.md"}); err != nil {
+		t.Fatalf("Move B: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(vault, "C.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[[archive/A]] `[[sub/B]]` [[x/B]]\n"; string(data) != want {
+		t.Errorf("C.md = %q, want %q", data, want)
+	}
+	// The indexed columns match a fresh parse of the file.
+	db := openTestDB(t, dbPath(vault))
+	defer db.Close()
+	for _, link := range parseLinks(string(data)) {
+		var n int
+		if err := db.QueryRow(
+			`SELECT COUNT(*) FROM edges e JOIN nodes s ON s.id = e.source_id
+			 WHERE s.path = 'C.md' AND e.raw_link = ? AND e.col = ?`, link.rawLink, link.col,
+		).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Errorf("edge %s at col %d indexed %d times, want 1", link.rawLink, link.col, n)
+		}
+	}
+}
+
+func TestMove_IndexWithoutEdgeCol(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"sub/A.md": "a\n",
+		"C.md":     "[[sub/A]] and [[sub/A]]\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	db := openTestDB(t, dbPath(vault))
+	if _, err := db.Exec("ALTER TABLE edges DROP COLUMN col"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if _, err := Move(vault, MoveOptions{From: "sub/A.md", To: "x/A.md"}); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(vault, "C.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[[x/A]] and [[x/A]]\n"; string(data) != want {
+		t.Errorf("C.md = %q, want %q", data, want)
+	}
+}
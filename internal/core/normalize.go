@@ -0,0 +1,220 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// NormalizeOptions controls the normalize operation.
+type NormalizeOptions struct {
+	Style  string // "basename" (default) or "path"
+	DryRun bool
+	Files  []string // limit to these source files
+}
+
+// NormalizeResult reports the outcome of the normalize operation.
+type NormalizeResult struct {
+	Rewritten []RewrittenLink
+}
+
+// Normalize rewrites every resolvable wikilink/markdown link to one canonical
+// form. With style "basename", links use the basename when it resolves
+// unambiguously (as simplify does) and the full vault-relative path otherwise
+// (as disambiguate does). With style "path", links always use the full path.
+// Wikilinks lose redundant "./" and ".md"; markdown links keep ".md" as
+// written. Broken, ambiguous, and vault-escape links are left for repair and
+// disambiguate. Running normalize twice changes nothing the second time.
+// It works by scanning files directly (no DB required).
+func Normalize(vaultPath string, opts NormalizeOptions) (*NormalizeResult, error) {
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	if opts.Style == "" {
+		opts.Style = "basename"
+	}
+	if opts.Style != "basename" && opts.Style != "path" {
+		return nil, fmt.Errorf("invalid style: %q (must be basename or path)", opts.Style)
+	}
+
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := collectMarkdownFiles(vaultPath, cfg.Build)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateGlobPatterns(cfg.Build.ExcludePaths); err != nil {
+		return nil, err
+	}
+	files = filterBuildExcludes(files, cfg.Build.ExcludePaths)
+
+	assetFiles, err := collectAssetFiles(vaultPath, cfg.Build)
+	if err != nil {
+		return nil, err
+	}
+	assetFiles = filterBuildExcludes(assetFiles, cfg.Build.ExcludePaths)
+
+	sort.Strings(files)
+
+	nm := buildNoteResolveMaps(files)
+	am := buildAssetResolveMaps(assetFiles)
+	nm.folderFirst = cfg.Build.folderFirst()
+	if !cfg.Build.rootPriority() {
+		// A root file does not claim its basename; duplicates stay ambiguous.
+		nm.rootBasenameToPath, am.rootBasenameToPath = nil, nil
+	}
+
+	fileSet := make(map[string]bool, len(files))
+	for _, f := range files {
+		fileSet[f] = true
+	}
+	fileScope := make(map[string]bool)
+	for _, f := range opts.Files {
+		np := NormalizePath(f)
+		if !fileSet[np] {
+			return nil, fmt.Errorf("file not found: %s", np)
+		}
+		fileScope[np] = true
+	}
+	scanFiles := files
+	if len(fileScope) > 0 {
+		scanFiles = nil
+		for _, f := range files {
+			if fileScope[f] {
+				scanFiles = append(scanFiles, f)
+			}
+		}
+	}
+
+	result := &NormalizeResult{}
+	var rewrites []rewriteEntry
+
+	for _, sourcePath := range scanFiles {
+		content, err := os.ReadFile(filepath.Join(vaultPath, sourcePath))
+		if err != nil {
+			return nil, err
+		}
+		for _, lo := range parseLinks(string(content)) {
+			if lo.linkType != "wikilink" && lo.linkType != "markdown" {
+				continue
+			}
+			if lo.target == "" {
+				continue // self-link
+			}
+			if isLinkEscaping(sourcePath, lo) {
+				continue
+			}
+
+			resolvedPath, isAsset, ok := resolveScanLink(sourcePath, lo, nm, am)
+			if !ok {
+				continue // broken or ambiguous
+			}
+
+			target := resolvedPath
+			if opts.Style == "basename" {
+				var canSimplify bool
+				if isAsset {
+					canSimplify, _ = canSimplifyAsset(resolvedPath, am)
+					// A note with the same basename key would take over the basename link.
+					canSimplify = canSimplify && nm.basenameCounts[assetBasenameKey(resolvedPath)] == 0
+				} else {
+					canSimplify, _ = canSimplifyNote(sourcePath, resolvedPath, nm)
+				}
+				if canSimplify {
+					target = filepath.Base(resolvedPath)
+				}
+			}
+
+			newRawLink := rewriteRawLink(lo.rawLink, lo.linkType, target)
+			if newRawLink == lo.rawLink {
+				continue
+			}
+			rewrites = append(rewrites, rewriteEntry{
+				rawLink:    lo.rawLink,
+				linkType:   lo.linkType,
+				lineStart:  lo.lineStart,
+				col:        lo.col,
+				sourcePath: sourcePath,
+				newRawLink: newRawLink,
+			})
+		}
+	}
+
+	for _, re := range rewrites {
+		result.Rewritten = append(result.Rewritten, RewrittenLink{
+			File:    re.sourcePath,
+			OldLink: re.rawLink,
+			NewLink: re.newRawLink,
+		})
+	}
+
+	if opts.DryRun || len(rewrites) == 0 {
+		return result, nil
+	}
+
+	groups := make(map[string][]rewriteEntry)
+	for _, re := range rewrites {
+		groups[re.sourcePath] = append(groups[re.sourcePath], re)
+	}
+	if _, _, err := applyFileRewrites(vaultPath, groups, false); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resolveScanLink resolves a wikilink/markdown link against the scanned vault
+// the same way build does, returning the target path and whether it is an
+// asset. ok is false for broken links and ambiguous basename links.
+func resolveScanLink(sourcePath string, lo linkOccur, nm noteResolveMaps, am assetResolveMaps) (path string, isAsset, ok bool) {
+	if lo.isBasename {
+		lower := strings.ToLower(lo.target)
+		if prefersAsset(lo.target) && am.basenameCounts[lower] > 0 {
+			if p, ok := am.basenameToPath[lower]; ok {
+				return p, true, true
+			}
+			if p, ok := am.rootBasenameToPath[lower]; ok {
+				return p, true, true
+			}
+			return "", false, false // ambiguous asset basename
+		}
+		if p, ok := nm.localPath(sourcePath, lower); ok {
+			return p, false, true
+		}
+		if p, ok := nm.basenameToPath[lower]; ok {
+			return p, false, true
+		}
+		if p, ok := nm.rootBasenameToPath[lower]; ok {
+			return p, false, true
+		}
+		if nm.basenameCounts[lower] > 0 {
+			return "", false, false // ambiguous note basename
+		}
+		if p, ok := am.basenameToPath[lower]; ok {
+			return p, true, true
+		}
+		if p, ok := am.rootBasenameToPath[lower]; ok {
+			return p, true, true
+		}
+		return "", false, false
+	}
+
+	lower := strings.ToLower(resolveToVaultRelative(sourcePath, lo))
+	if p, ok := nm.pathSetLower[lower]; ok {
+		return p, false, true
+	}
+	if p, ok := nm.pathSetLower[lower+".md"]; ok {
+		return p, false, true
+	}
+	if p, ok := am.pathSetLower[lower]; ok {
+		return p, true, true
+	}
+	return "", false, false
+}
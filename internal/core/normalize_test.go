@@ -0,0 +1,187 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupNormalizeVault creates a vault with links in assorted non-canonical forms.
+func setupNormalizeVault(t *testing.T) string {
+	t.Helper()
+	vault := t.TempDir()
+	files := map[string]string{
+		"A.md":             "# A\n",
+		"sub/B.md":         "# B\n",
+		"sub/Dup.md":       "# Dup\n",
+		"other/Dup.md":     "# Dup\n",
+		"images/photo.png": "png",
+		"sub/Src.md": "[[./B]]\n" +
+			"[[A.md]]\n" +
+			"[[sub/B#Head|alias]]\n" +
+			"[link](../A.md)\n" +
+			"![[../images/photo.png]]\n" +
+			"[[other/Dup]]\n" +
+			"[[Dup]]\n" +
+			"[[Missing]]\n" +
+			"[[B]]\n",
+	}
+	for rel, content := range files {
+		p := filepath.Join(vault, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return vault
+}
+
+func TestNormalizeBasenameStyle(t *testing.T) {
+	vault := setupNormalizeVault(t)
+
+	result, err := Normalize(vault, NormalizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(vault, "sub/Src.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[[B]]\n" +
+		"[[A]]\n" +
+		"[[B#Head|alias]]\n" +
+		"[link](A.md)\n" +
+		"![[photo.png]]\n" +
+		"[[other/Dup]]\n" +
+		"[[Dup]]\n" +
+		"[[Missing]]\n" +
+		"[[B]]\n"
+	if string(got) != want {
+		t.Errorf("content mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	if len(result.Rewritten) != 5 {
+		t.Errorf("expected 5 rewrites, got %d: %+v", len(result.Rewritten), result.Rewritten)
+	}
+}
+
+func TestNormalizePathStyle(t *testing.T) {
+	vault := setupNormalizeVault(t)
+
+	if _, err := Normalize(vault, NormalizeOptions{Style: "path"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(vault, "sub/Src.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[[sub/B]]\n" +
+		"[[A]]\n" +
+		"[[sub/B#Head|alias]]\n" +
+		"[link](A.md)\n" +
+		"![[images/photo.png]]\n" +
+		"[[other/Dup]]\n" +
+		"[[Dup]]\n" +
+		"[[Missing]]\n" +
+		"[[sub/B]]\n"
+	if string(got) != want {
+		t.Errorf("content mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNormalizeIdempotent(t *testing.T) {
+	for _, style := range []string{"basename", "path"} {
+		t.Run(style, func(t *testing.T) {
+			vault := setupNormalizeVault(t)
+			if _, err := Normalize(vault, NormalizeOptions{Style: style}); err != nil {
+				t.Fatal(err)
+			}
+			result, err := Normalize(vault, NormalizeOptions{Style: style})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Rewritten) != 0 {
+				t.Errorf("second run rewrote links: %+v", result.Rewritten)
+			}
+		})
+	}
+}
+
+func TestNormalizeDryRun(t *testing.T) {
+	vault := setupNormalizeVault(t)
+	before, err := os.ReadFile(filepath.Join(vault, "sub/Src.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Normalize(vault, NormalizeOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rewritten) == 0 {
+		t.Error("expected rewrites to be reported")
+	}
+	after, err := os.ReadFile(filepath.Join(vault, "sub/Src.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != string(after) {
+		t.Error("dry run modified the file")
+	}
+}
+
+func TestNormalizeInvalidStyle(t *testing.T) {
+	vault := setupNormalizeVault(t)
+	_, err := Normalize(vault, NormalizeOptions{Style: "short"})
+	if err == nil || !strings.Contains(err.Error(), "invalid style") {
+		t.Errorf("expected invalid style error, got %v", err)
+	}
+}
+
+// TestNormalizeRoundTrip checks that a rebuild after normalize resolves every
+// link to the same target as before.
+func TestNormalizeRoundTrip(t *testing.T) {
+	for _, style := range []string{"basename", "path"} {
+		t.Run(style, func(t *testing.T) {
+			vault := setupNormalizeVault(t)
+			// [[Dup]] is ambiguous and would fail the build.
+			p := filepath.Join(vault, "sub/Src.md")
+			content, err := os.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, []byte(strings.Replace(string(content), "[[Dup]]\n", "", 1)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := Build(vault); err != nil {
+				t.Fatal(err)
+			}
+			before := edgeTargets(queryEdges(t, dbPath(vault), "sub/Src.md"))
+
+			if _, err := Normalize(vault, NormalizeOptions{Style: style}); err != nil {
+				t.Fatal(err)
+			}
+			if err := Build(vault); err != nil {
+				t.Fatal(err)
+			}
+			after := edgeTargets(queryEdges(t, dbPath(vault), "sub/Src.md"))
+
+			if strings.Join(before, ",") != strings.Join(after, ",") {
+				t.Errorf("targets changed:\nbefore: %v\nafter:  %v", before, after)
+			}
+		})
+	}
+}
+
+func edgeTargets(edges []edgeRow) []string {
+	var out []string
+	for _, e := range edges {
+		out = append(out, e.targetKey+e.subpath)
+	}
+	return out
+}
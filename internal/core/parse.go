@@ -11,11 +11,13 @@ type linkOccur struct {
 	target     string
 	isBasename bool
 	isRelative bool
-	linkType   string // "wikilink", "markdown", "tag", "frontmatter"
+	linkType   string // "wikilink", "markdown", "tag", "frontmatter", "frontmatter-link"
 	rawLink    string
 	subpath    string
 	lineStart  int
 	lineEnd    int
+	isEmbed    bool // ![[...]] or ![...](...)
+	col        int  // byte offset of rawLink in its line (wikilinks and markdown links in the body)
 }
 
 // parseLinks parses all links (wikilinks, markdown links, tags, frontmatter tags) from content.
@@ -44,9 +46,8 @@ func parseLinks(content string) []linkOccur {
 		if inFence {
 			continue
 		}
-		clean := stripInlineCode(lines[i])
-		out = append(out, parseWikiLinks(clean, lineNum)...)
-		out = append(out, parseMarkdownLinks(clean, lineNum)...)
+		links, clean := parseLineLinks(lines[i], lineNum)
+		out = append(out, links...)
 		// Parse tags on a line with wikilinks/markdown links removed.
 		tagLine := stripWikiLinks(stripMarkdownLinks(clean))
 		out = append(out, parseTags(tagLine, lineNum)...)
@@ -54,8 +55,58 @@ func parseLinks(content string) []linkOccur {
 	return out
 }
 
+// parseLineLinks parses the wikilinks and markdown links of one body line,
+// skipping inline code, and records where each raw link starts in line. It
+// also returns the line with inline code removed.
+func parseLineLinks(line string, lineNum int) ([]linkOccur, string) {
+	clean, offsets := stripInlineCodeOffsets(line)
+	links := append(parseWikiLinks(clean, lineNum), parseMarkdownLinks(clean, lineNum)...)
+	for i := range links {
+		links[i].col = offsets[links[i].col]
+	}
+	return links, clean
+}
+
+// parseIndexLinks parses the links that are indexed as edges: everything from
+// parseLinks plus the frontmatter link fields of cfg. With
+// cfg.IgnoreTemplateLinks, links to unexpanded template placeholders
+// ([[{{title}}]]) are dropped.
+func parseIndexLinks(content string, cfg BuildConfig) []linkOccur {
+	links := append(parseLinks(content), parseFrontmatterLinks(content, cfg.linkKeys())...)
+	if !cfg.IgnoreTemplateLinks {
+		return links
+	}
+	out := links[:0]
+	for _, l := range links {
+		if isFileLinkType(l.linkType) && isTemplateTarget(l.target) {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// isTemplateTarget reports whether a link target contains a template token
+// such as Templater's or Dataview's {{...}}.
+func isTemplateTarget(target string) bool {
+	return strings.Contains(target, "{{") || strings.Contains(target, "}}")
+}
+
+// isFileLinkType reports whether linkType targets a note or asset (as opposed to a tag).
+func isFileLinkType(linkType string) bool {
+	return linkType == "wikilink" || linkType == "markdown" || linkType == "frontmatter-link"
+}
+
 func stripInlineCode(line string) string {
+	clean, _ := stripInlineCodeOffsets(line)
+	return clean
+}
+
+// stripInlineCodeOffsets removes inline code spans from line, like
+// stripInlineCode, and maps each byte of the result to its offset in line.
+func stripInlineCodeOffsets(line string) (string, []int) {
 	var out strings.Builder
+	offsets := make([]int, 0, len(line))
 	inCode := false
 	for i := 0; i < len(line); i++ {
 		ch := line[i]
@@ -65,9 +116,10 @@ func stripInlineCode(line string) string {
 		}
 		if !inCode {
 			out.WriteByte(ch)
+			offsets = append(offsets, i)
 		}
 	}
-	return out.String()
+	return out.String(), offsets
 }
 
 // stripWikiLinks removes [[...]] from a line to avoid tag false positives.
@@ -112,6 +164,7 @@ func stripMarkdownLinks(line string) string {
 func parseWikiLinks(line string, lineNum int) []linkOccur {
 	var out []linkOccur
 	remaining := line
+	base := 0 // offset of remaining in line
 	for {
 		start := strings.Index(remaining, "[[")
 		if start == -1 {
@@ -127,6 +180,8 @@ func parseWikiLinks(line string, lineNum int) []linkOccur {
 
 		name := splitAlias(inner)
 		target, subpath := extractSubpath(name)
+		target = normalizeSeparators(target)
+		embed := start > 0 && remaining[start-1] == '!'
 
 		if target == "" && subpath != "" {
 			// [[#Heading]] — self-link
@@ -139,6 +194,8 @@ func parseWikiLinks(line string, lineNum int) []linkOccur {
 				subpath:    subpath,
 				lineStart:  lineNum,
 				lineEnd:    lineNum,
+				isEmbed:    embed,
+				col:        base + start,
 			})
 		} else if target != "" {
 			out = append(out, linkOccur{
@@ -150,16 +207,45 @@ func parseWikiLinks(line string, lineNum int) []linkOccur {
 				subpath:    subpath,
 				lineStart:  lineNum,
 				lineEnd:    lineNum,
+				isEmbed:    embed,
+				col:        base + start,
 			})
 		}
 		remaining = remaining[end+2:]
+		base += end + 2
 	}
 	return out
 }
 
 func parseMarkdownLinks(line string, lineNum int) []linkOccur {
 	var out []linkOccur
+	scanMarkdownLinks(line, func(rawLink, rawTarget string, embed bool, col int) {
+		target, subpath := extractSubpath(rawTarget)
+		target = decodeMarkdownPath(normalizeSeparators(target))
+		if target != "" && !isURL(rawTarget) {
+			out = append(out, linkOccur{
+				target:     normalizeBasename(target),
+				isBasename: isBasenameLink(target),
+				isRelative: isRelativePath(target),
+				linkType:   "markdown",
+				rawLink:    rawLink,
+				subpath:    subpath,
+				lineStart:  lineNum,
+				lineEnd:    lineNum,
+				isEmbed:    embed,
+				col:        col,
+			})
+		}
+	})
+	return out
+}
+
+// scanMarkdownLinks calls fn for each [text](target) link in line with the raw
+// link, its destination (angle brackets removed), whether it is an embed, and
+// the byte offset of the raw link in line.
+func scanMarkdownLinks(line string, fn func(rawLink, rawTarget string, embed bool, col int)) {
 	remaining := line
+	base := 0 // offset of remaining in line
 	for {
 		open := strings.Index(remaining, "[")
 		if open == -1 {
@@ -168,6 +254,7 @@ func parseMarkdownLinks(line string, lineNum int) []linkOccur {
 		// Skip if this is actually a wikilink "[[".
 		if open+1 < len(remaining) && remaining[open+1] == '[' {
 			remaining = remaining[open+2:]
+			base += open + 2
 			continue
 		}
 		mid := strings.Index(remaining[open:], "](")
@@ -175,32 +262,103 @@ func parseMarkdownLinks(line string, lineNum int) []linkOccur {
 			break
 		}
 		mid = open + mid
-		close := strings.Index(remaining[mid+2:], ")")
+		// Angle-bracket destinations "(<my note.md>)" may contain ")" or spaces,
+		// so search for the closing paren after the ">".
+		searchFrom := mid + 2
+		if strings.HasPrefix(strings.TrimLeft(remaining[searchFrom:], " "), "<") {
+			if gt := strings.Index(remaining[searchFrom:], ">"); gt != -1 {
+				searchFrom += gt + 1
+			}
+		}
+		close := strings.Index(remaining[searchFrom:], ")")
 		if close == -1 {
 			break
 		}
-		close = mid + 2 + close
-		rawTarget := strings.TrimSpace(remaining[mid+2 : close])
-		rawLink := remaining[open : close+1]
+		close = searchFrom + close
+		rawTarget, _ := unwrapAngleURL(strings.TrimSpace(remaining[mid+2 : close]))
+		fn(remaining[open:close+1], rawTarget, open > 0 && remaining[open-1] == '!', base+open)
+		remaining = remaining[close+1:]
+		base += close + 1
+	}
+}
 
-		target, subpath := extractSubpath(rawTarget)
-		if target != "" && !isURL(rawTarget) {
-			out = append(out, linkOccur{
-				target:     normalizeBasename(target),
-				isBasename: isBasenameLink(target),
-				isRelative: isRelativePath(target),
-				linkType:   "markdown",
-				rawLink:    rawLink,
-				subpath:    subpath,
-				lineStart:  lineNum,
-				lineEnd:    lineNum,
-			})
+type externalLinkOccur struct {
+	url  string
+	line int
+}
+
+// parseExternalLinks extracts http(s) URLs from markdown link destinations and
+// bare autolinks (https://... or <https://...>) in the body. Frontmatter, code
+// fences, and inline code are skipped. These are not graph edges.
+func parseExternalLinks(content string) []externalLinkOccur {
+	var out []externalLinkOccur
+	lines := strings.Split(content, "\n")
+
+	startLine := 0
+	if fmEnd := frontmatterEnd(lines); fmEnd > 0 {
+		startLine = fmEnd + 1
+	}
+
+	inFence := false
+	for i := startLine; i < len(lines); i++ {
+		lineNum := i + 1 // 1-based
+		trim := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trim, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		clean := stripInlineCode(lines[i])
+		scanMarkdownLinks(clean, func(_, rawTarget string, _ bool, _ int) {
+			// Drop an optional link title: [t](https://x "title").
+			if f := strings.Fields(rawTarget); len(f) > 0 && isURL(f[0]) {
+				out = append(out, externalLinkOccur{url: f[0], line: lineNum})
+			}
+		})
+		for _, u := range findBareURLs(stripWikiLinks(stripMarkdownLinks(clean))) {
+			out = append(out, externalLinkOccur{url: u, line: lineNum})
 		}
-		remaining = remaining[close+1:]
 	}
 	return out
 }
 
+// findBareURLs returns the http(s) URLs appearing as plain text in line. A URL
+// ends at whitespace or an angle bracket/quote; trailing sentence punctuation
+// and an unbalanced closing paren are not part of it.
+func findBareURLs(line string) []string {
+	var out []string
+	for {
+		idx := strings.Index(line, "http")
+		if idx == -1 {
+			break
+		}
+		rest := line[idx:]
+		if !isURL(rest) || (idx > 0 && isWordByte(line[idx-1])) {
+			line = line[idx+4:]
+			continue
+		}
+		end := strings.IndexAny(rest, " \t<>\"'`")
+		if end == -1 {
+			end = len(rest)
+		}
+		u := strings.TrimRight(rest[:end], ".,;:!?")
+		if strings.HasSuffix(u, ")") && strings.Count(u, "(") < strings.Count(u, ")") {
+			u = u[:len(u)-1]
+		}
+		if u != "http://" && u != "https://" {
+			out = append(out, u)
+		}
+		line = rest[end:]
+	}
+	return out
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
 // isTagRune reports whether r is allowed in a tag body (blacklist approach, Obsidian-compatible).
 func isTagRune(r rune) bool {
 	if r <= 0x20 || unicode.IsSpace(r) {
@@ -217,6 +375,10 @@ func isTagRune(r rune) bool {
 	if r >= 0x2E00 && r <= 0x2E7F {
 		return false
 	}
+	// CJK Symbols and Punctuation (ideographic space, 、。「」 and so on).
+	if r >= 0x3000 && r <= 0x303F {
+		return false
+	}
 	return true
 }
 
@@ -236,13 +398,15 @@ func parseTags(line string, lineNum int) []linkOccur {
 	var out []linkOccur
 	runes := []rune(line)
 	n := len(runes)
+	tagEnd := -1 // end of the previous tag body, before trimming slashes
 
 	for i := 0; i < n; i++ {
 		if runes[i] != '#' {
 			continue
 		}
-		// '#' must be at start of line or preceded by a space character.
-		if i > 0 && !unicode.IsSpace(runes[i-1]) {
+		// '#' must be at start of line, preceded by a space character, or
+		// directly follow another tag (#tag#other is two tags).
+		if i > 0 && !unicode.IsSpace(runes[i-1]) && i != tagEnd {
 			continue
 		}
 		// Read tag body.
@@ -254,6 +418,7 @@ func parseTags(line string, lineNum int) []linkOccur {
 		for end < n && isTagRune(runes[end]) {
 			end++
 		}
+		tagEnd = end
 		// Trim trailing slashes.
 		for end > start && runes[end-1] == '/' {
 			end--
@@ -307,21 +472,8 @@ func frontmatterEnd(lines []string) int {
 // parseFrontmatter extracts tags from YAML frontmatter.
 // lines should include the opening and closing "---".
 func parseFrontmatter(lines []string) []linkOccur {
-	if len(lines) < 3 {
-		return nil
-	}
-	// Extract YAML content between --- markers.
-	yamlContent := strings.Join(lines[1:len(lines)-1], "\n")
-
-	var doc yaml.Node
-	if err := yaml.Unmarshal([]byte(yamlContent), &doc); err != nil {
-		return nil
-	}
-	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
-		return nil
-	}
-	mapping := doc.Content[0]
-	if mapping.Kind != yaml.MappingNode {
+	mapping := frontmatterMapping(lines)
+	if mapping == nil {
 		return nil
 	}
 
@@ -398,6 +550,139 @@ func parseFrontmatter(lines []string) []linkOccur {
 	return out
 }
 
+// parseFrontmatterLinks extracts note links from the frontmatter fields named
+// by keys (e.g. "related: [Design, sub/Impl]"). Each entry is resolved like a
+// wikilink target: no "/" means basename, otherwise a vault-relative path.
+// rawLink is the entry exactly as written so moves can rewrite it in place.
+func parseFrontmatterLinks(content string, keys []string) []linkOccur {
+	if len(keys) == 0 {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+	fmEnd := frontmatterEnd(lines)
+	if fmEnd <= 0 {
+		return nil
+	}
+	mapping := frontmatterMapping(lines[:fmEnd+1])
+	if mapping == nil {
+		return nil
+	}
+	keySet := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		keySet[k] = true
+	}
+
+	offset := 1 // lines[0] is "---"
+	var out []linkOccur
+	add := func(item *yaml.Node) {
+		if item.Kind != yaml.ScalarNode {
+			return
+		}
+		raw := strings.TrimSpace(item.Value)
+		if raw == "" {
+			return
+		}
+		target := raw
+		if strings.HasSuffix(strings.ToLower(target), ".md") {
+			target = target[:len(target)-3]
+		}
+		fileLine := item.Line + offset
+		out = append(out, linkOccur{
+			target:     target,
+			isBasename: !strings.Contains(target, "/"),
+			linkType:   "frontmatter-link",
+			rawLink:    raw,
+			lineStart:  fileLine,
+			lineEnd:    fileLine,
+		})
+	}
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		key := mapping.Content[i]
+		val := mapping.Content[i+1]
+		if !keySet[key.Value] {
+			continue
+		}
+		switch val.Kind {
+		case yaml.SequenceNode:
+			for _, item := range val.Content {
+				add(item)
+			}
+		case yaml.ScalarNode:
+			add(val)
+		}
+	}
+	return out
+}
+
+// frontmatterMapping parses frontmatter lines (including the opening and closing
+// "---") and returns the top-level YAML mapping, or nil if it is not a mapping.
+func frontmatterMapping(lines []string) *yaml.Node {
+	if len(lines) < 3 {
+		return nil
+	}
+	// Extract YAML content between --- markers.
+	yamlContent := strings.Join(lines[1:len(lines)-1], "\n")
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlContent), &doc); err != nil {
+		return nil
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	mapping := doc.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	return mapping
+}
+
+// parseAliases extracts note aliases from the frontmatter "aliases" (or "alias") key.
+// Both list and comma-separated scalar forms are accepted. Duplicates are removed
+// case-insensitively, keeping the first spelling.
+func parseAliases(content string) []string {
+	lines := strings.Split(content, "\n")
+	fmEnd := frontmatterEnd(lines)
+	if fmEnd <= 0 {
+		return nil
+	}
+	mapping := frontmatterMapping(lines[:fmEnd+1])
+	if mapping == nil {
+		return nil
+	}
+
+	var out []string
+	seen := make(map[string]bool)
+	add := func(v string) {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[strings.ToLower(v)] {
+			return
+		}
+		seen[strings.ToLower(v)] = true
+		out = append(out, v)
+	}
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		key := mapping.Content[i]
+		val := mapping.Content[i+1]
+		if key.Value != "aliases" && key.Value != "alias" {
+			continue
+		}
+		switch val.Kind {
+		case yaml.SequenceNode:
+			for _, item := range val.Content {
+				if item.Kind == yaml.ScalarNode {
+					add(item.Value)
+				}
+			}
+		case yaml.ScalarNode:
+			for _, a := range strings.Split(val.Value, ",") {
+				add(a)
+			}
+		}
+	}
+	return out
+}
+
 func splitAlias(input string) string {
 	if idx := strings.Index(input, "|"); idx != -1 {
 		return input[:idx]
@@ -414,6 +699,34 @@ func extractSubpath(input string) (string, string) {
 	return input, ""
 }
 
+// normalizeSeparators turns Windows-style backslash separators in a link
+// target into "/" ("sub\B.md" -> "sub/B.md"). A backslash before a link
+// syntax character is an escape and is dropped with the character kept, and
+// a trailing one is dropped: "[[B\|alias]]" (the escaped pipe used inside
+// tables) targets "B".
+func normalizeSeparators(target string) string {
+	if !strings.Contains(target, "\\") {
+		return target
+	}
+	var b strings.Builder
+	for i := 0; i < len(target); i++ {
+		if target[i] != '\\' {
+			b.WriteByte(target[i])
+			continue
+		}
+		if i+1 == len(target) {
+			break
+		}
+		if strings.IndexByte(`\|[]()<>*`+"`", target[i+1]) >= 0 {
+			i++
+			b.WriteByte(target[i])
+			continue
+		}
+		b.WriteByte('/')
+	}
+	return b.String()
+}
+
 func normalizeBasename(input string) string {
 	lower := strings.ToLower(input)
 	if strings.HasSuffix(lower, ".md") && len(input) >= 3 {
@@ -436,3 +749,95 @@ func isRelativePath(target string) bool {
 func isURL(target string) bool {
 	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
 }
+
+type headingOccur struct {
+	level int
+	text  string
+	line  int
+}
+
+// parseHeadings extracts ATX (# Heading) and setext (Heading + ===/---) headings
+// from content. Frontmatter and code fences are skipped.
+func parseHeadings(content string) []headingOccur {
+	var out []headingOccur
+	lines := strings.Split(content, "\n")
+
+	startLine := 0
+	if fmEnd := frontmatterEnd(lines); fmEnd > 0 {
+		startLine = fmEnd + 1
+	}
+
+	inFence := false
+	// prevText is the previous line when it can serve as setext heading text.
+	prevText := ""
+	for i := startLine; i < len(lines); i++ {
+		trim := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trim, "```") {
+			inFence = !inFence
+			prevText = ""
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if level, text, ok := parseATXHeading(lines[i]); ok {
+			out = append(out, headingOccur{level: level, text: text, line: i + 1})
+			prevText = ""
+			continue
+		}
+		if prevText != "" {
+			if level := setextLevel(trim); level > 0 {
+				out = append(out, headingOccur{level: level, text: prevText, line: i})
+				prevText = ""
+				continue
+			}
+		}
+		prevText = trim
+	}
+	return out
+}
+
+// parseATXHeading parses an ATX heading line. Up to 3 leading spaces are allowed,
+// and an optional closing sequence of '#' is removed.
+func parseATXHeading(line string) (int, string, bool) {
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	if indent > 3 {
+		return 0, "", false
+	}
+	s := line[indent:]
+	level := 0
+	for level < len(s) && s[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+	rest := s[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false
+	}
+	text := strings.TrimSpace(rest)
+	// Remove closing hashes: "## Title ##" → "Title". A closing sequence must be
+	// preceded by whitespace (or be the whole text).
+	trimmed := strings.TrimRight(text, "#")
+	if trimmed == "" {
+		text = ""
+	} else if trimmed != text && (strings.HasSuffix(trimmed, " ") || strings.HasSuffix(trimmed, "\t")) {
+		text = strings.TrimSpace(trimmed)
+	}
+	return level, text, true
+}
+
+// setextLevel returns 1 for a "===" underline, 2 for a "---" underline, and 0 otherwise.
+func setextLevel(trim string) int {
+	if trim == "" {
+		return 0
+	}
+	if strings.Trim(trim, "=") == "" {
+		return 1
+	}
+	if strings.Trim(trim, "-") == "" {
+		return 2
+	}
+	return 0
+}
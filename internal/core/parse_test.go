@@ -1,6 +1,7 @@
 package core
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -131,6 +132,85 @@ func TestParseMarkdownLinkSubpath(t *testing.T) {
 	}
 }
 
+func TestParseMarkdownLinkPercentEncoded(t *testing.T) {
+	tests := []struct {
+		line, target string
+	}{
+		{"[n](sub/My%20Note.md)", "sub/My Note"},
+		{"[n](My%20Note.md#Sec)", "My Note"},
+		{"[n](100%.md)", "100%"}, // invalid escape kept as written
+	}
+	for _, tt := range tests {
+		links := parseLinks(tt.line + "\n")
+		if len(links) != 1 {
+			t.Fatalf("%s: expected 1 link, got %d", tt.line, len(links))
+		}
+		if links[0].target != tt.target {
+			t.Errorf("%s: target = %q, want %q", tt.line, links[0].target, tt.target)
+		}
+		if links[0].rawLink != tt.line {
+			t.Errorf("%s: rawLink = %q", tt.line, links[0].rawLink)
+		}
+	}
+}
+
+func TestParseLinkBackslashSeparators(t *testing.T) {
+	tests := []struct {
+		line       string
+		target     string
+		isBasename bool
+		isRelative bool
+	}{
+		{`[link](sub\B.md)`, "sub/B", false, false},
+		{`[up](..\Root.md#Sec)`, "../Root", false, true},
+		{`[[sub\B]]`, "sub/B", false, false},
+		{`[[.\B|alias]]`, "./B", false, true},
+		{`[[B\|alias]]`, "B", true, false},          // escaped pipe inside a table
+		{`[[sub\B\|alias]]`, "sub/B", false, false}, // both in one link
+		{`[n](a\*b.md)`, "a*b", true, false},        // escape keeps the character
+	}
+	for _, tt := range tests {
+		links := parseLinks(tt.line + "\n")
+		if len(links) != 1 {
+			t.Fatalf("%s: expected 1 link, got %d", tt.line, len(links))
+		}
+		l := links[0]
+		if l.target != tt.target || l.isBasename != tt.isBasename || l.isRelative != tt.isRelative {
+			t.Errorf("%s: got target=%q basename=%v relative=%v, want %q %v %v",
+				tt.line, l.target, l.isBasename, l.isRelative, tt.target, tt.isBasename, tt.isRelative)
+		}
+		if l.rawLink != tt.line {
+			t.Errorf("%s: rawLink = %q, want unchanged", tt.line, l.rawLink)
+		}
+	}
+}
+
+func TestParseMarkdownLinkAngleBrackets(t *testing.T) {
+	tests := []struct {
+		line    string
+		target  string
+		subpath string
+	}{
+		{"[t](<my note.md>)", "my note", ""},
+		{"[t](<sub/my note.md#Intro>)", "sub/my note", "#Intro"},
+		{"[t](<my note.md>#Intro)", "my note", "#Intro"},
+		{"[t](<a (draft).md>)", "a (draft)", ""},
+	}
+	for _, tt := range tests {
+		links := parseLinks(tt.line + "\n")
+		if len(links) != 1 {
+			t.Fatalf("%s: expected 1 link, got %d", tt.line, len(links))
+		}
+		l := links[0]
+		if l.target != tt.target || l.subpath != tt.subpath {
+			t.Errorf("%s: target/subpath = %q/%q, want %q/%q", tt.line, l.target, l.subpath, tt.target, tt.subpath)
+		}
+		if l.rawLink != tt.line {
+			t.Errorf("%s: rawLink = %q", tt.line, l.rawLink)
+		}
+	}
+}
+
 func TestParseTagBasic(t *testing.T) {
 	links := parseLinks("Hello #tag world\n")
 	tags := filterByType(links, "tag")
@@ -437,6 +517,58 @@ func TestParseTagTrailingPeriod(t *testing.T) {
 	}
 }
 
+func TestParseTagTrailingPunctuation(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"End of sentence #tag.", []string{"#tag"}},
+		{"#tag, #other", []string{"#tag", "#other"}},
+		{"(see #tag)", []string{"#tag"}},
+		{"see #tag) and #other).", []string{"#tag", "#other"}},
+		{"#tag:", []string{"#tag"}},
+		{"#tag?!", []string{"#tag"}},
+		{"#a/b-c.", []string{"#a", "#a/b-c"}},
+		{"#a/b_c,", []string{"#a", "#a/b_c"}},
+		{"#日本語。", []string{"#日本語"}},
+		{"#日本語、 #タグ」", []string{"#日本語", "#タグ"}},
+	}
+	for _, tt := range tests {
+		tags := filterByType(parseLinks(tt.line+"\n"), "tag")
+		got := []string{}
+		for _, tag := range tags {
+			got = append(got, tag.target)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q: tags = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseTagAdjacent(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"#tag#other", []string{"#tag", "#other"}},
+		{"#a/b#c", []string{"#a", "#a/b", "#c"}},
+		{"#a/#b", []string{"#a", "#b"}},
+		{"#tag##other", []string{"#tag"}},
+		{"x#tag#other", []string{}},
+		{"#tag.#other", []string{"#tag"}},
+	}
+	for _, tt := range tests {
+		tags := filterByType(parseLinks(tt.line+"\n"), "tag")
+		got := []string{}
+		for _, tag := range tags {
+			got = append(got, tag.target)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q: tags = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
 func TestParseTagSlashFirst(t *testing.T) {
 	links := parseLinks("#/tag\n")
 	tags := filterByType(links, "tag")
@@ -477,6 +609,81 @@ func TestParseTagNBSP(t *testing.T) {
 	}
 }
 
+func TestParseHeadingsATXAndSetext(t *testing.T) {
+	content := "---\ntags: [a]\n---\n# Title\n\nSetext One\n===\n\n## Details ##\n\nSetext Two\n---\n### C# Notes\n"
+	got := parseHeadings(content)
+	want := []headingOccur{
+		{level: 1, text: "Title", line: 4},
+		{level: 1, text: "Setext One", line: 6},
+		{level: 2, text: "Details", line: 9},
+		{level: 2, text: "Setext Two", line: 11},
+		{level: 3, text: "C# Notes", line: 13},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d headings, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("heading[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseHeadingsCodeFenceExcluded(t *testing.T) {
+	got := parseHeadings("```\n# not a heading\nText\n---\n```\n")
+	if len(got) != 0 {
+		t.Errorf("expected no headings, got %+v", got)
+	}
+}
+
+func TestParseHeadingsThematicBreakNotSetext(t *testing.T) {
+	got := parseHeadings("Para\n\n---\n")
+	if len(got) != 0 {
+		t.Errorf("expected no headings, got %+v", got)
+	}
+}
+
+func TestParseHeadingsRequiresSpace(t *testing.T) {
+	got := parseHeadings("#tag\n####### seven\n#\n")
+	if len(got) != 1 || got[0].level != 1 || got[0].text != "" {
+		t.Errorf("expected only empty level-1 heading, got %+v", got)
+	}
+}
+
+func TestParseAliasesList(t *testing.T) {
+	got := parseAliases("---\naliases:\n  - Foo\n  - foo\n  - Bar Baz\n---\n# Note\n")
+	want := []string{"Foo", "Bar Baz"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("aliases = %v, want %v", got, want)
+	}
+}
+
+func TestParseAliasesScalar(t *testing.T) {
+	got := parseAliases("---\nalias: One, Two\n---\n")
+	if len(got) != 2 || got[0] != "One" || got[1] != "Two" {
+		t.Errorf("aliases = %v, want [One Two]", got)
+	}
+}
+
+func TestParseAliasesNoFrontmatter(t *testing.T) {
+	if got := parseAliases("aliases: [X]\n"); got != nil {
+		t.Errorf("aliases = %v, want nil", got)
+	}
+}
+
+func TestParseEmbedFlag(t *testing.T) {
+	links := parseLinks("![[A]] [[B]] ![img](c.png) [d](D.md)\n")
+	want := map[string]bool{"[[A]]": true, "[[B]]": false, "[img](c.png)": true, "[d](D.md)": false}
+	if len(links) != len(want) {
+		t.Fatalf("expected %d links, got %+v", len(want), links)
+	}
+	for _, l := range links {
+		if l.isEmbed != want[l.rawLink] {
+			t.Errorf("%s isEmbed = %v, want %v", l.rawLink, l.isEmbed, want[l.rawLink])
+		}
+	}
+}
+
 func filterByType(links []linkOccur, linkType string) []linkOccur {
 	var out []linkOccur
 	for _, l := range links {
@@ -486,3 +693,91 @@ func filterByType(links []linkOccur, linkType string) []linkOccur {
 	}
 	return out
 }
+
+func TestParseFrontmatterLinksInlineArray(t *testing.T) {
+	links := parseFrontmatterLinks("---\nrelated: [Design, sub/Impl]\n---\n", []string{"related"})
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %d: %+v", len(links), links)
+	}
+	if links[0].target != "Design" || !links[0].isBasename || links[0].linkType != "frontmatter-link" {
+		t.Errorf("link[0] = %+v, want basename Design", links[0])
+	}
+	if links[1].target != "sub/Impl" || links[1].isBasename {
+		t.Errorf("link[1] = %+v, want path sub/Impl", links[1])
+	}
+	if links[0].lineStart != 2 || links[1].lineStart != 2 {
+		t.Errorf("lineStart = %d/%d, want 2", links[0].lineStart, links[1].lineStart)
+	}
+}
+
+func TestParseFrontmatterLinksBlockList(t *testing.T) {
+	links := parseFrontmatterLinks("---\nrelated:\n  - Design\n  - sub/Impl.md\n---\n", []string{"related"})
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %d: %+v", len(links), links)
+	}
+	if links[0].lineStart != 3 || links[1].lineStart != 4 {
+		t.Errorf("lineStart = %d/%d, want 3/4", links[0].lineStart, links[1].lineStart)
+	}
+	// .md is dropped from the target but kept in rawLink for rewriting.
+	if links[1].target != "sub/Impl" || links[1].rawLink != "sub/Impl.md" {
+		t.Errorf("link[1] = %+v, want target sub/Impl, rawLink sub/Impl.md", links[1])
+	}
+}
+
+func TestParseFrontmatterLinksKeys(t *testing.T) {
+	content := "---\nrelated: [A]\nsee: [B]\n---\n"
+	if got := parseFrontmatterLinks(content, []string{"see"}); len(got) != 1 || got[0].target != "B" {
+		t.Errorf("links = %+v, want only B", got)
+	}
+	if got := parseFrontmatterLinks(content, nil); got != nil {
+		t.Errorf("links = %+v, want nil for no keys", got)
+	}
+}
+
+func TestParseExternalLinks(t *testing.T) {
+	content := "---\nsource: https://frontmatter.example\n---\n" +
+		"[site](http://example.com) and [note](note.md)\n" +
+		"![img](https://example.com/a.png \"title\") [[Wiki]]\n" +
+		"bare https://example.org/x_(y). <https://example.net>\n" +
+		"`http://code.example` xhttp://glued.example\n" +
+		"```\nhttp://fenced.example\n```\n"
+	got := parseExternalLinks(content)
+	want := []externalLinkOccur{
+		{url: "http://example.com", line: 4},
+		{url: "https://example.com/a.png", line: 5},
+		{url: "https://example.org/x_(y)", line: 6},
+		{url: "https://example.net", line: 6},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("external links = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("link[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseLinksIgnoresExternal(t *testing.T) {
+	for _, l := range parseLinks("[site](http://example.com) [note](note.md)\n") {
+		if isURL(l.target) {
+			t.Errorf("external URL parsed as link: %+v", l)
+		}
+	}
+}
+
+func TestParseLinksColumn(t *testing.T) {
+	line := "x `[[A]]` [[A]] ![b](B.md) [[A]]"
+	links := parseLinks(line + "\n")
+	var got []int
+	for _, l := range links {
+		if line[l.col:l.col+len(l.rawLink)] != l.rawLink {
+			t.Errorf("%s: col %d points at %q", l.rawLink, l.col, line[l.col:])
+		}
+		got = append(got, l.col)
+	}
+	// Wikilinks first, then markdown links, as parsed.
+	if want := []int{10, 27, 17}; len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("cols = %v, want %v", got, want)
+	}
+}
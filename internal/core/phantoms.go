@@ -0,0 +1,82 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// PhantomRefs is a phantom node with the links that reference it.
+type PhantomRefs struct {
+	Name     string
+	Count    int      // number of edges pointing at the phantom
+	RawLinks []string // distinct raw links used, sorted
+}
+
+// ListPhantoms returns every phantom with its reference count and the
+// distinct raw links used for it, most referenced first (ties by name).
+// [[Foo]], [[foo]] and [[Foo|bar]] all land on one phantom, so RawLinks shows
+// whether it is a missing note or a set of typos.
+func ListPhantoms(vaultPath string) ([]PhantomRefs, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(
+		`SELECT p.id, p.name, e.raw_link
+		 FROM nodes p
+		 LEFT JOIN edges e ON e.target_id = p.id
+		 WHERE p.type = 'phantom'
+		 ORDER BY p.id, e.raw_link`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var phantoms []PhantomRefs
+	lastID := int64(-1)
+	for rows.Next() {
+		var id int64
+		var name string
+		var raw *string
+		if err := rows.Scan(&id, &name, &raw); err != nil {
+			return nil, err
+		}
+		if id != lastID {
+			phantoms = append(phantoms, PhantomRefs{Name: name})
+			lastID = id
+		}
+		if raw == nil {
+			continue
+		}
+		p := &phantoms[len(phantoms)-1]
+		p.Count++
+		if n := len(p.RawLinks); n == 0 || p.RawLinks[n-1] != *raw {
+			p.RawLinks = append(p.RawLinks, *raw)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(phantoms, func(i, j int) bool {
+		if phantoms[i].Count != phantoms[j].Count {
+			return phantoms[i].Count > phantoms[j].Count
+		}
+		return phantoms[i].Name < phantoms[j].Name
+	})
+	return phantoms, nil
+}
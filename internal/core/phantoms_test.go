@@ -0,0 +1,54 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListPhantoms(t *testing.T) {
+	vault := copyVault(t, "vault_build_phantom")
+	buildVault(t, vault)
+
+	got, err := ListPhantoms(vault)
+	if err != nil {
+		t.Fatalf("ListPhantoms: %v", err)
+	}
+	want := []PhantomRefs{
+		{Name: "Missing", Count: 1, RawLinks: []string{"[[Missing|alias]]"}},
+		{Name: "NonExistent", Count: 1, RawLinks: []string{"[[NonExistent]]"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("phantoms = %+v, want %+v", got, want)
+	}
+}
+
+func TestListPhantoms_RawLinkVariants(t *testing.T) {
+	vault := copyVault(t, "vault_build_phantom")
+	writeVaultFiles(t, vault, map[string]string{
+		"C.md": "[[Missing]]\n[[missing]]\n[[Missing|alias]]\n[[Missing]]\n",
+	})
+	buildVault(t, vault)
+
+	got, err := ListPhantoms(vault)
+	if err != nil {
+		t.Fatalf("ListPhantoms: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("phantoms = %+v, want 2", got)
+	}
+	// Missing is referenced most, so it comes first.
+	want := PhantomRefs{
+		Name:     "Missing",
+		Count:    5,
+		RawLinks: []string{"[[Missing]]", "[[Missing|alias]]", "[[missing]]"},
+	}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("phantoms[0] = %+v, want %+v", got[0], want)
+	}
+}
+
+func TestListPhantoms_NoDB(t *testing.T) {
+	if _, err := ListPhantoms(t.TempDir()); err == nil {
+		t.Fatal("expected error without index")
+	}
+}
@@ -3,38 +3,62 @@ package core
 import (
 	"bufio"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // EntrySpec specifies the entry node for a query.
 type EntrySpec struct {
-	File    string // vault-relative path
-	Tag     string // tag name (# optional)
-	Phantom string // phantom name
-	Name    string // auto-detect: #tag → tag, otherwise note → phantom
+	File    string   // vault-relative path
+	Tag     string   // tag name (# optional)
+	Tags    []string // tag intersection (# optional); use QueryTags
+	Phantom string   // phantom name
+	Asset   string   // asset path, or basename resolved like a basename link
+	Name    string   // auto-detect: #tag → tag, otherwise note → phantom
+	// Heading narrows backlinks to links into this heading of the entry
+	// note ("Details" or "#Details"): edges whose subpath ends at it, as
+	// [[Impl#Details]] or [[Impl#Impl#Details]] do. Other fields are
+	// unaffected. Requires a note entry.
+	Heading string
 }
 
 // QueryOptions controls which fields to return and their limits.
 type QueryOptions struct {
-	Fields          []string       // nil/empty = all standard fields
-	IncludeHead     int            // 0 = skip
-	IncludeSnippet  int            // 0 = skip
-	MaxBacklinks    int            // default 100
-	MaxTwoHop       int            // default 100
-	MaxViaPerTarget int            // default 10
-	Exclude         *ExcludeFilter // nil = no exclusion
+	Fields              []string       // nil/empty = all standard fields
+	IncludeHead         int            // 0 = skip
+	IncludeFrontmatter  bool           // head: return the first IncludeHead lines as-is instead of skipping frontmatter
+	IncludeSnippet      int            // 0 = skip (unless SnippetMode is "paragraph")
+	SnippetMode         string         // "" or "lines" = IncludeSnippet lines around the link; "paragraph" = enclosing paragraph
+	SnippetQuery        string         // "" = path order; otherwise rank snippets by term frequency
+	SnippetGutter       bool           // prefix each snippet line with its line number
+	MaxBacklinks        int            // default 100
+	Offset              int            // backlinks to skip before MaxBacklinks applies
+	MaxTwoHop           int            // default 100
+	MaxViaPerTarget     int            // default 10
+	SortByWeight        bool           // order twohop-ranked targets by weight (descending) instead of path
+	Positions           bool           // also return backlink/outgoing link positions
+	PerEdge             bool           // positions: one entry per link occurrence (implies Positions); default first per node
+	TagsWithLocation    bool           // tags: also return where each tag occurs (TagLocations)
+	OutgoingTargetTypes []string       // nil = all; otherwise only outgoing links to these node types (note, phantom, asset)
+	IncludeSelf         bool           // keep the entry's self-links ([[#Heading]]) in backlinks and outgoing
+	AllowAmbiguous      bool           // ambiguous EntrySpec.Name or Asset: return Candidates instead of an error
+	ReparseStale        bool           // head/snippet: read files changed since the last build instead of failing (index untouched)
+	Exclude             *ExcludeFilter // nil = no exclusion
 }
 
 // NodeInfo describes a node in the graph.
 type NodeInfo struct {
-	Type   string // "note", "phantom", "tag", "asset"
-	Name   string
-	Path   string // note/asset only
-	Exists bool
+	Type    string // "note", "phantom", "tag", "asset"
+	Name    string
+	Path    string // note/asset only
+	Exists  bool
+	Aliases []string // frontmatter aliases; query entry note only
 }
 
 // TwoHopEntry represents a via node and the targets reachable through it.
@@ -43,6 +67,31 @@ type TwoHopEntry struct {
 	Targets []NodeInfo
 }
 
+// TwoHopTarget is a two-hop target with the via nodes connecting the entry to it.
+// Weight is the number of distinct vias (co-citation strength); Vias is capped
+// by MaxViaPerTarget.
+type TwoHopTarget struct {
+	Target NodeInfo
+	Weight int
+	Vias   []NodeInfo
+}
+
+// LinkPosition is a backlink or outgoing link with its location in the source
+// note: the backlink's source for backlinks, the entry note for outgoing.
+type LinkPosition struct {
+	Node      NodeInfo
+	LineStart int
+	LineEnd   int
+	RawLink   string
+}
+
+// TagLocation is an occurrence of one of the entry note's tags.
+type TagLocation struct {
+	Tag    string // tag name (with #), as in QueryResult.Tags
+	Line   int    // 1-based
+	Source string // "inline" or "frontmatter"
+}
+
 // SnippetEntry represents lines surrounding a link occurrence in a source file.
 type SnippetEntry struct {
 	SourcePath string
@@ -51,64 +100,186 @@ type SnippetEntry struct {
 	Lines      []string
 }
 
+// Heading is a heading in a note's outline.
+type Heading struct {
+	Level int
+	Text  string
+	Line  int // 1-based
+}
+
 // QueryResult contains all requested fields for a query.
 type QueryResult struct {
-	Entry     NodeInfo
-	Backlinks []NodeInfo     // nil = not requested
-	Outgoing  []NodeInfo     // nil = not requested
-	TwoHop    []TwoHopEntry  // nil = not requested
-	Tags      []string       // nil = not requested
-	Head      []string       // nil = not requested
-	Snippets  []SnippetEntry // nil = not requested
+	Entry             NodeInfo       // zero when Candidates is set
+	Candidates        []NodeInfo     // ambiguous name matches (QueryOptions.AllowAmbiguous); other fields unset
+	ViaAlias          string         // alias matched by EntrySpec.Name ("" = not resolved via alias)
+	Title             string         // first heading text, else the basename; note entry only
+	Backlinks         []NodeInfo     // nil = not requested
+	TotalBacklinks    int            // backlink count before Offset/MaxBacklinks paging
+	Outgoing          []NodeInfo     // nil = not requested
+	BacklinkPositions []LinkPosition // nil = not requested (QueryOptions.Positions)
+	OutgoingPositions []LinkPosition // nil = not requested (QueryOptions.Positions)
+	TwoHop            []TwoHopEntry  // nil = not requested
+	TwoHopRanked      []TwoHopTarget // nil = not requested (opt-in via Fields)
+	Tags              []string       // nil = not requested
+	TagLocations      []TagLocation  // nil = not requested (QueryOptions.TagsWithLocation)
+	Headings          []Heading      // nil = not requested
+	Head              []string       // nil = not requested
+	Snippets          []SnippetEntry // nil = not requested
+}
+
+// TagQueryResult contains the notes that carry every tag of a tag intersection query.
+type TagQueryResult struct {
+	Tags  []string // normalized tag names (with #)
+	Notes []NodeInfo
+}
+
+// TagTreeNode is a tag with the notes tagged at it and its descendant tags.
+type TagTreeNode struct {
+	Tag      string     // tag name (with #)
+	Notes    []NodeInfo // notes whose most specific tag in this subtree is Tag
+	Children []TagTreeNode
+}
+
+// TagTreeResult is the descendant tag tree of a tag entry.
+type TagTreeResult struct {
+	Root TagTreeNode
+}
+
+// TagLevel is an ancestor tag with the notes tagged at it.
+type TagLevel struct {
+	Tag   string     // tag name (with #)
+	Notes []NodeInfo // notes whose most specific tag under the top-level tag is Tag
+}
+
+// TagParentsResult is the ancestor chain of a tag entry.
+type TagParentsResult struct {
+	Tag       string     // the entry tag
+	Ancestors []TagLevel // outermost first; empty for a top-level tag
 }
 
 // Query returns related information for the given entry node.
 func Query(vaultPath string, entry EntrySpec, opts QueryOptions) (*QueryResult, error) {
+	if err := validateQueryOptions(opts); err != nil {
+		return nil, err
+	}
+
 	dbp := dbPath(vaultPath)
 	if _, err := os.Stat(dbp); os.IsNotExist(err) {
 		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
 	}
 
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
 	db, err := openDBAt(dbp)
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
 
-	nodeID, info, err := findEntryNode(db, entry)
+	return queryDB(db, vaultPath, entry, opts)
+}
+
+func validateQueryOptions(opts QueryOptions) error {
+	switch opts.SnippetMode {
+	case "", "lines", "paragraph":
+	default:
+		return fmt.Errorf("unknown snippet mode: %s (want lines or paragraph)", opts.SnippetMode)
+	}
+	for _, t := range opts.OutgoingTargetTypes {
+		switch t {
+		case "note", "phantom", "asset":
+		default:
+			return fmt.Errorf("unknown outgoing target type: %s (want note, phantom or asset)", t)
+		}
+	}
+	return nil
+}
+
+// outgoingTypeSQL returns the condition restricting outgoing targets to
+// types, or to every linkable type when types is empty.
+func outgoingTypeSQL(types []string) (string, []any) {
+	if len(types) == 0 {
+		return ` AND n.type IN ('note','phantom','asset')`, nil
+	}
+	args := make([]any, len(types))
+	for i, t := range types {
+		args[i] = t
+	}
+	return ` AND n.type IN (?` + strings.Repeat(",?", len(types)-1) + `)`, args
+}
+
+// queryDB runs Query against an open index.
+func queryDB(db dbExecer, vaultPath string, entry EntrySpec, opts QueryOptions) (*QueryResult, error) {
+	cfg, err := LoadConfig(vaultPath)
 	if err != nil {
 		return nil, err
 	}
+	nodeID, result, err := queryEntryResult(db, entry, cfg.Build)
+	if err != nil {
+		var amb *ambiguousNameError
+		if opts.AllowAmbiguous && errors.As(err, &amb) {
+			return &QueryResult{Candidates: amb.candidates}, nil
+		}
+		return nil, err
+	}
+	info := result.Entry
 
 	if opts.MaxBacklinks <= 0 {
 		opts.MaxBacklinks = 100
 	}
+	if opts.Offset < 0 {
+		opts.Offset = 0
+	}
 	if opts.MaxTwoHop <= 0 {
 		opts.MaxTwoHop = 100
 	}
 	if opts.MaxViaPerTarget <= 0 {
 		opts.MaxViaPerTarget = 10
 	}
-
-	result := &QueryResult{Entry: info}
+	if opts.PerEdge {
+		opts.Positions = true
+	}
 
 	ef := opts.Exclude
 
 	if isFieldActive("backlinks", opts.Fields) {
-		bl, err := queryBacklinks(db, nodeID, opts.MaxBacklinks, ef)
+		bl, err := queryBacklinks(db, nodeID, opts.MaxBacklinks, opts.Offset, opts.IncludeSelf, entry.Heading, ef)
 		if err != nil {
 			return nil, err
 		}
 		result.Backlinks = bl
+		total, err := countBacklinks(db, nodeID, opts.IncludeSelf, entry.Heading, ef)
+		if err != nil {
+			return nil, err
+		}
+		result.TotalBacklinks = total
+		if opts.Positions {
+			pos, err := queryLinkPositions(db, nodeID, true, opts.PerEdge, opts.MaxBacklinks, opts.Offset, nil, opts.IncludeSelf, entry.Heading, ef)
+			if err != nil {
+				return nil, err
+			}
+			result.BacklinkPositions = pos
+		}
 	}
 
 	if isFieldActive("outgoing", opts.Fields) {
 		if info.Type == "note" {
-			og, err := queryOutgoing(db, nodeID, ef)
+			og, err := queryOutgoing(db, nodeID, opts.OutgoingTargetTypes, opts.IncludeSelf, ef)
 			if err != nil {
 				return nil, err
 			}
 			result.Outgoing = og
+			if opts.Positions {
+				pos, err := queryLinkPositions(db, nodeID, false, opts.PerEdge, -1, 0, opts.OutgoingTargetTypes, opts.IncludeSelf, "", ef)
+				if err != nil {
+					return nil, err
+				}
+				result.OutgoingPositions = pos
+			}
 		}
 	}
 
@@ -119,6 +290,33 @@ func Query(vaultPath string, entry EntrySpec, opts QueryOptions) (*QueryResult,
 				return nil, err
 			}
 			result.Tags = tags
+			if opts.TagsWithLocation {
+				locs, err := queryTagLocations(db, nodeID, tags, ef)
+				if err != nil {
+					return nil, err
+				}
+				result.TagLocations = locs
+			}
+		}
+	}
+
+	if isFieldActive("title", opts.Fields) {
+		if info.Type == "note" {
+			title, err := queryTitle(db, nodeID, info.Name)
+			if err != nil {
+				return nil, err
+			}
+			result.Title = title
+		}
+	}
+
+	if isFieldActive("headings", opts.Fields) {
+		if info.Type == "note" {
+			hs, err := queryHeadings(db, nodeID)
+			if err != nil {
+				return nil, err
+			}
+			result.Headings = hs
 		}
 	}
 
@@ -130,9 +328,17 @@ func Query(vaultPath string, entry EntrySpec, opts QueryOptions) (*QueryResult,
 		result.TwoHop = th
 	}
 
+	if isFieldExplicit("twohop-ranked", opts.Fields) {
+		ranked, err := queryTwoHopRanked(db, nodeID, info.Type, opts.MaxTwoHop, opts.MaxViaPerTarget, opts.SortByWeight, ef)
+		if err != nil {
+			return nil, err
+		}
+		result.TwoHopRanked = ranked
+	}
+
 	if isFieldActive("head", opts.Fields) && opts.IncludeHead > 0 {
 		if info.Type == "note" && info.Exists {
-			head, err := readHead(db, vaultPath, nodeID, opts.IncludeHead)
+			head, err := readHead(db, vaultPath, nodeID, opts.IncludeHead, opts.IncludeFrontmatter, opts.ReparseStale)
 			if err != nil {
 				return nil, err
 			}
@@ -140,19 +346,366 @@ func Query(vaultPath string, entry EntrySpec, opts QueryOptions) (*QueryResult,
 		}
 	}
 
-	if isFieldActive("snippet", opts.Fields) && opts.IncludeSnippet > 0 {
-		snippets, err := readSnippets(db, vaultPath, nodeID, opts.IncludeSnippet, ef)
+	if isFieldActive("snippet", opts.Fields) && (opts.IncludeSnippet > 0 || opts.SnippetMode == "paragraph") {
+		snippets, err := readSnippets(db, vaultPath, nodeID, opts.IncludeSnippet, opts.SnippetMode == "paragraph", opts.ReparseStale, ef)
 		if err != nil {
 			return nil, err
 		}
+		if opts.SnippetQuery != "" {
+			rankSnippets(snippets, opts.SnippetQuery)
+		}
+		if opts.SnippetGutter {
+			numberSnippetLines(snippets)
+		}
 		result.Snippets = snippets
 	}
 
 	return result, nil
 }
 
-// findEntryNode resolves an EntrySpec to a node ID and NodeInfo.
-func findEntryNode(db dbExecer, spec EntrySpec) (int64, NodeInfo, error) {
+// QueryTags returns the notes whose tag edges cover every tag in entry.Tags.
+// Nested tags match their ancestors (#status matches a note tagged #status/active),
+// since build expands nested tags into one edge per level.
+func QueryTags(vaultPath string, entry EntrySpec, opts QueryOptions) (*TagQueryResult, error) {
+	if len(entry.Tags) == 0 {
+		return nil, fmt.Errorf("no tags specified")
+	}
+	if entry.File != "" || entry.Tag != "" || entry.Phantom != "" || entry.Asset != "" || entry.Name != "" {
+		return nil, fmt.Errorf("multiple entry specs: --tags cannot be combined with --file, --tag, --phantom, --asset, --name")
+	}
+
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	result := &TagQueryResult{}
+	seen := make(map[string]bool)
+	var keys []any
+	for _, t := range entry.Tags {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !strings.HasPrefix(t, "#") {
+			t = "#" + t
+		}
+		key, err := lookupTagKey(db, t)
+		if err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result.Tags = append(result.Tags, t)
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no tags specified")
+	}
+
+	notes, err := queryNotesWithAllTags(db, keys, opts.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	result.Notes = notes
+	return result, nil
+}
+
+func queryNotesWithAllTags(db dbExecer, tagKeys []any, ef *ExcludeFilter) ([]NodeInfo, error) {
+	placeholders := strings.Repeat("?,", len(tagKeys))
+	placeholders = placeholders[:len(placeholders)-1]
+
+	q := fmt.Sprintf(`SELECT n.type, n.name, COALESCE(n.path,''), n.exists_flag
+		 FROM edges e
+		 JOIN nodes n ON n.id = e.source_id
+		 JOIN nodes t ON t.id = e.target_id
+		 WHERE n.type = 'note' AND t.type = 'tag' AND t.node_key IN (%s)`, placeholders)
+	args := append([]any{}, tagKeys...)
+
+	if ef != nil {
+		pathSQL, pathArgs := ef.PathExcludeSQL("n.path")
+		q += pathSQL
+		args = append(args, pathArgs...)
+	}
+
+	q += ` GROUP BY n.id HAVING COUNT(DISTINCT t.id) = ? ORDER BY n.path`
+	args = append(args, len(tagKeys))
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []NodeInfo{}
+	for rows.Next() {
+		var typ, name, path string
+		var exists int
+		if err := rows.Scan(&typ, &name, &path, &exists); err != nil {
+			return nil, err
+		}
+		result = append(result, NodeInfo{Type: typ, Name: name, Path: path, Exists: exists == 1})
+	}
+	return result, rows.Err()
+}
+
+// QueryTagTree returns entry.Tag with every descendant tag (#a → #a/b, #a/b/c)
+// and the notes tagged at each. Since build expands nested tags into one edge
+// per level, each note is listed only under its most specific tags.
+func QueryTagTree(vaultPath string, entry EntrySpec, opts QueryOptions) (*TagTreeResult, error) {
+	if entry.Tag == "" {
+		return nil, fmt.Errorf("tag tree requires a tag entry")
+	}
+	if entry.File != "" || len(entry.Tags) > 0 || entry.Phantom != "" || entry.Asset != "" || entry.Name != "" {
+		return nil, fmt.Errorf("multiple entry specs: tag tree takes only --tag")
+	}
+
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	_, info, err := findEntryByTag(db, entry.Tag)
+	if err != nil {
+		return nil, err
+	}
+	// Tags are grouped by node key: lower-cased unless build.tag_case is
+	// preserve, where #A/x is not a descendant of #a.
+	rootKey, err := lookupTagKey(db, info.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	q := `SELECT n.id, n.type, n.name, COALESCE(n.path,''), n.exists_flag, t.name, t.node_key
+		 FROM edges e
+		 JOIN nodes n ON n.id = e.source_id
+		 JOIN nodes t ON t.id = e.target_id
+		 WHERE n.type = 'note' AND t.type = 'tag'
+		 AND (t.node_key = ? OR t.node_key GLOB ?)`
+	// Tag names cannot contain GLOB metacharacters (* ? [).
+	args := []any{rootKey, rootKey + "/*"}
+	if ef := opts.Exclude; ef != nil {
+		pathSQL, pathArgs := ef.PathExcludeSQL("n.path")
+		q += pathSQL
+		args = append(args, pathArgs...)
+		tagSQL, tagArgs := ef.TagExcludeSQL("t.name")
+		q += tagSQL
+		args = append(args, tagArgs...)
+	}
+	q += ` ORDER BY n.path`
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tagNames := map[string]string{rootKey: info.Name} // node key → display name
+	notes := make(map[int64]NodeInfo)
+	var noteOrder []int64
+	noteTags := make(map[int64][]string)
+	for rows.Next() {
+		var id int64
+		var typ, name, path, tag, key string
+		var exists int
+		if err := rows.Scan(&id, &typ, &name, &path, &exists, &tag, &key); err != nil {
+			return nil, err
+		}
+		if _, ok := tagNames[key]; !ok {
+			tagNames[key] = tag
+		}
+		if _, ok := notes[id]; !ok {
+			notes[id] = NodeInfo{Type: typ, Name: name, Path: path, Exists: exists == 1}
+			noteOrder = append(noteOrder, id)
+		}
+		noteTags[id] = append(noteTags[id], key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Assign each note to its leaf tags so ancestors don't double-count it.
+	tagged := make(map[string][]NodeInfo)
+	for _, id := range noteOrder {
+		for _, t := range filterLeafTags(noteTags[id]) {
+			tagged[t] = append(tagged[t], notes[id])
+		}
+	}
+
+	// Link each tag to its parent. Ancestors always exist as tag nodes
+	// because build expands nested tags into every level.
+	children := make(map[string][]string)
+	for key := range tagNames {
+		if key == rootKey {
+			continue
+		}
+		parent := key[:strings.LastIndex(key, "/")]
+		children[parent] = append(children[parent], key)
+	}
+	var build func(key string) TagTreeNode
+	build = func(key string) TagTreeNode {
+		node := TagTreeNode{Tag: tagNames[key], Notes: tagged[key]}
+		kids := children[key]
+		sort.Strings(kids)
+		for _, k := range kids {
+			node.Children = append(node.Children, build(k))
+		}
+		return node
+	}
+	return &TagTreeResult{Root: build(rootKey)}, nil
+}
+
+// QueryTagParents returns the ancestor tags of entry.Tag (#a/b/c → #a, #a/b)
+// and the notes tagged at each. As in QueryTagTree, a note is listed only
+// under its most specific tags, so a note tagged #a/b/c does not appear at #a.
+func QueryTagParents(vaultPath string, entry EntrySpec, opts QueryOptions) (*TagParentsResult, error) {
+	if entry.Tag == "" {
+		return nil, fmt.Errorf("tag parents requires a tag entry")
+	}
+	if entry.File != "" || len(entry.Tags) > 0 || entry.Phantom != "" || entry.Asset != "" || entry.Name != "" {
+		return nil, fmt.Errorf("multiple entry specs: tag parents takes only --tag")
+	}
+
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	_, info, err := findEntryByTag(db, entry.Tag)
+	if err != nil {
+		return nil, err
+	}
+	key, err := lookupTagKey(db, info.Name)
+	if err != nil {
+		return nil, err
+	}
+	result := &TagParentsResult{Tag: info.Name, Ancestors: []TagLevel{}}
+
+	// Ancestors always exist as tag nodes because build expands nested tags
+	// into every level.
+	var ancestors []string
+	for i, r := range key {
+		if r == '/' {
+			ancestors = append(ancestors, key[:i])
+		}
+	}
+	if len(ancestors) == 0 {
+		return result, nil
+	}
+	top := ancestors[0]
+
+	// A note's most specific tags are judged over the whole top-level tree.
+	q := `SELECT n.id, n.type, n.name, COALESCE(n.path,''), n.exists_flag, t.name, t.node_key
+		 FROM edges e
+		 JOIN nodes n ON n.id = e.source_id
+		 JOIN nodes t ON t.id = e.target_id
+		 WHERE n.type = 'note' AND t.type = 'tag'
+		 AND (t.node_key = ? OR t.node_key GLOB ?)`
+	args := []any{top, top + "/*"}
+	if ef := opts.Exclude; ef != nil {
+		pathSQL, pathArgs := ef.PathExcludeSQL("n.path")
+		q += pathSQL
+		args = append(args, pathArgs...)
+		tagSQL, tagArgs := ef.TagExcludeSQL("t.name")
+		q += tagSQL
+		args = append(args, tagArgs...)
+	}
+	q += ` ORDER BY n.path`
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tagNames := make(map[string]string) // node key → display name
+	notes := make(map[int64]NodeInfo)
+	var noteOrder []int64
+	noteTags := make(map[int64][]string)
+	for rows.Next() {
+		var id int64
+		var typ, name, path, tag, k string
+		var exists int
+		if err := rows.Scan(&id, &typ, &name, &path, &exists, &tag, &k); err != nil {
+			return nil, err
+		}
+		if _, ok := tagNames[k]; !ok {
+			tagNames[k] = tag
+		}
+		if _, ok := notes[id]; !ok {
+			notes[id] = NodeInfo{Type: typ, Name: name, Path: path, Exists: exists == 1}
+			noteOrder = append(noteOrder, id)
+		}
+		noteTags[id] = append(noteTags[id], k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tagged := make(map[string][]NodeInfo)
+	for _, id := range noteOrder {
+		for _, t := range filterLeafTags(noteTags[id]) {
+			tagged[t] = append(tagged[t], notes[id])
+		}
+	}
+	for _, a := range ancestors {
+		name, ok := tagNames[a]
+		if !ok {
+			// Every note at this level was excluded; the tag itself stays
+			// unless --exclude-tag names it.
+			if err := db.QueryRow("SELECT name FROM nodes WHERE node_key = ?", a).Scan(&name); err != nil {
+				return nil, err
+			}
+			if opts.Exclude.IsViaExcluded(NodeInfo{Type: "tag", Name: name}) {
+				continue
+			}
+		}
+		result.Ancestors = append(result.Ancestors, TagLevel{Tag: name, Notes: tagged[a]})
+	}
+	return result, nil
+}
+
+// findEntryNode resolves an EntrySpec to a node ID and NodeInfo. rootPriority
+// lets a name or bare asset matching several files resolve to the root one.
+func findEntryNode(db dbExecer, spec EntrySpec, rootPriority bool) (int64, NodeInfo, error) {
 	count := 0
 	if spec.File != "" {
 		count++
@@ -163,14 +716,20 @@ func findEntryNode(db dbExecer, spec EntrySpec) (int64, NodeInfo, error) {
 	if spec.Phantom != "" {
 		count++
 	}
+	if spec.Asset != "" {
+		count++
+	}
 	if spec.Name != "" {
 		count++
 	}
+	if len(spec.Tags) > 0 {
+		return 0, NodeInfo{}, fmt.Errorf("tags entry is not supported by Query: use QueryTags")
+	}
 	if count == 0 {
-		return 0, NodeInfo{}, fmt.Errorf("no entry specified: provide --file, --tag, --phantom, or --name")
+		return 0, NodeInfo{}, fmt.Errorf("no entry specified: provide --file, --tag, --phantom, --asset, or --name")
 	}
 	if count > 1 {
-		return 0, NodeInfo{}, fmt.Errorf("multiple entry specs: provide exactly one of --file, --tag, --phantom, --name")
+		return 0, NodeInfo{}, fmt.Errorf("multiple entry specs: provide exactly one of --file, --tag, --phantom, --asset, --name")
 	}
 
 	if spec.File != "" {
@@ -182,7 +741,10 @@ func findEntryNode(db dbExecer, spec EntrySpec) (int64, NodeInfo, error) {
 	if spec.Phantom != "" {
 		return findEntryByPhantom(db, spec.Phantom)
 	}
-	return findEntryByName(db, spec.Name)
+	if spec.Asset != "" {
+		return findEntryByAsset(db, spec.Asset, rootPriority)
+	}
+	return findEntryByName(db, spec.Name, rootPriority)
 }
 
 func findEntryByKey(db dbExecer, key, errMsg string) (int64, NodeInfo, error) {
@@ -221,14 +783,18 @@ func findEntryByTag(db dbExecer, tag string) (int64, NodeInfo, error) {
 	if !strings.HasPrefix(tag, "#") {
 		tag = "#" + tag
 	}
-	return findEntryByKey(db, fmt.Sprintf("tag:name:%s", strings.ToLower(tag)), fmt.Sprintf("tag not in index: %s", tag))
+	key, err := lookupTagKey(db, tag)
+	if err != nil {
+		return 0, NodeInfo{}, err
+	}
+	return findEntryByKey(db, key, fmt.Sprintf("tag not in index: %s", tag))
 }
 
 func findEntryByPhantom(db dbExecer, name string) (int64, NodeInfo, error) {
 	return findEntryByKey(db, phantomKey(name), fmt.Sprintf("phantom not in index: %s", name))
 }
 
-func findEntryByName(db dbExecer, name string) (int64, NodeInfo, error) {
+func findEntryByName(db dbExecer, name string, rootPriority bool) (int64, NodeInfo, error) {
 	if strings.HasPrefix(name, "#") {
 		return findEntryByTag(db, name)
 	}
@@ -264,91 +830,391 @@ func findEntryByName(db dbExecer, name string) (int64, NodeInfo, error) {
 		return 0, NodeInfo{}, err
 	}
 
+	// Notes whose frontmatter aliases match the name (excluding basename matches).
+	aliasMatches, err := queryAliasMatches(db, lower)
+	if err != nil {
+		return 0, NodeInfo{}, err
+	}
+	basenameIDs := make(map[int64]bool, len(matches))
+	for _, m := range matches {
+		basenameIDs[m.id] = true
+	}
+	var aliasOnly []aliasMatch
+	for _, am := range aliasMatches {
+		if !basenameIDs[am.id] {
+			aliasOnly = append(aliasOnly, am)
+		}
+	}
+	if len(aliasOnly) > 0 {
+		if len(matches) == 0 && len(aliasOnly) == 1 {
+			return aliasOnly[0].id, aliasOnly[0].info, nil
+		}
+		var candidates []string
+		var nodes []NodeInfo
+		for _, m := range matches {
+			candidates = append(candidates, m.info.Path+" (basename)")
+			nodes = append(nodes, m.info)
+		}
+		for _, am := range aliasOnly {
+			candidates = append(candidates, am.info.Path+" (alias)")
+			nodes = append(nodes, am.info)
+		}
+		return 0, NodeInfo{}, newAmbiguousNameError(nodes,
+			"ambiguous name: %s matches %s", name, strings.Join(candidates, ", "))
+	}
+
 	if len(matches) == 1 {
 		return matches[0].id, matches[0].info, nil
 	}
 	if len(matches) > 1 {
 		// Root-priority: if one match is at vault root, resolve to it.
 		for _, m := range matches {
-			if isRootFile(m.info.Path) {
+			if rootPriority && isRootFile(m.info.Path) {
 				return m.id, m.info, nil
 			}
 		}
-		return 0, NodeInfo{}, fmt.Errorf("ambiguous name: %s matches %d notes", name, len(matches))
+		nodes := make([]NodeInfo, len(matches))
+		for i, m := range matches {
+			nodes[i] = m.info
+		}
+		return 0, NodeInfo{}, newAmbiguousNameError(nodes, "ambiguous name: %s matches %d notes", name, len(matches))
 	}
 
 	// Try asset by basename (case-insensitive).
-	assetRows, err := db.Query(
+	if id, info, ok, err := findAssetByBasename(db, name, rootPriority); ok || err != nil {
+		return id, info, err
+	}
+
+	// Try phantom.
+	return findEntryByKey(db, phantomKey(name), fmt.Sprintf("name not found: %s", name))
+}
+
+// findEntryByAsset resolves an asset entry: a path containing "/" must match
+// exactly, a bare name is looked up by basename like findEntryByName (root
+// priority when enabled, otherwise ambiguous).
+func findEntryByAsset(db dbExecer, asset string, rootPriority bool) (int64, NodeInfo, error) {
+	path := NormalizePath(asset)
+	notFound := fmt.Sprintf("asset not found: %s", path)
+	if strings.Contains(path, "/") {
+		return findEntryByKey(db, assetKey(path), notFound)
+	}
+	if id, info, ok, err := findAssetByBasename(db, path, rootPriority); ok || err != nil {
+		return id, info, err
+	}
+	return 0, NodeInfo{}, fmt.Errorf("%s", notFound)
+}
+
+// findAssetByBasename looks up assets by basename (case-insensitive). ok is
+// false when none matches; several matches resolve to the one at the vault
+// root when rootPriority is set, or fail as ambiguous.
+func findAssetByBasename(db dbExecer, name string, rootPriority bool) (int64, NodeInfo, bool, error) {
+	rows, err := db.Query(
 		`SELECT id, type, name, COALESCE(path,''), exists_flag FROM nodes WHERE type='asset' AND LOWER(name)=?`,
-		lower,
+		strings.ToLower(name),
 	)
 	if err != nil {
-		return 0, NodeInfo{}, err
+		return 0, NodeInfo{}, false, err
 	}
-	defer assetRows.Close()
+	defer rows.Close()
 
-	var assetMatches []struct {
+	var matches []struct {
 		id   int64
 		info NodeInfo
 	}
-	for assetRows.Next() {
+	for rows.Next() {
 		var id int64
 		var typ, n, p string
 		var exists int
-		if err := assetRows.Scan(&id, &typ, &n, &p, &exists); err != nil {
-			return 0, NodeInfo{}, err
+		if err := rows.Scan(&id, &typ, &n, &p, &exists); err != nil {
+			return 0, NodeInfo{}, false, err
 		}
-		assetMatches = append(assetMatches, struct {
+		matches = append(matches, struct {
 			id   int64
 			info NodeInfo
 		}{id, NodeInfo{Type: typ, Name: n, Path: p, Exists: exists == 1}})
 	}
-	if err := assetRows.Err(); err != nil {
-		return 0, NodeInfo{}, err
+	if err := rows.Err(); err != nil {
+		return 0, NodeInfo{}, false, err
 	}
 
-	if len(assetMatches) == 1 {
-		return assetMatches[0].id, assetMatches[0].info, nil
+	if len(matches) == 0 {
+		return 0, NodeInfo{}, false, nil
 	}
-	if len(assetMatches) > 1 {
-		for _, m := range assetMatches {
-			if isRootFile(m.info.Path) {
-				return m.id, m.info, nil
-			}
+	if len(matches) == 1 {
+		return matches[0].id, matches[0].info, true, nil
+	}
+	for _, m := range matches {
+		if rootPriority && isRootFile(m.info.Path) {
+			return m.id, m.info, true, nil
 		}
-		return 0, NodeInfo{}, fmt.Errorf("ambiguous name: %s matches %d assets", name, len(assetMatches))
+	}
+	nodes := make([]NodeInfo, len(matches))
+	for i, m := range matches {
+		nodes[i] = m.info
+	}
+	return 0, NodeInfo{}, true, newAmbiguousNameError(nodes, "ambiguous name: %s matches %d assets", name, len(matches))
+}
+
+// ambiguousNameError is returned by findEntryByName when a name matches
+// several nodes. Query with AllowAmbiguous turns it into Candidates.
+type ambiguousNameError struct {
+	msg        string
+	candidates []NodeInfo // sorted by path
+}
+
+func newAmbiguousNameError(candidates []NodeInfo, format string, args ...any) *ambiguousNameError {
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Path < candidates[j].Path })
+	return &ambiguousNameError{msg: fmt.Sprintf(format, args...), candidates: candidates}
+}
+
+func (e *ambiguousNameError) Error() string { return e.msg }
+
+type aliasMatch struct {
+	id   int64
+	info NodeInfo
+}
+
+// queryAliasMatches returns notes having an alias equal to lowerName (case-insensitive).
+func queryAliasMatches(db dbExecer, lowerName string) ([]aliasMatch, error) {
+	rows, err := db.Query(
+		`SELECT DISTINCT n.id, n.type, n.name, COALESCE(n.path,''), n.exists_flag
+		 FROM aliases a JOIN nodes n ON n.id = a.node_id
+		 WHERE n.type='note' AND LOWER(a.alias)=?
+		 ORDER BY n.path`,
+		lowerName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []aliasMatch
+	for rows.Next() {
+		var am aliasMatch
+		var exists int
+		if err := rows.Scan(&am.id, &am.info.Type, &am.info.Name, &am.info.Path, &exists); err != nil {
+			return nil, err
+		}
+		am.info.Exists = exists == 1
+		out = append(out, am)
+	}
+	return out, rows.Err()
+}
+
+// queryAliases returns the frontmatter aliases of a note in declaration order.
+func queryAliases(db dbExecer, nodeID int64) ([]string, error) {
+	rows, err := db.Query(`SELECT alias FROM aliases WHERE node_id = ? ORDER BY id`, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var a string
+		if err := rows.Scan(&a); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// fetchNodeInfo retrieves NodeInfo for a node by ID.
+func fetchNodeInfo(db dbExecer, nodeID int64) (NodeInfo, error) {
+	var typ, name, path string
+	var existsFlag int
+
+	err := db.QueryRow(
+		`SELECT type, name, COALESCE(path,''), exists_flag FROM nodes WHERE id = ?`,
+		nodeID,
+	).Scan(&typ, &name, &path, &existsFlag)
+	if err != nil {
+		return NodeInfo{}, err
+	}
+
+	return NodeInfo{
+		Type:   typ,
+		Name:   name,
+		Path:   path,
+		Exists: existsFlag == 1,
+	}, nil
+}
+
+// QueryBacklinksFunc is the streaming form of Query for the backlinks field.
+// head is called once with Entry, ViaAlias, and TotalBacklinks set; fn is then
+// called for each backlink of the page (Offset/MaxBacklinks) as its row is
+// read, so the page is never held in memory. An error from head or fn stops
+// the iteration and is returned.
+func QueryBacklinksFunc(vaultPath string, entry EntrySpec, opts QueryOptions, head func(*QueryResult) error, fn func(NodeInfo) error) error {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return err
+	}
+	nodeID, result, err := queryEntryResult(db, entry, cfg.Build)
+	if err != nil {
+		return err
+	}
+
+	if opts.MaxBacklinks <= 0 {
+		opts.MaxBacklinks = 100
+	}
+	if opts.Offset < 0 {
+		opts.Offset = 0
+	}
+
+	total, err := countBacklinks(db, nodeID, opts.IncludeSelf, entry.Heading, opts.Exclude)
+	if err != nil {
+		return err
+	}
+	result.TotalBacklinks = total
+	if err := head(result); err != nil {
+		return err
+	}
+	return eachBacklink(db, nodeID, opts.MaxBacklinks, opts.Offset, opts.IncludeSelf, entry.Heading, opts.Exclude, fn)
+}
+
+// queryEntryResult resolves entry and returns its node ID and a QueryResult
+// with Entry (including a note's aliases) and ViaAlias filled in. bc decides
+// whether a name matching several files resolves by root priority.
+func queryEntryResult(db dbExecer, entry EntrySpec, bc BuildConfig) (int64, *QueryResult, error) {
+	nodeID, info, err := findEntryNode(db, entry, bc.rootPriority())
+	if err != nil {
+		return 0, nil, err
+	}
+	if entry.Heading != "" && info.Type != "note" {
+		return 0, nil, fmt.Errorf("heading requires a note entry, got %s: %s", info.Type, info.Name)
+	}
+	if info.Type == "note" {
+		aliases, err := queryAliases(db, nodeID)
+		if err != nil {
+			return 0, nil, err
+		}
+		info.Aliases = aliases
+	}
+
+	result := &QueryResult{Entry: info}
+	if entry.Name != "" && info.Type == "note" && !strings.EqualFold(entry.Name, info.Name) {
+		result.ViaAlias = entry.Name
+	}
+	return nodeID, result, nil
+}
+
+// queryBacklinks returns one page of distinct source nodes linking to targetID,
+// ordered by path then name so that paging with offset is stable across calls.
+// The node's own self-links count only with includeSelf. A non-empty heading
+// keeps only links into that heading (see headingEdgeSQL).
+func queryBacklinks(db dbExecer, targetID int64, limit, offset int, includeSelf bool, heading string, ef *ExcludeFilter) ([]NodeInfo, error) {
+	var result []NodeInfo
+	err := eachBacklink(db, targetID, limit, offset, includeSelf, heading, ef, func(n NodeInfo) error {
+		result = append(result, n)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// eachBacklink calls fn for each row of the page queryBacklinks returns.
+func eachBacklink(db dbExecer, targetID int64, limit, offset int, includeSelf bool, heading string, ef *ExcludeFilter, fn func(NodeInfo) error) error {
+	headingSQL, headingArgs := headingEdgeSQL(heading)
+	q := `SELECT DISTINCT n.type, n.name, COALESCE(n.path,''), n.exists_flag
+		 FROM edges e JOIN nodes n ON n.id = e.source_id
+		 WHERE e.target_id = ?` + selfEdgeSQL(includeSelf) + headingSQL
+	args := append([]any{targetID}, headingArgs...)
+
+	if ef != nil {
+		pathSQL, pathArgs := ef.PathExcludeSQL("n.path")
+		q += pathSQL
+		args = append(args, pathArgs...)
+	}
+
+	q += ` ORDER BY n.path, n.name LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var typ, name, path string
+		var exists int
+		if err := rows.Scan(&typ, &name, &path, &exists); err != nil {
+			return err
+		}
+		if err := fn(NodeInfo{Type: typ, Name: name, Path: path, Exists: exists == 1}); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// countBacklinks returns the number of distinct source nodes linking to targetID.
+func countBacklinks(db dbExecer, targetID int64, includeSelf bool, heading string, ef *ExcludeFilter) (int, error) {
+	headingSQL, headingArgs := headingEdgeSQL(heading)
+	q := `SELECT COUNT(DISTINCT n.id)
+		 FROM edges e JOIN nodes n ON n.id = e.source_id
+		 WHERE e.target_id = ?` + selfEdgeSQL(includeSelf) + headingSQL
+	args := append([]any{targetID}, headingArgs...)
+
+	if ef != nil {
+		pathSQL, pathArgs := ef.PathExcludeSQL("n.path")
+		q += pathSQL
+		args = append(args, pathArgs...)
 	}
 
-	// Try phantom.
-	return findEntryByKey(db, phantomKey(name), fmt.Sprintf("name not found: %s", name))
+	var n int
+	err := db.QueryRow(q, args...).Scan(&n)
+	return n, err
 }
 
-// fetchNodeInfo retrieves NodeInfo for a node by ID.
-func fetchNodeInfo(db dbExecer, nodeID int64) (NodeInfo, error) {
-	var typ, name, path string
-	var existsFlag int
-
-	err := db.QueryRow(
-		`SELECT type, name, COALESCE(path,''), exists_flag FROM nodes WHERE id = ?`,
-		nodeID,
-	).Scan(&typ, &name, &path, &existsFlag)
-	if err != nil {
-		return NodeInfo{}, err
+// selfEdgeSQL returns the condition that drops self-links ([[#Heading]]) from
+// a query over edges e, or "" with includeSelf.
+func selfEdgeSQL(includeSelf bool) string {
+	if includeSelf {
+		return ""
 	}
+	return ` AND e.source_id != e.target_id`
+}
 
-	return NodeInfo{
-		Type:   typ,
-		Name:   name,
-		Path:   path,
-		Exists: existsFlag == 1,
-	}, nil
+// headingEdgeSQL returns the condition keeping only edges e whose subpath
+// ends at heading: "#Details" and "#Impl#Details" both do for "Details".
+// Heading text matches case-insensitively (ASCII only, as SQLite's LOWER).
+// It returns "" for an empty heading.
+func headingEdgeSQL(heading string) (string, []any) {
+	if heading == "" {
+		return "", nil
+	}
+	suffix := "#" + strings.TrimSpace(strings.TrimPrefix(heading, "#"))
+	return ` AND LOWER(substr(COALESCE(e.subpath,''), -?)) = LOWER(?)`, []any{utf8.RuneCountInString(suffix), suffix}
 }
 
-func queryBacklinks(db dbExecer, targetID int64, limit int, ef *ExcludeFilter) ([]NodeInfo, error) {
+func queryOutgoing(db dbExecer, sourceID int64, targetTypes []string, includeSelf bool, ef *ExcludeFilter) ([]NodeInfo, error) {
+	typeSQL, typeArgs := outgoingTypeSQL(targetTypes)
 	q := `SELECT DISTINCT n.type, n.name, COALESCE(n.path,''), n.exists_flag
-		 FROM edges e JOIN nodes n ON n.id = e.source_id
-		 WHERE e.target_id = ?`
-	args := []any{targetID}
+		 FROM edges e JOIN nodes n ON n.id = e.target_id
+		 WHERE e.source_id = ?` + selfEdgeSQL(includeSelf) + typeSQL
+	args := append([]any{sourceID}, typeArgs...)
 
 	if ef != nil {
 		pathSQL, pathArgs := ef.PathExcludeSQL("n.path")
@@ -356,8 +1222,7 @@ func queryBacklinks(db dbExecer, targetID int64, limit int, ef *ExcludeFilter) (
 		args = append(args, pathArgs...)
 	}
 
-	q += ` ORDER BY n.path, n.name LIMIT ?`
-	args = append(args, limit)
+	q += ` ORDER BY n.path, n.name`
 
 	rows, err := db.Query(q, args...)
 	if err != nil {
@@ -377,11 +1242,32 @@ func queryBacklinks(db dbExecer, targetID int64, limit int, ef *ExcludeFilter) (
 	return result, rows.Err()
 }
 
-func queryOutgoing(db dbExecer, sourceID int64, ef *ExcludeFilter) ([]NodeInfo, error) {
-	q := `SELECT DISTINCT n.type, n.name, COALESCE(n.path,''), n.exists_flag
-		 FROM edges e JOIN nodes n ON n.id = e.target_id
-		 WHERE e.source_id = ? AND e.target_id != ? AND n.type IN ('note','phantom','asset')`
-	args := []any{sourceID, sourceID}
+// queryLinkPositions returns the backlinks (inbound) or outgoing links of
+// nodeID with their line positions, ordered like queryBacklinks/queryOutgoing.
+// Without perEdge only the first occurrence per linked node is returned.
+// limit < 0 means no limit. targetTypes filters outgoing links as in
+// queryOutgoing, heading backlinks as in queryBacklinks.
+func queryLinkPositions(db dbExecer, nodeID int64, inbound, perEdge bool, limit, offset int, targetTypes []string, includeSelf bool, heading string, ef *ExcludeFilter) ([]LinkPosition, error) {
+	// With GROUP BY, SQLite takes the bare columns from the row holding MIN(e.line_start).
+	cols := `n.type, n.name, COALESCE(n.path,''), n.exists_flag, COALESCE(e.line_start,0), COALESCE(e.line_end,0), e.raw_link`
+	if !perEdge {
+		cols = `n.type, n.name, COALESCE(n.path,''), n.exists_flag, MIN(COALESCE(e.line_start,0)), COALESCE(e.line_end,0), e.raw_link`
+	}
+	var q string
+	var args []any
+	if inbound {
+		headingSQL, headingArgs := headingEdgeSQL(heading)
+		q = `SELECT ` + cols + `
+			 FROM edges e JOIN nodes n ON n.id = e.source_id
+			 WHERE e.target_id = ?` + selfEdgeSQL(includeSelf) + headingSQL
+		args = append([]any{nodeID}, headingArgs...)
+	} else {
+		typeSQL, typeArgs := outgoingTypeSQL(targetTypes)
+		q = `SELECT ` + cols + `
+			 FROM edges e JOIN nodes n ON n.id = e.target_id
+			 WHERE e.source_id = ?` + selfEdgeSQL(includeSelf) + typeSQL
+		args = append([]any{nodeID}, typeArgs...)
+	}
 
 	if ef != nil {
 		pathSQL, pathArgs := ef.PathExcludeSQL("n.path")
@@ -389,7 +1275,13 @@ func queryOutgoing(db dbExecer, sourceID int64, ef *ExcludeFilter) ([]NodeInfo,
 		args = append(args, pathArgs...)
 	}
 
-	q += ` ORDER BY n.path, n.name`
+	if perEdge {
+		q += ` ORDER BY n.path, n.name, e.line_start, e.id`
+	} else {
+		q += ` GROUP BY n.id ORDER BY n.path, n.name`
+	}
+	q += ` LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
 
 	rows, err := db.Query(q, args...)
 	if err != nil {
@@ -397,14 +1289,15 @@ func queryOutgoing(db dbExecer, sourceID int64, ef *ExcludeFilter) ([]NodeInfo,
 	}
 	defer rows.Close()
 
-	var result []NodeInfo
+	result := []LinkPosition{}
 	for rows.Next() {
-		var typ, name, path string
+		var lp LinkPosition
 		var exists int
-		if err := rows.Scan(&typ, &name, &path, &exists); err != nil {
+		if err := rows.Scan(&lp.Node.Type, &lp.Node.Name, &lp.Node.Path, &exists, &lp.LineStart, &lp.LineEnd, &lp.RawLink); err != nil {
 			return nil, err
 		}
-		result = append(result, NodeInfo{Type: typ, Name: name, Path: path, Exists: exists == 1})
+		lp.Node.Exists = exists == 1
+		result = append(result, lp)
 	}
 	return result, rows.Err()
 }
@@ -443,6 +1336,87 @@ func queryTags(db dbExecer, sourceID int64, ef *ExcludeFilter) ([]string, error)
 	return filterLeafTags(all), nil
 }
 
+// queryTagLocations returns every occurrence of the given tags in the note,
+// ordered by tag, then line. Occurrences of ancestor tags that only exist
+// through a nested tag (#a from #a/b) are left out along with the ancestor.
+func queryTagLocations(db dbExecer, sourceID int64, tags []string, ef *ExcludeFilter) ([]TagLocation, error) {
+	want := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		want[t] = true
+	}
+
+	q := `SELECT n.name, COALESCE(e.line_start,0), e.link_type FROM edges e JOIN nodes n ON n.id = e.target_id
+		 WHERE e.source_id = ? AND n.type = 'tag'`
+	args := []any{sourceID}
+
+	if ef != nil {
+		tagSQL, tagArgs := ef.TagExcludeSQL("n.name")
+		q += tagSQL
+		args = append(args, tagArgs...)
+	}
+
+	q += ` ORDER BY n.name, e.line_start, e.id`
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []TagLocation{}
+	for rows.Next() {
+		var loc TagLocation
+		var linkType string
+		if err := rows.Scan(&loc.Tag, &loc.Line, &linkType); err != nil {
+			return nil, err
+		}
+		if !want[loc.Tag] {
+			continue
+		}
+		loc.Source = "inline"
+		if linkType == "frontmatter" {
+			loc.Source = "frontmatter"
+		}
+		result = append(result, loc)
+	}
+	return result, rows.Err()
+}
+
+func queryHeadings(db dbExecer, nodeID int64) ([]Heading, error) {
+	rows, err := db.Query(
+		`SELECT level, text, line FROM headings WHERE node_id = ? ORDER BY line`,
+		nodeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []Heading
+	for rows.Next() {
+		var h Heading
+		if err := rows.Scan(&h.Level, &h.Text, &h.Line); err != nil {
+			return nil, err
+		}
+		result = append(result, h)
+	}
+	return result, rows.Err()
+}
+
+// queryTitle returns the text of the note's first non-empty heading (ATX or
+// Setext, as stored by the headings extractor), or basename when it has none.
+func queryTitle(db dbExecer, nodeID int64, basename string) (string, error) {
+	var title string
+	err := db.QueryRow(
+		`SELECT text FROM headings WHERE node_id = ? AND text != '' ORDER BY line LIMIT 1`,
+		nodeID,
+	).Scan(&title)
+	if err == sql.ErrNoRows {
+		return basename, nil
+	}
+	return title, err
+}
+
 func filterLeafTags(tags []string) []string {
 	if len(tags) <= 1 {
 		return tags
@@ -514,37 +1488,10 @@ func fetchNodeInfoBatch(db dbExecer, ids []int64) (map[int64]NodeInfo, error) {
 }
 
 func queryTwoHop(db dbExecer, entryID int64, entryType string, maxTwoHop, maxViaPerTarget int, ef *ExcludeFilter) ([]TwoHopEntry, error) {
-	var seedQuery string
-	var seedIsOutbound bool
-
-	switch entryType {
-	case "note":
-		// Outbound seed: targets of the entry.
-		seedQuery = `SELECT DISTINCT target_id FROM edges WHERE source_id = ?`
-		seedIsOutbound = true
-	default:
-		// Inbound seed: sources linking to the entry.
-		seedQuery = `SELECT DISTINCT source_id FROM edges WHERE target_id = ?`
-		seedIsOutbound = false
-	}
-
-	seedRows, err := db.Query(seedQuery, entryID)
+	seedIDs, seedIsOutbound, err := queryTwoHopSeeds(db, entryID, entryType)
 	if err != nil {
 		return nil, err
 	}
-	defer seedRows.Close()
-
-	var seedIDs []int64
-	for seedRows.Next() {
-		var id int64
-		if err := seedRows.Scan(&id); err != nil {
-			return nil, err
-		}
-		seedIDs = append(seedIDs, id)
-	}
-	if err := seedRows.Err(); err != nil {
-		return nil, err
-	}
 
 	viaInfoMap, err := fetchNodeInfoBatch(db, seedIDs)
 	if err != nil {
@@ -566,26 +1513,9 @@ func queryTwoHop(db dbExecer, entryID int64, entryType string, maxTwoHop, maxVia
 			continue
 		}
 
-		var targetQuery string
-		var targetArgs []any
-		if seedIsOutbound {
-			targetQuery = `SELECT DISTINCT n.type, n.name, COALESCE(n.path,''), n.exists_flag
-				 FROM edges e JOIN nodes n ON n.id = e.source_id
-				 WHERE e.target_id = ? AND e.source_id != ?`
-			targetArgs = []any{viaID, entryID}
-		} else {
-			targetQuery = `SELECT DISTINCT n.type, n.name, COALESCE(n.path,''), n.exists_flag
-				 FROM edges e JOIN nodes n ON n.id = e.target_id
-				 WHERE e.source_id = ? AND e.target_id != ?`
-			targetArgs = []any{viaID, entryID}
-		}
-
-		if ef != nil {
-			pathSQL, pathArgs := ef.PathExcludeSQL("n.path")
-			targetQuery += pathSQL
-			targetArgs = append(targetArgs, pathArgs...)
-		}
-
+		targetQuery, targetArgs := twoHopTargetQuery(
+			`SELECT DISTINCT n.type, n.name, COALESCE(n.path,''), n.exists_flag`,
+			entryID, viaID, seedIsOutbound, ef)
 		targetQuery += ` ORDER BY n.path, n.name LIMIT ?`
 		targetArgs = append(targetArgs, maxViaPerTarget)
 
@@ -617,7 +1547,154 @@ func queryTwoHop(db dbExecer, entryID int64, entryType string, maxTwoHop, maxVia
 	return entries, nil
 }
 
-func readHead(db dbExecer, vaultPath string, nodeID int64, n int) ([]string, error) {
+// queryTwoHopRanked aggregates the two-hop join by target instead of by via.
+// Each target is weighted by the number of distinct vias that connect it to the
+// entry. Targets are ordered by path, or by weight (descending) with sortByWeight.
+func queryTwoHopRanked(db dbExecer, entryID int64, entryType string, maxTwoHop, maxViaPerTarget int, sortByWeight bool, ef *ExcludeFilter) ([]TwoHopTarget, error) {
+	seedIDs, seedIsOutbound, err := queryTwoHopSeeds(db, entryID, entryType)
+	if err != nil {
+		return nil, err
+	}
+
+	viaInfoMap, err := fetchNodeInfoBatch(db, seedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	viasByTarget := make(map[int64][]NodeInfo)
+	var targetIDs []int64
+	for _, viaID := range seedIDs {
+		viaInfo, ok := viaInfoMap[viaID]
+		if !ok {
+			return nil, fmt.Errorf("node not found in batch: id=%d", viaID)
+		}
+
+		if ef != nil && ef.IsViaExcluded(viaInfo) {
+			continue
+		}
+
+		targetQuery, targetArgs := twoHopTargetQuery(`SELECT DISTINCT n.id`, entryID, viaID, seedIsOutbound, ef)
+		targetRows, err := db.Query(targetQuery, targetArgs...)
+		if err != nil {
+			return nil, err
+		}
+		for targetRows.Next() {
+			var id int64
+			if err := targetRows.Scan(&id); err != nil {
+				targetRows.Close()
+				return nil, err
+			}
+			if _, ok := viasByTarget[id]; !ok {
+				targetIDs = append(targetIDs, id)
+			}
+			viasByTarget[id] = append(viasByTarget[id], viaInfo)
+		}
+		targetRows.Close()
+		if err := targetRows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	targetInfoMap, err := fetchNodeInfoBatch(db, targetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]TwoHopTarget, 0, len(targetIDs))
+	for _, id := range targetIDs {
+		info, ok := targetInfoMap[id]
+		if !ok {
+			return nil, fmt.Errorf("node not found in batch: id=%d", id)
+		}
+		vias := viasByTarget[id]
+		sort.Slice(vias, func(i, j int) bool { return nodeInfoLess(vias[i], vias[j]) })
+		weight := len(vias)
+		if len(vias) > maxViaPerTarget {
+			vias = vias[:maxViaPerTarget]
+		}
+		ranked = append(ranked, TwoHopTarget{Target: info, Weight: weight, Vias: vias})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if sortByWeight && ranked[i].Weight != ranked[j].Weight {
+			return ranked[i].Weight > ranked[j].Weight
+		}
+		return nodeInfoLess(ranked[i].Target, ranked[j].Target)
+	})
+	if len(ranked) > maxTwoHop {
+		ranked = ranked[:maxTwoHop]
+	}
+	return ranked, nil
+}
+
+// nodeInfoLess orders nodes by path, then name (the twohop target order).
+func nodeInfoLess(a, b NodeInfo) bool {
+	if a.Path != b.Path {
+		return a.Path < b.Path
+	}
+	return a.Name < b.Name
+}
+
+// queryTwoHopSeeds returns the via candidates of a two-hop query: the targets
+// of a note entry (outbound) or the sources linking to any other entry
+// (inbound). The entry's self-links never make it its own via.
+func queryTwoHopSeeds(db dbExecer, entryID int64, entryType string) ([]int64, bool, error) {
+	var seedQuery string
+	var seedIsOutbound bool
+
+	switch entryType {
+	case "note":
+		// Outbound seed: targets of the entry.
+		seedQuery = `SELECT DISTINCT target_id FROM edges WHERE source_id = ? AND target_id != source_id`
+		seedIsOutbound = true
+	default:
+		// Inbound seed: sources linking to the entry.
+		seedQuery = `SELECT DISTINCT source_id FROM edges WHERE target_id = ? AND source_id != target_id`
+		seedIsOutbound = false
+	}
+
+	seedRows, err := db.Query(seedQuery, entryID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer seedRows.Close()
+
+	var seedIDs []int64
+	for seedRows.Next() {
+		var id int64
+		if err := seedRows.Scan(&id); err != nil {
+			return nil, false, err
+		}
+		seedIDs = append(seedIDs, id)
+	}
+	return seedIDs, seedIsOutbound, seedRows.Err()
+}
+
+// twoHopTargetQuery builds the query for the nodes reached through viaID,
+// excluding the entry itself and any excluded paths. cols selects from n.
+func twoHopTargetQuery(cols string, entryID, viaID int64, seedIsOutbound bool, ef *ExcludeFilter) (string, []any) {
+	var q string
+	if seedIsOutbound {
+		q = cols + `
+			 FROM edges e JOIN nodes n ON n.id = e.source_id
+			 WHERE e.target_id = ? AND e.source_id != ?`
+	} else {
+		q = cols + `
+			 FROM edges e JOIN nodes n ON n.id = e.target_id
+			 WHERE e.source_id = ? AND e.target_id != ?`
+	}
+	args := []any{viaID, entryID}
+	if ef != nil {
+		pathSQL, pathArgs := ef.PathExcludeSQL("n.path")
+		q += pathSQL
+		args = append(args, pathArgs...)
+	}
+	return q, args
+}
+
+// readHead returns the first n lines of a note. Unless raw is set, the
+// frontmatter and the blank lines after it are skipped first. With
+// reparseStale, a note changed since the last build is read as it is now.
+func readHead(db dbExecer, vaultPath string, nodeID int64, n int, raw, reparseStale bool) ([]string, error) {
 	var path string
 	var mtime int64
 	err := db.QueryRow(
@@ -629,8 +1706,10 @@ func readHead(db dbExecer, vaultPath string, nodeID int64, n int) ([]string, err
 	}
 
 	fullPath := filepath.Join(vaultPath, path)
-	if err := checkStale(fullPath, mtime); err != nil {
-		return nil, err
+	if !reparseStale {
+		if err := checkStale(fullPath, mtime); err != nil {
+			return nil, err
+		}
 	}
 
 	lines, err := readFileLines(fullPath)
@@ -638,16 +1717,17 @@ func readHead(db dbExecer, vaultPath string, nodeID int64, n int) ([]string, err
 		return nil, err
 	}
 
-	// Skip frontmatter.
-	fmEnd := frontmatterEnd(lines)
 	start := 0
-	if fmEnd > 0 {
-		start = fmEnd + 1
-	}
+	if !raw {
+		// Skip frontmatter.
+		if fmEnd := frontmatterEnd(lines); fmEnd > 0 {
+			start = fmEnd + 1
+		}
 
-	// Skip leading blank lines.
-	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
-		start++
+		// Skip leading blank lines.
+		for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+			start++
+		}
 	}
 
 	end := start + n
@@ -658,8 +1738,12 @@ func readHead(db dbExecer, vaultPath string, nodeID int64, n int) ([]string, err
 	return lines[start:end], nil
 }
 
-func readSnippets(db dbExecer, vaultPath string, targetID int64, contextLines int, ef *ExcludeFilter) ([]SnippetEntry, error) {
-	q := `SELECT n.path, n.mtime, e.line_start, e.line_end
+// readSnippets returns the lines around each link to targetID: contextLines
+// on either side, or the enclosing blank-line-delimited paragraph. With
+// reparseStale, links in sources changed since the last build are located
+// in their current content (see relocateStaleEdges).
+func readSnippets(db dbExecer, vaultPath string, targetID int64, contextLines int, paragraph, reparseStale bool, ef *ExcludeFilter) ([]SnippetEntry, error) {
+	q := `SELECT n.path, n.mtime, e.line_start, e.line_end, e.raw_link, e.link_type
 		 FROM edges e JOIN nodes n ON n.id = e.source_id
 		 WHERE e.target_id = ?`
 	args := []any{targetID}
@@ -678,17 +1762,10 @@ func readSnippets(db dbExecer, vaultPath string, targetID int64, contextLines in
 	}
 	defer rows.Close()
 
-	type edgeInfo struct {
-		path      string
-		mtime     int64
-		lineStart int
-		lineEnd   int
-	}
-
-	var edgeInfos []edgeInfo
+	var edgeInfos []snippetEdge
 	for rows.Next() {
-		var ei edgeInfo
-		if err := rows.Scan(&ei.path, &ei.mtime, &ei.lineStart, &ei.lineEnd); err != nil {
+		var ei snippetEdge
+		if err := rows.Scan(&ei.path, &ei.mtime, &ei.lineStart, &ei.lineEnd, &ei.rawLink, &ei.linkType); err != nil {
 			return nil, err
 		}
 		edgeInfos = append(edgeInfos, ei)
@@ -697,6 +1774,16 @@ func readSnippets(db dbExecer, vaultPath string, targetID int64, contextLines in
 		return nil, err
 	}
 
+	if reparseStale {
+		cfg, err := LoadConfig(vaultPath)
+		if err != nil {
+			return nil, err
+		}
+		if edgeInfos, err = relocateStaleEdges(vaultPath, edgeInfos, cfg.Build); err != nil {
+			return nil, err
+		}
+	}
+
 	// Cache file lines per source path.
 	fileCache := make(map[string][]string)
 	var snippets []SnippetEntry
@@ -705,8 +1792,10 @@ func readSnippets(db dbExecer, vaultPath string, targetID int64, contextLines in
 		fullPath := filepath.Join(vaultPath, ei.path)
 
 		if _, ok := fileCache[ei.path]; !ok {
-			if err := checkStale(fullPath, ei.mtime); err != nil {
-				return nil, err
+			if !reparseStale {
+				if err := checkStale(fullPath, ei.mtime); err != nil {
+					return nil, err
+				}
 			}
 			lines, err := readFileLines(fullPath)
 			if err != nil {
@@ -717,13 +1806,18 @@ func readSnippets(db dbExecer, vaultPath string, targetID int64, contextLines in
 
 		lines := fileCache[ei.path]
 		// line_start and line_end are 1-based.
-		start := ei.lineStart - contextLines - 1 // 0-based
-		if start < 0 {
-			start = 0
-		}
-		end := ei.lineEnd + contextLines // 0-based exclusive
-		if end > len(lines) {
-			end = len(lines)
+		var start, end int
+		if paragraph {
+			start, end = paragraphBounds(lines, ei.lineStart-1, ei.lineEnd)
+		} else {
+			start = ei.lineStart - contextLines - 1 // 0-based
+			if start < 0 {
+				start = 0
+			}
+			end = ei.lineEnd + contextLines // 0-based exclusive
+			if end > len(lines) {
+				end = len(lines)
+			}
 		}
 
 		snippets = append(snippets, SnippetEntry{
@@ -737,6 +1831,127 @@ func readSnippets(db dbExecer, vaultPath string, targetID int64, contextLines in
 	return snippets, nil
 }
 
+// snippetEdge is an edge readSnippets cuts a snippet around.
+type snippetEdge struct {
+	path      string
+	mtime     int64
+	lineStart int
+	lineEnd   int
+	rawLink   string
+	linkType  string
+}
+
+// relocateStaleEdges points the edges of sources modified since the last
+// build at the lines where each raw link occurs in their current content,
+// as relocateRewrites does for a forced move. Links no longer present are
+// dropped; links added since the build are not found. edges must be grouped
+// by source path and stay so, in line order. The index is not touched.
+func relocateStaleEdges(vaultPath string, edges []snippetEdge, cfg BuildConfig) ([]snippetEdge, error) {
+	type linkKey struct{ rawLink, linkType string }
+	out := make([]snippetEdge, 0, len(edges))
+	for i := 0; i < len(edges); {
+		j := i
+		for j < len(edges) && edges[j].path == edges[i].path {
+			j++
+		}
+		group := edges[i:j]
+		i = j
+
+		fullPath := filepath.Join(vaultPath, group[0].path)
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			return nil, fmt.Errorf("file not found: %s", fullPath)
+		}
+		if info.ModTime().Unix() == group[0].mtime {
+			out = append(out, group...)
+			continue
+		}
+		content, err := os.ReadFile(fullPath)
+		if err != nil {
+			return nil, err
+		}
+		occurs := make(map[linkKey][]linkOccur)
+		for _, link := range parseIndexLinks(string(content), cfg) {
+			k := linkKey{link.rawLink, link.linkType}
+			occurs[k] = append(occurs[k], link)
+		}
+
+		var relocated []snippetEdge
+		seen := make(map[linkKey]bool)
+		for _, e := range group {
+			k := linkKey{e.rawLink, e.linkType}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			for _, link := range occurs[k] {
+				moved := e
+				moved.lineStart, moved.lineEnd = link.lineStart, link.lineEnd
+				relocated = append(relocated, moved)
+			}
+		}
+		sort.SliceStable(relocated, func(a, b int) bool { return relocated[a].lineStart < relocated[b].lineStart })
+		out = append(out, relocated...)
+	}
+	return out, nil
+}
+
+// paragraphBounds widens the 0-based half-open range [start, end) to the
+// surrounding non-blank lines, stopping at blank lines and file bounds.
+func paragraphBounds(lines []string, start, end int) (int, int) {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+	for end < len(lines) && strings.TrimSpace(lines[end]) != "" {
+		end++
+	}
+	return start, end
+}
+
+// rankSnippets orders snippets by how often query occurs (case-insensitively)
+// in their lines, most frequent first. Ties keep source path order.
+func rankSnippets(snippets []SnippetEntry, query string) {
+	q := strings.ToLower(query)
+	type scored struct {
+		entry SnippetEntry
+		score int
+	}
+	ranked := make([]scored, len(snippets))
+	for i, sn := range snippets {
+		ranked[i] = scored{entry: sn, score: strings.Count(strings.ToLower(strings.Join(sn.Lines, "\n")), q)}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].entry.SourcePath < ranked[j].entry.SourcePath
+	})
+	for i := range ranked {
+		snippets[i] = ranked[i].entry
+	}
+}
+
+// numberSnippetLines prefixes each snippet line with its line number, right
+// aligned to the widest number in the snippet. Lines are copied, since
+// snippets from one source share its cached lines.
+func numberSnippetLines(snippets []SnippetEntry) {
+	for i := range snippets {
+		sn := &snippets[i]
+		width := len(strconv.Itoa(sn.LineEnd))
+		numbered := make([]string, len(sn.Lines))
+		for j, line := range sn.Lines {
+			numbered[j] = fmt.Sprintf("%*d | %s", width, sn.LineStart+j, line)
+		}
+		sn.Lines = numbered
+	}
+}
+
 func checkStale(fullPath string, dbMtime int64) error {
 	info, err := os.Stat(fullPath)
 	if err != nil {
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -142,6 +144,45 @@ func TestQueryEntryNameAmbiguous(t *testing.T) {
 	}
 }
 
+func TestQueryEntryNameAllowAmbiguous(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_ambiguous_name")
+	buildForQuery(t, vault)
+
+	result, err := Query(vault, EntrySpec{Name: "A"}, QueryOptions{AllowAmbiguous: true})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if result.Entry.Type != "" {
+		t.Errorf("Entry = %+v, want zero", result.Entry)
+	}
+	var paths []string
+	for _, c := range result.Candidates {
+		paths = append(paths, c.Path)
+	}
+	if want := []string{"sub1/A.md", "sub2/A.md"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Candidates = %v, want %v", paths, want)
+	}
+}
+
+func TestQueryEntryNameAllowAmbiguousUnique(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_ambiguous_name")
+	if err := os.WriteFile(filepath.Join(vault, "A.md"), []byte("# A at root\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	buildForQuery(t, vault)
+
+	result, err := Query(vault, EntrySpec{Name: "A"}, QueryOptions{AllowAmbiguous: true})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if result.Entry.Path != "A.md" {
+		t.Errorf("Path = %q, want %q", result.Entry.Path, "A.md")
+	}
+	if result.Candidates != nil {
+		t.Errorf("Candidates = %v, want nil", result.Candidates)
+	}
+}
+
 func TestQueryEntryNameRootPriority(t *testing.T) {
 	vault := copyVaultForQuery(t, "vault_query_ambiguous_name")
 	// Add A.md at root — root-priority resolves [[A]] to root file.
@@ -162,6 +203,22 @@ func TestQueryEntryNameRootPriority(t *testing.T) {
 	}
 }
 
+func TestQueryEntryNameRootPriorityDisabled(t *testing.T) {
+	// With root_priority: false, the root A.md no longer wins over sub1/A.md
+	// and sub2/A.md.
+	vault := copyVaultForQuery(t, "vault_query_ambiguous_name")
+	writeVaultFiles(t, vault, map[string]string{
+		"mdhop.yaml": "build:\n  root_priority: false\n",
+		"A.md":       "# A at root\n",
+	})
+	buildForQuery(t, vault)
+
+	_, err := Query(vault, EntrySpec{Name: "A"}, QueryOptions{})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous name: A matches 3 notes") {
+		t.Fatalf("error = %v, want ambiguous name: A matches 3 notes", err)
+	}
+}
+
 func TestQueryErrorMultipleEntry(t *testing.T) {
 	vault := setupFullVault(t)
 	_, err := Query(vault, EntrySpec{File: "Index.md", Tag: "overview"}, QueryOptions{})
@@ -279,6 +336,73 @@ func TestQueryBacklinksLimit(t *testing.T) {
 	}
 }
 
+func TestQueryBacklinksOffsetPaging(t *testing.T) {
+	vault := t.TempDir()
+	files := map[string]string{
+		"Hub.md":       "# Hub\n",
+		"a/N1.md":      "[[Hub]]\n",
+		"b/N2.md":      "[[Hub]]\n[[Hub]]\n",
+		"N3.md":        "[[Hub]]\n",
+		"c/d/N4.md":    "[[Hub]]\n",
+		"N5.md":        "[Hub](Hub.md)\n",
+		"Unrelated.md": "nothing\n",
+	}
+	for rel, content := range files {
+		full := filepath.Join(vault, rel)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buildForQuery(t, vault)
+
+	var paged []string
+	for offset := 0; offset < 10; offset += 2 {
+		res, err := Query(vault, EntrySpec{File: "Hub.md"}, QueryOptions{
+			Fields:       []string{"backlinks"},
+			MaxBacklinks: 2,
+			Offset:       offset,
+		})
+		if err != nil {
+			t.Fatalf("offset %d: %v", offset, err)
+		}
+		if res.TotalBacklinks != 5 {
+			t.Errorf("offset %d: TotalBacklinks = %d, want 5", offset, res.TotalBacklinks)
+		}
+		if len(res.Backlinks) > 2 {
+			t.Errorf("offset %d: page size = %d, want <= 2", offset, len(res.Backlinks))
+		}
+		for _, bl := range res.Backlinks {
+			paged = append(paged, bl.Path)
+		}
+	}
+
+	// Pages concatenate to the full list, ordered by path, with no duplicates.
+	want := []string{"N3.md", "N5.md", "a/N1.md", "b/N2.md", "c/d/N4.md"}
+	if strings.Join(paged, ",") != strings.Join(want, ",") {
+		t.Errorf("paged backlinks = %v, want %v", paged, want)
+	}
+}
+
+func TestQueryBacklinksOffsetBeyondEnd(t *testing.T) {
+	vault := setupFullVault(t)
+	res, err := Query(vault, EntrySpec{File: "Design.md"}, QueryOptions{
+		Fields: []string{"backlinks"},
+		Offset: 10,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Backlinks) != 0 {
+		t.Errorf("backlinks = %v, want none", res.Backlinks)
+	}
+	if res.TotalBacklinks != 2 {
+		t.Errorf("TotalBacklinks = %d, want 2", res.TotalBacklinks)
+	}
+}
+
 func TestQueryBacklinksDistinct(t *testing.T) {
 	vault := setupFullVault(t)
 	// sub/Impl.md is linked from Index.md thrice (wikilink + markdown + relative wikilink).
@@ -297,8 +421,162 @@ func TestQueryBacklinksDistinct(t *testing.T) {
 	}
 }
 
+// TestQueryBacklinksEveryOccurrence covers the "find all references" mode:
+// Backlinks stays one entry per source note, while PerEdge lists each link
+// from that note with its line.
+func TestQueryBacklinksEveryOccurrence(t *testing.T) {
+	vault := setupFullVault(t)
+	res, err := Query(vault, EntrySpec{File: "sub/Impl.md"}, QueryOptions{Fields: []string{"backlinks"}, PerEdge: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	count := 0
+	for _, bl := range res.Backlinks {
+		if bl.Name == "Index" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Index appears %d times in backlinks, want 1", count)
+	}
+
+	var lines []int
+	for _, p := range res.BacklinkPositions {
+		if p.Node.Path == "Index.md" {
+			lines = append(lines, p.LineStart)
+		}
+	}
+	if !reflect.DeepEqual(lines, []int{10, 13, 15}) {
+		t.Errorf("Index.md occurrences at lines %v, want [10 13 15]", lines)
+	}
+}
+
+// TestQueryBacklinksHeading covers heading-scoped backlinks: only
+// Design.md's [[sub/Impl#Details]] links into the Details heading, so
+// Index.md's plain links to sub/Impl are dropped.
+func TestQueryBacklinksHeading(t *testing.T) {
+	vault := setupFullVault(t)
+	for _, heading := range []string{"Details", "#details"} {
+		res, err := Query(vault, EntrySpec{File: "sub/Impl.md", Heading: heading}, QueryOptions{Fields: []string{"backlinks"}, PerEdge: true})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", heading, err)
+		}
+		if len(res.Backlinks) != 1 || res.Backlinks[0].Path != "Design.md" {
+			t.Errorf("%s: backlinks = %v, want [Design.md]", heading, res.Backlinks)
+		}
+		if res.TotalBacklinks != 1 {
+			t.Errorf("%s: TotalBacklinks = %d, want 1", heading, res.TotalBacklinks)
+		}
+		if len(res.BacklinkPositions) != 1 || res.BacklinkPositions[0].RawLink != "[[sub/Impl#Details]]" {
+			t.Errorf("%s: positions = %+v, want only [[sub/Impl#Details]]", heading, res.BacklinkPositions)
+		}
+	}
+
+	res, err := Query(vault, EntrySpec{File: "sub/Impl.md", Heading: "Impl"}, QueryOptions{Fields: []string{"backlinks"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Backlinks) != 0 {
+		t.Errorf("backlinks to #Impl = %v, want none", res.Backlinks)
+	}
+
+	_, err = Query(vault, EntrySpec{Tag: "#design", Heading: "Details"}, QueryOptions{})
+	if err == nil || !strings.Contains(err.Error(), "heading requires a note entry") {
+		t.Errorf("expected heading entry error, got: %v", err)
+	}
+}
+
 // --- Outgoing tests ---
 
+// setupPositionsVault builds a vault where Src.md links Target twice
+// (lines 1 and 3) and Other.md links it once (line 2).
+func setupPositionsVault(t *testing.T) string {
+	t.Helper()
+	vault := t.TempDir()
+	files := map[string]string{
+		"Target.md": "# Target\n",
+		"Src.md":    "[[Target]]\n\nsee [t](Target.md)\n",
+		"Other.md":  "# Other\n[[Target|alias]]\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	return vault
+}
+
+func TestQueryBacklinkPositions(t *testing.T) {
+	vault := setupPositionsVault(t)
+	res, err := Query(vault, EntrySpec{File: "Target.md"}, QueryOptions{Fields: []string{"backlinks"}, Positions: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []LinkPosition{
+		{Node: NodeInfo{Type: "note", Name: "Other", Path: "Other.md", Exists: true}, LineStart: 2, LineEnd: 2, RawLink: "[[Target|alias]]"},
+		{Node: NodeInfo{Type: "note", Name: "Src", Path: "Src.md", Exists: true}, LineStart: 1, LineEnd: 1, RawLink: "[[Target]]"},
+	}
+	if len(res.BacklinkPositions) != len(want) {
+		t.Fatalf("positions = %+v, want %+v", res.BacklinkPositions, want)
+	}
+	for i := range want {
+		if res.BacklinkPositions[i].Node.Path != want[i].Node.Path ||
+			res.BacklinkPositions[i].LineStart != want[i].LineStart ||
+			res.BacklinkPositions[i].RawLink != want[i].RawLink {
+			t.Errorf("positions[%d] = %+v, want %+v", i, res.BacklinkPositions[i], want[i])
+		}
+	}
+	// Backlinks stay deduplicated by source.
+	if len(res.Backlinks) != 2 {
+		t.Errorf("backlinks = %+v, want 2", res.Backlinks)
+	}
+}
+
+func TestQueryBacklinkPositionsPerEdge(t *testing.T) {
+	vault := setupPositionsVault(t)
+	res, err := Query(vault, EntrySpec{File: "Target.md"}, QueryOptions{Fields: []string{"backlinks"}, PerEdge: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var srcLines []int
+	for _, p := range res.BacklinkPositions {
+		if p.Node.Path == "Src.md" {
+			srcLines = append(srcLines, p.LineStart)
+		}
+	}
+	if len(res.BacklinkPositions) != 3 || len(srcLines) != 2 || srcLines[0] != 1 || srcLines[1] != 3 {
+		t.Errorf("positions = %+v, want Other:2, Src:1, Src:3", res.BacklinkPositions)
+	}
+}
+
+func TestQueryOutgoingPositions(t *testing.T) {
+	vault := setupPositionsVault(t)
+	res, err := Query(vault, EntrySpec{File: "Src.md"}, QueryOptions{Fields: []string{"outgoing"}, PerEdge: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.OutgoingPositions) != 2 {
+		t.Fatalf("outgoing positions = %+v, want 2", res.OutgoingPositions)
+	}
+	if p := res.OutgoingPositions[1]; p.Node.Path != "Target.md" || p.LineStart != 3 || p.RawLink != "[t](Target.md)" {
+		t.Errorf("outgoing[1] = %+v, want Target.md at line 3", p)
+	}
+}
+
+func TestQueryPositionsNotRequested(t *testing.T) {
+	vault := setupPositionsVault(t)
+	res, err := Query(vault, EntrySpec{File: "Target.md"}, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.BacklinkPositions != nil || res.OutgoingPositions != nil {
+		t.Errorf("positions should be nil without Positions: %+v %+v", res.BacklinkPositions, res.OutgoingPositions)
+	}
+}
+
 func TestQueryOutgoing(t *testing.T) {
 	vault := setupFullVault(t)
 	res, err := Query(vault, EntrySpec{File: "Index.md"}, QueryOptions{Fields: []string{"outgoing"}})
@@ -323,6 +601,85 @@ func TestQueryOutgoing(t *testing.T) {
 	}
 }
 
+func TestQueryOutgoingTargetTypes(t *testing.T) {
+	vault := setupFullVault(t)
+
+	res, err := Query(vault, EntrySpec{File: "Index.md"}, QueryOptions{
+		Fields:              []string{"outgoing"},
+		OutgoingTargetTypes: []string{"phantom"},
+		Positions:           true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := nodeNames(res.Outgoing); len(names) != 1 || names[0] != "Missing" {
+		t.Errorf("phantom outgoing = %v, want [Missing]", names)
+	}
+	if len(res.OutgoingPositions) != 1 || res.OutgoingPositions[0].Node.Name != "Missing" {
+		t.Errorf("phantom outgoing positions = %+v, want Missing only", res.OutgoingPositions)
+	}
+
+	res, err = Query(vault, EntrySpec{File: "Index.md"}, QueryOptions{
+		Fields:              []string{"outgoing"},
+		OutgoingTargetTypes: []string{"note"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := nodeNames(res.Outgoing)
+	expectContains(t, names, "Design")
+	expectContains(t, names, "Impl")
+	if len(names) != 2 {
+		t.Errorf("note outgoing = %v, want [Design Impl]", names)
+	}
+
+	_, err = Query(vault, EntrySpec{File: "Index.md"}, QueryOptions{OutgoingTargetTypes: []string{"tag"}})
+	if err == nil || !strings.Contains(err.Error(), "unknown outgoing target type") {
+		t.Errorf("expected unknown target type error, got: %v", err)
+	}
+}
+
+func TestQueryIncludeSelf(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "# Intro\n\nSee [[#Intro]] and [[B]].\n",
+		"B.md": "[[A]]\n",
+	})
+	buildVault(t, vault)
+
+	fields := []string{"backlinks", "outgoing", "twohop"}
+	res, err := Query(vault, EntrySpec{File: "A.md"}, QueryOptions{Fields: fields, Positions: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := nodeNames(res.Backlinks); len(names) != 1 || names[0] != "B" {
+		t.Errorf("backlinks = %v, want [B]", names)
+	}
+	if res.TotalBacklinks != 1 || len(res.BacklinkPositions) != 1 {
+		t.Errorf("total = %d, positions = %+v, want B only", res.TotalBacklinks, res.BacklinkPositions)
+	}
+	if names := nodeNames(res.Outgoing); len(names) != 1 || names[0] != "B" {
+		t.Errorf("outgoing = %v, want [B]", names)
+	}
+	// A's self-link must not make A a via to its own backlinks.
+	for _, th := range res.TwoHop {
+		if th.Via.Path == "A.md" {
+			t.Errorf("twohop via A.md: %+v", th)
+		}
+	}
+
+	res, err = Query(vault, EntrySpec{File: "A.md"}, QueryOptions{Fields: fields, IncludeSelf: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := nodeNames(res.Backlinks)
+	if len(names) != 2 || res.TotalBacklinks != 2 {
+		t.Errorf("backlinks with IncludeSelf = %v (total %d), want [A B]", names, res.TotalBacklinks)
+	}
+	expectContains(t, names, "A")
+	expectContains(t, nodeNames(res.Outgoing), "A")
+}
+
 func TestQueryOutgoingExcludesTags(t *testing.T) {
 	vault := setupFullVault(t)
 	res, err := Query(vault, EntrySpec{File: "Index.md"}, QueryOptions{Fields: []string{"outgoing"}})
@@ -389,6 +746,36 @@ func TestQueryTagsNonNote(t *testing.T) {
 	}
 }
 
+func TestQueryTagsWithLocation(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_build_tags")
+	buildForQuery(t, vault)
+	res, err := Query(vault, EntrySpec{File: "A.md"}, QueryOptions{Fields: []string{"tags"}, TagsWithLocation: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A.md: frontmatter fm_tag (line 3) and nested/deep/tag (line 4), inline
+	// #simple (line 8) and #parent/child (line 9). Ancestors are not listed.
+	var got []string
+	for _, l := range res.TagLocations {
+		got = append(got, fmt.Sprintf("%s:%d:%s", l.Tag, l.Line, l.Source))
+	}
+	want := "#fm_tag:3:frontmatter,#nested/deep/tag:4:frontmatter,#parent/child:9:inline,#simple:8:inline"
+	if strings.Join(got, ",") != want {
+		t.Errorf("tag locations = %v, want %s", got, want)
+	}
+	if len(res.Tags) != 4 {
+		t.Errorf("tags = %v, want 4 leaf tags", res.Tags)
+	}
+
+	res, err = Query(vault, EntrySpec{File: "A.md"}, QueryOptions{Fields: []string{"tags"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.TagLocations != nil {
+		t.Errorf("tag locations = %v, want nil when not requested", res.TagLocations)
+	}
+}
+
 // --- TwoHop tests ---
 
 func TestQueryTwoHop(t *testing.T) {
@@ -496,6 +883,91 @@ func TestQueryTwoHopTagVia(t *testing.T) {
 	}
 }
 
+// setupCoCitationVault builds a vault where Entry links to ViaA and ViaB.
+// Zeta links to both (weight 2); Alpha links only to ViaA (weight 1).
+func setupCoCitationVault(t *testing.T) string {
+	t.Helper()
+	vault := t.TempDir()
+	files := map[string]string{
+		"Entry.md": "[[ViaA]] [[ViaB]]\n",
+		"ViaA.md":  "# A\n",
+		"ViaB.md":  "# B\n",
+		"Alpha.md": "[[ViaA]]\n",
+		"Zeta.md":  "[[ViaA]]\n[[ViaB]]\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(vault, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	return vault
+}
+
+func TestQueryTwoHopRanked(t *testing.T) {
+	vault := setupCoCitationVault(t)
+	res, err := Query(vault, EntrySpec{File: "Entry.md"}, QueryOptions{Fields: []string{"twohop-ranked"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.TwoHop != nil {
+		t.Errorf("twohop = %v, want nil when only twohop-ranked is requested", res.TwoHop)
+	}
+	if len(res.TwoHopRanked) != 2 {
+		t.Fatalf("twohop-ranked = %+v, want 2 targets", res.TwoHopRanked)
+	}
+	// Default order is by path.
+	alpha, zeta := res.TwoHopRanked[0], res.TwoHopRanked[1]
+	if alpha.Target.Path != "Alpha.md" || alpha.Weight != 1 {
+		t.Errorf("first = %s (weight %d), want Alpha.md (weight 1)", alpha.Target.Path, alpha.Weight)
+	}
+	if zeta.Target.Path != "Zeta.md" || zeta.Weight != 2 {
+		t.Errorf("second = %s (weight %d), want Zeta.md (weight 2)", zeta.Target.Path, zeta.Weight)
+	}
+	if len(zeta.Vias) != 2 || zeta.Vias[0].Path != "ViaA.md" || zeta.Vias[1].Path != "ViaB.md" {
+		t.Errorf("Zeta vias = %+v, want [ViaA.md ViaB.md]", zeta.Vias)
+	}
+}
+
+func TestQueryTwoHopRankedSortByWeight(t *testing.T) {
+	vault := setupCoCitationVault(t)
+	res, err := Query(vault, EntrySpec{File: "Entry.md"}, QueryOptions{
+		Fields:          []string{"twohop-ranked"},
+		SortByWeight:    true,
+		MaxViaPerTarget: 1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.TwoHopRanked) != 2 {
+		t.Fatalf("twohop-ranked = %+v, want 2 targets", res.TwoHopRanked)
+	}
+	top := res.TwoHopRanked[0]
+	if top.Target.Path != "Zeta.md" || top.Weight != 2 {
+		t.Errorf("first = %s (weight %d), want Zeta.md (weight 2)", top.Target.Path, top.Weight)
+	}
+	// MaxViaPerTarget caps the listed vias but not the weight.
+	if len(top.Vias) != 1 {
+		t.Errorf("Zeta vias = %+v, want 1", top.Vias)
+	}
+}
+
+func TestQueryTwoHopRankedNotDefault(t *testing.T) {
+	vault := setupCoCitationVault(t)
+	res, err := Query(vault, EntrySpec{File: "Entry.md"}, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.TwoHopRanked != nil {
+		t.Errorf("twohop-ranked = %+v, want nil without explicit field", res.TwoHopRanked)
+	}
+	if len(res.TwoHop) != 2 {
+		t.Errorf("twohop = %+v, want 2 via entries", res.TwoHop)
+	}
+}
+
 func TestQueryTwoHopPhantom(t *testing.T) {
 	vault := setupFullVault(t)
 	// Missing is a phantom linked from Index.md.
@@ -580,6 +1052,41 @@ func TestQueryHeadNoFrontmatter(t *testing.T) {
 	}
 }
 
+func TestQueryHeadIncludeFrontmatter(t *testing.T) {
+	vault := setupFullVault(t)
+	res, err := Query(vault, EntrySpec{File: "Index.md"}, QueryOptions{
+		Fields:             []string{"head"},
+		IncludeHead:        3,
+		IncludeFrontmatter: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"---", "tags:", "  - project"}
+	if len(res.Head) != len(want) {
+		t.Fatalf("head lines = %d, want %d: %v", len(res.Head), len(want), res.Head)
+	}
+	for i, line := range want {
+		if res.Head[i] != line {
+			t.Errorf("head[%d] = %q, want %q", i, res.Head[i], line)
+		}
+	}
+
+	// The stale check applies as without the option.
+	time.Sleep(1100 * time.Millisecond)
+	path := filepath.Join(vault, "Index.md")
+	content, _ := os.ReadFile(path)
+	os.WriteFile(path, append(content, []byte("\nmodified\n")...), 0o644)
+	_, err = Query(vault, EntrySpec{File: "Index.md"}, QueryOptions{
+		Fields:             []string{"head"},
+		IncludeHead:        3,
+		IncludeFrontmatter: true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Errorf("error = %v, want stale", err)
+	}
+}
+
 func TestQueryHeadStale(t *testing.T) {
 	vault := setupFullVault(t)
 	// Modify the file after build to make it stale.
@@ -614,6 +1121,289 @@ func TestQueryHeadNotRequested(t *testing.T) {
 	}
 }
 
+// --- Alias tests ---
+
+func TestQueryNameResolvesViaAlias(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_alias")
+	buildForQuery(t, vault)
+	res, err := Query(vault, EntrySpec{Name: "foo"}, QueryOptions{Fields: []string{"backlinks"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Entry.Path != "Bar.md" {
+		t.Errorf("entry path = %q, want Bar.md", res.Entry.Path)
+	}
+	if res.ViaAlias != "foo" {
+		t.Errorf("via alias = %q, want foo", res.ViaAlias)
+	}
+	if len(res.Entry.Aliases) != 2 || res.Entry.Aliases[0] != "Foo" || res.Entry.Aliases[1] != "Shared" {
+		t.Errorf("aliases = %v, want [Foo Shared]", res.Entry.Aliases)
+	}
+}
+
+func TestQueryNameBasenameNotViaAlias(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_alias")
+	buildForQuery(t, vault)
+	res, err := Query(vault, EntrySpec{Name: "Bar"}, QueryOptions{Fields: []string{"backlinks"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ViaAlias != "" {
+		t.Errorf("via alias = %q, want empty", res.ViaAlias)
+	}
+	if len(res.Entry.Aliases) != 2 {
+		t.Errorf("aliases = %v, want 2 entries", res.Entry.Aliases)
+	}
+}
+
+func TestQueryNameAliasBasenameCollision(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_alias")
+	buildForQuery(t, vault)
+	_, err := Query(vault, EntrySpec{Name: "Shared"}, QueryOptions{})
+	if err == nil {
+		t.Fatal("expected ambiguous error, got nil")
+	}
+	for _, want := range []string{"ambiguous name", "Shared.md (basename)", "Bar.md (alias)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want containing %q", err.Error(), want)
+		}
+	}
+}
+
+// --- Tag intersection tests ---
+
+func TestQueryTagsIntersection(t *testing.T) {
+	vault := setupFullVault(t)
+	// #status matches Index.md via nested expansion of #status/active.
+	res, err := QueryTags(vault, EntrySpec{Tags: []string{"project", "#status"}}, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Notes) != 1 || res.Notes[0].Path != "Index.md" {
+		t.Errorf("notes = %+v, want [Index.md]", res.Notes)
+	}
+	if len(res.Tags) != 2 || res.Tags[0] != "#project" || res.Tags[1] != "#status" {
+		t.Errorf("tags = %v, want [#project #status]", res.Tags)
+	}
+}
+
+func TestQueryTagsSharedTag(t *testing.T) {
+	vault := setupFullVault(t)
+	res, err := QueryTags(vault, EntrySpec{Tags: []string{"#overview"}}, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"Design.md", "Index.md"}
+	if len(res.Notes) != len(want) {
+		t.Fatalf("notes = %+v, want %v", res.Notes, want)
+	}
+	for i, p := range want {
+		if res.Notes[i].Path != p {
+			t.Errorf("notes[%d] = %q, want %q", i, res.Notes[i].Path, p)
+		}
+	}
+}
+
+func TestQueryTagsEmptyResult(t *testing.T) {
+	vault := setupFullVault(t)
+	res, err := QueryTags(vault, EntrySpec{Tags: []string{"#design", "#project"}}, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Notes == nil || len(res.Notes) != 0 {
+		t.Errorf("notes = %+v, want empty", res.Notes)
+	}
+}
+
+func TestQueryTagsCombinedWithFileError(t *testing.T) {
+	vault := setupFullVault(t)
+	_, err := QueryTags(vault, EntrySpec{Tags: []string{"#design"}, File: "Index.md"}, QueryOptions{})
+	if err == nil || !strings.Contains(err.Error(), "multiple entry specs") {
+		t.Errorf("expected multiple entry specs error, got: %v", err)
+	}
+}
+
+// --- Headings tests ---
+
+// --- Tag tree tests ---
+
+func TestQueryTagTreeUnicode(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_build_tags_unicode")
+	// B carries the parent tag directly; A only through #parent/子タグ.
+	if err := os.WriteFile(filepath.Join(vault, "B.md"), []byte("# B\n\nShared #my-tag tag.\nDirect #parent tag.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	buildForQuery(t, vault)
+
+	res, err := QueryTagTree(vault, EntrySpec{Tag: "parent"}, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	root := res.Root
+	if root.Tag != "#parent" {
+		t.Errorf("root tag = %q, want #parent", root.Tag)
+	}
+	if names := nodeNames(root.Notes); len(names) != 1 || names[0] != "B" {
+		t.Errorf("root notes = %v, want [B]", names)
+	}
+	if len(root.Children) != 1 {
+		t.Fatalf("children = %d, want 1", len(root.Children))
+	}
+	child := root.Children[0]
+	if child.Tag != "#parent/子タグ" {
+		t.Errorf("child tag = %q, want #parent/子タグ", child.Tag)
+	}
+	// A is listed under its leaf tag only, not double-counted under #parent.
+	if names := nodeNames(child.Notes); len(names) != 1 || names[0] != "A" {
+		t.Errorf("child notes = %v, want [A]", names)
+	}
+}
+
+func TestQueryTagTreeDeepNesting(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_build_tags")
+	buildForQuery(t, vault)
+
+	res, err := QueryTagTree(vault, EntrySpec{Tag: "#nested"}, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// #nested → #nested/deep → #nested/deep/tag (A)
+	root := res.Root
+	if len(root.Notes) != 0 || len(root.Children) != 1 {
+		t.Fatalf("root = %+v, want no notes and one child", root)
+	}
+	deep := root.Children[0]
+	if deep.Tag != "#nested/deep" || len(deep.Notes) != 0 || len(deep.Children) != 1 {
+		t.Fatalf("deep = %+v", deep)
+	}
+	leaf := deep.Children[0]
+	if leaf.Tag != "#nested/deep/tag" {
+		t.Errorf("leaf tag = %q", leaf.Tag)
+	}
+	if names := nodeNames(leaf.Notes); len(names) != 1 || names[0] != "A" {
+		t.Errorf("leaf notes = %v, want [A]", names)
+	}
+}
+
+func TestQueryTagTreeNotFound(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_build_tags")
+	buildForQuery(t, vault)
+
+	_, err := QueryTagTree(vault, EntrySpec{Tag: "missing"}, QueryOptions{})
+	if err == nil || !strings.Contains(err.Error(), "tag not in index: #missing") {
+		t.Errorf("expected tag not in index error, got %v", err)
+	}
+}
+
+func TestQueryTagParentsUnicode(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_build_tags_unicode")
+	// B carries the parent tag directly; A only through #parent/子タグ.
+	if err := os.WriteFile(filepath.Join(vault, "B.md"), []byte("# B\n\nShared #my-tag tag.\nDirect #parent tag.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	buildForQuery(t, vault)
+
+	res, err := QueryTagParents(vault, EntrySpec{Tag: "#parent/子タグ"}, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Tag != "#parent/子タグ" {
+		t.Errorf("tag = %q, want #parent/子タグ", res.Tag)
+	}
+	if len(res.Ancestors) != 1 || res.Ancestors[0].Tag != "#parent" {
+		t.Fatalf("ancestors = %+v, want [#parent]", res.Ancestors)
+	}
+	// A is tagged at #parent only through its child tag, so it is not listed.
+	if names := nodeNames(res.Ancestors[0].Notes); len(names) != 1 || names[0] != "B" {
+		t.Errorf("#parent notes = %v, want [B]", names)
+	}
+
+	// A top-level tag has no ancestors.
+	res, err = QueryTagParents(vault, EntrySpec{Tag: "parent"}, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Ancestors) != 0 {
+		t.Errorf("ancestors of #parent = %+v, want none", res.Ancestors)
+	}
+}
+
+func TestQueryTagParentsDeepNesting(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_build_tags")
+	buildForQuery(t, vault)
+
+	res, err := QueryTagParents(vault, EntrySpec{Tag: "#nested/deep/tag"}, QueryOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var tags []string
+	for _, a := range res.Ancestors {
+		tags = append(tags, a.Tag)
+		if len(a.Notes) != 0 {
+			t.Errorf("%s notes = %v, want none", a.Tag, nodeNames(a.Notes))
+		}
+	}
+	if len(tags) != 2 || tags[0] != "#nested" || tags[1] != "#nested/deep" {
+		t.Errorf("ancestors = %v, want [#nested #nested/deep]", tags)
+	}
+}
+
+func TestQueryHeadings(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_headings")
+	buildForQuery(t, vault)
+	res, err := Query(vault, EntrySpec{File: "Outline.md"}, QueryOptions{Fields: []string{"headings"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Heading{
+		{Level: 1, Text: "Title", Line: 4},
+		{Level: 1, Text: "Setext One", Line: 8},
+		{Level: 2, Text: "Details", Line: 11},
+		{Level: 2, Text: "Setext Two", Line: 17},
+		{Level: 3, Text: "C# Notes", Line: 20},
+	}
+	if len(res.Headings) != len(want) {
+		t.Fatalf("headings = %+v, want %+v", res.Headings, want)
+	}
+	for i := range want {
+		if res.Headings[i] != want[i] {
+			t.Errorf("headings[%d] = %+v, want %+v", i, res.Headings[i], want[i])
+		}
+	}
+}
+
+func TestQueryHeadingsNoneForTag(t *testing.T) {
+	vault := setupFullVault(t)
+	res, err := Query(vault, EntrySpec{Tag: "overview"}, QueryOptions{Fields: []string{"headings"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Headings != nil {
+		t.Errorf("headings = %+v, want nil for tag entry", res.Headings)
+	}
+}
+
+func TestQueryHeadingsAfterUpdate(t *testing.T) {
+	vault := copyVaultForQuery(t, "vault_query_headings")
+	buildForQuery(t, vault)
+
+	time.Sleep(1100 * time.Millisecond) // ensure mtime changes (1s resolution)
+	if err := os.WriteFile(filepath.Join(vault, "Plain.md"), []byte("## Added\n\n[[Outline]]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Update(vault, UpdateOptions{Files: []string{"Plain.md"}}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	res, err := Query(vault, EntrySpec{File: "Plain.md"}, QueryOptions{Fields: []string{"headings"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Headings) != 1 || res.Headings[0] != (Heading{Level: 2, Text: "Added", Line: 1}) {
+		t.Errorf("headings = %+v, want [{2 Added 1}]", res.Headings)
+	}
+}
+
 // --- Snippet tests ---
 
 func TestQuerySnippet(t *testing.T) {
@@ -667,6 +1457,68 @@ func TestQuerySnippetStale(t *testing.T) {
 	}
 }
 
+// TestQueryReparseStale covers ReparseStale: head and snippets come from
+// the current content of a note changed since the build, with the link
+// located at its new line, and the index keeps the old mtime.
+func TestQueryReparseStale(t *testing.T) {
+	vault := setupFullVault(t)
+	path := filepath.Join(vault, "Design.md")
+	content, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, append([]byte("Intro\n\n"), content...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Query(vault, EntrySpec{File: "Design.md"}, QueryOptions{
+		Fields:       []string{"head"},
+		IncludeHead:  1,
+		ReparseStale: true,
+	})
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+	if !reflect.DeepEqual(res.Head, []string{"Intro"}) {
+		t.Errorf("head = %v, want [Intro]", res.Head)
+	}
+
+	res, err = Query(vault, EntrySpec{File: "sub/Impl.md"}, QueryOptions{
+		Fields:         []string{"snippet"},
+		IncludeSnippet: 1,
+		ReparseStale:   true,
+	})
+	if err != nil {
+		t.Fatalf("snippet: %v", err)
+	}
+	var design []SnippetEntry
+	for _, sn := range res.Snippets {
+		if sn.SourcePath == "Design.md" {
+			design = append(design, sn)
+		}
+	}
+	want := []SnippetEntry{{
+		SourcePath: "Design.md",
+		LineStart:  5,
+		LineEnd:    7,
+		Lines:      []string{"Back to [[Index]].", "Also see [[sub/Impl#Details]].", "Tag: #overview #design"},
+	}}
+	if !reflect.DeepEqual(design, want) {
+		t.Errorf("Design.md snippets = %+v, want %+v", design, want)
+	}
+
+	db := openTestDB(t, dbPath(vault))
+	defer db.Close()
+	var dbMtime int64
+	if err := db.QueryRow("SELECT mtime FROM nodes WHERE path = 'Design.md'").Scan(&dbMtime); err != nil {
+		t.Fatal(err)
+	}
+	if dbMtime == future.Unix() {
+		t.Error("ReparseStale must not update the index")
+	}
+}
+
 // --- Fields filter tests ---
 
 func TestQueryFieldsFilter(t *testing.T) {
@@ -765,6 +1617,59 @@ func TestQuerySnippetContextLines(t *testing.T) {
 	}
 }
 
+func TestQuerySnippetParagraph(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"T.md": "# T\n",
+		"S.md": "# S\n\nIntro line.\n\nFirst line\nsee [[T]] here\nlast line\n\nOutro.\n",
+		"E.md": "top [[T]]\nnext\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	res, err := Query(vault, EntrySpec{File: "T.md"}, QueryOptions{
+		Fields:      []string{"snippet"},
+		SnippetMode: "paragraph",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(res.Snippets) != 2 {
+		t.Fatalf("snippets count = %d, want 2", len(res.Snippets))
+	}
+	// E.md: the paragraph starts at the first line of the file.
+	if e := res.Snippets[0]; e.SourcePath != "E.md" || e.LineStart != 1 || e.LineEnd != 2 {
+		t.Errorf("E.md snippet = %+v, want lines 1-2", e)
+	}
+	// S.md: stops at the blank lines around the link paragraph.
+	sn := res.Snippets[1]
+	if sn.SourcePath != "S.md" || sn.LineStart != 5 || sn.LineEnd != 7 {
+		t.Errorf("S.md snippet = %+v, want lines 5-7", sn)
+	}
+	if want := []string{"First line", "see [[T]] here", "last line"}; !reflect.DeepEqual(sn.Lines, want) {
+		t.Errorf("S.md lines = %q, want %q", sn.Lines, want)
+	}
+
+	// lines mode keeps the fixed radius and crosses paragraph boundaries.
+	res, err = Query(vault, EntrySpec{File: "T.md"}, QueryOptions{
+		Fields:         []string{"snippet"},
+		IncludeSnippet: 2,
+		SnippetMode:    "lines",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sn := res.Snippets[1]; sn.LineStart != 4 || sn.LineEnd != 8 {
+		t.Errorf("lines mode S.md snippet = %d-%d, want 4-8", sn.LineStart, sn.LineEnd)
+	}
+
+	if _, err := Query(vault, EntrySpec{File: "T.md"}, QueryOptions{SnippetMode: "sentence"}); err == nil ||
+		!strings.Contains(err.Error(), "unknown snippet mode") {
+		t.Errorf("expected unknown snippet mode error, got: %v", err)
+	}
+}
+
 func TestQuerySnippetBoundary(t *testing.T) {
 	vault := setupFullVault(t)
 	res, err := Query(vault, EntrySpec{File: "Design.md"}, QueryOptions{
@@ -852,3 +1757,124 @@ func expectContains(t *testing.T, list []string, want string) {
 	}
 	t.Errorf("expected %q in %v", want, list)
 }
+
+func TestQuerySnippetQueryRanking(t *testing.T) {
+	vault := t.TempDir()
+	files := map[string]string{
+		"Target.md": "# Target\n",
+		"A.md":      "Unrelated note.\nSee [[Target]].\n",
+		"B.md":      "The caching layer.\nSee [[Target]] for caching.\n",
+	}
+	for rel, content := range files {
+		if err := os.WriteFile(filepath.Join(vault, rel), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	sources := func(opts QueryOptions) []string {
+		t.Helper()
+		res, err := Query(vault, EntrySpec{File: "Target.md"}, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var out []string
+		for _, s := range res.Snippets {
+			out = append(out, s.SourcePath)
+		}
+		return out
+	}
+
+	// Without a query: source path order.
+	got := sources(QueryOptions{Fields: []string{"snippet"}, IncludeSnippet: 1})
+	if len(got) != 2 || got[0] != "A.md" || got[1] != "B.md" {
+		t.Errorf("default order = %v, want [A.md B.md]", got)
+	}
+
+	// The term appears only around B's link, so B ranks first (case-insensitive).
+	got = sources(QueryOptions{Fields: []string{"snippet"}, IncludeSnippet: 1, SnippetQuery: "Caching"})
+	if len(got) != 2 || got[0] != "B.md" || got[1] != "A.md" {
+		t.Errorf("ranked order = %v, want [B.md A.md]", got)
+	}
+
+	// No matches anywhere: ties fall back to source path.
+	got = sources(QueryOptions{Fields: []string{"snippet"}, IncludeSnippet: 1, SnippetQuery: "zzz"})
+	if len(got) != 2 || got[0] != "A.md" || got[1] != "B.md" {
+		t.Errorf("tie order = %v, want [A.md B.md]", got)
+	}
+}
+
+func TestQueryTitle(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"ATX.md":      "---\ntags: [x]\n---\nintro\n\n## Closing Hashes ##\n# Later\n",
+		"Setext.md":   "Plain Title\n===========\n\n# Not First\n",
+		"Untitled.md": "no heading here\n\n```\n# in a code fence\n```\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"ATX.md", "Closing Hashes"},
+		{"Setext.md", "Plain Title"},
+		{"Untitled.md", "Untitled"},
+	}
+	for _, tt := range tests {
+		result, err := Query(vault, EntrySpec{File: tt.file}, QueryOptions{Fields: []string{"title"}})
+		if err != nil {
+			t.Fatalf("query %s: %v", tt.file, err)
+		}
+		if result.Title != tt.want {
+			t.Errorf("%s: Title = %q, want %q", tt.file, result.Title, tt.want)
+		}
+		if result.Headings != nil {
+			t.Errorf("%s: Headings should be nil when only title is requested", tt.file)
+		}
+	}
+}
+
+func TestQuerySnippetGutter(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"T.md": "# T\n",
+		"S.md": "1\n2\n3\n4\n5\n6\n7\nbefore\nsee [[T]]\nafter\n11\n",
+	})
+	buildVault(t, vault)
+
+	opts := QueryOptions{Fields: []string{"snippet"}, IncludeSnippet: 1}
+	plain, err := Query(vault, EntrySpec{File: "T.md"}, opts)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	opts.SnippetGutter = true
+	numbered, err := Query(vault, EntrySpec{File: "T.md"}, opts)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(plain.Snippets) != 1 || len(numbered.Snippets) != 1 {
+		t.Fatalf("snippets = %+v / %+v, want 1 each", plain.Snippets, numbered.Snippets)
+	}
+
+	p, n := plain.Snippets[0], numbered.Snippets[0]
+	if p.LineStart != 8 || n.LineStart != 8 || n.LineEnd != 10 {
+		t.Fatalf("range = %d-%d, want 8-10", n.LineStart, n.LineEnd)
+	}
+	want := []string{" 8 | before", " 9 | see [[T]]", "10 | after"}
+	if strings.Join(n.Lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("numbered lines = %q, want %q", n.Lines, want)
+	}
+	for i, line := range p.Lines {
+		if !strings.HasSuffix(n.Lines[i], " | "+line) || !strings.HasPrefix(strings.TrimSpace(n.Lines[i]), strconv.Itoa(p.LineStart+i)+" ") {
+			t.Errorf("line %d: %q does not number %q as %d", i, n.Lines[i], line, p.LineStart+i)
+		}
+	}
+	if p.Lines[0] != "before" {
+		t.Errorf("plain lines = %q, want no gutter", p.Lines)
+	}
+}
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// RebuildEdges re-parses every registered note and recreates all edges, like
+// Build, but in the existing index: note and asset nodes keep their ids, and
+// tag and phantom nodes keep theirs while still referenced. Use it after
+// changing parse settings in mdhop.yaml. The set of files is not rescanned;
+// notes missing on disk are an error (run update or build first). Links are
+// resolved against the whole vault, as update does.
+func RebuildEdges(vaultPath string) error {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := ensureEdgeCol(db); err != nil {
+		return err
+	}
+
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return err
+	}
+
+	rm, err := buildMapsFromDB(db)
+	if err != nil {
+		return err
+	}
+	rm.applyBuildConfig(cfg.Build)
+
+	paths := make([]string, 0, len(rm.pathToID))
+	for p := range rm.pathToID {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	parsed := make([]parsedFile, 0, len(paths))
+	var userErrors []BuildIssue
+	for _, p := range paths {
+		fullPath := filepath.Join(vaultPath, p)
+		info, err := os.Stat(fullPath)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("note file not found on disk: %s (run 'mdhop update' first)", p)
+		}
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(fullPath)
+		if err != nil {
+			return err
+		}
+		links := parseIndexLinks(string(content), cfg.Build)
+		for _, link := range links {
+			if !isFileLinkType(link.linkType) {
+				continue
+			}
+			issue := BuildIssue{File: p, Line: link.lineStart}
+			if (link.isRelative && escapesVault(p, link.target)) ||
+				(!link.isRelative && !link.isBasename && pathEscapesVault(link.target)) {
+				issue.Kind = "escape"
+				issue.Message = fmt.Sprintf("link escapes vault: %s in %s", link.rawLink, p)
+			} else if link.isBasename && isAmbiguousBasenameLink(p, link.target, rm) {
+				issue.Kind = "ambiguous"
+				issue.Message = fmt.Sprintf("ambiguous link: %s in %s", link.target, p)
+				issue.Name = link.target
+			} else if isAmbiguousFolderNoteLink(p, link, rm) {
+				issue.Kind = "ambiguous"
+				issue.Message = fmt.Sprintf("ambiguous link: %s in %s (note and folder note)", link.target, p)
+				issue.Name = link.target
+			} else {
+				continue
+			}
+			userErrors = append(userErrors, issue)
+			if len(userErrors) >= maxBuildErrors {
+				return formatBuildErrors(userErrors)
+			}
+		}
+		parsed = append(parsed, parsedFile{
+			path:     p,
+			mtime:    info.ModTime().Unix(),
+			links:    links,
+			headings: parseHeadings(string(content)),
+			external: parseExternalLinks(string(content)),
+			aliases:  parseAliases(string(content)),
+			body:     string(content),
+		})
+	}
+	if len(userErrors) > 0 {
+		return formatBuildErrors(userErrors)
+	}
+
+	rm.noteAliases = make(map[string][]string)
+	for _, pf := range parsed {
+		if len(pf.aliases) > 0 {
+			rm.noteAliases[pf.path] = pf.aliases
+		}
+	}
+	rm.aliasToPath = aliasTargets(rm.noteAliases)
+
+	dbTx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+	tx := newStmtCache(dbTx)
+	defer tx.close()
+	nt, err := newNoteText(tx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM edges"); err != nil {
+		return err
+	}
+	edges := 0
+	for _, pf := range parsed {
+		id := rm.pathToID[pf.path]
+		if _, err := tx.Exec("UPDATE nodes SET mtime = ? WHERE id = ?", pf.mtime, id); err != nil {
+			return err
+		}
+		if err := replaceHeadings(tx, id, pf.headings); err != nil {
+			return err
+		}
+		if err := replaceExternalLinks(tx, id, pf.external); err != nil {
+			return err
+		}
+		if err := replaceAliases(tx, id, pf.aliases); err != nil {
+			return err
+		}
+		if err := nt.replace(tx, id, pf.body); err != nil {
+			return err
+		}
+		for _, link := range pf.links {
+			targetID, subpath, err := resolveLink(tx, pf.path, link, rm)
+			if err != nil {
+				return err
+			}
+			if targetID == 0 {
+				continue
+			}
+			if err := insertEdge(tx, id, targetID, link, subpath); err != nil {
+				return err
+			}
+			edges++
+		}
+	}
+	// Unlike cleanupOrphanedNodes, unreferenced assets stay: their ids are
+	// kept like the notes'.
+	if _, err := tx.Exec(`DELETE FROM nodes WHERE type IN ('tag', 'phantom')
+		AND id NOT IN (SELECT DISTINCT target_id FROM edges)`); err != nil {
+		return err
+	}
+	verbosef("rebuild edges: inserted %d edges for %d notes", edges, len(parsed))
+
+	tx.close()
+	if err := bumpIndexVersion(dbTx); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fileNodeIDs returns the ids of the note and asset nodes keyed by node_key.
+func fileNodeIDs(t *testing.T, dbp string) map[string]int64 {
+	t.Helper()
+	db := openTestDB(t, dbp)
+	defer db.Close()
+	rows, err := db.Query(`SELECT node_key, id FROM nodes WHERE type IN ('note', 'asset')`)
+	if err != nil {
+		t.Fatalf("query nodes: %v", err)
+	}
+	defer rows.Close()
+	ids := make(map[string]int64)
+	for rows.Next() {
+		var key string
+		var id int64
+		if err := rows.Scan(&key, &id); err != nil {
+			t.Fatalf("scan node: %v", err)
+		}
+		ids[key] = id
+	}
+	return ids
+}
+
+func TestRebuildEdgesKeepsNodeIDs(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":      "[[B]] [[{{link}}]] #draft\n",
+		"B.md":      "![[img.png]]\n",
+		"img.png":   "png",
+		"other.pdf": "pdf",
+	})
+	buildVault(t, vault)
+	// Give the new note a higher id than a fresh build would.
+	writeVaultFiles(t, vault, map[string]string{"0.md": "[[A]]\n"})
+	if _, err := Add(vault, AddOptions{Files: []string{"0.md"}}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	before := fileNodeIDs(t, dbPath(vault))
+	if got := countEdges(t, dbPath(vault)); got != 5 {
+		t.Fatalf("edges before = %d, want 5", got)
+	}
+
+	writeVaultFiles(t, vault, map[string]string{"mdhop.yaml": "build:\n  ignore_template_links: true\n"})
+	if err := RebuildEdges(vault); err != nil {
+		t.Fatalf("RebuildEdges: %v", err)
+	}
+
+	after := fileNodeIDs(t, dbPath(vault))
+	if len(after) != len(before) {
+		t.Fatalf("file nodes = %v, want %v", after, before)
+	}
+	for key, id := range before {
+		if after[key] != id {
+			t.Errorf("id of %s = %d, want %d", key, after[key], id)
+		}
+	}
+	// [[{{link}}]] is dropped, and its phantom with it.
+	if got := countEdges(t, dbPath(vault)); got != 4 {
+		t.Errorf("edges after = %d, want 4", got)
+	}
+	if got := queryNodes(t, dbPath(vault), "phantom"); len(got) != 0 {
+		t.Errorf("phantoms = %+v, want none", got)
+	}
+	if got := queryNodes(t, dbPath(vault), "tag"); len(got) != 1 {
+		t.Errorf("tags = %+v, want #draft", got)
+	}
+}
+
+func TestRebuildEdgesMissingNote(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "[[B]]\n",
+		"B.md": "# B\n",
+	})
+	buildVault(t, vault)
+	if err := os.Remove(filepath.Join(vault, "B.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := RebuildEdges(vault)
+	if err == nil || !strings.Contains(err.Error(), "note file not found on disk: B.md") {
+		t.Fatalf("err = %v, want note file not found", err)
+	}
+	if got := countEdges(t, dbPath(vault)); got != 1 {
+		t.Errorf("edges = %d, want 1 (index unchanged)", got)
+	}
+}
@@ -9,7 +9,8 @@ import (
 
 // RepairOptions controls the repair operation.
 type RepairOptions struct {
-	DryRun bool
+	DryRun     bool
+	KeepBackup bool // as MoveOptions.KeepBackup
 }
 
 // RepairResult reports the outcome of the repair operation.
@@ -29,18 +30,26 @@ type SkippedLink struct {
 // Repair rewrites broken path links and vault-escape links to basename links.
 // It works by scanning files directly (no DB required).
 // Vault-escape links are always converted to basename (escape resolution is top priority).
-// Broken path links are converted when 0-1 candidates exist; 2+ candidates are skipped.
+// Broken path links are converted when 0-1 candidates exist. With 2+ candidates,
+// a unique candidate matching the link's directory segments is rewritten to its
+// full path; otherwise the link is skipped.
 func Repair(vaultPath string, opts RepairOptions) (*RepairResult, error) {
-	// Collect all .md files.
-	files, err := collectMarkdownFiles(vaultPath)
+	lock, err := lockIndex(vaultPath)
 	if err != nil {
 		return nil, err
 	}
+	defer lock.unlock()
 
+	// Collect all .md files.
 	cfg, err := LoadConfig(vaultPath)
 	if err != nil {
 		return nil, err
 	}
+
+	files, err := collectMarkdownFiles(vaultPath, cfg.Build)
+	if err != nil {
+		return nil, err
+	}
 	if err := validateGlobPatterns(cfg.Build.ExcludePaths); err != nil {
 		return nil, err
 	}
@@ -103,6 +112,22 @@ func Repair(vaultPath string, opts RepairOptions) (*RepairResult, error) {
 			candidates := basenameMap[bk]
 
 			if !escaping && len(candidates) >= 2 {
+				// Basename is ambiguous: fall back to the unique candidate whose
+				// directory best matches the link's remaining path segments.
+				if picked := pickCandidateByDirSuffix(lo.target, candidates); picked != "" {
+					newRawLink := rewriteRawLink(lo.rawLink, lo.linkType, picked)
+					if newRawLink != lo.rawLink {
+						rewrites = append(rewrites, rewriteEntry{
+							rawLink:    lo.rawLink,
+							linkType:   lo.linkType,
+							lineStart:  lo.lineStart,
+							col:        lo.col,
+							sourcePath: sourcePath,
+							newRawLink: newRawLink,
+						})
+					}
+					continue
+				}
 				// Broken path link + 2+ candidates → skip, report with dedup
 				key := sourcePath + "\x00" + lo.rawLink
 				if !skippedSet[key] {
@@ -131,6 +156,7 @@ func Repair(vaultPath string, opts RepairOptions) (*RepairResult, error) {
 				rawLink:    lo.rawLink,
 				linkType:   lo.linkType,
 				lineStart:  lo.lineStart,
+				col:        lo.col,
 				sourcePath: sourcePath,
 				newRawLink: newRawLink,
 			})
@@ -155,14 +181,54 @@ func Repair(vaultPath string, opts RepairOptions) (*RepairResult, error) {
 	for _, re := range rewrites {
 		groups[re.sourcePath] = append(groups[re.sourcePath], re)
 	}
-	_, _, applyErr := applyFileRewrites(vaultPath, groups)
+	_, backups, applyErr := applyFileRewrites(vaultPath, groups, false)
 	if applyErr != nil {
 		return nil, applyErr
 	}
+	if opts.KeepBackup {
+		if err := keepBackups(vaultPath, backups); err != nil {
+			return nil, err
+		}
+	}
 
 	return result, nil
 }
 
+// pickCandidateByDirSuffix returns the single candidate whose parent directories
+// share the longest trailing match with the link target's directories
+// (e.g. "old/dir1/M" prefers "dir1/M.md" over "dir2/M.md").
+// Returns "" when no candidate matches at least one segment or the best match is tied.
+func pickCandidateByDirSuffix(target string, candidates []string) string {
+	linkDirs := strings.Split(strings.ToLower(filepath.ToSlash(filepath.Dir(strings.TrimPrefix(target, "/")))), "/")
+	best := ""
+	bestScore := 0
+	tied := false
+	for _, c := range candidates {
+		dir := filepath.Dir(c)
+		if dir == "." {
+			continue
+		}
+		candDirs := strings.Split(strings.ToLower(dir), "/")
+		score := 0
+		for score < len(linkDirs) && score < len(candDirs) &&
+			linkDirs[len(linkDirs)-1-score] == candDirs[len(candDirs)-1-score] {
+			score++
+		}
+		if score == 0 {
+			continue
+		}
+		if score > bestScore {
+			best, bestScore, tied = c, score, false
+		} else if score == bestScore {
+			tied = true
+		}
+	}
+	if tied {
+		return ""
+	}
+	return best
+}
+
 // isLinkEscaping checks if a link escapes the vault boundary.
 // Mirrors build.go's validation logic:
 //   - relative links (./  ../) → escapesVault()
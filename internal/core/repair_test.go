@@ -457,3 +457,64 @@ func TestRepairDotInBasename(t *testing.T) {
 		t.Errorf("A.md should contain [[Note.v1]], got: %s", content)
 	}
 }
+
+func TestRepairAmbiguousFallsBackToUniqueFullPath(t *testing.T) {
+	vault := copyVault(t, "vault_repair")
+	if err := os.WriteFile(filepath.Join(vault, "C.md"), []byte("[[archive/dir2/M]]\n[m](old/dir1/M.md)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Repair(vault, RepairOptions{})
+	if err != nil {
+		t.Fatalf("repair: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(vault, "C.md"))
+	if err != nil {
+		t.Fatalf("read C.md: %v", err)
+	}
+	got := string(content)
+	if !strings.Contains(got, "[[dir2/M]]") {
+		t.Errorf("C.md should contain [[dir2/M]], got:\n%s", got)
+	}
+	if !strings.Contains(got, "[m](dir1/M.md)") {
+		t.Errorf("C.md should contain [m](dir1/M.md), got:\n%s", got)
+	}
+
+	// [[old/M]] in A.md has no matching directory → still skipped.
+	if len(result.Skipped) != 1 || result.Skipped[0].RawLink != "[[old/M]]" {
+		t.Errorf("Skipped = %+v, want only [[old/M]]", result.Skipped)
+	}
+}
+
+func TestRepairAmbiguousTiedCandidatesSkipped(t *testing.T) {
+	vault := copyVault(t, "vault_repair")
+	if err := os.MkdirAll(filepath.Join(vault, "x", "shared"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(vault, "y", "shared"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"x/shared/N.md", "y/shared/N.md"} {
+		if err := os.WriteFile(filepath.Join(vault, p), []byte("# N\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(vault, "C.md"), []byte("[[gone/shared/N]]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Repair(vault, RepairOptions{})
+	if err != nil {
+		t.Fatalf("repair: %v", err)
+	}
+	var found bool
+	for _, s := range result.Skipped {
+		if s.File == "C.md" && s.RawLink == "[[gone/shared/N]]" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("[[gone/shared/N]] should be skipped, got %+v", result.Skipped)
+	}
+}
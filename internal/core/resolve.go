@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -15,6 +16,15 @@ type ResolveResult struct {
 	Path    string // vault-relative path (note/asset only, empty otherwise)
 	Exists  bool   // file existence flag
 	Subpath string // "#Heading" / "#^block" (if any)
+	// Anchor locates a "#Heading" subpath in the target note. Resolve sets
+	// it for notes only; nil for block references and links without one.
+	Anchor *Anchor
+}
+
+// Anchor is the heading a link's subpath points to.
+type Anchor struct {
+	Heading string // last heading of the subpath ("Details" for "#Design#Details")
+	Line    int    // 1-based line of the heading; 0 when the note has no such heading
 }
 
 // Resolve resolves a link from a source file and returns the target node info.
@@ -24,12 +34,31 @@ func Resolve(vaultPath, fromPath, link string) (*ResolveResult, error) {
 		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
 	}
 
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
 	db, err := openDBAt(dbp)
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
 
+	return resolveDB(db, vaultPath, fromPath, link)
+}
+
+// resolveDB runs Resolve against an open index.
+func resolveDB(db dbExecer, vaultPath, fromPath, link string) (*ResolveResult, error) {
+	if err := checkFullIndex(db); err != nil {
+		return nil, err
+	}
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+
 	fromPath = NormalizePath(fromPath)
 
 	// Look up source node.
@@ -54,7 +83,7 @@ func Resolve(vaultPath, fromPath, link string) (*ResolveResult, error) {
 	}
 
 	// Resolve the link via DB.
-	targetID, subpath, err := resolveLinkFromDB(db, fromPath, *occur)
+	targetID, subpath, err := resolveLinkFromDB(db, fromPath, *occur, cfg.Build)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +98,196 @@ func Resolve(vaultPath, fromPath, link string) (*ResolveResult, error) {
 	}
 
 	// Fetch target node info.
-	return fetchNodeResult(db, targetID, subpath)
+	result, err := fetchNodeResult(db, targetID, subpath)
+	if err != nil {
+		return nil, err
+	}
+	if result.Type == "note" {
+		if result.Anchor, err = headingAnchor(db, targetID, subpath); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// headingAnchor looks up a "#Heading" subpath in the note's indexed headings.
+// Heading text matches case-insensitively; in a nested subpath ("#A#B") each
+// heading must follow the previous one at a deeper level. It returns nil for
+// an empty subpath and block references ("#^id").
+func headingAnchor(db dbExecer, nodeID int64, subpath string) (*Anchor, error) {
+	if subpath == "" || strings.HasPrefix(subpath, "#^") {
+		return nil, nil
+	}
+	segments := strings.Split(strings.TrimPrefix(subpath, "#"), "#")
+	anchor := &Anchor{Heading: strings.TrimSpace(segments[len(segments)-1])}
+	headings, err := queryHeadings(db, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	next, level := 0, 0
+	for _, seg := range segments {
+		seg = strings.TrimSpace(seg)
+		found := -1
+		for i := next; i < len(headings); i++ {
+			if headings[i].Level > level && strings.EqualFold(strings.TrimSpace(headings[i].Text), seg) {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			anchor.Line = 0
+			return anchor, nil
+		}
+		next, level = found+1, headings[found].Level
+		anchor.Line = headings[found].Line
+	}
+	return anchor, nil
+}
+
+// LinkResolution is the outcome of resolving one link in a source file.
+type LinkResolution struct {
+	RawLink    string
+	LineStart  int
+	LineEnd    int
+	Status     string         // "ok", "not_found" (phantom), "ambiguous", or "escape"
+	Target     *ResolveResult // nil when Status is "ambiguous" or "escape"
+	Candidates []string       // paths sharing the basename when Status is "ambiguous"
+}
+
+// ResolveAll resolves every note/asset link (wikilinks, markdown links, and
+// frontmatter link fields) in the source file as it is on disk now, ordered
+// by line (wikilinks before markdown links within a line). The resolve maps are built from the index once for the whole file.
+// Unlike build, ambiguous and vault-escape links are reported per link
+// instead of failing. Tags are not included. The index is not modified.
+func ResolveAll(vaultPath, sourcePath string) ([]LinkResolution, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return resolveAllDB(db, vaultPath, sourcePath, &resolverContext{})
+}
+
+// resolveAllDB runs ResolveAll against an open index, taking the resolve
+// maps from rc.
+func resolveAllDB(db *sql.DB, vaultPath, sourcePath string, rc *resolverContext) ([]LinkResolution, error) {
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sourcePath = NormalizePath(sourcePath)
+	if _, err := getNodeID(db, noteKey(sourcePath)); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("source not in index: %s", sourcePath)
+		}
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(vaultPath, sourcePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("source file not found on disk: %s", sourcePath)
+		}
+		return nil, err
+	}
+
+	cached, err := rc.maps(db)
+	if err != nil {
+		return nil, err
+	}
+	// The cached maps are shared; set the per-call option on a copy.
+	rm := *cached
+	rm.applyBuildConfig(cfg.Build)
+
+	// resolveLink creates phantom nodes for unresolved links; do it in a
+	// transaction that is always rolled back so the index stays untouched.
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var out []LinkResolution
+	for _, link := range parseIndexLinks(string(content), cfg.Build) {
+		if !isFileLinkType(link.linkType) {
+			continue
+		}
+		lr := LinkResolution{RawLink: link.rawLink, LineStart: link.lineStart, LineEnd: link.lineEnd}
+		switch {
+		case link.isRelative && escapesVault(sourcePath, link.target),
+			!link.isRelative && !link.isBasename && pathEscapesVault(link.target):
+			lr.Status = "escape"
+		case link.isBasename && isAmbiguousBasenameLink(sourcePath, link.target, &rm):
+			lr.Status = "ambiguous"
+			lr.Candidates = basenameCandidates(&rm, link.target)
+		case isAmbiguousFolderNoteLink(sourcePath, link, &rm):
+			lr.Status = "ambiguous"
+			lr.Candidates = folderNoteCandidates(sourcePath, link, &rm)
+		default:
+			id, subpath, err := resolveLink(tx, sourcePath, link, &rm)
+			if err != nil {
+				return nil, err
+			}
+			lr.Target, err = fetchNodeResult(tx, id, subpath)
+			if err != nil {
+				return nil, err
+			}
+			lr.Status = "ok"
+			if lr.Target.Type == "phantom" {
+				lr.Status = "not_found"
+			}
+		}
+		out = append(out, lr)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].LineStart < out[j].LineStart })
+	return out, nil
+}
+
+// folderNoteCandidates returns the note and the folder note that a link
+// reported by isAmbiguousFolderNoteLink could both refer to.
+func folderNoteCandidates(sourcePath string, link linkOccur, rm *resolveMaps) []string {
+	lower := strings.ToLower(link.target)
+	if !link.isBasename {
+		lower = strings.ToLower(resolveToVaultRelative(sourcePath, link))
+	}
+	out := []string{rm.pathSet[lower], rm.pathSet[folderNotePath(lower)]}
+	sort.Strings(out)
+	return out
+}
+
+// basenameCandidates returns the note paths (or, when no note matches, the
+// asset paths) sharing target's basename, sorted.
+func basenameCandidates(rm *resolveMaps, target string) []string {
+	lower := strings.ToLower(target)
+	var notes, assets []string
+	for p := range rm.pathToID {
+		if basenameKey(p) == lower {
+			notes = append(notes, p)
+		}
+	}
+	for p := range rm.assetPathToID {
+		if assetBasenameKey(p) == lower {
+			assets = append(assets, p)
+		}
+	}
+	out := notes
+	if len(out) == 0 || (prefersAsset(target) && len(assets) > 0) {
+		out = assets
+	}
+	sort.Strings(out)
+	return out
 }
 
 // selectLinkOccur picks the linkOccur whose rawLink matches the input exactly.
@@ -85,7 +303,7 @@ func selectLinkOccur(links []linkOccur, input string) *linkOccur {
 
 // resolveLinkFromDB resolves a linkOccur to a target node ID using DB queries.
 // Mirrors resolveLink() in build.go but uses DB instead of in-memory maps.
-func resolveLinkFromDB(db dbExecer, sourcePath string, link linkOccur) (int64, string, error) {
+func resolveLinkFromDB(db dbExecer, sourcePath string, link linkOccur, bc BuildConfig) (int64, string, error) {
 	// Self-link: [[#Heading]]
 	if link.target == "" && link.subpath != "" {
 		id, err := getNodeID(db, noteKey(sourcePath))
@@ -97,7 +315,10 @@ func resolveLinkFromDB(db dbExecer, sourcePath string, link linkOccur) (int64, s
 
 	// Tag or frontmatter tag
 	if link.linkType == "tag" || link.linkType == "frontmatter" {
-		key := fmt.Sprintf("tag:name:%s", strings.ToLower(link.target))
+		key, err := lookupTagKey(db, link.target)
+		if err != nil {
+			return 0, "", err
+		}
 		id, err := getNodeID(db, key)
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -116,7 +337,7 @@ func resolveLinkFromDB(db dbExecer, sourcePath string, link linkOccur) (int64, s
 			return 0, "", fmt.Errorf("link escapes vault: %s in %s", link.rawLink, sourcePath)
 		}
 		resolved := NormalizePath(filepath.Join(filepath.Dir(sourcePath), target))
-		return resolvePathFromDB(db, resolved, link)
+		return resolvePathFromDB(db, resolved, link, bc.FolderNotes)
 	}
 
 	// Vault-absolute path escape check (defense-in-depth).
@@ -127,40 +348,58 @@ func resolveLinkFromDB(db dbExecer, sourcePath string, link linkOccur) (int64, s
 	// Absolute path (/ prefix): /sub/B.md → sub/B.md
 	if strings.HasPrefix(target, "/") {
 		stripped := strings.TrimPrefix(target, "/")
-		return resolvePathFromDB(db, stripped, link)
+		return resolvePathFromDB(db, stripped, link, bc.FolderNotes)
 	}
 
 	// Wikilink with vault-relative path (contains /, not relative): [[path/to/Note]]
 	if link.linkType == "wikilink" && !link.isBasename {
-		return resolvePathFromDB(db, target, link)
+		return resolvePathFromDB(db, target, link, bc.FolderNotes)
 	}
 
 	// Basename resolution
 	if link.isBasename {
-		return resolveBasenameFromDB(db, target, link)
+		return resolveBasenameFromDB(db, sourcePath, target, link, bc)
 	}
 
 	// Markdown link with path that is not relative and not / prefix
-	return resolvePathFromDB(db, target, link)
+	return resolvePathFromDB(db, target, link, bc.FolderNotes)
 }
 
 // resolvePathFromDB finds a note/asset node by path, falling back to phantom.
-// Resolution order: note exact → note+.md → asset exact → phantom.
-func resolvePathFromDB(db dbExecer, resolved string, link linkOccur) (int64, string, error) {
+// Resolution order: note exact → note+.md → folder note (if enabled) → asset exact → phantom.
+func resolvePathFromDB(db dbExecer, resolved string, link linkOccur, folderNotes bool) (int64, string, error) {
 	normalized := NormalizePath(resolved)
 	lower := strings.ToLower(normalized)
 
 	// Try note: exact path or path+.md (case-insensitive).
+	// A trailing slash names the folder, so only its folder note can match.
 	var id int64
-	err := db.QueryRow(
-		`SELECT id FROM nodes WHERE type='note' AND (LOWER(path) = ? OR LOWER(path) = ?)`,
-		lower, lower+".md",
-	).Scan(&id)
-	if err == nil {
-		return id, link.subpath, nil
+	var err error
+	if !folderNotes || !strings.HasSuffix(link.target, "/") {
+		err = db.QueryRow(
+			`SELECT id FROM nodes WHERE type='note' AND (LOWER(path) = ? OR LOWER(path) = ?)`,
+			lower, lower+".md",
+		).Scan(&id)
+		if err == nil {
+			return id, link.subpath, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, "", err
+		}
 	}
-	if err != sql.ErrNoRows {
-		return 0, "", err
+
+	// Try folder note: Dir → Dir/Dir.md.
+	if folderNotes {
+		err = db.QueryRow(
+			`SELECT id FROM nodes WHERE type='note' AND LOWER(path) = ?`,
+			folderNotePath(lower),
+		).Scan(&id)
+		if err == nil {
+			return id, link.subpath, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, "", err
+		}
 	}
 
 	// Try asset: exact path (case-insensitive).
@@ -195,9 +434,12 @@ func resolvePathFromDB(db dbExecer, resolved string, link linkOccur) (int64, str
 
 // resolveBasenameFromDB finds a note/asset node by basename (case-insensitive).
 // Resolution order: note → asset → phantom.
-// When multiple nodes match within the same type, applies root-priority rule.
-func resolveBasenameFromDB(db dbExecer, target string, link linkOccur) (int64, string, error) {
+// When multiple nodes match within the same type, applies the root-priority
+// rule unless bc turns it off; with folder-first resolution, a note in
+// sourcePath's folder wins first.
+func resolveBasenameFromDB(db dbExecer, sourcePath, target string, link linkOccur, bc BuildConfig) (int64, string, error) {
 	lower := strings.ToLower(target)
+	rootPriority := bc.rootPriority()
 
 	// Try note by basename.
 	type match struct {
@@ -205,6 +447,25 @@ func resolveBasenameFromDB(db dbExecer, target string, link linkOccur) (int64, s
 		path string
 	}
 
+	// A target with a non-.md extension names a file: try assets first.
+	if prefersAsset(target) {
+		assetMatches, err := queryBasenameMatches(db, "asset", lower)
+		if err != nil {
+			return 0, "", err
+		}
+		if len(assetMatches) == 1 {
+			return assetMatches[0].id, link.subpath, nil
+		}
+		for _, m := range assetMatches {
+			if rootPriority && isRootFile(m.path) {
+				return m.id, link.subpath, nil
+			}
+		}
+		if len(assetMatches) > 1 {
+			return 0, "", fmt.Errorf("ambiguous link: %s resolves to %d assets", target, len(assetMatches))
+		}
+	}
+
 	noteMatches, err := queryBasenameMatches(db, "note", lower)
 	if err != nil {
 		return 0, "", err
@@ -214,8 +475,16 @@ func resolveBasenameFromDB(db dbExecer, target string, link linkOccur) (int64, s
 		return noteMatches[0].id, link.subpath, nil
 	}
 	if len(noteMatches) > 1 {
+		if bc.folderFirst() {
+			local := localNoteKey(sourcePath, lower)
+			for _, m := range noteMatches {
+				if strings.ToLower(m.path) == local {
+					return m.id, link.subpath, nil
+				}
+			}
+		}
 		for _, m := range noteMatches {
-			if isRootFile(m.path) {
+			if rootPriority && isRootFile(m.path) {
 				return m.id, link.subpath, nil
 			}
 		}
@@ -233,13 +502,38 @@ func resolveBasenameFromDB(db dbExecer, target string, link linkOccur) (int64, s
 	}
 	if len(assetMatches) > 1 {
 		for _, m := range assetMatches {
-			if isRootFile(m.path) {
+			if rootPriority && isRootFile(m.path) {
 				return m.id, link.subpath, nil
 			}
 		}
 		return 0, "", fmt.Errorf("ambiguous link: %s resolves to %d assets", target, len(assetMatches))
 	}
 
+	// Alias claimed by exactly one note.
+	var aliasIDs []int64
+	arows, err := db.Query(
+		`SELECT DISTINCT n.id FROM aliases a
+		 JOIN nodes n ON n.id = a.node_id AND n.type = 'note' AND n.exists_flag = 1
+		 WHERE a.alias = ? COLLATE NOCASE`, target)
+	if err != nil {
+		return 0, "", err
+	}
+	for arows.Next() {
+		var id int64
+		if err := arows.Scan(&id); err != nil {
+			arows.Close()
+			return 0, "", err
+		}
+		aliasIDs = append(aliasIDs, id)
+	}
+	arows.Close()
+	if err := arows.Err(); err != nil {
+		return 0, "", err
+	}
+	if len(aliasIDs) == 1 {
+		return aliasIDs[0], link.subpath, nil
+	}
+
 	// 0 matches → look for phantom.
 	pk := phantomKey(target)
 	var id int64
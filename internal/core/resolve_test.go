@@ -1,6 +1,7 @@
 package core
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -88,6 +89,43 @@ func TestResolveWikilinkSubpath(t *testing.T) {
 	}
 }
 
+func TestResolveHeadingAnchor(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "[[B#Details]] [[B#details]] [[B#Nope]]\n[[B#Top#Details]] [[B#Other#Details]] [[B#^blk]] [[B]]\n",
+		"B.md": "# Top\n\n## Details\n\n# Other\n\ntext ^blk\n",
+	})
+	buildVault(t, vault)
+
+	tests := []struct {
+		link string
+		want *Anchor
+	}{
+		{"[[B#Details]]", &Anchor{Heading: "Details", Line: 3}},
+		{"[[B#details]]", &Anchor{Heading: "details", Line: 3}},
+		{"[[B#Nope]]", &Anchor{Heading: "Nope", Line: 0}},
+		{"[[B#Top#Details]]", &Anchor{Heading: "Details", Line: 3}},
+		{"[[B#Other#Details]]", &Anchor{Heading: "Details", Line: 0}},
+		{"[[B#^blk]]", nil},
+		{"[[B]]", nil},
+	}
+	for _, tt := range tests {
+		res, err := Resolve(vault, "A.md", tt.link)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.link, err)
+		}
+		if tt.want == nil {
+			if res.Anchor != nil {
+				t.Errorf("%s: anchor = %+v, want nil", tt.link, res.Anchor)
+			}
+			continue
+		}
+		if res.Anchor == nil || *res.Anchor != *tt.want {
+			t.Errorf("%s: anchor = %+v, want %+v", tt.link, res.Anchor, tt.want)
+		}
+	}
+}
+
 func TestResolveWikilinkSelfLink(t *testing.T) {
 	vault := copyVaultForResolve(t, "vault_build_full")
 	buildVault(t, vault)
@@ -348,6 +386,20 @@ func TestResolveBasenameRootPriority(t *testing.T) {
 	}
 }
 
+func TestResolveBasenameRootPriorityDisabled(t *testing.T) {
+	vault := copyVault(t, "vault_query_ambiguous_name")
+	writeVaultFiles(t, vault, map[string]string{
+		"mdhop.yaml": "build:\n  root_priority: false\n",
+		"A.md":       "# A at root\n",
+	})
+	buildVault(t, vault)
+
+	_, err := Resolve(vault, "Root.md", "[[A]]")
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous error (root priority disabled), got: %v", err)
+	}
+}
+
 func TestResolveBasenameAmbiguousNoRoot(t *testing.T) {
 	// Two notes in subdirs (no root) → ambiguous.
 	vault := filepath.Join(t.TempDir(), "vault")
@@ -398,3 +450,111 @@ func TestResolveErrorDBNotFound(t *testing.T) {
 		t.Errorf("error = %q, want containing %q", err.Error(), "index not found")
 	}
 }
+
+func TestResolveAll(t *testing.T) {
+	vault := copyVaultForResolve(t, "vault_resolve_all")
+	buildVault(t, vault)
+
+	// Links that build rejects are added after the build; ResolveAll reads
+	// the file as it is on disk.
+	indexPath := filepath.Join(vault, "Index.md")
+	content, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content = append(content, []byte("[[X]] and [out](../Outside.md) and [[Ghost]]\n")...)
+	if err := os.WriteFile(indexPath, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := ResolveAll(vault, "Index.md")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(links) != 6 {
+		t.Fatalf("got %d links, want 6: %+v", len(links), links)
+	}
+
+	want := []struct {
+		raw, status, typ, path string
+		line                   int
+	}{
+		{"[[Design#Goals]]", "ok", "note", "Design.md", 6},
+		{"[[Missing]]", "not_found", "phantom", "", 6},
+		{"[spec](sub1/X.md)", "ok", "note", "sub1/X.md", 8},
+		{"[[X]]", "ambiguous", "", "", 9},
+		{"[[Ghost]]", "not_found", "phantom", "", 9},
+		{"[out](../Outside.md)", "escape", "", "", 9},
+	}
+	for i, w := range want {
+		l := links[i]
+		if l.RawLink != w.raw || l.Status != w.status || l.LineStart != w.line {
+			t.Errorf("links[%d] = {%q %q line %d}, want {%q %q line %d}", i, l.RawLink, l.Status, l.LineStart, w.raw, w.status, w.line)
+			continue
+		}
+		if w.typ == "" {
+			if l.Target != nil {
+				t.Errorf("links[%d] target = %+v, want nil", i, l.Target)
+			}
+			continue
+		}
+		if l.Target == nil || l.Target.Type != w.typ || l.Target.Path != w.path {
+			t.Errorf("links[%d] target = %+v, want type %q path %q", i, l.Target, w.typ, w.path)
+		}
+	}
+	if links[0].Target != nil && links[0].Target.Subpath != "#Goals" {
+		t.Errorf("subpath = %q, want %q", links[0].Target.Subpath, "#Goals")
+	}
+	if got := links[3].Candidates; len(got) != 2 || got[0] != "sub1/X.md" || got[1] != "sub2/X.md" {
+		t.Errorf("candidates = %v, want [sub1/X.md sub2/X.md]", got)
+	}
+
+	// Resolving must not write the new phantom to the index.
+	for _, n := range queryNodes(t, dbPath(vault), "phantom") {
+		if n.name == "Ghost" {
+			t.Errorf("phantom %q was written to the index", n.name)
+		}
+	}
+}
+
+func TestResolveAllSourceNotInIndex(t *testing.T) {
+	vault := copyVaultForResolve(t, "vault_resolve_all")
+	buildVault(t, vault)
+
+	if _, err := ResolveAll(vault, "Nope.md"); err == nil || !strings.Contains(err.Error(), "source not in index") {
+		t.Fatalf("expected source not in index error, got %v", err)
+	}
+}
+
+func TestResolveBackslashPath(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":     "[link](sub\\B.md) and [[sub\\B#Sec]]\n",
+		"sub/B.md": "# B\n## Sec\n",
+		"sub/C.md": "[up](..\\A.md)\n",
+		"Table.md": "| [[sub\\B\\|b]] |\n",
+	})
+	buildVault(t, vault)
+
+	for _, tt := range []struct{ from, link, path string }{
+		{"A.md", `[link](sub\B.md)`, "sub/B.md"},
+		{"A.md", `[[sub\B#Sec]]`, "sub/B.md"},
+		{"sub/C.md", `[up](..\A.md)`, "A.md"},
+		{"Table.md", `[[sub\B\|b]]`, "sub/B.md"},
+	} {
+		r, err := Resolve(vault, tt.from, tt.link)
+		if err != nil {
+			t.Errorf("%s: %v", tt.link, err)
+			continue
+		}
+		if r.Type != "note" || r.Path != tt.path {
+			t.Errorf("%s: got %s %q, want note %q", tt.link, r.Type, r.Path, tt.path)
+		}
+	}
+
+	// raw_link keeps the text as written.
+	edges := aliasEdgeTargets(t, vault, "A.md")
+	if got := edges[`[link](sub\B.md)`]; got != noteKey("sub/B.md") {
+		t.Errorf("edge target = %q, want %q (edges %v)", got, noteKey("sub/B.md"), edges)
+	}
+}
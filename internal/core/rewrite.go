@@ -1,9 +1,12 @@
 package core
 
 import (
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 // rewriteBackup holds original file content for rollback on failure.
@@ -19,6 +22,7 @@ type rewriteEntry struct {
 	rawLink    string
 	linkType   string
 	lineStart  int
+	col        int // byte offset of rawLink in its line, or -1 when unknown
 	sourcePath string
 	sourceID   int64
 	newRawLink string
@@ -33,6 +37,69 @@ func buildRewritePath(targetPath string) string {
 	return targetPath
 }
 
+// splitMarkdownRawLink splits a markdown raw link "[text](url#frag)" into the
+// "[text](" prefix, the URL path, and the fragment (including "#").
+// Angle-bracket URLs like "(<my note.md>)" are unwrapped; angled reports
+// whether the brackets were present.
+// The fragment is returned exactly as written: rewriters only ever change the
+// path, so an encoded fragment such as "#Some%20Heading" keeps its bytes.
+func splitMarkdownRawLink(rawLink string) (textPart, urlPath, frag string, angled, ok bool) {
+	start := strings.Index(rawLink, "](")
+	if start < 0 {
+		return "", "", "", false, false
+	}
+	textPart = rawLink[:start+2]
+	urlPath, angled = unwrapAngleURL(strings.TrimSuffix(rawLink[start+2:], ")"))
+	if idx := strings.Index(urlPath, "#"); idx >= 0 {
+		frag = urlPath[idx:]
+		urlPath = urlPath[:idx]
+	}
+	return textPart, urlPath, frag, angled, true
+}
+
+// unwrapAngleURL strips the <...> brackets around a markdown link destination.
+// A fragment after the closing bracket ("<my note.md>#h") is kept.
+func unwrapAngleURL(u string) (string, bool) {
+	if !strings.HasPrefix(u, "<") {
+		return u, false
+	}
+	gt := strings.Index(u, ">")
+	if gt < 0 {
+		return u, false
+	}
+	return u[1:gt] + u[gt+1:], true
+}
+
+// decodeMarkdownPath URL-decodes a markdown link path ("My%20Note.md" →
+// "My Note.md"). Paths that are not valid escapes (e.g. "100%.md") are kept.
+func decodeMarkdownPath(p string) string {
+	if !strings.Contains(p, "%") {
+		return p
+	}
+	if d, err := url.PathUnescape(p); err == nil {
+		return d
+	}
+	return p
+}
+
+// encodeSpacesLike re-encodes spaces in newPath as "%20" when the original
+// markdown link path used "%20", so rewrites keep the link's style.
+func encodeSpacesLike(origPath, newPath string) string {
+	if !strings.Contains(origPath, "%20") {
+		return newPath
+	}
+	return strings.ReplaceAll(newPath, " ", "%20")
+}
+
+// formatMarkdownURL joins a markdown link destination, wrapping it in angle
+// brackets when it was bracketed before or the path contains spaces.
+func formatMarkdownURL(urlPath, frag string, angled bool) string {
+	if angled || strings.Contains(urlPath, " ") {
+		return "<" + urlPath + frag + ">"
+	}
+	return urlPath + frag
+}
+
 // rewriteRawLink replaces the target in a raw link with the rewritten path.
 func rewriteRawLink(rawLink, linkType, targetPath string) string {
 	switch linkType {
@@ -46,6 +113,11 @@ func rewriteRawLink(rawLink, linkType, targetPath string) string {
 		if idx := strings.Index(inner, "|"); idx >= 0 {
 			alias = inner[idx:] // includes |
 			inner = inner[:idx]
+			// Keep the escape of a table-safe "\|".
+			if strings.HasSuffix(inner, "\\") {
+				alias = "\\" + alias
+				inner = strings.TrimSuffix(inner, "\\")
+			}
 		}
 		// Extract subpath (after #).
 		if idx := strings.Index(inner, "#"); idx >= 0 {
@@ -56,21 +128,11 @@ func rewriteRawLink(rawLink, linkType, targetPath string) string {
 		return "[[" + newPath + subpath + alias + "]]"
 
 	case "markdown":
-		// rawLink: [text](url), [text](url#frag)
-		start := strings.Index(rawLink, "](")
-		if start < 0 {
+		// rawLink: [text](url), [text](url#frag), [text](<url>)
+		textPart, urlPart, frag, angled, ok := splitMarkdownRawLink(rawLink)
+		if !ok {
 			return rawLink
 		}
-		textPart := rawLink[:start+2] // "[text]("
-		urlPart := rawLink[start+2:]
-		urlPart = strings.TrimSuffix(urlPart, ")")
-
-		// Extract fragment.
-		var frag string
-		if idx := strings.Index(urlPart, "#"); idx >= 0 {
-			frag = urlPart[idx:] // includes #
-			urlPart = urlPart[:idx]
-		}
 
 		// Check if original URL had .md extension.
 		hasMdExt := strings.HasSuffix(strings.ToLower(urlPart), ".md")
@@ -79,40 +141,147 @@ func rewriteRawLink(rawLink, linkType, targetPath string) string {
 		if hasMdExt {
 			newPath += ".md"
 		}
+		newPath = encodeSpacesLike(urlPart, newPath)
 
-		return textPart + newPath + frag + ")"
+		return textPart + formatMarkdownURL(newPath, frag, angled) + ")"
+
+	case "frontmatter-link":
+		// rawLink: Target, path/to/Target, Target.md
+		newPath := buildRewritePath(targetPath)
+		if strings.HasSuffix(strings.ToLower(rawLink), ".md") {
+			newPath += ".md"
+		}
+		return newPath
 	}
 	return rawLink
 }
 
-// replaceOutsideInlineCode replaces occurrences of old with new in line,
-// but only outside backtick-delimited inline code spans.
-func replaceOutsideInlineCode(line, old, new string) string {
+// replaceRawLink replaces a raw link on a line using the matching rule for its
+// link type: frontmatter entries must match whole, body links are replaced at
+// col (see replaceLinksAt).
+func replaceRawLink(line, linkType, old, new string, col int) string {
+	return replaceRawLinks(line, []linkReplacement{{linkType, old, new, col}})
+}
+
+// linkReplacement is one raw link substitution for replaceRawLinks.
+type linkReplacement struct {
+	linkType string
+	old, new string
+	col      int // byte offset of old in the line (body links), or -1 when unknown
+}
+
+// replaceRawLinks applies several raw link replacements to a line in a single
+// pass, with the same matching rules as replaceRawLink. Replaced text is never
+// matched again, so swapping [[A]] and [[B]] on one line does not chain.
+func replaceRawLinks(line string, reps []linkReplacement) string {
+	var body, fm []linkReplacement
+	for _, r := range reps {
+		if r.old == "" {
+			continue
+		}
+		if r.linkType == "frontmatter-link" {
+			fm = append(fm, r)
+		} else {
+			body = append(body, r)
+		}
+	}
+	if len(fm) > 0 {
+		line = replaceFrontmatterEntries(line, fm)
+	}
+	if len(body) > 0 {
+		line = replaceLinksAt(line, body)
+	}
+	return line
+}
+
+// replaceLinksAt replaces body links by position. A replacement with a known
+// column is spliced there only. One without a column (an edge indexed before
+// columns were recorded), or whose column no longer holds its old text (the
+// file was edited by a command that leaves the index to the next build),
+// falls back to every link the parsed line holds with that raw text. Text
+// that merely looks like the link (inside inline code, or part of a longer
+// link) is left alone either way, and a long line is scanned at most once.
+func replaceLinksAt(line string, reps []linkReplacement) string {
+	type splice struct {
+		col      int
+		old, new string
+	}
+	var splices []splice
+	anyCol := make(map[string]string)
+	for _, r := range reps {
+		if r.col >= 0 && r.col+len(r.old) <= len(line) && line[r.col:r.col+len(r.old)] == r.old {
+			splices = append(splices, splice{r.col, r.old, r.new})
+			continue
+		}
+		if _, ok := anyCol[r.old]; !ok {
+			anyCol[r.old] = r.new
+		}
+	}
+	if len(anyCol) > 0 {
+		links, _ := parseLineLinks(line, 0)
+		for _, l := range links {
+			// Skip links split by inline code in the source.
+			if new, ok := anyCol[l.rawLink]; ok && strings.HasPrefix(line[l.col:], l.rawLink) {
+				splices = append(splices, splice{l.col, l.rawLink, new})
+			}
+		}
+	}
+	if len(splices) == 0 {
+		return line
+	}
+	sort.Slice(splices, func(i, j int) bool { return splices[i].col < splices[j].col })
+
+	var result strings.Builder
+	last := 0
+	for _, sp := range splices {
+		if sp.col < last {
+			continue // overlapping, or the same link listed twice
+		}
+		result.WriteString(line[last:sp.col])
+		result.WriteString(sp.new)
+		last = sp.col + len(sp.old)
+	}
+	result.WriteString(line[last:])
+	return result.String()
+}
+
+// replaceFrontmatterEntry replaces old with new in a frontmatter line, but only
+// where old is a whole YAML list entry: bounded by the line edges, whitespace,
+// quotes, or flow-sequence punctuation. This keeps "[Design, DesignDoc]" from
+// matching "Design" inside "DesignDoc" and preserves the list syntax as written.
+func replaceFrontmatterEntry(line, old, new string) string {
+	return replaceFrontmatterEntries(line, []linkReplacement{{"frontmatter-link", old, new, -1}})
+}
+
+// replaceFrontmatterEntries is replaceFrontmatterEntry for several entries in
+// a single pass, longer old entries first.
+func replaceFrontmatterEntries(line string, reps []linkReplacement) string {
+	sorted := append([]linkReplacement(nil), reps...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].old) > len(sorted[j].old) })
+
 	var result strings.Builder
 	i := 0
 	for i < len(line) {
-		if line[i] == '`' {
-			// Find the closing backtick.
-			end := strings.IndexByte(line[i+1:], '`')
-			if end < 0 {
-				// No closing backtick — rest of line is code.
-				result.WriteString(line[i:])
-				return result.String()
+		matched := false
+		for _, r := range sorted {
+			if !strings.HasPrefix(line[i:], r.old) {
+				continue
 			}
-			// Copy the inline code span verbatim.
-			span := line[i : i+1+end+1]
-			result.WriteString(span)
-			i += len(span)
-			continue
+			end := i + len(r.old)
+			startOK := i == 0 || strings.IndexByte(" \t[,\"'", line[i-1]) >= 0
+			endOK := end == len(line) || strings.IndexByte(" \t],\"'#", line[end]) >= 0
+			if !startOK || !endOK {
+				continue
+			}
+			result.WriteString(r.new)
+			i = end
+			matched = true
+			break
 		}
-		// Check for old string match.
-		if strings.HasPrefix(line[i:], old) {
-			result.WriteString(new)
-			i += len(old)
-			continue
+		if !matched {
+			result.WriteByte(line[i])
+			i++
 		}
-		result.WriteByte(line[i])
-		i++
 	}
 	return result.String()
 }
@@ -134,15 +303,24 @@ func restoreBackups(vaultPath string, backups []rewriteBackup) {
 	}
 }
 
+// restoreMtime sets path's modification time back to mtime after a
+// rewrite (PreserveMtime). The access time becomes now.
+func restoreMtime(path string, mtime time.Time) error {
+	return os.Chtimes(path, time.Now(), mtime)
+}
+
 // applyFileRewrites applies rewrite entries to source files on disk.
 // Returns a map of sourceID → new mtime after writing, and backups for rollback.
-// On error during write, restores already-written files (best-effort).
-func applyFileRewrites(vaultPath string, groups map[string][]rewriteEntry) (map[int64]int64, []rewriteBackup, error) {
+// With preserveMtime, each file keeps its original mtime and that is what
+// the map reports. On error during write, restores already-written files
+// (best-effort).
+func applyFileRewrites(vaultPath string, groups map[string][]rewriteEntry, preserveMtime bool) (map[int64]int64, []rewriteBackup, error) {
 	newMtimes := make(map[int64]int64)
 
 	// Phase 1: read all originals before any writes.
 	originals := make(map[string][]byte, len(groups))
 	perms := make(map[string]os.FileMode, len(groups))
+	mtimes := make(map[string]time.Time, len(groups))
 	for sourcePath := range groups {
 		fullPath := filepath.Join(vaultPath, sourcePath)
 		info, err := os.Stat(fullPath)
@@ -150,6 +328,7 @@ func applyFileRewrites(vaultPath string, groups map[string][]rewriteEntry) (map[
 			return nil, nil, err
 		}
 		perms[sourcePath] = info.Mode().Perm()
+		mtimes[sourcePath] = info.ModTime()
 		content, err := os.ReadFile(fullPath)
 		if err != nil {
 			return nil, nil, err
@@ -183,9 +362,11 @@ func applyFileRewrites(vaultPath string, groups map[string][]rewriteEntry) (map[
 				continue
 			}
 			idx := lineNum - 1 // convert 1-based to 0-based
-			for _, re := range res {
-				lines[idx] = replaceOutsideInlineCode(lines[idx], re.rawLink, re.newRawLink)
+			reps := make([]linkReplacement, len(res))
+			for i, re := range res {
+				reps[i] = linkReplacement{re.linkType, re.rawLink, re.newRawLink, re.col}
 			}
+			lines[idx] = replaceRawLinks(lines[idx], reps)
 		}
 
 		newContent := []byte(strings.Join(lines, "\n"))
@@ -194,6 +375,12 @@ func applyFileRewrites(vaultPath string, groups map[string][]rewriteEntry) (map[
 			return nil, nil, err
 		}
 		written = append(written, rewriteBackup{path: sourcePath, content: original, perm: perms[sourcePath]})
+		if preserveMtime {
+			if err := restoreMtime(fullPath, mtimes[sourcePath]); err != nil {
+				restore()
+				return nil, nil, err
+			}
+		}
 
 		// Collect new mtime.
 		info, err := os.Stat(fullPath)
@@ -208,6 +395,28 @@ func applyFileRewrites(vaultPath string, groups map[string][]rewriteEntry) (map[
 	return newMtimes, written, nil
 }
 
+// shiftEdgeCols moves the recorded column of the edges that follow each
+// rewritten link on its line by the change in the link's length, so that later
+// rewrites of those edges still splice at the right place. Rewrites are taken
+// right to left, so no shift moves a column past a rewrite not yet applied.
+func shiftEdgeCols(tx dbExecer, rewrites []rewriteEntry) error {
+	sorted := append([]rewriteEntry(nil), rewrites...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].col > sorted[j].col })
+	for _, re := range sorted {
+		delta := len(re.newRawLink) - len(re.rawLink)
+		if re.col < 0 || delta == 0 || re.linkType == "frontmatter-link" {
+			continue
+		}
+		if _, err := tx.Exec(
+			"UPDATE edges SET col = col + ? WHERE source_id = ? AND line_start = ? AND col > ?",
+			delta, re.sourceID, re.lineStart, re.col,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // isBasenameRawLink checks if a raw_link represents a basename link (no path separators).
 func isBasenameRawLink(rawLink, linkType string) bool {
 	switch linkType {
@@ -223,6 +432,7 @@ func isBasenameRawLink(rawLink, linkType string) bool {
 		if idx := strings.Index(inner, "#"); idx >= 0 {
 			inner = inner[:idx]
 		}
+		inner = normalizeSeparators(inner)
 		// Empty target means self-link like [[#Heading]], not a basename link.
 		if inner == "" {
 			return false
@@ -230,21 +440,17 @@ func isBasenameRawLink(rawLink, linkType string) bool {
 		return !strings.Contains(inner, "/")
 	case "markdown":
 		// raw_link is like "[text](url)" or "[text](url#heading)"
-		start := strings.Index(rawLink, "](")
-		if start < 0 {
+		_, url, _, _, ok := splitMarkdownRawLink(rawLink)
+		if !ok {
 			return false
 		}
-		url := rawLink[start+2:]
-		url = strings.TrimSuffix(url, ")")
-		// Remove fragment.
-		if idx := strings.Index(url, "#"); idx >= 0 {
-			url = url[:idx]
-		}
 		// Empty url means self-link like [text](#heading), not a basename link.
 		if url == "" {
 			return false
 		}
-		return !strings.Contains(url, "/")
+		return !strings.Contains(normalizeSeparators(url), "/")
+	case "frontmatter-link":
+		return !strings.Contains(rawLink, "/")
 	}
 	return false
 }
@@ -3,6 +3,7 @@ package core
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -70,7 +71,7 @@ func TestApplyFileRewritesPreservesPermission(t *testing.T) {
 		},
 	}
 
-	_, backups, err := applyFileRewrites(vault, groups)
+	_, backups, err := applyFileRewrites(vault, groups, false)
 	if err != nil {
 		t.Fatalf("applyFileRewrites: %v", err)
 	}
@@ -101,3 +102,135 @@ func TestApplyFileRewritesPreservesPermission(t *testing.T) {
 		t.Errorf("backup perm = %o, want %o", backups[0].perm, 0o600)
 	}
 }
+
+func TestReplaceFrontmatterEntry(t *testing.T) {
+	tests := []struct {
+		line, old, new, want string
+	}{
+		{"related: [Design, DesignDoc]", "Design", "sub/Design", "related: [sub/Design, DesignDoc]"},
+		{"related: [DesignDoc, Design]", "Design", "sub/Design", "related: [DesignDoc, sub/Design]"},
+		{"  - Design", "Design", "sub/Design", "  - sub/Design"},
+		{"  - \"Design\"", "Design", "sub/Design", "  - \"sub/Design\""},
+		{"related: Design", "Design", "sub/Design", "related: sub/Design"},
+		{"  - old/Design", "Design", "sub/Design", "  - old/Design"},
+	}
+	for _, tt := range tests {
+		if got := replaceFrontmatterEntry(tt.line, tt.old, tt.new); got != tt.want {
+			t.Errorf("replaceFrontmatterEntry(%q, %q, %q) = %q, want %q", tt.line, tt.old, tt.new, got, tt.want)
+		}
+	}
+}
+
+func TestReplaceRawLinkOnlyParsedOccurrences(t *testing.T) {
+	tests := []struct {
+		line, linkType, old, new string
+		col                      int
+		want                     string
+	}{
+		// Only the link at col is rewritten, not other copies of it.
+		{"[[A]] `[[A]]` [[A]]", "wikilink", "[[A]]", "[[sub/A]]", 0, "[[sub/A]] `[[A]]` [[A]]"},
+		{"[[A]] `[[A]]` [[A]]", "wikilink", "[[A]]", "[[sub/A]]", 14, "[[A]] `[[A]]` [[sub/A]]"},
+		// Without a column both real links are rewritten; the inline code copy is not.
+		{"[[A]] `[[A]]` [[A]]", "wikilink", "[[A]]", "[[sub/A]]", -1, "[[sub/A]] `[[A]]` [[sub/A]]"},
+		// "[[a](A.md)" is not a markdown link, so only the second occurrence is.
+		{"[[a](A.md) and [a](A.md)", "markdown", "[a](A.md)", "[a](sub/A.md)", -1, "[[a](A.md) and [a](sub/A.md)"},
+		{"[[a](A.md) and [a](A.md)", "markdown", "[a](A.md)", "[a](sub/A.md)", 15, "[[a](A.md) and [a](sub/A.md)"},
+		// The embed marker stays in front of the rewritten link.
+		{"![[A]] [[A|x]]", "wikilink", "[[A]]", "[[sub/A]]", 1, "![[sub/A]] [[A|x]]"},
+		// A link split by inline code cannot be rewritten in place.
+		{"[[A`x`]]", "wikilink", "[[A]]", "[[sub/A]]", 0, "[[A`x`]]"},
+		{"[[A`x`]]", "wikilink", "[[A]]", "[[sub/A]]", -1, "[[A`x`]]"},
+		// A stale column falls back to the parsed links.
+		{"x [[A]]", "wikilink", "[[A]]", "[[sub/A]]", 0, "x [[sub/A]]"},
+	}
+	for _, tt := range tests {
+		if got := replaceRawLink(tt.line, tt.linkType, tt.old, tt.new, tt.col); got != tt.want {
+			t.Errorf("replaceRawLink(%q, %q → %q at %d) = %q, want %q", tt.line, tt.old, tt.new, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestReplaceRawLinksLongLine(t *testing.T) {
+	data := "![img](data:image/png;base64," + strings.Repeat("QUFB", 1<<18) + ")"
+	line := "[[A]] " + data + " [[A]]"
+	got := replaceRawLinks(line, []linkReplacement{
+		{"wikilink", "[[A]]", "[[sub/A]]", 0},
+		{"wikilink", "[[A]]", "[[sub/A]]", len(line) - len("[[A]]")},
+	})
+	if want := "[[sub/A]] " + data + " [[sub/A]]"; got != want {
+		t.Errorf("long line rewritten incorrectly (len %d, want %d)", len(got), len(want))
+	}
+}
+
+func TestRewritePreservesEncodedFragment(t *testing.T) {
+	tests := []struct {
+		rawLink, linkType, target, want string
+	}{
+		{"[t](old/Note.md#Some%20Heading)", "markdown", "new/Note.md", "[t](new/Note.md#Some%20Heading)"},
+		{"[t](old/Note.md#a%23b%2)", "markdown", "new/Note.md", "[t](new/Note.md#a%23b%2)"},
+		{"[t](old/Note#%E6%97%A5)", "markdown", "new dir/Note.md", "[t](<new dir/Note#%E6%97%A5>)"},
+		{"[t](<old/My Note.md>#Some%20Heading)", "markdown", "new/My Note.md", "[t](<new/My Note.md#Some%20Heading>)"},
+		{"[[old/Note#Some%20Heading|x]]", "wikilink", "new/Note.md", "[[new/Note#Some%20Heading|x]]"},
+	}
+	for _, tt := range tests {
+		if got := rewriteRawLink(tt.rawLink, tt.linkType, tt.target); got != tt.want {
+			t.Errorf("rewriteRawLink(%q) = %q, want %q", tt.rawLink, got, tt.want)
+		}
+	}
+
+	outgoing := []struct {
+		rawLink, linkType, want string
+	}{
+		{"[t](../Note.md#Some%20Heading)", "markdown", "[t](../../Note.md#Some%20Heading)"},
+		{"[t](./Note%20B.md#x%2Fy)", "markdown", "[t](../Note%20B.md#x%2Fy)"},
+		{"[[../Note#Some%20Heading]]", "wikilink", "[[../../Note#Some%20Heading]]"},
+	}
+	for _, tt := range outgoing {
+		got, err := rewriteOutgoingRelativeLink(tt.rawLink, tt.linkType, "sub/A.md", "sub/deep/A.md")
+		if err != nil {
+			t.Fatalf("rewriteOutgoingRelativeLink(%q): %v", tt.rawLink, err)
+		}
+		if got != tt.want {
+			t.Errorf("rewriteOutgoingRelativeLink(%q) = %q, want %q", tt.rawLink, got, tt.want)
+		}
+		got, err = rewriteOutgoingRelativeLinkBatch(tt.rawLink, tt.linkType, "sub/A.md", "sub/deep/A.md", nil)
+		if err != nil {
+			t.Fatalf("rewriteOutgoingRelativeLinkBatch(%q): %v", tt.rawLink, err)
+		}
+		if got != tt.want {
+			t.Errorf("rewriteOutgoingRelativeLinkBatch(%q) = %q, want %q", tt.rawLink, got, tt.want)
+		}
+	}
+}
+
+func TestRewriteOutgoingRelativeLinkDepth(t *testing.T) {
+	tests := []struct {
+		rawLink, linkType, from, to, want string
+	}{
+		// Up two levels: the link climbs less.
+		{"[x](../../shared/deep/er/Y.md)", "markdown", "a/b/c/N.md", "a/N.md", "[x](./shared/deep/er/Y.md)"},
+		{"[[../../shared/deep/er/Y]]", "wikilink", "a/b/c/N.md", "a/N.md", "[[./shared/deep/er/Y]]"},
+		// To the root and back down into a sibling tree.
+		{"[x](../../shared/Y.md)", "markdown", "a/b/c/N.md", "N.md", "[x](./a/shared/Y.md)"},
+		{"[x](../../../shared/Y.md)", "markdown", "a/b/c/N.md", "x/N.md", "[x](../shared/Y.md)"},
+		{"[x](./Y.md)", "markdown", "a/N.md", "a/b/c/d/N.md", "[x](../../../Y.md)"},
+		// A directory whose name starts with ".." is inside the vault.
+		{"[x](../..notes/Y.md)", "markdown", "a/N.md", "b/N.md", "[x](../..notes/Y.md)"},
+		{"[[../..notes/Y]]", "wikilink", "a/N.md", "N.md", "[[./..notes/Y]]"},
+	}
+	for _, tt := range tests {
+		got, err := rewriteOutgoingRelativeLink(tt.rawLink, tt.linkType, tt.from, tt.to)
+		if err != nil {
+			t.Errorf("rewriteOutgoingRelativeLink(%q, %s → %s): %v", tt.rawLink, tt.from, tt.to, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("rewriteOutgoingRelativeLink(%q, %s → %s) = %q, want %q", tt.rawLink, tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	if _, err := rewriteOutgoingRelativeLink("[x](../../../../Y.md)", "markdown", "a/b/c/N.md", "N.md"); err == nil ||
+		!strings.Contains(err.Error(), "escape vault") {
+		t.Errorf("escaping link: error = %v, want escape error", err)
+	}
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// SearchOptions controls full-text search.
+type SearchOptions struct {
+	Limit int // default 20
+}
+
+// SearchHit is a note whose body matches the search query.
+type SearchHit struct {
+	Path    string
+	Snippet string // matched terms wrapped in [ ]
+}
+
+// SearchResult reports the matching notes and which backend produced them.
+type SearchResult struct {
+	Mode string // "fts5" or "like"
+	Hits []SearchHit
+}
+
+const snippetRadius = 40 // runes of context on each side in LIKE mode
+
+// Search runs a full-text query over note bodies. With FTS5 the query uses
+// FTS5 syntax ("exact phrase", prefix*, AND/OR/NOT) and hits are ranked by
+// relevance. Without FTS5 the query is matched as a case-insensitive substring
+// and hits are ordered by path. The index keeps no note bodies, so snippets
+// (and, without FTS5, the matching itself) read the note files.
+func Search(vaultPath, query string, opts SearchOptions) (*SearchResult, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("search query is required")
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	fts, err := hasNoteFTS(db)
+	if err != nil {
+		return nil, err
+	}
+	if fts {
+		return searchFTS(db, vaultPath, query, opts.Limit)
+	}
+	return searchLike(db, vaultPath, query, opts.Limit)
+}
+
+func searchFTS(db dbExecer, vaultPath, query string, limit int) (*SearchResult, error) {
+	rows, err := db.Query(
+		`SELECT n.path
+		 FROM note_fts JOIN nodes n ON n.id = note_fts.rowid AND n.type = 'note'
+		 WHERE note_fts MATCH ?
+		 ORDER BY rank, n.path
+		 LIMIT ?`, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search query: %w", err)
+	}
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("invalid search query: %w", err)
+	}
+
+	result := &SearchResult{Mode: "fts5"}
+	terms := ftsTerms(query)
+	for _, p := range paths {
+		content, err := os.ReadFile(filepath.Join(vaultPath, p))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		result.Hits = append(result.Hits, SearchHit{Path: p, Snippet: termsSnippet(string(content), terms)})
+	}
+	return result, nil
+}
+
+func searchLike(db dbExecer, vaultPath, query string, limit int) (*SearchResult, error) {
+	// FTS5 phrase quotes and prefix stars have no meaning for a substring scan.
+	term := strings.TrimSuffix(strings.Trim(strings.TrimSpace(query), `"`), "*")
+	paths, err := listRegisteredNotes(db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SearchResult{Mode: "like"}
+	for _, p := range paths {
+		if len(result.Hits) == limit {
+			break
+		}
+		content, err := os.ReadFile(filepath.Join(vaultPath, p))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if snippet := likeSnippet(string(content), term); snippet != "" {
+			result.Hits = append(result.Hits, SearchHit{Path: p, Snippet: snippet})
+		}
+	}
+	return result, nil
+}
+
+// ftsTerms returns the phrases and words of an FTS5 query without its
+// operators, parentheses, and prefix stars.
+func ftsTerms(query string) []string {
+	var terms []string
+	for {
+		query = strings.TrimLeft(query, " \t\n()")
+		if query == "" {
+			return terms
+		}
+		if query[0] == '"' {
+			end := strings.IndexByte(query[1:], '"')
+			if end < 0 {
+				end = len(query) - 1
+			}
+			terms = append(terms, query[1:1+end])
+			query = query[min(2+end, len(query)):]
+			continue
+		}
+		end := strings.IndexAny(query, " \t\n()\"")
+		if end < 0 {
+			end = len(query)
+		}
+		word := strings.TrimSuffix(query[:end], "*")
+		query = query[end:]
+		switch word {
+		case "", "AND", "OR", "NOT", "NEAR":
+			continue
+		}
+		terms = append(terms, word)
+	}
+}
+
+// termsSnippet returns the likeSnippet of the first of terms found in body,
+// trying the words of a phrase on their own when the phrase is not found
+// verbatim (FTS5 matches it across any whitespace or punctuation).
+func termsSnippet(body string, terms []string) string {
+	for _, t := range terms {
+		if s := likeSnippet(body, t); s != "" {
+			return s
+		}
+	}
+	for _, t := range terms {
+		for _, w := range strings.Fields(t) {
+			if s := likeSnippet(body, w); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// likeSnippet returns the text around the first case-insensitive occurrence
+// of term in body, with the match wrapped in [ ] and newlines flattened.
+func likeSnippet(body, term string) string {
+	runes := []rune(body)
+	lower := []rune(strings.Map(unicode.ToLower, body))
+	needle := []rune(strings.Map(unicode.ToLower, term))
+	idx := -1
+	for i := 0; i+len(needle) <= len(lower); i++ {
+		if string(lower[i:i+len(needle)]) == string(needle) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return ""
+	}
+	start := idx - snippetRadius
+	prefix := "..."
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	end := idx + len(needle) + snippetRadius
+	suffix := "..."
+	if end >= len(runes) {
+		end, suffix = len(runes), ""
+	}
+	s := prefix + string(runes[start:idx]) + "[" + string(runes[idx:idx+len(needle)]) + "]" +
+		string(runes[idx+len(needle):end]) + suffix
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func searchPaths(r *SearchResult) []string {
+	var out []string
+	for _, h := range r.Hits {
+		out = append(out, h.Path)
+	}
+	return out
+}
+
+func TestSearch_FTS(t *testing.T) {
+	vault := setupFullVault(t)
+
+	res, err := Search(vault, "welcome", SearchOptions{})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if res.Mode != "fts5" {
+		t.Fatalf("mode = %s, want fts5", res.Mode)
+	}
+	if got := searchPaths(res); len(got) != 1 || got[0] != "Index.md" {
+		t.Fatalf("hits = %v, want [Index.md]", got)
+	}
+	if !strings.Contains(res.Hits[0].Snippet, "[Welcome]") {
+		t.Errorf("snippet = %q, want highlighted [Welcome]", res.Hits[0].Snippet)
+	}
+
+	// The full-text table is contentless: the index keeps no note bodies.
+	db := openTestDB(t, dbPath(vault))
+	defer db.Close()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM note_fts WHERE body IS NOT NULL").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("note_fts holds %d bodies, want none", n)
+	}
+}
+
+func TestSearch_FTSPhraseAndPrefix(t *testing.T) {
+	vault := setupFullVault(t)
+
+	res, err := Search(vault, `"implementation details"`, SearchOptions{})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if got := searchPaths(res); len(got) != 1 || got[0] != "sub/Impl.md" {
+		t.Errorf("phrase hits = %v, want [sub/Impl.md]", got)
+	}
+	if len(res.Hits) == 1 && !strings.Contains(strings.ToLower(res.Hits[0].Snippet), "[implementation details]") {
+		t.Errorf("phrase snippet = %q, want highlighted phrase", res.Hits[0].Snippet)
+	}
+
+	res, err = Search(vault, "implem*", SearchOptions{})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if got := searchPaths(res); len(got) != 1 || got[0] != "sub/Impl.md" {
+		t.Errorf("prefix hits = %v, want [sub/Impl.md]", got)
+	}
+
+	res, err = Search(vault, "design", SearchOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(res.Hits) != 1 {
+		t.Errorf("limit: hits = %d, want 1", len(res.Hits))
+	}
+}
+
+func TestSearch_InvalidQuery(t *testing.T) {
+	vault := setupFullVault(t)
+	if _, err := Search(vault, `"unterminated`, SearchOptions{}); err == nil || !strings.Contains(err.Error(), "invalid search query") {
+		t.Errorf("expected invalid query error, got: %v", err)
+	}
+	if _, err := Search(vault, "  ", SearchOptions{}); err == nil || !strings.Contains(err.Error(), "search query is required") {
+		t.Errorf("expected query required error, got: %v", err)
+	}
+}
+
+func TestSearch_LikeFallback(t *testing.T) {
+	vault := setupFullVault(t)
+
+	// Simulate a driver without FTS5: without note_fts, search scans the files.
+	db := openTestDB(t, dbPath(vault))
+	if _, err := db.Exec("DROP TABLE note_fts"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Search(vault, "WELCOME", SearchOptions{})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if res.Mode != "like" {
+		t.Fatalf("mode = %s, want like", res.Mode)
+	}
+	if got := searchPaths(res); len(got) != 1 || got[0] != "Index.md" {
+		t.Fatalf("hits = %v, want [Index.md]", got)
+	}
+	if !strings.Contains(res.Hits[0].Snippet, "[Welcome]") {
+		t.Errorf("snippet = %q, want highlighted [Welcome]", res.Hits[0].Snippet)
+	}
+}
+
+func TestSearch_SyncedByIncrementalOps(t *testing.T) {
+	vault := setupFullVault(t)
+
+	// Add a new note.
+	if err := os.WriteFile(filepath.Join(vault, "New.md"), []byte("zebra crossing\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Add(vault, AddOptions{Files: []string{"New.md"}}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	expectHits := func(query string, want ...string) {
+		t.Helper()
+		res, err := Search(vault, query, SearchOptions{})
+		if err != nil {
+			t.Fatalf("search %q: %v", query, err)
+		}
+		got := searchPaths(res)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("search %q = %v, want %v", query, got, want)
+		}
+	}
+	expectHits("zebra", "New.md")
+
+	// Update changes the body.
+	if err := os.WriteFile(filepath.Join(vault, "New.md"), []byte("giraffe only\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Update(vault, UpdateOptions{Files: []string{"New.md"}}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	expectHits("zebra")
+	expectHits("giraffe", "New.md")
+
+	// Move keeps the body under the new path.
+	if _, err := Move(vault, MoveOptions{From: "New.md", To: "archive/New.md"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	expectHits("giraffe", "archive/New.md")
+
+	// Delete removes it.
+	if _, err := Delete(vault, DeleteOptions{Files: []string{"archive/New.md"}, RemoveFiles: true}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	expectHits("giraffe")
+}
+
+func TestFTSTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"welcome", []string{"welcome"}},
+		{`"exact phrase" OR prefix*`, []string{"exact phrase", "prefix"}},
+		{"(a AND b) NOT c", []string{"a", "b", "c"}},
+		{`"unterminated`, []string{"unterminated"}},
+	}
+	for _, tt := range tests {
+		if got := ftsTerms(tt.query); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("ftsTerms(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -10,8 +10,9 @@ import (
 
 // SimplifyOptions controls the simplify operation.
 type SimplifyOptions struct {
-	DryRun bool
-	Files  []string
+	DryRun     bool
+	Files      []string
+	KeepBackup bool // as MoveOptions.KeepBackup
 }
 
 // SimplifyResult reports the outcome of the simplify operation.
@@ -24,21 +25,27 @@ type SimplifyResult struct {
 // or can be resolved via root-priority. It works by scanning files directly
 // (no DB required).
 func Simplify(vaultPath string, opts SimplifyOptions) (*SimplifyResult, error) {
-	files, err := collectMarkdownFiles(vaultPath)
+	lock, err := lockIndex(vaultPath)
 	if err != nil {
 		return nil, err
 	}
+	defer lock.unlock()
 
 	cfg, err := LoadConfig(vaultPath)
 	if err != nil {
 		return nil, err
 	}
+
+	files, err := collectMarkdownFiles(vaultPath, cfg.Build)
+	if err != nil {
+		return nil, err
+	}
 	if err := validateGlobPatterns(cfg.Build.ExcludePaths); err != nil {
 		return nil, err
 	}
 	files = filterBuildExcludes(files, cfg.Build.ExcludePaths)
 
-	assetFiles, err := collectAssetFiles(vaultPath)
+	assetFiles, err := collectAssetFiles(vaultPath, cfg.Build)
 	if err != nil {
 		return nil, err
 	}
@@ -49,6 +56,11 @@ func Simplify(vaultPath string, opts SimplifyOptions) (*SimplifyResult, error) {
 	// Build resolve maps.
 	nm := buildNoteResolveMaps(files)
 	am := buildAssetResolveMaps(assetFiles)
+	nm.folderFirst = cfg.Build.folderFirst()
+	if !cfg.Build.rootPriority() {
+		// A root file does not claim its basename; duplicates stay ambiguous.
+		nm.rootBasenameToPath, am.rootBasenameToPath = nil, nil
+	}
 
 	// Build file set for validation.
 	fileSet := make(map[string]bool, len(files))
@@ -140,7 +152,7 @@ func Simplify(vaultPath string, opts SimplifyOptions) (*SimplifyResult, error) {
 				canSimplify, skippedCandidates = canSimplifyAsset(resolvedPath, am)
 				basenameTarget = filepath.Base(resolvedPath)
 			} else {
-				canSimplify, skippedCandidates = canSimplifyNote(resolvedPath, nm)
+				canSimplify, skippedCandidates = canSimplifyNote(sourcePath, resolvedPath, nm)
 				basenameTarget = filepath.Base(resolvedPath)
 			}
 
@@ -176,6 +188,7 @@ func Simplify(vaultPath string, opts SimplifyOptions) (*SimplifyResult, error) {
 				rawLink:    lo.rawLink,
 				linkType:   lo.linkType,
 				lineStart:  lo.lineStart,
+				col:        lo.col,
 				sourcePath: sourcePath,
 				newRawLink: newRawLink,
 			})
@@ -200,10 +213,15 @@ func Simplify(vaultPath string, opts SimplifyOptions) (*SimplifyResult, error) {
 	for _, re := range rewrites {
 		groups[re.sourcePath] = append(groups[re.sourcePath], re)
 	}
-	_, _, applyErr := applyFileRewrites(vaultPath, groups)
+	_, backups, applyErr := applyFileRewrites(vaultPath, groups, false)
 	if applyErr != nil {
 		return nil, applyErr
 	}
+	if opts.KeepBackup {
+		if err := keepBackups(vaultPath, backups); err != nil {
+			return nil, err
+		}
+	}
 
 	return result, nil
 }
@@ -211,8 +229,12 @@ func Simplify(vaultPath string, opts SimplifyOptions) (*SimplifyResult, error) {
 // canSimplifyNote checks if a note path link can be simplified to basename.
 // Returns (canSimplify, candidatesIfSkipped).
 // candidatesIfSkipped is non-nil only when the link is ambiguous and should be reported as skipped.
-func canSimplifyNote(resolvedPath string, nm noteResolveMaps) (bool, []string) {
+func canSimplifyNote(sourcePath, resolvedPath string, nm noteResolveMaps) (bool, []string) {
 	bk := basenameKey(resolvedPath)
+	// Folder-first: a note of that name in the source's folder takes the link.
+	if local, ok := nm.localPath(sourcePath, bk); ok {
+		return resolvedPath == local, nil
+	}
 	count := nm.basenameCounts[bk]
 	if count == 1 {
 		return true, nil
@@ -3,6 +3,7 @@ package core_test
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -172,6 +173,25 @@ func TestSimplifyDryRun(t *testing.T) {
 	}
 }
 
+func TestSimplifyDryRunMatchesRun(t *testing.T) {
+	tmp := t.TempDir()
+	if err := testutil.CopyDir("../../testdata/vault_simplify", tmp); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := core.Simplify(tmp, core.SimplifyOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := core.Simplify(tmp, core.SimplifyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(plan.Rewritten, result.Rewritten) {
+		t.Errorf("dry-run Rewritten = %v, want %v", plan.Rewritten, result.Rewritten)
+	}
+}
+
 func TestSimplifyBasenameUntouched(t *testing.T) {
 	tmp := t.TempDir()
 	if err := testutil.CopyDir("../../testdata/vault_simplify", tmp); err != nil {
@@ -510,3 +530,29 @@ func writeFile(t *testing.T, dir, rel, content string) {
 }
 
 
+
+func TestSimplifyFolderFirst(t *testing.T) {
+	// Under folder-first, [[Note]] in sub/ names sub/Note.md, so a path link
+	// there to the root Note.md must stay, while the one to its sibling can
+	// be shortened.
+	tmp := t.TempDir()
+	writeFile(t, tmp, "mdhop.yaml", "build:\n  link_resolution: folder-first\n")
+	writeFile(t, tmp, "Note.md", "# Root note\n")
+	writeFile(t, tmp, "sub/Note.md", "# Sub note\n")
+	writeFile(t, tmp, "sub/A.md", "[r](../Note.md) [s](./Note.md)\n")
+
+	result, err := core.Simplify(tmp, core.SimplifyOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]string{}
+	for _, r := range result.Rewritten {
+		found[r.OldLink] = r.NewLink
+	}
+	if _, ok := found["[r](../Note.md)"]; ok {
+		t.Error("[r](../Note.md) should not be simplified: [r](Note.md) names sub/Note.md")
+	}
+	if got := found["[s](./Note.md)"]; got != "[s](Note.md)" {
+		t.Errorf("[s](./Note.md) -> %q, want [s](Note.md)", got)
+	}
+}
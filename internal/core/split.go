@@ -0,0 +1,217 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SplitOptions controls the split operation.
+type SplitOptions struct {
+	Source  string // vault-relative note to split
+	Heading string // heading text of the section to extract ("## " prefix optional)
+	Target  string // vault-relative path of the new note
+}
+
+// SplitResult reports the outcome of the split operation.
+type SplitResult struct {
+	Link      string          // link inserted in Source in place of the section
+	Rewritten []RewrittenLink // relative links rewritten in the extracted section
+	Promoted  []string        // phantom nodes promoted to the new note
+}
+
+// Split moves the section under a heading of Source (the heading line up to
+// the next heading of the same or a higher level) into a new note Target,
+// replacing it in Source with a wikilink to Target. Relative links in the
+// section are rewritten for Target's location. Target is indexed through Add
+// and Source through Update under one hold of the index lock. If either step
+// fails, both files are restored, and a Target already added is removed from
+// the index again. Links to the heading itself ([[Source#Heading]]) are not
+// rewritten.
+func Split(vaultPath string, opts SplitOptions) (*SplitResult, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	source := NormalizePath(opts.Source)
+	target := NormalizePath(opts.Target)
+	heading := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(opts.Heading), "#"))
+
+	if heading == "" {
+		return nil, fmt.Errorf("heading is required")
+	}
+	if pathEscapesVault(target) {
+		return nil, fmt.Errorf("destination escapes vault: %s", target)
+	}
+	if source == target {
+		return nil, fmt.Errorf("source and destination are the same: %s", source)
+	}
+	if !strings.HasSuffix(strings.ToLower(target), ".md") {
+		return nil, fmt.Errorf("destination must be a .md file: %s", target)
+	}
+
+	if err := checkCopyRegistration(dbp, source, target); err != nil {
+		return nil, err
+	}
+
+	sourceFull := filepath.Join(vaultPath, source)
+	targetFull := filepath.Join(vaultPath, target)
+	if !fileExists(sourceFull) {
+		return nil, fmt.Errorf("source file not found on disk: %s", source)
+	}
+	if fileExists(targetFull) {
+		return nil, fmt.Errorf("destination already exists on disk: %s", target)
+	}
+
+	info, err := os.Stat(sourceFull)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(sourceFull)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(content), "\n")
+	start, end, err := sectionBounds(string(content), lines, heading)
+	if err != nil {
+		return nil, fmt.Errorf("%w in %s", err, source)
+	}
+
+	// Rewrite relative links in the section from Source's location to Target's.
+	result := &SplitResult{}
+	section := append([]string(nil), lines[start:end]...)
+	lineReps := make(map[int][]linkReplacement)
+	for _, link := range parseLinks(string(content)) {
+		if !link.isRelative || (link.linkType != "wikilink" && link.linkType != "markdown") {
+			continue
+		}
+		if link.lineStart-1 < start || link.lineStart-1 >= end {
+			continue
+		}
+		newRL, err := rewriteOutgoingRelativeLink(link.rawLink, link.linkType, source, target)
+		if err != nil {
+			return nil, err
+		}
+		if newRL == link.rawLink {
+			continue
+		}
+		idx := link.lineStart - 1 - start
+		lineReps[idx] = append(lineReps[idx], linkReplacement{link.linkType, link.rawLink, newRL, link.col})
+		result.Rewritten = append(result.Rewritten, RewrittenLink{
+			File:    target,
+			OldLink: link.rawLink,
+			NewLink: newRL,
+		})
+	}
+	for idx, reps := range lineReps {
+		section[idx] = replaceRawLinks(section[idx], reps)
+	}
+
+	link, err := splitLink(dbp, target)
+	if err != nil {
+		return nil, err
+	}
+	result.Link = link
+	rest := make([]string, 0, len(lines)-(end-start)+1)
+	rest = append(rest, lines[:start]...)
+	rest = append(rest, link)
+	rest = append(rest, lines[end:]...)
+
+	if err := os.MkdirAll(filepath.Dir(targetFull), 0o755); err != nil {
+		return nil, err
+	}
+	if err := writeFilePreservePerm(targetFull, []byte(strings.Join(section, "\n")+"\n"), info.Mode().Perm()); err != nil {
+		return nil, err
+	}
+	if err := writeFilePreservePerm(sourceFull, []byte(strings.Join(rest, "\n")), info.Mode().Perm()); err != nil {
+		_ = os.Remove(targetFull)
+		_ = CleanupEmptyDirs(vaultPath, []string{target})
+		return nil, err
+	}
+
+	restore := func() {
+		_ = writeFilePreservePerm(sourceFull, content, info.Mode().Perm())
+		_ = os.Remove(targetFull)
+		_ = CleanupEmptyDirs(vaultPath, []string{target})
+	}
+	addResult, err := addLocked(vaultPath, AddOptions{Files: []string{target}})
+	if err != nil {
+		restore()
+		return nil, err
+	}
+	result.Promoted = addResult.Promoted
+	if _, err := updateLocked(vaultPath, UpdateOptions{Files: []string{source}}); err != nil {
+		// Source's index entry is unchanged; undo adding Target (a promoted
+		// phantom becomes a phantom again).
+		restore()
+		if _, derr := deleteLocked(vaultPath, DeleteOptions{Files: []string{target}}); derr != nil {
+			return nil, fmt.Errorf("updating %s failed: %w; removing %s from the index also failed (run 'mdhop delete --file %s'): %v", source, err, target, target, derr)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// sectionBounds returns the 0-based half-open line range of the section under
+// the heading with the given text: the heading line up to the next heading of
+// the same or a higher level, without trailing blank lines.
+func sectionBounds(content string, lines []string, heading string) (int, int, error) {
+	headings := parseHeadings(content)
+	found := -1
+	for i, h := range headings {
+		if h.text != heading {
+			continue
+		}
+		if found >= 0 {
+			return 0, 0, fmt.Errorf("heading is not unique: %s", heading)
+		}
+		found = i
+	}
+	if found < 0 {
+		return 0, 0, fmt.Errorf("heading not found: %s", heading)
+	}
+
+	h := headings[found]
+	start := h.line - 1
+	end := len(lines)
+	for _, next := range headings[found+1:] {
+		if next.level <= h.level {
+			end = next.line - 1
+			break
+		}
+	}
+	for end > start+1 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return start, end, nil
+}
+
+// splitLink returns the wikilink Split leaves in place of the section: the
+// target's basename when no registered note shares it, otherwise its path.
+func splitLink(dbp, target string) (string, error) {
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	paths, err := listRegisteredNotes(db)
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(target, filepath.Ext(target))
+	for _, p := range paths {
+		if basenameKey(p) == basenameKey(target) {
+			return "[[" + name + "]]", nil
+		}
+	}
+	return "[[" + filepath.Base(name) + "]]", nil
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplit_ExtractsSection(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"notes/Spec.md": "# Spec\n\nIntro [[Other]].\n\n## Details\n\nSee [B](../B.md) and [[Other]].\n\n### Sub\n\nMore.\n\n## Next\n\nTail.\n",
+		"B.md":          "# B\n",
+		"Other.md":      "# Other\n",
+	})
+	buildVault(t, vault)
+
+	result, err := Split(vault, SplitOptions{Source: "notes/Spec.md", Heading: "## Details", Target: "notes/details/Details.md"})
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	if result.Link != "[[Details]]" {
+		t.Errorf("link = %q, want [[Details]]", result.Link)
+	}
+	if len(result.Rewritten) != 1 || result.Rewritten[0].NewLink != "[B](../../B.md)" {
+		t.Errorf("rewritten = %+v, want [B](../../B.md)", result.Rewritten)
+	}
+
+	target, err := os.ReadFile(filepath.Join(vault, "notes/details/Details.md"))
+	if err != nil {
+		t.Fatalf("read target: %v", err)
+	}
+	if got, want := string(target), "## Details\n\nSee [B](../../B.md) and [[Other]].\n\n### Sub\n\nMore.\n"; got != want {
+		t.Errorf("target = %q, want %q", got, want)
+	}
+	source, err := os.ReadFile(filepath.Join(vault, "notes/Spec.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(source), "# Spec\n\nIntro [[Other]].\n\n[[Details]]\n\n## Next\n\nTail.\n"; got != want {
+		t.Errorf("source = %q, want %q", got, want)
+	}
+
+	// The stub links Source to Target, and the section's links moved along.
+	var stub bool
+	for _, e := range queryEdges(t, dbPath(vault), "notes/Spec.md") {
+		if e.targetKey == noteKey("notes/details/Details.md") {
+			stub = true
+		}
+		if e.targetKey == noteKey("B.md") {
+			t.Errorf("source still links to B.md: %s", e.rawLink)
+		}
+	}
+	if !stub {
+		t.Error("missing edge from source to the new note")
+	}
+	var toB, toOther bool
+	for _, e := range queryEdges(t, dbPath(vault), "notes/details/Details.md") {
+		toB = toB || e.targetKey == noteKey("B.md")
+		toOther = toOther || e.targetKey == noteKey("Other.md")
+	}
+	if !toB || !toOther {
+		t.Errorf("target edges: B=%v Other=%v, want both", toB, toOther)
+	}
+}
+
+func TestSplit_PathLinkWhenBasenameTaken(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"Spec.md":    "# Spec\n\n## Details\n\nBody.\n",
+		"Details.md": "# Details\n",
+	})
+	buildVault(t, vault)
+
+	result, err := Split(vault, SplitOptions{Source: "Spec.md", Heading: "Details", Target: "sub/Details.md"})
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	if result.Link != "[[sub/Details]]" {
+		t.Errorf("link = %q, want [[sub/Details]]", result.Link)
+	}
+}
+
+func TestSplit_HeadingNotFound(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"Spec.md": "# Spec\n\n## Details\n\nBody.\n\n## Details\n",
+	})
+	buildVault(t, vault)
+	orig, err := os.ReadFile(filepath.Join(vault, "Spec.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for heading, want := range map[string]string{"Missing": "heading not found", "Details": "not unique"} {
+		_, err := Split(vault, SplitOptions{Source: "Spec.md", Heading: heading, Target: "New.md"})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v, want %q", heading, err, want)
+		}
+	}
+	after, err := os.ReadFile(filepath.Join(vault, "Spec.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(orig) || fileExists(filepath.Join(vault, "New.md")) {
+		t.Error("failed split changed the vault")
+	}
+}
+
+func TestSplit_RollbackOnUpdateFailure(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"a/Note.md": "# Note\n",
+		"Spec.md":   "# Spec\n\n## Details\n\nBody.\n",
+	})
+	buildVault(t, vault)
+	// [[Note]] is added after the build, so Add of b/Note.md sees no edge to
+	// make ambiguous, but Update of Spec.md then fails on it.
+	edited := "# Spec\n\n[[Note]]\n\n## Details\n\nBody.\n"
+	writeVaultFiles(t, vault, map[string]string{"Spec.md": edited})
+
+	_, err := Split(vault, SplitOptions{Source: "Spec.md", Heading: "Details", Target: "b/Note.md"})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous error, got: %v", err)
+	}
+	after, err := os.ReadFile(filepath.Join(vault, "Spec.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != edited {
+		t.Errorf("source = %q, want %q", after, edited)
+	}
+	if _, statErr := os.Stat(filepath.Join(vault, "b")); !os.IsNotExist(statErr) {
+		t.Errorf("target and its directory should be removed after failure, stat err = %v", statErr)
+	}
+	db := openTestDB(t, dbPath(vault))
+	defer db.Close()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM nodes WHERE node_key = ?", noteKey("b/Note.md")).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Error("target still in the index after failed split")
+	}
+}
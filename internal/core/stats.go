@@ -8,25 +8,86 @@ import (
 // StatsOptions controls which fields to return.
 type StatsOptions struct {
 	Fields []string // nil/empty = all
+	ByDir  bool     // group notes/edges/orphans by directory
+	Depth  int      // directory prefix depth for ByDir (default 1)
+
+	Histogram bool // distribution of outgoing link counts per note
+	Incoming  bool // also the distribution of incoming link counts (needs Histogram)
+
+	Top    int  // rank notes by incoming and outgoing edges, N per list (0 = off)
+	NoTags bool // with Top, do not count tag edges as outgoing
+}
+
+// DirStats contains per-directory statistics.
+// Dir is "." for notes at the vault root.
+type DirStats struct {
+	Dir     string
+	Notes   int
+	Edges   int // outgoing edges of notes in the directory (occurrence-based)
+	Orphans int // notes with no incoming edges from other nodes
+}
+
+// HistogramBucket is the number of notes having exactly Links links.
+type HistogramBucket struct {
+	Links int
+	Notes int
+}
+
+// LinkHistogram is the distribution of per-note link counts over existing
+// notes. Buckets are ascending by Links and omit counts no note has.
+type LinkHistogram struct {
+	Buckets []HistogramBucket
+	Min     int
+	Max     int
+	Median  float64
+}
+
+// NoteCount is a note with the number of edges counted for it.
+type NoteCount struct {
+	Path  string
+	Count int
 }
 
 // StatsResult contains vault statistics.
 type StatsResult struct {
-	NotesTotal    int
-	NotesExists   int
-	EdgesTotal    int
-	TagsTotal     int
-	PhantomsTotal int
-	AssetsTotal   int
+	NotesTotal         int
+	NotesExists        int
+	EdgesTotal         int
+	TagsTotal          int
+	PhantomsTotal      int
+	AssetsTotal        int
+	ExternalLinksTotal int            // external (http/https) link occurrences
+	ExternalURLsTotal  int            // distinct external URLs
+	Dirs               []DirStats     // nil = not requested
+	OutgoingHistogram  *LinkHistogram // nil = not requested
+	IncomingHistogram  *LinkHistogram // nil = not requested
+	TopReferenced      []NoteCount    // most incoming edges; nil = not requested
+	TopLinking         []NoteCount    // most outgoing edges; nil = not requested
 }
 
 // Stats returns aggregate statistics for the indexed vault.
 func Stats(vaultPath string, opts StatsOptions) (*StatsResult, error) {
+	if opts.Incoming && !opts.Histogram {
+		return nil, fmt.Errorf("incoming requires histogram")
+	}
+	if opts.Top < 0 {
+		return nil, fmt.Errorf("top must be >= 0")
+	}
+	if opts.NoTags && opts.Top == 0 {
+		return nil, fmt.Errorf("no tags requires top")
+	}
+
 	dbp := dbPath(vaultPath)
 	if _, err := os.Stat(dbp); os.IsNotExist(err) {
 		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
 	}
 
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
 	db, err := openDBAt(dbp)
 	if err != nil {
 		return nil, err
@@ -71,5 +132,182 @@ func Stats(vaultPath string, opts StatsOptions) (*StatsResult, error) {
 		}
 	}
 
+	if isFieldActive("external_links_total", opts.Fields) {
+		if err := db.QueryRow(`SELECT COUNT(*) FROM external_links`).Scan(&result.ExternalLinksTotal); err != nil {
+			return nil, err
+		}
+	}
+
+	if isFieldActive("external_urls_total", opts.Fields) {
+		if err := db.QueryRow(`SELECT COUNT(DISTINCT url) FROM external_links`).Scan(&result.ExternalURLsTotal); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.ByDir {
+		depth := opts.Depth
+		if depth <= 0 {
+			depth = 1
+		}
+		dirs, err := statsByDir(db, depth)
+		if err != nil {
+			return nil, err
+		}
+		result.Dirs = dirs
+	}
+
+	if opts.Histogram {
+		h, err := linkHistogram(db, `e.source_id = n.id`)
+		if err != nil {
+			return nil, err
+		}
+		result.OutgoingHistogram = h
+		if opts.Incoming {
+			h, err := linkHistogram(db, `e.target_id = n.id AND e.source_id != n.id`)
+			if err != nil {
+				return nil, err
+			}
+			result.IncomingHistogram = h
+		}
+	}
+
+	if opts.Top > 0 {
+		// Links from a note to itself do not make it referenced.
+		result.TopReferenced, err = topNotes(db, `e.target_id`, `e.source_id != e.target_id`, opts.Top)
+		if err != nil {
+			return nil, err
+		}
+		cond := `1 = 1`
+		if opts.NoTags {
+			cond = `e.link_type != 'tag'`
+		}
+		result.TopLinking, err = topNotes(db, `e.source_id`, cond, opts.Top)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return result, nil
 }
+
+// topNotes ranks existing notes by the number of edges matching edgeCond
+// whose column side (e.source_id or e.target_id) is the note, most first and
+// then by path, returning at most limit notes. Notes without edges are left out.
+func topNotes(db dbExecer, side, edgeCond string, limit int) ([]NoteCount, error) {
+	rows, err := db.Query(`
+		SELECT n.path, COUNT(*) AS cnt
+		FROM edges e JOIN nodes n ON n.id = `+side+`
+		WHERE n.type = 'note' AND n.exists_flag = 1 AND `+edgeCond+`
+		GROUP BY n.id
+		ORDER BY cnt DESC, n.path
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []NoteCount{}
+	for rows.Next() {
+		var nc NoteCount
+		if err := rows.Scan(&nc.Path, &nc.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, nc)
+	}
+	return out, rows.Err()
+}
+
+// linkHistogram groups existing notes by how many link edges match edgeCond
+// (an SQL condition on e relating it to the note n). Tags are not links.
+func linkHistogram(db dbExecer, edgeCond string) (*LinkHistogram, error) {
+	rows, err := db.Query(`
+		SELECT cnt, COUNT(*) FROM (
+			SELECT (SELECT COUNT(*) FROM edges e
+			        WHERE ` + edgeCond + `
+			          AND e.link_type IN ('wikilink', 'markdown', 'frontmatter-link')) AS cnt
+			FROM nodes n WHERE n.type = 'note' AND n.exists_flag = 1
+		)
+		GROUP BY cnt
+		ORDER BY cnt`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	h := &LinkHistogram{Buckets: []HistogramBucket{}}
+	total := 0
+	for rows.Next() {
+		var b HistogramBucket
+		if err := rows.Scan(&b.Links, &b.Notes); err != nil {
+			return nil, err
+		}
+		h.Buckets = append(h.Buckets, b)
+		total += b.Notes
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if total == 0 {
+		return h, nil
+	}
+
+	h.Min = h.Buckets[0].Links
+	h.Max = h.Buckets[len(h.Buckets)-1].Links
+	// nth returns the link count of the i-th note (0-based) in sorted order.
+	nth := func(i int) int {
+		for _, b := range h.Buckets {
+			if i < b.Notes {
+				return b.Links
+			}
+			i -= b.Notes
+		}
+		return h.Max
+	}
+	h.Median = float64(nth((total-1)/2)+nth(total/2)) / 2
+	return h, nil
+}
+
+// statsByDir aggregates notes, outgoing edges, and orphans per directory prefix
+// of at most depth segments, sorted by note count descending.
+func statsByDir(db dbExecer, depth int) ([]DirStats, error) {
+	rows, err := db.Query(`
+		WITH RECURSIVE seg(id, rest, dir, depth) AS (
+			SELECT id, path, '', 0 FROM nodes WHERE type='note'
+			UNION ALL
+			SELECT id,
+			       substr(rest, instr(rest, '/') + 1),
+			       CASE WHEN dir = '' THEN '' ELSE dir || '/' END || substr(rest, 1, instr(rest, '/') - 1),
+			       depth + 1
+			FROM seg WHERE instr(rest, '/') > 0 AND depth < ?
+		),
+		note_dir AS (
+			SELECT id, dir FROM seg s
+			WHERE depth = (SELECT MAX(depth) FROM seg t WHERE t.id = s.id)
+		)
+		SELECT nd.dir,
+		       COUNT(*),
+		       SUM((SELECT COUNT(*) FROM edges e WHERE e.source_id = nd.id)),
+		       SUM(CASE WHEN EXISTS (SELECT 1 FROM edges e WHERE e.target_id = nd.id AND e.source_id != nd.id) THEN 0 ELSE 1 END)
+		FROM note_dir nd
+		GROUP BY nd.dir
+		ORDER BY COUNT(*) DESC, nd.dir`,
+		depth,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dirs := []DirStats{}
+	for rows.Next() {
+		var d DirStats
+		if err := rows.Scan(&d.Dir, &d.Notes, &d.Edges, &d.Orphans); err != nil {
+			return nil, err
+		}
+		if d.Dir == "" {
+			d.Dir = "."
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs, rows.Err()
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -134,3 +135,181 @@ func TestStats_Phantoms(t *testing.T) {
 		t.Errorf("phantoms_total = %d, want 2", result.PhantomsTotal)
 	}
 }
+
+func TestStats_ByDir(t *testing.T) {
+	vault := setupVaultForStats(t, "vault_stats_dirs")
+
+	result, err := Stats(vault, StatsOptions{ByDir: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []DirStats{
+		{Dir: "a", Notes: 3, Edges: 4, Orphans: 0},
+		{Dir: "b", Notes: 3, Edges: 2, Orphans: 2},
+		{Dir: ".", Notes: 1, Edges: 2, Orphans: 1},
+	}
+	if len(result.Dirs) != len(want) {
+		t.Fatalf("dirs = %+v, want %+v", result.Dirs, want)
+	}
+	for i := range want {
+		if result.Dirs[i] != want[i] {
+			t.Errorf("dirs[%d] = %+v, want %+v", i, result.Dirs[i], want[i])
+		}
+	}
+
+	// Grouped totals must sum to vault totals.
+	var notes, edges int
+	for _, d := range result.Dirs {
+		notes += d.Notes
+		edges += d.Edges
+	}
+	if notes != result.NotesTotal {
+		t.Errorf("sum notes = %d, want %d", notes, result.NotesTotal)
+	}
+	if edges != result.EdgesTotal {
+		t.Errorf("sum edges = %d, want %d", edges, result.EdgesTotal)
+	}
+}
+
+func TestStats_ByDirDepth(t *testing.T) {
+	vault := setupVaultForStats(t, "vault_stats_dirs")
+
+	result, err := Stats(vault, StatsOptions{ByDir: true, Depth: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantDirs := []string{"b", "a", ".", "a/deep"}
+	if len(result.Dirs) != len(wantDirs) {
+		t.Fatalf("dirs = %+v, want %v", result.Dirs, wantDirs)
+	}
+	for i, d := range wantDirs {
+		if result.Dirs[i].Dir != d {
+			t.Errorf("dirs[%d] = %q, want %q", i, result.Dirs[i].Dir, d)
+		}
+	}
+}
+
+func TestStats_ByDirNotRequested(t *testing.T) {
+	vault := setupVaultForStats(t, "vault_stats_dirs")
+
+	result, err := Stats(vault, StatsOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Dirs != nil {
+		t.Errorf("dirs = %+v, want nil", result.Dirs)
+	}
+}
+
+func TestStats_Histogram(t *testing.T) {
+	vault := setupVaultForStats(t, "vault_stats_dirs")
+
+	result, err := Stats(vault, StatsOptions{Histogram: true, Incoming: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, h := range map[string]*LinkHistogram{
+		"outgoing": result.OutgoingHistogram,
+		"incoming": result.IncomingHistogram,
+	} {
+		if h == nil {
+			t.Fatalf("%s histogram is nil", name)
+		}
+		sum := 0
+		for i, b := range h.Buckets {
+			if i > 0 && b.Links <= h.Buckets[i-1].Links {
+				t.Errorf("%s buckets not ascending: %+v", name, h.Buckets)
+			}
+			sum += b.Notes
+		}
+		if sum != result.NotesExists {
+			t.Errorf("%s histogram sums to %d, want notes_exists %d", name, sum, result.NotesExists)
+		}
+		if h.Min != h.Buckets[0].Links || h.Max != h.Buckets[len(h.Buckets)-1].Links {
+			t.Errorf("%s min/max = %d/%d, buckets %+v", name, h.Min, h.Max, h.Buckets)
+		}
+		if h.Median < float64(h.Min) || h.Median > float64(h.Max) {
+			t.Errorf("%s median = %v, outside [%d, %d]", name, h.Median, h.Min, h.Max)
+		}
+	}
+}
+
+func TestStats_HistogramFull(t *testing.T) {
+	vault := setupVaultForStats(t, "vault_build_full")
+
+	result, err := Stats(vault, StatsOptions{Histogram: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Outgoing links (tags excluded): Design 2, sub/Impl 3, Index 8.
+	h := result.OutgoingHistogram
+	want := []HistogramBucket{{Links: 2, Notes: 1}, {Links: 3, Notes: 1}, {Links: 8, Notes: 1}}
+	if len(h.Buckets) != len(want) {
+		t.Fatalf("buckets = %+v, want %+v", h.Buckets, want)
+	}
+	for i := range want {
+		if h.Buckets[i] != want[i] {
+			t.Errorf("buckets[%d] = %+v, want %+v", i, h.Buckets[i], want[i])
+		}
+	}
+	if h.Min != 2 || h.Max != 8 || h.Median != 3 {
+		t.Errorf("min/max/median = %d/%d/%v, want 2/8/3", h.Min, h.Max, h.Median)
+	}
+	if result.IncomingHistogram != nil {
+		t.Errorf("incoming histogram = %+v, want nil", result.IncomingHistogram)
+	}
+}
+
+func TestStats_HistogramEmpty(t *testing.T) {
+	vault := setupVaultForStats(t, "vault_build_empty")
+
+	result, err := Stats(vault, StatsOptions{Histogram: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h := result.OutgoingHistogram; len(h.Buckets) != 0 || h.Min != 0 || h.Max != 0 || h.Median != 0 {
+		t.Errorf("histogram = %+v, want empty", h)
+	}
+}
+
+func TestStats_IncomingRequiresHistogram(t *testing.T) {
+	vault := setupVaultForStats(t, "vault_build_full")
+
+	if _, err := Stats(vault, StatsOptions{Incoming: true}); err == nil {
+		t.Error("expected error for incoming without histogram")
+	}
+}
+
+func TestStats_Top(t *testing.T) {
+	vault := setupVaultForStats(t, "vault_build_full")
+
+	result, err := Stats(vault, StatsOptions{Top: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Design and sub/Impl are linked 4 times each; ties go by path.
+	wantRef := []NoteCount{{Path: "Design.md", Count: 4}, {Path: "sub/Impl.md", Count: 4}}
+	if !reflect.DeepEqual(result.TopReferenced, wantRef) {
+		t.Errorf("top referenced = %+v, want %+v", result.TopReferenced, wantRef)
+	}
+	wantLink := []NoteCount{{Path: "Index.md", Count: 12}, {Path: "Design.md", Count: 4}}
+	if !reflect.DeepEqual(result.TopLinking, wantLink) {
+		t.Errorf("top linking = %+v, want %+v", result.TopLinking, wantLink)
+	}
+
+	// Without tags Design's two tags drop out of its outgoing count.
+	result, err = Stats(vault, StatsOptions{Top: 3, NoTags: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantLink = []NoteCount{{Path: "Index.md", Count: 11}, {Path: "sub/Impl.md", Count: 4}, {Path: "Design.md", Count: 2}}
+	if !reflect.DeepEqual(result.TopLinking, wantLink) {
+		t.Errorf("top linking without tags = %+v, want %+v", result.TopLinking, wantLink)
+	}
+
+	if _, err := Stats(vault, StatsOptions{NoTags: true}); err == nil {
+		t.Error("expected error for NoTags without Top")
+	}
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// TagOptions controls the tag operation.
+type TagOptions struct {
+	Op    string   // "add" or "remove"
+	Tag   string   // tag name ("#" prefix optional)
+	Files []string // vault-relative notes to edit
+}
+
+// TagResult reports the outcome of the tag operation.
+type TagResult struct {
+	Updated   []string // notes whose frontmatter was rewritten
+	Unchanged []string // notes that already had (add) or did not list (remove) the tag
+}
+
+// Tag adds a tag to, or removes it from, the frontmatter "tags" of each note
+// in Files, then reindexes the rewritten notes through Update, which also
+// deletes tag nodes left without references. Add creates the frontmatter or
+// the "tags" key when missing and skips notes already listing the tag
+// (case-insensitive unless build.tag_case is preserve). Remove only edits
+// frontmatter: inline #tags in the body stay, and so does the tag's node
+// while they reference it. Every note is checked and edited in memory before
+// any file is written, and the index lock is held throughout. If a write or
+// the reindex fails, every file already written is restored.
+func Tag(vaultPath string, opts TagOptions) (*TagResult, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	if opts.Op != "add" && opts.Op != "remove" {
+		return nil, fmt.Errorf("invalid tag operation: %s (want add or remove)", opts.Op)
+	}
+	bare := strings.TrimPrefix(strings.TrimSpace(opts.Tag), "#")
+	if !validTagName(bare) {
+		return nil, fmt.Errorf("invalid tag: %s", opts.Tag)
+	}
+	if len(opts.Files) == 0 {
+		return nil, fmt.Errorf("at least one file is required")
+	}
+
+	files := make([]string, 0, len(opts.Files))
+	seen := make(map[string]bool)
+	for _, f := range opts.Files {
+		p := NormalizePath(f)
+		if !seen[p] {
+			seen[p] = true
+			files = append(files, p)
+		}
+	}
+	if err := checkTagRegistration(dbp, files); err != nil {
+		return nil, err
+	}
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+
+	type edit struct {
+		path    string
+		content []byte
+		orig    []byte
+		perm    os.FileMode
+	}
+	var edits []edit
+	result := &TagResult{}
+	for _, p := range files {
+		full := filepath.Join(vaultPath, p)
+		info, err := os.Stat(full)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file not found on disk: %s", p)
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(full)
+		if err != nil {
+			return nil, err
+		}
+		var out string
+		var changed bool
+		if opts.Op == "add" {
+			out, changed, err = addFrontmatterTag(string(content), bare, cfg.Build.preserveTagCase())
+		} else {
+			out, changed, err = removeFrontmatterTag(string(content), bare, cfg.Build.preserveTagCase())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, p)
+		}
+		if !changed {
+			result.Unchanged = append(result.Unchanged, p)
+			continue
+		}
+		edits = append(edits, edit{path: p, content: []byte(out), orig: content, perm: info.Mode().Perm()})
+		result.Updated = append(result.Updated, p)
+	}
+	if len(edits) == 0 {
+		return result, nil
+	}
+
+	written := 0
+	restore := func() {
+		for _, e := range edits[:written] {
+			_ = writeFilePreservePerm(filepath.Join(vaultPath, e.path), e.orig, e.perm)
+		}
+	}
+	for _, e := range edits {
+		if err := writeFilePreservePerm(filepath.Join(vaultPath, e.path), e.content, e.perm); err != nil {
+			restore()
+			return nil, err
+		}
+		written++
+		verbosef("tag: %s #%s in %s", opts.Op, bare, e.path)
+	}
+	if _, err := updateLocked(vaultPath, UpdateOptions{Files: result.Updated}); err != nil {
+		restore()
+		return nil, err
+	}
+	return result, nil
+}
+
+// validTagName reports whether bare (without "#") would be parsed as a tag.
+func validTagName(bare string) bool {
+	first, _ := utf8.DecodeRuneInString(bare)
+	if bare == "" || !isTagFirstRune(first) {
+		return false
+	}
+	for _, r := range bare {
+		if !isTagRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTagRegistration returns an error for the first of files that is not a
+// registered note.
+func checkTagRegistration(dbp string, files []string) error {
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	paths, err := listRegisteredNotes(db)
+	if err != nil {
+		return err
+	}
+	registered := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		registered[p] = true
+	}
+	for _, f := range files {
+		if !registered[f] {
+			return fmt.Errorf("note not registered: %s", f)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTagAdd(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"Plain.md": "# Plain\n",
+		"Block.md": "---\ntitle: Block\ntags:\n    - a\n    - \"#b\"   # kept\n---\nbody\n",
+		"Flow.md":  "---\ntags: [a]\n---\n",
+		"List.md":  "---\ntags: a, b\nstatus: x\n---\n",
+		"Has.md":   "---\ntags: [Review]\n---\n",
+	})
+	buildVault(t, vault)
+
+	files := []string{"Plain.md", "Block.md", "Flow.md", "List.md", "Has.md"}
+	result, err := Tag(vault, TagOptions{Op: "add", Tag: "#review", Files: files})
+	if err != nil {
+		t.Fatalf("Tag: %v", err)
+	}
+	if want := []string{"Plain.md", "Block.md", "Flow.md", "List.md"}; !reflect.DeepEqual(result.Updated, want) {
+		t.Errorf("Updated = %v, want %v", result.Updated, want)
+	}
+	if want := []string{"Has.md"}; !reflect.DeepEqual(result.Unchanged, want) {
+		t.Errorf("Unchanged = %v, want %v", result.Unchanged, want)
+	}
+
+	want := map[string]string{
+		"Plain.md": "---\ntags:\n  - review\n---\n# Plain\n",
+		"Block.md": "---\ntitle: Block\ntags:\n    - a\n    - \"#b\"   # kept\n    - review\n---\nbody\n",
+		"Flow.md":  "---\ntags: [a, review]\n---\n",
+		"List.md":  "---\ntags: a, b, review\nstatus: x\n---\n",
+		"Has.md":   "---\ntags: [Review]\n---\n",
+	}
+	for name, w := range want {
+		got, err := os.ReadFile(filepath.Join(vault, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != w {
+			t.Errorf("%s =\n%q\nwant\n%q", name, got, w)
+		}
+	}
+
+	db := openTestDB(t, dbPath(vault))
+	defer db.Close()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM edges e JOIN nodes t ON t.id = e.target_id
+		WHERE t.node_key = 'tag:name:#review'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("#review edges = %d, want 5", n)
+	}
+}
+
+func TestTagRemoveCleansOrphanedTag(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "---\ntags:\n  - old\n---\nbody\n",
+		"B.md": "---\ntags: [keep, old]\n---\n",
+		"C.md": "---\ntags: old\n---\n",
+		"D.md": "---\ntags: [keep]\n---\n",
+	})
+	buildVault(t, vault)
+
+	result, err := Tag(vault, TagOptions{Op: "remove", Tag: "old", Files: []string{"A.md", "B.md", "C.md", "D.md"}})
+	if err != nil {
+		t.Fatalf("Tag: %v", err)
+	}
+	if want := []string{"A.md", "B.md", "C.md"}; !reflect.DeepEqual(result.Updated, want) {
+		t.Errorf("Updated = %v, want %v", result.Updated, want)
+	}
+
+	want := map[string]string{
+		"A.md": "---\n---\nbody\n",
+		"B.md": "---\ntags: [keep]\n---\n",
+		"C.md": "---\ntags:\n---\n",
+	}
+	for name, w := range want {
+		got, _ := os.ReadFile(filepath.Join(vault, name))
+		if string(got) != w {
+			t.Errorf("%s =\n%q\nwant\n%q", name, got, w)
+		}
+	}
+
+	var names []string
+	for _, n := range queryNodes(t, dbPath(vault), "tag") {
+		names = append(names, n.name)
+	}
+	if want := []string{"#keep"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("tag nodes = %v, want %v", names, want)
+	}
+}
+
+func TestTagErrors(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{"A.md": "# A\n"})
+	buildVault(t, vault)
+
+	tests := []struct {
+		name string
+		opts TagOptions
+		want string
+	}{
+		{"bad op", TagOptions{Op: "set", Tag: "x", Files: []string{"A.md"}}, "invalid tag operation"},
+		{"bad tag", TagOptions{Op: "add", Tag: "1abc", Files: []string{"A.md"}}, "invalid tag"},
+		{"space", TagOptions{Op: "add", Tag: "a b", Files: []string{"A.md"}}, "invalid tag"},
+		{"no files", TagOptions{Op: "add", Tag: "x"}, "at least one file"},
+		{"unregistered", TagOptions{Op: "add", Tag: "x", Files: []string{"A.md", "Z.md"}}, "note not registered: Z.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Tag(vault, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	// A failed check leaves every file untouched.
+	got, _ := os.ReadFile(filepath.Join(vault, "A.md"))
+	if string(got) != "# A\n" {
+		t.Errorf("A.md = %q, want it unchanged", got)
+	}
+}
+
+func TestTagRollbackOnUpdateFailure(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":   "# A\n",
+		"B.md":   "# B\n",
+		"x/N.md": "# N\n",
+		"y/N.md": "# N\n",
+	})
+	buildVault(t, vault)
+	// [[N]] is added after the build, so reindexing B.md fails on it.
+	writeVaultFiles(t, vault, map[string]string{"B.md": "[[N]]\n"})
+
+	_, err := Tag(vault, TagOptions{Op: "add", Tag: "review", Files: []string{"A.md", "B.md"}})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous link") {
+		t.Fatalf("expected ambiguous link error, got: %v", err)
+	}
+	for name, want := range map[string]string{"A.md": "# A\n", "B.md": "[[N]]\n"} {
+		got, err := os.ReadFile(filepath.Join(vault, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q (restored)", name, got, want)
+		}
+	}
+}
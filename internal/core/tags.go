@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// TagsOptions controls ListTags.
+type TagsOptions struct {
+	MinCount int  // omit tags used by fewer notes; 0 = keep all
+	LeafOnly bool // only tags without a descendant tag (#a/b but not #a)
+	Tree     bool // order depth-first under each parent instead of by count alone
+}
+
+// TagCount is a tag with the number of notes using it. A note tagged #a/b
+// counts toward #a as well, since build expands nested tags into every level.
+type TagCount struct {
+	Tag   string
+	Count int
+	Depth int // nesting level: 0 for #a, 1 for #a/b, ...
+}
+
+// ListTags returns every tag with its note count, most used first (ties by
+// tag name). With Tree, children follow their parent, ordered the same way.
+func ListTags(vaultPath string, opts TagsOptions) ([]TagCount, error) {
+	if opts.MinCount < 0 {
+		return nil, fmt.Errorf("min count must be >= 0")
+	}
+	if opts.LeafOnly && opts.Tree {
+		return nil, fmt.Errorf("leaf-only cannot be combined with tree")
+	}
+
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(
+		`SELECT t.name, t.node_key, COUNT(DISTINCT e.source_id)
+		 FROM edges e
+		 JOIN nodes t ON t.id = e.target_id AND t.type = 'tag'
+		 JOIN nodes s ON s.id = e.source_id AND s.type = 'note' AND s.exists_flag = 1
+		 WHERE e.link_type IN ('tag', 'frontmatter')
+		 GROUP BY t.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Tags are keyed by node key, which folds case unless build.tag_case
+	// is preserve.
+	byKey := make(map[string]TagCount)
+	keyOf := make(map[string]string) // display name → node key
+	var keys []string
+	for rows.Next() {
+		var tc TagCount
+		var key string
+		if err := rows.Scan(&tc.Tag, &key, &tc.Count); err != nil {
+			return nil, err
+		}
+		tc.Depth = strings.Count(key, "/")
+		byKey[key] = tc
+		keyOf[tc.Tag] = key
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if opts.LeafOnly {
+		keys = filterLeafTags(keys)
+	}
+	var tags []TagCount
+	for _, key := range keys {
+		if tc := byKey[key]; tc.Count >= opts.MinCount {
+			tags = append(tags, tc)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		if li, lj := strings.ToLower(tags[i].Tag), strings.ToLower(tags[j].Tag); li != lj {
+			return li < lj
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	if !opts.Tree {
+		return tags, nil
+	}
+
+	// Regroup depth-first. A parent counts at least as many notes as any
+	// child, so MinCount never drops a parent while keeping its children.
+	children := make(map[string][]TagCount)
+	var roots []TagCount
+	for _, tc := range tags {
+		key := keyOf[tc.Tag]
+		if i := strings.LastIndex(key, "/"); i >= 0 {
+			children[key[:i]] = append(children[key[:i]], tc)
+		} else {
+			roots = append(roots, tc)
+		}
+	}
+	var out []TagCount
+	var walk func(tc TagCount)
+	walk = func(tc TagCount) {
+		out = append(out, tc)
+		for _, c := range children[keyOf[tc.Tag]] {
+			walk(c)
+		}
+	}
+	for _, r := range roots {
+		walk(r)
+	}
+	return out, nil
+}
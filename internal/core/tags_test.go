@@ -0,0 +1,150 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// listTags runs ListTags over vault_build_tags and returns "tag=count" for each tag.
+func listTags(t *testing.T, opts TagsOptions) []string {
+	t.Helper()
+	vault := copyVault(t, "vault_build_tags")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	tags, err := ListTags(vault, opts)
+	if err != nil {
+		t.Fatalf("ListTags: %v", err)
+	}
+	var out []string
+	for _, tc := range tags {
+		out = append(out, fmt.Sprintf("%s=%d", tc.Tag, tc.Count))
+	}
+	return out
+}
+
+func TestListTags_Counts(t *testing.T) {
+	got := listTags(t, TagsOptions{})
+	// #simple is used by A.md and B.md; ties are ordered by name.
+	want := []string{
+		"#simple=2",
+		"#fm_tag=1", "#nested=1", "#nested/deep=1", "#nested/deep/tag=1", "#parent=1", "#parent/child=1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %v, want %v", got, want)
+	}
+}
+
+func TestListTags_MinCount(t *testing.T) {
+	got := listTags(t, TagsOptions{MinCount: 2})
+	if want := []string{"#simple=2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %v, want %v", got, want)
+	}
+}
+
+func TestListTags_LeafOnly(t *testing.T) {
+	got := listTags(t, TagsOptions{LeafOnly: true})
+	want := []string{"#simple=2", "#fm_tag=1", "#nested/deep/tag=1", "#parent/child=1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %v, want %v", got, want)
+	}
+}
+
+func TestListTags_Tree(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "#a/x #b\n",
+		"B.md": "#a/y #a/x\n",
+		"C.md": "#a/y\n",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	tags, err := ListTags(vault, TagsOptions{Tree: true})
+	if err != nil {
+		t.Fatalf("ListTags: %v", err)
+	}
+	var got []string
+	for _, tc := range tags {
+		got = append(got, fmt.Sprintf("%d %s=%d", tc.Depth, tc.Tag, tc.Count))
+	}
+	// Children follow their parent, tie on count broken by name.
+	want := []string{"0 #a=3", "1 #a/x=2", "1 #a/y=2", "0 #b=1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tags = %v, want %v", got, want)
+	}
+
+	if _, err := ListTags(vault, TagsOptions{Tree: true, LeafOnly: true}); err == nil {
+		t.Error("expected error for tree with leaf-only")
+	}
+}
+
+func TestTagCase(t *testing.T) {
+	files := map[string]string{
+		"A.md": "#Project first\n#project again\n#Project/Sub\n",
+		"B.md": "#project\n",
+	}
+	tests := []struct {
+		tagCase   string
+		wantTags  []string // ListTags as "tag=count"
+		wantNotes []string // QueryTags for #project
+		wantTree  []string // children of #project in QueryTagTree
+	}{
+		{"", []string{"#Project=2", "#Project/Sub=1"}, []string{"A.md", "B.md"}, []string{"#Project/Sub"}},
+		{"fold", []string{"#Project=2", "#Project/Sub=1"}, []string{"A.md", "B.md"}, []string{"#Project/Sub"}},
+		{"preserve", []string{"#project=2", "#Project=1", "#Project/Sub=1"}, []string{"A.md", "B.md"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run("tag_case="+tt.tagCase, func(t *testing.T) {
+			vault := t.TempDir()
+			writeVaultFiles(t, vault, files)
+			writeVaultFiles(t, vault, map[string]string{"mdhop.yaml": "build:\n  tag_case: " + tt.tagCase + "\n"})
+			buildVault(t, vault)
+
+			tags, err := ListTags(vault, TagsOptions{})
+			if err != nil {
+				t.Fatalf("ListTags: %v", err)
+			}
+			var got []string
+			for _, tc := range tags {
+				got = append(got, fmt.Sprintf("%s=%d", tc.Tag, tc.Count))
+			}
+			if !reflect.DeepEqual(got, tt.wantTags) {
+				t.Errorf("tags = %v, want %v", got, tt.wantTags)
+			}
+
+			res, err := QueryTags(vault, EntrySpec{Tags: []string{"#project"}}, QueryOptions{})
+			if err != nil {
+				t.Fatalf("QueryTags: %v", err)
+			}
+			var notes []string
+			for _, n := range res.Notes {
+				notes = append(notes, n.Path)
+			}
+			if !reflect.DeepEqual(notes, tt.wantNotes) {
+				t.Errorf("notes tagged #project = %v, want %v", notes, tt.wantNotes)
+			}
+
+			tree, err := QueryTagTree(vault, EntrySpec{Tag: "project"}, QueryOptions{})
+			if err != nil {
+				t.Fatalf("QueryTagTree: %v", err)
+			}
+			var kids []string
+			for _, c := range tree.Root.Children {
+				kids = append(kids, c.Tag)
+			}
+			if !reflect.DeepEqual(kids, tt.wantTree) {
+				t.Errorf("children of #project = %v, want %v", kids, tt.wantTree)
+			}
+		})
+	}
+}
+
+func TestTagCaseInvalid(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{"mdhop.yaml": "build:\n  tag_case: upper\n"})
+	if _, err := LoadConfig(vault); err == nil {
+		t.Error("expected error for invalid build.tag_case")
+	}
+}
@@ -5,12 +5,22 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 // UpdateOptions controls which files to re-parse and update in the index.
 type UpdateOptions struct {
-	Files []string // vault-relative paths
+	Files []string  // vault-relative paths; empty with Since set = all registered notes
+	Since time.Time // zero = no filter; otherwise skip disk-present files not modified after Since
+
+	// DetectMoves pairs a registered note missing from disk with an
+	// unregistered note of identical content and moves it in the index,
+	// rewriting links, instead of deleting it. Without Files or Since, only
+	// the missing notes are processed. The moves and the updates share one
+	// transaction, so if the update fails the moves are rolled back too.
+	DetectMoves bool
 }
 
 // UpdateResult reports the outcome for each processed file.
@@ -18,6 +28,9 @@ type UpdateResult struct {
 	Updated   []string // files whose content was re-parsed
 	Deleted   []string // files completely removed (disk-absent, no references)
 	Phantomed []string // files converted to phantom (disk-absent, has references)
+
+	Moved     []MovedFile     // disk-absent notes found at a new path (DetectMoves)
+	Rewritten []RewrittenLink // links rewritten for those moves
 }
 
 // Update re-parses the specified files and updates the existing index DB in-place.
@@ -29,23 +42,101 @@ func Update(vaultPath string, opts UpdateOptions) (*UpdateResult, error) {
 		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
 	}
 
-	db, err := openDBAt(dbp)
+	lock, err := lockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	return updateLocked(vaultPath, opts)
+}
+
+// updateLocked is Update for a caller that already holds the exclusive index
+// lock.
+func updateLocked(vaultPath string, opts UpdateOptions) (*UpdateResult, error) {
+	db, err := openDBAt(dbPath(vaultPath))
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
+	if err := ensureEdgeCol(db); err != nil {
+		return nil, err
+	}
+
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UpdateResult{}
+	if opts.DetectMoves {
+		moves, err := detectMoves(vaultPath, db, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if len(moves) > 0 {
+			// The updates run inside the move's transaction, so a failure
+			// rolls back the moves and their link rewrites as well.
+			_, err := moveFiles(vaultPath, db, cfg, moves, nil, false, cfg.Rewrite.PreserveMtime,
+				func(tx *sql.Tx, moved *MoveDirResult) error {
+					result.Moved = moved.Moved
+					result.Rewritten = moved.Rewritten
+					return updateFiles(vaultPath, tx, cfg, opts, result)
+				})
+			if err != nil {
+				return nil, err
+			}
+			return result, nil
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err := updateFiles(vaultPath, tx, cfg, opts, result); err != nil {
+		return nil, err
+	}
+	if err := bumpIndexVersion(tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	verbosef("update: committed %d updated, %d deleted, %d phantomed", len(result.Updated), len(result.Deleted), len(result.Phantomed))
+	return result, nil
+}
 
+// updateFiles re-parses the files selected by opts and applies the changes in
+// tx, adding to result. Notes in result.Moved are skipped. The caller bumps
+// the index version and commits.
+func updateFiles(vaultPath string, tx *sql.Tx, cfg Config, opts UpdateOptions, result *UpdateResult) error {
 	// Normalize and deduplicate input paths, collect node info for validation.
 	type fileInfo struct {
 		id   int64
 		name string
 		path string // normalized vault-relative path
 	}
+	var err error
+	inputs := opts.Files
+	if len(inputs) == 0 && !opts.Since.IsZero() {
+		inputs, err = listRegisteredNotes(tx)
+		if err != nil {
+			return err
+		}
+	} else if len(inputs) == 0 && opts.DetectMoves {
+		inputs, err = listMissingNotes(vaultPath, tx)
+		if err != nil {
+			return err
+		}
+	}
+
 	seen := make(map[string]bool)
 	var files []fileInfo
-	for _, f := range opts.Files {
+	for _, f := range inputs {
 		np := NormalizePath(f)
-		if seen[np] {
+		if seen[np] || movedFrom(result.Moved, np) {
 			continue
 		}
 		seen[np] = true
@@ -53,12 +144,12 @@ func Update(vaultPath string, opts UpdateOptions) (*UpdateResult, error) {
 		key := noteKey(np)
 		var id int64
 		var name, path string
-		err := db.QueryRow("SELECT id, name, path FROM nodes WHERE node_key = ? AND type = 'note'", key).Scan(&id, &name, &path)
+		err := tx.QueryRow("SELECT id, name, path FROM nodes WHERE node_key = ? AND type = 'note'", key).Scan(&id, &name, &path)
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("file not registered: %s", f)
+			return fmt.Errorf("file not registered: %s", f)
 		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 		files = append(files, fileInfo{id: id, name: name, path: np})
 	}
@@ -75,7 +166,10 @@ func Update(vaultPath string, opts UpdateOptions) (*UpdateResult, error) {
 		if os.IsNotExist(err) {
 			classified = append(classified, classifiedFile{fileInfo: fi, existsOnDisk: false})
 		} else if err != nil {
-			return nil, err
+			return err
+		} else if !opts.Since.IsZero() && !info.ModTime().After(opts.Since) {
+			// Unchanged since the given time: leave node and edges untouched.
+			continue
 		} else {
 			classified = append(classified, classifiedFile{
 				fileInfo:     fi,
@@ -86,10 +180,11 @@ func Update(vaultPath string, opts UpdateOptions) (*UpdateResult, error) {
 	}
 
 	// Build in-memory maps from DB (mirrors build's Pass 1).
-	rm, err := buildMapsFromDB(db)
+	rm, err := buildMapsFromDB(tx)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	rm.applyBuildConfig(cfg.Build)
 
 	// Adjust maps to reflect post-update vault state.
 	for _, cf := range classified {
@@ -109,6 +204,7 @@ func Update(vaultPath string, opts UpdateOptions) (*UpdateResult, error) {
 				if isRootFile(cf.path) {
 					delete(rm.rootBasenameToPath, bk)
 				}
+				setNoteAliases(rm, cf.path, nil)
 			}
 		} else {
 			// Ensure present in maps (normally already there for registered notes).
@@ -143,8 +239,13 @@ func Update(vaultPath string, opts UpdateOptions) (*UpdateResult, error) {
 
 	// Pre-mutation: read and validate disk-present files.
 	type parsedFile struct {
-		cf    classifiedFile
-		links []linkOccur
+		cf       classifiedFile
+		links    []linkOccur
+		headings []headingOccur
+		external []externalLinkOccur
+		aliases  []string
+		dropped  []string // aliases removed since the last index
+		body     string
 	}
 	var toUpdate []parsedFile
 	for _, cf := range classified {
@@ -153,66 +254,99 @@ func Update(vaultPath string, opts UpdateOptions) (*UpdateResult, error) {
 		}
 		content, err := os.ReadFile(filepath.Join(vaultPath, cf.path))
 		if err != nil {
-			return nil, err
+			return err
 		}
-		links := parseLinks(string(content))
+		links := parseIndexLinks(string(content), cfg.Build)
 
 		// Check for ambiguous links and vault escape (same logic as build's inline validation).
 		for _, link := range links {
-			if link.linkType != "wikilink" && link.linkType != "markdown" {
+			if !isFileLinkType(link.linkType) {
 				continue
 			}
 			if link.isRelative && escapesVault(cf.path, link.target) {
-				return nil, fmt.Errorf("link escapes vault: %s in %s", link.rawLink, cf.path)
+				return fmt.Errorf("link escapes vault: %s in %s", link.rawLink, cf.path)
 			}
 			if !link.isRelative && !link.isBasename && pathEscapesVault(link.target) {
-				return nil, fmt.Errorf("link escapes vault: %s in %s", link.rawLink, cf.path)
+				return fmt.Errorf("link escapes vault: %s in %s", link.rawLink, cf.path)
+			}
+			if link.isBasename && isAmbiguousBasenameLink(cf.path, link.target, rm) {
+				return fmt.Errorf("ambiguous link: %s in %s", link.target, cf.path)
 			}
-			if link.isBasename && isAmbiguousBasenameLink(link.target, rm) {
-				return nil, fmt.Errorf("ambiguous link: %s in %s", link.target, cf.path)
+			if isAmbiguousFolderNoteLink(cf.path, link, rm) {
+				return fmt.Errorf("ambiguous link: %s in %s (note and folder note)", link.target, cf.path)
 			}
 		}
 
-		toUpdate = append(toUpdate, parsedFile{cf: cf, links: links})
+		aliases := parseAliases(string(content))
+		toUpdate = append(toUpdate, parsedFile{
+			cf:       cf,
+			links:    links,
+			headings: parseHeadings(string(content)),
+			external: parseExternalLinks(string(content)),
+			aliases:  aliases,
+			dropped:  droppedAliases(rm.noteAliases[cf.path], aliases),
+			body:     string(content),
+		})
+	}
+	// Aliases take effect for every updated file's links, as in build.
+	for _, pf := range toUpdate {
+		setNoteAliases(rm, pf.cf.path, pf.aliases)
 	}
 
-	// Begin transaction.
-	tx, err := db.Begin()
+	nt, err := newNoteText(tx)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer tx.Rollback()
-
-	result := &UpdateResult{}
 
 	// Phase A: update disk-present files.
 	for _, pf := range toUpdate {
 		// Delete all outgoing edges.
 		if _, err := tx.Exec("DELETE FROM edges WHERE source_id = ?", pf.cf.id); err != nil {
-			return nil, err
+			return err
 		}
 
 		// Update mtime and exists_flag.
 		if _, err := tx.Exec("UPDATE nodes SET exists_flag=1, mtime=? WHERE id=?", pf.cf.diskMtime, pf.cf.id); err != nil {
-			return nil, err
+			return err
+		}
+
+		if err := replaceHeadings(tx, pf.cf.id, pf.headings); err != nil {
+			return err
+		}
+		if err := replaceExternalLinks(tx, pf.cf.id, pf.external); err != nil {
+			return err
+		}
+		if err := demoteAliasEdges(tx, pf.cf.id, pf.dropped); err != nil {
+			return err
+		}
+		if err := replaceAliases(tx, pf.cf.id, pf.aliases); err != nil {
+			return err
+		}
+		if err := nt.replace(tx, pf.cf.id, pf.body); err != nil {
+			return err
 		}
 
 		// Re-resolve links and create new edges.
 		for _, link := range pf.links {
 			targetID, subpath, err := resolveLink(tx, pf.cf.path, link, rm)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			if targetID == 0 {
 				continue
 			}
-			if err := insertEdge(tx, pf.cf.id, targetID, link.linkType, link.rawLink, subpath, link.lineStart, link.lineEnd); err != nil {
-				return nil, err
+			if err := insertEdge(tx, pf.cf.id, targetID, link, subpath); err != nil {
+				return err
 			}
 		}
 
 		result.Updated = append(result.Updated, pf.cf.path)
 	}
+	for _, pf := range toUpdate {
+		if _, err := promoteAliasPhantoms(tx, rm, pf.cf.path); err != nil {
+			return err
+		}
+	}
 
 	// Phase B: handle disk-absent files (same logic as delete).
 	for _, cf := range classified {
@@ -222,7 +356,7 @@ func Update(vaultPath string, opts UpdateOptions) (*UpdateResult, error) {
 
 		phantomized, err := removeOrPhantomize(tx, cf.id, cf.name)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if phantomized {
 			result.Phantomed = append(result.Phantomed, cf.path)
@@ -233,19 +367,17 @@ func Update(vaultPath string, opts UpdateOptions) (*UpdateResult, error) {
 
 	// Orphan cleanup: remove tags/phantoms not referenced by any edge.
 	if err := cleanupOrphanedNodes(tx); err != nil {
-		return nil, err
-	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, err
+		return err
 	}
-
-	return result, nil
+	return nil
 }
 
 // buildMapsFromDB constructs in-memory resolveMaps from existing DB nodes,
 // mirroring build's Pass 1 structure.
 func buildMapsFromDB(db dbExecer) (*resolveMaps, error) {
+	if err := checkFullIndex(db); err != nil {
+		return nil, err
+	}
 	rm := &resolveMaps{
 		pathToID:                make(map[string]int64),
 		pathSet:                 make(map[string]string),
@@ -331,5 +463,161 @@ func buildMapsFromDB(db dbExecer) (*resolveMaps, error) {
 		}
 	}
 
+	// Load aliases of existing notes.
+	rm.noteAliases = make(map[string][]string)
+	alrows, err := db.Query(
+		`SELECT n.path, a.alias FROM aliases a
+		 JOIN nodes n ON n.id = a.node_id AND n.type = 'note' AND n.exists_flag = 1
+		 ORDER BY a.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer alrows.Close()
+	for alrows.Next() {
+		var path, alias string
+		if err := alrows.Scan(&path, &alias); err != nil {
+			return nil, err
+		}
+		rm.noteAliases[path] = append(rm.noteAliases[path], alias)
+	}
+	if err := alrows.Err(); err != nil {
+		return nil, err
+	}
+	rm.aliasToPath = aliasTargets(rm.noteAliases)
+
 	return rm, nil
 }
+
+// listRegisteredNotes returns the paths of all note nodes in the index.
+func listRegisteredNotes(db dbExecer) ([]string, error) {
+	rows, err := db.Query(`SELECT path FROM nodes WHERE type='note' ORDER BY path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// detectMoves pairs registered notes missing from disk with unregistered notes
+// on disk by content hash. A hash shared by more than one missing note or more
+// than one new file is ambiguous, so those notes are left to the normal
+// delete handling and the new files stay unregistered.
+func detectMoves(vaultPath string, db *sql.DB, cfg Config) ([]batchMove, error) {
+	hashed, err := hasContentHash(db)
+	if err != nil {
+		return nil, err
+	}
+	if !hashed {
+		return nil, fmt.Errorf("index has no content hashes: run 'mdhop build' first")
+	}
+
+	rows, err := db.Query(`SELECT id, path, content_hash FROM nodes
+		WHERE type = 'note' AND exists_flag = 1 AND content_hash IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	registered := make(map[string]bool)
+	missing := make(map[string][]batchMove) // hash → disk-absent notes
+	for rows.Next() {
+		var m batchMove
+		var hash string
+		if err := rows.Scan(&m.nodeID, &m.from, &hash); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		registered[m.from] = true
+		if !fileExists(filepath.Join(vaultPath, m.from)) {
+			missing[hash] = append(missing[hash], m)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	// Scan the same files build would index.
+	bc, err := indexedBuildConfig(db, cfg.Build)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := collectMarkdownFiles(vaultPath, bc)
+	if err != nil {
+		return nil, err
+	}
+	paths = filterBuildExcludes(paths, bc.ExcludePaths)
+	appeared := make(map[string][]string) // hash → unregistered files
+	for _, p := range paths {
+		if registered[p] {
+			continue
+		}
+		var id int64
+		err := db.QueryRow("SELECT id FROM nodes WHERE node_key = ?", noteKey(p)).Scan(&id)
+		if err == nil {
+			continue // registered but disk state recorded as absent
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+		content, err := os.ReadFile(filepath.Join(vaultPath, p))
+		if err != nil {
+			return nil, err
+		}
+		h := contentHash(string(content))
+		if len(missing[h]) > 0 {
+			appeared[h] = append(appeared[h], p)
+		}
+	}
+
+	var moves []batchMove
+	for h, from := range missing {
+		to := appeared[h]
+		if len(from) != 1 || len(to) != 1 {
+			continue
+		}
+		m := from[0]
+		m.to = to[0]
+		// The matching hash vouches for the content; a move by copy and
+		// delete gives a new mtime, so skip the stale check.
+		m.force = true
+		verbosef("update: detected move %s -> %s", m.from, m.to)
+		moves = append(moves, m)
+	}
+	sort.Slice(moves, func(i, j int) bool { return moves[i].from < moves[j].from })
+	return moves, nil
+}
+
+// listMissingNotes returns the paths of registered notes absent from disk.
+func listMissingNotes(vaultPath string, db dbExecer) ([]string, error) {
+	paths, err := listRegisteredNotes(db)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, p := range paths {
+		if !fileExists(filepath.Join(vaultPath, p)) {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
+func movedFrom(moved []MovedFile, path string) bool {
+	for _, m := range moved {
+		if m.From == path {
+			return true
+		}
+	}
+	return false
+}
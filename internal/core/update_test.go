@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestUpdateUnregisteredFile(t *testing.T) {
@@ -732,3 +733,244 @@ func TestUpdateRootDeletedResolvesToSubdir(t *testing.T) {
 		t.Error("B→A edge should exist pointing to sub/A.md")
 	}
 }
+
+func TestUpdateSinceSkipsOlderFiles(t *testing.T) {
+	vault := copyVault(t, "vault_update")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+
+	// B.md changes content but keeps an mtime older than since.
+	bPath := filepath.Join(vault, "B.md")
+	if err := os.WriteFile(bPath, []byte("[[C]]\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	old := since.Add(-time.Hour)
+	if err := os.Chtimes(bPath, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	// A.md changes content with a fresh mtime.
+	if err := os.WriteFile(filepath.Join(vault, "A.md"), []byte("[[C]]\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	result, err := Update(vault, UpdateOptions{Files: []string{"A.md", "B.md"}, Since: since})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if len(result.Updated) != 1 || result.Updated[0] != "A.md" {
+		t.Errorf("Updated = %v, want [A.md]", result.Updated)
+	}
+
+	// B.md edges remain untouched (still [[A]] and #shared).
+	edges := queryEdges(t, dbPath(vault), "B.md")
+	if len(edges) != 2 || edges[0].rawLink != "[[A]]" || edges[1].rawLink != "#shared" {
+		t.Errorf("B.md edges changed: %+v", edges)
+	}
+}
+
+func TestUpdateSinceWholeVault(t *testing.T) {
+	vault := copyVault(t, "vault_update")
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	old := since.Add(-time.Hour)
+	for _, name := range []string{"A.md", "B.md", "C.md"} {
+		p := filepath.Join(vault, name)
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(vault, "C.md"), []byte("[[A]]\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	result, err := Update(vault, UpdateOptions{Since: since})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if len(result.Updated) != 1 || result.Updated[0] != "C.md" {
+		t.Errorf("Updated = %v, want [C.md]", result.Updated)
+	}
+	edges := queryEdges(t, dbPath(vault), "C.md")
+	if len(edges) != 1 || edges[0].rawLink != "[[A]]" {
+		t.Errorf("C.md edges = %+v, want [[A]]", edges)
+	}
+}
+
+func TestUpdateDetectMovesRewritesBacklinks(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":       "[[notes/B]] and [B](notes/B.md)\n",
+		"notes/B.md": "# B\n",
+		"C.md":       "# C\n",
+	})
+	buildVault(t, vault)
+
+	if err := os.MkdirAll(filepath.Join(vault, "archive"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(vault, "notes", "B.md"), filepath.Join(vault, "archive", "B.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Update(vault, UpdateOptions{DetectMoves: true})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if len(result.Moved) != 1 || result.Moved[0] != (MovedFile{From: "notes/B.md", To: "archive/B.md"}) {
+		t.Errorf("moved = %+v, want notes/B.md -> archive/B.md", result.Moved)
+	}
+	if len(result.Deleted) != 0 || len(result.Phantomed) != 0 {
+		t.Errorf("deleted = %v, phantomed = %v, want none", result.Deleted, result.Phantomed)
+	}
+
+	data, err := os.ReadFile(filepath.Join(vault, "A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "[[archive/B]] and [B](archive/B.md)\n"; got != want {
+		t.Errorf("A.md = %q, want %q", got, want)
+	}
+	for _, e := range queryEdges(t, dbPath(vault), "A.md") {
+		if e.targetKey != noteKey("archive/B.md") {
+			t.Errorf("edge %s → %s, want archive/B.md", e.rawLink, e.targetKey)
+		}
+	}
+}
+
+func TestUpdateDetectMovesHashCollision(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md": "[[X]] [[Y]]\n",
+		"X.md": "same\n",
+		"Y.md": "same\n",
+	})
+	buildVault(t, vault)
+
+	for _, p := range []string{"X.md", "Y.md"} {
+		if err := os.Remove(filepath.Join(vault, p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeVaultFiles(t, vault, map[string]string{"Z.md": "same\n"})
+
+	result, err := Update(vault, UpdateOptions{DetectMoves: true})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if len(result.Moved) != 0 {
+		t.Errorf("moved = %+v, want none for an ambiguous hash", result.Moved)
+	}
+	if len(result.Phantomed) != 2 {
+		t.Errorf("phantomed = %v, want X.md and Y.md", result.Phantomed)
+	}
+	data, err := os.ReadFile(filepath.Join(vault, "A.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "[[X]] [[Y]]\n" {
+		t.Errorf("A.md rewritten: %q", data)
+	}
+}
+
+func TestUpdateDetectMovesOrder(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"a.md": "# a\n",
+		"b.md": "# b\n",
+		"c.md": "# c\n",
+	})
+	buildVault(t, vault)
+	if err := os.MkdirAll(filepath.Join(vault, "moved"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"c.md", "a.md", "b.md"} {
+		if err := os.Rename(filepath.Join(vault, p), filepath.Join(vault, "moved", p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := Update(vault, UpdateOptions{DetectMoves: true})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	var got []string
+	for _, m := range result.Moved {
+		got = append(got, m.From)
+	}
+	if strings.Join(got, ",") != "a.md,b.md,c.md" {
+		t.Errorf("moved from = %v, want [a.md b.md c.md]", got)
+	}
+}
+
+func TestUpdateDetectMovesRolledBackOnUpdateFailure(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"notes/B.md": "# B\n",
+		"L.md":       "[[notes/B]]\n",
+		"x/N.md":     "# N\n",
+		"y/N.md":     "# N\n",
+		"C.md":       "# C\n",
+	})
+	buildVault(t, vault)
+	if err := os.MkdirAll(filepath.Join(vault, "archive"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(vault, "notes", "B.md"), filepath.Join(vault, "archive", "B.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeVaultFiles(t, vault, map[string]string{"C.md": "[[N]]\n"})
+
+	_, err := Update(vault, UpdateOptions{Files: []string{"C.md"}, DetectMoves: true})
+	if err == nil || !strings.Contains(err.Error(), "ambiguous link") {
+		t.Fatalf("error = %v, want ambiguous link", err)
+	}
+	db := openTestDB(t, dbPath(vault))
+	defer db.Close()
+	for key, want := range map[string]int{noteKey("archive/B.md"): 0, noteKey("notes/B.md"): 1} {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM nodes WHERE node_key = ?", key).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Errorf("%s: %d nodes, want %d (detected move not rolled back)", key, n, want)
+		}
+	}
+	got, err := os.ReadFile(filepath.Join(vault, "L.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "[[notes/B]]\n" {
+		t.Errorf("L.md = %q, want the link rewrite restored", got)
+	}
+}
+
+func TestUpdateDetectMovesSkipsBuildExcludes(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"notes/B.md": "# B\n",
+	})
+	if err := BuildWithOptions(vault, BuildOptions{ExcludePaths: []string{"drafts/**"}}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	// The only copy of B's content lies under an excluded path, so it is
+	// not a move target and B is deleted instead.
+	if err := os.MkdirAll(filepath.Join(vault, "drafts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(vault, "notes", "B.md"), filepath.Join(vault, "drafts", "B.md")); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Update(vault, UpdateOptions{DetectMoves: true})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if len(result.Moved) != 0 || len(result.Deleted) != 1 || result.Deleted[0] != "notes/B.md" {
+		t.Errorf("result = %+v, want notes/B.md deleted and no moves", result)
+	}
+}
@@ -38,26 +38,84 @@ func hasRootInPathSet(bk string, pathSet map[string]string) bool {
 	return ok && isRootFile(p)
 }
 
-// isAmbiguousBasenameLink checks if a basename link is ambiguous.
+// localNoteKey returns the lowercase path of the note a lowercase basename
+// names in sourcePath's folder: ("sub/A.md", "b") → "sub/b.md".
+func localNoteKey(sourcePath, lower string) string {
+	dir := filepath.ToSlash(filepath.Dir(sourcePath))
+	if dir == "." {
+		return lower + ".md"
+	}
+	return strings.ToLower(dir) + "/" + lower + ".md"
+}
+
+// isAmbiguousBasenameLink checks if a basename link in sourcePath is ambiguous.
 // Returns true if the basename has multiple files AND there is no root-level file.
-// When a root-level file exists, the basename link resolves to it (root-priority rule).
+// When a root-level file exists, the basename link resolves to it (root-priority
+// rule) unless build.root_priority is off. Under folder-first resolution a note
+// of that name in sourcePath's folder resolves the link first.
 // Checks note basenames first, then asset basenames (separate key spaces).
-func isAmbiguousBasenameLink(target string, rm *resolveMaps) bool {
+func isAmbiguousBasenameLink(sourcePath, target string, rm *resolveMaps) bool {
 	lower := strings.ToLower(target)
+	if prefersAsset(target) && rm.assetBasenameCounts[lower] > 0 {
+		return rm.assetBasenameCounts[lower] > 1 && !rm.rootWins(lower, rm.assetPathSet)
+	}
+	if _, ok := rm.localNotePath(sourcePath, lower); ok {
+		return false
+	}
 	// Check note namespace.
 	if rm.basenameCounts[lower] > 1 {
-		return !hasRootInPathSet(lower, rm.pathSet)
+		return !rm.rootWins(lower, rm.pathSet)
 	}
 	if rm.basenameCounts[lower] == 1 {
 		return false // unique note match
 	}
 	// Check asset namespace.
 	if rm.assetBasenameCounts[lower] > 1 {
-		return !hasRootInPathSet(lower, rm.assetPathSet)
+		return !rm.rootWins(lower, rm.assetPathSet)
 	}
 	return false
 }
 
+// prefersAsset reports whether a basename link target carries an extension
+// other than .md ([[report.pdf]]). Such a link names a file, so an asset with
+// that filename wins over a note whose basename happens to match.
+func prefersAsset(target string) bool {
+	ext := strings.ToLower(filepath.Ext(target))
+	return ext != "" && ext != ".md"
+}
+
+// folderNotePath returns the folder note path for a directory path:
+// "Projects" → "Projects/Projects.md".
+func folderNotePath(dir string) string {
+	dir = strings.TrimSuffix(dir, "/")
+	return dir + "/" + filepath.Base(dir) + ".md"
+}
+
+// isAmbiguousFolderNoteLink reports whether, with folder notes enabled, a link
+// matches both a note and the folder note of the same name: [[Projects]] with
+// root Projects.md and Projects/Projects.md, or [[Area/Projects]] with
+// Area/Projects.md and Area/Projects/Projects.md. Trailing-slash links name
+// the folder explicitly and are never ambiguous.
+func isAmbiguousFolderNoteLink(sourcePath string, link linkOccur, rm *resolveMaps) bool {
+	if !rm.folderNotes || strings.HasSuffix(link.target, "/") {
+		return false
+	}
+	var lower string
+	if link.isBasename {
+		lower = strings.ToLower(link.target)
+		if !hasRootInPathSet(lower, rm.pathSet) {
+			return false
+		}
+	} else {
+		lower = strings.ToLower(resolveToVaultRelative(sourcePath, link))
+		if _, ok := rm.pathSet[lower]; !ok {
+			return false
+		}
+	}
+	_, ok := rm.pathSet[folderNotePath(lower)]
+	return ok
+}
+
 // CleanupEmptyDirs removes empty directories left after file deletion.
 // It walks from each path's parent directory upward, removing empty directories
 // until it reaches vaultPath or encounters a non-empty directory.
@@ -88,6 +146,60 @@ func CleanupEmptyDirs(vaultPath string, paths []string) error {
 	return nil
 }
 
+// pruneEmptyDirs removes the vault-relative directory dir and its
+// subdirectories when they hold no files other than hidden ones such as
+// .DS_Store, then removes ancestors that were left empty the same way. The
+// vault root is never removed. Removal is best-effort: a directory that
+// cannot be removed is left in place.
+func pruneEmptyDirs(vaultPath, dir string) {
+	abs := filepath.Join(vaultPath, dir)
+	if !pruneDir(vaultPath, abs, true) {
+		return
+	}
+	parent := filepath.Dir(abs)
+	for pruneDir(vaultPath, parent, false) {
+		parent = filepath.Dir(parent)
+	}
+}
+
+// pruneDir removes abs if it contains only hidden files (and, with recurse,
+// subdirectories that prune away). Hidden subdirectories such as .obsidian
+// keep abs in place. Reports whether abs was removed.
+func pruneDir(vaultPath, abs string, recurse bool) bool {
+	rel, err := filepath.Rel(vaultPath, abs)
+	if err != nil || rel == "." || strings.HasPrefix(filepath.ToSlash(rel), "..") {
+		return false
+	}
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return false
+	}
+	var hidden []string
+	empty := true
+	for _, e := range entries {
+		p := filepath.Join(abs, e.Name())
+		switch {
+		case e.IsDir():
+			if !recurse || strings.HasPrefix(e.Name(), ".") || !pruneDir(vaultPath, p, true) {
+				empty = false
+			}
+		case strings.HasPrefix(e.Name(), "."):
+			hidden = append(hidden, p)
+		default:
+			empty = false
+		}
+	}
+	if !empty {
+		return false
+	}
+	for _, h := range hidden {
+		if os.Remove(h) != nil {
+			return false
+		}
+	}
+	return os.Remove(abs) == nil
+}
+
 // HasNonMDFiles checks whether the given directory (vault-relative) contains
 // any non-.md files on disk. Hidden files/directories (starting with ".") are
 // ignored. Returns the first non-.md path found (vault-relative), or "" if none.
@@ -144,6 +256,17 @@ type noteResolveMaps struct {
 	basenameToPath     map[string]string // lower basename → path (count==1 only)
 	rootBasenameToPath map[string]string // lower basename → root path
 	pathSetLower       map[string]string // lower path → actual path
+	folderFirst        bool              // build.link_resolution: folder-first
+}
+
+// localPath returns the note a lowercase basename names in the folder of
+// sourcePath under folder-first resolution.
+func (nm noteResolveMaps) localPath(sourcePath, lower string) (string, bool) {
+	if !nm.folderFirst {
+		return "", false
+	}
+	p, ok := nm.pathSetLower[localNoteKey(sourcePath, lower)]
+	return p, ok
 }
 
 // buildNoteResolveMaps builds note resolve maps from a list of vault-relative .md file paths.
@@ -220,3 +343,14 @@ func isFieldActive(field string, fields []string) bool {
 	}
 	return false
 }
+
+// isFieldExplicit returns true only if the field is listed in fields. Used for
+// opt-in fields that are not part of the default (empty) field set.
+func isFieldExplicit(field string, fields []string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -83,7 +83,7 @@ func TestIsAmbiguousBasenameLink(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := isAmbiguousBasenameLink(tt.target, tt.rm)
+			got := isAmbiguousBasenameLink("A.md", tt.target, tt.rm)
 			if got != tt.want {
 				t.Errorf("isAmbiguousBasenameLink(%q) = %v, want %v", tt.target, got, tt.want)
 			}
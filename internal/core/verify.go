@@ -0,0 +1,318 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// VerifyIssue describes a single discrepancy between the index and the vault.
+type VerifyIssue struct {
+	Kind   string // "missing_file", "mtime_mismatch", "dangling_edge", "basename_count_mismatch", "duplicate_node_key"
+	Path   string // vault-relative path, basename, or node_key depending on Kind
+	Detail string
+}
+
+// VerifyResult reports the outcome of an index integrity check.
+type VerifyResult struct {
+	Issues []VerifyIssue // sorted by kind, then path
+}
+
+// OK reports whether no discrepancies were found.
+func (r *VerifyResult) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Verify checks that the index matches the vault on disk without modifying
+// either. It complements Diagnose, which reports link semantics rather than
+// index integrity.
+func Verify(vaultPath string) (*VerifyResult, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := checkFullIndex(db); err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{}
+
+	// Note and asset nodes: file exists with the indexed mtime.
+	rows, err := db.Query(`SELECT type, path, mtime FROM nodes WHERE type IN ('note','asset') AND exists_flag=1 ORDER BY path`)
+	if err != nil {
+		return nil, err
+	}
+	dbBasenameCounts := make(map[string]int)
+	dbAssetBasenameCounts := make(map[string]int)
+	for rows.Next() {
+		var typ, path string
+		var mtime sql.NullInt64
+		if err := rows.Scan(&typ, &path, &mtime); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if typ == "note" {
+			dbBasenameCounts[basenameKey(path)]++
+		} else {
+			dbAssetBasenameCounts[assetBasenameKey(path)]++
+		}
+		info, err := os.Stat(filepath.Join(vaultPath, path))
+		if os.IsNotExist(err) {
+			result.Issues = append(result.Issues, VerifyIssue{Kind: "missing_file", Path: path, Detail: "indexed but not on disk"})
+			continue
+		}
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if info.ModTime().Unix() != mtime.Int64 {
+			result.Issues = append(result.Issues, VerifyIssue{
+				Kind:   "mtime_mismatch",
+				Path:   path,
+				Detail: fmt.Sprintf("index %d, disk %d", mtime.Int64, info.ModTime().Unix()),
+			})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Edges whose source or target node no longer exists.
+	rows, err = db.Query(
+		`SELECT e.id, e.source_id, e.target_id,
+		        CASE WHEN sn.id IS NULL THEN 0 ELSE 1 END,
+		        CASE WHEN tn.id IS NULL THEN 0 ELSE 1 END
+		 FROM edges e
+		 LEFT JOIN nodes sn ON sn.id = e.source_id
+		 LEFT JOIN nodes tn ON tn.id = e.target_id
+		 WHERE sn.id IS NULL OR tn.id IS NULL
+		 ORDER BY e.id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var edgeID, sourceID, targetID int64
+		var hasSource, hasTarget int
+		if err := rows.Scan(&edgeID, &sourceID, &targetID, &hasSource, &hasTarget); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		detail := fmt.Sprintf("edge %d: missing", edgeID)
+		if hasSource == 0 {
+			detail += fmt.Sprintf(" source %d", sourceID)
+		}
+		if hasTarget == 0 {
+			detail += fmt.Sprintf(" target %d", targetID)
+		}
+		result.Issues = append(result.Issues, VerifyIssue{Kind: "dangling_edge", Path: fmt.Sprintf("edge:%d", edgeID), Detail: detail})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Duplicate node keys (guarded by the schema, but manual edits can break it).
+	rows, err = db.Query(`SELECT node_key, COUNT(*) FROM nodes GROUP BY node_key HAVING COUNT(*) > 1 ORDER BY node_key`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var key string
+		var n int
+		if err := rows.Scan(&key, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		result.Issues = append(result.Issues, VerifyIssue{Kind: "duplicate_node_key", Path: key, Detail: fmt.Sprintf("%d nodes", n)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Note and asset basename counts against a fresh scan with the walk
+	// options of the build that created the index.
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	bc, err := indexedBuildConfig(db, cfg.Build)
+	if err != nil {
+		return nil, err
+	}
+	files, err := collectMarkdownFiles(vaultPath, bc)
+	if err != nil {
+		return nil, err
+	}
+	files = filterBuildExcludes(files, bc.ExcludePaths)
+	assets, err := collectAssetFiles(vaultPath, bc)
+	if err != nil {
+		return nil, err
+	}
+	assets = filterBuildExcludes(assets, bc.ExcludePaths)
+	result.Issues = append(result.Issues, basenameCountIssues(dbBasenameCounts, countBasenames(files))...)
+	result.Issues = append(result.Issues, basenameCountIssues(dbAssetBasenameCounts, countAssetBasenames(assets))...)
+
+	sort.SliceStable(result.Issues, func(i, j int) bool {
+		if result.Issues[i].Kind != result.Issues[j].Kind {
+			return result.Issues[i].Kind < result.Issues[j].Kind
+		}
+		return result.Issues[i].Path < result.Issues[j].Path
+	})
+	return result, nil
+}
+
+// basenameCountIssues reports every basename whose count in the index differs
+// from its count on disk.
+func basenameCountIssues(dbCounts, diskCounts map[string]int) []VerifyIssue {
+	keys := make(map[string]bool)
+	for k := range dbCounts {
+		keys[k] = true
+	}
+	for k := range diskCounts {
+		keys[k] = true
+	}
+	var issues []VerifyIssue
+	for k := range keys {
+		if dbCounts[k] != diskCounts[k] {
+			issues = append(issues, VerifyIssue{
+				Kind:   "basename_count_mismatch",
+				Path:   k,
+				Detail: fmt.Sprintf("index %d, disk %d", dbCounts[k], diskCounts[k]),
+			})
+		}
+	}
+	return issues
+}
+
+// FastVerifyResult compares the note and asset aggregates recorded in the
+// index with the same aggregates taken from the vault on disk.
+type FastVerifyResult struct {
+	Recorded           bool  // false for an index built before the aggregates existed
+	IndexNotes         int64 // notes in the index
+	IndexMaxMtime      int64 // newest note mtime in the index (Unix seconds)
+	IndexDigest        int64 // hash of the indexed note paths and mtimes
+	IndexAssets        int64 // assets in the index
+	IndexAssetMaxMtime int64 // newest asset mtime in the index (Unix seconds)
+	IndexAssetDigest   int64 // hash of the indexed asset paths and mtimes
+	DiskNotes          int64
+	DiskMaxMtime       int64
+	DiskDigest         int64
+	DiskAssets         int64
+	DiskAssetMaxMtime  int64
+	DiskAssetDigest    int64
+}
+
+// UpToDate reports whether the recorded aggregates match the disk.
+func (r *FastVerifyResult) UpToDate() bool {
+	return r.Recorded && r.IndexNotes == r.DiskNotes && r.IndexMaxMtime == r.DiskMaxMtime &&
+		r.IndexDigest == r.DiskDigest && r.IndexAssets == r.DiskAssets &&
+		r.IndexAssetMaxMtime == r.DiskAssetMaxMtime && r.IndexAssetDigest == r.DiskAssetDigest
+}
+
+// VerifyFast is a cheap staleness check: for notes and for assets it compares
+// the count, the newest mtime and a digest of the paths and mtimes, stored in
+// meta on every mutation, against a directory scan that stats files without
+// parsing them. Editing, adding, removing or renaming a file changes the
+// digest. The scan applies the walk options saved by the build that created
+// the index, so one-off build options do not make it stale.
+func VerifyFast(vaultPath string) (*FastVerifyResult, error) {
+	dbp := dbPath(vaultPath)
+	if _, err := os.Stat(dbp); os.IsNotExist(err) {
+		return nil, fmt.Errorf("index not found: run 'mdhop build' first")
+	}
+
+	lock, err := rlockIndex(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+
+	db, err := openDBAt(dbp)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := checkFullIndex(db); err != nil {
+		return nil, err
+	}
+
+	result := &FastVerifyResult{Recorded: true}
+	for _, m := range []struct {
+		key string
+		dst *int64
+	}{
+		{"note_count", &result.IndexNotes},
+		{"note_max_mtime", &result.IndexMaxMtime},
+		{"note_digest", &result.IndexDigest},
+		{"asset_count", &result.IndexAssets},
+		{"asset_max_mtime", &result.IndexAssetMaxMtime},
+		{"asset_digest", &result.IndexAssetDigest},
+	} {
+		value, ok, err := metaValue(db, m.key)
+		if err != nil {
+			return nil, err
+		}
+		*m.dst = value
+		result.Recorded = result.Recorded && ok
+	}
+
+	cfg, err := LoadConfig(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	bc, err := indexedBuildConfig(db, cfg.Build)
+	if err != nil {
+		return nil, err
+	}
+	files, err := collectMarkdownFiles(vaultPath, bc)
+	if err != nil {
+		return nil, err
+	}
+	files = filterBuildExcludes(files, bc.ExcludePaths)
+	result.DiskNotes, result.DiskMaxMtime, result.DiskDigest, err = diskAggregate(vaultPath, files)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := collectAssetFiles(vaultPath, bc)
+	if err != nil {
+		return nil, err
+	}
+	assets = filterBuildExcludes(assets, bc.ExcludePaths)
+	result.DiskAssets, result.DiskAssetMaxMtime, result.DiskAssetDigest, err = diskAggregate(vaultPath, assets)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// diskAggregate stats files and returns their count, newest mtime and digest,
+// matching what bumpIndexVersion records for the index.
+func diskAggregate(vaultPath string, files []string) (count, maxMtime, digest int64, err error) {
+	stamps := make([]noteStamp, 0, len(files))
+	for _, f := range files {
+		info, err := os.Stat(filepath.Join(vaultPath, f))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		mtime := info.ModTime().Unix()
+		maxMtime = max(maxMtime, mtime)
+		stamps = append(stamps, noteStamp{path: f, mtime: mtime})
+	}
+	return int64(len(files)), maxMtime, notesDigest(stamps), nil
+}
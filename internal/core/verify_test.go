@@ -0,0 +1,288 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func verifyKinds(r *VerifyResult) []string {
+	var kinds []string
+	for _, is := range r.Issues {
+		kinds = append(kinds, is.Kind+":"+is.Path)
+	}
+	return kinds
+}
+
+func TestVerifyClean(t *testing.T) {
+	vault := setupFullVault(t)
+	r, err := Verify(vault)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !r.OK() {
+		t.Errorf("issues = %v, want none", verifyKinds(r))
+	}
+}
+
+func TestVerifyNoDB(t *testing.T) {
+	_, err := Verify(t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "index not found") {
+		t.Fatalf("expected index not found error, got %v", err)
+	}
+}
+
+func TestVerifyMtimeMismatch(t *testing.T) {
+	vault := setupFullVault(t)
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(vault, "Design.md"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Verify(vault)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	got := verifyKinds(r)
+	if len(got) != 1 || got[0] != "mtime_mismatch:Design.md" {
+		t.Errorf("issues = %v, want [mtime_mismatch:Design.md]", got)
+	}
+}
+
+func TestVerifyMissingAndUnindexedFiles(t *testing.T) {
+	vault := setupFullVault(t)
+	if err := os.Remove(filepath.Join(vault, "Design.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vault, "New.md"), []byte("# New\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Verify(vault)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	got := strings.Join(verifyKinds(r), ",")
+	want := "basename_count_mismatch:design,basename_count_mismatch:new,missing_file:Design.md"
+	if got != want {
+		t.Errorf("issues = %s, want %s", got, want)
+	}
+}
+
+func TestVerifyAssetBasenameCounts(t *testing.T) {
+	vault := setupFullVault(t)
+	if err := os.WriteFile(filepath.Join(vault, "unused.pdf"), []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Verify(vault)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	got := verifyKinds(r)
+	if len(got) != 1 || got[0] != "basename_count_mismatch:unused.pdf" {
+		t.Errorf("issues = %v, want [basename_count_mismatch:unused.pdf]", got)
+	}
+}
+
+func TestVerifyBuildOptions(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":         "# A\n",
+		"drafts/D.md":  "# D\n",
+		"drafts/d.png": "png",
+	})
+	if err := BuildWithOptions(vault, BuildOptions{ExcludePaths: []string{"drafts/**"}}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	r, err := Verify(vault)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !r.OK() {
+		t.Errorf("issues = %v, want none with the build's exclusions applied", verifyKinds(r))
+	}
+}
+
+func TestVerifyDanglingEdge(t *testing.T) {
+	vault := setupFullVault(t)
+	db := openTestDB(t, dbPath(vault))
+	if _, err := db.Exec("DELETE FROM nodes WHERE node_key = 'phantom:name:missing'"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	r, err := Verify(vault)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if len(r.Issues) != 1 || r.Issues[0].Kind != "dangling_edge" {
+		t.Fatalf("issues = %v, want one dangling_edge", verifyKinds(r))
+	}
+	if !strings.Contains(r.Issues[0].Detail, "target") {
+		t.Errorf("detail = %q, want missing target", r.Issues[0].Detail)
+	}
+}
+
+func TestVerifyReadOnly(t *testing.T) {
+	vault := setupFullVault(t)
+	before, err := os.Stat(dbPath(vault))
+	if err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(vault, "Design.md"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(vault); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	// Running again reports the same discrepancy: nothing was repaired.
+	r, err := Verify(vault)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if r.OK() {
+		t.Error("expected mtime mismatch to persist")
+	}
+	after, err := os.Stat(dbPath(vault))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() != before.Size() {
+		t.Errorf("db size changed: %d → %d", before.Size(), after.Size())
+	}
+}
+
+func TestVerifyFast(t *testing.T) {
+	vault := setupFullVault(t)
+	r, err := VerifyFast(vault)
+	if err != nil {
+		t.Fatalf("verify fast: %v", err)
+	}
+	if !r.UpToDate() {
+		t.Fatalf("fresh build not up to date: %+v", r)
+	}
+
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(vault, "Design.md"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	r, err = VerifyFast(vault)
+	if err != nil {
+		t.Fatalf("verify fast: %v", err)
+	}
+	if r.UpToDate() || r.DiskMaxMtime != future.Unix() {
+		t.Errorf("touched file: %+v, want stale with disk max mtime %d", r, future.Unix())
+	}
+
+	// Update records the new mtime, so the index is up to date again.
+	if _, err := Update(vault, UpdateOptions{Files: []string{"Design.md"}}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	r, err = VerifyFast(vault)
+	if err != nil {
+		t.Fatalf("verify fast: %v", err)
+	}
+	if !r.UpToDate() {
+		t.Errorf("after update: %+v, want up to date", r)
+	}
+
+	if err := os.WriteFile(filepath.Join(vault, "New.md"), []byte("# New\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(vault, "New.md"), time.Unix(0, 0), time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	r, err = VerifyFast(vault)
+	if err != nil {
+		t.Fatalf("verify fast: %v", err)
+	}
+	if r.UpToDate() || r.DiskNotes != r.IndexNotes+1 {
+		t.Errorf("new file: %+v, want stale with one more disk note", r)
+	}
+}
+
+func TestVerifyFastRename(t *testing.T) {
+	vault := setupFullVault(t)
+	// A rename keeps the note count and mtimes, so only the digest sees it.
+	if err := os.Rename(filepath.Join(vault, "Design.md"), filepath.Join(vault, "Renamed.md")); err != nil {
+		t.Fatal(err)
+	}
+	r, err := VerifyFast(vault)
+	if err != nil {
+		t.Fatalf("verify fast: %v", err)
+	}
+	if r.IndexNotes != r.DiskNotes || r.IndexMaxMtime != r.DiskMaxMtime {
+		t.Fatalf("rename changed count or max mtime: %+v", r)
+	}
+	if r.UpToDate() {
+		t.Errorf("renamed file: %+v, want stale", r)
+	}
+}
+
+func TestVerifyFastNotRecorded(t *testing.T) {
+	vault := setupFullVault(t)
+	db := openTestDB(t, dbPath(vault))
+	if _, err := db.Exec("DELETE FROM meta WHERE key = 'note_count'"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	r, err := VerifyFast(vault)
+	if err != nil {
+		t.Fatalf("verify fast: %v", err)
+	}
+	if r.Recorded || r.UpToDate() {
+		t.Errorf("result = %+v, want stale without a recorded aggregate", r)
+	}
+}
+
+func TestVerifyFastAssets(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":      "![[image.png]]\n",
+		"image.png": "png",
+	})
+	if err := Build(vault); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	r, err := VerifyFast(vault)
+	if err != nil {
+		t.Fatalf("verify fast: %v", err)
+	}
+	if !r.UpToDate() || r.IndexAssets != 1 {
+		t.Fatalf("fresh build: %+v, want up to date with 1 asset", r)
+	}
+
+	// Build indexes every asset, so an unreferenced new one is stale too.
+	if err := os.WriteFile(filepath.Join(vault, "unused.pdf"), []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err = VerifyFast(vault)
+	if err != nil {
+		t.Fatalf("verify fast: %v", err)
+	}
+	if r.UpToDate() || r.DiskAssets != 2 || r.IndexNotes != r.DiskNotes {
+		t.Errorf("new asset: %+v, want stale with 2 disk assets", r)
+	}
+}
+
+func TestVerifyFastBuildOptions(t *testing.T) {
+	vault := t.TempDir()
+	writeVaultFiles(t, vault, map[string]string{
+		"A.md":         "# A\n",
+		"B.md":         "# B\n",
+		"drafts/D.md":  "# D\n",
+		"drafts/d.png": "png",
+	})
+	if err := BuildWithOptions(vault, BuildOptions{ExcludePaths: []string{"drafts/**"}}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	r, err := VerifyFast(vault)
+	if err != nil {
+		t.Fatalf("verify fast: %v", err)
+	}
+	if !r.UpToDate() || r.DiskNotes != 2 || r.DiskAssets != 0 {
+		t.Errorf("result = %+v, want up to date with the build's exclusions applied", r)
+	}
+}